@@ -0,0 +1,214 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/korjavin/whatsfordinner/pkg/audit"
+	"github.com/korjavin/whatsfordinner/pkg/migrate"
+	"github.com/korjavin/whatsfordinner/pkg/openai"
+	"github.com/korjavin/whatsfordinner/pkg/simulate"
+	"github.com/korjavin/whatsfordinner/pkg/state"
+	"github.com/korjavin/whatsfordinner/pkg/storage"
+)
+
+// handleGC handles the /gc command
+func (a *app) handleGC(message *tgbotapi.Message) {
+	chatID := message.Chat.ID
+
+	if !a.requireAdmin(message) {
+		return
+	}
+
+	before, err := a.store.Stats()
+	if err != nil {
+		a.log.Error("Failed to get database stats: %v", err)
+		a.bot.SendMessage(chatID, "😢 Sorry, I couldn't read the database stats. Please try again later.")
+		return
+	}
+
+	var outcome string
+	err = a.store.RunGC()
+	switch {
+	case errors.Is(err, storage.ErrNoRewrite):
+		outcome = "🧹 Nothing to clean up, the database is already compact."
+	case err != nil:
+		a.log.Error("Database GC failed: %v", err)
+		outcome = fmt.Sprintf("😢 Cleaning up the database failed: %v", err)
+	default:
+		outcome = "🧹 Cleaned up the database and reclaimed unused space."
+	}
+
+	after, err := a.store.Stats()
+	if err != nil {
+		after = before
+	}
+
+	msgText := fmt.Sprintf("%s\n\n🗄️ Keys: %d\n📇 Index size: %s\n📦 Value log size: %s (was %s)\n",
+		outcome, after.Keys, formatBytes(after.LSMSize), formatBytes(after.ValueLogSize), formatBytes(before.ValueLogSize))
+	msgText += formatKeyTypes(after.KeysByType)
+	msgText += "\nSizes are refreshed about once a minute."
+	a.bot.SendMessage(chatID, msgText)
+}
+
+// handleAudit handles the /audit command
+func (a *app) handleAudit(message *tgbotapi.Message) {
+	chatID := message.Chat.ID
+
+	if !a.requireAdmin(message) {
+		return
+	}
+
+	limit := audit.DefaultLimit
+	if args := strings.TrimSpace(message.CommandArguments()); args != "" {
+		n, err := strconv.Atoi(args)
+		if err != nil || n < 1 || n > audit.MaxLimit {
+			a.bot.SendMessage(chatID, fmt.Sprintf("🤔 Please give a number of events from 1 to %d, e.g. /audit 30", audit.MaxLimit))
+			return
+		}
+		limit = n
+	}
+
+	events, err := a.auditService.Recent(chatID, limit)
+	if err != nil {
+		a.log.Error("Failed to get audit events: %v", err)
+		a.bot.SendMessage(chatID, "😢 Sorry, I couldn't read the audit log. Please try again later.")
+		return
+	}
+
+	if len(events) == 0 {
+		a.bot.SendMessage(chatID, "📜 Nothing has happened in this chat yet.")
+		return
+	}
+
+	a.bot.SendMessage(chatID, formatAuditEvents(events, a.channelLocation(chatID)))
+}
+
+// handleUsage handles the /usage command
+func (a *app) handleUsage(message *tgbotapi.Message) {
+	chatID := message.Chat.ID
+	if !a.requireAdmin(message) {
+		return
+	}
+
+	formatUsage := func(usage openai.Usage) string {
+		text := fmt.Sprintf("%d requests, %d prompt + %d completion tokens", usage.Requests, usage.PromptTokens, usage.CompletionTokens)
+		if cost, ok := a.openaiClient.EstimateCost(usage); ok {
+			text += fmt.Sprintf(" (~$%.4f)", cost)
+		}
+		return text
+	}
+
+	msgText := "🤖 AI usage since the bot started:\n\n"
+	msgText += fmt.Sprintf("This chat: %s\n", formatUsage(a.openaiClient.ChannelUsage(chatID)))
+	msgText += fmt.Sprintf("All chats: %s\n", formatUsage(a.openaiClient.TotalUsage()))
+
+	a.bot.SendMessage(chatID, msgText)
+}
+
+// handleAICheck handles the /ai_check command
+func (a *app) handleAICheck(message *tgbotapi.Message) {
+	chatID := message.Chat.ID
+	if !a.requireAdmin(message) {
+		return
+	}
+
+	processingMsg, _ := a.bot.SendMessage(chatID, "🤖 Asking the AI to reply...")
+	check := a.openaiClient.WithChannel(chatID).Check()
+	a.bot.EditMessage(chatID, processingMsg.MessageID, formatHealthCheck(check))
+}
+
+// handleReset handles the /reset command
+func (a *app) handleReset(message *tgbotapi.Message) {
+	chatID := message.Chat.ID
+
+	current := a.stateManager.GetState(chatID)
+	a.stateManager.ClearState(chatID)
+
+	if current == state.StateNormal {
+		a.bot.SendMessage(chatID, "👌 All good, I wasn't waiting for anything. Your messages are treated normally.")
+		return
+	}
+	a.bot.SendMessage(chatID, fmt.Sprintf("🔄 Back to normal! I was waiting for %s, but I won't treat your messages that way anymore.", chatStateDescription(current)))
+}
+
+// handleMigrateFrom handles the /migrate_from command
+func (a *app) handleMigrateFrom(message *tgbotapi.Message) {
+	chatID := message.Chat.ID
+
+	arg := strings.TrimSpace(message.CommandArguments())
+	if arg == "" {
+		a.bot.SendMessage(chatID, fmt.Sprintf("📦 Moved here from another group? Tell me the old group's ID and I'll copy its fridge, stats, dinners and suggestions, e.g. /migrate_from -1001234567890. This group's ID is %d.", chatID))
+		return
+	}
+	oldChatID, err := strconv.ParseInt(arg, 10, 64)
+	if err != nil {
+		a.bot.SendMessage(chatID, "❓ Please give me the old group's numeric ID, e.g. /migrate_from -1001234567890")
+		return
+	}
+
+	if !a.requireAdmin(message) {
+		return
+	}
+
+	// Only admins of the old group may take its data, so nobody can copy another family's data by guessing an ID
+	isOldAdmin, err := a.bot.IsChatAdmin(&tgbotapi.Chat{ID: oldChatID, Type: "supergroup"}, message.From.ID)
+	if err != nil || !isOldAdmin {
+		if err != nil {
+			a.log.Error("Failed to check admin status in chat %d: %v", oldChatID, err)
+		}
+		a.bot.SendMessage(chatID, "🔒 I can only copy data from a group where you're an admin and I'm still a member.")
+		return
+	}
+
+	result, err := a.migrateService.Copy(oldChatID, chatID)
+	switch {
+	case errors.Is(err, migrate.ErrSameChannel):
+		a.bot.SendMessage(chatID, "🤔 That's this group's ID. Please give me the ID of the old group.")
+		return
+	case errors.Is(err, migrate.ErrTargetHasData):
+		a.bot.SendMessage(chatID, "⚠️ This group already has a fridge, stats, dinners or suggestions, so I won't copy anything over them.")
+		return
+	case errors.Is(err, migrate.ErrNothingToMigrate):
+		a.bot.SendMessage(chatID, "🤷 I don't have any data for that group.")
+		return
+	case err != nil:
+		a.log.Error("Failed to migrate channel %d to %d: %v", oldChatID, chatID, err)
+		a.bot.SendMessage(chatID, "😢 Sorry, I couldn't copy everything over. Please try again later.")
+		return
+	}
+
+	resultMsg := fmt.Sprintf("📦 Copied from the old group: %d ingredients, %d dinners and %d suggestions", result.Ingredients, result.Dinners, result.Suggestions)
+	if result.Stats {
+		resultMsg += ", plus the cooking stats"
+	}
+	a.bot.SendMessage(chatID, resultMsg+". The old group keeps its data.")
+}
+
+// handleSimulate handles the /simulate command, which is only registered in development mode
+func (a *app) handleSimulate(message *tgbotapi.Message) {
+	chatID := message.Chat.ID
+
+	if !a.requireAdmin(message) {
+		return
+	}
+
+	steps, err := a.simulateService.Run(chatID)
+	msgText := "🧪 Simulation\n\n"
+	for i, step := range steps {
+		msgText += fmt.Sprintf("%d. %s\n", i+1, step)
+	}
+	switch {
+	case errors.Is(err, simulate.ErrBusy):
+		msgText = "🧪 A poll or dinner is in progress. Finish it before running a simulation."
+	case err != nil:
+		a.log.Error("Simulation failed: %v", err)
+		msgText += fmt.Sprintf("\n❌ Failed: %v", err)
+	default:
+		msgText += "\n✅ Every step went through."
+	}
+	a.bot.SendMessage(chatID, msgText)
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/korjavin/whatsfordinner/pkg/state"
+)
+
+func TestGCReportsOutcomeAndStats(t *testing.T) {
+	ta := newTestApp(t)
+	admin := testUser(1, "Anna")
+	ta.telegram.SetAdmin(admin.ID)
+	for _, name := range []string{"milk", "eggs"} {
+		if err := ta.fridgeService.AddIngredient(testChatID, name, ""); err != nil {
+			t.Fatalf("AddIngredient failed: %v", err)
+		}
+	}
+
+	// A fresh database has nothing to clean up
+	ta.handleGC(command(admin, "/gc"))
+	reply := ta.telegram.LastText()
+	if !strings.Contains(reply, "Nothing to clean up") {
+		t.Errorf("/gc replied %q, want it to report there was nothing to reclaim", reply)
+	}
+	if !strings.Contains(reply, "Keys: 1\n") || !strings.Contains(reply, "fridge") {
+		t.Errorf("/gc replied %q, want the key count and types", reply)
+	}
+}
+
+func TestGCIsAdminOnly(t *testing.T) {
+	ta := newTestApp(t)
+
+	ta.handleGC(command(testUser(2, "Ben"), "/gc"))
+	if reply := ta.telegram.LastText(); !strings.Contains(reply, "Only chat admins") {
+		t.Errorf("/gc by a member replied %q, want it refused", reply)
+	}
+}
+
+func TestResetClearsTheChatState(t *testing.T) {
+	ta := newTestApp(t)
+	anna := testUser(1, "Anna")
+	ta.stateManager.SetState(testChatID, state.StateAddingIngredients)
+
+	ta.handleReset(command(anna, "/reset"))
+	if got := ta.stateManager.GetState(testChatID); got != state.StateNormal {
+		t.Errorf("state after /reset = %q, want normal", got)
+	}
+	if reply := ta.telegram.LastText(); !strings.HasPrefix(reply, "🔄 Back to normal!") || !strings.Contains(reply, "ingredients to add to the fridge") {
+		t.Errorf("/reset replied %q, want it to confirm the reset and say what was pending", reply)
+	}
+
+	// Resetting again has nothing to clear
+	ta.handleReset(command(anna, "/reset"))
+	if reply := ta.telegram.LastText(); !strings.Contains(reply, "I wasn't waiting for anything") {
+		t.Errorf("second /reset replied %q, want it to say nothing was pending", reply)
+	}
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/korjavin/whatsfordinner/pkg/audit"
+	"github.com/korjavin/whatsfordinner/pkg/config"
+	"github.com/korjavin/whatsfordinner/pkg/dinner"
+	"github.com/korjavin/whatsfordinner/pkg/fridge"
+	"github.com/korjavin/whatsfordinner/pkg/logger"
+	"github.com/korjavin/whatsfordinner/pkg/messages"
+	"github.com/korjavin/whatsfordinner/pkg/migrate"
+	"github.com/korjavin/whatsfordinner/pkg/models"
+	"github.com/korjavin/whatsfordinner/pkg/openai"
+	"github.com/korjavin/whatsfordinner/pkg/poll"
+	"github.com/korjavin/whatsfordinner/pkg/prefs"
+	"github.com/korjavin/whatsfordinner/pkg/scheduler"
+	"github.com/korjavin/whatsfordinner/pkg/simulate"
+	"github.com/korjavin/whatsfordinner/pkg/state"
+	"github.com/korjavin/whatsfordinner/pkg/stats"
+	"github.com/korjavin/whatsfordinner/pkg/storage"
+	"github.com/korjavin/whatsfordinner/pkg/suggest"
+	"github.com/korjavin/whatsfordinner/pkg/telegram"
+)
+
+// app holds the services the bot's command and callback handlers work with.
+// main builds it and wires its handlers up, the handlers live in one file per feature.
+type app struct {
+	cfg          *config.Config
+	store        *storage.Store
+	bot          *telegram.Bot
+	openaiClient *openai.Client
+	log          *logger.Logger
+	commands     *telegram.CommandRegistry
+
+	fridgeService    *fridge.Service
+	dinnerService    *dinner.Service
+	pollService      *poll.Service
+	messageService   *messages.Service
+	stateManager     *state.Manager
+	suggestService   *suggest.Service
+	statsService     *stats.Service
+	prefsService     *prefs.Service
+	auditService     *audit.Service
+	migrateService   *migrate.Service
+	schedulerService *scheduler.Service
+	simulateService  *simulate.Service // Only set in development mode
+
+	// Live vote tallies are edited at most once every few seconds per poll
+	tallyDebouncer *poll.Debouncer
+}
+
+// channelLocation returns the time zone of a chat
+func (a *app) channelLocation(chatID int64) *time.Location {
+	var channelState models.ChannelState
+	if err := a.store.Get(fmt.Sprintf("channel:%d", chatID), &channelState); err != nil {
+		return time.Local
+	}
+	return channelState.Location()
+}
+
+// requireAdmin checks that the sender of a message is a chat admin and tells them otherwise
+func (a *app) requireAdmin(message *tgbotapi.Message) bool {
+	isAdmin, err := a.bot.IsChatAdmin(message.Chat, message.From.ID)
+	if err != nil {
+		a.log.Error("Failed to check admin status: %v", err)
+		a.bot.SendMessage(message.Chat.ID, "😢 Sorry, I couldn't check your permissions right now. Please try again later.")
+		return false
+	}
+	if !isAdmin {
+		a.bot.SendMessage(message.Chat.ID, "🔒 Only chat admins can use this command.")
+		return false
+	}
+	return true
+}
+
+// targetUser finds the user a maintenance command is about: the author of the replied-to
+// message, a mentioned user, or an @username known from the stats.
+// It returns the user ID, a display name and the remaining command arguments.
+func (a *app) targetUser(message *tgbotapi.Message) (string, string, string, bool) {
+	args := strings.TrimSpace(message.CommandArguments())
+
+	if message.ReplyToMessage != nil && message.ReplyToMessage.From != nil {
+		from := message.ReplyToMessage.From
+		name := from.UserName
+		if name == "" {
+			name = from.FirstName
+		}
+		return fmt.Sprintf("%d", from.ID), name, args, true
+	}
+
+	fields := strings.Fields(args)
+	if len(fields) == 0 {
+		return "", "", "", false
+	}
+	rest := strings.TrimSpace(strings.TrimPrefix(args, fields[0]))
+
+	for _, entity := range message.Entities {
+		if entity.Type == "text_mention" && entity.User != nil {
+			return fmt.Sprintf("%d", entity.User.ID), entity.User.FirstName, rest, true
+		}
+	}
+
+	if strings.HasPrefix(fields[0], "@") {
+		username := strings.TrimPrefix(fields[0], "@")
+		if userID, ok := a.statsService.FindUserIDByUsername(message.Chat.ID, username); ok {
+			return userID, username, rest, true
+		}
+	}
+
+	return "", "", "", false
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/korjavin/whatsfordinner/internal/test"
+	"github.com/korjavin/whatsfordinner/pkg/audit"
+	"github.com/korjavin/whatsfordinner/pkg/config"
+	"github.com/korjavin/whatsfordinner/pkg/dinner"
+	"github.com/korjavin/whatsfordinner/pkg/fridge"
+	"github.com/korjavin/whatsfordinner/pkg/logger"
+	"github.com/korjavin/whatsfordinner/pkg/messages"
+	"github.com/korjavin/whatsfordinner/pkg/migrate"
+	"github.com/korjavin/whatsfordinner/pkg/openai"
+	"github.com/korjavin/whatsfordinner/pkg/poll"
+	"github.com/korjavin/whatsfordinner/pkg/prefs"
+	"github.com/korjavin/whatsfordinner/pkg/scheduler"
+	"github.com/korjavin/whatsfordinner/pkg/state"
+	"github.com/korjavin/whatsfordinner/pkg/stats"
+	"github.com/korjavin/whatsfordinner/pkg/suggest"
+	"github.com/korjavin/whatsfordinner/pkg/telegram"
+)
+
+// testChatID is the group chat the handler tests talk in
+const testChatID int64 = -100
+
+// testApp is an app wired to a temporary store and fake Telegram and OpenAI APIs
+type testApp struct {
+	*app
+	telegram *test.Telegram
+	openai   *test.OpenAI
+}
+
+// newTestApp creates an app for handler tests. Live tallies are refreshed after 10ms.
+func newTestApp(t *testing.T) *testApp {
+	t.Helper()
+
+	store := test.NewStore(t)
+	fakeTelegram := test.NewTelegram(t)
+	fakeOpenAI := test.NewOpenAI(t)
+
+	bot, err := telegram.NewWithEndpoint("test-token", fakeTelegram.Endpoint(), 1)
+	if err != nil {
+		t.Fatalf("failed to create bot: %v", err)
+	}
+	bot.SetPacing(0, 0)
+
+	cfg := &config.Config{
+		Cuisines:             []string{"Italian"},
+		CookVolunteerTimeout: 15 * time.Minute,
+		RatingScale:          5,
+		MaxPhotosPerSession:  5,
+		ImageMaxDimension:    1024,
+	}
+
+	openaiClient := openai.New("test-key", fakeOpenAI.BaseURL(), "test-model")
+	fridgeService := fridge.New(store)
+	dinnerService := dinner.New(store, fridgeService, openaiClient)
+	pollService := poll.New(store)
+	statsService := stats.New(store)
+	prefsService := prefs.New(store)
+
+	a := &app{
+		cfg:              cfg,
+		store:            store,
+		bot:              bot,
+		openaiClient:     openaiClient,
+		log:              logger.New("test"),
+		commands:         telegram.NewCommandRegistry(),
+		fridgeService:    fridgeService,
+		dinnerService:    dinnerService,
+		pollService:      pollService,
+		messageService:   messages.New(store, openaiClient, false),
+		stateManager:     state.New(),
+		suggestService:   suggest.New(store),
+		statsService:     statsService,
+		prefsService:     prefsService,
+		auditService:     audit.New(store),
+		migrateService:   migrate.New(store),
+		schedulerService: scheduler.New(store, bot, fridgeService, pollService, dinnerService, statsService, prefsService, openaiClient, cfg.Cuisines, cfg.CookVolunteerTimeout, 0, dinner.Cooldown{}, cfg.RatingScale),
+		tallyDebouncer:   poll.NewDebouncer(10 * time.Millisecond),
+	}
+
+	// Cleanups run last in first out, so tally updates are done before the store is closed
+	t.Cleanup(a.tallyDebouncer.Stop)
+
+	return &testApp{app: a, telegram: fakeTelegram, openai: fakeOpenAI}
+}
+
+// testUser returns a chat member with the given ID
+func testUser(id int64, name string) *tgbotapi.User {
+	return &tgbotapi.User{ID: id, FirstName: name, UserName: strings.ToLower(name)}
+}
+
+// command builds a message sending a command like "/remove milk" to the test chat
+func command(from *tgbotapi.User, text string) *tgbotapi.Message {
+	commandLen := len(text)
+	if i := strings.Index(text, " "); i >= 0 {
+		commandLen = i
+	}
+	return &tgbotapi.Message{
+		MessageID: 1,
+		From:      from,
+		Chat:      &tgbotapi.Chat{ID: testChatID, Type: "group"},
+		Date:      int(time.Now().Unix()),
+		Text:      text,
+		Entities:  []tgbotapi.MessageEntity{{Type: "bot_command", Offset: 0, Length: commandLen}},
+	}
+}
+
+// callback builds a button press with the given data on a message in the test chat
+func callback(from *tgbotapi.User, messageID int, data string) *tgbotapi.CallbackQuery {
+	return &tgbotapi.CallbackQuery{
+		ID:      "callback",
+		From:    from,
+		Message: &tgbotapi.Message{MessageID: messageID, Chat: &tgbotapi.Chat{ID: testChatID, Type: "group"}},
+		Data:    data,
+	}
+}
+
+// press hands a button press to a callback handler the way the bot dispatches it, with the payload of its data
+func press(handler telegram.CallbackHandler, callback *tgbotapi.CallbackQuery) {
+	_, payload := telegram.ParseCallbackData(callback.Data)
+	handler(callback, payload)
+}
+
+// waitFor polls until cond is true, failing the test after a second
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
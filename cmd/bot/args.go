@@ -0,0 +1,35 @@
+package main
+
+import (
+	"strings"
+)
+
+// splitQuotedArgs splits command arguments on spaces, keeping "quoted phrases" together
+func splitQuotedArgs(args string) []string {
+	var result []string
+	var current strings.Builder
+	inQuotes := false
+	hasArg := false
+
+	for _, r := range args {
+		switch {
+		case r == '"' || r == '“' || r == '”':
+			inQuotes = !inQuotes
+			hasArg = true
+		case r == ' ' && !inQuotes:
+			if hasArg {
+				result = append(result, current.String())
+				current.Reset()
+				hasArg = false
+			}
+		default:
+			current.WriteRune(r)
+			hasArg = true
+		}
+	}
+	if hasArg {
+		result = append(result, current.String())
+	}
+
+	return result
+}
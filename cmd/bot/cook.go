@@ -0,0 +1,317 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/korjavin/whatsfordinner/pkg/dinner"
+	"github.com/korjavin/whatsfordinner/pkg/messages"
+	"github.com/korjavin/whatsfordinner/pkg/models"
+	"github.com/korjavin/whatsfordinner/pkg/poll"
+	"github.com/korjavin/whatsfordinner/pkg/storage"
+)
+
+// handleVolunteerCallback handles people volunteering to cook the winning dish
+func (a *app) handleVolunteerCallback(callback *tgbotapi.CallbackQuery, pollID string) {
+	chatID := callback.Message.Chat.ID
+	userID := fmt.Sprintf("%d", callback.From.ID)
+	username := callback.From.UserName
+	if username == "" {
+		username = callback.From.FirstName
+	}
+
+	if pollID == "" {
+		a.log.Error("Invalid callback data: %s", callback.Data)
+		a.bot.AnswerCallbackQuery(callback.ID, "Something went wrong. Please try again.")
+		return
+	}
+
+	// Add the volunteer
+	err := a.pollService.AddCookVolunteer(chatID, pollID, userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, poll.ErrNotWinningVoter):
+			message := "Only people who voted for the winning dish can cook it 🙂"
+			if vote, err := a.pollService.GetVote(chatID, pollID); err == nil {
+				message = fmt.Sprintf("Only people who voted for %s can cook it 🙂", vote.WinningDish)
+			}
+			a.bot.AnswerCallbackQuery(callback.ID, message)
+		case errors.Is(err, storage.ErrNotFound):
+			a.bot.AnswerCallbackQuery(callback.ID, "This poll is no longer available.")
+		case errors.Is(err, poll.ErrVoteOpen):
+			a.bot.AnswerCallbackQuery(callback.ID, "Voting is open again, wait for the poll to close 🙂")
+		default:
+			a.log.Error("Failed to add cook volunteer: %v", err)
+			a.bot.AnswerCallbackQuery(callback.ID, "Something went wrong. Please try again.")
+		}
+		return
+	}
+
+	// The first volunteer cooks
+	if err := a.pollService.SelectCook(chatID, pollID, userID); err != nil {
+		a.log.Error("Failed to select cook: %v", err)
+	}
+
+	// Answer the callback
+	a.bot.AnswerCallbackQuery(callback.ID, "Thanks for volunteering to cook!")
+
+	// Get the vote to find the winning dish
+	vote, err := a.pollService.GetVote(chatID, pollID)
+	if err != nil {
+		a.log.Error("Failed to get vote: %v", err)
+		return
+	}
+
+	// Edit the message to remove the buttons
+	a.bot.EditMessageWithKeyboard(chatID, callback.Message.MessageID, fmt.Sprintf("@%s has volunteered to cook %s tonight!", messages.EscapeMarkdown(username), messages.Bold(vote.WinningDish)), tgbotapi.InlineKeyboardMarkup{})
+
+	// Reuse the recipe of a dish cooked before, otherwise get dish information from OpenAI
+	dish, found := a.dinnerService.FindDish(chatID, vote.WinningDish)
+	if !found {
+		dishInfo, err := a.openaiClient.WithChannel(chatID).GetDishInfo(vote.WinningDish)
+		if err != nil {
+			a.log.Error("Failed to get dish info: %v", err)
+			a.bot.SendMessage(chatID, fmt.Sprintf("😢 Sorry, I couldn't find cooking instructions for %s. @%s, you're on your own for this one!", messages.EscapeMarkdown(vote.WinningDish), messages.EscapeMarkdown(username)))
+			return
+		}
+
+		// Create a dish object, falling back to the winning dish name
+		dish = dinner.DishFromInfo(dishInfo, vote.WinningDish)
+	}
+
+	// Create a dinner event
+	dinnerEvent, err := a.dinnerService.CreateDinner(chatID, dish, userID)
+	if err != nil {
+		a.log.Error("Failed to create dinner event: %v", err)
+		a.bot.SendMessage(chatID, a.messageService.GenerateErrorMessage("start the dinner"))
+		return
+	}
+
+	// Update cook statistics with the username
+	err = a.statsService.UpdateCookStats(chatID, userID, username, 0) // No rating yet
+	if err != nil {
+		a.log.Error("Failed to update cook stats: %v", err)
+		// Continue anyway
+	}
+
+	// Update suggester statistics if this was a user-suggested dish
+	// We would need to check if the dish was suggested by a user and update their stats
+	// For now, we'll just update the cook's stats when the dinner is rated
+
+	// Send cooking instructions
+	a.sendCookingInstructions(chatID, "", dish, dinnerEvent.ID)
+}
+
+// handleHandoffCallback handles a cook passing cooking on to someone else
+func (a *app) handleHandoffCallback(callback *tgbotapi.CallbackQuery, dinnerID string) {
+	chatID := callback.Message.Chat.ID
+	userID := fmt.Sprintf("%d", callback.From.ID)
+	username := callback.From.UserName
+	if username == "" {
+		username = callback.From.FirstName
+	}
+
+	dinnerEvent, err := a.dinnerService.PassCooking(chatID, dinnerID, userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, dinner.ErrNotCook):
+			a.bot.AnswerCallbackQuery(callback.ID, "Only the cook can pass cooking on.")
+		case errors.Is(err, dinner.ErrNoActiveDinner), errors.Is(err, storage.ErrNotFound):
+			a.bot.AnswerCallbackQuery(callback.ID, "This dinner is no longer being cooked.")
+		default:
+			a.log.Error("Failed to pass cooking on: %v", err)
+			a.bot.AnswerCallbackQuery(callback.ID, "Something went wrong. Please try again.")
+		}
+		return
+	}
+
+	// Reopen the cook call on the vote without redoing the poll
+	if vote, err := a.pollService.GetLastVote(chatID); err == nil && strings.EqualFold(vote.WinningDish, dinnerEvent.Dish.Name) {
+		if err := a.pollService.ReleaseCook(chatID, vote.PollID); err != nil {
+			a.log.Error("Failed to release cook: %v", err)
+		}
+	}
+
+	a.bot.AnswerCallbackQuery(callback.ID, "Asking who can take over.")
+
+	// The instructions were already sent, so only take the buttons off them
+	a.bot.EditMessageWithKeyboard(chatID, callback.Message.MessageID, messages.EscapeMarkdown(callback.Message.Text)+fmt.Sprintf("\n\n🔄 @%s passed cooking on.", messages.EscapeMarkdown(username)), tgbotapi.InlineKeyboardMarkup{})
+
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("👨‍🍳 I'll cook", fmt.Sprintf("takeover:%s", dinnerID)),
+		),
+	)
+	a.bot.SendMessageWithKeyboard(chatID, fmt.Sprintf("🔄 @%s can't cook %s after all. Who can take over? Press the button below!", messages.EscapeMarkdown(username), messages.EscapeMarkdown(dinnerEvent.Dish.Name)), keyboard)
+}
+
+// handleTakeoverCallback handles someone taking over cooking after a handoff
+func (a *app) handleTakeoverCallback(callback *tgbotapi.CallbackQuery, dinnerID string) {
+	chatID := callback.Message.Chat.ID
+	userID := fmt.Sprintf("%d", callback.From.ID)
+	username := callback.From.UserName
+	if username == "" {
+		username = callback.From.FirstName
+	}
+
+	// The same rules as for the first volunteer apply, e.g. only voters for the dish may cook it
+	vote, err := a.pollService.GetLastVote(chatID)
+	if err == nil {
+		err = a.pollService.AddCookVolunteer(chatID, vote.PollID, userID)
+		if errors.Is(err, poll.ErrNotWinningVoter) {
+			a.bot.AnswerCallbackQuery(callback.ID, fmt.Sprintf("Only people who voted for %s can cook it 🙂", vote.WinningDish))
+			return
+		}
+	}
+
+	dinnerEvent, previousCook, err := a.dinnerService.TakeOverCooking(chatID, dinnerID, userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, dinner.ErrNoHandoff):
+			a.bot.AnswerCallbackQuery(callback.ID, "Someone has already taken over.")
+		case errors.Is(err, dinner.ErrNoActiveDinner), errors.Is(err, storage.ErrNotFound):
+			a.bot.AnswerCallbackQuery(callback.ID, "This dinner is no longer being cooked.")
+		default:
+			a.log.Error("Failed to take over cooking: %v", err)
+			a.bot.AnswerCallbackQuery(callback.ID, "Something went wrong. Please try again.")
+		}
+		return
+	}
+
+	if vote != nil {
+		if err := a.pollService.SelectCook(chatID, vote.PollID, userID); err != nil {
+			a.log.Error("Failed to select cook: %v", err)
+		}
+	}
+
+	// Move the dinner in the cook statistics to the new cook
+	if previousCook != userID {
+		if _, err := a.statsService.AdjustCookStat(chatID, previousCook, -1, 0); err != nil {
+			a.log.Error("Failed to adjust cook stats: %v", err)
+		}
+		if err := a.statsService.UpdateCookStats(chatID, userID, username, 0); err != nil {
+			a.log.Error("Failed to update cook stats: %v", err)
+		}
+	}
+
+	a.bot.AnswerCallbackQuery(callback.ID, "Thanks for taking over!")
+
+	a.bot.EditMessageWithKeyboard(chatID, callback.Message.MessageID, fmt.Sprintf("👨‍🍳 @%s is taking over cooking %s tonight!", messages.EscapeMarkdown(username), messages.Bold(dinnerEvent.Dish.Name)), tgbotapi.InlineKeyboardMarkup{})
+
+	a.sendCookingInstructions(chatID, fmt.Sprintf("@%s, here's the recipe.\n\n", messages.EscapeMarkdown(username)), dinnerEvent.Dish, dinnerEvent.ID)
+}
+
+// handleDinnerReadyCallback handles the cook marking dinner as ready
+func (a *app) handleDinnerReadyCallback(callback *tgbotapi.CallbackQuery, dinnerID string) {
+	chatID := callback.Message.Chat.ID
+	userID := fmt.Sprintf("%d", callback.From.ID)
+	username := callback.From.UserName
+	if username == "" {
+		username = callback.From.FirstName
+	}
+
+	// The callback data is "dinner_ready:dinner:{channelID}:{timestamp}", its payload is the dinner ID
+	if dinnerID == "" {
+		a.log.Error("Invalid callback data: %s", callback.Data)
+		a.bot.AnswerCallbackQuery(callback.ID, "Something went wrong. Please try again.")
+		return
+	}
+
+	// Get the dinner event
+	a.log.Info("Looking up dinner with ID: %s", dinnerID)
+	var dinnerEvent models.Dinner
+	err := a.store.Get(dinnerID, &dinnerEvent)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			a.bot.AnswerCallbackQuery(callback.ID, "This dinner is no longer available.")
+			return
+		}
+		a.log.Error("Failed to get dinner event: %v", err)
+		a.bot.AnswerCallbackQuery(callback.ID, "Something went wrong. Please try again.")
+		return
+	}
+	a.log.Info("Successfully found dinner: %s cooked by %s", dinnerEvent.Dish.Name, dinnerEvent.Cook)
+
+	// Check if the user is the cook
+	if dinnerEvent.Cook != userID {
+		a.bot.AnswerCallbackQuery(callback.ID, "Only the cook can mark dinner as ready.")
+		return
+	}
+
+	// Mark the dinner as finished
+	dinnerService := dinner.New(a.store, a.fridgeService, a.openaiClient)
+	err = dinnerService.FinishDinner(chatID)
+	if err != nil {
+		if errors.Is(err, dinner.ErrNoActiveDinner) {
+			a.bot.AnswerCallbackQuery(callback.ID, "This dinner has already been marked as ready.")
+			return
+		}
+		a.log.Error("Failed to finish dinner: %v", err)
+		a.bot.AnswerCallbackQuery(callback.ID, "Something went wrong. Please try again.")
+		return
+	}
+
+	// Answer the callback
+	a.bot.AnswerCallbackQuery(callback.ID, "Dinner is ready!")
+
+	// Edit the message to remove the buttons
+	a.bot.EditMessageWithKeyboard(chatID, callback.Message.MessageID, messages.EscapeMarkdown(callback.Message.Text)+"\n\n✅ Dinner is ready!", tgbotapi.InlineKeyboardMarkup{})
+
+	// Send a message to the chat, letting everyone who eats check in
+	eatingKeyboard := tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("🍽️ I'm eating", fmt.Sprintf("eating:%s", dinnerID)),
+		),
+	)
+	a.bot.SendMessageWithKeyboard(chatID, fmt.Sprintf("🍽️ *Dinner is ready!* @%s has prepared %s. Enjoy your meal!", messages.EscapeMarkdown(username), messages.EscapeMarkdown(dinnerEvent.Dish.Name)), eatingKeyboard)
+
+	// Add rating buttons
+	a.log.Info("Creating rating buttons for dinner ID: %s", dinnerID)
+
+	keyboard := ratingKeyboard(dinnerID, a.cfg.RatingScale, dinnerService.RatingStyle(chatID))
+
+	ratingMsg, err := a.bot.SendMessageWithKeyboard(chatID, "How would you rate tonight's dinner? Tap a rating or react to this message (👍, 🔥, 🤔, 👎...). Your feedback helps improve future suggestions!", keyboard)
+	if err != nil {
+		a.log.Error("Failed to send rating message: %v", err)
+		return
+	}
+
+	// Remember the rating message so reactions on it count as ratings
+	err = dinnerService.SetRatingMessage(chatID, ratingMsg.MessageID, dinnerID)
+	if err != nil {
+		a.log.Error("Failed to save rating message: %v", err)
+	}
+}
+
+// handleEatingCallback handles attendance check-ins on the "dinner is ready" message
+func (a *app) handleEatingCallback(callback *tgbotapi.CallbackQuery, dinnerID string) {
+	chatID := callback.Message.Chat.ID
+	userID := fmt.Sprintf("%d", callback.From.ID)
+
+	count, added, err := a.dinnerService.AddAttendee(dinnerID, userID)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			a.bot.AnswerCallbackQuery(callback.ID, "This dinner is no longer available.")
+			return
+		}
+		a.log.Error("Failed to record attendance: %v", err)
+		a.bot.AnswerCallbackQuery(callback.ID, "Something went wrong. Please try again.")
+		return
+	}
+
+	if !added {
+		a.bot.AnswerCallbackQuery(callback.ID, "You're already on the list. Enjoy!")
+		return
+	}
+	a.bot.AnswerCallbackQuery(callback.ID, "Enjoy your meal! 😋")
+
+	// Show how many people are eating on the button
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("🍽️ I'm eating (%d)", count), callback.Data),
+		),
+	)
+	a.bot.EditMessageKeyboard(chatID, callback.Message.MessageID, keyboard)
+}
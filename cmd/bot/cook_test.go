@@ -0,0 +1,113 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/korjavin/whatsfordinner/pkg/models"
+)
+
+// pastaWon creates a closed vote Pasta won, with Anna and Cleo voting for Pasta and Ben for Soup
+func pastaWon(t *testing.T, ta *testApp) {
+	t.Helper()
+
+	if _, err := ta.pollService.CreateVote(testChatID, "poll-9", 1, []string{"Pasta", "Soup"}); err != nil {
+		t.Fatalf("CreateVote failed: %v", err)
+	}
+	if err := ta.pollService.RecordVote(testChatID, "poll-9", "1", "anna", "Pasta"); err != nil {
+		t.Fatalf("RecordVote failed: %v", err)
+	}
+	if err := ta.pollService.RecordVote(testChatID, "poll-9", "2", "ben", "Soup"); err != nil {
+		t.Fatalf("RecordVote failed: %v", err)
+	}
+	if err := ta.pollService.RecordVote(testChatID, "poll-9", "3", "cleo", "Pasta"); err != nil {
+		t.Fatalf("RecordVote failed: %v", err)
+	}
+	if _, err := ta.schedulerService.CloseVote(testChatID, "poll-9", time.Now()); err != nil {
+		t.Fatalf("CloseVote failed: %v", err)
+	}
+}
+
+func TestOnlyVotersCookWhenRestricted(t *testing.T) {
+	ta := newTestApp(t)
+	pastaWon(t, ta)
+	if err := ta.pollService.SetRestrictCookToVoters(testChatID, true); err != nil {
+		t.Fatalf("SetRestrictCookToVoters failed: %v", err)
+	}
+
+	press(ta.handleVolunteerCallback, callback(testUser(2, "Ben"), 5, "volunteer:poll-9"))
+
+	answers := ta.telegram.Calls("answerCallbackQuery")
+	if len(answers) != 1 || answers[0].Params.Get("text") != "Only people who voted for Pasta can cook it 🙂" {
+		t.Fatalf("answers = %v, want Ben told only Pasta voters can cook", answers)
+	}
+	vote, _ := ta.pollService.GetVote(testChatID, "poll-9")
+	if len(vote.CookVolunteers) != 0 || vote.SelectedCook != "" {
+		t.Errorf("volunteers = %v with cook %q, want nobody", vote.CookVolunteers, vote.SelectedCook)
+	}
+}
+
+func TestAnyoneCooksByDefault(t *testing.T) {
+	ta := newTestApp(t)
+	pastaWon(t, ta)
+
+	press(ta.handleVolunteerCallback, callback(testUser(2, "Ben"), 5, "volunteer:poll-9"))
+
+	vote, _ := ta.pollService.GetVote(testChatID, "poll-9")
+	if vote.SelectedCook != "2" {
+		t.Errorf("cook = %q, want Ben, who voted for Soup", vote.SelectedCook)
+	}
+}
+
+func TestEatingButtonRecordsAttendanceOnce(t *testing.T) {
+	ta := newTestApp(t)
+	dinnerID := "dinner:-100:1717266600"
+	if err := ta.store.Set(dinnerID, models.Dinner{ID: dinnerID, ChannelID: testChatID, Dish: models.Dish{Name: "Pasta"}}); err != nil {
+		t.Fatalf("failed to save dinner: %v", err)
+	}
+
+	anna, ben := testUser(1, "Anna"), testUser(2, "Ben")
+	press(ta.handleEatingCallback, callback(anna, 5, "eating:"+dinnerID))
+	press(ta.handleEatingCallback, callback(ben, 5, "eating:"+dinnerID))
+	press(ta.handleEatingCallback, callback(anna, 5, "eating:"+dinnerID))
+
+	var d models.Dinner
+	if err := ta.store.Get(dinnerID, &d); err != nil {
+		t.Fatalf("failed to get dinner: %v", err)
+	}
+	if len(d.Attendees) != 2 || d.Attendees[0] != "1" || d.Attendees[1] != "2" {
+		t.Errorf("attendees = %v, want Anna and Ben once each", d.Attendees)
+	}
+
+	edits := ta.telegram.Calls("editMessageReplyMarkup")
+	if len(edits) != 2 {
+		t.Fatalf("edited the button %d times, want only for new attendees", len(edits))
+	}
+	if markup := edits[1].Params.Get("reply_markup"); !strings.Contains(markup, "(2)") {
+		t.Errorf("button markup = %s, want it to count 2 people", markup)
+	}
+	answers := ta.telegram.Calls("answerCallbackQuery")
+	if got := answers[len(answers)-1].Params.Get("text"); !strings.Contains(got, "already on the list") {
+		t.Errorf("second press by Anna was answered %q, want her told she's on the list", got)
+	}
+}
+
+func TestVolunteerEditIsEscapedMarkdown(t *testing.T) {
+	ta := newTestApp(t)
+	pastaWon(t, ta)
+
+	press(ta.handleVolunteerCallback, callback(testUser(2, "Ben_B"), 5, "volunteer:poll-9"))
+
+	edits := ta.telegram.Calls("editMessageText")
+	if len(edits) == 0 {
+		t.Fatal("the volunteer message wasn't edited")
+	}
+	params := edits[0].Params
+	if got, want := params.Get("text"), "@ben\\_b has volunteered to cook *Pasta* tonight!"; got != want {
+		t.Errorf("edit text = %q, want %q", got, want)
+	}
+	if got := params.Get("parse_mode"); got != "Markdown" {
+		t.Errorf("edit parse_mode = %q, want Markdown", got)
+	}
+}
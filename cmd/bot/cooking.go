@@ -0,0 +1,125 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/korjavin/whatsfordinner/pkg/dinner"
+	"github.com/korjavin/whatsfordinner/pkg/messages"
+	"github.com/korjavin/whatsfordinner/pkg/models"
+)
+
+// cookingInstructions formats the recipe of a dish, scaled to the family size
+func (a *app) cookingInstructions(chatID int64, dish models.Dish) string {
+	msgText := fmt.Sprintf("🍳 %s\n\n", messages.Bold("Cooking Instructions for "+dish.Name))
+
+	// Scale the ingredients to the family size if it differs from the recipe
+	displayDish, scaled := a.dinnerService.ScaleForChannel(chatID, dish)
+	if scaled {
+		msgText += fmt.Sprintf("👪 Amounts scaled for %d servings\n\n", a.dinnerService.GetServings(chatID))
+	}
+
+	// Add ingredients
+	if len(displayDish.Ingredients) > 0 {
+		msgText += "*Ingredients:*\n"
+		for _, ingredient := range displayDish.Ingredients {
+			msgText += fmt.Sprintf("• %s\n", messages.EscapeMarkdown(ingredient))
+		}
+		msgText += "\n"
+	}
+
+	// Add instructions
+	if len(dish.Instructions) > 0 {
+		msgText += "*Instructions:*\n"
+		for i, instruction := range dish.Instructions {
+			msgText += fmt.Sprintf("%d. %s\n", i+1, messages.EscapeMarkdown(instruction))
+		}
+	}
+
+	return msgText
+}
+
+// sendCookingInstructions posts the recipe of a dinner, scaled to the family size,
+// with buttons to mark it ready or pass cooking on to someone else
+func (a *app) sendCookingInstructions(chatID int64, intro string, dish models.Dish, dinnerID string) {
+	msgText := intro + a.cookingInstructions(chatID, dish)
+
+	// Add cooking status buttons
+	callbackData := fmt.Sprintf("dinner_ready:%s", dinnerID)
+	a.log.Info("Creating 'Dinner is ready' button with callback data: %s", callbackData)
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("🍽️ Dinner is ready!", callbackData),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("🔄 Pass to someone else", fmt.Sprintf("handoff:%s", dinnerID)),
+		),
+	)
+	keyboard.InlineKeyboard = append(keyboard.InlineKeyboard, stepTimerRows(dinnerID, dish)...)
+
+	a.bot.SendMessageWithKeyboard(chatID, msgText, keyboard)
+}
+
+// handleCooking handles the /cooking command
+func (a *app) handleCooking(message *tgbotapi.Message) {
+	chatID := message.Chat.ID
+
+	current, err := a.dinnerService.CurrentDinner(chatID)
+	if errors.Is(err, dinner.ErrNoActiveDinner) {
+		a.bot.SendMessage(chatID, "🤷 Nobody is cooking right now. Start a dinner poll with /dinner.")
+		return
+	}
+	if err != nil {
+		a.log.Error("Failed to get current dinner: %v", err)
+		a.bot.SendMessage(chatID, "😢 Sorry, I couldn't find tonight's recipe right now. Please try again later.")
+		return
+	}
+
+	if len(current.Dish.Ingredients) == 0 && len(current.Dish.Instructions) == 0 {
+		a.bot.SendMessage(chatID, fmt.Sprintf("😢 I don't have a recipe for %s, you're on your own for this one!", messages.Bold(current.Dish.Name)))
+		return
+	}
+	if timers := stepTimerRows(current.ID, current.Dish); len(timers) > 0 {
+		a.bot.SendMessageWithKeyboard(chatID, a.cookingInstructions(chatID, current.Dish), tgbotapi.NewInlineKeyboardMarkup(timers...))
+		return
+	}
+	a.bot.SendMessage(chatID, a.cookingInstructions(chatID, current.Dish))
+}
+
+// handleExportRecipe handles the /export_recipe command
+func (a *app) handleExportRecipe(message *tgbotapi.Message) {
+	chatID := message.Chat.ID
+
+	dishName := strings.TrimSpace(message.CommandArguments())
+	if dishName == "" {
+		a.bot.SendMessage(chatID, "📄 Please tell me which dish you'd like the recipe for. For example: /export_recipe Borscht")
+		return
+	}
+
+	// Prefer the recipe we cooked before, so the export matches what the family knows
+	dish, found := a.dinnerService.FindDish(chatID, dishName)
+	if !found {
+		dishInfo, err := a.openaiClient.WithChannel(chatID).GetDishInfo(dishName)
+		if err != nil {
+			a.log.Error("Failed to get dish info: %v", err)
+			a.bot.SendMessage(chatID, fmt.Sprintf("😢 Sorry, I couldn't find a recipe for '%s'. Please try again with a different dish.", messages.EscapeMarkdown(dishName)))
+			return
+		}
+		dish = dinner.DishFromInfo(dishInfo, dishName)
+	}
+
+	if len(dish.Ingredients) == 0 && len(dish.Instructions) == 0 {
+		a.bot.SendMessage(chatID, fmt.Sprintf("😢 Sorry, I couldn't find a recipe for '%s'. Please try again with a different dish.", messages.EscapeMarkdown(dishName)))
+		return
+	}
+
+	recipe := formatRecipeMarkdown(dish)
+	_, err := a.bot.SendDocument(chatID, recipeFilename(dish.Name), []byte(recipe), fmt.Sprintf("📄 Recipe for %s", dish.Name))
+	if err != nil {
+		a.log.Error("Failed to send recipe document: %v", err)
+		// Fall back to plain text, so the recipe still gets through
+		a.bot.SendMessage(chatID, recipe)
+	}
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/korjavin/whatsfordinner/pkg/models"
+)
+
+func TestCookingRepostsTheCurrentRecipe(t *testing.T) {
+	ta := newTestApp(t)
+	anna := testUser(1, "Anna")
+
+	ta.handleCooking(command(anna, "/cooking"))
+	if reply := ta.telegram.LastText(); !strings.Contains(reply, "Nobody is cooking right now") {
+		t.Errorf("/cooking without a dinner replied %q, want nobody cooking", reply)
+	}
+
+	dish := models.Dish{
+		Name:         "Carbonara",
+		Cuisine:      "Italian",
+		Ingredients:  []string{"200g spaghetti", "2 eggs"},
+		Instructions: []string{"Boil the pasta", "Stir in the eggs"},
+	}
+	if _, err := ta.dinnerService.CreateDinner(testChatID, dish, "1"); err != nil {
+		t.Fatalf("CreateDinner failed: %v", err)
+	}
+
+	ta.handleCooking(command(anna, "/cooking"))
+	reply := ta.telegram.LastText()
+	for _, want := range []string{"Carbonara", "spaghetti", "2 eggs", "Boil the pasta", "Stir in the eggs"} {
+		if !strings.Contains(reply, want) {
+			t.Errorf("/cooking replied %q, want it to contain %q", reply, want)
+		}
+	}
+	if strings.Index(reply, "Boil the pasta") > strings.Index(reply, "Stir in the eggs") {
+		t.Errorf("/cooking replied %q, want the steps in order", reply)
+	}
+}
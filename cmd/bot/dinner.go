@@ -0,0 +1,444 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/korjavin/whatsfordinner/pkg/dinner"
+	"github.com/korjavin/whatsfordinner/pkg/messages"
+	"github.com/korjavin/whatsfordinner/pkg/models"
+	"github.com/korjavin/whatsfordinner/pkg/openai"
+	"github.com/korjavin/whatsfordinner/pkg/poll"
+)
+
+// startDinner runs the dinner suggestion flow and starts a poll
+// With tags, only dishes that have all of them are suggested
+func (a *app) startDinner(chatID int64, tags []string) {
+	// Get ingredients from the fridge
+	ingredients, err := a.fridgeService.ListIngredients(chatID)
+	if err != nil {
+		a.log.Error("Failed to list ingredients: %v", err)
+		errorMsg := a.messageService.GenerateErrorMessage("retrieve fridge contents")
+		a.bot.SendMessage(chatID, errorMsg)
+		return
+	}
+
+	if len(ingredients) == 0 {
+		a.bot.SendMessage(chatID, "😢 Your fridge is empty! Please add some ingredients with /sync_fridge or /add_photo before I can suggest dinner options.")
+		return
+	}
+
+	// Extract ingredient names
+	ingredientNames := make([]string, len(ingredients))
+	for i, ingredient := range ingredients {
+		ingredientNames[i] = ingredient.Name
+	}
+
+	// Send a processing message
+	processingMsg, _ := a.bot.SendMessage(chatID, "🧐 Thinking about dinner options based on your ingredients... This might take a moment.")
+
+	// Get user suggestions
+	// They have no tags, so a tagged poll leaves them for the next one
+	userSuggestions, err := a.suggestService.GetUnusedSuggestions(chatID)
+	if err != nil {
+		a.log.Error("Failed to get user suggestions: %v", err)
+		// Continue without user suggestions
+		userSuggestions = []*models.SuggestedDish{}
+	}
+	if len(tags) > 0 {
+		userSuggestions = []*models.SuggestedDish{}
+	}
+
+	// Determine how many AI suggestions to get
+	aiSuggestionCount := 4
+	if len(userSuggestions) > 0 {
+		// If we have user suggestions, get fewer AI suggestions
+		aiSuggestionCount = 4 - len(userSuggestions)
+		if aiSuggestionCount < 2 {
+			aiSuggestionCount = 2 // Always get at least 2 AI suggestions
+		}
+	}
+
+	// Dishes from re-rolled polls shouldn't come back
+	rejected := a.pollService.RejectedDishes(chatID)
+	cuisines := a.dinnerService.GetCuisines(chatID, a.cfg.Cuisines)
+
+	// Get dinner suggestions from OpenAI
+	offline := false
+	filter := openai.SuggestionFilter{Exclude: rejected, Tags: tags}
+	aiSuggestions, err := a.openaiClient.WithChannel(chatID).SuggestFilteredDinnerOptions(ingredientNames, cuisines, aiSuggestionCount, filter)
+	if err != nil {
+		a.log.Error("Failed to get dinner suggestions: %v", err)
+
+		// Fall back to scoring stored dishes against the fridge
+		offlineSuggestions, offlineErr := a.dinnerService.OfflineSuggestions(chatID, cuisines, tags, aiSuggestionCount+len(rejected))
+		offlineSuggestions = withoutRejected(offlineSuggestions, rejected)
+		if len(offlineSuggestions) > aiSuggestionCount {
+			offlineSuggestions = offlineSuggestions[:aiSuggestionCount]
+		}
+		if offlineErr != nil {
+			a.log.Error("Failed to get offline suggestions: %v", offlineErr)
+		} else if len(offlineSuggestions) > 0 {
+			a.log.Info("Using %d offline suggestions", len(offlineSuggestions))
+			aiSuggestions = offlineSuggestions
+			offline = true
+			err = nil
+		}
+	}
+	if err != nil {
+
+		// If we have user suggestions, continue with those
+		if len(userSuggestions) == 0 {
+			if errors.Is(err, openai.ErrUnavailable) {
+				a.bot.EditMessage(chatID, processingMsg.MessageID, "🤖 The AI is temporarily unavailable. Please try again in a few minutes, or suggest your own dishes with /suggest.")
+				return
+			}
+			a.bot.EditMessage(chatID, processingMsg.MessageID, "😢 Sorry, I couldn't come up with dinner suggestions right now. Please try again later.")
+			return
+		}
+
+		// Continue with just user suggestions
+		aiSuggestions = []map[string]interface{}{}
+	}
+
+	// The AI doesn't always listen, so drop rejected dishes it suggested anyway
+	aiSuggestions = withoutRejected(aiSuggestions, rejected)
+
+	// Combine AI and user suggestions
+	if len(aiSuggestions) == 0 && len(userSuggestions) == 0 {
+		a.bot.EditMessage(chatID, processingMsg.MessageID, "😢 I couldn't find any suitable dishes based on your fridge contents. Try adding more ingredients with /fridge or suggest your own dishes with /suggest.")
+		return
+	}
+
+	// Calculate total number of suggestions
+	totalSuggestions := len(aiSuggestions) + len(userSuggestions)
+
+	// Create options for the poll
+	options := make([]string, totalSuggestions)
+	dishNames := make([]string, totalSuggestions)
+	cards := make([]string, totalSuggestions)
+
+	// Create a detailed message with suggestions
+	detailedMsg := "🍲 Here are some dinner suggestions based on your ingredients:\n\n"
+	if offline {
+		detailedMsg = "📴 The AI is unavailable right now, so here are some offline suggestions from your saved dishes:\n\n"
+	}
+	if len(tags) > 0 {
+		detailedMsg = fmt.Sprintf("🏷 Only dishes tagged%s\n\n", formatTags(tags)) + detailedMsg
+	}
+	intro := strings.TrimSpace(detailedMsg)
+
+	// Note when dishes were last cooked, to help the family decide
+	history, err := a.dinnerService.DishHistory(chatID)
+	if err != nil {
+		a.log.Error("Failed to get dish history: %v", err)
+	}
+	now := time.Now().In(a.channelLocation(chatID))
+	lastCooked := func(name string) string {
+		if note := history.LastCookedNote(name, a.cfg.RatingScale, now); note != "" {
+			return " · " + note
+		}
+		return ""
+	}
+
+	// Add user suggestions first
+	for i, suggestion := range userSuggestions {
+		options[i] = suggestion.Name
+		dishNames[i] = fmt.Sprintf("%s (%s) - suggested by @%s", suggestion.Name, suggestion.Cuisine, suggestion.Username)
+
+		cards[i] = fmt.Sprintf("🍴 %s (%s)%s\n%s\nSuggested by @%s", messages.Bold(suggestion.Name), messages.EscapeMarkdown(suggestion.Cuisine), lastCooked(suggestion.Name), messages.EscapeMarkdown(suggestion.Description), messages.EscapeMarkdown(suggestion.Username))
+		detailedMsg += cards[i] + "\n\n"
+
+		// Mark the suggestion as used
+		err := a.suggestService.MarkAsUsed(suggestion.ID)
+		if err != nil {
+			a.log.Error("Failed to mark suggestion as used: %v", err)
+		}
+	}
+
+	// Add AI suggestions
+	for i, suggestion := range aiSuggestions {
+		name, _ := suggestion["name"].(string)
+		cuisine, _ := suggestion["cuisine"].(string)
+		description, _ := suggestion["description"].(string)
+
+		// Add to options at the correct index (after user suggestions)
+		index := len(userSuggestions) + i
+		options[index] = name
+		dishNames[index] = fmt.Sprintf("%s (%s)", name, cuisine)
+
+		cards[index] = fmt.Sprintf("🍴 %s (%s)%s%s\n%s", messages.Bold(name), messages.EscapeMarkdown(cuisine), formatTags(suggestionTags(suggestion)), lastCooked(name), messages.EscapeMarkdown(description))
+		detailedMsg += cards[index] + "\n\n"
+	}
+
+	// With vote cards every dish gets its own message and vote button instead
+	if a.pollService.VoteCards(chatID) {
+		a.bot.EditMessage(chatID, processingMsg.MessageID, intro)
+
+		pollID := poll.NewCardPollID(time.Now())
+		header, cardIDs, err := a.bot.CreateVoteCards(chatID, a.pollService.GetPollQuestion(chatID), pollID, cards)
+		if err != nil {
+			a.log.Error("Failed to create vote cards: %v", err)
+			errorMsg := a.messageService.GenerateErrorMessage("create poll")
+			a.bot.SendMessage(chatID, errorMsg)
+			return
+		}
+		a.log.Info("Created card vote %s for channel %d", pollID, chatID)
+
+		_, err = a.pollService.CreateVote(chatID, pollID, header.MessageID, options)
+		if err != nil {
+			a.log.Error("Failed to create vote state: %v", err)
+		} else {
+			if err := a.pollService.SetCardMessages(chatID, pollID, cardIDs); err != nil {
+				a.log.Error("Failed to save vote cards: %v", err)
+			}
+			if len(tags) > 0 {
+				if err := a.pollService.SetVoteTags(chatID, pollID, tags); err != nil {
+					a.log.Error("Failed to save vote tags: %v", err)
+				}
+			}
+		}
+
+		if left := a.pollService.RerollsLeft(chatID); left > 0 {
+			a.bot.SendMessageWithKeyboard(chatID, "🗳 Tap the vote button of your preferred dish above! Don't like any of them? Ask for new suggestions below.", rerollKeyboard(pollID, left))
+		} else {
+			a.bot.SendMessage(chatID, "🗳 Tap the vote button of your preferred dish above!")
+		}
+		return
+	}
+
+	// Edit the processing message to show the detailed suggestions
+	a.bot.EditMessage(chatID, processingMsg.MessageID, detailedMsg)
+
+	// Create poll
+	pollMsg, err := a.bot.CreatePoll(chatID, a.pollService.GetPollQuestion(chatID), options)
+	if err != nil {
+		a.log.Error("Failed to create poll: %v", err)
+		errorMsg := a.messageService.GenerateErrorMessage("create poll")
+		a.bot.SendMessage(chatID, errorMsg)
+		return
+	}
+
+	// Log the poll object to understand its structure
+	a.log.Info("Poll message: %+v", pollMsg)
+	a.log.Info("Poll object: %+v", pollMsg.Poll)
+
+	// In Telegram, the poll ID we receive in poll answers is different from the poll.ID
+	// We need to store the actual poll ID that will be used in poll answers
+	// For now, we'll use the poll ID directly from the message
+	pollID := pollMsg.Poll.ID
+	a.log.Info("Created poll with ID %s for channel %d", pollID, chatID)
+
+	// Store vote state - use the same poll ID for consistency
+	_, err = a.pollService.CreateVote(chatID, pollID, pollMsg.MessageID, options)
+	if err != nil {
+		a.log.Error("Failed to create vote state: %v", err)
+	} else if len(tags) > 0 {
+		if err := a.pollService.SetVoteTags(chatID, pollID, tags); err != nil {
+			a.log.Error("Failed to save vote tags: %v", err)
+		}
+	}
+
+	// Send a message with voting instructions, offering new suggestions while re-rolls are left
+	if left := a.pollService.RerollsLeft(chatID); left > 0 {
+		a.bot.SendMessageWithKeyboard(chatID, "🗳 Please vote for your preferred dinner option! The poll is above. Don't like any of them? Ask for new suggestions below.", rerollKeyboard(pollID, left))
+	} else {
+		a.bot.SendMessage(chatID, "🗳 Please vote for your preferred dinner option! The poll is above.")
+	}
+}
+
+// handleDinner handles the /dinner command
+func (a *app) handleDinner(message *tgbotapi.Message) {
+	chatID := message.Chat.ID
+	tags, rest := dinner.SplitTags(message.CommandArguments())
+
+	// Starting another dinner overwrites the current poll, so ask first unless explicitly told to
+	override := strings.EqualFold(rest, "again")
+	if !override && a.schedulerService.HasDinnerStartedToday(chatID) {
+		// Keep the tags until the answer comes in
+		a.stateManager.SetData(chatID, "pending_dinner_tags", strings.Join(tags, ","))
+
+		keyboard := tgbotapi.NewInlineKeyboardMarkup(
+			tgbotapi.NewInlineKeyboardRow(
+				tgbotapi.NewInlineKeyboardButtonData("Start a new poll", "dinner_anyway"),
+				tgbotapi.NewInlineKeyboardButtonData("Keep the current one", "dinner_keep"),
+			),
+		)
+		a.bot.SendMessageWithKeyboard(chatID, "🤔 A dinner poll was already started today. Starting a new one replaces it. Do you want to start a new poll anyway?", keyboard)
+		return
+	}
+
+	// A fresh dinner may suggest dishes that were re-rolled away before
+	if err := a.pollService.ClearRejections(chatID); err != nil {
+		a.log.Error("Failed to clear rejected dishes: %v", err)
+	}
+	a.startDinner(chatID, tags)
+}
+
+// handleDinnerAnywayCallback handles the button that starts another dinner poll on the same day
+func (a *app) handleDinnerAnywayCallback(callback *tgbotapi.CallbackQuery, _ string) {
+	chatID := callback.Message.Chat.ID
+
+	a.bot.AnswerCallbackQuery(callback.ID, "Starting a new dinner poll!")
+
+	// Edit the message to remove the buttons
+	a.bot.EditMessageWithKeyboard(chatID, callback.Message.MessageID, "🔄 Starting a new dinner poll...", tgbotapi.InlineKeyboardMarkup{})
+
+	// Pick up the tags /dinner was called with
+	var tags []string
+	if pendingTags, ok := a.stateManager.GetData(chatID, "pending_dinner_tags"); ok {
+		a.stateManager.ClearData(chatID, "pending_dinner_tags")
+		tags = dinner.NormalizeTags(strings.Split(pendingTags, ","))
+	}
+
+	if err := a.pollService.ClearRejections(chatID); err != nil {
+		a.log.Error("Failed to clear rejected dishes: %v", err)
+	}
+	a.startDinner(chatID, tags)
+}
+
+// handleDinnerKeepCallback handles the button that keeps today's dinner poll
+func (a *app) handleDinnerKeepCallback(callback *tgbotapi.CallbackQuery, _ string) {
+	chatID := callback.Message.Chat.ID
+	a.stateManager.ClearData(chatID, "pending_dinner_tags")
+
+	a.bot.AnswerCallbackQuery(callback.ID, "Keeping today's dinner.")
+
+	// Edit the message to remove the buttons
+	a.bot.EditMessageWithKeyboard(chatID, callback.Message.MessageID, "👍 OK, today's dinner stays as it is.", tgbotapi.InlineKeyboardMarkup{})
+}
+
+// handleSurprise handles the /surprise command
+func (a *app) handleSurprise(message *tgbotapi.Message) {
+	chatID := message.Chat.ID
+
+	if currentVote, err := a.pollService.GetCurrentVote(chatID); err == nil && currentVote.EndedAt.IsZero() {
+		a.bot.SendMessage(chatID, "🗳️ There's already a dinner poll running. Let's finish that one first!")
+		return
+	}
+
+	ingredients, err := a.fridgeService.ListIngredients(chatID)
+	if err != nil {
+		a.log.Error("Failed to list ingredients: %v", err)
+		a.bot.SendMessage(chatID, a.messageService.GenerateErrorMessage("retrieve fridge contents"))
+		return
+	}
+
+	ingredientNames := make([]string, len(ingredients))
+	for i, ingredient := range ingredients {
+		ingredientNames[i] = ingredient.Name
+	}
+
+	processingMsg, _ := a.bot.SendMessage(chatID, "🎲 Picking a surprise dinner for you...")
+
+	// Let the AI pick, falling back to the saved dishes
+	cuisines := a.dinnerService.GetCuisines(chatID, a.cfg.Cuisines)
+	suggestions, err := a.openaiClient.WithChannel(chatID).SuggestDinnerOptions(ingredientNames, cuisines, 1)
+	if err != nil {
+		a.log.Error("Failed to get dinner suggestions: %v", err)
+		suggestions, err = a.dinnerService.OfflineSuggestions(chatID, cuisines, nil, 1)
+		if err != nil {
+			a.log.Error("Failed to get offline suggestions: %v", err)
+		}
+	}
+
+	var dishName, description string
+	if len(suggestions) > 0 {
+		dishName, _ = suggestions[0]["name"].(string)
+		description, _ = suggestions[0]["description"].(string)
+	}
+	if dishName == "" {
+		a.bot.EditMessage(chatID, processingMsg.MessageID, "😢 Sorry, I couldn't come up with a surprise right now. Try /dinner to vote instead.")
+		return
+	}
+
+	vote, err := a.pollService.CreateSurpriseVote(chatID, dishName)
+	if err != nil {
+		a.log.Error("Failed to create surprise vote: %v", err)
+		a.bot.EditMessage(chatID, processingMsg.MessageID, "😢 Sorry, I couldn't set up the surprise dinner. Please try again later.")
+		return
+	}
+
+	a.bot.EditMessage(chatID, processingMsg.MessageID, fmt.Sprintf("🎉 Surprise! Tonight's dinner is %s.\n\n%s", messages.Bold(dishName), messages.EscapeMarkdown(description)))
+
+	// Go straight to asking for cook volunteers
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("I'll cook!", fmt.Sprintf("volunteer:%s", vote.PollID)),
+		),
+	)
+	a.bot.SendMessageWithKeyboard(chatID, fmt.Sprintf("Who wants to cook %s tonight? Press the button below to volunteer!", messages.Bold(dishName)), keyboard)
+}
+
+// handleCancook handles the /cancook command
+func (a *app) handleCancook(message *tgbotapi.Message) {
+	chatID := message.Chat.ID
+
+	matches, err := a.dinnerService.CanCook(chatID, dinner.CanCookThreshold)
+	if err != nil {
+		a.log.Error("Failed to find cookable dishes: %v", err)
+		a.bot.SendMessage(chatID, "😢 Sorry, I couldn't check what you can cook right now. Please try again later.")
+		return
+	}
+
+	if len(matches) == 0 {
+		a.bot.SendMessage(chatID, "🤷 I don't know any dish you have most of the ingredients for. Try /dinner for some fresh ideas!")
+		return
+	}
+
+	msgText := "👩‍🍳 Here's what you can make right now:\n\n"
+	for _, match := range matches {
+		if len(match.Missing) == 0 {
+			msgText += fmt.Sprintf("✅ %s – you have everything\n", messages.EscapeMarkdown(match.Dish.Name))
+			continue
+		}
+		msgText += fmt.Sprintf("🟡 %s – %.0f%%, missing %s\n", messages.EscapeMarkdown(match.Dish.Name), match.Score*100, messages.EscapeMarkdown(strings.Join(match.Missing, ", ")))
+	}
+
+	a.bot.SendMessage(chatID, msgText)
+}
+
+// handleDinnerInfo handles the /dinner_info command
+func (a *app) handleDinnerInfo(message *tgbotapi.Message) {
+	chatID := message.Chat.ID
+	dinnerService := dinner.New(a.store, a.fridgeService, a.openaiClient)
+	loc := a.channelLocation(chatID)
+
+	dinners, err := dinnerService.FindDinners(chatID, message.CommandArguments(), loc)
+	if err != nil {
+		a.log.Error("Failed to find dinners: %v", err)
+		a.bot.SendMessage(chatID, "😢 Sorry, I couldn't look up your dinners right now. Please try again later.")
+		return
+	}
+
+	switch len(dinners) {
+	case 0:
+		a.bot.SendMessage(chatID, "🤔 I couldn't find that dinner. Use /dinner_info last, a date like /dinner_info 2024-06-01 or a dinner ID.")
+
+	case 1:
+		// Collect the names we know from the leaderboards
+		names := make(map[string]string)
+		if stats, err := a.statsService.GetStatistics(chatID); err == nil {
+			for userID, stat := range stats.CookStats {
+				names[userID] = stat.Username
+			}
+			for userID, stat := range stats.HelperStats {
+				names[userID] = stat.Username
+			}
+			for userID, stat := range stats.SuggesterStats {
+				names[userID] = stat.Username
+			}
+		}
+		a.bot.SendMessage(chatID, formatDinnerInfo(dinners[0], names, loc))
+
+	default:
+		msgText := "📅 There were several dinners that day. Which one did you mean?\n\n"
+		for _, d := range dinners {
+			msgText += fmt.Sprintf("• %s on %s: /dinner_info %s\n", messages.EscapeMarkdown(d.Dish.Name), messages.FormatTime(d.StartedAt, loc), shortDinnerID(d))
+		}
+		a.bot.SendMessage(chatID, msgText)
+	}
+}
@@ -0,0 +1,237 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/korjavin/whatsfordinner/pkg/models"
+)
+
+// saveDishes stores dishes the offline suggester can pick from
+func saveDishes(t *testing.T, ta *testApp, dishes ...models.Dish) {
+	t.Helper()
+
+	for _, dish := range dishes {
+		if err := ta.store.Set(fmt.Sprintf("dish:%s:%s", dish.Cuisine, dish.Name), dish); err != nil {
+			t.Fatalf("failed to save dish: %v", err)
+		}
+	}
+}
+
+// stockFridge adds ingredients to the test chat's fridge
+func stockFridge(t *testing.T, ta *testApp, names ...string) {
+	t.Helper()
+
+	for _, name := range names {
+		if err := ta.fridgeService.AddIngredient(testChatID, name, ""); err != nil {
+			t.Fatalf("AddIngredient failed: %v", err)
+		}
+	}
+}
+
+func TestDinnerFallsBackToOfflineSuggestions(t *testing.T) {
+	ta := newTestApp(t)
+	saveDishes(t, ta,
+		models.Dish{Name: "Carbonara", Cuisine: "Italian", Ingredients: []string{"pasta", "eggs"}},
+		models.Dish{Name: "Risotto", Cuisine: "Italian", Ingredients: []string{"rice", "parmesan"}},
+	)
+	stockFridge(t, ta, "pasta", "eggs")
+	ta.openai.SetFailing(true)
+
+	ta.startDinner(testChatID, nil)
+
+	var offline string
+	for _, text := range ta.telegram.Texts() {
+		if strings.Contains(text, "offline suggestions") {
+			offline = text
+		}
+	}
+	if !strings.Contains(offline, "Carbonara") || !strings.Contains(offline, "Risotto") {
+		t.Fatalf("didn't label the stored dishes as offline suggestions, sent %q", ta.telegram.Texts())
+	}
+
+	polls := ta.telegram.Calls("sendPoll")
+	if len(polls) != 1 {
+		t.Fatalf("sent %d polls, want 1", len(polls))
+	}
+	if options := polls[0].Params.Get("options"); !strings.Contains(options, "Carbonara") {
+		t.Errorf("poll options = %s, want the offline suggestions", options)
+	}
+}
+
+func TestDinnerWithoutAIOrStoredDishesGivesUp(t *testing.T) {
+	ta := newTestApp(t)
+	stockFridge(t, ta, "pasta")
+	ta.openai.SetFailing(true)
+
+	ta.startDinner(testChatID, nil)
+
+	if polls := ta.telegram.Calls("sendPoll"); len(polls) != 0 {
+		t.Errorf("sent %d polls without any suggestions, want none", len(polls))
+	}
+	if text := ta.telegram.LastText(); !strings.Contains(text, "Sorry") {
+		t.Errorf("last message = %q, want an apology", text)
+	}
+}
+
+func TestSurpriseGoesStraightToTheCook(t *testing.T) {
+	ta := newTestApp(t)
+	saveDishes(t, ta, models.Dish{Name: "Carbonara", Cuisine: "Italian", Ingredients: []string{"pasta", "eggs"}})
+	ta.openai.SetFailing(true)
+
+	ta.handleSurprise(command(testUser(1, "Anna"), "/surprise"))
+
+	if polls := ta.telegram.Calls("sendPoll"); len(polls) != 0 {
+		t.Fatalf("sent %d polls for a surprise dinner, want none", len(polls))
+	}
+	vote, err := ta.pollService.GetLastVote(testChatID)
+	if err != nil {
+		t.Fatalf("GetLastVote failed: %v", err)
+	}
+	if vote.WinningDish != "Carbonara" || vote.EndedAt.IsZero() || len(vote.Options) != 1 {
+		t.Fatalf("surprise vote = %+v, want an ended vote Carbonara won", *vote)
+	}
+	var button string
+	for _, call := range ta.telegram.Calls("sendMessage") {
+		if markup := call.Params.Get("reply_markup"); strings.Contains(markup, "volunteer:") {
+			button = markup
+		}
+	}
+	if !strings.Contains(button, "volunteer:"+vote.PollID) {
+		t.Fatalf("no volunteer button for %s, sent %q", vote.PollID, ta.telegram.Texts())
+	}
+
+	// Volunteering works like after a poll
+	ta.openai.SetFailing(false)
+	ta.openai.SetReplies(`{"name": "Carbonara", "cuisine": "Italian", "ingredients": ["pasta", "eggs"], "instructions": ["Boil the pasta"]}`)
+	press(ta.handleVolunteerCallback, callback(testUser(2, "Ben"), 5, "volunteer:"+vote.PollID))
+
+	vote, err = ta.pollService.GetVote(testChatID, vote.PollID)
+	if err != nil {
+		t.Fatalf("GetVote failed: %v", err)
+	}
+	if vote.SelectedCook != "2" {
+		t.Errorf("cook = %q, want Ben", vote.SelectedCook)
+	}
+	var channelState models.ChannelState
+	if err := ta.store.Get(fmt.Sprintf("channel:%d", testChatID), &channelState); err != nil {
+		t.Fatalf("failed to get channel: %v", err)
+	}
+	if channelState.CurrentDinner == nil || channelState.CurrentDinner.Dish.Name != "Carbonara" || channelState.CurrentDinner.Cook != "2" {
+		t.Errorf("current dinner = %+v, want Ben cooking Carbonara", channelState.CurrentDinner)
+	}
+}
+
+func TestSecondDinnerOnTheSameDayAsksFirst(t *testing.T) {
+	ta := newTestApp(t)
+	saveDishes(t, ta, models.Dish{Name: "Carbonara", Cuisine: "Italian", Ingredients: []string{"pasta", "eggs"}})
+	stockFridge(t, ta, "pasta", "eggs")
+	ta.openai.SetFailing(true)
+	if _, err := ta.pollService.CreateVote(testChatID, "earlier", 1, []string{"Soup", "Curry"}); err != nil {
+		t.Fatalf("CreateVote failed: %v", err)
+	}
+	anna := testUser(1, "Anna")
+
+	ta.handleDinner(command(anna, "/dinner"))
+	if polls := ta.telegram.Calls("sendPoll"); len(polls) != 0 {
+		t.Fatalf("started %d polls without asking, want none", len(polls))
+	}
+	if markup := ta.telegram.Calls("sendMessage")[0].Params.Get("reply_markup"); !strings.Contains(markup, "dinner_anyway") {
+		t.Fatalf("warning has no button to start anyway, markup %s", markup)
+	}
+
+	// The button starts the poll anyway
+	press(ta.handleDinnerAnywayCallback, callback(anna, 2, "dinner_anyway"))
+	if polls := ta.telegram.Calls("sendPoll"); len(polls) != 1 {
+		t.Fatalf("started %d polls after confirming, want 1", len(polls))
+	}
+}
+
+func TestDinnerAgainSkipsTheWarning(t *testing.T) {
+	ta := newTestApp(t)
+	saveDishes(t, ta, models.Dish{Name: "Carbonara", Cuisine: "Italian", Ingredients: []string{"pasta", "eggs"}})
+	stockFridge(t, ta, "pasta", "eggs")
+	ta.openai.SetFailing(true)
+	if _, err := ta.pollService.CreateVote(testChatID, "earlier", 1, []string{"Soup", "Curry"}); err != nil {
+		t.Fatalf("CreateVote failed: %v", err)
+	}
+
+	ta.handleDinner(command(testUser(1, "Anna"), "/dinner again"))
+
+	if polls := ta.telegram.Calls("sendPoll"); len(polls) != 1 {
+		t.Errorf("started %d polls with /dinner again, want 1", len(polls))
+	}
+	for _, text := range ta.telegram.Texts() {
+		if strings.Contains(text, "already started today") {
+			t.Errorf("warned about today's poll although told to start again: %q", text)
+		}
+	}
+}
+
+func TestDinnerPromptAlwaysHasACuisine(t *testing.T) {
+	ta := newTestApp(t)
+	ta.cfg.Cuisines = nil
+	stockFridge(t, ta, "pasta")
+	ta.openai.SetReplies(`[{"name": "Pasta", "cuisine": "Italian", "description": "Quick"}, {"name": "Soup", "cuisine": "French", "description": "Warm"}]`)
+
+	ta.startDinner(testChatID, nil)
+
+	var prompt string
+	for _, p := range ta.openai.Prompts() {
+		if strings.Contains(p, "Preferred cuisines:") {
+			prompt = p
+		}
+	}
+	if !strings.Contains(prompt, "Preferred cuisines: any\n") {
+		t.Errorf("the suggestion prompt has no cuisine: %q", prompt)
+	}
+}
+
+func TestDinnerTagReachesThePrompt(t *testing.T) {
+	ta := newTestApp(t)
+	stockFridge(t, ta, "pasta")
+	ta.openai.SetReplies(`[{"name": "Pasta", "cuisine": "Italian", "description": "Quick", "tags": ["quick"]}, {"name": "Salad", "cuisine": "Greek", "description": "Fresh", "tags": ["quick"]}]`)
+
+	ta.handleDinner(command(testUser(1, "Anna"), "/dinner #Quick"))
+
+	var prompt string
+	for _, p := range ta.openai.Prompts() {
+		if strings.Contains(p, "Preferred cuisines:") {
+			prompt = p
+		}
+	}
+	if !strings.Contains(prompt, "Every dish must be: quick\n") {
+		t.Errorf("the suggestion prompt doesn't ask for quick dishes: %q", prompt)
+	}
+	if polls := ta.telegram.Calls("sendPoll"); len(polls) != 1 {
+		t.Errorf("sent %d polls, want 1", len(polls))
+	}
+}
+
+func TestSuggestionsShowWhenDishesWereLastCooked(t *testing.T) {
+	ta := newTestApp(t)
+	stockFridge(t, ta, "pasta")
+	cooked := time.Now().AddDate(0, 0, -12)
+	id := fmt.Sprintf("dinner:%d:%d", testChatID, cooked.UnixNano())
+	if err := ta.store.Set(id, models.Dinner{ID: id, ChannelID: testChatID, Dish: models.Dish{Name: "Pasta"}, StartedAt: cooked, Ratings: map[string]int{"1": 4}}); err != nil {
+		t.Fatalf("failed to save dinner: %v", err)
+	}
+	ta.openai.SetReplies(`[{"name": "Pasta", "cuisine": "Italian", "description": "Quick"}, {"name": "Soup", "cuisine": "French", "description": "Warm"}]`)
+
+	ta.startDinner(testChatID, nil)
+
+	var detailed string
+	for _, text := range ta.telegram.Texts() {
+		if strings.Contains(text, "Soup") && strings.Contains(text, "Pasta") {
+			detailed = text
+		}
+	}
+	if !strings.Contains(detailed, "(Italian) · last cooked 12 days ago, rated 4.0★\n") {
+		t.Errorf("Pasta isn't annotated with its last dinner in %q", detailed)
+	}
+	if strings.Count(detailed, "last cooked") != 1 {
+		t.Errorf("annotated more than Pasta in %q, want Soup without history left alone", detailed)
+	}
+}
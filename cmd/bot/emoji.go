@@ -0,0 +1,36 @@
+package main
+
+import (
+	"strings"
+)
+
+// defaultIngredientEmoji is shown before ingredients we don't have an emoji for
+const defaultIngredientEmoji = "🍽️"
+
+// ingredientEmojis maps words found in ingredient names to an emoji
+var ingredientEmojis = map[string]string{
+	"egg": "🥚", "eggs": "🥚", "milk": "🥛", "cheese": "🧀", "butter": "🧈",
+	"tomato": "🍅", "tomatoes": "🍅", "potato": "🥔", "potatoes": "🥔", "carrot": "🥕", "carrots": "🥕",
+	"onion": "🧅", "onions": "🧅", "garlic": "🧄", "cucumber": "🥒", "broccoli": "🥦", "lettuce": "🥬",
+	"spinach": "🥬", "cabbage": "🥬", "mushroom": "🍄", "mushrooms": "🍄", "corn": "🌽", "eggplant": "🍆",
+	"pepper": "🌶️", "peppers": "🫑", "avocado": "🥑", "beans": "🫘", "peas": "🫛",
+	"apple": "🍎", "apples": "🍎", "banana": "🍌", "bananas": "🍌", "lemon": "🍋", "lemons": "🍋",
+	"orange": "🍊", "oranges": "🍊", "pear": "🍐", "pears": "🍐", "grapes": "🍇", "strawberries": "🍓",
+	"chicken": "🍗", "beef": "🥩", "steak": "🥩", "pork": "🥩", "lamb": "🥩", "bacon": "🥓",
+	"sausage": "🌭", "sausages": "🌭", "fish": "🐟", "salmon": "🐟", "tuna": "🐟", "cod": "🐟",
+	"shrimp": "🦐", "prawns": "🦐",
+	"rice": "🍚", "pasta": "🍝", "spaghetti": "🍝", "noodles": "🍜", "bread": "🍞", "tortillas": "🫓",
+	"salt": "🧂", "honey": "🍯", "oil": "🫒",
+}
+
+// ingredientEmoji returns an emoji for an ingredient, or a plate for ingredients we don't know.
+// Like fridge.Categorize, the last known word wins, so "chicken stock" gets the chicken.
+func ingredientEmoji(name string) string {
+	emoji := defaultIngredientEmoji
+	for _, word := range strings.Fields(strings.ToLower(name)) {
+		if e, ok := ingredientEmojis[strings.Trim(word, ",.()")]; ok {
+			emoji = e
+		}
+	}
+	return emoji
+}
@@ -0,0 +1,23 @@
+package main
+
+import "testing"
+
+func TestIngredientEmoji(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"eggs", "🥚"},
+		{"Milk", "🥛"},
+		{"cherry tomatoes", "🍅"},
+		{"chicken stock", "🍗"},
+		{"potatoes (large)", "🥔"},
+		{"tofu", defaultIngredientEmoji},
+		{"", defaultIngredientEmoji},
+	}
+	for _, tt := range tests {
+		if got := ingredientEmoji(tt.name); got != tt.want {
+			t.Errorf("ingredientEmoji(%q) = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,343 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"github.com/korjavin/whatsfordinner/pkg/dinner"
+	"github.com/korjavin/whatsfordinner/pkg/fridge"
+	"github.com/korjavin/whatsfordinner/pkg/messages"
+	"github.com/korjavin/whatsfordinner/pkg/models"
+	"github.com/korjavin/whatsfordinner/pkg/openai"
+	"github.com/korjavin/whatsfordinner/pkg/state"
+)
+
+// formatIngredientList formats the fridge contents under the given header,
+// grouped by category and sorted by name, with pantry staples listed separately.
+// If normalize is set, quantities are converted to metric units where sensible.
+func formatIngredientList(header string, ingredients []models.Ingredient, normalize bool) string {
+	sort.Slice(ingredients, func(i, j int) bool {
+		ci, cj := fridge.CategoryOrder(ingredients[i].Category), fridge.CategoryOrder(ingredients[j].Category)
+		if ci != cj {
+			return ci < cj
+		}
+		return ingredients[i].Name < ingredients[j].Name
+	})
+
+	var fridgeItems, pantryItems []models.Ingredient
+	for _, ingredient := range ingredients {
+		if normalize && ingredient.Quantity != "" {
+			ingredient.Quantity = fridge.NormalizeQuantity(ingredient.Quantity)
+		}
+		if ingredient.Location == models.LocationPantry {
+			pantryItems = append(pantryItems, ingredient)
+		} else {
+			fridgeItems = append(fridgeItems, ingredient)
+		}
+	}
+
+	text := header + "\n"
+	text += formatCategories(fridgeItems)
+
+	if len(pantryItems) > 0 {
+		text += "\n🥫 In the pantry:\n"
+		for _, ingredient := range pantryItems {
+			text += formatIngredient(ingredient)
+		}
+	}
+
+	return text
+}
+
+// formatCategories formats sorted ingredients with a header above each category
+func formatCategories(ingredients []models.Ingredient) string {
+	text := ""
+	category := ""
+	for i, ingredient := range ingredients {
+		if i == 0 || ingredient.Category != category {
+			category = ingredient.Category
+			text += "\n" + fridge.CategoryLabel(category) + "\n"
+		}
+		text += formatIngredient(ingredient)
+	}
+	return text
+}
+
+// formatIngredient formats a single ingredient as a bullet point with its emoji
+func formatIngredient(ingredient models.Ingredient) string {
+	emoji := ingredientEmoji(ingredient.Name)
+	low := ""
+	if ingredient.LowStock {
+		low = " ⚠️"
+	}
+	if ingredient.Quantity != "" {
+		return fmt.Sprintf("%s %s (%s)%s\n", emoji, messages.EscapeMarkdown(ingredient.Name), messages.EscapeMarkdown(ingredient.Quantity), low)
+	}
+	return fmt.Sprintf("%s %s%s\n", emoji, messages.EscapeMarkdown(ingredient.Name), low)
+}
+
+// formatDinnerInfo formats everything we know about a dinner.
+// names maps user IDs to display names, unknown users are shown by their ID.
+func formatDinnerInfo(d models.Dinner, names map[string]string, loc *time.Location) string {
+	name := func(userID string) string {
+		if names[userID] != "" {
+			return names[userID]
+		}
+		return "user " + userID
+	}
+
+	text := fmt.Sprintf("🍽️ %s", messages.Bold(d.Dish.Name))
+	if d.Dish.Cuisine != "" && d.Dish.Cuisine != d.Dish.Name {
+		text += fmt.Sprintf(" (%s)", messages.EscapeMarkdown(d.Dish.Cuisine))
+	}
+	text += "\n\n"
+
+	text += fmt.Sprintf("📅 %s\n", messages.FormatTime(d.StartedAt, loc))
+	if d.Cook != "" {
+		text += fmt.Sprintf("👨‍🍳 Cooked by %s\n", messages.EscapeMarkdown(name(d.Cook)))
+	}
+	if d.FinishedAt.IsZero() {
+		text += "⏳ Still cooking\n"
+	}
+
+	if len(d.Ratings) > 0 {
+		raters := make([]string, 0, len(d.Ratings))
+		for userID := range d.Ratings {
+			raters = append(raters, userID)
+		}
+		sort.Slice(raters, func(i, j int) bool {
+			return name(raters[i]) < name(raters[j])
+		})
+
+		text += fmt.Sprintf("\n⭐ Average rating: %.1f from %d ratings\n", d.AverageRating, len(d.Ratings))
+		for _, userID := range raters {
+			text += fmt.Sprintf("• %s: %s\n", messages.EscapeMarkdown(name(userID)), strings.Repeat("⭐", d.Ratings[userID]))
+		}
+	} else {
+		text += "\n⭐ Not rated yet\n"
+	}
+
+	if len(d.UsedIngredients) > 0 {
+		text += fmt.Sprintf("\n🥕 Ingredients used: %s\n", messages.EscapeMarkdown(strings.Join(d.UsedIngredients, ", ")))
+	}
+
+	text += fmt.Sprintf("\n🆔 %s", shortDinnerID(d))
+	return text
+}
+
+// shortDinnerID returns the part of a dinner ID that /dinner_info accepts as a reference
+func shortDinnerID(d models.Dinner) string {
+	return strings.TrimPrefix(d.ID, fmt.Sprintf("dinner:%d:", d.ChannelID))
+}
+
+// formatRecipeMarkdown formats a dish as a standalone Markdown recipe,
+// with a title, a list of ingredients and numbered steps
+func formatRecipeMarkdown(dish models.Dish) string {
+	text := fmt.Sprintf("# %s\n\n", dish.Name)
+	if dish.Cuisine != "" && dish.Cuisine != dish.Name {
+		text += fmt.Sprintf("*%s cuisine*\n\n", dish.Cuisine)
+	}
+	if dish.Servings > 0 {
+		text += fmt.Sprintf("Serves %d\n\n", dish.Servings)
+	}
+
+	if len(dish.Ingredients) > 0 {
+		text += "## Ingredients\n\n"
+		for _, ingredient := range dish.Ingredients {
+			text += fmt.Sprintf("- %s\n", ingredient)
+		}
+		text += "\n"
+	}
+
+	if len(dish.Instructions) > 0 {
+		text += "## Instructions\n\n"
+		for i, instruction := range dish.Instructions {
+			text += fmt.Sprintf("%d. %s\n", i+1, instruction)
+		}
+	}
+
+	return text
+}
+
+// recipeFilename returns a file name for a recipe, e.g. "beef-stroganoff.md"
+func recipeFilename(dishName string) string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			return unicode.ToLower(r)
+		case r == ' ' || r == '-' || r == '_':
+			return '-'
+		default:
+			return -1
+		}
+	}, strings.TrimSpace(dishName))
+
+	if name == "" {
+		name = "recipe"
+	}
+	return name + ".md"
+}
+
+// formatBytes formats a size in bytes as a human readable string, e.g. "1.5 MB"
+func formatBytes(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+
+	value := float64(size)
+	suffixes := []string{"KB", "MB", "GB", "TB"}
+	i := -1
+	for value >= unit && i < len(suffixes)-1 {
+		value /= unit
+		i++
+	}
+	return fmt.Sprintf("%.1f %s", value, suffixes[i])
+}
+
+// formatKeyTypes lists the number of database keys per type, most common first
+func formatKeyTypes(keysByType map[string]int) string {
+	types := make([]string, 0, len(keysByType))
+	for keyType := range keysByType {
+		types = append(types, keyType)
+	}
+	sort.Slice(types, func(i, j int) bool {
+		if keysByType[types[i]] != keysByType[types[j]] {
+			return keysByType[types[i]] > keysByType[types[j]]
+		}
+		return types[i] < types[j]
+	})
+
+	text := ""
+	for _, keyType := range types {
+		text += fmt.Sprintf("• %s: %d\n", keyType, keysByType[keyType])
+	}
+	return text
+}
+
+// duplicateSuggestionText explains that a dish is already waiting in the suggestion pool
+func duplicateSuggestionText(existing *models.SuggestedDish, userID string) string {
+	if existing.UserID == userID {
+		return fmt.Sprintf("🔁 You already suggested '%s'. It's waiting for the next dinner poll.", messages.EscapeMarkdown(existing.Name))
+	}
+	return fmt.Sprintf("🔁 '%s' was already suggested by @%s. It's waiting for the next dinner poll.", messages.EscapeMarkdown(existing.Name), messages.EscapeMarkdown(existing.Username))
+}
+
+// formatAuditEvents lists audit events, oldest first, with their time in the chat's time zone
+func formatAuditEvents(events []models.AuditEvent, loc *time.Location) string {
+	text := fmt.Sprintf("📜 Last %d workflow events:\n\n", len(events))
+	for _, event := range events {
+		text += fmt.Sprintf("%s %s", messages.FormatTime(event.At, loc), event.Type)
+		if event.Details != "" {
+			text += ": " + event.Details
+		}
+		text += "\n"
+	}
+	return text
+}
+
+// formatTags returns dish tags as " #quick #spicy", or an empty string if there are none
+func formatTags(tags []string) string {
+	text := ""
+	for _, tag := range tags {
+		text += " #" + tag
+	}
+	return text
+}
+
+// suggestionTags returns the tags of a dinner suggestion, which are []interface{}
+// when they come from the AI and []string for offline suggestions
+func suggestionTags(suggestion map[string]interface{}) []string {
+	switch tags := suggestion["tags"].(type) {
+	case []string:
+		return dinner.NormalizeTags(tags)
+	case []interface{}:
+		var result []string
+		for _, tag := range tags {
+			if s, ok := tag.(string); ok {
+				result = append(result, s)
+			}
+		}
+		return dinner.NormalizeTags(result)
+	}
+	return nil
+}
+
+// formatFridgeTrend draws the daily ingredient counts as a bar chart, one line per day
+func formatFridgeTrend(snapshots []models.FridgeSnapshot) string {
+	const maxBar = 15
+
+	most := 0
+	for _, snapshot := range snapshots {
+		if snapshot.Count > most {
+			most = snapshot.Count
+		}
+	}
+
+	text := fmt.Sprintf("📈 Fridge over the last %d days:\n\n", len(snapshots))
+	for _, snapshot := range snapshots {
+		bar := 0
+		if most > 0 {
+			bar = (snapshot.Count*maxBar + most - 1) / most
+		}
+		day := snapshot.Date
+		if date, err := time.Parse("2006-01-02", snapshot.Date); err == nil {
+			day = messages.FormatDate(date, time.UTC)
+		}
+		text += fmt.Sprintf("%s %s %d\n", day, strings.Repeat("█", bar), snapshot.Count)
+	}
+	return text
+}
+
+// formatHealthCheck reports the result of an AI health check with the model and API it used
+func formatHealthCheck(check openai.HealthCheck) string {
+	text := "✅ The AI is working"
+	if check.Err != nil {
+		text = "❌ The AI request failed"
+	}
+	text += fmt.Sprintf(" (%d ms)\n\nModel: %s\nAPI: %s\n", check.Latency.Milliseconds(), check.Model, check.BaseURL)
+
+	if check.Err != nil {
+		text += fmt.Sprintf("Error: %v\n", check.Err)
+	} else if check.Reply != "" {
+		text += fmt.Sprintf("Reply: %s\n", check.Reply)
+	}
+	if check.BreakerOpen {
+		if check.Err != nil {
+			text += "\nRecent requests failed too, so AI features are paused for a minute at a time."
+		} else {
+			text += "\nRecent requests failed, but AI features are back on now."
+		}
+	}
+	return text
+}
+
+// chatStateDescription describes what the bot is waiting for in a chat state
+func chatStateDescription(chatState state.State) string {
+	switch chatState {
+	case state.StateAddingIngredients:
+		return "ingredients to add to the fridge"
+	case state.StateAddingPhotos:
+		return "photos of your fridge"
+	case state.StateSuggestingDish:
+		return "a dish suggestion"
+	default:
+		return string(chatState)
+	}
+}
+
+// userName returns a user's username, or their first name if they don't have one
+func userName(user *tgbotapi.User) string {
+	if user == nil {
+		return ""
+	}
+	if user.UserName != "" {
+		return user.UserName
+	}
+	return user.FirstName
+}
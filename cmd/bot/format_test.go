@@ -0,0 +1,137 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/korjavin/whatsfordinner/pkg/fridge"
+	"github.com/korjavin/whatsfordinner/pkg/models"
+)
+
+func TestFormatDinnerInfo(t *testing.T) {
+	started := time.Date(2024, 6, 1, 18, 30, 0, 0, time.UTC)
+	d := models.Dinner{
+		ID:              "dinner:-100:1717266600",
+		ChannelID:       -100,
+		Dish:            models.Dish{Name: "Lasagna", Cuisine: "Italian"},
+		Cook:            "1",
+		StartedAt:       started,
+		FinishedAt:      started.Add(time.Hour),
+		Ratings:         map[string]int{"1": 5, "2": 4, "3": 3},
+		AverageRating:   4,
+		UsedIngredients: []string{"pasta sheets", "tomatoes"},
+	}
+	names := map[string]string{"1": "Anna", "2": "Ben"}
+
+	got := formatDinnerInfo(d, names, time.UTC)
+	want := "🍽️ *Lasagna* (Italian)\n\n" +
+		"📅 Sat 1 Jun, 18:30\n" +
+		"👨‍🍳 Cooked by Anna\n" +
+		"\n⭐ Average rating: 4.0 from 3 ratings\n" +
+		"• Anna: ⭐⭐⭐⭐⭐\n" +
+		"• Ben: ⭐⭐⭐⭐\n" +
+		"• user 3: ⭐⭐⭐\n" +
+		"\n🥕 Ingredients used: pasta sheets, tomatoes\n" +
+		"\n🆔 1717266600"
+	if got != want {
+		t.Errorf("formatDinnerInfo() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestFormatDinnerInfoStillCooking(t *testing.T) {
+	d := models.Dinner{ID: "dinner:-100:1", ChannelID: -100, Dish: models.Dish{Name: "Soup"}, StartedAt: time.Now()}
+
+	got := formatDinnerInfo(d, nil, time.UTC)
+	for _, part := range []string{"⏳ Still cooking", "⭐ Not rated yet", "🆔 1"} {
+		if !strings.Contains(got, part) {
+			t.Errorf("formatDinnerInfo() = %q, want it to contain %q", got, part)
+		}
+	}
+}
+
+func TestFormatIngredientListNormalizesQuantities(t *testing.T) {
+	ingredients := []models.Ingredient{
+		{Name: "milk", Quantity: "2 cups", Category: fridge.Categorize("milk")},
+		{Name: "carrots", Quantity: "a bunch", Category: fridge.Categorize("carrots")},
+		{Name: "butter", Quantity: "8oz", Category: fridge.Categorize("butter")},
+	}
+
+	got := formatIngredientList("🧊 Fridge:", append([]models.Ingredient(nil), ingredients...), true)
+	for _, part := range []string{"milk (480ml)", "butter (226.8g)", "carrots (a bunch)"} {
+		if !strings.Contains(got, part) {
+			t.Errorf("normalized list = %q, want it to contain %q", got, part)
+		}
+	}
+	// Sorted by category, then by name
+	if strings.Index(got, "butter") > strings.Index(got, "milk") {
+		t.Errorf("normalized list = %q, want butter before milk", got)
+	}
+
+	got = formatIngredientList("🧊 Fridge:", append([]models.Ingredient(nil), ingredients...), false)
+	if !strings.Contains(got, "milk (2 cups)") || !strings.Contains(got, "butter (8oz)") {
+		t.Errorf("list = %q, want the quantities as entered when normalizing is off", got)
+	}
+}
+
+func TestFormatRecipeMarkdown(t *testing.T) {
+	dish := models.Dish{
+		Name:         "Beef Stroganoff",
+		Cuisine:      "Russian",
+		Servings:     4,
+		Ingredients:  []string{"500g beef", "1 onion", "200ml sour cream"},
+		Instructions: []string{"Slice the beef.", "Fry the onion.", "Stir in the sour cream."},
+	}
+
+	got := formatRecipeMarkdown(dish)
+	want := "# Beef Stroganoff\n\n" +
+		"*Russian cuisine*\n\n" +
+		"Serves 4\n\n" +
+		"## Ingredients\n\n" +
+		"- 500g beef\n" +
+		"- 1 onion\n" +
+		"- 200ml sour cream\n\n" +
+		"## Instructions\n\n" +
+		"1. Slice the beef.\n" +
+		"2. Fry the onion.\n" +
+		"3. Stir in the sour cream.\n"
+	if got != want {
+		t.Errorf("formatRecipeMarkdown() =\n%s\nwant\n%s", got, want)
+	}
+
+	// Sections without content are left out
+	if got := formatRecipeMarkdown(models.Dish{Name: "Toast"}); got != "# Toast\n\n" {
+		t.Errorf("formatRecipeMarkdown() of a bare dish = %q, want only the title", got)
+	}
+}
+
+func TestRecipeFilename(t *testing.T) {
+	tests := map[string]string{
+		"Beef Stroganoff": "beef-stroganoff.md",
+		"Mac & Cheese":    "mac--cheese.md",
+		" Crème brûlée ":  "crème-brûlée.md",
+		"!!!":             "recipe.md",
+	}
+	for name, want := range tests {
+		if got := recipeFilename(name); got != want {
+			t.Errorf("recipeFilename(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestFormatFridgeTrend(t *testing.T) {
+	snapshots := []models.FridgeSnapshot{
+		{Date: "2024-06-08", Count: 10},
+		{Date: "2024-06-09", Count: 4},
+		{Date: "2024-06-10", Count: 0},
+	}
+
+	got := formatFridgeTrend(snapshots)
+	want := "📈 Fridge over the last 3 days:\n\n" +
+		"Sat 8 Jun " + strings.Repeat("█", 15) + " 10\n" +
+		"Sun 9 Jun " + strings.Repeat("█", 6) + " 4\n" +
+		"Mon 10 Jun  0\n"
+	if got != want {
+		t.Errorf("formatFridgeTrend() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,602 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/korjavin/whatsfordinner/pkg/dinner"
+	"github.com/korjavin/whatsfordinner/pkg/fridge"
+	"github.com/korjavin/whatsfordinner/pkg/messages"
+	"github.com/korjavin/whatsfordinner/pkg/models"
+	"github.com/korjavin/whatsfordinner/pkg/state"
+	"github.com/korjavin/whatsfordinner/pkg/storage"
+)
+
+// handleFridge handles the /fridge command
+func (a *app) handleFridge(message *tgbotapi.Message) {
+	// Show current ingredients
+	chatID := message.Chat.ID
+
+	ingredients, err := a.fridgeService.ListIngredients(chatID)
+	if err != nil {
+		a.log.Error("Failed to list ingredients: %v", err)
+		a.bot.SendMessage(chatID, "😢 Sorry, I couldn't retrieve your fridge contents right now. Please try again later.")
+		return
+	}
+
+	if len(ingredients) == 0 {
+		a.bot.SendMessage(chatID, "Your fridge is empty! Add ingredients with /sync_fridge or by sending a photo with /add_photo.")
+		return
+	}
+
+	// Create a formatted message with all ingredients
+	msgText := formatIngredientList("🧊 Here's what's in your fridge:", ingredients, !a.fridgeService.RawQuantities(chatID))
+
+	a.bot.SendMessage(chatID, msgText)
+}
+
+// handleShowFridge handles the /show_fridge command
+func (a *app) handleShowFridge(message *tgbotapi.Message) {
+	// This is an alias for the /fridge command
+	// Show current ingredients
+	chatID := message.Chat.ID
+
+	ingredients, err := a.fridgeService.ListIngredients(chatID)
+	if err != nil {
+		a.log.Error("Failed to list ingredients: %v", err)
+		a.bot.SendMessage(chatID, "😢 Sorry, I couldn't retrieve your fridge contents right now. Please try again later.")
+		return
+	}
+
+	if len(ingredients) == 0 {
+		a.bot.SendMessage(chatID, "Your fridge is empty! Add ingredients with /sync_fridge or by sending a photo with /add_photo.")
+		return
+	}
+
+	// Create a formatted message with all ingredients
+	msgText := formatIngredientList("🧊 Here's what's in your fridge:", ingredients, !a.fridgeService.RawQuantities(chatID))
+
+	a.bot.SendMessage(chatID, msgText)
+}
+
+// handleShowFridgeCallback handles the button that shows the fridge
+func (a *app) handleShowFridgeCallback(callback *tgbotapi.CallbackQuery, _ string) {
+	chatID := callback.Message.Chat.ID
+
+	// Answer the callback
+	a.bot.AnswerCallbackQuery(callback.ID, "Here's what's in your fridge!")
+
+	// Edit the message to remove the buttons
+	a.bot.EditMessageWithKeyboard(chatID, callback.Message.MessageID, "Here's what's in your fridge:", tgbotapi.InlineKeyboardMarkup{})
+
+	// Show fridge contents
+	ingredients, err := a.fridgeService.ListIngredients(chatID)
+	if err != nil {
+		a.log.Error("Failed to list ingredients: %v", err)
+		a.bot.SendMessage(chatID, "😢 Sorry, I couldn't retrieve your fridge contents right now. Please try again later.")
+		return
+	}
+
+	if len(ingredients) == 0 {
+		a.bot.SendMessage(chatID, "Your fridge is empty! Add ingredients with /sync_fridge or by sending a photo with /add_photo.")
+		return
+	}
+
+	// Create a formatted message with all ingredients
+	msgText := formatIngredientList("🧊 Here's what's in your fridge:", ingredients, !a.fridgeService.RawQuantities(chatID))
+
+	a.bot.SendMessage(chatID, msgText)
+}
+
+// handleFridgeTrend handles the /fridge_trend command
+func (a *app) handleFridgeTrend(message *tgbotapi.Message) {
+	chatID := message.Chat.ID
+
+	snapshots, err := a.fridgeService.Trend(chatID, fridge.TrendDays, time.Now().In(a.channelLocation(chatID)))
+	if err != nil {
+		a.log.Error("Failed to get fridge trend: %v", err)
+		a.bot.SendMessage(chatID, "😢 Sorry, I couldn't retrieve the fridge history right now. Please try again later.")
+		return
+	}
+
+	if len(snapshots) == 0 {
+		a.bot.SendMessage(chatID, "📈 There's no fridge history yet. I take a snapshot of the fridge every day, check back tomorrow!")
+		return
+	}
+
+	a.bot.SendMessage(chatID, formatFridgeTrend(snapshots))
+}
+
+// handleSyncFridge handles the /sync_fridge command
+func (a *app) handleSyncFridge(message *tgbotapi.Message) {
+	// Reset the fridge
+	chatID := message.Chat.ID
+
+	err := a.fridgeService.ResetFridge(chatID)
+	if err != nil {
+		a.log.Error("Failed to reset fridge: %v", err)
+		errorMsg := a.messageService.GenerateErrorMessage("reset fridge")
+		a.bot.SendMessage(chatID, errorMsg)
+		return
+	}
+
+	// Set the chat state to adding ingredients
+	a.stateManager.SetState(chatID, state.StateAddingIngredients)
+	a.stateManager.ClearData(chatID, "photo_count")
+
+	a.bot.SendMessage(chatID, "🧹 Fridge reset! Pantry staples were kept. Now, please send me a list of ingredients you have. You can send multiple messages, and I'll add all the ingredients to your fridge.")
+}
+
+// handleAdd handles the /add command
+func (a *app) handleAdd(message *tgbotapi.Message) {
+	// Extract ingredients from text and add them to the fridge
+	chatID := message.Chat.ID
+
+	// Check if there's text in the command
+	args := message.CommandArguments()
+	if args == "" {
+		// No text provided, ask for it
+		a.bot.SendMessage(chatID, "🍎 Please provide a list of ingredients to add to your fridge. For example: /add eggs, milk, bread")
+		return
+	}
+
+	// Send a processing message
+	processingMsg, _ := a.bot.SendMessage(chatID, "🔍 Processing your ingredients... This might take a moment.")
+
+	// Parse ingredients from the text
+	ingredients, err := a.openaiClient.WithChannel(chatID).ParseIngredientsFromText(args)
+	if err != nil {
+		a.log.Error("Failed to parse ingredients: %v", err)
+		a.bot.EditMessage(chatID, processingMsg.MessageID, "😢 Sorry, I couldn't understand the ingredients. Please try again with a clearer list.")
+		return
+	}
+
+	if len(ingredients) == 0 {
+		a.bot.EditMessage(chatID, processingMsg.MessageID, "I couldn't find any ingredients in your message. Please try again with a list of ingredients.")
+		return
+	}
+
+	// Add ingredients to the fridge
+	for _, ingredient := range ingredients {
+		err := a.fridgeService.AddIngredient(chatID, ingredient, "")
+		if err != nil {
+			a.log.Error("Failed to add ingredient %s: %v", ingredient, err)
+		}
+	}
+
+	// Edit the processing message to show the results
+	a.bot.EditMessage(chatID, processingMsg.MessageID, fmt.Sprintf("✅ Added %d ingredients to your fridge: %s", len(ingredients), messages.EscapeMarkdown(strings.Join(ingredients, ", "))))
+
+	// Show the updated fridge
+	ingredientList, err := a.fridgeService.ListIngredients(chatID)
+	if err != nil {
+		a.log.Error("Failed to list ingredients: %v", err)
+		return
+	}
+
+	if len(ingredientList) == 0 {
+		a.bot.SendMessage(chatID, "Your fridge is still empty. Try adding ingredients with text or better photos.")
+		return
+	}
+
+	// Create a formatted message with all ingredients
+	msgText := formatIngredientList("🧊 Here's what's in your fridge now:", ingredientList, !a.fridgeService.RawQuantities(chatID))
+
+	a.bot.SendMessage(chatID, msgText)
+}
+
+// handleAddRaw handles the /add_raw command
+func (a *app) handleAddRaw(message *tgbotapi.Message) {
+	chatID := message.Chat.ID
+
+	items := fridge.SplitList(message.CommandArguments())
+	if len(items) == 0 {
+		a.bot.SendMessage(chatID, "🍎 Please list the ingredients to add, separated by commas or new lines. For example: /add_raw eggs, milk, bread")
+		return
+	}
+
+	added, skipped, err := a.fridgeService.AddList(chatID, items)
+	if err != nil {
+		a.log.Error("Failed to add ingredients: %v", err)
+		a.bot.SendMessage(chatID, "😢 Sorry, I couldn't add the ingredients. Please try again later.")
+		return
+	}
+
+	var msgText string
+	if len(added) > 0 {
+		msgText = fmt.Sprintf("✅ Added %d ingredients to your fridge: %s", len(added), messages.EscapeMarkdown(strings.Join(added, ", ")))
+	} else {
+		msgText = "🤷 Nothing new to add."
+	}
+	if len(skipped) > 0 {
+		msgText += fmt.Sprintf("\nAlready there: %s", messages.EscapeMarkdown(strings.Join(skipped, ", ")))
+	}
+	a.bot.SendMessage(chatID, msgText)
+}
+
+// handleAddPantry handles the /add_pantry command
+func (a *app) handleAddPantry(message *tgbotapi.Message) {
+	// Extract staples from text and add them to the pantry
+	chatID := message.Chat.ID
+
+	args := message.CommandArguments()
+	if args == "" {
+		a.bot.SendMessage(chatID, "🥫 Please provide a list of staples to keep in your pantry. For example: /add_pantry salt, flour, olive oil")
+		return
+	}
+
+	// Send a processing message
+	processingMsg, _ := a.bot.SendMessage(chatID, "🔍 Processing your staples... This might take a moment.")
+
+	// Parse ingredients from the text
+	ingredients, err := a.openaiClient.WithChannel(chatID).ParseIngredientsFromText(args)
+	if err != nil {
+		a.log.Error("Failed to parse ingredients: %v", err)
+		a.bot.EditMessage(chatID, processingMsg.MessageID, "😢 Sorry, I couldn't understand the ingredients. Please try again with a clearer list.")
+		return
+	}
+
+	if len(ingredients) == 0 {
+		a.bot.EditMessage(chatID, processingMsg.MessageID, "I couldn't find any ingredients in your message. Please try again with a list of ingredients.")
+		return
+	}
+
+	// Add ingredients to the pantry
+	for _, ingredient := range ingredients {
+		err := a.fridgeService.AddPantryIngredient(chatID, ingredient, "")
+		if err != nil {
+			a.log.Error("Failed to add pantry ingredient %s: %v", ingredient, err)
+		}
+	}
+
+	a.bot.EditMessage(chatID, processingMsg.MessageID, fmt.Sprintf("✅ Added %d staples to your pantry: %s\n\nThey'll stay there when you /sync_fridge.", len(ingredients), messages.EscapeMarkdown(strings.Join(ingredients, ", "))))
+}
+
+// handleStaples handles the /staples command
+func (a *app) handleStaples(message *tgbotapi.Message) {
+	chatID := message.Chat.ID
+	dinnerService := dinner.New(a.store, a.fridgeService, a.openaiClient)
+
+	action, rest, _ := strings.Cut(strings.TrimSpace(message.CommandArguments()), " ")
+	rest = strings.TrimSpace(rest)
+
+	switch strings.ToLower(action) {
+	case "":
+		staples := dinnerService.GetStaples(chatID)
+		if len(staples) == 0 {
+			a.bot.SendMessage(chatID, "🧂 You have no staples. Add some with /staples add salt, pepper")
+			return
+		}
+		a.bot.SendMessage(chatID, fmt.Sprintf("🧂 Staples you always have: %s\n\nThese never show up as missing ingredients.\nUse /staples add <items>, /staples remove <item> or /staples reset to change them.", messages.EscapeMarkdown(strings.Join(staples, ", "))))
+
+	case "add":
+		if rest == "" {
+			a.bot.SendMessage(chatID, "🤔 Please tell me what to add, for example: /staples add flour, rice")
+			return
+		}
+		staples, err := dinnerService.AddStaples(chatID, strings.Split(rest, ","))
+		if err != nil {
+			a.log.Error("Failed to add staples: %v", err)
+			a.bot.SendMessage(chatID, "😢 Sorry, I couldn't save your staples. Please try again later.")
+			return
+		}
+		a.bot.SendMessage(chatID, fmt.Sprintf("🧂 Got it! Your staples are now: %s", messages.EscapeMarkdown(strings.Join(staples, ", "))))
+
+	case "remove":
+		removed, err := dinnerService.RemoveStaple(chatID, rest)
+		if err != nil {
+			a.log.Error("Failed to remove staple: %v", err)
+			a.bot.SendMessage(chatID, "😢 Sorry, I couldn't save your staples. Please try again later.")
+			return
+		}
+		if !removed {
+			a.bot.SendMessage(chatID, fmt.Sprintf("🤔 %s isn't one of your staples.", messages.EscapeMarkdown(rest)))
+			return
+		}
+		a.bot.SendMessage(chatID, fmt.Sprintf("🗑️ Removed %s from your staples.", messages.EscapeMarkdown(rest)))
+
+	case "reset":
+		err := dinnerService.ResetStaples(chatID)
+		if err != nil {
+			a.log.Error("Failed to reset staples: %v", err)
+			a.bot.SendMessage(chatID, "😢 Sorry, I couldn't save your staples. Please try again later.")
+			return
+		}
+		a.bot.SendMessage(chatID, fmt.Sprintf("🧂 Back to the default staples: %s", strings.Join(dinner.DefaultStaples, ", ")))
+
+	default:
+		a.bot.SendMessage(chatID, "🤔 Usage: /staples, /staples add <items>, /staples remove <item> or /staples reset")
+	}
+}
+
+// handleClearCategory handles the /clear_category command
+func (a *app) handleClearCategory(message *tgbotapi.Message) {
+	chatID := message.Chat.ID
+
+	arg := strings.TrimSpace(message.CommandArguments())
+	category, ok := fridge.ParseCategory(arg)
+	if !ok {
+		a.bot.SendMessage(chatID, fmt.Sprintf("🤔 Please name one of the categories: %s. For example: /clear_category dairy", strings.Join(fridge.Categories, ", ")))
+		return
+	}
+
+	removed, err := a.fridgeService.RemoveByCategory(chatID, category)
+	if err != nil {
+		a.log.Error("Failed to clear category %s: %v", category, err)
+		a.bot.SendMessage(chatID, "😢 Sorry, I couldn't clear the category. Please try again later.")
+		return
+	}
+
+	if len(removed) == 0 {
+		a.bot.SendMessage(chatID, fmt.Sprintf("🤷 There's nothing in %s to remove.", fridge.CategoryLabel(category)))
+		return
+	}
+	a.bot.SendMessage(chatID, fmt.Sprintf("🧹 Removed %d ingredients from %s: %s", len(removed), fridge.CategoryLabel(category), messages.EscapeMarkdown(strings.Join(removed, ", "))))
+}
+
+// handleRemove handles the /remove command
+func (a *app) handleRemove(message *tgbotapi.Message) {
+	chatID := message.Chat.ID
+
+	var names []string
+	for _, name := range strings.Split(message.CommandArguments(), ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		a.bot.SendMessage(chatID, "🤔 Please tell me what to remove, separated by commas. For example: /remove milk, eggs")
+		return
+	}
+
+	var removed, notFound []string
+	for _, name := range names {
+		key, err := a.fridgeService.RemoveIngredient(chatID, name)
+		if errors.Is(err, fridge.ErrIngredientNotFound) {
+			notFound = append(notFound, name)
+			continue
+		}
+		if err != nil {
+			a.log.Error("Failed to remove ingredient %s: %v", name, err)
+			a.bot.SendMessage(chatID, "😢 Sorry, I couldn't update your fridge right now. Please try again later.")
+			return
+		}
+		removed = append(removed, key)
+	}
+
+	msgText := ""
+	if len(removed) > 0 {
+		msgText = fmt.Sprintf("🗑️ Removed from your fridge: %s", messages.EscapeMarkdown(strings.Join(removed, ", ")))
+	}
+	if len(notFound) > 0 {
+		if msgText != "" {
+			msgText += "\n"
+		}
+		msgText += fmt.Sprintf("🤔 I couldn't find %s in your fridge. Check /fridge for the exact names.", messages.EscapeMarkdown(strings.Join(notFound, ", ")))
+	}
+	a.bot.SendMessage(chatID, msgText)
+	if len(removed) == 0 {
+		return
+	}
+
+	// Show the updated fridge
+	ingredientList, err := a.fridgeService.ListIngredients(chatID)
+	if err != nil {
+		a.log.Error("Failed to list ingredients: %v", err)
+		return
+	}
+	if len(ingredientList) == 0 {
+		a.bot.SendMessage(chatID, "Your fridge is empty now. Add ingredients with /sync_fridge or by sending a photo with /add_photo.")
+		return
+	}
+	a.bot.SendMessage(chatID, formatIngredientList("🧊 Here's what's in your fridge now:", ingredientList, !a.fridgeService.RawQuantities(chatID)))
+}
+
+// handleMerge handles the /merge command
+func (a *app) handleMerge(message *tgbotapi.Message) {
+	chatID := message.Chat.ID
+
+	args := splitQuotedArgs(message.CommandArguments())
+	if len(args) != 2 {
+		a.bot.SendMessage(chatID, "🤔 Please name the ingredient to merge and the one to keep, in quotes if they have several words. For example: /merge \"red pepper\" \"bell pepper\"")
+		return
+	}
+
+	merged, err := a.fridgeService.MergeIngredients(chatID, args[0], args[1])
+	if errors.Is(err, fridge.ErrIngredientNotFound) {
+		a.bot.SendMessage(chatID, fmt.Sprintf("🤔 I couldn't find %s in your fridge. Check /fridge for the exact name.", messages.EscapeMarkdown(args[0])))
+		return
+	}
+	if err != nil {
+		a.log.Error("Failed to merge ingredients: %v", err)
+		a.bot.SendMessage(chatID, "😢 Sorry, I couldn't merge the ingredients. Please try again later.")
+		return
+	}
+
+	msgText := fmt.Sprintf("✅ Merged %s into %s", messages.EscapeMarkdown(args[0]), messages.EscapeMarkdown(merged.Name))
+	if merged.Quantity != "" {
+		msgText += fmt.Sprintf(" (%s)", messages.EscapeMarkdown(merged.Quantity))
+	}
+	a.bot.SendMessage(chatID, msgText+".")
+}
+
+// handleLow handles the /low command
+func (a *app) handleLow(message *tgbotapi.Message) {
+	chatID := message.Chat.ID
+
+	name := strings.TrimSpace(message.CommandArguments())
+	low := true
+	if rest, ok := strings.CutSuffix(strings.ToLower(name), " off"); ok {
+		name, low = strings.TrimSpace(name[:len(rest)]), false
+	}
+	if name == "" {
+		a.bot.SendMessage(chatID, "🤔 Please name the ingredient that's running low. For example: /low milk")
+		return
+	}
+
+	key, err := a.fridgeService.MarkLow(chatID, name, low)
+	if errors.Is(err, fridge.ErrIngredientNotFound) {
+		a.bot.SendMessage(chatID, fmt.Sprintf("🤔 I couldn't find %s in your fridge. Check /fridge for the exact name.", messages.EscapeMarkdown(name)))
+		return
+	}
+	if err != nil {
+		a.log.Error("Failed to mark ingredient as low: %v", err)
+		a.bot.SendMessage(chatID, "😢 Sorry, I couldn't update the ingredient. Please try again later.")
+		return
+	}
+
+	if low {
+		a.bot.SendMessage(chatID, fmt.Sprintf("⚠️ Noted, %s is running low. It'll be on the next shopping list.", messages.EscapeMarkdown(key)))
+	} else {
+		a.bot.SendMessage(chatID, fmt.Sprintf("✅ %s is no longer marked as running low.", messages.EscapeMarkdown(key)))
+	}
+}
+
+// handleQuantities handles the /quantities command
+func (a *app) handleQuantities(message *tgbotapi.Message) {
+	chatID := message.Chat.ID
+
+	var raw bool
+	switch strings.ToLower(strings.TrimSpace(message.CommandArguments())) {
+	case "":
+		if a.fridgeService.RawQuantities(chatID) {
+			a.bot.SendMessage(chatID, "⚖️ Fridge amounts are shown as entered. Use /quantities metric to convert them to grams and liters.")
+		} else {
+			a.bot.SendMessage(chatID, "⚖️ Fridge amounts are converted to grams and liters. Use /quantities raw to show them as entered.")
+		}
+		return
+	case "metric":
+		raw = false
+	case "raw":
+		raw = true
+	default:
+		a.bot.SendMessage(chatID, "🤔 Please use /quantities metric or /quantities raw")
+		return
+	}
+
+	err := a.fridgeService.SetRawQuantities(chatID, raw)
+	if err != nil {
+		a.log.Error("Failed to save quantity setting: %v", err)
+		a.bot.SendMessage(chatID, "😢 Sorry, I couldn't save the setting. Please try again later.")
+		return
+	}
+
+	if raw {
+		a.bot.SendMessage(chatID, "⚖️ Got it! Fridge amounts will be shown as entered.")
+	} else {
+		a.bot.SendMessage(chatID, "⚖️ Got it! Fridge amounts will be converted to grams and liters.")
+	}
+}
+
+// handleDoneAddingCallback handles the button that finishes adding ingredients
+func (a *app) handleDoneAddingCallback(callback *tgbotapi.CallbackQuery, _ string) {
+	chatID := callback.Message.Chat.ID
+
+	// Clear the state
+	a.stateManager.ClearState(chatID)
+
+	// Answer the callback
+	a.bot.AnswerCallbackQuery(callback.ID, "Thanks! Your fridge is now updated.")
+
+	// Edit the message to remove the buttons
+	a.bot.EditMessageWithKeyboard(chatID, callback.Message.MessageID, "✅ Fridge update complete! Use /fridge to see your ingredients or /dinner to get dinner suggestions.", tgbotapi.InlineKeyboardMarkup{})
+}
+
+// handleAddMoreCallback handles the button to add more ingredients
+func (a *app) handleAddMoreCallback(callback *tgbotapi.CallbackQuery, _ string) {
+	chatID := callback.Message.Chat.ID
+
+	// Keep the state as is
+
+	// Answer the callback
+	a.bot.AnswerCallbackQuery(callback.ID, "Please send more ingredients!")
+
+	// Edit the message to remove the buttons
+	a.bot.EditMessageWithKeyboard(chatID, callback.Message.MessageID, "Please send more ingredients. I'll add them to your fridge.", tgbotapi.InlineKeyboardMarkup{})
+}
+
+// handleUpdateFridgeCallback handles the button that removes the used ingredients from the fridge
+func (a *app) handleUpdateFridgeCallback(callback *tgbotapi.CallbackQuery, dinnerID string) {
+	chatID := callback.Message.Chat.ID
+
+	// The callback data is "update_fridge:dinner:{channelID}:{timestamp}", its payload is the dinner ID
+	if dinnerID == "" {
+		a.log.Error("Invalid callback data: %s", callback.Data)
+		a.bot.AnswerCallbackQuery(callback.ID, "Something went wrong. Please try again.")
+		return
+	}
+	a.log.Info("Extracted dinner ID: %s from callback data: %s", dinnerID, callback.Data)
+	a.log.Info("Looking up dinner with ID: %s for fridge update", dinnerID)
+
+	// Get the dinner event
+	var dinnerEvent models.Dinner
+	err := a.store.Get(dinnerID, &dinnerEvent)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			a.bot.AnswerCallbackQuery(callback.ID, "This dinner is no longer available.")
+			return
+		}
+		a.log.Error("Failed to get dinner event: %v", err)
+		a.bot.AnswerCallbackQuery(callback.ID, "Something went wrong. Please try again.")
+		return
+	}
+
+	// Remove ingredients from the fridge
+	for _, ingredient := range dinnerEvent.Dish.Ingredients {
+		// Recipe ingredients may start with an amount, fridge ingredients don't
+		_, name, _ := fridge.ParseQuantity(ingredient)
+		_, err := a.fridgeService.RemoveIngredient(chatID, name)
+		if err != nil && !errors.Is(err, fridge.ErrIngredientNotFound) {
+			a.log.Error("Failed to remove ingredient %s: %v", ingredient, err)
+			// Continue with other ingredients
+		}
+	}
+
+	// Update the dinner with the used ingredients
+	dinnerService := dinner.New(a.store, a.fridgeService, a.openaiClient)
+	err = dinnerService.UpdateUsedIngredients(dinnerID, dinnerEvent.Dish.Ingredients)
+	if err != nil {
+		a.log.Error("Failed to update used ingredients: %v", err)
+		// Continue anyway
+	}
+
+	// Update helper statistics (for future shopping feature)
+	// For now, we'll just acknowledge the callback
+
+	// Answer the callback
+	a.bot.AnswerCallbackQuery(callback.ID, "Fridge updated!")
+
+	// Edit the message to remove the buttons
+	a.bot.EditMessageWithKeyboard(chatID, callback.Message.MessageID, "✅ Your fridge has been updated by removing the ingredients used for this dinner.", tgbotapi.InlineKeyboardMarkup{})
+
+	// Show the updated fridge
+	ingredients, err := a.fridgeService.ListIngredients(chatID)
+	if err != nil {
+		a.log.Error("Failed to list ingredients: %v", err)
+		return
+	}
+
+	if len(ingredients) == 0 {
+		a.bot.SendMessage(chatID, "Your fridge is now empty! You might want to add more ingredients with /sync_fridge or /add_photo.")
+		return
+	}
+
+	// Create a formatted message with all ingredients
+	msgText := formatIngredientList("🧊 Here's what's left in your fridge:", ingredients, !a.fridgeService.RawQuantities(chatID))
+
+	a.bot.SendMessage(chatID, msgText)
+}
+
+// handleSkipUpdateFridgeCallback handles the button that keeps the fridge as it is after dinner
+func (a *app) handleSkipUpdateFridgeCallback(callback *tgbotapi.CallbackQuery, _ string) {
+	chatID := callback.Message.Chat.ID
+
+	// Answer the callback
+	a.bot.AnswerCallbackQuery(callback.ID, "Fridge not updated.")
+
+	// Edit the message to remove the buttons
+	a.bot.EditMessageWithKeyboard(chatID, callback.Message.MessageID, "Fridge not updated. Your ingredients remain the same.", tgbotapi.InlineKeyboardMarkup{})
+}
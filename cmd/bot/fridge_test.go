@@ -0,0 +1,52 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFridgeShowsPantrySeparately(t *testing.T) {
+	ta := newTestApp(t)
+	if err := ta.fridgeService.AddIngredient(testChatID, "milk", "1l"); err != nil {
+		t.Fatalf("AddIngredient failed: %v", err)
+	}
+	if err := ta.fridgeService.AddPantryIngredient(testChatID, "salt", ""); err != nil {
+		t.Fatalf("AddPantryIngredient failed: %v", err)
+	}
+
+	ta.handleFridge(command(testUser(1, "Anna"), "/fridge"))
+	text := ta.telegram.LastText()
+
+	milk, pantry, salt := strings.Index(text, "milk"), strings.Index(text, "In the pantry"), strings.Index(text, "salt")
+	if milk < 0 || pantry < 0 || salt < 0 || !(milk < pantry && pantry < salt) {
+		t.Errorf("/fridge said %q, want milk and then salt in the pantry", text)
+	}
+}
+
+func TestRemoveReportsWhatItCouldntFind(t *testing.T) {
+	ta := newTestApp(t)
+	for _, name := range []string{"milk", "eggs"} {
+		if err := ta.fridgeService.AddIngredient(testChatID, name, "1"); err != nil {
+			t.Fatalf("AddIngredient failed: %v", err)
+		}
+	}
+
+	ta.handleRemove(command(testUser(1, "Anna"), "/remove Milk, tofu"))
+	texts := ta.telegram.Texts()
+	if len(texts) != 2 {
+		t.Fatalf("/remove sent %q, want a summary and the updated fridge", texts)
+	}
+	if !strings.Contains(texts[0], "Removed from your fridge: milk") || !strings.Contains(texts[0], "I couldn't find tofu") {
+		t.Errorf("/remove said %q, want milk removed and tofu not found", texts[0])
+	}
+	if !strings.Contains(texts[1], "eggs") || strings.Contains(texts[1], "milk") {
+		t.Errorf("updated fridge is %q, want eggs without milk", texts[1])
+	}
+
+	// Nothing found, so there's no fridge listing
+	ta.telegram.Reset()
+	ta.handleRemove(command(testUser(1, "Anna"), "/remove tofu"))
+	if texts := ta.telegram.Texts(); len(texts) != 1 || !strings.Contains(texts[0], "I couldn't find tofu") || strings.Contains(texts[0], "Removed") {
+		t.Errorf("/remove tofu sent %q, want only the not found message", texts)
+	}
+}
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/korjavin/whatsfordinner/pkg/messages"
+	"github.com/korjavin/whatsfordinner/pkg/poll"
+	"github.com/korjavin/whatsfordinner/pkg/state"
+)
+
+// handlePollAnswer handles an answer to a dinner poll of a chat.
+// The bot found the chat when it queued the answer, it's 0 if the poll isn't running anymore.
+func (a *app) handlePollAnswer(chatID int64, answer *tgbotapi.PollAnswer) {
+	pollID := answer.PollID
+	userID := fmt.Sprintf("%d", answer.User.ID)
+
+	a.log.Info("Received poll answer for poll %s from user %s", pollID, userID)
+
+	if chatID == 0 {
+		a.log.Info("Ignoring an answer to poll %s, it isn't running", pollID)
+		return
+	}
+
+	// An empty answer means the user retracted their vote
+	if len(answer.OptionIDs) == 0 {
+		err := a.pollService.RetractVote(chatID, pollID, userID)
+		if errors.Is(err, poll.ErrVoteEnded) {
+			a.log.Info("Ignoring a retracted answer to closed poll %s", pollID)
+			return
+		}
+		if err != nil {
+			a.log.Error("Failed to retract vote: %v", err)
+			return
+		}
+		a.refreshTally(chatID, pollID)
+		return
+	}
+
+	// Get the option text from the poll
+	vote, err := a.pollService.GetVote(chatID, pollID)
+	if err != nil {
+		a.log.Error("Failed to get vote: %v", err)
+		return
+	}
+
+	// Polls are created as single-choice, so there should only ever be one option
+	if len(answer.OptionIDs) > 1 {
+		a.log.Warn("Received %d options for single-choice poll %s, using the first one", len(answer.OptionIDs), pollID)
+	}
+	optionID := answer.OptionIDs[0]
+	if optionID >= len(vote.Options) {
+		a.log.Error("Invalid option ID: %d", optionID)
+		return
+	}
+
+	option := vote.Options[optionID]
+
+	// Record the vote
+	err = a.pollService.RecordVote(chatID, pollID, userID, userName(&answer.User), option)
+	if errors.Is(err, poll.ErrVoteEnded) {
+		a.log.Info("Ignoring a late answer to closed poll %s", pollID)
+		return
+	}
+	if err != nil {
+		a.log.Error("Failed to record vote: %v", err)
+		return
+	}
+
+	a.handleVote(chatID, pollID)
+}
+
+// handleUpdate handles the updates that aren't commands, callbacks, reactions or poll answers:
+// photos and the text people send while the bot waits for their input
+func (a *app) handleUpdate(update tgbotapi.Update) {
+	// Skip if there's no message
+	if update.Message == nil {
+		return
+	}
+
+	chatID := update.Message.Chat.ID
+
+	// Handle photos (without command)
+	if len(update.Message.Photo) > 0 && !update.Message.IsCommand() {
+		// Check if the chat is in adding ingredients state
+		chatState := a.stateManager.GetState(chatID)
+		if chatState == state.StateAddingIngredients || chatState == state.StateAddingPhotos {
+			// Every photo costs an AI call, so a session only reads so many
+			if a.stateManager.IncrementData(chatID, "photo_count") > a.cfg.MaxPhotosPerSession {
+				done := "done_adding_photos"
+				if chatState == state.StateAddingIngredients {
+					done = "done_adding"
+				}
+				keyboard := tgbotapi.NewInlineKeyboardMarkup(
+					tgbotapi.NewInlineKeyboardRow(
+						tgbotapi.NewInlineKeyboardButtonData("Done", done),
+					),
+				)
+				a.bot.SendMessageWithKeyboard(chatID, fmt.Sprintf("✋ You've added the maximum of %d photos for now. Please press Done, then start again with /add_photo if there's more.", a.cfg.MaxPhotosPerSession), keyboard)
+				return
+			}
+
+			// Get the largest photo (last in the array)
+			photo := update.Message.Photo[len(update.Message.Photo)-1]
+
+			// Send a processing message
+			processingMsg, _ := a.bot.SendMessage(chatID, "🔍 Processing your photo... This might take a moment.")
+
+			// Extract ingredients from the photo and its caption
+			ingredients, fromCaption := a.photoIngredients(chatID, photo, update.Message.Caption)
+			if len(ingredients) == 0 {
+				a.bot.EditMessage(chatID, processingMsg.MessageID, "😢 Sorry, I couldn't identify any ingredients in your photo. Please try again with a clearer photo.")
+				return
+			}
+
+			// Add ingredients to the fridge
+			for _, ingredient := range ingredients {
+				err := a.fridgeService.AddIngredient(chatID, ingredient, "")
+				if err != nil {
+					a.log.Error("Failed to add ingredient %s: %v", ingredient, err)
+				}
+			}
+
+			// Edit the processing message to show the results
+			a.bot.EditMessage(chatID, processingMsg.MessageID, photoConfirmation(ingredients, fromCaption))
+
+			// Different buttons based on the state
+			var keyboard tgbotapi.InlineKeyboardMarkup
+			var promptText string
+
+			if chatState == state.StateAddingIngredients {
+				// For text-based ingredient adding
+				keyboard = tgbotapi.NewInlineKeyboardMarkup(
+					tgbotapi.NewInlineKeyboardRow(
+						tgbotapi.NewInlineKeyboardButtonData("Done adding ingredients", "done_adding"),
+						tgbotapi.NewInlineKeyboardButtonData("Add more", "add_more"),
+					),
+				)
+				promptText = "Would you like to add more ingredients or are you done?"
+			} else {
+				// For photo-based ingredient adding
+				keyboard = tgbotapi.NewInlineKeyboardMarkup(
+					tgbotapi.NewInlineKeyboardRow(
+						tgbotapi.NewInlineKeyboardButtonData("Done adding photos", "done_adding_photos"),
+					),
+				)
+				promptText = "Send more photos of your fridge or pantry, and I'll extract ingredients from them. Press 'Done' when you're finished."
+			}
+
+			a.bot.SendMessageWithKeyboard(chatID, promptText, keyboard)
+		} else {
+			// Suggest using /add_photo command
+			a.bot.SendMessage(chatID, "I see you sent a photo! If you want me to extract ingredients from it, please use the /add_photo command.")
+		}
+		return
+	}
+
+	// Handle text messages
+	if update.Message.Text != "" && !update.Message.IsCommand() {
+		text := update.Message.Text
+
+		// Check if the chat is in adding ingredients state
+		if a.stateManager.GetState(chatID) == state.StateAddingIngredients {
+			// Parse ingredients from the text
+			ingredients, err := a.openaiClient.WithChannel(chatID).ParseIngredientsFromText(text)
+			if err != nil {
+				a.log.Error("Failed to parse ingredients: %v", err)
+				a.bot.SendMessage(chatID, fmt.Sprintf("😢 Sorry, I couldn't understand the ingredients. Please try again with a clearer list."))
+				return
+			}
+
+			if len(ingredients) == 0 {
+				a.bot.SendMessage(chatID, "I couldn't find any ingredients in your message. Please try again with a list of ingredients.")
+				return
+			}
+
+			// Add ingredients to the fridge
+			for _, ingredient := range ingredients {
+				err := a.fridgeService.AddIngredient(chatID, ingredient, "")
+				if err != nil {
+					a.log.Error("Failed to add ingredient %s: %v", ingredient, err)
+				}
+			}
+
+			// Confirm the ingredients were added
+			a.bot.SendMessage(chatID, fmt.Sprintf("✅ Added %d ingredients to your fridge: %s", len(ingredients), messages.EscapeMarkdown(strings.Join(ingredients, ", "))))
+
+			// Ask if they want to add more
+			keyboard := tgbotapi.NewInlineKeyboardMarkup(
+				tgbotapi.NewInlineKeyboardRow(
+					tgbotapi.NewInlineKeyboardButtonData("Done adding ingredients", "done_adding"),
+					tgbotapi.NewInlineKeyboardButtonData("Add more", "add_more"),
+				),
+			)
+
+			a.bot.SendMessageWithKeyboard(chatID, "Would you like to add more ingredients or are you done?", keyboard)
+		} else if a.stateManager.GetState(chatID) == state.StateSuggestingDish {
+			// We're now handling this directly in the /suggest command
+			// Just clear the state and ask the user to use the command
+			a.stateManager.ClearState(chatID)
+			a.bot.SendMessage(chatID, "🍴 Please use the /suggest command followed by a dish name, like: /suggest Lasagna")
+		} else {
+			// Regular ingredient adding (single ingredient)
+			// Check if it looks like an ingredient
+			if !strings.Contains(text, " ") && len(text) < 30 {
+				err := a.fridgeService.AddIngredient(chatID, text, "")
+				if err != nil {
+					a.log.Error("Failed to add ingredient: %v", err)
+					a.bot.SendMessage(chatID, fmt.Sprintf("😢 Sorry, I couldn't add %s to your fridge.", messages.EscapeMarkdown(text)))
+					return
+				}
+
+				a.bot.SendMessage(chatID, fmt.Sprintf("✅ Added %s to your fridge!", messages.EscapeMarkdown(text)))
+			}
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/korjavin/whatsfordinner/pkg/dinner"
+	"github.com/korjavin/whatsfordinner/pkg/messages"
+	"github.com/korjavin/whatsfordinner/pkg/poll"
+)
+
+// handleExcuse handles the /excuse command
+func (a *app) handleExcuse(message *tgbotapi.Message) {
+	chatID := message.Chat.ID
+	loc := a.channelLocation(chatID)
+
+	if strings.TrimSpace(message.CommandArguments()) == "" && message.ReplyToMessage == nil {
+		excused := a.pollService.ExcusedMembers(chatID, time.Now())
+		if len(excused) == 0 {
+			a.bot.SendMessage(chatID, "👪 Everyone is home. Use /excuse @username to leave someone out while they're away.")
+			return
+		}
+
+		msgText := "🧳 Away right now:\n\n"
+		for _, member := range excused {
+			if member.Until.IsZero() {
+				msgText += fmt.Sprintf("• @%s until /unexcuse\n", messages.EscapeMarkdown(member.Username))
+				continue
+			}
+			msgText += fmt.Sprintf("• @%s until %s\n", messages.EscapeMarkdown(member.Username), messages.FormatDate(member.Until.Add(-time.Second), loc))
+		}
+		a.bot.SendMessage(chatID, msgText)
+		return
+	}
+
+	userID, name, rest, ok := a.targetUser(message)
+	if !ok {
+		a.bot.SendMessage(chatID, "🤔 I don't know who's away. Use /excuse @username, or reply to their message with /excuse.")
+		return
+	}
+
+	// The excuse lasts through the given day
+	var until time.Time
+	if rest != "" {
+		date, err := time.ParseInLocation(dinner.DateLayout, rest, loc)
+		if err != nil {
+			a.bot.SendMessage(chatID, "🤔 I couldn't understand that date. Please use the format /excuse @username 2024-06-10")
+			return
+		}
+		until = date.AddDate(0, 0, 1)
+		if !until.After(time.Now()) {
+			a.bot.SendMessage(chatID, "🤔 That date is in the past. Please pick a date in the future.")
+			return
+		}
+	}
+
+	err := a.pollService.ExcuseMember(chatID, userID, name, until)
+	if err != nil {
+		a.log.Error("Failed to excuse member: %v", err)
+		a.bot.SendMessage(chatID, "😢 Sorry, I couldn't save that right now. Please try again later.")
+		return
+	}
+
+	if until.IsZero() {
+		a.bot.SendMessage(chatID, fmt.Sprintf("🧳 @%s is away and won't count towards the dinner polls until /unexcuse.", messages.EscapeMarkdown(name)))
+		return
+	}
+	a.bot.SendMessage(chatID, fmt.Sprintf("🧳 @%s is away and won't count towards the dinner polls until %s.", messages.EscapeMarkdown(name), messages.FormatDate(until.Add(-time.Second), loc)))
+}
+
+// handleUnexcuse handles the /unexcuse command
+func (a *app) handleUnexcuse(message *tgbotapi.Message) {
+	chatID := message.Chat.ID
+
+	userID, name, _, ok := a.targetUser(message)
+	if !ok {
+		a.bot.SendMessage(chatID, "🤔 I don't know who's back. Use /unexcuse @username, or reply to their message with /unexcuse.")
+		return
+	}
+
+	err := a.pollService.UnexcuseMember(chatID, userID)
+	if errors.Is(err, poll.ErrNotExcused) {
+		a.bot.SendMessage(chatID, fmt.Sprintf("👪 @%s isn't away.", messages.EscapeMarkdown(name)))
+		return
+	}
+	if err != nil {
+		a.log.Error("Failed to unexcuse member: %v", err)
+		a.bot.SendMessage(chatID, "😢 Sorry, I couldn't save that right now. Please try again later.")
+		return
+	}
+
+	a.bot.SendMessage(chatID, fmt.Sprintf("🏠 Welcome back, @%s! You count towards the dinner polls again.", messages.EscapeMarkdown(name)))
+}
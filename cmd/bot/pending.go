@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/korjavin/whatsfordinner/pkg/messages"
+	"github.com/korjavin/whatsfordinner/pkg/models"
+	"github.com/korjavin/whatsfordinner/pkg/poll"
+)
+
+// handlePendingVoters handles the /pending_voters command
+func (a *app) handlePendingVoters(message *tgbotapi.Message) {
+	chatID := message.Chat.ID
+
+	currentVote, err := a.pollService.GetCurrentVote(chatID)
+	if err != nil || currentVote == nil || !currentVote.EndedAt.IsZero() {
+		a.bot.SendMessage(chatID, "🤷 There's no poll running right now. Start one with /dinner.")
+		return
+	}
+
+	// The channel's copy of the vote doesn't have the votes, the vote record does
+	vote, err := a.pollService.GetVote(chatID, currentVote.PollID)
+	if err != nil {
+		a.log.Error("Failed to get vote: %v", err)
+		a.bot.SendMessage(chatID, "😢 Sorry, I couldn't check who has voted right now. Please try again later.")
+		return
+	}
+
+	// Telegram doesn't let bots list group members, so the roster is everyone who
+	// voted in an earlier poll plus the chat admins
+	roster, err := a.pollService.Roster(chatID)
+	if err != nil {
+		a.log.Error("Failed to get the roster: %v", err)
+		a.bot.SendMessage(chatID, "😢 Sorry, I couldn't check who has voted right now. Please try again later.")
+		return
+	}
+	if !message.Chat.IsPrivate() {
+		admins, err := a.bot.GetChatAdministrators(chatID)
+		if err != nil {
+			a.log.Warn("Failed to get chat administrators: %v", err)
+		}
+		for _, admin := range admins {
+			if admin.User == nil || admin.User.IsBot {
+				continue
+			}
+			userID := fmt.Sprintf("%d", admin.User.ID)
+			if _, known := roster[userID]; !known {
+				roster[userID] = userName(admin.User)
+			}
+		}
+	}
+
+	var channelState models.ChannelState
+	if err := a.store.Get(fmt.Sprintf("channel:%d", chatID), &channelState); err != nil {
+		a.log.Error("Failed to get channel state: %v", err)
+		a.bot.SendMessage(chatID, "😢 Sorry, I couldn't check who has voted right now. Please try again later.")
+		return
+	}
+	pending := poll.PendingVoters(roster, vote, poll.ActiveExcuses(channelState.Excused, time.Now()))
+
+	msgText := fmt.Sprintf("✅ Everyone I know of has voted! (%d votes so far)", len(vote.Votes))
+	if len(pending) > 0 {
+		names := make([]string, len(pending))
+		for i, name := range pending {
+			names[i] = messages.EscapeMarkdown(name)
+		}
+		msgText = "⏳ Still waiting for votes from: " + strings.Join(names, ", ")
+	}
+	if len(roster) < channelState.MemberCount {
+		msgText += fmt.Sprintf("\n\nI only know %d of your %d members from earlier polls and the chat admins, so others may not have voted yet either.", len(roster), channelState.MemberCount)
+	}
+	a.bot.SendMessage(chatID, msgText)
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/korjavin/whatsfordinner/pkg/models"
+)
+
+func TestPendingVotersUsesEarlierVotersAndAdmins(t *testing.T) {
+	ta := newTestApp(t)
+	anna, bob := testUser(1, "Anna"), testUser(2, "Bob")
+	ta.telegram.SetMemberCount(10)
+
+	ta.handlePendingVoters(command(anna, "/pending_voters"))
+	if reply := ta.telegram.LastText(); !strings.Contains(reply, "no poll running") {
+		t.Fatalf("/pending_voters without a poll replied %q, want no poll running", reply)
+	}
+
+	if _, err := ta.pollService.CreateVote(testChatID, "earlier", 1, []string{"Pasta", "Soup"}); err != nil {
+		t.Fatalf("CreateVote failed: %v", err)
+	}
+	ta.answerPoll(anna, "earlier", 0)
+	ta.answerPoll(bob, "earlier", 1)
+	ta.answerPoll(testUser(4, "Foo_Bar"), "earlier", 1)
+	if err := ta.pollService.EndVote(testChatID, "earlier", "Pasta"); err != nil {
+		t.Fatalf("EndVote failed: %v", err)
+	}
+	if _, err := ta.pollService.CreateVote(testChatID, "tonight", 2, []string{"Curry", "Soup"}); err != nil {
+		t.Fatalf("CreateVote failed: %v", err)
+	}
+	if err := ta.pollService.RecordVote(testChatID, "tonight", "1", "anna", "Curry"); err != nil {
+		t.Fatalf("RecordVote failed: %v", err)
+	}
+	ta.telegram.SetAdmin(3)
+
+	var channelState models.ChannelState
+	if err := ta.store.Get("channel:-100", &channelState); err != nil {
+		t.Fatalf("failed to get channel: %v", err)
+	}
+	channelState.MemberCount = 6
+	if err := ta.store.Set("channel:-100", channelState); err != nil {
+		t.Fatalf("failed to save channel: %v", err)
+	}
+
+	ta.handlePendingVoters(command(anna, "/pending_voters"))
+	reply := ta.telegram.LastText()
+	// Names are escaped, so an underscore doesn't break the Markdown
+	if !strings.Contains(reply, "Still waiting for votes from: Admin 3, bob, foo\\_bar") {
+		t.Errorf("/pending_voters replied %q, want the admin, bob and foo_bar pending", reply)
+	}
+	if strings.Contains(reply, "anna") {
+		t.Errorf("/pending_voters replied %q, anna already voted", reply)
+	}
+	// Four of six members are known, so the list may be incomplete
+	if !strings.Contains(reply, "I only know 4 of your 6 members") {
+		t.Errorf("/pending_voters replied %q, want it to say the roster is incomplete", reply)
+	}
+}
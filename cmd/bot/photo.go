@@ -0,0 +1,194 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/korjavin/whatsfordinner/pkg/images"
+	"github.com/korjavin/whatsfordinner/pkg/messages"
+	"github.com/korjavin/whatsfordinner/pkg/state"
+)
+
+// mergeIngredients combines the ingredients found in a photo with the ones listed in its caption,
+// dropping duplicates regardless of case. It returns the merged list and how many ingredients
+// only the caption had.
+func mergeIngredients(fromPhoto, fromCaption []string) ([]string, int) {
+	seen := make(map[string]bool)
+	merged := make([]string, 0, len(fromPhoto)+len(fromCaption))
+	add := func(ingredient string) bool {
+		key := strings.ToLower(strings.TrimSpace(ingredient))
+		if key == "" || seen[key] {
+			return false
+		}
+		seen[key] = true
+		merged = append(merged, strings.TrimSpace(ingredient))
+		return true
+	}
+
+	for _, ingredient := range fromPhoto {
+		add(ingredient)
+	}
+	extra := 0
+	for _, ingredient := range fromCaption {
+		if add(ingredient) {
+			extra++
+		}
+	}
+
+	return merged, extra
+}
+
+// photoConfirmation tells the user which ingredients were added from a photo and its caption
+func photoConfirmation(ingredients []string, fromCaption int) string {
+	fromPhoto := len(ingredients) - fromCaption
+	list := messages.EscapeMarkdown(strings.Join(ingredients, ", "))
+
+	switch {
+	case fromCaption == 0:
+		return fmt.Sprintf("✅ I found %d ingredients in your photo: %s", fromPhoto, list)
+	case fromPhoto == 0:
+		return fmt.Sprintf("✅ I couldn't see anything in your photo, but added the %d ingredients from your caption: %s", fromCaption, list)
+	default:
+		return fmt.Sprintf("✅ I found %d ingredients in your photo and %d more in your caption: %s", fromPhoto, fromCaption, list)
+	}
+}
+
+// compressPhoto downscales a photo before it's sent to the AI, returning it as a data URL.
+// If the photo can't be compressed, the original URL is used instead.
+func (a *app) compressPhoto(photoURL string) string {
+	dataURL, err := images.CompressURL(photoURL, a.cfg.ImageMaxDimension)
+	if err != nil {
+		a.log.Warn("Failed to compress photo, sending the original: %v", err)
+		return photoURL
+	}
+	return dataURL
+}
+
+// photoIngredients extracts the ingredients from a photo and from its caption, if it has one,
+// and merges them without duplicates. Only the caption counts if the photo can't be read.
+// It returns the ingredients and how many of them only the caption had.
+func (a *app) photoIngredients(chatID int64, photo tgbotapi.PhotoSize, caption string) ([]string, int) {
+	var fromPhoto, fromCaption []string
+
+	photoURL, err := a.bot.GetFileURL(photo.FileID)
+	if err == nil {
+		fromPhoto, err = a.openaiClient.WithChannel(chatID).ExtractIngredientsFromPhoto(a.compressPhoto(photoURL))
+	}
+	if err != nil {
+		a.log.Error("Failed to extract ingredients from photo: %v", err)
+	}
+
+	if caption = strings.TrimSpace(caption); caption != "" {
+		fromCaption, err = a.openaiClient.WithChannel(chatID).ParseIngredientsFromText(caption)
+		if err != nil {
+			a.log.Error("Failed to parse ingredients from caption: %v", err)
+		}
+	}
+
+	return mergeIngredients(fromPhoto, fromCaption)
+}
+
+// handleAddPhoto handles the /add_photo command
+func (a *app) handleAddPhoto(message *tgbotapi.Message) {
+	chatID := message.Chat.ID
+
+	// Set the chat state to adding photos
+	a.stateManager.SetState(chatID, state.StateAddingPhotos)
+	a.stateManager.ClearData(chatID, "photo_count")
+
+	// If the message already has a photo, process it
+	if message.Photo != nil && len(message.Photo) > 0 {
+		a.stateManager.IncrementData(chatID, "photo_count")
+
+		// Get the largest photo (last in the array)
+		photo := message.Photo[len(message.Photo)-1]
+
+		// Send a processing message
+		processingMsg, _ := a.bot.SendMessage(chatID, "🔍 Processing your photo... This might take a moment.")
+
+		// Extract ingredients from the photo and its caption
+		ingredients, fromCaption := a.photoIngredients(chatID, photo, message.CommandArguments())
+		if len(ingredients) == 0 {
+			a.bot.EditMessage(chatID, processingMsg.MessageID, "😢 Sorry, I couldn't identify any ingredients in your photo. Please try again with a clearer photo.")
+			return
+		}
+
+		// Add ingredients to the fridge
+		for _, ingredient := range ingredients {
+			err := a.fridgeService.AddIngredient(chatID, ingredient, "")
+			if err != nil {
+				a.log.Error("Failed to add ingredient %s: %v", ingredient, err)
+			}
+		}
+
+		// Edit the processing message to show the results
+		a.bot.EditMessage(chatID, processingMsg.MessageID, photoConfirmation(ingredients, fromCaption))
+
+		// Ask if they want to add more photos
+		keyboard := tgbotapi.NewInlineKeyboardMarkup(
+			tgbotapi.NewInlineKeyboardRow(
+				tgbotapi.NewInlineKeyboardButtonData("Done adding photos", "done_adding_photos"),
+			),
+		)
+
+		a.bot.SendMessageWithKeyboard(chatID, "Send more photos of your fridge or pantry, and I'll extract ingredients from them. Press 'Done' when you're finished.", keyboard)
+	} else {
+		// No photo in the command, instruct the user to send photos
+		keyboard := tgbotapi.NewInlineKeyboardMarkup(
+			tgbotapi.NewInlineKeyboardRow(
+				tgbotapi.NewInlineKeyboardButtonData("Cancel", "cancel_adding_photos"),
+			),
+		)
+
+		a.bot.SendMessageWithKeyboard(chatID, "📷 Please send photos of your fridge or pantry, and I'll extract ingredients from them. Send as many photos as you need, and I'll process each one. Press 'Cancel' if you want to stop.", keyboard)
+	}
+}
+
+// handleDoneAddingPhotosCallback handles the button that finishes adding photos
+func (a *app) handleDoneAddingPhotosCallback(callback *tgbotapi.CallbackQuery, _ string) {
+	chatID := callback.Message.Chat.ID
+
+	// Clear the state
+	a.stateManager.ClearState(chatID)
+
+	// Answer the callback
+	a.bot.AnswerCallbackQuery(callback.ID, "Thanks! Your fridge is now updated with ingredients from your photos.")
+
+	// Edit the message to remove the buttons
+	a.bot.EditMessageWithKeyboard(chatID, callback.Message.MessageID, "✅ Photo processing complete! I've added all the ingredients I found to your fridge.", tgbotapi.InlineKeyboardMarkup{})
+
+	// Show fridge contents
+	ingredients, err := a.fridgeService.ListIngredients(chatID)
+	if err != nil {
+		a.log.Error("Failed to list ingredients: %v", err)
+		return
+	}
+
+	if len(ingredients) == 0 {
+		a.bot.SendMessage(chatID, "Your fridge is still empty. Try adding ingredients with text or better photos.")
+		return
+	}
+
+	// Create a formatted message with all ingredients
+	msgText := formatIngredientList("🧊 Here's what's in your fridge:", ingredients, !a.fridgeService.RawQuantities(chatID))
+
+	a.bot.SendMessage(chatID, msgText)
+
+	// Suggest next steps
+	a.bot.SendMessage(chatID, "You can now use /dinner to get dinner suggestions based on your ingredients!")
+}
+
+// handleCancelAddingPhotosCallback handles the button that cancels adding photos
+func (a *app) handleCancelAddingPhotosCallback(callback *tgbotapi.CallbackQuery, _ string) {
+	chatID := callback.Message.Chat.ID
+
+	// Clear the state
+	a.stateManager.ClearState(chatID)
+
+	// Answer the callback
+	a.bot.AnswerCallbackQuery(callback.ID, "Photo adding cancelled.")
+
+	// Edit the message to remove the buttons
+	a.bot.EditMessageWithKeyboard(chatID, callback.Message.MessageID, "Photo adding cancelled. You can use /fridge to see your current ingredients or /dinner to get dinner suggestions.", tgbotapi.InlineKeyboardMarkup{})
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+func TestPhotoAndCaptionBothContribute(t *testing.T) {
+	fromPhoto := []string{"milk", "Eggs", " cheese "}
+	fromCaption := []string{"eggs", "butter", "", "Cheese", "flour"}
+
+	merged, extra := mergeIngredients(fromPhoto, fromCaption)
+	if want := []string{"milk", "Eggs", "cheese", "butter", "flour"}; !reflect.DeepEqual(merged, want) {
+		t.Errorf("mergeIngredients() = %v, want %v", merged, want)
+	}
+	if extra != 2 {
+		t.Errorf("the caption added %d ingredients, want butter and flour", extra)
+	}
+
+	tests := []struct {
+		name        string
+		ingredients []string
+		fromCaption int
+		want        string
+	}{
+		{"both", merged, extra, "✅ I found 3 ingredients in your photo and 2 more in your caption: milk, Eggs, cheese, butter, flour"},
+		{"photo only", []string{"milk"}, 0, "✅ I found 1 ingredients in your photo: milk"},
+		{"caption only", []string{"butter", "flour"}, 2, "✅ I couldn't see anything in your photo, but added the 2 ingredients from your caption: butter, flour"},
+	}
+	for _, tt := range tests {
+		if got := photoConfirmation(tt.ingredients, tt.fromCaption); got != tt.want {
+			t.Errorf("%s: photoConfirmation() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+// photoMessage builds an update with a photo sent to the test chat
+func photoMessage(from *tgbotapi.User) tgbotapi.Update {
+	return tgbotapi.Update{Message: &tgbotapi.Message{
+		From:  from,
+		Chat:  &tgbotapi.Chat{ID: testChatID, Type: "group"},
+		Photo: []tgbotapi.PhotoSize{{FileID: "photo", Width: 800, Height: 600}},
+	}}
+}
+
+func TestPhotoSessionsAreCapped(t *testing.T) {
+	ta := newTestApp(t)
+	ta.cfg.MaxPhotosPerSession = 2
+	anna := testUser(1, "Anna")
+	capped := func() int {
+		count := 0
+		for _, text := range ta.telegram.Texts() {
+			if strings.Contains(text, "You've added the maximum of 2 photos") {
+				count++
+			}
+		}
+		return count
+	}
+
+	ta.handleAddPhoto(command(anna, "/add_photo"))
+	for i := 0; i < 3; i++ {
+		ta.handleUpdate(photoMessage(anna))
+	}
+	if got := capped(); got != 1 {
+		t.Fatalf("refused %d photos, want only the third", got)
+	}
+	if reads := len(ta.telegram.Calls("getFile")); reads != 2 {
+		t.Errorf("read %d photos, want 2", reads)
+	}
+
+	// A new session starts counting again
+	ta.telegram.Reset()
+	ta.handleAddPhoto(command(anna, "/add_photo"))
+	ta.handleUpdate(photoMessage(anna))
+	if got := capped(); got != 0 {
+		t.Errorf("refused %d photos in a new session, want none", got)
+	}
+}
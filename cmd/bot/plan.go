@@ -0,0 +1,112 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/korjavin/whatsfordinner/pkg/dinner"
+	"github.com/korjavin/whatsfordinner/pkg/fridge"
+	"github.com/korjavin/whatsfordinner/pkg/messages"
+	"github.com/korjavin/whatsfordinner/pkg/scheduler"
+)
+
+// weekDays are the days of the week in the order the meal plan is shown, starting on Monday
+var weekDays = []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday, time.Saturday, time.Sunday}
+
+// handlePlan handles the /plan command
+func (a *app) handlePlan(message *tgbotapi.Message) {
+	chatID := message.Chat.ID
+
+	args := strings.Fields(message.CommandArguments())
+	if len(args) == 0 {
+		plan := a.dinnerService.MealPlan(chatID)
+		if len(plan) == 0 {
+			a.bot.SendMessage(chatID, "📅 Nothing is planned yet. Plan a dish with /plan monday Lasagna")
+			return
+		}
+
+		msgText := "📅 This week's plan:\n\n"
+		for _, day := range weekDays {
+			if name, ok := plan[day]; ok {
+				msgText += fmt.Sprintf("• %s: %s\n", day, messages.EscapeMarkdown(name))
+			}
+		}
+		msgText += "\nGet one shopping list for all of it with /shopping_week"
+		a.bot.SendMessage(chatID, msgText)
+		return
+	}
+
+	day, ok := scheduler.ParseWeekday(args[0])
+	if !ok || len(args) < 2 {
+		a.bot.SendMessage(chatID, "🤔 Please tell me a day of the week and a dish, like /plan monday Lasagna")
+		return
+	}
+
+	name := strings.Join(args[1:], " ")
+	if strings.EqualFold(name, "off") {
+		name = ""
+	}
+	if err := a.dinnerService.PlanDish(chatID, day, name); err != nil {
+		a.log.Error("Failed to plan dish: %v", err)
+		a.bot.SendMessage(chatID, "😢 Sorry, I couldn't save that right now. Please try again later.")
+		return
+	}
+
+	if name == "" {
+		a.bot.SendMessage(chatID, fmt.Sprintf("📅 Okay, nothing is planned for %s.", day))
+		return
+	}
+	a.bot.SendMessage(chatID, fmt.Sprintf("📅 Got it! %s is planned for %s.", messages.EscapeMarkdown(name), day))
+}
+
+// handleShoppingWeek handles the /shopping_week command
+func (a *app) handleShoppingWeek(message *tgbotapi.Message) {
+	chatID := message.Chat.ID
+
+	if len(a.dinnerService.MealPlan(chatID)) == 0 {
+		a.bot.SendMessage(chatID, "📅 Nothing is planned for the week yet. Plan dishes with /plan monday Lasagna, then I'll make the shopping list.")
+		return
+	}
+
+	shopping, err := a.dinnerService.AggregateWeeklyShopping(chatID)
+	if err != nil {
+		a.log.Error("Failed to build the weekly shopping list: %v", err)
+		a.bot.SendMessage(chatID, "😢 Sorry, I couldn't build the shopping list right now. Please try again later.")
+		return
+	}
+
+	a.bot.SendMessage(chatID, formatWeeklyShopping(shopping))
+}
+
+// formatWeeklyShopping formats the shopping list for the week's plan with a header above each category
+func formatWeeklyShopping(shopping dinner.WeeklyShopping) string {
+	text := "🛒 You have everything for this week's plan!\n"
+	if len(shopping.Items) > 0 {
+		text = "🛒 Shopping list for this week's plan:\n"
+	}
+
+	category := ""
+	for i, item := range shopping.Items {
+		if i == 0 || item.Category != category {
+			category = item.Category
+			text += "\n" + fridge.CategoryLabel(category) + "\n"
+		}
+		if item.Quantity != "" {
+			text += fmt.Sprintf("• %s (%s)\n", messages.EscapeMarkdown(item.Name), messages.EscapeMarkdown(item.Quantity))
+		} else {
+			text += fmt.Sprintf("• %s\n", messages.EscapeMarkdown(item.Name))
+		}
+	}
+
+	if len(shopping.Unknown) > 0 {
+		names := make([]string, len(shopping.Unknown))
+		for i, name := range shopping.Unknown {
+			names[i] = messages.EscapeMarkdown(name)
+		}
+		text += fmt.Sprintf("\nI don't know the ingredients of %s yet, so they aren't on the list.", strings.Join(names, ", "))
+	}
+
+	return text
+}
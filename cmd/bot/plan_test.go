@@ -0,0 +1,41 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/korjavin/whatsfordinner/pkg/models"
+)
+
+func TestShoppingWeekListsThePlannedDishes(t *testing.T) {
+	ta := newTestApp(t)
+	anna := testUser(1, "Anna")
+	saveDishes(t, ta, models.Dish{Name: "Mac_Cheese", Cuisine: "American", Ingredients: []string{"250g macaroni", "200g cheddar"}})
+
+	ta.handleShoppingWeek(command(anna, "/shopping_week"))
+	if reply := ta.telegram.LastText(); !strings.Contains(reply, "Nothing is planned") {
+		t.Fatalf("/shopping_week without a plan replied %q, want nothing planned", reply)
+	}
+
+	ta.handlePlan(command(anna, "/plan mon Mac_Cheese"))
+	ta.handlePlan(command(anna, "/plan friday mac_cheese"))
+	ta.handlePlan(command(anna, "/plan sunday Grandma's Stew"))
+	ta.handlePlan(command(anna, "/plan"))
+	if reply := ta.telegram.LastText(); !strings.Contains(reply, "Monday: Mac\\_Cheese") || !strings.Contains(reply, "Sunday: Grandma's Stew") {
+		t.Errorf("/plan replied %q, want the planned days", reply)
+	}
+
+	ta.handleShoppingWeek(command(anna, "/shopping_week"))
+	reply := ta.telegram.LastText()
+	for _, want := range []string{"macaroni (500g)", "cheddar (400g)", "ingredients of Grandma's Stew"} {
+		if !strings.Contains(reply, want) {
+			t.Errorf("/shopping_week replied %q, want %q", reply, want)
+		}
+	}
+
+	ta.handlePlan(command(anna, "/plan sunday off"))
+	ta.handleShoppingWeek(command(anna, "/shopping_week"))
+	if reply := ta.telegram.LastText(); strings.Contains(reply, "Stew") {
+		t.Errorf("/shopping_week replied %q after Sunday was cleared", reply)
+	}
+}
@@ -0,0 +1,324 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/korjavin/whatsfordinner/pkg/dinner"
+	"github.com/korjavin/whatsfordinner/pkg/messages"
+	"github.com/korjavin/whatsfordinner/pkg/models"
+	"github.com/korjavin/whatsfordinner/pkg/storage"
+	"github.com/korjavin/whatsfordinner/pkg/telegram"
+)
+
+// ratingKeyboard builds the buttons to rate a dinner from 1 to scale
+// Up to five stars fit in a row, longer scales are shown as numbers in two rows.
+// The thumbs style only has a thumbs down and up, for the lowest and highest rating.
+func ratingKeyboard(dinnerID string, scale int, style string) tgbotapi.InlineKeyboardMarkup {
+	if style == dinner.RatingStyleThumbs {
+		return tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("👎", fmt.Sprintf("rate:%s:%d", dinnerID, dinner.ThumbsRating(false, scale))),
+			tgbotapi.NewInlineKeyboardButtonData("👍", fmt.Sprintf("rate:%s:%d", dinnerID, dinner.ThumbsRating(true, scale))),
+		))
+	}
+
+	var buttons []tgbotapi.InlineKeyboardButton
+	for rating := 1; rating <= scale; rating++ {
+		buttons = append(buttons, tgbotapi.NewInlineKeyboardButtonData(ratingButtonLabel(rating, scale), fmt.Sprintf("rate:%s:%d", dinnerID, rating)))
+	}
+
+	if scale <= 5 {
+		return tgbotapi.NewInlineKeyboardMarkup(buttons)
+	}
+
+	half := (scale + 1) / 2
+	return tgbotapi.NewInlineKeyboardMarkup(buttons[:half], buttons[half:])
+}
+
+// ratingButtonLabel returns the text on the button for a rating
+func ratingButtonLabel(rating, scale int) string {
+	switch {
+	case scale == 2 && rating == 1:
+		return "👎"
+	case scale == 2:
+		return "👍"
+	case scale <= 5:
+		return strings.Repeat("⭐", rating)
+	default:
+		return strconv.Itoa(rating)
+	}
+}
+
+// ratingLabel describes a rating in a sentence, e.g. "4 stars" or "7/10"
+func ratingLabel(rating, scale int, style string) string {
+	switch {
+	case style == dinner.RatingStyleThumbs && rating == dinner.ThumbsRating(true, scale):
+		return "👍"
+	case style == dinner.RatingStyleThumbs && rating == dinner.ThumbsRating(false, scale):
+		return "👎"
+	case scale == 2:
+		return ratingButtonLabel(rating, scale)
+	case scale <= 5 && rating == 1:
+		return "1 star"
+	case scale <= 5:
+		return fmt.Sprintf("%d stars", rating)
+	default:
+		return fmt.Sprintf("%d/%d", rating, scale)
+	}
+}
+
+// handleReaction handles reactions on the "how was dinner" message as an alternative to the rating buttons
+func (a *app) handleReaction(reaction *telegram.MessageReactionUpdated) {
+	// Anonymous admins react on behalf of the chat, we can't attribute that to a person
+	if reaction.User == nil {
+		return
+	}
+
+	chatID := reaction.Chat.ID
+	dinnerID, err := a.dinnerService.GetDinnerIDByRatingMessage(chatID, reaction.MessageID)
+	if err != nil {
+		// Not a rating message
+		return
+	}
+
+	// Use the most recent reaction that expresses a rating
+	rating := 0
+	for _, r := range reaction.NewReaction {
+		if value, ok := dinner.RatingFromReaction(r.Emoji); ok {
+			rating = value
+		}
+	}
+	if rating == 0 {
+		return
+	}
+	rating = dinner.ScaleRating(rating, a.cfg.RatingScale)
+
+	var dinnerEvent models.Dinner
+	err = a.store.Get(dinnerID, &dinnerEvent)
+	if err != nil {
+		a.log.Error("Failed to get dinner event: %v", err)
+		return
+	}
+
+	userID := fmt.Sprintf("%d", reaction.User.ID)
+
+	a.log.Info("User %s rated dinner %s with %d/%d via reaction", userID, dinnerID, rating, a.cfg.RatingScale)
+	err = a.dinnerService.RateDinner(dinnerID, userID, rating, a.cfg.RatingScale)
+	if errors.Is(err, dinner.ErrRatingsClosed) {
+		return
+	}
+	if err != nil {
+		a.log.Error("Failed to rate dinner: %v", err)
+		return
+	}
+
+	a.creditCook(chatID, &dinnerEvent, userID, "", rating)
+}
+
+// creditCook adds a user's rating of a dinner to the cook's stats.
+// dinnerEvent is the dinner as it was before the rating, so a user who changes their rating
+// moves the cook's total by the difference instead of counting the dinner twice.
+func (a *app) creditCook(chatID int64, dinnerEvent *models.Dinner, userID, cookUsername string, rating int) {
+	previous, alreadyRated := dinnerEvent.Ratings[userID]
+	if !alreadyRated {
+		err := a.statsService.UpdateCookStats(chatID, dinnerEvent.Cook, cookUsername, dinner.NormalizeRating(rating, a.cfg.RatingScale))
+		if err != nil {
+			a.log.Error("Failed to update cook stats: %v", err)
+		}
+		return
+	}
+	if previous == rating {
+		return
+	}
+
+	delta := dinner.NormalizeRating(rating, a.cfg.RatingScale) - dinner.NormalizeRating(previous, a.cfg.RatingScale)
+	if _, err := a.statsService.AdjustCookStat(chatID, dinnerEvent.Cook, 0, delta); err != nil {
+		a.log.Error("Failed to update cook stats: %v", err)
+	}
+}
+
+// handleRateCallback handles the dinner rating buttons
+func (a *app) handleRateCallback(callback *tgbotapi.CallbackQuery, payload string) {
+	chatID := callback.Message.Chat.ID
+	userID := fmt.Sprintf("%d", callback.From.ID)
+	username := callback.From.UserName
+	if username == "" {
+		username = callback.From.FirstName
+	}
+
+	// The payload is "dinner:{channelID}:{timestamp}:{rating}", the rating comes after the last colon
+	sep := strings.LastIndex(payload, ":")
+	if sep <= 0 {
+		a.log.Error("Invalid callback data: %s", callback.Data)
+		a.bot.AnswerCallbackQuery(callback.ID, "Something went wrong. Please try again.")
+		return
+	}
+	dinnerID, ratingStr := payload[:sep], payload[sep+1:]
+
+	rating, err := strconv.Atoi(ratingStr)
+	if err != nil || rating < 1 || rating > a.cfg.RatingScale {
+		a.log.Error("Invalid rating: %s", ratingStr)
+		a.bot.AnswerCallbackQuery(callback.ID, "Invalid rating. Please try again.")
+		return
+	}
+	a.log.Info("Looking up dinner with ID: %s for rating: %d", dinnerID, rating)
+
+	// Get the dinner event
+	var dinnerEvent models.Dinner
+	err = a.store.Get(dinnerID, &dinnerEvent)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			a.bot.AnswerCallbackQuery(callback.ID, "This dinner is no longer available.")
+			return
+		}
+		a.log.Error("Failed to get dinner event: %v", err)
+		a.bot.AnswerCallbackQuery(callback.ID, "Something went wrong. Please try again.")
+		return
+	}
+
+	// Add the rating
+	err = a.dinnerService.RateDinner(dinnerID, userID, rating, a.cfg.RatingScale)
+	if err != nil {
+		if errors.Is(err, dinner.ErrInvalidRating) {
+			a.bot.AnswerCallbackQuery(callback.ID, fmt.Sprintf("Please rate from 1 to %d.", a.cfg.RatingScale))
+			return
+		}
+		if errors.Is(err, dinner.ErrRatingsClosed) {
+			a.bot.AnswerCallbackQuery(callback.ID, "Ratings for this dinner are closed.")
+			return
+		}
+		a.log.Error("Failed to rate dinner: %v", err)
+		a.bot.AnswerCallbackQuery(callback.ID, "Something went wrong. Please try again.")
+		return
+	}
+
+	// Update cook statistics, we only know the cook's username if they rated their own dinner
+	cookUsername := ""
+	if dinnerEvent.Cook == userID {
+		cookUsername = username
+	}
+	a.creditCook(chatID, &dinnerEvent, userID, cookUsername, rating)
+
+	// Answer the callback
+	ratingStyle := a.dinnerService.RatingStyle(chatID)
+	a.bot.AnswerCallbackQuery(callback.ID, fmt.Sprintf("Thanks for rating %s!", ratingLabel(rating, a.cfg.RatingScale, ratingStyle)))
+
+	// Edit the message to remove the buttons
+	a.bot.EditMessageWithKeyboard(chatID, callback.Message.MessageID, fmt.Sprintf("Thanks for your feedback! @%s rated tonight's dinner %s.", messages.EscapeMarkdown(username), ratingLabel(rating, a.cfg.RatingScale, ratingStyle)), tgbotapi.InlineKeyboardMarkup{})
+
+	// Update the fridge by removing used ingredients
+	if len(dinnerEvent.Dish.Ingredients) > 0 {
+		// Ask if they want to update the fridge
+		a.log.Info("Creating update fridge buttons for dinner ID: %s", dinnerID)
+
+		// Create callback data for update fridge
+		updateFridgeCallback := fmt.Sprintf("update_fridge:%s", dinnerID)
+		a.log.Info("Update fridge callback: %s", updateFridgeCallback)
+
+		keyboard := tgbotapi.NewInlineKeyboardMarkup(
+			tgbotapi.NewInlineKeyboardRow(
+				tgbotapi.NewInlineKeyboardButtonData("Yes, update fridge", updateFridgeCallback),
+				tgbotapi.NewInlineKeyboardButtonData("No, keep as is", "skip_update_fridge"),
+			),
+		)
+
+		a.bot.SendMessageWithKeyboard(chatID, "Would you like to update your fridge by removing the ingredients used for this dinner?", keyboard)
+	}
+}
+
+// handleReopenRating handles the /reopen_rating command
+func (a *app) handleReopenRating(message *tgbotapi.Message) {
+	chatID := message.Chat.ID
+	if !a.requireAdmin(message) {
+		return
+	}
+
+	loc := a.channelLocation(chatID)
+	dinners, err := a.dinnerService.FindDinners(chatID, message.CommandArguments(), loc)
+	if err != nil {
+		a.log.Error("Failed to find dinners: %v", err)
+		a.bot.SendMessage(chatID, "😢 Sorry, I couldn't look up your dinners right now. Please try again later.")
+		return
+	}
+
+	if len(dinners) == 0 {
+		a.bot.SendMessage(chatID, "🤔 I couldn't find that dinner. Use /reopen_rating last, a date like /reopen_rating 2024-06-01 or a dinner ID.")
+		return
+	}
+	if len(dinners) > 1 {
+		msgText := "📅 There were several dinners that day. Which one did you mean?\n\n"
+		for _, d := range dinners {
+			msgText += fmt.Sprintf("• %s on %s: /reopen_rating %s\n", messages.EscapeMarkdown(d.Dish.Name), messages.FormatTime(d.StartedAt, loc), shortDinnerID(d))
+		}
+		a.bot.SendMessage(chatID, msgText)
+		return
+	}
+
+	reopened, err := a.dinnerService.ReopenRatings(chatID, dinners[0].ID, time.Now())
+	if err != nil {
+		switch {
+		case errors.Is(err, dinner.ErrRatingsOpen):
+			a.bot.SendMessage(chatID, fmt.Sprintf("⭐ Ratings for %s are still open, just tap a rating.", messages.EscapeMarkdown(dinners[0].Dish.Name)))
+		case errors.Is(err, dinner.ErrTooOldToReopen):
+			a.bot.SendMessage(chatID, fmt.Sprintf("⏳ %s was more than %d days ago, that's too late to rate it.", messages.EscapeMarkdown(dinners[0].Dish.Name), int(dinner.MaxReopenAge.Hours()/24)))
+		default:
+			a.log.Error("Failed to reopen ratings: %v", err)
+			a.bot.SendMessage(chatID, "😢 Sorry, I couldn't reopen the ratings right now. Please try again later.")
+		}
+		return
+	}
+
+	// New ratings update the cook's stats like any other rating
+	ratingMsg, err := a.bot.SendMessageWithKeyboard(chatID, fmt.Sprintf("⭐ Ratings for %s are open again for %d hours. Tap a rating or react to this message!", messages.EscapeMarkdown(reopened.Dish.Name), int(dinner.RatingWindow.Hours())), ratingKeyboard(reopened.ID, a.cfg.RatingScale, a.dinnerService.RatingStyle(chatID)))
+	if err != nil {
+		a.log.Error("Failed to send rating message: %v", err)
+		return
+	}
+
+	// Remember the rating message so reactions on it count as ratings
+	err = a.dinnerService.SetRatingMessage(chatID, ratingMsg.MessageID, reopened.ID)
+	if err != nil {
+		a.log.Error("Failed to save rating message: %v", err)
+	}
+}
+
+// handleRatingStyle handles the /rating_style command
+func (a *app) handleRatingStyle(message *tgbotapi.Message) {
+	chatID := message.Chat.ID
+
+	style := strings.ToLower(strings.TrimSpace(message.CommandArguments()))
+	switch style {
+	case "":
+		if a.dinnerService.RatingStyle(chatID) == dinner.RatingStyleThumbs {
+			a.bot.SendMessage(chatID, "👍 Dinners are rated with a thumbs up or down. Use /rating_style stars to rate with stars.")
+		} else {
+			a.bot.SendMessage(chatID, "⭐ Dinners are rated with stars. Use /rating_style thumbs for a quick thumbs up or down.")
+		}
+		return
+	case dinner.RatingStyleStars, dinner.RatingStyleThumbs:
+	default:
+		a.bot.SendMessage(chatID, "🤔 Please use /rating_style stars or /rating_style thumbs")
+		return
+	}
+
+	if !a.requireAdmin(message) {
+		return
+	}
+
+	err := a.dinnerService.SetRatingStyle(chatID, style)
+	if err != nil {
+		a.log.Error("Failed to save rating style: %v", err)
+		a.bot.SendMessage(chatID, "😢 Sorry, I couldn't save the rating style. Please try again later.")
+		return
+	}
+
+	if style == dinner.RatingStyleThumbs {
+		a.bot.SendMessage(chatID, fmt.Sprintf("👍 Got it! Dinners are rated with a thumbs up or down from now on, which count as %d and 1.", dinner.ThumbsRating(true, a.cfg.RatingScale)))
+	} else {
+		a.bot.SendMessage(chatID, "⭐ Got it! Dinners are rated with stars from now on.")
+	}
+}
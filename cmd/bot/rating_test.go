@@ -0,0 +1,137 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/korjavin/whatsfordinner/pkg/dinner"
+	"github.com/korjavin/whatsfordinner/pkg/models"
+	"github.com/korjavin/whatsfordinner/pkg/telegram"
+)
+
+// finishedDinner creates a finished dinner cooked by user 1, whose "how was dinner" message is message 50
+func finishedDinner(t *testing.T, ta *testApp) *models.Dinner {
+	t.Helper()
+
+	dinner, err := ta.dinnerService.CreateDinner(testChatID, models.Dish{Name: "Lasagna"}, "1")
+	if err != nil {
+		t.Fatalf("CreateDinner failed: %v", err)
+	}
+	if err := ta.dinnerService.FinishDinner(testChatID); err != nil {
+		t.Fatalf("FinishDinner failed: %v", err)
+	}
+	if err := ta.dinnerService.SetRatingMessage(testChatID, 50, dinner.ID); err != nil {
+		t.Fatalf("SetRatingMessage failed: %v", err)
+	}
+	return dinner
+}
+
+// reaction builds the update Telegram sends when a user reacts to a message in the test chat
+func reaction(userID int64, messageID int, emojis ...string) *telegram.MessageReactionUpdated {
+	update := &telegram.MessageReactionUpdated{
+		Chat:      tgbotapi.Chat{ID: testChatID, Type: "group"},
+		MessageID: messageID,
+		User:      testUser(userID, "User"),
+	}
+	for _, emoji := range emojis {
+		update.NewReaction = append(update.NewReaction, telegram.ReactionType{Type: "emoji", Emoji: emoji})
+	}
+	return update
+}
+
+func TestReactionRatesDinner(t *testing.T) {
+	ta := newTestApp(t)
+	dinner := finishedDinner(t, ta)
+
+	ta.handleReaction(reaction(2, 50, "👍"))
+	ta.handleReaction(reaction(3, 50, "🙏", "👎"))
+	// Reactions on other messages and reactions without an opinion aren't ratings
+	ta.handleReaction(reaction(4, 51, "👍"))
+	ta.handleReaction(reaction(5, 50, "🙏"))
+
+	var got models.Dinner
+	if err := ta.store.Get(dinner.ID, &got); err != nil {
+		t.Fatalf("failed to get dinner: %v", err)
+	}
+	if len(got.Ratings) != 2 || got.Ratings["2"] != 5 || got.Ratings["3"] != 1 {
+		t.Fatalf("ratings = %v, want 5 from user 2 and 1 from user 3", got.Ratings)
+	}
+
+	// Changing the reaction changes the rating
+	ta.handleReaction(reaction(2, 50, "🤔"))
+	if err := ta.store.Get(dinner.ID, &got); err != nil {
+		t.Fatalf("failed to get dinner: %v", err)
+	}
+	if got.Ratings["2"] != 3 || got.AverageRating != 2 {
+		t.Errorf("ratings = %v with average %v after changing a reaction, want 3 from user 2 and an average of 2", got.Ratings, got.AverageRating)
+	}
+
+	// The cook's stats follow the changed rating instead of counting it again
+	stats, err := ta.statsService.GetStatistics(testChatID)
+	if err != nil {
+		t.Fatalf("GetStatistics failed: %v", err)
+	}
+	if cook := stats.CookStats["1"]; cook.CookCount != 2 || cook.TotalRating != 4 || cook.AvgRating != 2 {
+		t.Errorf("cook stats = %+v, want 2 ratings adding up to 4", cook)
+	}
+}
+
+func TestReopenRatingAcceptsNewRatings(t *testing.T) {
+	ta := newTestApp(t)
+	finished := finishedDinner(t, ta)
+	press(ta.handleRateCallback, callback(testUser(2, "Ben"), 50, "rate:"+finished.ID+":4"))
+	if _, err := ta.dinnerService.FinalizeDueRatings(testChatID, time.Now().Add(dinner.RatingWindow+time.Hour)); err != nil {
+		t.Fatalf("FinalizeDueRatings failed: %v", err)
+	}
+
+	late := testUser(3, "Cleo")
+	press(ta.handleRateCallback, callback(late, 50, "rate:"+finished.ID+":2"))
+	if answers := ta.telegram.Calls("answerCallbackQuery"); answers[len(answers)-1].Params.Get("text") != "Ratings for this dinner are closed." {
+		t.Fatalf("late rating was answered with %q, want ratings closed", answers[len(answers)-1].Params.Get("text"))
+	}
+
+	admin := testUser(1, "Anna")
+	ta.telegram.SetAdmin(admin.ID)
+	ta.handleReopenRating(command(admin, "/reopen_rating last"))
+	press(ta.handleRateCallback, callback(late, 60, "rate:"+finished.ID+":2"))
+
+	var got models.Dinner
+	if err := ta.store.Get(finished.ID, &got); err != nil {
+		t.Fatalf("failed to get dinner: %v", err)
+	}
+	if got.RatingsFinalized || len(got.Ratings) != 2 || got.Ratings["3"] != 2 || got.AverageRating != 3 {
+		t.Errorf("dinner has ratings %v with average %v (finalized %v), want Cleo's 2 added for an average of 3", got.Ratings, got.AverageRating, got.RatingsFinalized)
+	}
+}
+
+func TestThumbsRecordNumericRatings(t *testing.T) {
+	ta := newTestApp(t)
+	finished := finishedDinner(t, ta)
+	admin := testUser(1, "Anna")
+	ta.telegram.SetAdmin(admin.ID)
+	ta.handleRatingStyle(command(admin, "/rating_style thumbs"))
+	if style := ta.dinnerService.RatingStyle(testChatID); style != dinner.RatingStyleThumbs {
+		t.Fatalf("rating style = %q, want thumbs", style)
+	}
+
+	keyboard := ratingKeyboard(finished.ID, ta.cfg.RatingScale, dinner.RatingStyleThumbs)
+	if len(keyboard.InlineKeyboard) != 1 || len(keyboard.InlineKeyboard[0]) != 2 {
+		t.Fatalf("thumbs keyboard = %+v, want a single row with two buttons", keyboard.InlineKeyboard)
+	}
+	down, up := keyboard.InlineKeyboard[0][0], keyboard.InlineKeyboard[0][1]
+	if down.Text != "👎" || up.Text != "👍" {
+		t.Fatalf("buttons are %q and %q, want 👎 and 👍", down.Text, up.Text)
+	}
+
+	press(ta.handleRateCallback, callback(testUser(2, "Ben"), 50, *up.CallbackData))
+	press(ta.handleRateCallback, callback(testUser(3, "Cleo"), 50, *down.CallbackData))
+
+	var got models.Dinner
+	if err := ta.store.Get(finished.ID, &got); err != nil {
+		t.Fatalf("failed to get dinner: %v", err)
+	}
+	if got.Ratings["2"] != 5 || got.Ratings["3"] != 1 || got.AverageRating != 3 {
+		t.Errorf("ratings = %v with average %v, want 5 for thumbs up and 1 for thumbs down", got.Ratings, got.AverageRating)
+	}
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/korjavin/whatsfordinner/pkg/poll"
+	"github.com/korjavin/whatsfordinner/pkg/telegram"
+)
+
+// handleReopen handles the /reopen command
+func (a *app) handleReopen(message *tgbotapi.Message) {
+	chatID := message.Chat.ID
+	if !a.requireAdmin(message) {
+		return
+	}
+
+	vote, err := a.pollService.ReopenVote(chatID)
+	if err != nil {
+		switch {
+		case errors.Is(err, poll.ErrNoVote):
+			a.bot.SendMessage(chatID, "🤔 There's no poll to reopen. Start one with /dinner.")
+		case errors.Is(err, poll.ErrDinnerStarted):
+			a.bot.SendMessage(chatID, "🍳 That dinner is already being cooked, so the poll can't be reopened.")
+		default:
+			a.log.Error("Failed to reopen vote: %v", err)
+			a.bot.SendMessage(chatID, "😢 Sorry, I couldn't reopen the poll right now. Please try again later.")
+		}
+		return
+	}
+
+	// Point everyone back to the original poll
+	_, err = a.bot.SendReply(chatID, vote.MessageID, fmt.Sprintf("↩️ Voting is open again! %d votes so far, keep voting on this poll.", len(vote.Votes)))
+	if err == nil {
+		return
+	}
+	if !errors.Is(err, telegram.ErrMessageNotFound) {
+		a.log.Error("Failed to send reopen message: %v", err)
+		return
+	}
+
+	// The original poll is gone, so start a new one with the same options
+	a.log.Warn("Original poll message %d is gone, creating a new poll", vote.MessageID)
+	pollMsg, err := a.bot.CreatePoll(chatID, a.pollService.GetPollQuestion(chatID), vote.Options)
+	if err != nil {
+		a.log.Error("Failed to create poll: %v", err)
+		a.bot.SendMessage(chatID, "😢 Sorry, I couldn't recreate the poll. Please start a new one with /dinner.")
+		return
+	}
+
+	vote, err = a.pollService.ReplacePoll(chatID, vote.PollID, pollMsg.Poll.ID, pollMsg.MessageID)
+	if err != nil {
+		a.log.Error("Failed to move the vote to the new poll: %v", err)
+		a.bot.SendMessage(chatID, "😢 Sorry, I couldn't set up the new poll. Please start a new one with /dinner.")
+		return
+	}
+
+	a.bot.SendMessage(chatID, fmt.Sprintf("↩️ Voting is open again! The old poll is gone, so please use the new one above. The %d votes so far still count.", len(vote.Votes)))
+}
+
+// handleUndoCloseCallback handles reopening a poll right after it closed
+func (a *app) handleUndoCloseCallback(callback *tgbotapi.CallbackQuery, pollID string) {
+	chatID := callback.Message.Chat.ID
+
+	if pollID == "" {
+		a.log.Error("Invalid callback data: %s", callback.Data)
+		a.bot.AnswerCallbackQuery(callback.ID, "Something went wrong. Please try again.")
+		return
+	}
+
+	vote, err := a.pollService.UndoClose(chatID, pollID, time.Now())
+	if err != nil {
+		switch {
+		case errors.Is(err, poll.ErrUndoExpired), errors.Is(err, poll.ErrNoVote):
+			a.bot.AnswerCallbackQuery(callback.ID, fmt.Sprintf("It's been more than %d minutes, ask an admin to /reopen the poll.", int(poll.UndoCloseWindow.Minutes())))
+		case errors.Is(err, poll.ErrDinnerStarted):
+			a.bot.AnswerCallbackQuery(callback.ID, "That dinner is already being cooked.")
+		default:
+			a.log.Error("Failed to undo poll close: %v", err)
+			a.bot.AnswerCallbackQuery(callback.ID, "Something went wrong. Please try again.")
+		}
+		a.bot.EditMessageKeyboard(chatID, callback.Message.MessageID, tgbotapi.InlineKeyboardMarkup{InlineKeyboard: [][]tgbotapi.InlineKeyboardButton{}})
+		return
+	}
+
+	a.bot.AnswerCallbackQuery(callback.ID, "Voting is open again!")
+	a.bot.EditMessageKeyboard(chatID, callback.Message.MessageID, tgbotapi.InlineKeyboardMarkup{InlineKeyboard: [][]tgbotapi.InlineKeyboardButton{}})
+
+	// Point everyone back to the original poll
+	a.bot.SendReply(chatID, vote.MessageID, fmt.Sprintf("↩️ Voting is open again! %d votes so far, keep voting on this poll. It closes once everyone has voted.", len(vote.Votes)))
+}
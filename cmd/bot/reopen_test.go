@@ -0,0 +1,113 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/korjavin/whatsfordinner/pkg/poll"
+)
+
+// closedVote creates a vote with two votes for Pasta and closes it
+func closedVote(t *testing.T, ta *testApp) {
+	t.Helper()
+
+	if _, err := ta.pollService.CreateVote(testChatID, "old-poll", 7, []string{"Pasta", "Soup"}); err != nil {
+		t.Fatalf("CreateVote failed: %v", err)
+	}
+	if err := ta.pollService.SetVoteTags(testChatID, "old-poll", []string{"quick"}); err != nil {
+		t.Fatalf("SetVoteTags failed: %v", err)
+	}
+	for _, userID := range []string{"1", "2"} {
+		if err := ta.pollService.RecordVote(testChatID, "old-poll", userID, "", "Pasta"); err != nil {
+			t.Fatalf("RecordVote failed: %v", err)
+		}
+	}
+	if _, err := ta.schedulerService.CloseVote(testChatID, "old-poll", time.Now()); err != nil {
+		t.Fatalf("CloseVote failed: %v", err)
+	}
+}
+
+func TestReopenRestoresVote(t *testing.T) {
+	ta := newTestApp(t)
+	closedVote(t, ta)
+	admin := testUser(1, "Anna")
+	ta.telegram.SetAdmin(admin.ID)
+
+	ta.handleReopen(command(admin, "/reopen"))
+
+	vote, err := ta.pollService.GetCurrentVote(testChatID)
+	if err != nil {
+		t.Fatalf("GetCurrentVote failed: %v", err)
+	}
+	if vote.PollID != "old-poll" || !vote.EndedAt.IsZero() || vote.WinningDish != "" || vote.ReopenedAt.IsZero() {
+		t.Errorf("current vote = %+v, want old-poll open again without a winner", *vote)
+	}
+	if len(vote.Votes) != 2 || len(vote.Tags) != 1 {
+		t.Errorf("votes %v with tags %v, want both votes and the tag kept", vote.Votes, vote.Tags)
+	}
+
+	// The poll message is still there, so everyone is pointed back to it
+	if polls := ta.telegram.Calls("sendPoll"); len(polls) != 0 {
+		t.Errorf("sent %d new polls, want none", len(polls))
+	}
+	sent := ta.telegram.Calls("sendMessage")
+	if len(sent) == 0 || sent[len(sent)-1].Params.Get("reply_to_message_id") != "7" {
+		t.Errorf("reopen message doesn't reply to the poll message")
+	}
+}
+
+func TestReopenRecreatesDeletedPoll(t *testing.T) {
+	ta := newTestApp(t)
+	closedVote(t, ta)
+	admin := testUser(1, "Anna")
+	ta.telegram.SetAdmin(admin.ID)
+	ta.telegram.Fail("sendMessage", http.StatusBadRequest, "Bad Request: message to be replied not found")
+
+	ta.handleReopen(command(admin, "/reopen"))
+
+	if polls := ta.telegram.Calls("sendPoll"); len(polls) != 1 {
+		t.Fatalf("sent %d new polls, want 1", len(polls))
+	}
+	vote, err := ta.pollService.GetCurrentVote(testChatID)
+	if err != nil {
+		t.Fatalf("GetCurrentVote failed: %v", err)
+	}
+	if vote.PollID == "old-poll" || !vote.EndedAt.IsZero() {
+		t.Fatalf("current vote = %+v, want an open vote on the new poll", *vote)
+	}
+	if len(vote.Votes) != 2 || len(vote.Tags) != 1 || vote.ReopenedAt.IsZero() {
+		t.Errorf("new vote has votes %v and tags %v, want both carried over from the reopened vote", vote.Votes, vote.Tags)
+	}
+
+	// The old poll is done
+	old, err := ta.pollService.GetVote(testChatID, "old-poll")
+	if err != nil {
+		t.Fatalf("GetVote failed: %v", err)
+	}
+	if old.EndedAt.IsZero() {
+		t.Error("old vote is still open, want it ended")
+	}
+	if err := ta.pollService.RecordVote(testChatID, "old-poll", "3", "", "Soup"); !errors.Is(err, poll.ErrVoteEnded) {
+		t.Errorf("RecordVote on the old poll returned %v, want ErrVoteEnded", err)
+	}
+}
+
+func TestReopenKeepsPollOnOtherSendErrors(t *testing.T) {
+	ta := newTestApp(t)
+	closedVote(t, ta)
+	admin := testUser(1, "Anna")
+	ta.telegram.SetAdmin(admin.ID)
+	ta.telegram.Fail("sendMessage", http.StatusInternalServerError, "Internal Server Error")
+
+	ta.handleReopen(command(admin, "/reopen"))
+
+	if polls := ta.telegram.Calls("sendPoll"); len(polls) != 0 {
+		t.Errorf("sent %d new polls after a server error, want none", len(polls))
+	}
+	vote, err := ta.pollService.GetCurrentVote(testChatID)
+	if err != nil || vote.PollID != "old-poll" {
+		t.Errorf("current vote = %v (%v), want old-poll", vote, err)
+	}
+}
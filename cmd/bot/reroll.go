@@ -0,0 +1,62 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/korjavin/whatsfordinner/pkg/poll"
+	"github.com/korjavin/whatsfordinner/pkg/storage"
+)
+
+// rerollKeyboard builds the button that replaces a dinner poll with new suggestions
+func rerollKeyboard(pollID string, left int) tgbotapi.InlineKeyboardMarkup {
+	return tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("🎲 New suggestions (%d left)", left), "reroll:"+pollID),
+		),
+	)
+}
+
+// withoutRejected drops suggestions whose name is among the rejected dishes
+func withoutRejected(suggestions []map[string]interface{}, rejected []string) []map[string]interface{} {
+	if len(rejected) == 0 {
+		return suggestions
+	}
+
+	kept := make([]map[string]interface{}, 0, len(suggestions))
+	for _, suggestion := range suggestions {
+		name, _ := suggestion["name"].(string)
+		if poll.IsRejected(rejected, name) {
+			continue
+		}
+		kept = append(kept, suggestion)
+	}
+	return kept
+}
+
+// handleRerollCallback handles re-rolling a dinner poll nobody likes
+func (a *app) handleRerollCallback(callback *tgbotapi.CallbackQuery, pollID string) {
+	chatID := callback.Message.Chat.ID
+
+	vote, err := a.pollService.RerollVote(chatID, pollID)
+	if err != nil {
+		switch {
+		case errors.Is(err, poll.ErrRerollLimit):
+			a.bot.AnswerCallbackQuery(callback.ID, fmt.Sprintf("This poll was already re-rolled %d times, please pick one of the options.", poll.MaxRerolls))
+		case errors.Is(err, poll.ErrVoteEnded), errors.Is(err, storage.ErrNotFound):
+			a.bot.AnswerCallbackQuery(callback.ID, "This poll has already ended.")
+		default:
+			a.log.Error("Failed to re-roll vote: %v", err)
+			a.bot.AnswerCallbackQuery(callback.ID, "Something went wrong. Please try again.")
+		}
+		return
+	}
+
+	a.bot.AnswerCallbackQuery(callback.ID, "Looking for something else!")
+	a.bot.StopPoll(chatID, vote.MessageID)
+
+	a.bot.EditMessageWithKeyboard(chatID, callback.Message.MessageID, "🎲 Nobody liked those options, let me find something else...", tgbotapi.InlineKeyboardMarkup{})
+
+	a.startDinner(chatID, vote.Tags)
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRerollExcludesRejectedDishes(t *testing.T) {
+	ta := newTestApp(t)
+	stockFridge(t, ta, "pasta", "tomatoes")
+	ta.openai.SetReplies(
+		`[{"name": "Pasta", "cuisine": "Italian", "description": "Quick"}, {"name": "Soup", "cuisine": "Italian", "description": "Warm"}]`,
+		// The AI suggests the soup again, it's dropped anyway
+		`[{"name": "soup", "cuisine": "Italian", "description": "Warm"}, {"name": "Curry", "cuisine": "Indian", "description": "Spicy"}, {"name": "Tacos", "cuisine": "Mexican", "description": "Fun"}]`,
+	)
+
+	ta.startDinner(testChatID, nil)
+	vote, err := ta.pollService.GetLastVote(testChatID)
+	if err != nil {
+		t.Fatalf("GetLastVote failed: %v", err)
+	}
+
+	press(ta.handleRerollCallback, callback(testUser(1, "Anna"), 3, "reroll:"+vote.PollID))
+
+	var rerollPrompt string
+	for _, prompt := range ta.openai.Prompts() {
+		if strings.Contains(prompt, "turned these dishes down") {
+			rerollPrompt = prompt
+		}
+	}
+	if !strings.Contains(rerollPrompt, "Pasta, Soup") {
+		t.Errorf("the re-roll prompt doesn't exclude the rejected dishes: %q", rerollPrompt)
+	}
+
+	polls := ta.telegram.Calls("sendPoll")
+	if len(polls) != 2 {
+		t.Fatalf("sent %d polls, want the re-rolled one too", len(polls))
+	}
+	options := polls[1].Params.Get("options")
+	if strings.Contains(strings.ToLower(options), "soup") || strings.Contains(options, "Pasta") {
+		t.Errorf("re-rolled poll options = %s, want the rejected dishes left out", options)
+	}
+	if !strings.Contains(options, "Curry") || !strings.Contains(options, "Tacos") {
+		t.Errorf("re-rolled poll options = %s, want the new suggestions", options)
+	}
+}
@@ -0,0 +1,241 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/korjavin/whatsfordinner/pkg/messages"
+	"github.com/korjavin/whatsfordinner/pkg/models"
+	"github.com/korjavin/whatsfordinner/pkg/scheduler"
+)
+
+// handleSchedule handles the /schedule command
+func (a *app) handleSchedule(message *tgbotapi.Message) {
+	chatID := message.Chat.ID
+
+	args := strings.TrimSpace(message.CommandArguments())
+	if args == "" {
+		// List the scheduled dinners
+		scheduled, err := a.schedulerService.ListScheduledDinners(chatID)
+		if err != nil {
+			a.log.Error("Failed to list scheduled dinners: %v", err)
+			a.bot.SendMessage(chatID, "😢 Sorry, I couldn't retrieve the scheduled dinners right now. Please try again later.")
+			return
+		}
+
+		if len(scheduled) == 0 {
+			a.bot.SendMessage(chatID, "📅 No dinners are scheduled. Schedule one with /schedule 2024-06-01 18:00")
+			return
+		}
+
+		msgText := "📅 Scheduled dinner polls:\n\n"
+		for i, dinner := range scheduled {
+			msgText += fmt.Sprintf("%d. %s\n", i+1, messages.FormatTime(dinner.At, a.channelLocation(chatID)))
+		}
+		msgText += "\nCancel one with /unschedule <number>"
+		a.bot.SendMessage(chatID, msgText)
+		return
+	}
+
+	at, err := a.schedulerService.ParseScheduleTime(chatID, args)
+	if err != nil {
+		a.bot.SendMessage(chatID, "🤔 I couldn't understand that time. Please use the format /schedule 2024-06-01 18:00")
+		return
+	}
+
+	if !at.After(time.Now()) {
+		a.bot.SendMessage(chatID, "🤔 That time is in the past. Please pick a time in the future.")
+		return
+	}
+
+	_, err = a.schedulerService.ScheduleDinner(chatID, at, fmt.Sprintf("%d", message.From.ID))
+	if errors.Is(err, scheduler.ErrTooManyScheduled) {
+		a.bot.SendMessage(chatID, fmt.Sprintf("📅 You already have %d dinners scheduled. Cancel one with /unschedule first.", scheduler.MaxScheduledDinners))
+		return
+	}
+	if err != nil {
+		a.log.Error("Failed to schedule dinner: %v", err)
+		a.bot.SendMessage(chatID, "😢 Sorry, I couldn't schedule the dinner right now. Please try again later.")
+		return
+	}
+
+	a.bot.SendMessage(chatID, fmt.Sprintf("📅 Got it! I'll start the dinner poll on %s.", messages.FormatTime(at, a.channelLocation(chatID))))
+}
+
+// handleUnschedule handles the /unschedule command
+func (a *app) handleUnschedule(message *tgbotapi.Message) {
+	chatID := message.Chat.ID
+
+	scheduled, err := a.schedulerService.ListScheduledDinners(chatID)
+	if err != nil {
+		a.log.Error("Failed to list scheduled dinners: %v", err)
+		a.bot.SendMessage(chatID, "😢 Sorry, I couldn't retrieve the scheduled dinners right now. Please try again later.")
+		return
+	}
+
+	number, err := strconv.Atoi(strings.TrimSpace(message.CommandArguments()))
+	if err != nil || number < 1 || number > len(scheduled) {
+		a.bot.SendMessage(chatID, "🤔 Please give the number of the dinner to cancel, as shown by /schedule.")
+		return
+	}
+
+	dinner := scheduled[number-1]
+	err = a.schedulerService.CancelScheduledDinner(dinner.ID)
+	if err != nil {
+		a.log.Error("Failed to cancel scheduled dinner: %v", err)
+		a.bot.SendMessage(chatID, "😢 Sorry, I couldn't cancel the scheduled dinner right now. Please try again later.")
+		return
+	}
+
+	a.bot.SendMessage(chatID, fmt.Sprintf("🗑️ Cancelled the dinner poll scheduled for %s.", messages.FormatTime(dinner.At, a.channelLocation(chatID))))
+}
+
+// handleTimezone handles the /timezone command
+func (a *app) handleTimezone(message *tgbotapi.Message) {
+	chatID := message.Chat.ID
+
+	timezone := strings.TrimSpace(message.CommandArguments())
+	if timezone == "" {
+		a.bot.SendMessage(chatID, fmt.Sprintf("🕒 This chat uses the %s time zone. Change it with /timezone Europe/Berlin", a.channelLocation(chatID)))
+		return
+	}
+
+	err := a.schedulerService.SetTimezone(chatID, timezone)
+	if err != nil {
+		a.bot.SendMessage(chatID, "🤔 I don't know that time zone. Please use a name like Europe/Berlin or America/New_York.")
+		return
+	}
+
+	a.bot.SendMessage(chatID, fmt.Sprintf("🕒 Got it! This chat now uses the %s time zone.", timezone))
+}
+
+// handleShoppingDay handles the /shopping_day command
+func (a *app) handleShoppingDay(message *tgbotapi.Message) {
+	chatID := message.Chat.ID
+
+	args := strings.TrimSpace(message.CommandArguments())
+	if args == "" {
+		var channelState models.ChannelState
+		a.store.Get(fmt.Sprintf("channel:%d", chatID), &channelState)
+		if channelState.ShoppingDay == nil {
+			a.bot.SendMessage(chatID, "🛒 You don't have a shopping day yet. Set one with /shopping_day saturday and I'll post what's running out the evening before.")
+			return
+		}
+		a.bot.SendMessage(chatID, fmt.Sprintf("🛒 Your shopping day is %s. Change it with /shopping_day <day> or turn the reminder off with /shopping_day off.", *channelState.ShoppingDay))
+		return
+	}
+
+	if strings.EqualFold(args, "off") {
+		if err := a.schedulerService.SetShoppingDay(chatID, nil); err != nil {
+			a.log.Error("Failed to clear shopping day: %v", err)
+			a.bot.SendMessage(chatID, "😢 Sorry, I couldn't save that right now. Please try again later.")
+			return
+		}
+		a.bot.SendMessage(chatID, "🛒 Okay, no more shopping day reminders.")
+		return
+	}
+
+	day, ok := scheduler.ParseWeekday(args)
+	if !ok {
+		a.bot.SendMessage(chatID, "🤔 I don't know that day. Please use a day of the week, like /shopping_day saturday")
+		return
+	}
+
+	if err := a.schedulerService.SetShoppingDay(chatID, &day); err != nil {
+		a.log.Error("Failed to set shopping day: %v", err)
+		a.bot.SendMessage(chatID, "😢 Sorry, I couldn't save that right now. Please try again later.")
+		return
+	}
+	a.bot.SendMessage(chatID, fmt.Sprintf("🛒 Got it! Every %s evening I'll post what's running out, so you can plan %s's shopping.", (day+6)%7, day))
+}
+
+// handlePlanNudge handles the /plan_nudge command
+func (a *app) handlePlanNudge(message *tgbotapi.Message) {
+	chatID := message.Chat.ID
+
+	args := strings.Fields(message.CommandArguments())
+	if len(args) == 0 {
+		nudge := a.schedulerService.PlanNudge(chatID)
+		if nudge.Off {
+			a.bot.SendMessage(chatID, "📅 I don't remind you to plan the next week. Turn the reminder on with /plan_nudge saturday 10")
+			return
+		}
+		a.bot.SendMessage(chatID, fmt.Sprintf("📅 I remind you to plan the next week every %s at %d:00. Change it with /plan_nudge <day> [hour] or turn it off with /plan_nudge off.", nudge.Day, nudge.Hour))
+		return
+	}
+
+	if strings.EqualFold(args[0], "off") {
+		if err := a.schedulerService.SetPlanNudge(chatID, &models.PlanNudge{Off: true}); err != nil {
+			a.log.Error("Failed to turn off the plan nudge: %v", err)
+			a.bot.SendMessage(chatID, "😢 Sorry, I couldn't save that right now. Please try again later.")
+			return
+		}
+		a.bot.SendMessage(chatID, "📅 Okay, no more reminders to plan the next week.")
+		return
+	}
+
+	day, ok := scheduler.ParseWeekday(args[0])
+	if !ok || len(args) > 2 {
+		a.bot.SendMessage(chatID, "🤔 Please tell me a day of the week and optionally an hour, like /plan_nudge saturday 10")
+		return
+	}
+	nudge := models.PlanNudge{Day: day, Hour: models.DefaultPlanNudge.Hour}
+	if len(args) == 2 {
+		hour, err := strconv.Atoi(args[1])
+		if err != nil || hour < 0 || hour > 23 {
+			a.bot.SendMessage(chatID, "🤔 The hour must be a number from 0 to 23, like /plan_nudge saturday 10")
+			return
+		}
+		nudge.Hour = hour
+	}
+
+	if err := a.schedulerService.SetPlanNudge(chatID, &nudge); err != nil {
+		a.log.Error("Failed to set the plan nudge: %v", err)
+		a.bot.SendMessage(chatID, "😢 Sorry, I couldn't save that right now. Please try again later.")
+		return
+	}
+	a.bot.SendMessage(chatID, fmt.Sprintf("📅 Got it! Every %s at %d:00 I'll remind you to plan the next week.", nudge.Day, nudge.Hour))
+}
+
+// handlePlanWeekCallback handles the button on the weekly planning reminder
+func (a *app) handlePlanWeekCallback(callback *tgbotapi.CallbackQuery, _ string) {
+	chatID := callback.Message.Chat.ID
+
+	a.bot.AnswerCallbackQuery(callback.ID, "Let's plan next week!")
+
+	// Remove the button, so the week isn't planned twice from the same reminder
+	a.bot.EditMessageWithKeyboard(chatID, callback.Message.MessageID, messages.EscapeMarkdown(callback.Message.Text), tgbotapi.InlineKeyboardMarkup{})
+
+	scheduled, err := a.schedulerService.ListScheduledDinners(chatID)
+	if err != nil {
+		a.log.Error("Failed to list scheduled dinners: %v", err)
+		a.bot.SendMessage(chatID, "😢 Sorry, I couldn't retrieve the scheduled dinners right now. Please try again later.")
+		return
+	}
+
+	weekEnd := time.Now().AddDate(0, 0, 8)
+	msgText := "📅 Nothing is planned for the next week yet.\n"
+	count := 0
+	for _, dinner := range scheduled {
+		if dinner.At.After(weekEnd) {
+			continue
+		}
+		if count == 0 {
+			msgText = "📅 Already planned for the next week:\n\n"
+		}
+		count++
+		msgText += fmt.Sprintf("%d. %s\n", count, messages.FormatTime(dinner.At, a.channelLocation(chatID)))
+	}
+	msgText += "\nSchedule a dinner poll for any evening with /schedule 2024-06-01 18:00"
+	if a.schedulerService.AutoDinnerEnabled(chatID) {
+		msgText += ", on top of the poll I start every afternoon."
+	} else {
+		msgText += "."
+	}
+	msgText += "\nPlan a dish for each day with /plan monday Lasagna and get one shopping list for the week with /shopping_week."
+	a.bot.SendMessage(chatID, msgText)
+}
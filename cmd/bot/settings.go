@@ -0,0 +1,233 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/korjavin/whatsfordinner/pkg/dinner"
+	"github.com/korjavin/whatsfordinner/pkg/messages"
+	"github.com/korjavin/whatsfordinner/pkg/models"
+)
+
+// handleSetQuestion handles the /set_question command
+func (a *app) handleSetQuestion(message *tgbotapi.Message) {
+	chatID := message.Chat.ID
+
+	args := strings.TrimSpace(message.CommandArguments())
+	if args == "" {
+		a.bot.SendMessage(chatID, fmt.Sprintf("🗳️ The dinner poll currently asks: %s\n\nChange it with /set_question What's for dinner on {date}?\nUse /set_question default to go back to the default question.", messages.EscapeMarkdown(a.pollService.GetPollQuestion(chatID))))
+		return
+	}
+
+	question := args
+	if strings.EqualFold(args, "default") {
+		question = ""
+	}
+
+	err := a.pollService.SetPollQuestion(chatID, question)
+	if err != nil {
+		a.log.Error("Failed to set poll question: %v", err)
+		a.bot.SendMessage(chatID, fmt.Sprintf("🤔 I can't use that question: %v", err))
+		return
+	}
+
+	a.bot.SendMessage(chatID, fmt.Sprintf("✅ Got it! The next dinner poll will ask: %s", messages.EscapeMarkdown(a.pollService.GetPollQuestion(chatID))))
+}
+
+// handleServings handles the /servings command
+func (a *app) handleServings(message *tgbotapi.Message) {
+	chatID := message.Chat.ID
+	dinnerService := dinner.New(a.store, a.fridgeService, a.openaiClient)
+
+	args := strings.TrimSpace(message.CommandArguments())
+	if args == "" {
+		servings := dinnerService.GetServings(chatID)
+		if servings == 0 {
+			a.bot.SendMessage(chatID, fmt.Sprintf("👪 Recipes are shown as written (usually %d servings). Set your family size with /servings 3", dinner.DefaultServings))
+		} else {
+			a.bot.SendMessage(chatID, fmt.Sprintf("👪 Recipes are scaled for %d servings. Change it with /servings 3", servings))
+		}
+		return
+	}
+
+	servings, err := strconv.Atoi(args)
+	if err != nil || servings < 1 || servings > dinner.MaxServings {
+		a.bot.SendMessage(chatID, fmt.Sprintf("🤔 Please give a number of servings between 1 and %d, for example: /servings 3", dinner.MaxServings))
+		return
+	}
+
+	err = dinnerService.SetServings(chatID, servings)
+	if err != nil {
+		a.log.Error("Failed to set servings: %v", err)
+		a.bot.SendMessage(chatID, "😢 Sorry, I couldn't save the number of servings. Please try again later.")
+		return
+	}
+
+	a.bot.SendMessage(chatID, fmt.Sprintf("👪 Got it! Recipe amounts will be scaled for %d servings.", servings))
+}
+
+// handleSetMembers handles the /set_members command
+func (a *app) handleSetMembers(message *tgbotapi.Message) {
+	chatID := message.Chat.ID
+
+	args := strings.TrimSpace(message.CommandArguments())
+	if args == "" {
+		var channelState models.ChannelState
+		a.store.Get(fmt.Sprintf("channel:%d", chatID), &channelState)
+		if channelState.MemberCountManual {
+			a.bot.SendMessage(chatID, fmt.Sprintf("👪 Polls close when 2/3 of %d family members have voted. Use /set_members auto to count the chat members instead.", channelState.MemberCount))
+		} else {
+			a.bot.SendMessage(chatID, "👪 I count the chat members to decide when a poll closes. If that's wrong, set the number of family members with /set_members 5")
+		}
+		return
+	}
+
+	count := 0
+	if !strings.EqualFold(args, "auto") {
+		var err error
+		count, err = strconv.Atoi(args)
+		if err != nil || count < 1 {
+			a.bot.SendMessage(chatID, "🤔 Please give the number of family members, for example /set_members 5, or /set_members auto.")
+			return
+		}
+	}
+
+	if !a.requireAdmin(message) {
+		return
+	}
+
+	err := a.pollService.SetMemberCount(chatID, count)
+	if err != nil {
+		a.log.Error("Failed to set member count: %v", err)
+		a.bot.SendMessage(chatID, "😢 Sorry, I couldn't save the member count. Please try again later.")
+		return
+	}
+
+	if count == 0 {
+		a.bot.SendMessage(chatID, "👪 Got it! I'll count the chat members again.")
+	} else {
+		a.bot.SendMessage(chatID, fmt.Sprintf("👪 Got it! Polls will close when 2/3 of %d family members have voted.", count))
+	}
+}
+
+// handleVoteMode handles the /vote_mode command
+func (a *app) handleVoteMode(message *tgbotapi.Message) {
+	chatID := message.Chat.ID
+
+	var cards bool
+	switch strings.ToLower(strings.TrimSpace(message.CommandArguments())) {
+	case "":
+		if a.pollService.VoteCards(chatID) {
+			a.bot.SendMessage(chatID, "🗳 Every suggested dish is posted as its own card with a vote button. Use /vote_mode poll to vote with a Telegram poll instead.")
+		} else {
+			a.bot.SendMessage(chatID, "🗳 Dinner votes use a Telegram poll. Use /vote_mode cards to post every dish as its own card with a vote button.")
+		}
+		return
+	case "poll":
+		cards = false
+	case "cards":
+		cards = true
+	default:
+		a.bot.SendMessage(chatID, "🤔 Please use /vote_mode poll or /vote_mode cards")
+		return
+	}
+
+	if !a.requireAdmin(message) {
+		return
+	}
+
+	err := a.pollService.SetVoteCards(chatID, cards)
+	if err != nil {
+		a.log.Error("Failed to save vote mode: %v", err)
+		a.bot.SendMessage(chatID, "😢 Sorry, I couldn't save the vote mode. Please try again later.")
+		return
+	}
+
+	if cards {
+		a.bot.SendMessage(chatID, "🗳 Got it! From the next vote on, every dish gets its own card with a vote button.")
+	} else {
+		a.bot.SendMessage(chatID, "🗳 Got it! From the next vote on, we'll vote with a Telegram poll.")
+	}
+}
+
+// handleAutodinner handles the /autodinner command
+func (a *app) handleAutodinner(message *tgbotapi.Message) {
+	chatID := message.Chat.ID
+
+	var enabled bool
+	switch strings.ToLower(strings.TrimSpace(message.CommandArguments())) {
+	case "":
+		if a.schedulerService.AutoDinnerEnabled(chatID) {
+			a.bot.SendMessage(chatID, "⏰ I start the dinner poll every day around 3pm. Use /autodinner off if you'd rather start it yourself with /dinner.")
+		} else {
+			a.bot.SendMessage(chatID, "⏰ I don't start dinner polls on my own, use /dinner whenever you're ready. Use /autodinner on to get one every day around 3pm.")
+		}
+		return
+	case "on":
+		enabled = true
+	case "off":
+		enabled = false
+	default:
+		a.bot.SendMessage(chatID, "🤔 Please use /autodinner on or /autodinner off")
+		return
+	}
+
+	if !a.requireAdmin(message) {
+		return
+	}
+
+	err := a.schedulerService.SetAutoDinner(chatID, enabled)
+	if err != nil {
+		a.log.Error("Failed to save auto dinner setting: %v", err)
+		a.bot.SendMessage(chatID, "😢 Sorry, I couldn't save the setting. Please try again later.")
+		return
+	}
+
+	if enabled {
+		a.bot.SendMessage(chatID, "⏰ Got it! I'll start the dinner poll every day around 3pm.")
+	} else {
+		a.bot.SendMessage(chatID, "⏰ Got it! No more automatic dinner polls, start one with /dinner whenever you're ready.")
+	}
+}
+
+// handleCookRule handles the /cook_rule command
+func (a *app) handleCookRule(message *tgbotapi.Message) {
+	chatID := message.Chat.ID
+
+	var restrict bool
+	switch strings.ToLower(strings.TrimSpace(message.CommandArguments())) {
+	case "":
+		if a.pollService.RestrictCookToVoters(chatID) {
+			a.bot.SendMessage(chatID, "👩‍🍳 Only people who voted for the winning dish can volunteer to cook it. Use /cook_rule anyone to let everyone volunteer.")
+		} else {
+			a.bot.SendMessage(chatID, "👩‍🍳 Anyone can volunteer to cook the winning dish. Use /cook_rule voters to only allow people who voted for it.")
+		}
+		return
+	case "voters":
+		restrict = true
+	case "anyone":
+		restrict = false
+	default:
+		a.bot.SendMessage(chatID, "🤔 Please use /cook_rule voters or /cook_rule anyone")
+		return
+	}
+
+	if !a.requireAdmin(message) {
+		return
+	}
+
+	err := a.pollService.SetRestrictCookToVoters(chatID, restrict)
+	if err != nil {
+		a.log.Error("Failed to save cook rule: %v", err)
+		a.bot.SendMessage(chatID, "😢 Sorry, I couldn't save the cook rule. Please try again later.")
+		return
+	}
+
+	if restrict {
+		a.bot.SendMessage(chatID, "👩‍🍳 Got it! Only people who voted for the winning dish can volunteer to cook it.")
+	} else {
+		a.bot.SendMessage(chatID, "👩‍🍳 Got it! Anyone can volunteer to cook the winning dish.")
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/korjavin/whatsfordinner/pkg/models"
+)
+
+// channelState returns the saved state of the test chat
+func channelState(t *testing.T, ta *testApp) models.ChannelState {
+	t.Helper()
+
+	var state models.ChannelState
+	if err := ta.store.Get(fmt.Sprintf("channel:%d", testChatID), &state); err != nil {
+		t.Fatalf("failed to get channel state: %v", err)
+	}
+	return state
+}
+
+func TestManualMemberCountSticksThroughRefresh(t *testing.T) {
+	ta := newTestApp(t)
+	ta.telegram.SetMemberCount(10)
+	admin := testUser(1, "Anna")
+	ta.telegram.SetAdmin(admin.ID)
+
+	ta.handleSetMembers(command(admin, "/set_members 2"))
+	if state := channelState(t, ta); state.MemberCount != 2 || !state.MemberCountManual {
+		t.Fatalf("member count = %d (manual %v), want a manual 2", state.MemberCount, state.MemberCountManual)
+	}
+
+	if _, err := ta.pollService.CreateVote(testChatID, "poll-1", 1, []string{"Pasta", "Soup"}); err != nil {
+		t.Fatalf("CreateVote failed: %v", err)
+	}
+	ta.answerPoll(admin, "poll-1", 0)
+	if state := channelState(t, ta); state.MemberCount != 2 {
+		t.Fatalf("member count = %d after a vote, want the manual 2 kept although Telegram counts 10", state.MemberCount)
+	}
+
+	// Both family members voted, so the poll closes
+	ta.answerPoll(testUser(2, "Ben"), "poll-1", 0)
+	vote, err := ta.pollService.GetVote(testChatID, "poll-1")
+	if err != nil {
+		t.Fatalf("GetVote failed: %v", err)
+	}
+	if vote.EndedAt.IsZero() {
+		t.Error("poll is still open after both of the 2 members voted")
+	}
+
+	ta.handleSetMembers(command(admin, "/set_members auto"))
+	if state := channelState(t, ta); state.MemberCountManual {
+		t.Error("member count is still manual after /set_members auto")
+	}
+}
+
+func TestSetMembersIsForAdmins(t *testing.T) {
+	ta := newTestApp(t)
+
+	ta.handleSetMembers(command(testUser(2, "Ben"), "/set_members 2"))
+
+	var state models.ChannelState
+	if err := ta.store.Get(fmt.Sprintf("channel:%d", testChatID), &state); err == nil && state.MemberCountManual {
+		t.Error("a non-admin set the member count")
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// handleStart handles the /start command
+func (a *app) handleStart(message *tgbotapi.Message) {
+	// Remember private chats, so the bot can send opted-in users their digests
+	if message.Chat.IsPrivate() && message.From != nil {
+		if err := a.prefsService.SetPrivateChat(message.From.ID, message.Chat.ID); err != nil {
+			a.log.Error("Failed to save private chat: %v", err)
+		}
+	}
+
+	welcomeMsg := a.messageService.GenerateWelcomeMessage()
+	a.bot.SendMessage(message.Chat.ID, welcomeMsg)
+}
+
+// handleDigest handles the /digest command
+func (a *app) handleDigest(message *tgbotapi.Message) {
+	chatID := message.Chat.ID
+	if message.From == nil {
+		return
+	}
+	userID := message.From.ID
+
+	var enabled bool
+	switch strings.ToLower(strings.TrimSpace(message.CommandArguments())) {
+	case "":
+		if a.prefsService.Get(userID).CookDigest {
+			a.bot.SendMessage(chatID, "📬 You'll get a private recap when the ratings of a dinner you cooked close. Use /digest off to stop.")
+		} else {
+			a.bot.SendMessage(chatID, "📭 You don't get cook recaps. Use /digest on to get a private message when the ratings of a dinner you cooked close.")
+		}
+		return
+	case "on":
+		enabled = true
+	case "off":
+		enabled = false
+	default:
+		a.bot.SendMessage(chatID, "🤔 Please use /digest on or /digest off")
+		return
+	}
+
+	err := a.prefsService.SetCookDigest(userID, enabled)
+	if err != nil {
+		a.log.Error("Failed to save digest preference: %v", err)
+		a.bot.SendMessage(chatID, "😢 Sorry, I couldn't save your preference. Please try again later.")
+		return
+	}
+
+	if !enabled {
+		a.bot.SendMessage(chatID, "📭 Got it! No more cook recaps.")
+		return
+	}
+
+	if a.prefsService.Get(userID).PrivateChatID == 0 && !message.Chat.IsPrivate() {
+		a.bot.SendMessage(chatID, "📬 Got it! To receive your recaps, please open a private chat with me and send /start, so I'm allowed to message you.")
+		return
+	}
+	if message.Chat.IsPrivate() {
+		if err := a.prefsService.SetPrivateChat(userID, chatID); err != nil {
+			a.log.Error("Failed to save private chat: %v", err)
+		}
+	}
+	a.bot.SendMessage(chatID, "📬 Got it! I'll send you a private recap when the ratings of a dinner you cooked close.")
+}
+
+// handleHelp handles the /help command
+func (a *app) handleHelp(message *tgbotapi.Message) {
+	a.bot.SendMessage(message.Chat.ID, a.commands.HelpText())
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestHelpListsEveryCommand(t *testing.T) {
+	ta := newTestApp(t)
+	ta.registerCommands()
+
+	ta.handleHelp(command(testUser(1, "Anna"), "/help"))
+	// Underscores in commands are escaped for Markdown
+	help := strings.ReplaceAll(ta.telegram.LastText(), `\_`, "_")
+
+	handlers := ta.commands.Handlers()
+	for _, cmd := range ta.commands.Commands() {
+		if handlers[cmd.Name] == nil {
+			t.Errorf("/%s has no handler", cmd.Name)
+		}
+		if cmd.Description == "" {
+			t.Errorf("/%s has no description", cmd.Name)
+		}
+		if cmd.Name == "show_fridge" {
+			continue
+		}
+		if !strings.Contains(help, "/"+cmd.Name+" – ") {
+			t.Errorf("/help doesn't list /%s", cmd.Name)
+		}
+	}
+	for _, name := range []string{"dinner", "suggest", "vote", "pending_voters", "cooking", "remove", "help"} {
+		if handlers[name] == nil {
+			t.Errorf("/%s isn't registered", name)
+		}
+	}
+}
+
+func TestCommandMenuIsScoped(t *testing.T) {
+	ta := newTestApp(t)
+	ta.registerCommands()
+
+	// A failing group menu doesn't keep the private one from being set
+	ta.telegram.Fail("setMyCommands", 400, "Bad Request: too many commands")
+	ta.setCommandMenu()
+	ta.setCommandMenu()
+
+	calls := ta.telegram.Calls("setMyCommands")
+	if len(calls) != 4 {
+		t.Fatalf("setMyCommands was called %d times, want 4", len(calls))
+	}
+	group, private := calls[2].Params, calls[3].Params
+	if !strings.Contains(group.Get("scope"), "all_group_chats") || !strings.Contains(private.Get("scope"), "all_private_chats") {
+		t.Fatalf("menus were set for scopes %s and %s", group.Get("scope"), private.Get("scope"))
+	}
+
+	for _, want := range []string{`"dinner"`, `"start"`, `"help"`, `"vote"`} {
+		if !strings.Contains(group.Get("commands"), want) {
+			t.Errorf("the group menu doesn't have %s", want)
+		}
+	}
+	if strings.Contains(group.Get("commands"), `"show_fridge"`) {
+		t.Error("the group menu has the hidden /show_fridge")
+	}
+	for _, want := range []string{`"start"`, `"digest"`, `"help"`} {
+		if !strings.Contains(private.Get("commands"), want) {
+			t.Errorf("the private menu doesn't have %s", want)
+		}
+	}
+	if strings.Contains(private.Get("commands"), `"dinner"`) {
+		t.Error("the private menu has /dinner, which only works in groups")
+	}
+}
@@ -0,0 +1,302 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/korjavin/whatsfordinner/pkg/messages"
+	"github.com/korjavin/whatsfordinner/pkg/models"
+)
+
+// handleStats handles the /stats command
+func (a *app) handleStats(message *tgbotapi.Message) {
+	// Show family leaderboards
+	chatID := message.Chat.ID
+
+	// Get statistics
+	stats, err := a.statsService.GetStatistics(chatID)
+	if err != nil {
+		a.log.Error("Failed to get statistics: %v", err)
+		a.bot.SendMessage(chatID, "😢 Sorry, I couldn't retrieve the statistics right now. Please try again later.")
+		return
+	}
+
+	// Check if we have any statistics
+	if len(stats.CookStats) == 0 && len(stats.HelperStats) == 0 && len(stats.SuggesterStats) == 0 {
+		a.bot.SendMessage(chatID, "📊 No statistics available yet. Start cooking and rating meals to build up your family leaderboards!")
+		return
+	}
+
+	// Create a formatted message with statistics
+	msgText := "🏆 *Family Leaderboards*\n\n"
+
+	// Add cook statistics
+	if len(stats.CookStats) > 0 {
+		msgText += "👨‍🍳 *Top Cooks*\n"
+
+		// Convert map to slice for sorting
+		cooks := make([]models.CookStat, 0, len(stats.CookStats))
+		for _, cookStat := range stats.CookStats {
+			cooks = append(cooks, cookStat)
+		}
+
+		// Sort by average rating (descending)
+		sort.Slice(cooks, func(i, j int) bool {
+			return cooks[i].AvgRating > cooks[j].AvgRating
+		})
+
+		// Take the top 3 cooks
+		limit := 3
+		if len(cooks) < limit {
+			limit = len(cooks)
+		}
+
+		for i := 0; i < limit; i++ {
+			cook := cooks[i]
+			// Use username if available, otherwise try to get a friendly name
+			displayName := cook.Username
+			if displayName == "" {
+				// Try to convert user ID to integer for Telegram API
+				userIDInt, err := strconv.ParseInt(cook.UserID, 10, 64)
+				if err == nil {
+					// Try to get chat member info
+					member, err := a.bot.GetChatMember(chatID, userIDInt)
+					if err == nil && member.User != nil {
+						// Use username if available, otherwise use first name
+						if member.User.UserName != "" {
+							displayName = "@" + member.User.UserName
+							// Update the stored username for future use
+							a.statsService.UpdateCookStats(chatID, cook.UserID, member.User.UserName, 0)
+						} else if member.User.FirstName != "" {
+							displayName = member.User.FirstName
+							// Update the stored username for future use
+							a.statsService.UpdateCookStats(chatID, cook.UserID, member.User.FirstName, 0)
+						}
+					}
+				}
+
+				// If we still don't have a display name, use the user ID
+				if displayName == "" {
+					displayName = fmt.Sprintf("User %s", cook.UserID)
+				}
+			}
+			msgText += fmt.Sprintf("%d. %s - %.1f stars (%d meals)\n", i+1, messages.EscapeMarkdown(displayName), cook.AvgRating, cook.CookCount)
+		}
+		msgText += "\n"
+	}
+
+	// Add helper statistics
+	if len(stats.HelperStats) > 0 {
+		msgText += "🛒 *Top Shoppers*\n"
+
+		// Convert map to slice for sorting
+		helpers := make([]models.HelperStat, 0, len(stats.HelperStats))
+		for _, helperStat := range stats.HelperStats {
+			helpers = append(helpers, helperStat)
+		}
+
+		// Sort by shopping count (descending)
+		sort.Slice(helpers, func(i, j int) bool {
+			return helpers[i].ShoppingCount > helpers[j].ShoppingCount
+		})
+
+		// Take the top 3 helpers
+		limit := 3
+		if len(helpers) < limit {
+			limit = len(helpers)
+		}
+
+		for i := 0; i < limit; i++ {
+			helper := helpers[i]
+			// Use username if available, otherwise try to get a friendly name
+			displayName := helper.Username
+			if displayName == "" {
+				// Try to convert user ID to integer for Telegram API
+				userIDInt, err := strconv.ParseInt(helper.UserID, 10, 64)
+				if err == nil {
+					// Try to get chat member info
+					member, err := a.bot.GetChatMember(chatID, userIDInt)
+					if err == nil && member.User != nil {
+						// Use username if available, otherwise use first name
+						if member.User.UserName != "" {
+							displayName = "@" + member.User.UserName
+							// Update the stored username for future use
+							a.statsService.UpdateHelperStats(chatID, helper.UserID, member.User.UserName)
+						} else if member.User.FirstName != "" {
+							displayName = member.User.FirstName
+							// Update the stored username for future use
+							a.statsService.UpdateHelperStats(chatID, helper.UserID, member.User.FirstName)
+						}
+					}
+				}
+
+				// If we still don't have a display name, use the user ID
+				if displayName == "" {
+					displayName = fmt.Sprintf("User %s", helper.UserID)
+				}
+			}
+			msgText += fmt.Sprintf("%d. %s - %d shopping trips\n", i+1, messages.EscapeMarkdown(displayName), helper.ShoppingCount)
+		}
+		msgText += "\n"
+	}
+
+	// Add suggester statistics
+	if len(stats.SuggesterStats) > 0 {
+		msgText += "💡 *Top Suggesters*\n"
+
+		// Convert map to slice for sorting
+		suggesters := make([]models.SuggesterStat, 0, len(stats.SuggesterStats))
+		for _, suggesterStat := range stats.SuggesterStats {
+			suggesters = append(suggesters, suggesterStat)
+		}
+
+		// Sort by acceptance rate (descending)
+		sort.Slice(suggesters, func(i, j int) bool {
+			// Calculate acceptance rates
+			rateI := 0.0
+			if suggesterStat := suggesters[i]; suggesterStat.SuggestionCount > 0 {
+				rateI = float64(suggesterStat.AcceptedCount) / float64(suggesterStat.SuggestionCount)
+			}
+
+			rateJ := 0.0
+			if suggesterStat := suggesters[j]; suggesterStat.SuggestionCount > 0 {
+				rateJ = float64(suggesterStat.AcceptedCount) / float64(suggesterStat.SuggestionCount)
+			}
+
+			return rateI > rateJ
+		})
+
+		// Take the top 3 suggesters
+		limit := 3
+		if len(suggesters) < limit {
+			limit = len(suggesters)
+		}
+
+		for i := 0; i < limit; i++ {
+			suggester := suggesters[i]
+			rate := 0.0
+			if suggester.SuggestionCount > 0 {
+				rate = float64(suggester.AcceptedCount) / float64(suggester.SuggestionCount) * 100
+			}
+			// Use username if available, otherwise try to get a friendly name
+			displayName := suggester.Username
+			if displayName == "" {
+				// Try to convert user ID to integer for Telegram API
+				userIDInt, err := strconv.ParseInt(suggester.UserID, 10, 64)
+				if err == nil {
+					// Try to get chat member info
+					member, err := a.bot.GetChatMember(chatID, userIDInt)
+					if err == nil && member.User != nil {
+						// Use username if available, otherwise use first name
+						if member.User.UserName != "" {
+							displayName = "@" + member.User.UserName
+							// Update the stored username for future use
+							a.statsService.UpdateSuggesterStats(chatID, suggester.UserID, member.User.UserName, false)
+						} else if member.User.FirstName != "" {
+							displayName = member.User.FirstName
+							// Update the stored username for future use
+							a.statsService.UpdateSuggesterStats(chatID, suggester.UserID, member.User.FirstName, false)
+						}
+					}
+				}
+
+				// If we still don't have a display name, use the user ID
+				if displayName == "" {
+					displayName = fmt.Sprintf("User %s", suggester.UserID)
+				}
+			}
+			msgText += fmt.Sprintf("%d. %s - %.1f%% acceptance (%d/%d)\n", i+1, messages.EscapeMarkdown(displayName), rate, suggester.AcceptedCount, suggester.SuggestionCount)
+		}
+	}
+
+	// Add who ate more often than they cooked, as a gentle hint for the next volunteer
+	balance, err := a.statsService.CookBalance(chatID)
+	if err != nil {
+		a.log.Error("Failed to get cook balance: %v", err)
+	}
+	var owing []models.AttendanceStat
+	for _, stat := range balance {
+		if stat.Eaten > stat.Cooked && len(owing) < 3 {
+			owing = append(owing, stat)
+		}
+	}
+	if len(owing) > 0 {
+		msgText += "\n🍽️ *Ate more than they cooked*\n"
+		for _, stat := range owing {
+			displayName := stat.Username
+			if displayName == "" {
+				displayName = fmt.Sprintf("User %s", stat.UserID)
+			}
+			msgText += fmt.Sprintf("• %s - ate %d, cooked %d\n", messages.EscapeMarkdown(displayName), stat.Eaten, stat.Cooked)
+		}
+	}
+
+	a.bot.SendMessage(chatID, msgText)
+}
+
+// handleCredit handles the /credit command
+func (a *app) handleCredit(message *tgbotapi.Message) {
+	chatID := message.Chat.ID
+	if !a.requireAdmin(message) {
+		return
+	}
+
+	userID, name, dish, ok := a.targetUser(message)
+	if !ok {
+		a.bot.SendMessage(chatID, "🤔 I don't know who to credit. Use /credit @username <dish>, or reply to their message with /credit <dish>.")
+		return
+	}
+
+	// The credited dinner counts with the rating the family gave the dish, if they rated it
+	rating, _ := a.dinnerService.DishRating(chatID, dish, a.cfg.RatingScale)
+	cookStat, err := a.statsService.AdjustCookStat(chatID, userID, 1, rating)
+	if err != nil {
+		a.log.Error("Failed to adjust cook stats: %v", err)
+		a.bot.SendMessage(chatID, "😢 Sorry, I couldn't update the statistics right now. Please try again later.")
+		return
+	}
+
+	msgText := fmt.Sprintf("✅ Credited @%s with cooking", messages.EscapeMarkdown(name))
+	if dish != "" {
+		msgText += " " + messages.EscapeMarkdown(dish)
+	}
+	msgText += fmt.Sprintf(". They have now cooked %d dinners.", cookStat.CookCount)
+	a.bot.SendMessage(chatID, msgText)
+}
+
+// handleUncredit handles the /uncredit command
+func (a *app) handleUncredit(message *tgbotapi.Message) {
+	chatID := message.Chat.ID
+	if !a.requireAdmin(message) {
+		return
+	}
+
+	userID, name, dish, ok := a.targetUser(message)
+	if !ok {
+		a.bot.SendMessage(chatID, "🤔 I don't know who to uncredit. Use /uncredit @username <dish>, or reply to their message with /uncredit <dish>.")
+		return
+	}
+
+	// Take away the dish's rating, or an average one, so the average of the other dinners stays the same
+	rating, rated := a.dinnerService.DishRating(chatID, dish, a.cfg.RatingScale)
+	if !rated {
+		stats, err := a.statsService.GetStatistics(chatID)
+		if err != nil {
+			a.log.Error("Failed to get statistics: %v", err)
+			a.bot.SendMessage(chatID, "😢 Sorry, I couldn't update the statistics right now. Please try again later.")
+			return
+		}
+		rating = stats.CookStats[userID].AvgRating
+	}
+
+	cookStat, err := a.statsService.AdjustCookStat(chatID, userID, -1, -rating)
+	if err != nil {
+		a.log.Error("Failed to adjust cook stats: %v", err)
+		a.bot.SendMessage(chatID, "😢 Sorry, I couldn't update the statistics right now. Please try again later.")
+		return
+	}
+
+	a.bot.SendMessage(chatID, fmt.Sprintf("✅ Removed one dinner from @%s. They have now cooked %d dinners.", messages.EscapeMarkdown(name), cookStat.CookCount))
+}
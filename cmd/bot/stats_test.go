@@ -0,0 +1,50 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/korjavin/whatsfordinner/pkg/models"
+)
+
+func TestCreditKeepsTheAverageHonest(t *testing.T) {
+	ta := newTestApp(t)
+	admin := testUser(1, "Anna")
+	ta.telegram.SetAdmin(admin.ID)
+
+	// Boris cooked one dinner rated 4, and the family rated Fish_Soup 2
+	if err := ta.statsService.UpdateCookStats(testChatID, "2", "boris", 4); err != nil {
+		t.Fatalf("UpdateCookStats failed: %v", err)
+	}
+	id := fmt.Sprintf("dinner:%d:%d", testChatID, time.Now().UnixNano())
+	soup := models.Dinner{ID: id, ChannelID: testChatID, Dish: models.Dish{Name: "Fish_Soup"}, StartedAt: time.Now(), Ratings: map[string]int{"1": 2}, AverageRating: 2}
+	if err := ta.store.Set(id, soup); err != nil {
+		t.Fatalf("failed to save dinner: %v", err)
+	}
+
+	cookStat := func() models.CookStat {
+		t.Helper()
+		stats, err := ta.statsService.GetStatistics(testChatID)
+		if err != nil {
+			t.Fatalf("GetStatistics failed: %v", err)
+		}
+		return stats.CookStats["2"]
+	}
+
+	ta.handleCredit(command(admin, "/credit @boris fish_soup"))
+	if got := cookStat(); got.CookCount != 2 || math.Abs(got.AvgRating-3) > 0.01 {
+		t.Errorf("after /credit Boris cooked %d with an average of %.2f, want 2 with the dish's 2 averaging 3", got.CookCount, got.AvgRating)
+	}
+	if reply := ta.telegram.LastText(); !strings.Contains(reply, `fish\_soup`) {
+		t.Errorf("/credit replied %q, want the dish name escaped", reply)
+	}
+
+	// Without a rated dish an average dinner is taken away, which keeps the average
+	ta.handleUncredit(command(admin, "/uncredit @boris"))
+	if got := cookStat(); got.CookCount != 1 || math.Abs(got.AvgRating-3) > 0.01 {
+		t.Errorf("after /uncredit Boris cooked %d with an average of %.2f, want 1 still averaging 3", got.CookCount, got.AvgRating)
+	}
+}
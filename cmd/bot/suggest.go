@@ -0,0 +1,402 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/korjavin/whatsfordinner/pkg/dinner"
+	"github.com/korjavin/whatsfordinner/pkg/messages"
+	"github.com/korjavin/whatsfordinner/pkg/models"
+	"github.com/korjavin/whatsfordinner/pkg/poll"
+	"github.com/korjavin/whatsfordinner/pkg/suggest"
+)
+
+// handleSuggest handles the /suggest command
+func (a *app) handleSuggest(message *tgbotapi.Message) {
+	// Start dish suggestion flow
+	chatID := message.Chat.ID
+	userID := fmt.Sprintf("%d", message.From.ID)
+	username := message.From.UserName
+	if username == "" {
+		username = message.From.FirstName
+	}
+
+	// Check if there's a dish name in the command
+	args := message.CommandArguments()
+	if args != "" {
+		// User provided a dish name with the command
+		// Send a processing message
+		processingMsg, _ := a.bot.SendMessage(chatID, fmt.Sprintf("🧐 Looking up information about '%s'... This might take a moment.", messages.EscapeMarkdown(args)))
+
+		// Get dish information from OpenAI
+		dishInfo, err := a.openaiClient.WithChannel(chatID).GetDishInfo(args)
+		if err != nil {
+			a.log.Error("Failed to get dish info: %v", err)
+			a.bot.EditMessage(chatID, processingMsg.MessageID, fmt.Sprintf("😢 Sorry, I couldn't find information about '%s'. Please try again with a different dish.", messages.EscapeMarkdown(args)))
+			return
+		}
+
+		// Extract dish information
+		dishName, _ := dishInfo["name"].(string)
+		if dishName == "" {
+			dishName = args // Fallback to the user-provided name
+		}
+
+		// Don't offer to save a dish that's already waiting for a poll
+		if existing, ok := a.suggestService.FindUnusedSuggestion(chatID, dishName); ok {
+			a.bot.EditMessage(chatID, processingMsg.MessageID, duplicateSuggestionText(existing, userID))
+			return
+		}
+
+		cuisine, _ := dishInfo["cuisine"].(string)
+		description, _ := dishInfo["description"].(string)
+
+		// Get ingredients needed
+		var ingredientsNeeded []string
+		ingredientsList, ok := dishInfo["ingredients_needed"].([]interface{})
+		if !ok {
+			// Try alternative key
+			ingredientsList, ok = dishInfo["ingredients"].([]interface{})
+		}
+
+		if ok {
+			ingredientsNeeded = make([]string, len(ingredientsList))
+			for i, ing := range ingredientsList {
+				if ingStr, ok := ing.(string); ok {
+					ingredientsNeeded[i] = ingStr
+				}
+			}
+		}
+
+		// Compare ingredients and amounts with the fridge, leaving out the staples the family always has
+		var missingIngredients []string
+		fridgeIngredients, err := a.fridgeService.ListIngredients(chatID)
+		if err != nil {
+			a.log.Error("Failed to list ingredients: %v", err)
+			// Continue without fridge comparison
+			fridgeIngredients = nil
+		}
+		if len(fridgeIngredients) > 0 && len(ingredientsNeeded) > 0 {
+			missingIngredients, err = a.dinnerService.MissingIngredients(chatID, ingredientsNeeded)
+			if err != nil {
+				a.log.Error("Failed to compare ingredients: %v", err)
+				missingIngredients = nil
+			}
+		}
+
+		// Keep the suggestion pending on this message until the user confirms it
+		pending := models.SuggestedDish{
+			ChannelID:   chatID,
+			UserID:      userID,
+			Username:    username,
+			Name:        dishName,
+			Cuisine:     cuisine,
+			Description: description,
+		}
+		if err := a.suggestService.SetPending(chatID, processingMsg.MessageID, pending); err != nil {
+			a.log.Error("Failed to save pending suggestion: %v", err)
+			a.bot.EditMessage(chatID, processingMsg.MessageID, fmt.Sprintf("😢 Sorry, I couldn't save your suggestion for '%s'. Please try again later.", messages.EscapeMarkdown(args)))
+			return
+		}
+
+		// Create a detailed message about the dish
+		detailedMsg := fmt.Sprintf("🍴 %s (%s cuisine)\n\n%s\n\n", messages.Bold(dishName), messages.EscapeMarkdown(cuisine), messages.EscapeMarkdown(description))
+
+		// Add ingredients information
+		if len(ingredientsNeeded) > 0 {
+			detailedMsg += "*Ingredients needed:*\n"
+			for _, ingredient := range ingredientsNeeded {
+				detailedMsg += fmt.Sprintf("• %s\n", messages.EscapeMarkdown(ingredient))
+			}
+			detailedMsg += "\n"
+		}
+
+		// Add missing ingredients information
+		if len(missingIngredients) > 0 {
+			detailedMsg += "*Missing from your fridge:*\n"
+			for _, ingredient := range missingIngredients {
+				detailedMsg += fmt.Sprintf("• %s\n", messages.EscapeMarkdown(ingredient))
+			}
+			detailedMsg += "\n"
+		}
+
+		detailedMsg += "Is this the dish you meant?"
+
+		// Ask the user to confirm before the suggestion is saved
+		keyboard := tgbotapi.NewInlineKeyboardMarkup(
+			tgbotapi.NewInlineKeyboardRow(
+				tgbotapi.NewInlineKeyboardButtonData("✅ Add to poll pool", fmt.Sprintf("suggest_confirm:%d", processingMsg.MessageID)),
+				tgbotapi.NewInlineKeyboardButtonData("❌ Cancel", fmt.Sprintf("suggest_cancel:%d", processingMsg.MessageID)),
+			),
+		)
+
+		a.bot.EditMessageWithKeyboard(chatID, processingMsg.MessageID, detailedMsg, keyboard)
+	} else {
+		// No dish name provided, ask for it
+		a.bot.SendMessage(chatID, "🍴 You can suggest a dish for dinner! Please use the command like this: /suggest Lasagna")
+	}
+}
+
+// handleSuggestConfirmCallback handles the confirmation of a pending dish suggestion
+func (a *app) handleSuggestConfirmCallback(callback *tgbotapi.CallbackQuery, payload string) {
+	chatID := callback.Message.Chat.ID
+	userID := fmt.Sprintf("%d", callback.From.ID)
+
+	messageID, err := strconv.Atoi(payload)
+	if err != nil {
+		a.log.Error("Invalid pending suggestion: %s", callback.Data)
+		return
+	}
+
+	pending, err := a.suggestService.GetPending(chatID, messageID)
+	if errors.Is(err, suggest.ErrNoPending) {
+		a.bot.AnswerCallbackQuery(callback.ID, "This suggestion has expired.")
+		a.bot.EditMessageWithKeyboard(chatID, callback.Message.MessageID, "⌛ This suggestion has expired. Please use /suggest again.", tgbotapi.InlineKeyboardMarkup{})
+		return
+	}
+	if err != nil {
+		a.log.Error("Failed to get pending suggestion: %v", err)
+		a.bot.AnswerCallbackQuery(callback.ID, "Something went wrong. Please try again.")
+		return
+	}
+
+	// Only the user who suggested the dish can confirm it
+	if pending.UserID != userID {
+		a.bot.AnswerCallbackQuery(callback.ID, "Only the person who suggested this dish can confirm it.")
+		return
+	}
+	if err := a.suggestService.DeletePending(chatID, messageID); err != nil {
+		a.log.Error("Failed to delete pending suggestion: %v", err)
+	}
+
+	// Add the suggestion
+	suggestion, err := a.suggestService.AddSuggestion(chatID, pending.UserID, pending.Username, pending.Name, pending.Cuisine, pending.Description)
+	if errors.Is(err, suggest.ErrDuplicate) {
+		a.bot.AnswerCallbackQuery(callback.ID, "Already suggested!")
+		a.bot.EditMessageWithKeyboard(chatID, callback.Message.MessageID, duplicateSuggestionText(suggestion, pending.UserID), tgbotapi.InlineKeyboardMarkup{})
+		return
+	}
+	if err != nil {
+		a.log.Error("Failed to add suggestion: %v", err)
+		a.bot.AnswerCallbackQuery(callback.ID, "Something went wrong. Please try again.")
+		return
+	}
+
+	a.bot.AnswerCallbackQuery(callback.ID, "Suggestion saved!")
+
+	resultMsg := fmt.Sprintf("✅ Thanks for suggesting %s (%s cuisine)!\n\n", messages.Bold(suggestion.Name), messages.EscapeMarkdown(suggestion.Cuisine))
+
+	// Check if there's an ongoing poll in the channel
+	currentVote, err := a.pollService.GetCurrentVote(chatID)
+	if err == nil && currentVote != nil && currentVote.EndedAt.IsZero() {
+		// There's an active poll, add the suggestion to it
+		a.log.Info("Adding suggestion '%s' to ongoing poll %s", suggestion.Name, currentVote.PollID)
+
+		if containsOption(currentVote.Options, suggestion.Name) {
+			resultMsg += "It's already an option in the current dinner poll!"
+		} else {
+			resultMsg += a.addSuggestionToPoll(chatID, currentVote, suggestion.Name)
+		}
+	} else {
+		// No active poll, just store the suggestion for future polls
+		resultMsg += "Your suggestion will be included in future dinner polls."
+	}
+
+	// Edit the confirmation message to remove the buttons
+	a.bot.EditMessageWithKeyboard(chatID, callback.Message.MessageID, resultMsg, tgbotapi.InlineKeyboardMarkup{})
+}
+
+// addSuggestionToPoll replaces the running poll with one that also offers the new dish,
+// carrying the votes over, and returns what to tell the user who suggested it
+func (a *app) addSuggestionToPoll(chatID int64, currentVote *models.VoteState, name string) string {
+	newOptions := append(append([]string{}, currentVote.Options...), name)
+	newPollMsg, err := a.bot.CreatePoll(chatID, a.pollService.GetPollQuestion(chatID), newOptions)
+	if err != nil {
+		a.log.Error("Failed to create updated poll: %v", err)
+		return "Your suggestion will be included in future dinner polls."
+	}
+
+	// Add the option to the running vote first so ReplacePoll carries it over with the votes
+	_, err = a.pollService.AddOptionToVote(chatID, currentVote.PollID, name)
+	if err == nil {
+		_, err = a.pollService.ReplacePoll(chatID, currentVote.PollID, newPollMsg.Poll.ID, newPollMsg.MessageID)
+	}
+	if err != nil {
+		if !errors.Is(err, poll.ErrVoteEnded) {
+			a.log.Error("Failed to move the vote to the updated poll: %v", err)
+		}
+		// The vote closed in the meantime (or couldn't be moved), so the new poll must not stay open
+		a.bot.StopPoll(chatID, newPollMsg.MessageID)
+		return "Your suggestion will be included in future dinner polls."
+	}
+
+	// Try to stop the poll in Telegram (currently not supported by Telegram API)
+	a.bot.StopPoll(chatID, currentVote.MessageID)
+
+	a.bot.SendMessage(chatID, "⚠️ The previous dinner poll has been replaced with a new one that includes the latest suggestion.")
+	a.bot.SendMessage(chatID, fmt.Sprintf("🔄 The dinner poll has been updated with a new suggestion: %s. Please vote in the new poll above!", messages.Bold(name)))
+
+	return "Your suggestion has been added to the current dinner poll!"
+}
+
+// containsOption reports whether a poll already offers the named dish
+func containsOption(options []string, name string) bool {
+	for _, option := range options {
+		if strings.EqualFold(option, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// handleSuggestCancelCallback handles the cancellation of a pending dish suggestion
+func (a *app) handleSuggestCancelCallback(callback *tgbotapi.CallbackQuery, payload string) {
+	chatID := callback.Message.Chat.ID
+	userID := fmt.Sprintf("%d", callback.From.ID)
+
+	messageID, err := strconv.Atoi(payload)
+	if err != nil {
+		a.log.Error("Invalid pending suggestion: %s", callback.Data)
+		return
+	}
+
+	// Only the user who suggested the dish can cancel it
+	pending, err := a.suggestService.GetPending(chatID, messageID)
+	if err == nil && pending.UserID != userID {
+		a.bot.AnswerCallbackQuery(callback.ID, "Only the person who suggested this dish can cancel it.")
+		return
+	}
+	if err := a.suggestService.DeletePending(chatID, messageID); err != nil {
+		a.log.Error("Failed to delete pending suggestion: %v", err)
+	}
+
+	// Answer the callback
+	a.bot.AnswerCallbackQuery(callback.ID, "Suggestion cancelled.")
+
+	// Edit the message to remove the buttons
+	a.bot.EditMessageWithKeyboard(chatID, callback.Message.MessageID, "❌ Suggestion cancelled. Nothing was added to the poll pool.", tgbotapi.InlineKeyboardMarkup{})
+}
+
+// handleSuggestions handles the /suggestions command
+func (a *app) handleSuggestions(message *tgbotapi.Message) {
+	chatID := message.Chat.ID
+
+	suggestions, err := a.suggestService.GetUnusedSuggestions(chatID)
+	if err != nil {
+		a.log.Error("Failed to get suggestions: %v", err)
+		a.bot.SendMessage(chatID, "😢 Sorry, I couldn't retrieve the suggestions right now. Please try again later.")
+		return
+	}
+
+	if len(suggestions) == 0 {
+		a.bot.SendMessage(chatID, "💡 No suggestions are waiting. Suggest a dish with /suggest Lasagna")
+		return
+	}
+
+	sort.Slice(suggestions, func(i, j int) bool {
+		return suggestions[i].SuggestedAt.Before(suggestions[j].SuggestedAt)
+	})
+
+	msgText := "💡 Suggestions for the next dinner poll:\n\n"
+	for i, suggestion := range suggestions {
+		msgText += fmt.Sprintf("%d. %s (%s), suggested by %s\n", i+1, messages.EscapeMarkdown(suggestion.Name), messages.EscapeMarkdown(suggestion.Cuisine), messages.EscapeMarkdown(suggestion.Username))
+	}
+	msgText += "\nRemove one with /archive_suggestion <number>"
+	a.bot.SendMessage(chatID, msgText)
+}
+
+// handleArchiveSuggestion handles the /archive_suggestion command
+func (a *app) handleArchiveSuggestion(message *tgbotapi.Message) {
+	chatID := message.Chat.ID
+	userID := fmt.Sprintf("%d", message.From.ID)
+
+	suggestions, err := a.suggestService.GetUnusedSuggestions(chatID)
+	if err != nil {
+		a.log.Error("Failed to get suggestions: %v", err)
+		a.bot.SendMessage(chatID, "😢 Sorry, I couldn't retrieve the suggestions right now. Please try again later.")
+		return
+	}
+
+	sort.Slice(suggestions, func(i, j int) bool {
+		return suggestions[i].SuggestedAt.Before(suggestions[j].SuggestedAt)
+	})
+
+	number, err := strconv.Atoi(strings.TrimSpace(message.CommandArguments()))
+	if err != nil || number < 1 || number > len(suggestions) {
+		a.bot.SendMessage(chatID, "🤔 Please give the number of the suggestion to remove, as shown by /suggestions.")
+		return
+	}
+
+	// Suggesters can archive their own suggestions, anything else needs an admin
+	suggestion := suggestions[number-1]
+	if suggestion.UserID != userID && !a.requireAdmin(message) {
+		return
+	}
+
+	err = a.suggestService.ArchiveSuggestion(suggestion.ID)
+	if err != nil {
+		a.log.Error("Failed to archive suggestion: %v", err)
+		a.bot.SendMessage(chatID, "😢 Sorry, I couldn't remove the suggestion right now. Please try again later.")
+		return
+	}
+
+	a.bot.SendMessage(chatID, fmt.Sprintf("🗑️ %s won't be included in the next dinner poll.", messages.EscapeMarkdown(suggestion.Name)))
+}
+
+// handleAgain handles the /again command
+func (a *app) handleAgain(message *tgbotapi.Message) {
+	chatID := message.Chat.ID
+	userID := fmt.Sprintf("%d", message.From.ID)
+	username := message.From.UserName
+	if username == "" {
+		username = message.From.FirstName
+	}
+
+	dishName := strings.TrimSpace(message.CommandArguments())
+	if dishName == "" {
+		a.bot.SendMessage(chatID, "🔁 Tell me which favorite you'd like to have again, e.g. /again Lasagna")
+		return
+	}
+
+	favorite, err := a.dinnerService.FindFavorite(chatID, dishName, a.cfg.RatingScale)
+	if err != nil {
+		switch {
+		case errors.Is(err, dinner.ErrNeverCooked):
+			a.bot.SendMessage(chatID, fmt.Sprintf("🤔 I can't find '%s' among your past dinners. Use /suggest to suggest a new dish.", messages.EscapeMarkdown(dishName)))
+		case errors.Is(err, dinner.ErrNotFavorite):
+			a.bot.SendMessage(chatID, fmt.Sprintf("🤔 '%s' wasn't rated highly enough to count as a favorite. You can still suggest it with /suggest.", messages.EscapeMarkdown(dishName)))
+		default:
+			a.log.Error("Failed to find favorite: %v", err)
+			a.bot.SendMessage(chatID, "😢 Sorry, I couldn't look through your past dinners right now. Please try again later.")
+		}
+		return
+	}
+
+	// Credit whoever suggested the dish first, if it came from a suggestion
+	suggesterID, suggesterName := userID, username
+	if original, ok := a.suggestService.FindSuggester(chatID, favorite.Dish.Name); ok {
+		suggesterID, suggesterName = original.UserID, original.Username
+	}
+
+	description := fmt.Sprintf("A family favorite, rated %.1f out of %d.", dinner.AverageRating(favorite.Ratings), a.cfg.RatingScale)
+	suggestion, err := a.suggestService.AddSuggestion(chatID, suggesterID, suggesterName, favorite.Dish.Name, favorite.Dish.Cuisine, description)
+	if errors.Is(err, suggest.ErrDuplicate) {
+		a.bot.SendMessage(chatID, duplicateSuggestionText(suggestion, userID))
+		return
+	}
+	if err != nil {
+		a.log.Error("Failed to add suggestion: %v", err)
+		a.bot.SendMessage(chatID, "😢 Sorry, I couldn't save the suggestion right now. Please try again later.")
+		return
+	}
+
+	msgText := fmt.Sprintf("🔁 %s is back in the pool for the next dinner poll!", messages.EscapeMarkdown(suggestion.Name))
+	if suggestion.UserID != userID {
+		msgText += fmt.Sprintf(" Originally suggested by @%s.", messages.EscapeMarkdown(suggestion.Username))
+	}
+	a.bot.SendMessage(chatID, msgText)
+}
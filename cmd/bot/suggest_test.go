@@ -0,0 +1,201 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/korjavin/whatsfordinner/pkg/models"
+)
+
+const lasagnaInfo = `{"name": "Lasagna", "cuisine": "Italian", "ingredients_needed": ["pasta sheets", "tomato sauce"], "description": "Layered pasta bake"}`
+
+// suggestDish sends /suggest for a dish and returns the data of its confirm and cancel buttons
+func suggestDish(t *testing.T, ta *testApp, user int64, name string) (string, string) {
+	t.Helper()
+
+	ta.openai.SetReplies(lasagnaInfo)
+	ta.handleSuggest(command(testUser(user, name), "/suggest lasagna"))
+
+	edits := ta.telegram.Calls("editMessageText")
+	if len(edits) == 0 {
+		t.Fatal("the suggestion wasn't shown for confirmation")
+	}
+	markup := edits[len(edits)-1].Params.Get("reply_markup")
+	confirm := between(markup, `"callback_data":"`, `"`)
+	cancel := between(markup[strings.Index(markup, confirm)+len(confirm):], `"callback_data":"`, `"`)
+	return confirm, cancel
+}
+
+// between returns the text in s between the first start and the following end
+func between(s, start, end string) string {
+	i := strings.Index(s, start)
+	if i < 0 {
+		return ""
+	}
+	s = s[i+len(start):]
+	return s[:strings.Index(s, end)]
+}
+
+func TestSuggestConfirmSavesSuggestion(t *testing.T) {
+	ta := newTestApp(t)
+	anna, boris := testUser(1, "Anna"), testUser(2, "Boris")
+
+	confirm, _ := suggestDish(t, ta, anna.ID, "Anna")
+	if unused, _ := ta.suggestService.GetUnusedSuggestions(testChatID); len(unused) != 0 {
+		t.Fatalf("the suggestion was saved before it was confirmed")
+	}
+
+	// Nobody else can confirm it, and /reset doesn't drop it
+	press(ta.handleSuggestConfirmCallback, callback(boris, 1, confirm))
+	ta.handleReset(command(anna, "/reset"))
+	press(ta.handleSuggestConfirmCallback, callback(anna, 1, confirm))
+
+	unused, err := ta.suggestService.GetUnusedSuggestions(testChatID)
+	if err != nil {
+		t.Fatalf("GetUnusedSuggestions failed: %v", err)
+	}
+	if len(unused) != 1 || unused[0].Name != "Lasagna" || unused[0].UserID != "1" {
+		t.Fatalf("saved suggestions = %+v, want Anna's Lasagna", unused)
+	}
+
+	// A second press finds nothing to confirm
+	press(ta.handleSuggestConfirmCallback, callback(anna, 1, confirm))
+	if unused, _ := ta.suggestService.GetUnusedSuggestions(testChatID); len(unused) != 1 {
+		t.Errorf("confirming twice saved %d suggestions, want 1", len(unused))
+	}
+}
+
+func TestSuggestCancelDiscardsSuggestion(t *testing.T) {
+	ta := newTestApp(t)
+	anna, boris := testUser(1, "Anna"), testUser(2, "Boris")
+
+	confirmAnna, cancelAnna := suggestDish(t, ta, anna.ID, "Anna")
+	confirmSecond, _ := suggestDish(t, ta, anna.ID, "Anna")
+	if confirmAnna == confirmSecond {
+		t.Fatalf("two suggestions share the button data %q", confirmAnna)
+	}
+
+	// Only the suggester can cancel
+	press(ta.handleSuggestCancelCallback, callback(boris, 1, cancelAnna))
+	if _, err := ta.suggestService.GetPending(testChatID, pendingMessageID(t, cancelAnna)); err != nil {
+		t.Fatalf("someone else cancelled the suggestion: %v", err)
+	}
+
+	press(ta.handleSuggestCancelCallback, callback(anna, 1, cancelAnna))
+	press(ta.handleSuggestConfirmCallback, callback(anna, 1, confirmAnna))
+	if unused, _ := ta.suggestService.GetUnusedSuggestions(testChatID); len(unused) != 0 {
+		t.Fatalf("a cancelled suggestion was saved: %+v", unused)
+	}
+	if !strings.Contains(ta.telegram.LastText(), "expired") {
+		t.Errorf("confirming a cancelled suggestion said %q", ta.telegram.LastText())
+	}
+
+	// The other pending suggestion is untouched
+	press(ta.handleSuggestConfirmCallback, callback(anna, 1, confirmSecond))
+	if unused, _ := ta.suggestService.GetUnusedSuggestions(testChatID); len(unused) != 1 {
+		t.Errorf("the second suggestion wasn't saved after cancelling the first")
+	}
+}
+
+// pendingMessageID returns the message ID in a suggestion button's data
+func pendingMessageID(t *testing.T, data string) int {
+	t.Helper()
+
+	var id int
+	if _, err := fmt.Sscanf(data[strings.Index(data, ":")+1:], "%d", &id); err != nil {
+		t.Fatalf("invalid button data %q", data)
+	}
+	return id
+}
+
+func TestAgainResuggestsAStoredFavorite(t *testing.T) {
+	ta := newTestApp(t)
+	saveDinner := func(id int, dish models.Dish, ratings map[string]int) {
+		t.Helper()
+		d := models.Dinner{
+			ID:         fmt.Sprintf("dinner:%d:%d", testChatID, id),
+			ChannelID:  testChatID,
+			Dish:       dish,
+			StartedAt:  time.Now().AddDate(0, 0, -id),
+			FinishedAt: time.Now().AddDate(0, 0, -id).Add(time.Hour),
+			Ratings:    ratings,
+		}
+		if err := ta.store.Set(d.ID, d); err != nil {
+			t.Fatalf("failed to save dinner: %v", err)
+		}
+	}
+	lasagna := models.Dish{Name: "Lasagna", Cuisine: "Italian", Ingredients: []string{"pasta sheets"}, Instructions: []string{"Bake"}}
+	saveDinner(1, lasagna, map[string]int{"1": 5, "2": 4})
+	saveDinner(2, models.Dish{Name: "Soup", Cuisine: "French"}, map[string]int{"1": 2})
+
+	// Boris suggested the lasagna the first time
+	original, err := ta.suggestService.AddSuggestion(testChatID, "2", "boris", "Lasagna", "Italian", "")
+	if err != nil {
+		t.Fatalf("AddSuggestion failed: %v", err)
+	}
+	if err := ta.suggestService.MarkAsUsed(original.ID); err != nil {
+		t.Fatalf("MarkAsUsed failed: %v", err)
+	}
+
+	ta.handleAgain(command(testUser(1, "Anna"), "/again lasagna"))
+
+	unused, err := ta.suggestService.GetUnusedSuggestions(testChatID)
+	if err != nil {
+		t.Fatalf("GetUnusedSuggestions failed: %v", err)
+	}
+	if len(unused) != 1 {
+		t.Fatalf("%d suggestions wait for the next poll, want the lasagna", len(unused))
+	}
+	if s := unused[0]; s.Name != "Lasagna" || s.Cuisine != "Italian" || s.UserID != "2" {
+		t.Errorf("suggestion = %+v, want Boris's Italian lasagna", *s)
+	}
+	if prompts := ta.openai.Prompts(); len(prompts) != 0 {
+		t.Errorf("asked the AI %d times, want the stored dish used", len(prompts))
+	}
+	if reply := ta.telegram.LastText(); !strings.Contains(reply, "Originally suggested by @boris") {
+		t.Errorf("/again replied %q, want Boris credited", reply)
+	}
+
+	ta.handleAgain(command(testUser(1, "Anna"), "/again soup"))
+	if reply := ta.telegram.LastText(); !strings.Contains(reply, "wasn't rated highly enough") {
+		t.Errorf("/again soup replied %q, want it refused as not a favorite", reply)
+	}
+	ta.handleAgain(command(testUser(1, "Anna"), "/again pizza"))
+	if reply := ta.telegram.LastText(); !strings.Contains(reply, "can't find 'pizza'") {
+		t.Errorf("/again pizza replied %q, want it refused as never cooked", reply)
+	}
+}
+
+func TestSuggestionJoinsTheRunningPollWithItsVotes(t *testing.T) {
+	ta := newTestApp(t)
+	ta.telegram.SetMemberCount(10)
+	anna, boris := testUser(1, "Anna"), testUser(2, "Boris")
+	if _, err := ta.pollService.CreateVote(testChatID, "poll-1", 1, []string{"Pasta", "Soup"}); err != nil {
+		t.Fatalf("CreateVote failed: %v", err)
+	}
+	ta.answerPoll(boris, "poll-1", 1)
+
+	confirm, _ := suggestDish(t, ta, anna.ID, "Anna")
+	press(ta.handleSuggestConfirmCallback, callback(anna, 1, confirm))
+
+	current, err := ta.pollService.GetCurrentVote(testChatID)
+	if err != nil {
+		t.Fatalf("GetCurrentVote failed: %v", err)
+	}
+	if current.PollID == "poll-1" || strings.Join(current.Options, ",") != "Pasta,Soup,Lasagna" {
+		t.Fatalf("current vote = %s %v, want a new poll with the lasagna", current.PollID, current.Options)
+	}
+	if current.Votes["2"] != "Soup" {
+		t.Errorf("votes = %v, want Boris's vote for Soup carried over", current.Votes)
+	}
+
+	old, err := ta.pollService.GetVote(testChatID, "poll-1")
+	if err != nil {
+		t.Fatalf("GetVote failed: %v", err)
+	}
+	if old.EndedAt.IsZero() || old.WinningDish != "" {
+		t.Errorf("old vote ended at %v with winner %q, want it ended without a winner", old.EndedAt, old.WinningDish)
+	}
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"github.com/korjavin/whatsfordinner/pkg/poll"
+)
+
+// refreshTally schedules an update of the live tally message for a poll
+// The debouncer never runs two updates of a poll at once, so the tally message is only sent once.
+func (a *app) refreshTally(channelID int64, pollID string) {
+	a.tallyDebouncer.Trigger(pollID, func() {
+		currentVote, err := a.pollService.GetVote(channelID, pollID)
+		if err != nil {
+			a.log.Error("Failed to get vote for tally: %v", err)
+			return
+		}
+
+		tallyMessageID, err := a.pollService.TallyMessage(channelID, pollID)
+		if err != nil {
+			a.log.Error("Failed to get tally message: %v", err)
+			return
+		}
+
+		tallyText := poll.FormatTally(currentVote)
+		if tallyMessageID == 0 {
+			tallyMsg, err := a.bot.SendMessage(channelID, tallyText)
+			if err != nil {
+				a.log.Error("Failed to send tally message: %v", err)
+				return
+			}
+			if err := a.pollService.SetTallyMessage(channelID, pollID, tallyMsg.MessageID); err != nil {
+				a.log.Error("Failed to save tally message: %v", err)
+			}
+			return
+		}
+
+		if _, err := a.bot.EditMessage(channelID, tallyMessageID, tallyText); err != nil {
+			// Telegram rejects edits that don't change the text, which is harmless here
+			a.log.Debug("Failed to edit tally message: %v", err)
+		}
+	})
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRefreshTallySendsOnceThenEdits(t *testing.T) {
+	ta := newTestApp(t)
+	if _, err := ta.pollService.CreateVote(testChatID, "poll-1", 1, []string{"Pasta", "Soup"}); err != nil {
+		t.Fatalf("CreateVote failed: %v", err)
+	}
+
+	if err := ta.pollService.RecordVote(testChatID, "poll-1", "1", "anna", "Pasta"); err != nil {
+		t.Fatalf("RecordVote failed: %v", err)
+	}
+	ta.refreshTally(testChatID, "poll-1")
+	ta.refreshTally(testChatID, "poll-1")
+	waitFor(t, "the tally message", func() bool {
+		id, _ := ta.pollService.TallyMessage(testChatID, "poll-1")
+		return id != 0
+	})
+
+	if err := ta.pollService.RecordVote(testChatID, "poll-1", "2", "boris", "Soup"); err != nil {
+		t.Fatalf("RecordVote failed: %v", err)
+	}
+	ta.refreshTally(testChatID, "poll-1")
+	waitFor(t, "the tally edit", func() bool { return len(ta.telegram.Calls("editMessageText")) == 1 })
+
+	if sent := ta.telegram.Calls("sendMessage"); len(sent) != 1 {
+		t.Fatalf("sent %d tally messages, want 1", len(sent))
+	}
+	edit := ta.telegram.Calls("editMessageText")[0].Params.Get("text")
+	for _, want := range []string{"2 so far", "Pasta: 1", "Soup: 1"} {
+		if !strings.Contains(edit, want) {
+			t.Errorf("tally %q doesn't contain %q", edit, want)
+		}
+	}
+}
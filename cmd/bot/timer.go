@@ -0,0 +1,104 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/korjavin/whatsfordinner/pkg/dinner"
+	"github.com/korjavin/whatsfordinner/pkg/messages"
+	"github.com/korjavin/whatsfordinner/pkg/models"
+	"github.com/korjavin/whatsfordinner/pkg/scheduler"
+	"github.com/korjavin/whatsfordinner/pkg/storage"
+)
+
+// stepTimerRows builds a "Start timer" button for every recipe step that mentions a time,
+// two buttons to a row
+func stepTimerRows(dinnerID string, dish models.Dish) [][]tgbotapi.InlineKeyboardButton {
+	var buttons []tgbotapi.InlineKeyboardButton
+	for i, step := range dish.Instructions {
+		duration, ok := dinner.ParseStepDuration(step)
+		if !ok {
+			continue
+		}
+		label := fmt.Sprintf("⏱ Step %d: %s", i+1, messages.FormatDuration(duration))
+		buttons = append(buttons, tgbotapi.NewInlineKeyboardButtonData(label, fmt.Sprintf("step_timer:%d:%s", i, dinnerID)))
+	}
+
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for len(buttons) > 0 {
+		n := min(2, len(buttons))
+		rows = append(rows, buttons[:n])
+		buttons = buttons[n:]
+	}
+	return rows
+}
+
+// parseStepTimerData splits the payload of a step timer button into the step index and dinner ID
+func parseStepTimerData(payload string) (int, string, bool) {
+	indexText, dinnerID, ok := strings.Cut(payload, ":")
+	if !ok || dinnerID == "" {
+		return 0, "", false
+	}
+	index, err := strconv.Atoi(indexText)
+	if err != nil || index < 0 {
+		return 0, "", false
+	}
+	return index, dinnerID, true
+}
+
+// handleStepTimerCallback handles starting a timer for a recipe step that mentions a time
+// The format is "step_timer:{step index}:{dinner ID}"
+func (a *app) handleStepTimerCallback(callback *tgbotapi.CallbackQuery, payload string) {
+	chatID := callback.Message.Chat.ID
+
+	index, dinnerID, ok := parseStepTimerData(payload)
+	if !ok {
+		a.log.Error("Invalid callback data: %s", callback.Data)
+		a.bot.AnswerCallbackQuery(callback.ID, "Something went wrong. Please try again.")
+		return
+	}
+
+	var dinnerEvent models.Dinner
+	if err := a.store.Get(dinnerID, &dinnerEvent); err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			a.bot.AnswerCallbackQuery(callback.ID, "This dinner is no longer available.")
+			return
+		}
+		a.log.Error("Failed to get dinner event: %v", err)
+		a.bot.AnswerCallbackQuery(callback.ID, "Something went wrong. Please try again.")
+		return
+	}
+	if !dinnerEvent.FinishedAt.IsZero() {
+		a.bot.AnswerCallbackQuery(callback.ID, "This dinner is already finished.")
+		return
+	}
+	if index >= len(dinnerEvent.Dish.Instructions) {
+		a.bot.AnswerCallbackQuery(callback.ID, "This step is no longer in the recipe.")
+		return
+	}
+
+	step := dinnerEvent.Dish.Instructions[index]
+	duration, ok := dinner.ParseStepDuration(step)
+	if !ok {
+		a.bot.AnswerCallbackQuery(callback.ID, "This step doesn't mention a time.")
+		return
+	}
+
+	_, err := a.schedulerService.StartStepTimer(chatID, fmt.Sprintf("Step %d: %s", index+1, step), userName(callback.From), duration, time.Now())
+	if errors.Is(err, scheduler.ErrTooManyTimers) {
+		a.bot.AnswerCallbackQuery(callback.ID, fmt.Sprintf("You already have %d timers running.", scheduler.MaxStepTimers))
+		return
+	}
+	if err != nil {
+		a.log.Error("Failed to start step timer: %v", err)
+		a.bot.AnswerCallbackQuery(callback.ID, "Something went wrong. Please try again.")
+		return
+	}
+
+	a.bot.AnswerCallbackQuery(callback.ID, fmt.Sprintf("⏱ Timer set for %s!", messages.FormatDuration(duration)))
+	a.bot.SendMessage(chatID, fmt.Sprintf("⏱ @%s started a %s timer for step %d. I'll ping you when it's up!", messages.EscapeMarkdown(userName(callback.From)), messages.FormatDuration(duration), index+1))
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/korjavin/whatsfordinner/pkg/messages"
+	"github.com/korjavin/whatsfordinner/pkg/models"
+	"github.com/korjavin/whatsfordinner/pkg/poll"
+)
+
+// handleVote refreshes the live tally after a vote was recorded and closes the vote
+// once enough of the family has voted, for Telegram polls and vote cards alike
+func (a *app) handleVote(channelID int64, pollID string) {
+	// Refresh the live tally, coalescing bursts of votes into a single edit
+	a.refreshTally(channelID, pollID)
+
+	// Get the channel state to check the member count
+	var channelState models.ChannelState
+	err := a.store.Get(fmt.Sprintf("channel:%d", channelID), &channelState)
+	if err != nil {
+		a.log.Error("Failed to get channel state: %v", err)
+		return
+	}
+
+	// Get the latest member count from Telegram API, unless an admin set it with /set_members
+	if channelState.MemberCountManual {
+		a.log.Info("Using member count set by an admin: %d", channelState.MemberCount)
+	} else {
+		a.log.Info("Attempting to get chat member count for channel %d", channelID)
+		chatMemberCount, err := a.bot.GetChatMemberCount(channelID)
+		if err != nil {
+			a.log.Error("Failed to get chat member count: %v", err)
+			// Fallback to default value if API call fails
+			if channelState.MemberCount == 0 {
+				channelState.MemberCount = 3
+				a.log.Info("Using default member count: %d", channelState.MemberCount)
+			} else {
+				a.log.Info("Using existing member count: %d", channelState.MemberCount)
+			}
+		} else {
+			a.log.Info("Got chat member count from Telegram API: %d", chatMemberCount)
+			channelState.MemberCount = chatMemberCount - 1 // bot is not a family member
+			// Only save the member count, the rest of channelState may be stale by now
+			err = a.pollService.SetDetectedMemberCount(channelID, channelState.MemberCount)
+			if err != nil {
+				a.log.Error("Failed to update member count: %v", err)
+			}
+		}
+	}
+
+	// Check if we've reached the threshold to close the poll
+	// Members who are away don't have to vote
+	memberCount := poll.PresentMembers(channelState.MemberCount, poll.ActiveExcuses(channelState.Excused, time.Now()))
+	settings := channelState.Effective(a.cfg.ChannelDefaults())
+	thresholdReached, winningOption, err := a.pollService.CheckVoteThreshold(channelID, pollID, memberCount, settings.VoteThreshold)
+	if err != nil {
+		a.log.Error("Failed to check vote threshold: %v", err)
+		return
+	}
+
+	if thresholdReached {
+		// End the vote, picking the winner or starting a runoff the same way the scheduled closes do
+		outcome, err := a.schedulerService.CloseVote(channelID, pollID, time.Now())
+		if errors.Is(err, poll.ErrVoteEnded) {
+			// Another close got there first and already announced the winner
+			return
+		}
+		if err != nil {
+			a.log.Error("Failed to close vote: %v", err)
+			return
+		}
+		if outcome.Runoff != nil {
+			// A tie, the family votes again in the runoff poll
+			return
+		}
+		winningOption = outcome.Winner
+
+		// Send a message that the poll is closed, with the final tally
+		closeMsg := "🎉 The poll has closed! " + outcome.Summary(time.Now())
+
+		// Let the family keep voting if the poll closed too early
+		undoKeyboard := tgbotapi.NewInlineKeyboardMarkup(
+			tgbotapi.NewInlineKeyboardRow(
+				tgbotapi.NewInlineKeyboardButtonData("↩️ Reopen voting", fmt.Sprintf("undo_close:%s", pollID)),
+			),
+		)
+		a.bot.SendMessageWithKeyboard(channelID, closeMsg, undoKeyboard)
+
+		// Ask for cook volunteers
+		keyboard := tgbotapi.NewInlineKeyboardMarkup(
+			tgbotapi.NewInlineKeyboardRow(
+				tgbotapi.NewInlineKeyboardButtonData("I'll cook!", fmt.Sprintf("volunteer:%s", pollID)),
+			),
+		)
+
+		a.bot.SendMessageWithKeyboard(channelID, fmt.Sprintf("Who wants to cook %s tonight? Press the button below to volunteer!", messages.Bold(winningOption)), keyboard)
+	}
+}
+
+// handleVoteCommand handles the /vote command
+func (a *app) handleVoteCommand(message *tgbotapi.Message) {
+	chatID := message.Chat.ID
+	userID := fmt.Sprintf("%d", message.From.ID)
+
+	vote, err := a.pollService.GetCurrentVote(chatID)
+	if err != nil || vote == nil || !vote.EndedAt.IsZero() {
+		a.bot.SendMessage(chatID, "🤷 There's no poll running right now. Start one with /dinner.")
+		return
+	}
+
+	choices := ""
+	for i, option := range vote.Options {
+		choices += fmt.Sprintf("\n%d. %s", i+1, messages.EscapeMarkdown(option))
+	}
+
+	arg := strings.TrimSpace(message.CommandArguments())
+	if arg == "" {
+		a.bot.SendMessage(chatID, "🗳 Please tell me what you vote for, by number or name, e.g. /vote 1. The options are:"+choices)
+		return
+	}
+
+	option, ok := poll.MatchOption(vote.Options, arg)
+	if !ok {
+		a.bot.SendMessage(chatID, fmt.Sprintf("🤔 %s isn't one of the options. Please pick one by number or name:%s", messages.EscapeMarkdown(arg), choices))
+		return
+	}
+
+	if err := a.pollService.RecordVote(chatID, vote.PollID, userID, userName(message.From), option); err != nil {
+		if errors.Is(err, poll.ErrVoteEnded) {
+			a.bot.SendMessage(chatID, "🤷 The poll has just closed, your vote didn't make it in time.")
+			return
+		}
+		a.log.Error("Failed to record vote: %v", err)
+		a.bot.SendMessage(chatID, "😢 Sorry, I couldn't record your vote. Please try again later.")
+		return
+	}
+
+	a.bot.SendMessage(chatID, fmt.Sprintf("✅ Got it, %s votes for %s!", messages.EscapeMarkdown(message.From.FirstName), messages.Bold(option)))
+	a.handleVote(chatID, vote.PollID)
+}
+
+// handleVoteOptCallback handles a vote on a dish card, for channels that vote with cards instead of Telegram polls
+func (a *app) handleVoteOptCallback(callback *tgbotapi.CallbackQuery, payload string) {
+	chatID := callback.Message.Chat.ID
+	userID := fmt.Sprintf("%d", callback.From.ID)
+
+	pollID, indexText, _ := strings.Cut(payload, ":")
+	index, err := strconv.Atoi(indexText)
+	if pollID == "" || err != nil {
+		a.log.Error("Invalid callback data: %s", callback.Data)
+		a.bot.AnswerCallbackQuery(callback.ID, "Something went wrong. Please try again.")
+		return
+	}
+
+	vote, err := a.pollService.GetVote(chatID, pollID)
+	if err != nil {
+		a.log.Error("Failed to get vote: %v", err)
+		a.bot.AnswerCallbackQuery(callback.ID, "This vote is gone, please start a new one with /dinner.")
+		return
+	}
+	if !vote.EndedAt.IsZero() {
+		a.bot.AnswerCallbackQuery(callback.ID, "This vote has already closed.")
+		return
+	}
+	if index < 0 || index >= len(vote.Options) {
+		a.log.Error("Invalid option index %d for vote %s", index, pollID)
+		a.bot.AnswerCallbackQuery(callback.ID, "Something went wrong. Please try again.")
+		return
+	}
+
+	option := vote.Options[index]
+	if err := a.pollService.RecordVote(chatID, pollID, userID, userName(callback.From), option); err != nil {
+		if errors.Is(err, poll.ErrVoteEnded) {
+			a.bot.AnswerCallbackQuery(callback.ID, "This vote has already closed.")
+			return
+		}
+		a.log.Error("Failed to record vote: %v", err)
+		a.bot.AnswerCallbackQuery(callback.ID, "Something went wrong. Please try again.")
+		return
+	}
+
+	a.bot.AnswerCallbackQuery(callback.ID, fmt.Sprintf("You voted for %s!", option))
+	a.handleVote(chatID, pollID)
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// pollAnswer builds the update Telegram sends when a user answers a poll
+// answerPoll answers a poll the way the bot hands answers over, with the chat looked up once
+func (ta *testApp) answerPoll(from *tgbotapi.User, pollID string, optionIDs ...int) {
+	chatID, _ := ta.pollService.FindChannelByPollID(pollID)
+	ta.handlePollAnswer(chatID, &tgbotapi.PollAnswer{PollID: pollID, User: *from, OptionIDs: optionIDs})
+}
+
+func TestPollAnswerWithSeveralOptionsRecordsTheFirst(t *testing.T) {
+	ta := newTestApp(t)
+	ta.telegram.SetMemberCount(10)
+	if _, err := ta.pollService.CreateVote(testChatID, "poll-1", 1, []string{"Pasta", "Soup", "Curry"}); err != nil {
+		t.Fatalf("CreateVote failed: %v", err)
+	}
+	anna := testUser(1, "Anna")
+
+	ta.answerPoll(anna, "poll-1", 2, 0)
+	vote, err := ta.pollService.GetVote(testChatID, "poll-1")
+	if err != nil {
+		t.Fatalf("GetVote failed: %v", err)
+	}
+	if len(vote.Votes) != 1 || vote.Votes["1"] != "Curry" {
+		t.Fatalf("votes = %v, want Anna's single vote for Curry", vote.Votes)
+	}
+
+	// Changing the answer replaces the vote instead of adding one, so the threshold counts people
+	ta.answerPoll(anna, "poll-1", 1)
+	vote, _ = ta.pollService.GetVote(testChatID, "poll-1")
+	if len(vote.Votes) != 1 || vote.Votes["1"] != "Soup" {
+		t.Errorf("votes = %v after changing the answer, want Anna's single vote for Soup", vote.Votes)
+	}
+
+	// Retracting removes it
+	ta.answerPoll(anna, "poll-1")
+	vote, _ = ta.pollService.GetVote(testChatID, "poll-1")
+	if len(vote.Votes) != 0 {
+		t.Errorf("votes = %v after retracting, want none", vote.Votes)
+	}
+}
+
+func TestPollAnswerWithUnknownOptionIsIgnored(t *testing.T) {
+	ta := newTestApp(t)
+	if _, err := ta.pollService.CreateVote(testChatID, "poll-1", 1, []string{"Pasta", "Soup"}); err != nil {
+		t.Fatalf("CreateVote failed: %v", err)
+	}
+
+	ta.answerPoll(testUser(1, "Anna"), "poll-1", 5, 0)
+	vote, _ := ta.pollService.GetVote(testChatID, "poll-1")
+	if len(vote.Votes) != 0 {
+		t.Errorf("votes = %v, want none for an option the poll doesn't have", vote.Votes)
+	}
+}
+
+func TestTiedPollGetsOneRunoff(t *testing.T) {
+	ta := newTestApp(t)
+	// Two members besides the bot, so the poll closes once both voted
+	ta.telegram.SetMemberCount(3)
+	if _, err := ta.pollService.CreateVote(testChatID, "first", 1, []string{"Pasta", "Soup", "Curry"}); err != nil {
+		t.Fatalf("CreateVote failed: %v", err)
+	}
+	anna, ben := testUser(1, "Anna"), testUser(2, "Ben")
+
+	ta.answerPoll(anna, "first", 2)
+	ta.answerPoll(ben, "first", 1)
+
+	polls := ta.telegram.Calls("sendPoll")
+	if len(polls) != 1 {
+		t.Fatalf("sent %d polls after a tie, want a runoff poll", len(polls))
+	}
+	if options := polls[0].Params.Get("options"); !strings.Contains(options, "Soup") || !strings.Contains(options, "Curry") || strings.Contains(options, "Pasta") {
+		t.Errorf("runoff options = %s, want Soup and Curry", options)
+	}
+	runoff, err := ta.pollService.GetCurrentVote(testChatID)
+	if err != nil || runoff.RunoffOf != "first" {
+		t.Fatalf("current vote = %v (%v), want the runoff of the first poll", runoff, err)
+	}
+
+	// The runoff ties again, so the earliest option wins instead of another runoff
+	ta.answerPoll(anna, runoff.PollID, 1)
+	ta.answerPoll(ben, runoff.PollID, 0)
+
+	if polls := ta.telegram.Calls("sendPoll"); len(polls) != 1 {
+		t.Errorf("sent %d polls, want no second runoff", len(polls))
+	}
+	vote, err := ta.pollService.GetVote(testChatID, runoff.PollID)
+	if err != nil {
+		t.Fatalf("GetVote failed: %v", err)
+	}
+	if vote.EndedAt.IsZero() || vote.WinningDish != "Soup" {
+		t.Errorf("runoff ended at %v with winner %q, want Soup", vote.EndedAt, vote.WinningDish)
+	}
+}
+
+func TestVoteCardsCollectVotesAndClose(t *testing.T) {
+	ta := newTestApp(t)
+	// Two members besides the bot, so the vote closes once both voted
+	ta.telegram.SetMemberCount(3)
+	if err := ta.pollService.SetVoteCards(testChatID, true); err != nil {
+		t.Fatalf("SetVoteCards failed: %v", err)
+	}
+	stockFridge(t, ta, "pasta")
+	ta.openai.SetReplies(`[{"name": "Pasta", "cuisine": "Italian", "description": "Quick"}, {"name": "Soup", "cuisine": "French", "description": "Warm"}]`)
+
+	ta.startDinner(testChatID, nil)
+
+	if polls := ta.telegram.Calls("sendPoll"); len(polls) != 0 {
+		t.Fatalf("sent %d Telegram polls, want cards instead", len(polls))
+	}
+	vote, err := ta.pollService.GetCurrentVote(testChatID)
+	if err != nil {
+		t.Fatalf("GetCurrentVote failed: %v", err)
+	}
+	var buttons []string
+	for _, call := range ta.telegram.Calls("sendMessage") {
+		if markup := call.Params.Get("reply_markup"); strings.Contains(markup, "vote_opt:") {
+			buttons = append(buttons, markup)
+		}
+	}
+	if len(buttons) != 2 || !strings.Contains(buttons[1], "vote_opt:"+vote.PollID+":1") {
+		t.Fatalf("card buttons = %v, want one per dish for %s", buttons, vote.PollID)
+	}
+
+	anna, ben := testUser(1, "Anna"), testUser(2, "Ben")
+	press(ta.handleVoteOptCallback, callback(anna, 10, "vote_opt:"+vote.PollID+":1"))
+	// Changing the vote replaces it
+	press(ta.handleVoteOptCallback, callback(anna, 10, "vote_opt:"+vote.PollID+":0"))
+	vote, _ = ta.pollService.GetVote(testChatID, vote.PollID)
+	if len(vote.Votes) != 1 || vote.Votes["1"] != "Pasta" || !vote.EndedAt.IsZero() {
+		t.Fatalf("votes = %v (ended %v), want Anna's vote for Pasta and the vote still open", vote.Votes, vote.EndedAt)
+	}
+
+	press(ta.handleVoteOptCallback, callback(ben, 11, "vote_opt:"+vote.PollID+":0"))
+	vote, _ = ta.pollService.GetVote(testChatID, vote.PollID)
+	if vote.EndedAt.IsZero() || vote.WinningDish != "Pasta" {
+		t.Fatalf("vote = %+v, want it closed with Pasta winning", *vote)
+	}
+
+	// Late votes are turned away
+	press(ta.handleVoteOptCallback, callback(testUser(3, "Cleo"), 10, "vote_opt:"+vote.PollID+":1"))
+	answers := ta.telegram.Calls("answerCallbackQuery")
+	if got := answers[len(answers)-1].Params.Get("text"); got != "This vote has already closed." {
+		t.Errorf("late vote was answered with %q, want the vote closed", got)
+	}
+}
+
+func TestVoteCommandRecordsVotesAndClosesThePoll(t *testing.T) {
+	ta := newTestApp(t)
+	// Two members besides the bot, so the poll closes once both voted
+	ta.telegram.SetMemberCount(3)
+	if _, err := ta.pollService.CreateVote(testChatID, "poll-1", 1, []string{"Pasta", "Soup"}); err != nil {
+		t.Fatalf("CreateVote failed: %v", err)
+	}
+	anna, ben := testUser(1, "Anna"), testUser(2, "Ben")
+
+	ta.handleVoteCommand(command(anna, "/vote curry"))
+	if reply := ta.telegram.LastText(); !strings.Contains(reply, "isn't one of the options") {
+		t.Errorf("/vote curry replied %q, want the options listed", reply)
+	}
+
+	ta.handleVoteCommand(command(anna, "/vote soup"))
+	vote, _ := ta.pollService.GetVote(testChatID, "poll-1")
+	if vote.Votes["1"] != "Soup" || !vote.EndedAt.IsZero() {
+		t.Fatalf("votes = %v (ended %v), want Anna's vote for Soup and the poll still open", vote.Votes, vote.EndedAt)
+	}
+
+	// By number, which re-checks the threshold
+	ta.handleVoteCommand(command(ben, "/vote 2"))
+	vote, _ = ta.pollService.GetVote(testChatID, "poll-1")
+	if vote.Votes["2"] != "Soup" || vote.EndedAt.IsZero() || vote.WinningDish != "Soup" {
+		t.Fatalf("vote = %+v, want it closed with Soup winning", *vote)
+	}
+
+	ta.handleVoteCommand(command(anna, "/vote 1"))
+	if reply := ta.telegram.LastText(); !strings.Contains(reply, "There's no poll running") {
+		t.Errorf("/vote after the close replied %q, want no poll running", reply)
+	}
+}
+
+func TestVoteCommandEscapesNamesAndOptions(t *testing.T) {
+	ta := newTestApp(t)
+	ta.telegram.SetMemberCount(10)
+	if _, err := ta.pollService.CreateVote(testChatID, "poll-1", 1, []string{"Mac_Cheese", "Soup"}); err != nil {
+		t.Fatalf("CreateVote failed: %v", err)
+	}
+	anna := testUser(1, "Anna_K")
+
+	ta.handleVoteCommand(command(anna, "/vote *pizza*"))
+	if got, want := ta.telegram.LastText(), "🤔 \\*pizza\\* isn't one of the options. Please pick one by number or name:\n1. Mac\\_Cheese\n2. Soup"; got != want {
+		t.Errorf("/vote with an unknown option replied %q, want %q", got, want)
+	}
+
+	ta.handleVoteCommand(command(anna, "/vote 1"))
+	sent := ta.telegram.Calls("sendMessage")
+	if got, want := sent[len(sent)-1].Params.Get("text"), "✅ Got it, Anna\\_K votes for *Mac_Cheese*!"; got != want {
+		t.Errorf("/vote replied %q, want %q", got, want)
+	}
+	for _, call := range sent {
+		if call.Params.Get("parse_mode") != "Markdown" {
+			t.Errorf("%q was sent without Markdown", call.Params.Get("text"))
+		}
+	}
+}
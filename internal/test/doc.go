@@ -0,0 +1,3 @@
+// Package test provides shared helpers for the bot's tests: a temporary store
+// and fakes of the Telegram Bot API and the OpenAI API that run on a local HTTP server.
+package test
@@ -0,0 +1,105 @@
+package test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+// OpenAI is a fake OpenAI API that answers chat completions with canned replies
+type OpenAI struct {
+	server *httptest.Server
+
+	mu      sync.Mutex
+	replies []string
+	fail    bool
+	prompts []string
+}
+
+// NewOpenAI starts a fake OpenAI API that is shut down when the test ends.
+// It answers with the given replies in order and repeats the last one.
+func NewOpenAI(t *testing.T, replies ...string) *OpenAI {
+	t.Helper()
+
+	fake := &OpenAI{replies: replies}
+	fake.server = httptest.NewServer(http.HandlerFunc(fake.handle))
+	t.Cleanup(fake.server.Close)
+
+	return fake
+}
+
+// BaseURL returns the API base URL to create a client with, see openai.New
+func (f *OpenAI) BaseURL() string {
+	return f.server.URL + "/v1"
+}
+
+// SetReplies replaces the replies still to come, the last one is repeated
+func (f *OpenAI) SetReplies(replies ...string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.replies = replies
+}
+
+// SetFailing makes every request fail with a server error, or succeed again
+func (f *OpenAI) SetFailing(fail bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.fail = fail
+}
+
+// Prompts returns the user prompts of all requests, in order
+func (f *OpenAI) Prompts() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.prompts...)
+}
+
+// handle answers a chat completion request with the next reply and a fixed token usage
+func (f *OpenAI) handle(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		Messages []struct {
+			Role    string          `json:"role"`
+			Content json.RawMessage `json:"content"`
+		} `json:"messages"`
+	}
+	json.NewDecoder(r.Body).Decode(&request)
+
+	f.mu.Lock()
+	for _, message := range request.Messages {
+		var text string
+		if json.Unmarshal(message.Content, &text) == nil {
+			f.prompts = append(f.prompts, text)
+		} else {
+			f.prompts = append(f.prompts, string(message.Content))
+		}
+	}
+	if f.fail {
+		f.mu.Unlock()
+		w.WriteHeader(http.StatusInternalServerError)
+		writeJSON(w, map[string]interface{}{"error": map[string]interface{}{"message": "server error", "type": "server_error"}})
+		return
+	}
+	reply := ""
+	if len(f.replies) > 0 {
+		reply = f.replies[0]
+		if len(f.replies) > 1 {
+			f.replies = f.replies[1:]
+		}
+	}
+	f.mu.Unlock()
+
+	writeJSON(w, map[string]interface{}{
+		"id":      "chatcmpl-test",
+		"object":  "chat.completion",
+		"created": 0,
+		"model":   "test",
+		"choices": []map[string]interface{}{{
+			"index":         0,
+			"message":       map[string]interface{}{"role": "assistant", "content": reply},
+			"finish_reason": "stop",
+		}},
+		"usage": map[string]interface{}{"prompt_tokens": 10, "completion_tokens": 5, "total_tokens": 15},
+	})
+}
@@ -0,0 +1,20 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/korjavin/whatsfordinner/pkg/storage"
+)
+
+// NewStore opens a store in a temporary directory that is removed when the test ends
+func NewStore(t *testing.T) *storage.Store {
+	t.Helper()
+
+	store, err := storage.New(t.TempDir(), false)
+	if err != nil {
+		t.Fatalf("failed to open store: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	return store
+}
@@ -0,0 +1,236 @@
+package test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TelegramCall is a request the bot sent to the fake Telegram Bot API
+type TelegramCall struct {
+	Method string
+	Params url.Values
+}
+
+// Telegram is a fake Telegram Bot API. It records every request and answers them
+// like Telegram would, giving sent messages increasing IDs and polls IDs like "poll-1".
+type Telegram struct {
+	server *httptest.Server
+
+	mu          sync.Mutex
+	calls       []TelegramCall
+	nextID      int
+	memberCount int
+	admins      map[int64]bool
+	failures    map[string][]telegramError
+}
+
+// telegramError is an error the fake returns for the next call of a method
+type telegramError struct {
+	code        int
+	description string
+	retryAfter  int
+}
+
+// NewTelegram starts a fake Telegram Bot API that is shut down when the test ends.
+// Chats have 3 members by default and nobody is an admin.
+func NewTelegram(t *testing.T) *Telegram {
+	t.Helper()
+
+	fake := &Telegram{
+		memberCount: 3,
+		admins:      make(map[int64]bool),
+		failures:    make(map[string][]telegramError),
+	}
+	fake.server = httptest.NewServer(http.HandlerFunc(fake.handle))
+	t.Cleanup(fake.server.Close)
+
+	return fake
+}
+
+// Endpoint returns the API endpoint to create a bot with, see telegram.NewWithEndpoint
+func (f *Telegram) Endpoint() string {
+	return f.server.URL + "/bot%s/%s"
+}
+
+// SetMemberCount sets the member count reported for every chat
+func (f *Telegram) SetMemberCount(count int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.memberCount = count
+}
+
+// SetAdmin makes a user an admin of every chat
+func (f *Telegram) SetAdmin(userID int64) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.admins[userID] = true
+}
+
+// Fail makes the next call of a method fail with the given error code and description
+func (f *Telegram) Fail(method string, code int, description string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.failures[method] = append(f.failures[method], telegramError{code: code, description: description})
+}
+
+// RateLimit makes the next call of a method fail with 429 Too Many Requests, asking to retry after the given seconds
+func (f *Telegram) RateLimit(method string, retryAfter int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.failures[method] = append(f.failures[method], telegramError{
+		code:        http.StatusTooManyRequests,
+		description: fmt.Sprintf("Too Many Requests: retry after %d", retryAfter),
+		retryAfter:  retryAfter,
+	})
+}
+
+// Calls returns the recorded requests of a method, or of all methods if method is empty
+func (f *Telegram) Calls(method string) []TelegramCall {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var calls []TelegramCall
+	for _, call := range f.calls {
+		if method == "" || call.Method == method {
+			calls = append(calls, call)
+		}
+	}
+	return calls
+}
+
+// Texts returns the texts of all sent and edited messages, in order
+func (f *Telegram) Texts() []string {
+	var texts []string
+	for _, call := range f.Calls("") {
+		if call.Method == "sendMessage" || call.Method == "editMessageText" {
+			texts = append(texts, call.Params.Get("text"))
+		}
+	}
+	return texts
+}
+
+// LastText returns the text of the last sent or edited message
+func (f *Telegram) LastText() string {
+	texts := f.Texts()
+	if len(texts) == 0 {
+		return ""
+	}
+	return texts[len(texts)-1]
+}
+
+// Reset forgets the recorded requests
+func (f *Telegram) Reset() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = nil
+}
+
+// handle answers a Bot API request
+func (f *Telegram) handle(w http.ResponseWriter, r *http.Request) {
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/") {
+		r.ParseMultipartForm(1 << 20)
+	} else {
+		r.ParseForm()
+	}
+	method := r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:]
+	params := r.Form
+
+	f.mu.Lock()
+	if method != "getMe" {
+		f.calls = append(f.calls, TelegramCall{Method: method, Params: params})
+	}
+	if failures := f.failures[method]; len(failures) > 0 {
+		f.failures[method] = failures[1:]
+		f.mu.Unlock()
+		writeJSON(w, map[string]interface{}{
+			"ok":          false,
+			"error_code":  failures[0].code,
+			"description": failures[0].description,
+			"parameters":  map[string]interface{}{"retry_after": failures[0].retryAfter},
+		})
+		return
+	}
+	result := f.result(method, params)
+	f.mu.Unlock()
+
+	writeJSON(w, map[string]interface{}{"ok": true, "result": result})
+}
+
+// result builds the result of a successful request, f.mu must be held
+func (f *Telegram) result(method string, params url.Values) interface{} {
+	chatID, _ := strconv.ParseInt(params.Get("chat_id"), 10, 64)
+
+	switch method {
+	case "getMe":
+		return map[string]interface{}{"id": 1, "is_bot": true, "first_name": "Dinner", "username": "dinner_bot"}
+	case "sendMessage", "editMessageText", "editMessageReplyMarkup", "sendDocument", "sendPhoto":
+		messageID := f.messageID(params)
+		return map[string]interface{}{
+			"message_id": messageID,
+			"date":       time.Now().Unix(),
+			"chat":       map[string]interface{}{"id": chatID, "type": "group"},
+			"text":       params.Get("text"),
+		}
+	case "sendPoll":
+		f.nextID++
+		var options []string
+		json.Unmarshal([]byte(params.Get("options")), &options)
+		pollOptions := make([]map[string]interface{}, len(options))
+		for i, option := range options {
+			pollOptions[i] = map[string]interface{}{"text": option, "voter_count": 0}
+		}
+		return map[string]interface{}{
+			"message_id": f.nextID,
+			"date":       time.Now().Unix(),
+			"chat":       map[string]interface{}{"id": chatID, "type": "group"},
+			"poll": map[string]interface{}{
+				"id":       fmt.Sprintf("poll-%d", f.nextID),
+				"question": params.Get("question"),
+				"options":  pollOptions,
+			},
+		}
+	case "getChatMemberCount", "getChatMembersCount":
+		return f.memberCount
+	case "getChatMember":
+		userID, _ := strconv.ParseInt(params.Get("user_id"), 10, 64)
+		status := "member"
+		if f.admins[userID] {
+			status = "administrator"
+		}
+		return map[string]interface{}{"status": status, "user": map[string]interface{}{"id": userID, "first_name": "User"}}
+	case "getChatAdministrators":
+		var admins []map[string]interface{}
+		for userID := range f.admins {
+			admins = append(admins, map[string]interface{}{
+				"status": "administrator",
+				"user":   map[string]interface{}{"id": userID, "first_name": fmt.Sprintf("Admin %d", userID)},
+			})
+		}
+		return admins
+	default:
+		return true
+	}
+}
+
+// messageID returns the ID of an edited message, or a new ID for a new message, f.mu must be held
+func (f *Telegram) messageID(params url.Values) int {
+	if id, err := strconv.Atoi(params.Get("message_id")); err == nil {
+		return id
+	}
+	f.nextID++
+	return f.nextID
+}
+
+// writeJSON writes a value as a JSON response
+func writeJSON(w http.ResponseWriter, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(value)
+}
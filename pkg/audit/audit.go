@@ -0,0 +1,87 @@
+package audit
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/korjavin/whatsfordinner/pkg/logger"
+	"github.com/korjavin/whatsfordinner/pkg/models"
+	"github.com/korjavin/whatsfordinner/pkg/storage"
+)
+
+// Event types, one per workflow state transition
+const (
+	VoteCreated      = "vote_created"
+	VoteEnded        = "vote_ended"
+	VoteReopened     = "vote_reopened"
+	VoteRerolled     = "vote_rerolled"
+	RunoffCreated    = "runoff_created"
+	CookSelected     = "cook_selected"
+	CookHandoff      = "cook_handoff"
+	DinnerCreated    = "dinner_created"
+	DinnerFinished   = "dinner_finished"
+	RatingsFinalized = "ratings_finalized"
+	RatingsReopened  = "ratings_reopened"
+)
+
+// DefaultLimit is how many events /audit shows when no number is given
+const DefaultLimit = 20
+
+// MaxLimit is the most events /audit shows, so the list fits in one Telegram message
+const MaxLimit = 30
+
+// Service records and reads audit events
+type Service struct {
+	store  *storage.Store
+	logger *logger.Logger
+}
+
+// New creates a new audit service
+func New(store *storage.Store) *Service {
+	return &Service{
+		store:  store,
+		logger: logger.New(""),
+	}
+}
+
+// Record appends an event to a channel's audit log
+// Failures are only logged, so auditing never breaks the transition it records
+func (s *Service) Record(channelID int64, eventType, format string, args ...interface{}) {
+	now := time.Now()
+	event := models.AuditEvent{
+		ChannelID: channelID,
+		Type:      eventType,
+		Details:   fmt.Sprintf(format, args...),
+		At:        now,
+	}
+
+	// Nanosecond timestamps have the same number of digits for centuries, so keys sort by time
+	key := fmt.Sprintf("audit:%d:%d", channelID, now.UnixNano())
+	if err := s.store.Set(key, event); err != nil {
+		s.logger.Error("Failed to record %s audit event for channel %d: %v", eventType, channelID, err)
+	}
+}
+
+// Recent returns the last limit events of a channel, oldest first
+func (s *Service) Recent(channelID int64, limit int) ([]models.AuditEvent, error) {
+	keys, err := s.store.List(fmt.Sprintf("audit:%d:", channelID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list audit events: %w", err)
+	}
+
+	if limit > 0 && len(keys) > limit {
+		keys = keys[len(keys)-limit:]
+	}
+
+	events := make([]models.AuditEvent, 0, len(keys))
+	for _, key := range keys {
+		var event models.AuditEvent
+		if err := s.store.Get(key, &event); err != nil {
+			s.logger.Error("Failed to get audit event %s: %v", key, err)
+			continue
+		}
+		events = append(events, event)
+	}
+
+	return events, nil
+}
@@ -0,0 +1,3 @@
+// Package audit keeps a per-channel log of workflow state transitions for the WhatsForDinner bot,
+// like votes being created and ended or dinners being started and finished.
+package audit
@@ -1,12 +1,20 @@
 package config
 
 import (
+	"errors"
 	"fmt"
 	"log"
+	"net"
+	"net/url"
 	"os"
+	"regexp"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
+	"github.com/korjavin/whatsfordinner/pkg/dinner"
+	"github.com/korjavin/whatsfordinner/pkg/models"
 )
 
 // Config holds all configuration for the application
@@ -18,12 +26,68 @@ type Config struct {
 	OpenAIAPIBase string
 	OpenAIAPIKey  string
 	OpenAIModel   string
+	// OpenAICostPer1K is the price of 1000 tokens for usage cost estimates, 0 disables them
+	OpenAICostPer1K float64
 
 	// Application configuration
 	Cuisines []string
+
+	// CookVolunteerTimeout is how long to wait for a cook volunteer
+	// before the dinner workflow is restarted
+	CookVolunteerTimeout time.Duration
+
+	// VoteIdleGrace closes a poll once nobody voted for this long, 0 disables it
+	VoteIdleGrace time.Duration
+
+	// RepeatCooldown is how soon the same dish may win a poll again and what happens if it does
+	RepeatCooldown dinner.Cooldown
+
+	// MetricsAddr is the address the /metrics HTTP endpoint listens on
+	MetricsAddr string
+
+	// UpdateWorkers is the number of Telegram updates handled at the same time
+	UpdateWorkers int
+
+	// ImageMaxDimension is the longest side photos are downscaled to before they are sent to the AI
+	ImageMaxDimension int
+
+	// MaxPhotosPerSession is how many photos one /add_photo or /sync_fridge session reads with the AI
+	MaxPhotosPerSession int
+
+	// UseAIMessages makes chat messages like the welcome message AI-generated instead of static
+	UseAIMessages bool
+
+	// RatingScale is the highest rating a dinner can get, e.g. 5 for 1-5 stars or 2 for thumbs down/up
+	RatingScale int
+
+	// DevMode enables development helpers like /simulate, never turn it on in production
+	DevMode bool
+
+	// BadgerSyncWrites makes every database write wait until it's on disk, trading speed for durability
+	BadgerSyncWrites bool
+}
+
+// ChannelDefaults returns the settings of a channel that hasn't changed any of them,
+// with the configured cuisines, cook volunteer timeout and rating scale
+func (c *Config) ChannelDefaults() models.Settings {
+	defaults := models.DefaultSettings()
+	if len(c.Cuisines) > 0 {
+		defaults.Cuisines = c.Cuisines
+	}
+	if c.CookVolunteerTimeout > 0 {
+		defaults.CookVolunteerTimeout = c.CookVolunteerTimeout
+	}
+	if c.RatingScale > 0 {
+		defaults.RatingScale = c.RatingScale
+	}
+	return defaults
 }
 
+// modelPattern matches plausible model names like "gpt-4o-mini" or "meta-llama/llama-3.1-70b"
+var modelPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._:/-]*$`)
+
 // LoadFromEnv loads configuration from environment variables
+// All invalid values are reported at once in a single error
 func LoadFromEnv() (*Config, error) {
 	// Load .env file if it exists
 	err := godotenv.Load()
@@ -32,27 +96,154 @@ func LoadFromEnv() (*Config, error) {
 	}
 
 	cfg := &Config{}
+	var errs []error
 
 	// Required configurations
-	botToken := os.Getenv("BOT_TOKEN")
-	if botToken == "" {
-		return nil, fmt.Errorf("BOT_TOKEN environment variable is required")
+	cfg.BotToken = strings.TrimSpace(os.Getenv("BOT_TOKEN"))
+	if cfg.BotToken == "" {
+		errs = append(errs, fmt.Errorf("BOT_TOKEN environment variable is required"))
 	}
-	cfg.BotToken = botToken
 
-	openAIAPIKey := os.Getenv("OPENAI_API_KEY")
-	if openAIAPIKey == "" {
-		return nil, fmt.Errorf("OPENAI_API_KEY environment variable is required")
+	cfg.OpenAIAPIKey = strings.TrimSpace(os.Getenv("OPENAI_API_KEY"))
+	if cfg.OpenAIAPIKey == "" {
+		errs = append(errs, fmt.Errorf("OPENAI_API_KEY environment variable is required"))
 	}
-	cfg.OpenAIAPIKey = openAIAPIKey
 
 	// Optional configurations with defaults
 	cfg.OpenAIAPIBase = getEnvWithDefault("OPENAI_API_BASE", "https://api.openai.com/v1")
+	if apiBase, err := url.Parse(cfg.OpenAIAPIBase); err != nil || (apiBase.Scheme != "http" && apiBase.Scheme != "https") || apiBase.Host == "" {
+		errs = append(errs, fmt.Errorf("invalid OPENAI_API_BASE %q: must be an http or https URL", cfg.OpenAIAPIBase))
+	}
+
 	cfg.OpenAIModel = getEnvWithDefault("OPENAI_MODEL", "gpt-3.5-turbo")
+	if !modelPattern.MatchString(cfg.OpenAIModel) {
+		errs = append(errs, fmt.Errorf("invalid OPENAI_MODEL %q: must be a model name like gpt-4o-mini", cfg.OpenAIModel))
+	}
+
+	cfg.MetricsAddr = getEnvWithDefault("METRICS_ADDR", ":8080")
+	if _, _, err := net.SplitHostPort(cfg.MetricsAddr); err != nil {
+		errs = append(errs, fmt.Errorf("invalid METRICS_ADDR %q: must be host:port or :port", cfg.MetricsAddr))
+	}
+
+	// Parse the optional token price
+	if costStr := os.Getenv("OPENAI_COST_PER_1K_TOKENS"); costStr != "" {
+		cost, err := strconv.ParseFloat(costStr, 64)
+		if err != nil || cost < 0 {
+			errs = append(errs, fmt.Errorf("invalid OPENAI_COST_PER_1K_TOKENS %q: must be a non-negative number", costStr))
+		}
+		cfg.OpenAICostPer1K = cost
+	}
 
-	// Parse cuisines
+	// Parse cuisines, trimming spaces and skipping empty entries like the one after a trailing comma
+	// and repeated cuisines like "Italian, italian"
 	cuisinesStr := getEnvWithDefault("CUISINES", "European,Russian,Italian")
-	cfg.Cuisines = strings.Split(cuisinesStr, ",")
+	seenCuisines := make(map[string]bool)
+	for _, cuisine := range strings.Split(cuisinesStr, ",") {
+		cuisine = strings.TrimSpace(cuisine)
+		if cuisine == "" || seenCuisines[strings.ToLower(cuisine)] {
+			continue
+		}
+		seenCuisines[strings.ToLower(cuisine)] = true
+		cfg.Cuisines = append(cfg.Cuisines, cuisine)
+	}
+	if len(cfg.Cuisines) == 0 {
+		errs = append(errs, fmt.Errorf("invalid CUISINES %q: must list at least one cuisine", cuisinesStr))
+	}
+
+	// Parse cook volunteer timeout
+	cookTimeoutStr := getEnvWithDefault("COOK_VOLUNTEER_TIMEOUT", models.DefaultCookVolunteerTimeout.String())
+	cookTimeout, err := time.ParseDuration(cookTimeoutStr)
+	if err != nil {
+		errs = append(errs, fmt.Errorf("invalid COOK_VOLUNTEER_TIMEOUT %q: %w", cookTimeoutStr, err))
+	} else if cookTimeout <= 0 {
+		errs = append(errs, fmt.Errorf("COOK_VOLUNTEER_TIMEOUT must be positive, got %s", cookTimeoutStr))
+	}
+	cfg.CookVolunteerTimeout = cookTimeout
+
+	// Parse the idle vote grace period
+	idleGraceStr := getEnvWithDefault("VOTE_IDLE_GRACE", "0")
+	idleGrace, err := time.ParseDuration(idleGraceStr)
+	if err != nil {
+		errs = append(errs, fmt.Errorf("invalid VOTE_IDLE_GRACE %q: %w", idleGraceStr, err))
+	} else if idleGrace < 0 {
+		errs = append(errs, fmt.Errorf("VOTE_IDLE_GRACE must not be negative, got %s", idleGraceStr))
+	}
+	cfg.VoteIdleGrace = idleGrace
+
+	// Parse the repeat cooldown
+	repeatWindowStr := getEnvWithDefault("REPEAT_WINDOW", "72h")
+	repeatWindow, err := time.ParseDuration(repeatWindowStr)
+	if err != nil {
+		errs = append(errs, fmt.Errorf("invalid REPEAT_WINDOW %q: %w", repeatWindowStr, err))
+	} else if repeatWindow < 0 {
+		errs = append(errs, fmt.Errorf("REPEAT_WINDOW must not be negative, got %s", repeatWindowStr))
+	}
+	repeatPolicyStr := getEnvWithDefault("REPEAT_POLICY", string(dinner.RepeatWarn))
+	repeatPolicy, err := dinner.ParseRepeatPolicy(repeatPolicyStr)
+	if err != nil {
+		errs = append(errs, fmt.Errorf("invalid REPEAT_POLICY %q: %w", repeatPolicyStr, err))
+	}
+	cfg.RepeatCooldown = dinner.Cooldown{Window: repeatWindow, Policy: repeatPolicy}
+
+	// Parse the number of update workers
+	workersStr := getEnvWithDefault("UPDATE_WORKERS", "8")
+	workers, err := strconv.Atoi(workersStr)
+	if err != nil || workers < 1 {
+		errs = append(errs, fmt.Errorf("invalid UPDATE_WORKERS %q: must be a positive number", workersStr))
+	}
+	cfg.UpdateWorkers = workers
+
+	// Parse the maximum photo size
+	maxDimensionStr := getEnvWithDefault("IMAGE_MAX_DIMENSION", "1024")
+	maxDimension, err := strconv.Atoi(maxDimensionStr)
+	if err != nil || maxDimension < 1 {
+		errs = append(errs, fmt.Errorf("invalid IMAGE_MAX_DIMENSION %q: must be a positive number", maxDimensionStr))
+	}
+	cfg.ImageMaxDimension = maxDimension
+
+	// Parse the photo limit per session
+	maxPhotosStr := getEnvWithDefault("MAX_PHOTOS_PER_SESSION", "10")
+	maxPhotos, err := strconv.Atoi(maxPhotosStr)
+	if err != nil || maxPhotos < 1 {
+		errs = append(errs, fmt.Errorf("invalid MAX_PHOTOS_PER_SESSION %q: must be a positive number", maxPhotosStr))
+	}
+	cfg.MaxPhotosPerSession = maxPhotos
+
+	// Parse the AI message flag
+	useAIStr := getEnvWithDefault("USE_AI_MESSAGES", "true")
+	useAI, err := strconv.ParseBool(useAIStr)
+	if err != nil {
+		errs = append(errs, fmt.Errorf("invalid USE_AI_MESSAGES %q: must be true or false", useAIStr))
+	}
+	cfg.UseAIMessages = useAI
+
+	// Parse the rating scale
+	ratingScaleStr := getEnvWithDefault("RATING_SCALE", strconv.Itoa(dinner.DefaultRatingScale))
+	ratingScale, err := strconv.Atoi(ratingScaleStr)
+	if err != nil || ratingScale < dinner.MinRatingScale || ratingScale > dinner.MaxRatingScale {
+		errs = append(errs, fmt.Errorf("invalid RATING_SCALE %q: must be a number from %d to %d", ratingScaleStr, dinner.MinRatingScale, dinner.MaxRatingScale))
+	}
+	cfg.RatingScale = ratingScale
+
+	// Parse the development mode flag
+	devModeStr := getEnvWithDefault("DEV_MODE", "false")
+	devMode, err := strconv.ParseBool(devModeStr)
+	if err != nil {
+		errs = append(errs, fmt.Errorf("invalid DEV_MODE %q: must be true or false", devModeStr))
+	}
+	cfg.DevMode = devMode
+
+	// Parse the database sync mode
+	syncWritesStr := getEnvWithDefault("BADGER_SYNC_WRITES", "false")
+	syncWrites, err := strconv.ParseBool(syncWritesStr)
+	if err != nil {
+		errs = append(errs, fmt.Errorf("invalid BADGER_SYNC_WRITES %q: must be true or false", syncWritesStr))
+	}
+	cfg.BadgerSyncWrites = syncWrites
+
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("invalid configuration:\n%w", errors.Join(errs...))
+	}
 
 	// Log configuration with sensitive data redacted
 	logCfg := *cfg
@@ -0,0 +1,122 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// setEnv sets a valid configuration, changed by the given variables
+func setEnv(t *testing.T, vars map[string]string) {
+	t.Helper()
+
+	env := map[string]string{
+		"BOT_TOKEN":      "123456:token",
+		"OPENAI_API_KEY": "sk-test",
+	}
+	for key, value := range vars {
+		env[key] = value
+	}
+	for key, value := range env {
+		t.Setenv(key, value)
+	}
+}
+
+func TestLoadFromEnvDefaults(t *testing.T) {
+	setEnv(t, nil)
+
+	cfg, err := LoadFromEnv()
+	if err != nil {
+		t.Fatalf("LoadFromEnv failed: %v", err)
+	}
+	if cfg.OpenAIAPIBase != "https://api.openai.com/v1" || cfg.UpdateWorkers != 8 || cfg.ImageMaxDimension != 1024 {
+		t.Errorf("config = %+v, want the defaults", cfg)
+	}
+	if cfg.BadgerSyncWrites {
+		t.Error("sync writes are on by default, want them off")
+	}
+
+	setEnv(t, map[string]string{"BADGER_SYNC_WRITES": "true"})
+	cfg, err = LoadFromEnv()
+	if err != nil {
+		t.Fatalf("LoadFromEnv failed: %v", err)
+	}
+	if !cfg.BadgerSyncWrites {
+		t.Error("BADGER_SYNC_WRITES=true didn't turn sync writes on")
+	}
+}
+
+func TestLoadFromEnvReportsEveryProblem(t *testing.T) {
+	tests := []struct {
+		name string
+		env  map[string]string
+		want []string
+	}{
+		{
+			name: "missing secrets",
+			env:  map[string]string{"BOT_TOKEN": " ", "OPENAI_API_KEY": ""},
+			want: []string{"BOT_TOKEN", "OPENAI_API_KEY"},
+		},
+		{
+			name: "bad AI settings",
+			env:  map[string]string{"OPENAI_API_BASE": "api.openai.com", "OPENAI_MODEL": "gpt 4", "OPENAI_COST_PER_1K_TOKENS": "-1"},
+			want: []string{"OPENAI_API_BASE", "OPENAI_MODEL", "OPENAI_COST_PER_1K_TOKENS"},
+		},
+		{
+			name: "empty cuisines",
+			env:  map[string]string{"CUISINES": " , ,"},
+			want: []string{"CUISINES"},
+		},
+		{
+			name: "bad durations",
+			env:  map[string]string{"COOK_VOLUNTEER_TIMEOUT": "0s", "VOTE_IDLE_GRACE": "soon", "REPEAT_WINDOW": "-1h", "REPEAT_POLICY": "never"},
+			want: []string{"COOK_VOLUNTEER_TIMEOUT", "VOTE_IDLE_GRACE", "REPEAT_WINDOW", "REPEAT_POLICY"},
+		},
+		{
+			name: "bad numbers and flags",
+			env: map[string]string{
+				"UPDATE_WORKERS": "0", "IMAGE_MAX_DIMENSION": "big", "MAX_PHOTOS_PER_SESSION": "-2",
+				"RATING_SCALE": "100", "USE_AI_MESSAGES": "maybe", "DEV_MODE": "2", "BADGER_SYNC_WRITES": "yes please",
+				"METRICS_ADDR": "8080",
+			},
+			want: []string{"UPDATE_WORKERS", "IMAGE_MAX_DIMENSION", "MAX_PHOTOS_PER_SESSION", "RATING_SCALE", "USE_AI_MESSAGES", "DEV_MODE", "BADGER_SYNC_WRITES", "METRICS_ADDR"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setEnv(t, tt.env)
+
+			cfg, err := LoadFromEnv()
+			if err == nil {
+				t.Fatalf("LoadFromEnv() = %+v, want an error", cfg)
+			}
+			for _, key := range tt.want {
+				if !strings.Contains(err.Error(), key) {
+					t.Errorf("error %q doesn't mention %s", err, key)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadFromEnvCleansCuisines(t *testing.T) {
+	tests := []struct {
+		cuisines string
+		want     []string
+	}{
+		{"European, Russian", []string{"European", "Russian"}},
+		{" Italian ,Thai,", []string{"Italian", "Thai"}},
+		{"Italian,, italian ,Mexican", []string{"Italian", "Mexican"}},
+	}
+	for _, tt := range tests {
+		setEnv(t, map[string]string{"CUISINES": tt.cuisines})
+
+		cfg, err := LoadFromEnv()
+		if err != nil {
+			t.Fatalf("LoadFromEnv failed: %v", err)
+		}
+		if !reflect.DeepEqual(cfg.Cuisines, tt.want) {
+			t.Errorf("CUISINES=%q gives %q, want %q", tt.cuisines, cfg.Cuisines, tt.want)
+		}
+	}
+}
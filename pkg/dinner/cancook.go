@@ -0,0 +1,44 @@
+package dinner
+
+import (
+	"sort"
+)
+
+// CanCookThreshold is the share of ingredients a dish needs to have in the fridge to count as cookable
+const CanCookThreshold = 0.8
+
+// CanCook returns the known dishes the channel has at least minScore (a share from 0 to 1) of the ingredients for,
+// most complete first. It scores the saved dishes instead of asking the AI for suggestions.
+func (s *Service) CanCook(channelID int64, minScore float64) ([]ScoredDish, error) {
+	dishes, err := s.GetDishes()
+	if err != nil {
+		return nil, err
+	}
+
+	ingredients, err := s.fridgeService.ListIngredients(channelID)
+	if err != nil {
+		return nil, err
+	}
+	ingredientNames := make([]string, len(ingredients))
+	for i, ingredient := range ingredients {
+		ingredientNames[i] = ingredient.Name
+	}
+	staples := s.GetStaples(channelID)
+
+	var matches []ScoredDish
+	for _, dish := range dishes {
+		match := ScoreDish(dish, ingredientNames, staples)
+		if match.Score >= minScore {
+			matches = append(matches, match)
+		}
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].Score != matches[j].Score {
+			return matches[i].Score > matches[j].Score
+		}
+		return matches[i].Dish.Name < matches[j].Dish.Name
+	})
+
+	return matches, nil
+}
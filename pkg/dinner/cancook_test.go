@@ -0,0 +1,56 @@
+package dinner
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/korjavin/whatsfordinner/pkg/models"
+)
+
+func TestCanCookFiltersByThreshold(t *testing.T) {
+	service, store := newTestService(t)
+	dishes := []models.Dish{
+		// 4 of 5 ingredients, exactly at the threshold
+		{Name: "Pasta al pomodoro", Cuisine: "Italian", Ingredients: []string{"spaghetti", "tomatoes", "basil", "garlic", "parmesan"}},
+		// Everything but the salt, which is a staple
+		{Name: "Omelette", Cuisine: "French", Ingredients: []string{"eggs", "milk", "salt"}},
+		// 2 of 4 ingredients
+		{Name: "Curry", Cuisine: "Indian", Ingredients: []string{"chicken", "rice", "curry paste", "coconut milk"}},
+	}
+	for _, dish := range dishes {
+		if err := store.Set(fmt.Sprintf("dish:%s:%s", dish.Cuisine, dish.Name), dish); err != nil {
+			t.Fatalf("failed to save dish: %v", err)
+		}
+	}
+	for _, name := range []string{"spaghetti", "tomatoes", "basil", "garlic", "eggs", "milk", "rice"} {
+		if err := service.fridgeService.AddIngredient(1, name, ""); err != nil {
+			t.Fatalf("AddIngredient failed: %v", err)
+		}
+	}
+
+	matches, err := service.CanCook(1, CanCookThreshold)
+	if err != nil {
+		t.Fatalf("CanCook failed: %v", err)
+	}
+
+	var names []string
+	for _, match := range matches {
+		names = append(names, match.Dish.Name)
+	}
+	if want := []string{"Omelette", "Pasta al pomodoro"}; !reflect.DeepEqual(names, want) {
+		t.Fatalf("CanCook() = %v, want %v, most complete first", names, want)
+	}
+	if !reflect.DeepEqual(matches[1].Missing, []string{"parmesan"}) {
+		t.Errorf("pasta is missing %v, want only parmesan", matches[1].Missing)
+	}
+
+	// A lower threshold lets the curry in last
+	matches, err = service.CanCook(1, 0.5)
+	if err != nil {
+		t.Fatalf("CanCook failed: %v", err)
+	}
+	if len(matches) != 3 || matches[2].Dish.Name != "Curry" {
+		t.Errorf("CanCook(0.5) returned %d dishes, want the curry last", len(matches))
+	}
+}
@@ -0,0 +1,144 @@
+package dinner
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/korjavin/whatsfordinner/pkg/messages"
+)
+
+// RepeatPolicy decides what happens when a poll is won by a dish that was cooked recently
+type RepeatPolicy string
+
+const (
+	// RepeatWarn keeps the winner, but tells the channel it was cooked recently
+	RepeatWarn RepeatPolicy = "warn"
+	// RepeatReject lets the runner-up win instead, if there is one that wasn't cooked recently
+	RepeatReject RepeatPolicy = "reject"
+)
+
+// ParseRepeatPolicy parses a repeat policy, ignoring case
+func ParseRepeatPolicy(value string) (RepeatPolicy, error) {
+	switch policy := RepeatPolicy(strings.ToLower(strings.TrimSpace(value))); policy {
+	case RepeatWarn, RepeatReject:
+		return policy, nil
+	}
+	return "", fmt.Errorf("must be %q or %q", RepeatWarn, RepeatReject)
+}
+
+// Cooldown is the window in which the same dish shouldn't win twice, a zero window disables it
+type Cooldown struct {
+	Window time.Duration
+	Policy RepeatPolicy
+}
+
+// CooldownCheck is the outcome of checking a poll winner against the cooldown
+type CooldownCheck struct {
+	// Winner is the dish that wins after the check
+	Winner string
+	// Repeated is the poll winner if it was cooked within the cooldown window, empty otherwise
+	Repeated string
+	// LastCooked is when Repeated was last cooked
+	LastCooked time.Time
+	// Replaced is set when the runner-up won instead of Repeated
+	Replaced bool
+}
+
+// LastCooked returns when a channel last cooked a dish with the given name, ignoring case
+func (s *Service) LastCooked(channelID int64, name string) (time.Time, bool) {
+	dinners, err := s.ListDinners(channelID)
+	if err != nil {
+		s.logger.Error("Failed to list dinners: %v", err)
+		return time.Time{}, false
+	}
+
+	// Dinners are newest first
+	for _, dinner := range dinners {
+		if sameDish(dinner.Dish.Name, name) {
+			return dinner.StartedAt, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// CheckCooldown checks whether a poll winner was cooked within the cooldown window and,
+// if the policy rejects repeats, picks the runner-up with the most votes that wasn't
+func (s *Service) CheckCooldown(channelID int64, results map[string]int, winner string, cooldown Cooldown, now time.Time) CooldownCheck {
+	check := CooldownCheck{Winner: winner}
+	if cooldown.Window <= 0 || winner == "" {
+		return check
+	}
+
+	recent := func(dish string) (time.Time, bool) {
+		last, ok := s.LastCooked(channelID, dish)
+		return last, ok && now.Sub(last) < cooldown.Window
+	}
+
+	last, ok := recent(winner)
+	if !ok {
+		return check
+	}
+	check.Repeated = winner
+	check.LastCooked = last
+
+	if cooldown.Policy != RepeatReject {
+		return check
+	}
+
+	// Runner-ups by votes, ties broken by name so the pick is deterministic
+	options := make([]string, 0, len(results))
+	for option, count := range results {
+		if option != winner && count > 0 {
+			options = append(options, option)
+		}
+	}
+	sort.Slice(options, func(i, j int) bool {
+		if results[options[i]] != results[options[j]] {
+			return results[options[i]] > results[options[j]]
+		}
+		return options[i] < options[j]
+	})
+
+	for _, option := range options {
+		if _, isRecent := recent(option); !isRecent {
+			check.Winner = option
+			check.Replaced = true
+			break
+		}
+	}
+
+	return check
+}
+
+// Message explains the outcome of the check to the channel, or is empty if the winner wasn't a repeat
+func (c CooldownCheck) Message(now time.Time) string {
+	if c.Repeated == "" {
+		return ""
+	}
+
+	ago := daysAgo(c.LastCooked, now)
+	if c.Replaced {
+		return fmt.Sprintf("♻️ %s was already cooked %s, so the runner-up %s wins instead.", messages.EscapeMarkdown(c.Repeated), ago, messages.EscapeMarkdown(c.Winner))
+	}
+	return fmt.Sprintf("♻️ Heads up: %s was already cooked %s.", messages.EscapeMarkdown(c.Repeated), ago)
+}
+
+// daysAgo describes how long ago t was in calendar days, e.g. "today", "yesterday" or "12 days ago".
+// Calendar days are counted, so last night's dinner is "yesterday" even if it was less than 24 hours ago.
+func daysAgo(t, now time.Time) string {
+	y, m, d := now.Date()
+	today := time.Date(y, m, d, 0, 0, 0, 0, now.Location())
+	y, m, d = t.In(now.Location()).Date()
+	day := time.Date(y, m, d, 0, 0, 0, 0, now.Location())
+
+	switch days := int(today.Sub(day).Hours()/24 + 0.5); {
+	case days == 1:
+		return "yesterday"
+	case days > 1:
+		return fmt.Sprintf("%d days ago", days)
+	default:
+		return "today"
+	}
+}
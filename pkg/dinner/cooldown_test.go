@@ -0,0 +1,60 @@
+package dinner
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/korjavin/whatsfordinner/pkg/models"
+)
+
+func TestCheckCooldownFollowsThePolicy(t *testing.T) {
+	service, store := newTestService(t)
+	now := time.Date(2024, 6, 10, 19, 0, 0, 0, time.UTC)
+	cooked := map[string]time.Time{
+		"Pasta":      now.AddDate(0, 0, -2),
+		"Soup":       now.AddDate(0, 0, -3),
+		"Stew":       now.AddDate(0, 0, -30),
+		"Mac_Cheese": now.AddDate(0, 0, -1),
+	}
+	for name, startedAt := range cooked {
+		id := fmt.Sprintf("dinner:1:%d", startedAt.UnixNano())
+		if err := store.Set(id, models.Dinner{ID: id, ChannelID: 1, Dish: models.Dish{Name: name}, StartedAt: startedAt}); err != nil {
+			t.Fatalf("failed to save dinner: %v", err)
+		}
+	}
+	results := map[string]int{"pasta": 3, "Soup": 2, "Stew": 2, "Curry": 0}
+	week := 7 * 24 * time.Hour
+
+	// Warning keeps the winner and says when it was cooked
+	check := service.CheckCooldown(1, results, "pasta", Cooldown{Window: week, Policy: RepeatWarn}, now)
+	if check.Winner != "pasta" || check.Repeated != "pasta" || check.Replaced {
+		t.Errorf("warn check = %+v, want pasta to keep winning", check)
+	}
+	if got, want := check.Message(now), "♻️ Heads up: pasta was already cooked 2 days ago."; got != want {
+		t.Errorf("warn message = %q, want %q", got, want)
+	}
+
+	// Rejecting skips the runner-up that was also cooked recently
+	check = service.CheckCooldown(1, results, "pasta", Cooldown{Window: week, Policy: RepeatReject}, now)
+	if check.Winner != "Stew" || !check.Replaced {
+		t.Errorf("reject check = %+v, want Stew to win instead", check)
+	}
+	if got, want := check.Message(now), "♻️ pasta was already cooked 2 days ago, so the runner-up Stew wins instead."; got != want {
+		t.Errorf("reject message = %q, want %q", got, want)
+	}
+
+	// Dish names are escaped, so they can't break the Markdown of the announcement
+	check = service.CheckCooldown(1, map[string]int{"Mac_Cheese": 2, "Stew": 1}, "Mac_Cheese", Cooldown{Window: week, Policy: RepeatReject}, now)
+	if got, want := check.Message(now), "♻️ Mac\\_Cheese was already cooked yesterday, so the runner-up Stew wins instead."; got != want {
+		t.Errorf("message = %q, want %q", got, want)
+	}
+
+	// Outside the window, or without one, nothing changes
+	for _, cooldown := range []Cooldown{{Window: 24 * time.Hour, Policy: RepeatReject}, {Policy: RepeatReject}} {
+		check = service.CheckCooldown(1, results, "pasta", cooldown, now)
+		if check.Winner != "pasta" || check.Repeated != "" || check.Message(now) != "" {
+			t.Errorf("check with window %v = %+v, want no repeat", cooldown.Window, check)
+		}
+	}
+}
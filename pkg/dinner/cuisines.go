@@ -0,0 +1,42 @@
+package dinner
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/korjavin/whatsfordinner/pkg/models"
+)
+
+// DefaultCuisine is asked for when neither the channel nor the configuration lists any cuisines,
+// so the suggestion prompt never has an empty cuisine list
+const DefaultCuisine = models.DefaultCuisine
+
+// GetCuisines returns the preferred cuisines of a channel, falling back to the given defaults
+// and then to DefaultCuisine, so the result always has at least one cuisine
+func (s *Service) GetCuisines(channelID int64, defaults []string) []string {
+	var channelState models.ChannelState
+	err := s.store.Get(fmt.Sprintf("channel:%d", channelID), &channelState)
+	if err == nil {
+		if cuisines := nonEmpty(channelState.Cuisines); len(cuisines) > 0 {
+			return cuisines
+		}
+	}
+
+	if cuisines := nonEmpty(defaults); len(cuisines) > 0 {
+		return cuisines
+	}
+
+	s.logger.Warn("No cuisines configured for channel %d, using %q", channelID, DefaultCuisine)
+	return []string{DefaultCuisine}
+}
+
+// nonEmpty returns the trimmed cuisines, leaving out blank ones
+func nonEmpty(cuisines []string) []string {
+	var result []string
+	for _, cuisine := range cuisines {
+		if cuisine = strings.TrimSpace(cuisine); cuisine != "" {
+			result = append(result, cuisine)
+		}
+	}
+	return result
+}
@@ -0,0 +1,41 @@
+package dinner
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/korjavin/whatsfordinner/pkg/models"
+)
+
+func TestGetCuisinesNeverEmpty(t *testing.T) {
+	service, store := newTestService(t)
+	channels := map[int64][]string{
+		1: {"Thai"},
+		2: {" ", ""},
+		3: nil,
+	}
+	for channelID, cuisines := range channels {
+		if err := store.Set(fmt.Sprintf("channel:%d", channelID), models.ChannelState{ChannelID: channelID, Cuisines: cuisines}); err != nil {
+			t.Fatalf("failed to save channel: %v", err)
+		}
+	}
+
+	tests := []struct {
+		channelID int64
+		defaults  []string
+		want      []string
+	}{
+		{1, []string{"Italian"}, []string{"Thai"}},
+		{2, []string{"Italian", " "}, []string{"Italian"}},
+		{2, []string{""}, []string{DefaultCuisine}},
+		{3, nil, []string{DefaultCuisine}},
+		// A channel without any saved state
+		{4, nil, []string{DefaultCuisine}},
+	}
+	for _, tt := range tests {
+		if got := service.GetCuisines(tt.channelID, tt.defaults); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("GetCuisines(%d, %q) = %q, want %q", tt.channelID, tt.defaults, got, tt.want)
+		}
+	}
+}
@@ -1,10 +1,15 @@
 package dinner
 
 import (
+	"errors"
 	"fmt"
 	"math/rand"
+	"slices"
+	"sort"
+	"strings"
 	"time"
 
+	"github.com/korjavin/whatsfordinner/pkg/audit"
 	"github.com/korjavin/whatsfordinner/pkg/fridge"
 	"github.com/korjavin/whatsfordinner/pkg/logger"
 	"github.com/korjavin/whatsfordinner/pkg/models"
@@ -12,11 +17,36 @@ import (
 	"github.com/korjavin/whatsfordinner/pkg/storage"
 )
 
+// ErrNoActiveDinner is returned when finishing a dinner while none is being cooked
+var ErrNoActiveDinner = errors.New("no active dinner")
+
+// ErrInvalidRating is returned for ratings outside the configured rating scale
+var ErrInvalidRating = errors.New("invalid rating")
+
+// ErrRatingsClosed is returned when rating a dinner whose rating window has closed
+var ErrRatingsClosed = errors.New("ratings are closed")
+
+// RatingWindow is how long after a dinner is finished ratings are accepted
+const RatingWindow = 12 * time.Hour
+
+// ErrRatingsOpen is returned when reopening the ratings of a dinner that can still be rated
+var ErrRatingsOpen = errors.New("ratings are still open")
+
+// ErrTooOldToReopen is returned when reopening the ratings of a dinner finished more than MaxReopenAge ago
+var ErrTooOldToReopen = errors.New("dinner is too old to reopen ratings")
+
+// MaxReopenAge is how long after a dinner is finished its ratings can still be reopened
+const MaxReopenAge = 7 * 24 * time.Hour
+
+// retryWindow is how soon after a dinner was started creating the same dinner again counts as a retry
+const retryWindow = 5 * time.Second
+
 // Service provides dinner planning functionality
 type Service struct {
 	store         *storage.Store
 	fridgeService *fridge.Service
 	openaiClient  *openai.Client
+	audit         *audit.Service
 	logger        *logger.Logger
 }
 
@@ -26,6 +56,7 @@ func New(store *storage.Store, fridgeService *fridge.Service, openaiClient *open
 		store:         store,
 		fridgeService: fridgeService,
 		openaiClient:  openaiClient,
+		audit:         audit.New(store),
 		logger:        logger.New(""),
 	}
 }
@@ -99,6 +130,7 @@ func (s *Service) GetDishes() ([]models.Dish, error) {
 			Cuisine:      defaultDish.Cuisine,
 			Ingredients:  ingredientStrs,
 			Instructions: instructionStrs,
+			Tags:         NormalizeTags(interfaceStrings(dishInfo["tags"])),
 		}
 
 		// Save dish to database
@@ -116,17 +148,42 @@ func (s *Service) GetDishes() ([]models.Dish, error) {
 
 // SuggestDishes suggests dishes based on available ingredients and cuisine preferences
 func (s *Service) SuggestDishes(channelID int64, cuisines []string, count int) ([]models.Dish, error) {
+	scoredDishes, err := s.ScoreDishes(channelID, cuisines, count)
+	if err != nil {
+		return nil, err
+	}
+
+	dishes := make([]models.Dish, len(scoredDishes))
+	for i, scored := range scoredDishes {
+		dishes[i] = scored.Dish
+	}
+	return dishes, nil
+}
+
+// ScoreDishes works like SuggestDishes, but also returns how complete each dish is and what's missing
+func (s *Service) ScoreDishes(channelID int64, cuisines []string, count int) ([]ScoredDish, error) {
+	return s.scoreDishes(channelID, cuisines, nil, count)
+}
+
+// scoreDishes scores the stored dishes that have all the given tags
+// Unlike cuisines, tags are a hard filter, so no dish may match
+func (s *Service) scoreDishes(channelID int64, cuisines, tags []string, count int) ([]ScoredDish, error) {
 	allDishes, err := s.GetDishes()
 	if err != nil {
 		return nil, err
 	}
 
+	allDishes = FilterByTags(allDishes, tags)
+	if len(allDishes) == 0 {
+		return nil, nil
+	}
+
 	// Filter dishes by cuisine
 	var filteredDishes []models.Dish
 	if len(cuisines) > 0 {
 		for _, dish := range allDishes {
 			for _, cuisine := range cuisines {
-				if dish.Cuisine == cuisine {
+				if strings.EqualFold(strings.TrimSpace(dish.Cuisine), strings.TrimSpace(cuisine)) {
 					filteredDishes = append(filteredDishes, dish)
 					break
 				}
@@ -148,68 +205,68 @@ func (s *Service) SuggestDishes(channelID int64, cuisines []string, count int) (
 		return nil, err
 	}
 
-	// Create a map of available ingredients for faster lookup
-	availableIngredients := make(map[string]bool)
-	for _, ingredient := range ingredients {
-		availableIngredients[ingredient.Name] = true
-	}
-
 	// Score dishes based on available ingredients
-	type scoredDish struct {
-		dish  models.Dish
-		score float64
+	ingredientNames := make([]string, len(ingredients))
+	for i, ingredient := range ingredients {
+		ingredientNames[i] = ingredient.Name
 	}
+	staples := s.GetStaples(channelID)
 
-	var scoredDishes []scoredDish
+	scoredDishes := make([]ScoredDish, 0, len(filteredDishes))
 	for _, dish := range filteredDishes {
-		var matchCount int
-		for _, ingredient := range dish.Ingredients {
-			if availableIngredients[ingredient] {
-				matchCount++
-			}
-		}
-
-		// Calculate score as percentage of matching ingredients
-		score := float64(matchCount) / float64(len(dish.Ingredients))
-		scoredDishes = append(scoredDishes, scoredDish{dish, score})
+		scoredDishes = append(scoredDishes, ScoreDish(dish, ingredientNames, staples))
 	}
 
 	// Sort dishes by score (descending)
+	// Shuffle first so dishes with the same score come in a random order
 	// Use a local random source instead of the deprecated rand.Seed
 	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
 	rng.Shuffle(len(scoredDishes), func(i, j int) {
 		scoredDishes[i], scoredDishes[j] = scoredDishes[j], scoredDishes[i]
 	})
+	sort.SliceStable(scoredDishes, func(i, j int) bool {
+		return scoredDishes[i].Score > scoredDishes[j].Score
+	})
 
 	// Take the top N dishes
-	result := make([]models.Dish, 0, count)
-	for i := 0; i < len(scoredDishes) && i < count; i++ {
-		result = append(result, scoredDishes[i].dish)
+	if len(scoredDishes) > count {
+		scoredDishes = scoredDishes[:count]
 	}
 
-	return result, nil
+	return scoredDishes, nil
 }
 
-// CreateDinner creates a new dinner event
-func (s *Service) CreateDinner(channelID int64, dish models.Dish, cook string) (*models.Dinner, error) {
-	dinner := &models.Dinner{
-		ID:        fmt.Sprintf("dinner:%d:%d", channelID, time.Now().Unix()),
-		ChannelID: channelID,
-		Dish:      dish,
-		Cook:      cook,
-		StartedAt: time.Now(),
-		Ratings:   make(map[string]int),
-	}
-
-	err := s.store.Set(dinner.ID, dinner)
+// OfflineSuggestions suggests stored dishes that best match the fridge without calling the AI.
+// Only dishes with all the given tags are suggested.
+// The suggestions have the same shape as openai.Client.SuggestDinnerOptions results,
+// so they can be used in their place when the AI is unavailable.
+func (s *Service) OfflineSuggestions(channelID int64, cuisines, tags []string, count int) ([]map[string]interface{}, error) {
+	scoredDishes, err := s.scoreDishes(channelID, cuisines, tags, count)
 	if err != nil {
 		return nil, err
 	}
 
-	// Update channel state
+	suggestions := make([]map[string]interface{}, 0, len(scoredDishes))
+	for _, scored := range scoredDishes {
+		dish := scored.Dish
+		suggestions = append(suggestions, map[string]interface{}{
+			"name":        dish.Name,
+			"cuisine":     dish.Cuisine,
+			"description": fmt.Sprintf("You have %d of %d ingredients", len(dish.Ingredients)-len(scored.Missing), len(dish.Ingredients)),
+			"tags":        dish.Tags,
+		})
+	}
+
+	return suggestions, nil
+}
+
+// CreateDinner creates a new dinner event
+// It is safe to retry: if the channel started an unfinished dinner for the same dish and cook
+// within the last few seconds (e.g. after a double tap), that dinner is returned instead of a new one.
+func (s *Service) CreateDinner(channelID int64, dish models.Dish, cook string) (*models.Dinner, error) {
 	channelKey := fmt.Sprintf("channel:%d", channelID)
 	var channelState models.ChannelState
-	err = s.store.Get(channelKey, &channelState)
+	err := s.store.Get(channelKey, &channelState)
 	if err != nil {
 		// Create new channel state if it doesn't exist
 		channelState = models.ChannelState{
@@ -219,6 +276,29 @@ func (s *Service) CreateDinner(channelID int64, dish models.Dish, cook string) (
 		}
 	}
 
+	existing := channelState.CurrentDinner
+	if existing != nil && existing.FinishedAt.IsZero() && sameDish(existing.Dish.Name, dish.Name) && existing.Cook == cook &&
+		time.Since(existing.StartedAt) < retryWindow {
+		s.logger.Info("Dinner %s already exists for channel %d, reusing it", existing.ID, channelID)
+		return existing, nil
+	}
+
+	// Use nanoseconds so dinners created in the same second don't collide
+	dinner := &models.Dinner{
+		ID:        fmt.Sprintf("dinner:%d:%d", channelID, time.Now().UnixNano()),
+		ChannelID: channelID,
+		Dish:      dish,
+		Cook:      cook,
+		StartedAt: time.Now(),
+		Ratings:   make(map[string]int),
+	}
+
+	err = s.store.Set(dinner.ID, dinner)
+	if err != nil {
+		return nil, err
+	}
+
+	// Update channel state
 	channelState.CurrentDinner = dinner
 	channelState.LastActivity = time.Now()
 
@@ -227,9 +307,23 @@ func (s *Service) CreateDinner(channelID int64, dish models.Dish, cook string) (
 		return nil, err
 	}
 
+	s.audit.Record(channelID, audit.DinnerCreated, "%s cooked by %s (%s)", dish.Name, cook, dinner.ID)
 	return dinner, nil
 }
 
+// CurrentDinner returns the dinner a channel is cooking, or ErrNoActiveDinner if there is none
+func (s *Service) CurrentDinner(channelID int64) (*models.Dinner, error) {
+	var channelState models.ChannelState
+	err := s.store.Get(fmt.Sprintf("channel:%d", channelID), &channelState)
+	if errors.Is(err, storage.ErrNotFound) || (err == nil && channelState.CurrentDinner == nil) {
+		return nil, ErrNoActiveDinner
+	}
+	if err != nil {
+		return nil, err
+	}
+	return channelState.CurrentDinner, nil
+}
+
 // FinishDinner marks a dinner as finished
 func (s *Service) FinishDinner(channelID int64) error {
 	channelKey := fmt.Sprintf("channel:%d", channelID)
@@ -240,7 +334,7 @@ func (s *Service) FinishDinner(channelID int64) error {
 	}
 
 	if channelState.CurrentDinner == nil {
-		return fmt.Errorf("no active dinner")
+		return ErrNoActiveDinner
 	}
 
 	dinner := channelState.CurrentDinner
@@ -261,17 +355,31 @@ func (s *Service) FinishDinner(channelID int64) error {
 	channelState.CurrentDinner = nil
 	channelState.LastActivity = time.Now()
 
-	return s.store.Set(channelKey, channelState)
+	err = s.store.Set(channelKey, channelState)
+	if err != nil {
+		return err
+	}
+
+	s.audit.Record(channelID, audit.DinnerFinished, "%s (%s)", dinner.Dish.Name, dinner.ID)
+	return nil
 }
 
-// RateDinner adds a rating to a dinner
-func (s *Service) RateDinner(dinnerID, userID string, rating int) error {
+// RateDinner adds a rating from 1 to scale to a dinner
+func (s *Service) RateDinner(dinnerID, userID string, rating, scale int) error {
+	if rating < 1 || rating > scale {
+		return fmt.Errorf("%w: must be between 1 and %d, got %d", ErrInvalidRating, scale, rating)
+	}
+
 	var dinner models.Dinner
 	err := s.store.Get(dinnerID, &dinner)
 	if err != nil {
 		return err
 	}
 
+	if dinner.RatingsFinalized {
+		return ErrRatingsClosed
+	}
+
 	// Initialize the Ratings map if it's nil
 	if dinner.Ratings == nil {
 		s.logger.Info("Initializing Ratings map for dinner %s", dinnerID)
@@ -279,15 +387,112 @@ func (s *Service) RateDinner(dinnerID, userID string, rating int) error {
 	}
 
 	dinner.Ratings[userID] = rating
+	dinner.AverageRating = AverageRating(dinner.Ratings)
+
+	return s.store.Set(dinnerID, dinner)
+}
+
+// AddAttendee records that a user ate a dinner and returns the number of people who ate it.
+// added is false if the user was already recorded.
+func (s *Service) AddAttendee(dinnerID, userID string) (count int, added bool, err error) {
+	var dinner models.Dinner
+	if err := s.store.Get(dinnerID, &dinner); err != nil {
+		return 0, false, err
+	}
+
+	if slices.Contains(dinner.Attendees, userID) {
+		return len(dinner.Attendees), false, nil
+	}
+
+	dinner.Attendees = append(dinner.Attendees, userID)
+	if err := s.store.Set(dinnerID, dinner); err != nil {
+		return 0, false, err
+	}
+
+	return len(dinner.Attendees), true, nil
+}
+
+// FinalizeDueRatings closes the rating window of every finished dinner of a channel
+// that was finished more than RatingWindow before now, and returns those dinners
+func (s *Service) FinalizeDueRatings(channelID int64, now time.Time) ([]models.Dinner, error) {
+	keys, err := s.store.List(fmt.Sprintf("dinner:%d:", channelID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list dinners: %w", err)
+	}
+
+	var finalized []models.Dinner
+	for _, key := range keys {
+		var dinner models.Dinner
+		if err := s.store.Get(key, &dinner); err != nil {
+			s.logger.Error("Failed to get dinner %s: %v", key, err)
+			continue
+		}
+
+		if dinner.RatingsFinalized || dinner.FinishedAt.IsZero() || now.Sub(ratingsOpenedAt(dinner)) < RatingWindow {
+			continue
+		}
+
+		dinner.RatingsFinalized = true
+		if err := s.store.Set(key, dinner); err != nil {
+			s.logger.Error("Failed to finalize ratings of dinner %s: %v", key, err)
+			continue
+		}
+		s.audit.Record(channelID, audit.RatingsFinalized, "%s with %d ratings (%s)", dinner.Dish.Name, len(dinner.Ratings), dinner.ID)
+		finalized = append(finalized, dinner)
+	}
+
+	return finalized, nil
+}
+
+// ratingsOpenedAt returns when the rating window of a finished dinner started,
+// which is when it was finished or when its ratings were last reopened
+func ratingsOpenedAt(dinner models.Dinner) time.Time {
+	if dinner.RatingsReopenedAt.After(dinner.FinishedAt) {
+		return dinner.RatingsReopenedAt
+	}
+	return dinner.FinishedAt
+}
+
+// ReopenRatings opens the rating window of a finished dinner again for another RatingWindow,
+// e.g. when the family rated late. Dinners finished more than MaxReopenAge ago can't be reopened.
+func (s *Service) ReopenRatings(channelID int64, dinnerID string, now time.Time) (*models.Dinner, error) {
+	var dinner models.Dinner
+	if err := s.store.Get(dinnerID, &dinner); err != nil {
+		return nil, err
+	}
+	if dinner.ChannelID != channelID {
+		return nil, storage.ErrNotFound
+	}
+
+	if !dinner.RatingsFinalized {
+		return nil, ErrRatingsOpen
+	}
+	if now.Sub(dinner.FinishedAt) > MaxReopenAge {
+		return nil, ErrTooOldToReopen
+	}
+
+	dinner.RatingsFinalized = false
+	dinner.RatingsReopenedAt = now
+	dinner.AverageRating = AverageRating(dinner.Ratings)
+	if err := s.store.Set(dinnerID, dinner); err != nil {
+		return nil, err
+	}
+
+	s.audit.Record(channelID, audit.RatingsReopened, "%s with %d ratings (%s)", dinner.Dish.Name, len(dinner.Ratings), dinner.ID)
+	return &dinner, nil
+}
+
+// AverageRating returns the average of the given ratings, or 0 if there are none
+func AverageRating(ratings map[string]int) float64 {
+	if len(ratings) == 0 {
+		return 0
+	}
 
-	// Calculate average rating
 	var sum int
-	for _, r := range dinner.Ratings {
+	for _, r := range ratings {
 		sum += r
 	}
-	dinner.AverageRating = float64(sum) / float64(len(dinner.Ratings))
-
-	return s.store.Set(dinnerID, dinner)
+	return float64(sum) / float64(len(ratings))
 }
 
 // UpdateUsedIngredients updates the list of ingredients used for a dinner
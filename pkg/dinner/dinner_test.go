@@ -0,0 +1,128 @@
+package dinner
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/korjavin/whatsfordinner/internal/test"
+	"github.com/korjavin/whatsfordinner/pkg/fridge"
+	"github.com/korjavin/whatsfordinner/pkg/models"
+	"github.com/korjavin/whatsfordinner/pkg/storage"
+)
+
+// newTestService creates a dinner service on a temporary store, without an AI client
+func newTestService(t *testing.T) (*Service, *storage.Store) {
+	t.Helper()
+
+	store := test.NewStore(t)
+	return New(store, fridge.New(store), nil), store
+}
+
+func TestCreateDinnerRapidCreatesGetDistinctIDs(t *testing.T) {
+	service, _ := newTestService(t)
+
+	ids := make(map[string]bool)
+	for i := 0; i < 20; i++ {
+		dinner, err := service.CreateDinner(1, models.Dish{Name: fmt.Sprintf("Dish %d", i)}, "1")
+		if err != nil {
+			t.Fatalf("CreateDinner failed: %v", err)
+		}
+		if ids[dinner.ID] {
+			t.Fatalf("dinner ID %s was handed out twice", dinner.ID)
+		}
+		ids[dinner.ID] = true
+	}
+}
+
+func TestCreateDinnerRetryReturnsSameDinner(t *testing.T) {
+	service, store := newTestService(t)
+
+	first, err := service.CreateDinner(1, models.Dish{Name: "Lasagna"}, "1")
+	if err != nil {
+		t.Fatalf("CreateDinner failed: %v", err)
+	}
+
+	retry, err := service.CreateDinner(1, models.Dish{Name: "lasagna"}, "1")
+	if err != nil {
+		t.Fatalf("CreateDinner failed: %v", err)
+	}
+	if retry.ID != first.ID {
+		t.Errorf("a double tap created dinner %s, want %s again", retry.ID, first.ID)
+	}
+
+	otherCook, err := service.CreateDinner(1, models.Dish{Name: "Lasagna"}, "2")
+	if err != nil {
+		t.Fatalf("CreateDinner failed: %v", err)
+	}
+	if otherCook.ID == first.ID {
+		t.Errorf("another cook got the same dinner %s", first.ID)
+	}
+
+	// The same dinner started a while ago is a new dinner, not a retry
+	var channelState models.ChannelState
+	if err := store.Get("channel:1", &channelState); err != nil {
+		t.Fatalf("failed to get channel state: %v", err)
+	}
+	channelState.CurrentDinner.StartedAt = time.Now().Add(-time.Minute)
+	if err := store.Set("channel:1", channelState); err != nil {
+		t.Fatalf("failed to save channel state: %v", err)
+	}
+	later, err := service.CreateDinner(1, models.Dish{Name: "Lasagna"}, "2")
+	if err != nil {
+		t.Fatalf("CreateDinner failed: %v", err)
+	}
+	if later.ID == otherCook.ID {
+		t.Errorf("a dinner started a minute later reused %s", otherCook.ID)
+	}
+}
+
+func TestAverageRatingOfNoRatingsIsZero(t *testing.T) {
+	if got := AverageRating(nil); got != 0 {
+		t.Errorf("AverageRating(nil) = %v, want 0", got)
+	}
+	if got := AverageRating(map[string]int{}); got != 0 {
+		t.Errorf("AverageRating of no ratings = %v, want 0", got)
+	}
+	if got := AverageRating(map[string]int{"1": 4, "2": 5}); got != 4.5 {
+		t.Errorf("AverageRating = %v, want 4.5", got)
+	}
+}
+
+func TestRateDinnerWithoutRatingsMap(t *testing.T) {
+	service, store := newTestService(t)
+	// A dinner saved before it was finished has no ratings map yet
+	if err := store.Set("dinner:1:1", models.Dinner{ID: "dinner:1:1", ChannelID: 1}); err != nil {
+		t.Fatalf("failed to save dinner: %v", err)
+	}
+
+	if err := service.RateDinner("dinner:1:1", "7", 4, 5); err != nil {
+		t.Fatalf("RateDinner failed: %v", err)
+	}
+
+	var dinner models.Dinner
+	if err := store.Get("dinner:1:1", &dinner); err != nil {
+		t.Fatalf("failed to get dinner: %v", err)
+	}
+	if dinner.Ratings["7"] != 4 || dinner.AverageRating != 4 {
+		t.Errorf("dinner ratings %v averaging %v, want one 4", dinner.Ratings, dinner.AverageRating)
+	}
+}
+
+func TestDinnerErrorsWrapSentinels(t *testing.T) {
+	service, store := newTestService(t)
+
+	if err := service.FinishDinner(1); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("FinishDinner of an unknown channel returned %v, want storage.ErrNotFound", err)
+	}
+	if err := store.Set("channel:1", models.ChannelState{ChannelID: 1}); err != nil {
+		t.Fatalf("failed to save channel: %v", err)
+	}
+	if err := service.FinishDinner(1); !errors.Is(err, ErrNoActiveDinner) {
+		t.Errorf("FinishDinner without a dinner returned %v, want ErrNoActiveDinner", err)
+	}
+	if err := service.RateDinner("dinner:1:1", "7", 6, 5); !errors.Is(err, ErrInvalidRating) {
+		t.Errorf("RateDinner out of range returned %v, want ErrInvalidRating", err)
+	}
+}
@@ -0,0 +1,45 @@
+package dinner
+
+import (
+	"github.com/korjavin/whatsfordinner/pkg/models"
+)
+
+// DishFromInfo converts the dish information returned by the AI into a dish.
+// name is used when the AI doesn't return a name, and as the cuisine when it doesn't return one either.
+func DishFromInfo(info map[string]interface{}, name string) models.Dish {
+	dish := models.Dish{Name: name, Cuisine: name}
+
+	if dishName, _ := info["name"].(string); dishName != "" {
+		dish.Name = dishName
+	}
+	if cuisine, _ := info["cuisine"].(string); cuisine != "" {
+		dish.Cuisine = cuisine
+	}
+
+	// The ingredients come as "ingredients_needed" or "ingredients", depending on the prompt
+	ingredients, ok := info["ingredients_needed"].([]interface{})
+	if !ok {
+		ingredients, _ = info["ingredients"].([]interface{})
+	}
+	dish.Ingredients = interfaceStrings(ingredients)
+	dish.Instructions = interfaceStrings(info["instructions"])
+
+	if servings, ok := info["servings"].(float64); ok {
+		dish.Servings = int(servings)
+	}
+	dish.Tags = NormalizeTags(interfaceStrings(info["tags"]))
+
+	return dish
+}
+
+// interfaceStrings returns the strings in a decoded JSON array, skipping anything else
+func interfaceStrings(value interface{}) []string {
+	list, _ := value.([]interface{})
+	result := make([]string, 0, len(list))
+	for _, item := range list {
+		if s, ok := item.(string); ok {
+			result = append(result, s)
+		}
+	}
+	return result
+}
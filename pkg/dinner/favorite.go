@@ -0,0 +1,63 @@
+package dinner
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/korjavin/whatsfordinner/pkg/models"
+)
+
+// FavoriteRating is the lowest average rating, on the default 1-5 scale, that makes a dish a favorite
+const FavoriteRating = 4.0
+
+// ErrNeverCooked is returned when looking up a dish the channel has never cooked
+var ErrNeverCooked = errors.New("dish was never cooked")
+
+// ErrNotFavorite is returned when a dish was cooked before but wasn't rated highly enough
+var ErrNotFavorite = errors.New("dish is not a favorite")
+
+// FindFavorite returns the best-rated dinner of a channel with the given dish name, ignoring case.
+// Ratings are on the given scale. The dish must have an average of at least FavoriteRating.
+func (s *Service) FindFavorite(channelID int64, name string, scale int) (models.Dinner, error) {
+	dinners, err := s.ListDinners(channelID)
+	if err != nil {
+		return models.Dinner{}, err
+	}
+
+	var best models.Dinner
+	bestRating := 0.0
+	cooked := false
+	for _, dinner := range dinners {
+		if !sameDish(dinner.Dish.Name, name) {
+			continue
+		}
+		cooked = true
+
+		if len(dinner.Ratings) == 0 {
+			continue
+		}
+		// Dinners are newest first, so ties keep the most recent recipe
+		rating := normalizeAverage(AverageRating(dinner.Ratings), scale)
+		if rating > bestRating {
+			best = dinner
+			bestRating = rating
+		}
+	}
+
+	if !cooked {
+		return models.Dinner{}, ErrNeverCooked
+	}
+	if bestRating < FavoriteRating {
+		return models.Dinner{}, fmt.Errorf("%w: best average is %.1f, favorites need %.1f", ErrNotFavorite, bestRating, FavoriteRating)
+	}
+
+	return best, nil
+}
+
+// normalizeAverage converts an average rating on the given scale to the default 1-5 scale
+func normalizeAverage(average float64, scale int) float64 {
+	if scale == DefaultRatingScale || average == 0 {
+		return average
+	}
+	return 1 + (average-1)*float64(DefaultRatingScale-1)/float64(scale-1)
+}
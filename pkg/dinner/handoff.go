@@ -0,0 +1,94 @@
+package dinner
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/korjavin/whatsfordinner/pkg/audit"
+	"github.com/korjavin/whatsfordinner/pkg/models"
+)
+
+// ErrNotCook is returned when someone other than the cook passes a dinner on
+var ErrNotCook = errors.New("user is not the cook")
+
+// ErrNoHandoff is returned when taking over a dinner nobody passed on
+var ErrNoHandoff = errors.New("dinner was not passed on")
+
+// PassCooking marks the channel's current dinner as waiting for someone to take over from its cook.
+// The cook stays responsible until someone takes over.
+func (s *Service) PassCooking(channelID int64, dinnerID, userID string) (*models.Dinner, error) {
+	dinner, err := s.currentDinner(channelID, dinnerID)
+	if err != nil {
+		return nil, err
+	}
+
+	if dinner.Cook != userID {
+		return nil, ErrNotCook
+	}
+
+	dinner.HandoffFrom = userID
+	if err := s.saveCurrentDinner(channelID, dinner); err != nil {
+		return nil, err
+	}
+
+	s.audit.Record(channelID, audit.CookHandoff, "%s passed %s on (%s)", userID, dinner.Dish.Name, dinner.ID)
+	return dinner, nil
+}
+
+// TakeOverCooking makes a user the cook of the channel's current dinner after its cook passed it on,
+// and returns the dinner together with the cook who passed it on. The previous cook can take it back as well.
+func (s *Service) TakeOverCooking(channelID int64, dinnerID, userID string) (*models.Dinner, string, error) {
+	dinner, err := s.currentDinner(channelID, dinnerID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	previousCook := dinner.HandoffFrom
+	if previousCook == "" {
+		return nil, "", ErrNoHandoff
+	}
+
+	dinner.Cook = userID
+	dinner.HandoffFrom = ""
+	if err := s.saveCurrentDinner(channelID, dinner); err != nil {
+		return nil, "", err
+	}
+
+	s.audit.Record(channelID, audit.CookHandoff, "%s took over %s from %s (%s)", userID, dinner.Dish.Name, previousCook, dinner.ID)
+	return dinner, previousCook, nil
+}
+
+// currentDinner returns the channel's current dinner if it is the given one and still being cooked
+func (s *Service) currentDinner(channelID int64, dinnerID string) (*models.Dinner, error) {
+	var channelState models.ChannelState
+	err := s.store.Get(fmt.Sprintf("channel:%d", channelID), &channelState)
+	if err != nil {
+		return nil, err
+	}
+
+	dinner := channelState.CurrentDinner
+	if dinner == nil || dinner.ID != dinnerID || !dinner.FinishedAt.IsZero() {
+		return nil, ErrNoActiveDinner
+	}
+
+	return dinner, nil
+}
+
+// saveCurrentDinner stores a dinner both under its own key and as the channel's current dinner
+func (s *Service) saveCurrentDinner(channelID int64, dinner *models.Dinner) error {
+	if err := s.store.Set(dinner.ID, dinner); err != nil {
+		return err
+	}
+
+	channelKey := fmt.Sprintf("channel:%d", channelID)
+	var channelState models.ChannelState
+	if err := s.store.Get(channelKey, &channelState); err != nil {
+		return err
+	}
+
+	channelState.CurrentDinner = dinner
+	channelState.LastActivity = time.Now()
+
+	return s.store.Set(channelKey, channelState)
+}
@@ -0,0 +1,52 @@
+package dinner
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/korjavin/whatsfordinner/pkg/models"
+)
+
+func TestHandoffReassignsTheCook(t *testing.T) {
+	service, store := newTestService(t)
+	created, err := service.CreateDinner(1, models.Dish{Name: "Pasta"}, "1")
+	if err != nil {
+		t.Fatalf("CreateDinner failed: %v", err)
+	}
+
+	if _, _, err := service.TakeOverCooking(1, created.ID, "2"); !errors.Is(err, ErrNoHandoff) {
+		t.Fatalf("TakeOverCooking before a handoff returned %v, want ErrNoHandoff", err)
+	}
+	if _, err := service.PassCooking(1, created.ID, "2"); !errors.Is(err, ErrNotCook) {
+		t.Fatalf("PassCooking by someone else returned %v, want ErrNotCook", err)
+	}
+
+	if _, err := service.PassCooking(1, created.ID, "1"); err != nil {
+		t.Fatalf("PassCooking failed: %v", err)
+	}
+	taken, previousCook, err := service.TakeOverCooking(1, created.ID, "2")
+	if err != nil {
+		t.Fatalf("TakeOverCooking failed: %v", err)
+	}
+	if previousCook != "1" || taken.Cook != "2" || taken.HandoffFrom != "" {
+		t.Errorf("took over from %q, cook = %q, handoff from %q, want 2 taking over from 1", previousCook, taken.Cook, taken.HandoffFrom)
+	}
+
+	// Both the dinner and the channel's current dinner have the new cook
+	var saved models.Dinner
+	if err := store.Get(created.ID, &saved); err != nil {
+		t.Fatalf("failed to get dinner: %v", err)
+	}
+	var channelState models.ChannelState
+	if err := store.Get("channel:1", &channelState); err != nil {
+		t.Fatalf("failed to get channel: %v", err)
+	}
+	if saved.Cook != "2" || channelState.CurrentDinner.Cook != "2" {
+		t.Errorf("stored cook = %q, current dinner cook = %q, want 2", saved.Cook, channelState.CurrentDinner.Cook)
+	}
+
+	// A second taker is too late
+	if _, _, err := service.TakeOverCooking(1, created.ID, "3"); !errors.Is(err, ErrNoHandoff) {
+		t.Errorf("second TakeOverCooking returned %v, want ErrNoHandoff", err)
+	}
+}
@@ -0,0 +1,175 @@
+package dinner
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/korjavin/whatsfordinner/pkg/models"
+)
+
+// DateLayout is the date format used to refer to past dinners, e.g. /dinner_info 2024-06-01
+const DateLayout = "2006-01-02"
+
+// ListDinners returns all dinners of a channel, newest first
+func (s *Service) ListDinners(channelID int64) ([]models.Dinner, error) {
+	keys, err := s.store.List(fmt.Sprintf("dinner:%d:", channelID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list dinners: %w", err)
+	}
+
+	dinners := make([]models.Dinner, 0, len(keys))
+	for _, key := range keys {
+		var dinner models.Dinner
+		if err := s.store.Get(key, &dinner); err != nil {
+			s.logger.Error("Failed to get dinner %s: %v", key, err)
+			continue
+		}
+		dinners = append(dinners, dinner)
+	}
+
+	sort.Slice(dinners, func(i, j int) bool {
+		return dinners[i].StartedAt.After(dinners[j].StartedAt)
+	})
+
+	return dinners, nil
+}
+
+// FindDinners resolves a short reference to the matching dinners of a channel, newest first.
+// The reference can be "last", a full dinner ID, the numeric part of a dinner ID
+// or a date in DateLayout, which is interpreted in the given time zone.
+// A date can match several dinners, so callers should handle more than one result.
+func (s *Service) FindDinners(channelID int64, ref string, loc *time.Location) ([]models.Dinner, error) {
+	ref = strings.TrimSpace(ref)
+
+	dinners, err := s.ListDinners(channelID)
+	if err != nil {
+		return nil, err
+	}
+
+	if ref == "" || strings.EqualFold(ref, "last") {
+		if len(dinners) == 0 {
+			return nil, nil
+		}
+		return dinners[:1], nil
+	}
+
+	if date, err := time.ParseInLocation(DateLayout, ref, loc); err == nil {
+		var matches []models.Dinner
+		for _, dinner := range dinners {
+			if dinner.StartedAt.In(loc).Format(DateLayout) == date.Format(DateLayout) {
+				matches = append(matches, dinner)
+			}
+		}
+		return matches, nil
+	}
+
+	id := ref
+	if !strings.HasPrefix(id, "dinner:") {
+		id = fmt.Sprintf("dinner:%d:%s", channelID, ref)
+	}
+	for _, dinner := range dinners {
+		if dinner.ID == id {
+			return []models.Dinner{dinner}, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// normalizeDishName returns the form dish names are compared in, lowercased and with
+// runs of spaces collapsed, so "Spaghetti  Bolognese " and "spaghetti bolognese" are the same dish.
+// Use it for matching only, output keeps the name as it was entered.
+func normalizeDishName(name string) string {
+	return strings.Join(strings.Fields(strings.ToLower(name)), " ")
+}
+
+// sameDish reports whether two dish names refer to the same dish
+func sameDish(a, b string) bool {
+	return normalizeDishName(a) == normalizeDishName(b)
+}
+
+// FindDish returns the recipe of the most recent dinner of a channel with the given dish name, ignoring case
+func (s *Service) FindDish(channelID int64, name string) (models.Dish, bool) {
+	dinners, err := s.ListDinners(channelID)
+	if err != nil {
+		s.logger.Error("Failed to list dinners: %v", err)
+		return models.Dish{}, false
+	}
+
+	for _, dinner := range dinners {
+		if sameDish(dinner.Dish.Name, name) && len(dinner.Dish.Instructions) > 0 {
+			return dinner.Dish, true
+		}
+	}
+
+	return models.Dish{}, false
+}
+
+// DishRating returns the average rating of the most recent rated dinner of a channel with the
+// given dish name, ignoring case, converted from the scale to the default 1-5 scale like NormalizeRating.
+// ok is false if the dish was never rated.
+func (s *Service) DishRating(channelID int64, name string, scale int) (rating float64, ok bool) {
+	dinners, err := s.ListDinners(channelID)
+	if err != nil {
+		s.logger.Error("Failed to list dinners: %v", err)
+		return 0, false
+	}
+
+	for _, dinner := range dinners {
+		if sameDish(dinner.Dish.Name, name) && len(dinner.Ratings) > 0 {
+			if scale == DefaultRatingScale {
+				return dinner.AverageRating, true
+			}
+			return 1 + (dinner.AverageRating-1)*float64(DefaultRatingScale-1)/float64(scale-1), true
+		}
+	}
+
+	return 0, false
+}
+
+// DishHistory maps dish names, in the form they are compared in, to the most recent dinner they were cooked at
+type DishHistory map[string]models.Dinner
+
+// DishHistory returns the most recent dinner of every dish a channel has cooked
+func (s *Service) DishHistory(channelID int64) (DishHistory, error) {
+	dinners, err := s.ListDinners(channelID)
+	if err != nil {
+		return nil, err
+	}
+
+	history := make(DishHistory)
+	// Dinners are newest first, so the first one of each dish is the most recent
+	for _, dinner := range dinners {
+		name := normalizeDishName(dinner.Dish.Name)
+		if _, ok := history[name]; !ok {
+			history[name] = dinner
+		}
+	}
+	return history, nil
+}
+
+// Last returns the most recent dinner with the given dish name
+func (h DishHistory) Last(name string) (models.Dinner, bool) {
+	dinner, ok := h[normalizeDishName(name)]
+	return dinner, ok
+}
+
+// LastCookedNote describes when a dish was last cooked and how that dinner was rated on the given scale,
+// e.g. "last cooked 12 days ago, rated 4.3★". It is empty for dishes that were never cooked.
+func (h DishHistory) LastCookedNote(name string, scale int, now time.Time) string {
+	dinner, ok := h.Last(name)
+	if !ok {
+		return ""
+	}
+
+	note := "last cooked " + daysAgo(dinner.StartedAt, now)
+	if len(dinner.Ratings) == 0 {
+		return note
+	}
+	if scale == DefaultRatingScale {
+		return note + fmt.Sprintf(", rated %.1f★", AverageRating(dinner.Ratings))
+	}
+	return note + fmt.Sprintf(", rated %.1f/%d", AverageRating(dinner.Ratings), scale)
+}
@@ -0,0 +1,147 @@
+package dinner
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/korjavin/whatsfordinner/pkg/models"
+)
+
+func TestFindDinnersResolvesReferences(t *testing.T) {
+	service, store := newTestService(t)
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skipf("no time zone data: %v", err)
+	}
+
+	// The first dinner is on May 31st in UTC but already June 1st in Berlin
+	dinners := []models.Dinner{
+		{ID: "dinner:1:100", ChannelID: 1, Dish: models.Dish{Name: "Soup"}, StartedAt: time.Date(2024, 5, 31, 22, 30, 0, 0, time.UTC)},
+		{ID: "dinner:1:200", ChannelID: 1, Dish: models.Dish{Name: "Pasta"}, StartedAt: time.Date(2024, 6, 1, 18, 0, 0, 0, time.UTC)},
+		{ID: "dinner:1:300", ChannelID: 1, Dish: models.Dish{Name: "Curry"}, StartedAt: time.Date(2024, 6, 3, 18, 0, 0, 0, time.UTC)},
+		{ID: "dinner:2:400", ChannelID: 2, Dish: models.Dish{Name: "Tacos"}, StartedAt: time.Date(2024, 6, 4, 18, 0, 0, 0, time.UTC)},
+	}
+	for _, dinner := range dinners {
+		if err := store.Set(dinner.ID, dinner); err != nil {
+			t.Fatalf("failed to save dinner: %v", err)
+		}
+	}
+
+	tests := []struct {
+		ref  string
+		want []string
+	}{
+		{"last", []string{"dinner:1:300"}},
+		{"", []string{"dinner:1:300"}},
+		{"2024-06-01", []string{"dinner:1:200", "dinner:1:100"}},
+		{"200", []string{"dinner:1:200"}},
+		{"dinner:1:100", []string{"dinner:1:100"}},
+		{"400", nil},
+		{"2024-07-01", nil},
+		{"pasta", nil},
+	}
+	for _, tt := range tests {
+		found, err := service.FindDinners(1, tt.ref, berlin)
+		if err != nil {
+			t.Fatalf("FindDinners(%q) failed: %v", tt.ref, err)
+		}
+		var ids []string
+		for _, dinner := range found {
+			ids = append(ids, dinner.ID)
+		}
+		if len(ids) != len(tt.want) {
+			t.Errorf("FindDinners(%q) = %v, want %v", tt.ref, ids, tt.want)
+			continue
+		}
+		for i := range ids {
+			if ids[i] != tt.want[i] {
+				t.Errorf("FindDinners(%q) = %v, want %v", tt.ref, ids, tt.want)
+				break
+			}
+		}
+	}
+}
+
+func TestDishNameVariantsAggregateTogether(t *testing.T) {
+	service, store := newTestService(t)
+	start := time.Date(2024, 6, 1, 19, 0, 0, 0, time.UTC)
+	variants := []struct {
+		name   string
+		rating int
+	}{
+		{"Spaghetti Bolognese", 3},
+		{"spaghetti bolognese", 5},
+		{"  SPAGHETTI   Bolognese ", 4},
+	}
+	for i, variant := range variants {
+		startedAt := start.AddDate(0, 0, i)
+		id := fmt.Sprintf("dinner:1:%d", startedAt.UnixNano())
+		d := models.Dinner{ID: id, ChannelID: 1, Dish: models.Dish{Name: variant.name}, StartedAt: startedAt, Ratings: map[string]int{"1": variant.rating}}
+		if err := store.Set(id, d); err != nil {
+			t.Fatalf("failed to save dinner: %v", err)
+		}
+	}
+
+	history, err := service.DishHistory(1)
+	if err != nil {
+		t.Fatalf("DishHistory failed: %v", err)
+	}
+	if len(history) != 1 {
+		t.Fatalf("history has %d dishes, want the variants as one", len(history))
+	}
+	last, ok := history.Last("Spaghetti bolognese")
+	if !ok || last.Dish.Name != "  SPAGHETTI   Bolognese " {
+		t.Errorf("Last() = %q, want the most recent variant with its name as entered", last.Dish.Name)
+	}
+
+	favorite, err := service.FindFavorite(1, "SPAGHETTI BOLOGNESE", DefaultRatingScale)
+	if err != nil {
+		t.Fatalf("FindFavorite failed: %v", err)
+	}
+	if favorite.Dish.Name != "spaghetti bolognese" {
+		t.Errorf("favorite is %q, want the best rated variant", favorite.Dish.Name)
+	}
+
+	cooked, ok := service.LastCooked(1, "spaghetti bolognese")
+	if !ok || !cooked.Equal(start.AddDate(0, 0, 2)) {
+		t.Errorf("LastCooked() = %v, %v, want the most recent variant", cooked, ok)
+	}
+}
+
+func TestLastCookedNote(t *testing.T) {
+	service, store := newTestService(t)
+	now := time.Date(2024, 6, 13, 19, 0, 0, 0, time.UTC)
+	seeded := []models.Dinner{
+		{Dish: models.Dish{Name: "Lasagna"}, StartedAt: now.AddDate(0, 0, -20), Ratings: map[string]int{"1": 2}},
+		{Dish: models.Dish{Name: "Lasagna"}, StartedAt: now.AddDate(0, 0, -12), Ratings: map[string]int{"1": 5, "2": 4, "3": 4}},
+		{Dish: models.Dish{Name: "Soup"}, StartedAt: now.Add(-20 * time.Hour)},
+	}
+	for _, d := range seeded {
+		d.ID = fmt.Sprintf("dinner:1:%d", d.StartedAt.UnixNano())
+		d.ChannelID = 1
+		if err := store.Set(d.ID, d); err != nil {
+			t.Fatalf("failed to save dinner: %v", err)
+		}
+	}
+
+	history, err := service.DishHistory(1)
+	if err != nil {
+		t.Fatalf("DishHistory failed: %v", err)
+	}
+	tests := []struct {
+		name  string
+		scale int
+		want  string
+	}{
+		{"lasagna", DefaultRatingScale, "last cooked 12 days ago, rated 4.3★"},
+		{"Lasagna", 10, "last cooked 12 days ago, rated 4.3/10"},
+		{"Soup", DefaultRatingScale, "last cooked yesterday"},
+		{"Curry", DefaultRatingScale, ""},
+	}
+	for _, tt := range tests {
+		if got := history.LastCookedNote(tt.name, tt.scale, now); got != tt.want {
+			t.Errorf("LastCookedNote(%q, %d) = %q, want %q", tt.name, tt.scale, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,185 @@
+package dinner
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/korjavin/whatsfordinner/pkg/fridge"
+	"github.com/korjavin/whatsfordinner/pkg/models"
+)
+
+// ShoppingItem is one line of a shopping list covering several dishes
+type ShoppingItem struct {
+	Name     string
+	Quantity string // Summed over all dishes, empty if the recipes don't say
+	Category string
+}
+
+// WeeklyShopping is the shopping list for the dishes planned for the week
+type WeeklyShopping struct {
+	Items []ShoppingItem
+	// Unknown are planned dishes whose ingredients aren't known, so they aren't on the list
+	Unknown []string
+}
+
+// MealPlan returns the dishes planned for each day of the week
+func (s *Service) MealPlan(channelID int64) map[time.Weekday]string {
+	var channelState models.ChannelState
+	if err := s.store.Get(fmt.Sprintf("channel:%d", channelID), &channelState); err != nil {
+		return nil
+	}
+	return channelState.MealPlan
+}
+
+// PlanDish plans a dish for a day of the week, an empty name clears the day
+func (s *Service) PlanDish(channelID int64, day time.Weekday, name string) error {
+	channelKey := fmt.Sprintf("channel:%d", channelID)
+	var channelState models.ChannelState
+	err := s.store.Get(channelKey, &channelState)
+	if err != nil {
+		// Create new channel state if it doesn't exist
+		channelState = models.ChannelState{
+			ChannelID: channelID,
+			FridgeID:  fmt.Sprintf("fridge:%d", channelID),
+		}
+	}
+
+	name = strings.TrimSpace(name)
+	if name == "" {
+		delete(channelState.MealPlan, day)
+	} else {
+		if channelState.MealPlan == nil {
+			channelState.MealPlan = make(map[time.Weekday]string)
+		}
+		channelState.MealPlan[day] = name
+	}
+	channelState.LastActivity = time.Now()
+
+	return s.store.Set(channelKey, channelState)
+}
+
+// AggregateWeeklyShopping builds one shopping list for all dishes planned for the week.
+// The same ingredient in several dishes, like "200g spaghetti" and "300g spaghetti", becomes one item
+// with the amounts added up. Ingredients in the fridge or pantry and staples are left out,
+// unless the fridge has less than the dishes need together, then the shortfall is listed.
+// Items are sorted by category and name.
+func (s *Service) AggregateWeeklyShopping(channelID int64) (WeeklyShopping, error) {
+	var shopping WeeklyShopping
+
+	plan := s.MealPlan(channelID)
+	days := make([]time.Weekday, 0, len(plan))
+	for day := range plan {
+		days = append(days, day)
+	}
+	sort.Slice(days, func(i, j int) bool { return days[i] < days[j] })
+
+	staples := s.GetStaples(channelID)
+	needed := make(map[string]*ShoppingItem)
+	var names []string
+	for _, day := range days {
+		ingredients, ok := s.dishIngredients(channelID, plan[day])
+		if !ok {
+			shopping.Unknown = append(shopping.Unknown, plan[day])
+			continue
+		}
+
+		for _, ingredient := range ingredients {
+			quantity, name, ok := fridge.ParseQuantity(normalizeIngredient(ingredient))
+			if !ok || name == "" {
+				name = normalizeIngredient(ingredient)
+			}
+			if name == "" || isStaple(name, staples) {
+				continue
+			}
+
+			item, exists := needed[name]
+			if !exists {
+				item = &ShoppingItem{Name: name, Category: fridge.Categorize(name)}
+				needed[name] = item
+				names = append(names, name)
+			}
+			if ok {
+				item.Quantity = fridge.SumQuantities(item.Quantity, quantity.String())
+			}
+		}
+	}
+
+	stock, err := s.fridgeService.ListIngredients(channelID)
+	if err != nil {
+		return shopping, err
+	}
+	stockNames := make([]string, len(stock))
+	for i, ingredient := range stock {
+		stockNames[i] = ingredient.Name
+	}
+
+	absent := make(map[string]bool)
+	for _, name := range CompareIngredients(names, stockNames) {
+		absent[name] = true
+	}
+
+	// Check whether what's there is enough for the whole week
+	requirements := make(map[string]string)
+	for _, name := range names {
+		if !absent[name] && needed[name].Quantity != "" {
+			requirements[name] = needed[name].Quantity
+		}
+	}
+	_, shortfalls := s.fridgeService.HasEnough(channelID, requirements)
+
+	for _, name := range names {
+		item := *needed[name]
+		if !absent[name] {
+			short, ok := shortfalls[name]
+			if !ok {
+				continue
+			}
+			item.Quantity = short
+		}
+		shopping.Items = append(shopping.Items, item)
+	}
+
+	sort.Slice(shopping.Items, func(i, j int) bool {
+		ci, cj := fridge.CategoryOrder(shopping.Items[i].Category), fridge.CategoryOrder(shopping.Items[j].Category)
+		if ci != cj {
+			return ci < cj
+		}
+		return shopping.Items[i].Name < shopping.Items[j].Name
+	})
+
+	return shopping, nil
+}
+
+// dishIngredients finds the ingredients of a dish by name, ignoring case,
+// first among the channel's past dinners and then in the dish catalog
+func (s *Service) dishIngredients(channelID int64, name string) ([]string, bool) {
+	dinners, err := s.ListDinners(channelID)
+	if err != nil {
+		s.logger.Error("Failed to list dinners: %v", err)
+	}
+	for _, dinner := range dinners {
+		if sameDish(dinner.Dish.Name, name) && len(dinner.Dish.Ingredients) > 0 {
+			return dinner.Dish.Ingredients, true
+		}
+	}
+
+	dishKeys, err := s.store.List("dish:")
+	if err != nil {
+		s.logger.Error("Failed to list dishes: %v", err)
+		return nil, false
+	}
+	for _, key := range dishKeys {
+		var dish models.Dish
+		if err := s.store.Get(key, &dish); err != nil {
+			s.logger.Error("Failed to get dish %s: %v", key, err)
+			continue
+		}
+		if sameDish(dish.Name, name) && len(dish.Ingredients) > 0 {
+			return dish.Ingredients, true
+		}
+	}
+
+	return nil, false
+}
@@ -0,0 +1,91 @@
+package dinner
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/korjavin/whatsfordinner/pkg/models"
+)
+
+func TestAggregateWeeklyShoppingSumsPlannedDishes(t *testing.T) {
+	service, store := newTestService(t)
+
+	// One dish is known from an earlier dinner, the other from the catalog
+	bolognese := models.Dish{Name: "Spaghetti Bolognese", Ingredients: []string{"200g spaghetti", "300g minced beef", "1 onion", "salt"}}
+	if _, err := service.CreateDinner(1, bolognese, "1"); err != nil {
+		t.Fatalf("CreateDinner failed: %v", err)
+	}
+	carbonara := models.Dish{Name: "Carbonara", Ingredients: []string{"300g Spaghetti", "2 eggs", "100g pancetta"}}
+	if err := store.Set("dish:Italian:Carbonara", carbonara); err != nil {
+		t.Fatalf("failed to save dish: %v", err)
+	}
+
+	plan := map[time.Weekday]string{time.Monday: "Spaghetti Bolognese", time.Wednesday: "carbonara", time.Friday: "Mystery Stew"}
+	for day, name := range plan {
+		if err := service.PlanDish(1, day, name); err != nil {
+			t.Fatalf("PlanDish failed: %v", err)
+		}
+	}
+
+	shopping, err := service.AggregateWeeklyShopping(1)
+	if err != nil {
+		t.Fatalf("AggregateWeeklyShopping failed: %v", err)
+	}
+	want := []ShoppingItem{
+		{Name: "onion", Quantity: "1", Category: "vegetables"},
+		{Name: "eggs", Quantity: "2", Category: "dairy"},
+		{Name: "minced beef", Quantity: "300g", Category: "meat"},
+		{Name: "spaghetti", Quantity: "500g", Category: "grains"},
+		{Name: "pancetta", Quantity: "100g", Category: "other"},
+	}
+	if !reflect.DeepEqual(shopping.Items, want) {
+		t.Errorf("AggregateWeeklyShopping() items = %+v, want %+v", shopping.Items, want)
+	}
+	if !reflect.DeepEqual(shopping.Unknown, []string{"Mystery Stew"}) {
+		t.Errorf("AggregateWeeklyShopping() unknown = %v, want the dish without ingredients", shopping.Unknown)
+	}
+
+	// Clearing a day takes its dish off the list
+	if err := service.PlanDish(1, time.Friday, ""); err != nil {
+		t.Fatalf("PlanDish failed: %v", err)
+	}
+	if _, planned := service.MealPlan(1)[time.Friday]; planned {
+		t.Errorf("Friday is still planned after clearing it")
+	}
+}
+
+func TestAggregateWeeklyShoppingSubtractsTheFridge(t *testing.T) {
+	service, store := newTestService(t)
+
+	carbonara := models.Dish{Name: "Carbonara", Ingredients: []string{"300g spaghetti", "2 eggs", "100g pancetta", "1 onion"}}
+	if err := store.Set("dish:Italian:Carbonara", carbonara); err != nil {
+		t.Fatalf("failed to save dish: %v", err)
+	}
+	for _, day := range []time.Weekday{time.Monday, time.Thursday} {
+		if err := service.PlanDish(1, day, "Carbonara"); err != nil {
+			t.Fatalf("PlanDish failed: %v", err)
+		}
+	}
+
+	stock := map[string]string{"spaghetti": "200g", "eggs": "6", "onion": ""}
+	for name, quantity := range stock {
+		if err := service.fridgeService.AddIngredient(1, name, quantity); err != nil {
+			t.Fatalf("AddIngredient failed: %v", err)
+		}
+	}
+	if err := service.fridgeService.AddPantryIngredient(1, "pancetta", "500g"); err != nil {
+		t.Fatalf("AddPantryIngredient failed: %v", err)
+	}
+
+	shopping, err := service.AggregateWeeklyShopping(1)
+	if err != nil {
+		t.Fatalf("AggregateWeeklyShopping failed: %v", err)
+	}
+	// Two carbonaras need 600g of spaghetti, only the shortfall is bought.
+	// The eggs, the onion and the pancetta in the pantry are enough.
+	want := []ShoppingItem{{Name: "spaghetti", Quantity: "400g", Category: "grains"}}
+	if !reflect.DeepEqual(shopping.Items, want) {
+		t.Errorf("AggregateWeeklyShopping() items = %+v, want %+v", shopping.Items, want)
+	}
+}
@@ -0,0 +1,84 @@
+package dinner
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/korjavin/whatsfordinner/pkg/models"
+)
+
+// Rating scales go from 1 up to the scale, e.g. 1-5 stars. A scale of 2 is thumbs down/up.
+const (
+	DefaultRatingScale = models.DefaultRatingScale
+	MinRatingScale     = 2
+	MaxRatingScale     = 10
+)
+
+// ScaleRating converts a rating on the default 1-5 scale, like one from a reaction, to the given scale
+func ScaleRating(rating, scale int) int {
+	if scale == DefaultRatingScale {
+		return rating
+	}
+	scaled := 1 + float64(rating-1)*float64(scale-1)/float64(DefaultRatingScale-1)
+	return int(math.Round(scaled))
+}
+
+// NormalizeRating converts a rating on the given scale to the default 1-5 scale,
+// so cook statistics stay comparable when the scale changes
+func NormalizeRating(rating, scale int) float64 {
+	if scale == DefaultRatingScale {
+		return float64(rating)
+	}
+	return 1 + float64(rating-1)*float64(DefaultRatingScale-1)/float64(scale-1)
+}
+
+// Rating styles a channel can rate dinners with
+const (
+	// RatingStyleStars rates from 1 up to the rating scale
+	RatingStyleStars = models.DefaultRatingStyle
+	// RatingStyleThumbs only has thumbs down and up, the lowest and highest rating
+	RatingStyleThumbs = "thumbs"
+)
+
+// ThumbsRating converts a thumbs up or down to a rating on the given scale,
+// e.g. 5 or 1 on the default scale
+func ThumbsRating(up bool, scale int) int {
+	if up {
+		return scale
+	}
+	return 1
+}
+
+// RatingStyle returns how a channel rates dinners, RatingStyleStars unless it picked thumbs
+func (s *Service) RatingStyle(channelID int64) string {
+	var channelState models.ChannelState
+	err := s.store.Get(fmt.Sprintf("channel:%d", channelID), &channelState)
+	if err != nil {
+		return RatingStyleStars
+	}
+	return channelState.Effective(models.DefaultSettings()).RatingStyle
+}
+
+// SetRatingStyle sets how a channel rates dinners, RatingStyleStars or RatingStyleThumbs
+func (s *Service) SetRatingStyle(channelID int64, style string) error {
+	if style != RatingStyleStars && style != RatingStyleThumbs {
+		return fmt.Errorf("unknown rating style %q", style)
+	}
+
+	channelKey := fmt.Sprintf("channel:%d", channelID)
+	var channelState models.ChannelState
+	err := s.store.Get(channelKey, &channelState)
+	if err != nil {
+		// Create new channel state if it doesn't exist
+		channelState = models.ChannelState{
+			ChannelID: channelID,
+			FridgeID:  fmt.Sprintf("fridge:%d", channelID),
+		}
+	}
+
+	channelState.RatingStyle = style
+	channelState.LastActivity = time.Now()
+
+	return s.store.Set(channelKey, channelState)
+}
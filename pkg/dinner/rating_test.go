@@ -0,0 +1,66 @@
+package dinner
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/korjavin/whatsfordinner/pkg/models"
+)
+
+func TestRateDinnerValidatesAtTheScaleBounds(t *testing.T) {
+	service, store := newTestService(t)
+	if err := store.Set("dinner:1:1", models.Dinner{ID: "dinner:1:1", ChannelID: 1}); err != nil {
+		t.Fatalf("failed to save dinner: %v", err)
+	}
+
+	tests := []struct {
+		rating, scale int
+		valid         bool
+	}{
+		{1, 5, true},
+		{5, 5, true},
+		{0, 5, false},
+		{6, 5, false},
+		{10, 10, true},
+		{11, 10, false},
+		{2, 2, true},
+		{3, 2, false},
+	}
+	for _, tt := range tests {
+		err := service.RateDinner("dinner:1:1", "7", tt.rating, tt.scale)
+		if tt.valid && err != nil {
+			t.Errorf("RateDinner(%d of %d) failed: %v", tt.rating, tt.scale, err)
+		}
+		if !tt.valid && !errors.Is(err, ErrInvalidRating) {
+			t.Errorf("RateDinner(%d of %d) returned %v, want ErrInvalidRating", tt.rating, tt.scale, err)
+		}
+	}
+}
+
+func TestRatingsConvertBetweenScales(t *testing.T) {
+	tests := []struct {
+		rating, scale int
+		normalized    float64
+	}{
+		{1, 10, 1},
+		{10, 10, 5},
+		{3, 5, 3},
+		{1, 2, 1},
+		{2, 2, 5},
+	}
+	for _, tt := range tests {
+		if got := NormalizeRating(tt.rating, tt.scale); got != tt.normalized {
+			t.Errorf("NormalizeRating(%d, %d) = %v, want %v", tt.rating, tt.scale, got, tt.normalized)
+		}
+	}
+
+	if got := ScaleRating(5, 10); got != 10 {
+		t.Errorf("ScaleRating(5, 10) = %d, want 10", got)
+	}
+	if got := ScaleRating(1, 10); got != 1 {
+		t.Errorf("ScaleRating(1, 10) = %d, want 1", got)
+	}
+	if got := ScaleRating(3, 2); got != 2 {
+		t.Errorf("ScaleRating(3, 2) = %d, want 2", got)
+	}
+}
@@ -0,0 +1,60 @@
+package dinner
+
+import (
+	"fmt"
+)
+
+// reactionRatings maps Telegram reaction emojis to dinner ratings on the default 1-5 scale
+var reactionRatings = map[string]int{
+	"👍":   5,
+	"❤":   5,
+	"❤️":  5,
+	"❤‍🔥": 5,
+	"🔥":   5,
+	"😍":   5,
+	"🥰":   5,
+	"🤩":   5,
+	"💯":   5,
+	"🏆":   5,
+	"👏":   5,
+	"🎉":   5,
+	"👌":   4,
+	"😁":   4,
+	"🤗":   4,
+	"🤔":   3,
+	"😐":   3,
+	"🤷":   3,
+	"🥱":   3,
+	"😢":   2,
+	"🥴":   2,
+	"🤨":   2,
+	"💔":   2,
+	"👎":   1,
+	"🤮":   1,
+	"💩":   1,
+	"🤬":   1,
+	"😡":   1,
+}
+
+// RatingFromReaction converts a reaction emoji to a dinner rating on the default 1-5 scale.
+// It returns false if the emoji doesn't express an opinion about the food.
+func RatingFromReaction(emoji string) (int, bool) {
+	rating, ok := reactionRatings[emoji]
+	return rating, ok
+}
+
+// SetRatingMessage remembers which dinner a "how was dinner" message belongs to,
+// so that reactions on that message can be recorded as ratings
+func (s *Service) SetRatingMessage(channelID int64, messageID int, dinnerID string) error {
+	return s.store.Set(fmt.Sprintf("rating_message:%d:%d", channelID, messageID), dinnerID)
+}
+
+// GetDinnerIDByRatingMessage returns the ID of the dinner a "how was dinner" message belongs to
+func (s *Service) GetDinnerIDByRatingMessage(channelID int64, messageID int) (string, error) {
+	var dinnerID string
+	err := s.store.Get(fmt.Sprintf("rating_message:%d:%d", channelID, messageID), &dinnerID)
+	if err != nil {
+		return "", err
+	}
+	return dinnerID, nil
+}
@@ -0,0 +1,37 @@
+package dinner
+
+import "testing"
+
+func TestRatingFromReaction(t *testing.T) {
+	tests := []struct {
+		emoji  string
+		rating int
+		ok     bool
+	}{
+		{"👍", 5, true},
+		{"❤", 5, true},
+		{"❤️", 5, true},
+		{"👌", 4, true},
+		{"🤔", 3, true},
+		{"😢", 2, true},
+		{"👎", 1, true},
+		{"💩", 1, true},
+		{"🙏", 0, false},
+		{"", 0, false},
+	}
+
+	for _, tt := range tests {
+		rating, ok := RatingFromReaction(tt.emoji)
+		if rating != tt.rating || ok != tt.ok {
+			t.Errorf("RatingFromReaction(%q) = %d, %v, want %d, %v", tt.emoji, rating, ok, tt.rating, tt.ok)
+		}
+	}
+}
+
+func TestReactionRatingsAreOnTheDefaultScale(t *testing.T) {
+	for emoji, rating := range reactionRatings {
+		if rating < 1 || rating > DefaultRatingScale {
+			t.Errorf("%s rates %d, outside 1-%d", emoji, rating, DefaultRatingScale)
+		}
+	}
+}
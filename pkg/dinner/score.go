@@ -0,0 +1,25 @@
+package dinner
+
+import (
+	"github.com/korjavin/whatsfordinner/pkg/models"
+)
+
+// ScoredDish is a dish scored by how well the fridge covers it
+type ScoredDish struct {
+	Dish    models.Dish
+	Missing []string // Ingredients that aren't in the fridge, staples excluded
+	Score   float64  // Share of the ingredients that are available, from 0 to 1
+}
+
+// ScoreDish checks which ingredients of a dish are available, counting staples as available.
+// A dish without ingredients counts as complete.
+func ScoreDish(dish models.Dish, fridgeIngredients, staples []string) ScoredDish {
+	missing := FilterStaples(CompareIngredients(dish.Ingredients, fridgeIngredients), staples)
+
+	score := 1.0
+	if len(dish.Ingredients) > 0 {
+		score = float64(len(dish.Ingredients)-len(missing)) / float64(len(dish.Ingredients))
+	}
+
+	return ScoredDish{Dish: dish, Missing: missing, Score: score}
+}
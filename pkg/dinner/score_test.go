@@ -0,0 +1,75 @@
+package dinner
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/korjavin/whatsfordinner/pkg/models"
+)
+
+func TestScoreDish(t *testing.T) {
+	tests := []struct {
+		name        string
+		ingredients []string
+		fridge      []string
+		score       float64
+		missing     []string
+	}{
+		{"all there", []string{"eggs", "milk"}, []string{"Eggs", "milk"}, 1, nil},
+		{"half there", []string{"rice", "chicken breast", "peas", "carrots"}, []string{"rice", "carrots"}, 0.5, []string{"chicken breast", "peas"}},
+		{"nothing there", []string{"beef"}, nil, 0, []string{"beef"}},
+		{"staples count as there", []string{"potatoes", "salt", "oil"}, []string{"potatoes"}, 1, nil},
+		{"no ingredients", nil, nil, 1, nil},
+	}
+	for _, tt := range tests {
+		scored := ScoreDish(models.Dish{Name: tt.name, Ingredients: tt.ingredients}, tt.fridge, DefaultStaples)
+		if scored.Score != tt.score {
+			t.Errorf("%s: score = %v, want %v", tt.name, scored.Score, tt.score)
+		}
+		if fmt.Sprint(scored.Missing) != fmt.Sprint(tt.missing) {
+			t.Errorf("%s: missing = %v, want %v", tt.name, scored.Missing, tt.missing)
+		}
+	}
+}
+
+func TestScoreDishesReturnsScoresAndMissing(t *testing.T) {
+	service, store := newTestService(t)
+	dishes := []models.Dish{
+		{Name: "Fried rice", Cuisine: "Chinese", Ingredients: []string{"rice", "eggs", "spring onions"}},
+		{Name: "Pancakes", Cuisine: "French", Ingredients: []string{"flour", "eggs", "milk"}},
+	}
+	for _, dish := range dishes {
+		if err := store.Set(fmt.Sprintf("dish:%s:%s", dish.Cuisine, dish.Name), dish); err != nil {
+			t.Fatalf("failed to save dish: %v", err)
+		}
+	}
+	for _, name := range []string{"flour", "eggs", "milk", "rice"} {
+		if err := service.fridgeService.AddIngredient(1, name, ""); err != nil {
+			t.Fatalf("AddIngredient failed: %v", err)
+		}
+	}
+
+	scored, err := service.ScoreDishes(1, nil, 5)
+	if err != nil {
+		t.Fatalf("ScoreDishes failed: %v", err)
+	}
+	if len(scored) != 2 || scored[0].Dish.Name != "Pancakes" || scored[1].Dish.Name != "Fried rice" {
+		t.Fatalf("ScoreDishes() = %v, want pancakes before fried rice", scored)
+	}
+	if scored[0].Score != 1 || len(scored[0].Missing) != 0 {
+		t.Errorf("pancakes scored %v missing %v, want 1 and nothing missing", scored[0].Score, scored[0].Missing)
+	}
+	if scored[1].Score != 2.0/3 || !reflect.DeepEqual(scored[1].Missing, []string{"spring onions"}) {
+		t.Errorf("fried rice scored %v missing %v, want 2/3 and spring onions", scored[1].Score, scored[1].Missing)
+	}
+
+	// The plain dishes come in the same order
+	plain, err := service.SuggestDishes(1, nil, 1)
+	if err != nil {
+		t.Fatalf("SuggestDishes failed: %v", err)
+	}
+	if len(plain) != 1 || plain[0].Name != "Pancakes" {
+		t.Errorf("SuggestDishes() = %v, want only the pancakes", plain)
+	}
+}
@@ -0,0 +1,105 @@
+package dinner
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/korjavin/whatsfordinner/pkg/fridge"
+	"github.com/korjavin/whatsfordinner/pkg/models"
+)
+
+// DefaultServings is the number of servings a recipe is assumed to make
+// when the recipe doesn't say
+const DefaultServings = 4
+
+// MaxServings is the largest family size accepted by /servings
+const MaxServings = 20
+
+// ScaleRecipe returns a copy of the dish with the amounts of its ingredients
+// multiplied by factor. Ingredients without a leading amount are left unchanged.
+func ScaleRecipe(dish models.Dish, factor float64) models.Dish {
+	scaled := dish
+	scaled.Ingredients = make([]string, len(dish.Ingredients))
+
+	for i, ingredient := range dish.Ingredients {
+		quantity, rest, ok := fridge.ParseQuantity(ingredient)
+		if !ok {
+			scaled.Ingredients[i] = ingredient
+			continue
+		}
+
+		quantity.Amount *= factor
+		scaled.Ingredients[i] = quantity.String()
+		if rest != "" {
+			scaled.Ingredients[i] += " " + rest
+		}
+	}
+
+	return scaled
+}
+
+// ScaleForChannel scales the dish to the number of servings configured for a channel.
+// It returns the dish unchanged and false if no servings are configured or they already match.
+func (s *Service) ScaleForChannel(channelID int64, dish models.Dish) (models.Dish, bool) {
+	servings := s.GetServings(channelID)
+	recipeServings := dish.Servings
+	if recipeServings <= 0 {
+		recipeServings = DefaultServings
+	}
+
+	if servings <= 0 || servings == recipeServings {
+		return dish, false
+	}
+
+	return ScaleRecipe(dish, float64(servings)/float64(recipeServings)), true
+}
+
+// GetServings returns the number of servings configured for a channel.
+// If none are configured, it's the number of people who ate the last dinner with recorded attendance,
+// or 0 if that isn't known either.
+func (s *Service) GetServings(channelID int64) int {
+	var channelState models.ChannelState
+	err := s.store.Get(fmt.Sprintf("channel:%d", channelID), &channelState)
+	if err == nil && channelState.Servings > 0 {
+		return channelState.Servings
+	}
+	return s.lastAttendance(channelID)
+}
+
+// lastAttendance returns the number of people who ate the most recent dinner with recorded attendance
+func (s *Service) lastAttendance(channelID int64) int {
+	dinners, err := s.ListDinners(channelID)
+	if err != nil {
+		return 0
+	}
+
+	for _, dinner := range dinners {
+		if len(dinner.Attendees) > 0 {
+			return min(len(dinner.Attendees), MaxServings)
+		}
+	}
+	return 0
+}
+
+// SetServings sets the number of servings recipes are scaled to for a channel
+func (s *Service) SetServings(channelID int64, servings int) error {
+	if servings < 1 || servings > MaxServings {
+		return fmt.Errorf("servings must be between 1 and %d, got %d", MaxServings, servings)
+	}
+
+	channelKey := fmt.Sprintf("channel:%d", channelID)
+	var channelState models.ChannelState
+	err := s.store.Get(channelKey, &channelState)
+	if err != nil {
+		// Create new channel state if it doesn't exist
+		channelState = models.ChannelState{
+			ChannelID: channelID,
+			FridgeID:  fmt.Sprintf("fridge:%d", channelID),
+		}
+	}
+
+	channelState.Servings = servings
+	channelState.LastActivity = time.Now()
+
+	return s.store.Set(channelKey, channelState)
+}
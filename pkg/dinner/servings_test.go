@@ -0,0 +1,51 @@
+package dinner
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/korjavin/whatsfordinner/pkg/models"
+)
+
+func TestScaleRecipe(t *testing.T) {
+	dish := models.Dish{
+		Name:        "Pancakes",
+		Ingredients: []string{"200g flour", "2 eggs", "salt to taste"},
+	}
+
+	scaled := ScaleRecipe(dish, 1.5)
+
+	want := []string{"300g flour", "3 eggs", "salt to taste"}
+	if !reflect.DeepEqual(scaled.Ingredients, want) {
+		t.Errorf("ScaleRecipe(1.5) = %q, want %q", scaled.Ingredients, want)
+	}
+	if dish.Ingredients[0] != "200g flour" {
+		t.Errorf("ScaleRecipe changed the original dish to %q", dish.Ingredients)
+	}
+}
+
+func TestScaleForChannel(t *testing.T) {
+	service, _ := newTestService(t)
+	dish := models.Dish{Name: "Pancakes", Servings: 2, Ingredients: []string{"200g flour"}}
+
+	if _, scaled := service.ScaleForChannel(1, dish); scaled {
+		t.Error("scaled a recipe for a channel without servings")
+	}
+
+	if err := service.SetServings(1, 3); err != nil {
+		t.Fatalf("SetServings failed: %v", err)
+	}
+	got, scaled := service.ScaleForChannel(1, dish)
+	if !scaled || got.Ingredients[0] != "300g flour" {
+		t.Errorf("ScaleForChannel() = %q, %v, want 300g flour for 3 instead of 2 servings", got.Ingredients, scaled)
+	}
+
+	dish.Servings = 3
+	if _, scaled := service.ScaleForChannel(1, dish); scaled {
+		t.Error("scaled a recipe that already has the channel's servings")
+	}
+
+	if err := service.SetServings(1, MaxServings+1); err == nil {
+		t.Errorf("SetServings(%d) succeeded, want an error", MaxServings+1)
+	}
+}
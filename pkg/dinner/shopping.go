@@ -0,0 +1,85 @@
+package dinner
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/korjavin/whatsfordinner/pkg/fridge"
+)
+
+// MissingIngredients lists what has to be bought for a dish's ingredients, staples excluded.
+// Ingredients that aren't in the fridge are listed as they are. Ingredients with an amount,
+// like "500g flour", that are in the fridge but not enough of are listed with the shortfall,
+// e.g. "flour (400g more)". Amounts that can't be compared count as enough.
+func (s *Service) MissingIngredients(channelID int64, needed []string) ([]string, error) {
+	ingredients, err := s.fridgeService.ListIngredients(channelID)
+	if err != nil {
+		return nil, err
+	}
+	fridgeNames := make([]string, len(ingredients))
+	for i, ingredient := range ingredients {
+		fridgeNames[i] = ingredient.Name
+	}
+
+	staples := s.GetStaples(channelID)
+	missing := FilterStaples(CompareIngredients(needed, fridgeNames), staples)
+
+	absent := make(map[string]bool, len(missing))
+	for _, ingredient := range missing {
+		absent[ingredient] = true
+	}
+
+	// Check the amounts of the ingredients that are there
+	requirements := make(map[string]string)
+	for _, ingredient := range needed {
+		if absent[ingredient] {
+			continue
+		}
+		quantity, name, ok := fridge.ParseQuantity(normalizeIngredient(ingredient))
+		if !ok || name == "" || isStaple(name, staples) {
+			continue
+		}
+		requirements[name] = quantity.String()
+	}
+
+	_, shortfalls := s.fridgeService.HasEnough(channelID, requirements)
+	names := make([]string, 0, len(shortfalls))
+	for name := range shortfalls {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		missing = append(missing, fmt.Sprintf("%s (%s more)", name, shortfalls[name]))
+	}
+
+	return missing, nil
+}
+
+// RestockList lists what to buy on the next shopping trip: the staples that aren't in the fridge
+// or pantry, followed by the ingredients that are running low, e.g. "milk (100ml left)", or were marked so with /low
+func (s *Service) RestockList(channelID int64) ([]string, error) {
+	ingredients, err := s.fridgeService.ListIngredients(channelID)
+	if err != nil {
+		return nil, err
+	}
+	fridgeNames := make([]string, len(ingredients))
+	for i, ingredient := range ingredients {
+		fridgeNames[i] = ingredient.Name
+	}
+
+	list := CompareIngredients(s.GetStaples(channelID), fridgeNames)
+	sort.Strings(list)
+
+	var low []string
+	for _, ingredient := range ingredients {
+		switch {
+		case fridge.IsLow(ingredient.Quantity):
+			low = append(low, fmt.Sprintf("%s (%s left)", ingredient.Name, ingredient.Quantity))
+		case ingredient.LowStock:
+			low = append(low, fmt.Sprintf("%s (running low)", ingredient.Name))
+		}
+	}
+	sort.Strings(low)
+
+	return append(list, low...), nil
+}
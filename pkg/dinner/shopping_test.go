@@ -0,0 +1,55 @@
+package dinner
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/korjavin/whatsfordinner/pkg/fridge"
+)
+
+func TestLowIngredientsAreOnTheRestockList(t *testing.T) {
+	service, _ := newTestService(t)
+	stock := map[string]string{"milk": "1l", "cream": "50ml", "butter": "250g", "rice": "1kg"}
+	for name, quantity := range stock {
+		if err := service.fridgeService.AddIngredient(1, name, quantity); err != nil {
+			t.Fatalf("AddIngredient failed: %v", err)
+		}
+	}
+	for _, name := range []string{"Milk", "butter", "rice"} {
+		if _, err := service.fridgeService.MarkLow(1, name, true); err != nil {
+			t.Fatalf("MarkLow failed: %v", err)
+		}
+	}
+	if _, err := service.fridgeService.MarkLow(1, "rice", false); err != nil {
+		t.Fatalf("MarkLow failed: %v", err)
+	}
+	if _, err := service.fridgeService.MarkLow(1, "saffron", true); !errors.Is(err, fridge.ErrIngredientNotFound) {
+		t.Errorf("marking a missing ingredient returned %v, want ErrIngredientNotFound", err)
+	}
+
+	// Buying more butter clears its flag, a smaller amount of milk doesn't
+	if err := service.fridgeService.AddIngredient(1, "butter", "500g"); err != nil {
+		t.Fatalf("AddIngredient failed: %v", err)
+	}
+	if err := service.fridgeService.AddIngredient(1, "milk", "500ml"); err != nil {
+		t.Fatalf("AddIngredient failed: %v", err)
+	}
+
+	list, err := service.RestockList(1)
+	if err != nil {
+		t.Fatalf("RestockList failed: %v", err)
+	}
+	if len(list) < 2 {
+		t.Fatalf("RestockList() = %v, want the low ingredients at the end", list)
+	}
+	if low := list[len(list)-2:]; !reflect.DeepEqual(low, []string{"cream (50ml left)", "milk (running low)"}) {
+		t.Errorf("low ingredients = %v, want cream and milk", low)
+	}
+	for _, item := range list {
+		if strings.HasPrefix(item, "butter") || strings.HasPrefix(item, "rice") {
+			t.Errorf("%q is on the restock list, want its flag cleared", item)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package dinner
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/korjavin/whatsfordinner/pkg/models"
+)
+
+// DefaultStaples are the basics most families always have at home.
+// They are used for channels that haven't configured their own staples.
+var DefaultStaples = models.DefaultStaples
+
+// FilterStaples removes staples from a list of missing ingredients.
+// An ingredient counts as a staple if every word of the staple appears as a whole word in it,
+// so "sea salt" and "2 tbsp olive oil" match the staples "salt" and "oil", but "salted butter" doesn't.
+func FilterStaples(missing []string, staples []string) []string {
+	var filtered []string
+	for _, ingredient := range missing {
+		if !isStaple(ingredient, staples) {
+			filtered = append(filtered, ingredient)
+		}
+	}
+	return filtered
+}
+
+// isStaple checks whether an ingredient matches any of the staples
+func isStaple(ingredient string, staples []string) bool {
+	words := make(map[string]bool)
+	for _, word := range strings.FieldsFunc(normalizeIngredient(ingredient), isWordSeparator) {
+		words[word] = true
+	}
+
+	for _, staple := range staples {
+		stapleWords := strings.FieldsFunc(normalizeIngredient(staple), isWordSeparator)
+		if len(stapleWords) == 0 {
+			continue
+		}
+
+		matched := true
+		for _, word := range stapleWords {
+			if !words[word] {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isWordSeparator reports whether r separates words in an ingredient name
+func isWordSeparator(r rune) bool {
+	return r == ' ' || r == ',' || r == '-' || r == '/'
+}
+
+// GetStaples returns the staples of a channel, or the default staples if none are configured
+func (s *Service) GetStaples(channelID int64) []string {
+	var channelState models.ChannelState
+	err := s.store.Get(fmt.Sprintf("channel:%d", channelID), &channelState)
+	if err != nil {
+		return DefaultStaples
+	}
+	return channelState.Effective(models.DefaultSettings()).Staples
+}
+
+// AddStaples adds staples to a channel, skipping ones it already has
+func (s *Service) AddStaples(channelID int64, names []string) ([]string, error) {
+	staples := append([]string(nil), s.GetStaples(channelID)...)
+
+	for _, name := range names {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+
+		exists := false
+		for _, staple := range staples {
+			if staple == name {
+				exists = true
+				break
+			}
+		}
+		if !exists {
+			staples = append(staples, name)
+		}
+	}
+
+	return staples, s.setStaples(channelID, staples)
+}
+
+// RemoveStaple removes a staple from a channel, returning false if it wasn't a staple
+func (s *Service) RemoveStaple(channelID int64, name string) (bool, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+
+	staples := make([]string, 0)
+	removed := false
+	for _, staple := range s.GetStaples(channelID) {
+		if staple == name {
+			removed = true
+			continue
+		}
+		staples = append(staples, staple)
+	}
+
+	if !removed {
+		return false, nil
+	}
+
+	return true, s.setStaples(channelID, staples)
+}
+
+// ResetStaples restores the default staples for a channel
+func (s *Service) ResetStaples(channelID int64) error {
+	return s.setStaples(channelID, nil)
+}
+
+// setStaples saves the staples of a channel, nil means the default staples
+func (s *Service) setStaples(channelID int64, staples []string) error {
+	channelKey := fmt.Sprintf("channel:%d", channelID)
+	var channelState models.ChannelState
+	err := s.store.Get(channelKey, &channelState)
+	if err != nil {
+		// Create new channel state if it doesn't exist
+		channelState = models.ChannelState{
+			ChannelID: channelID,
+			FridgeID:  fmt.Sprintf("fridge:%d", channelID),
+		}
+	}
+
+	channelState.Staples = staples
+	channelState.LastActivity = time.Now()
+
+	return s.store.Set(channelKey, channelState)
+}
@@ -0,0 +1,58 @@
+package dinner
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/korjavin/whatsfordinner/pkg/models"
+)
+
+func TestFilterStaples(t *testing.T) {
+	missing := []string{"sea salt", "2 tbsp olive oil", "salted butter", "Black Pepper", "spaghetti"}
+
+	got := FilterStaples(missing, DefaultStaples)
+	want := []string{"salted butter", "spaghetti"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("FilterStaples() = %v, want %v", got, want)
+	}
+}
+
+func TestStaplesNeverMissing(t *testing.T) {
+	service, _ := newTestService(t)
+	if _, err := service.AddStaples(1, []string{"Garlic"}); err != nil {
+		t.Fatalf("AddStaples failed: %v", err)
+	}
+	staples := service.GetStaples(1)
+
+	dish := models.Dish{Name: "Aglio e olio", Ingredients: []string{"spaghetti", "3 cloves garlic", "olive oil", "salt", "parsley"}}
+	scored := ScoreDish(dish, []string{"spaghetti"}, staples)
+	if !reflect.DeepEqual(scored.Missing, []string{"parsley"}) {
+		t.Errorf("missing = %v, want only parsley", scored.Missing)
+	}
+	if scored.Score != 0.8 {
+		t.Errorf("score = %v, want 0.8 with staples counted as available", scored.Score)
+	}
+
+	// The fridge is empty, so only the staples are left off the shopping list
+	shopping, err := service.MissingIngredients(1, dish.Ingredients)
+	if err != nil {
+		t.Fatalf("MissingIngredients failed: %v", err)
+	}
+	for _, item := range shopping {
+		if isStaple(item, staples) {
+			t.Errorf("shopping list has the staple %q", item)
+		}
+	}
+	if len(shopping) != 2 {
+		t.Errorf("shopping list = %v, want spaghetti and parsley", shopping)
+	}
+
+	// Once it's no longer a staple, garlic is missing again
+	if removed, err := service.RemoveStaple(1, "garlic"); err != nil || !removed {
+		t.Fatalf("RemoveStaple = %v, %v, want it removed", removed, err)
+	}
+	scored = ScoreDish(dish, []string{"spaghetti"}, service.GetStaples(1))
+	if len(scored.Missing) != 2 {
+		t.Errorf("missing = %v after removing the staple, want garlic and parsley", scored.Missing)
+	}
+}
@@ -0,0 +1,69 @@
+package dinner
+
+import (
+	"strings"
+
+	"github.com/korjavin/whatsfordinner/pkg/models"
+)
+
+// NormalizeTags lowercases tags and strips a leading "#", dropping blanks and duplicates
+func NormalizeTags(tags []string) []string {
+	var result []string
+	seen := make(map[string]bool)
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(tag), "#")))
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		result = append(result, tag)
+	}
+	return result
+}
+
+// SplitTags separates "#tag" words from the rest of command arguments,
+// e.g. "#quick again" returns ["quick"] and "again"
+func SplitTags(args string) (tags []string, rest string) {
+	var words []string
+	for _, word := range strings.Fields(args) {
+		if strings.HasPrefix(word, "#") {
+			tags = append(tags, word)
+			continue
+		}
+		words = append(words, word)
+	}
+	return NormalizeTags(tags), strings.Join(words, " ")
+}
+
+// HasTags reports whether a dish has all the given tags, ignoring case
+func HasTags(dish models.Dish, tags []string) bool {
+	dishTags := NormalizeTags(dish.Tags)
+	for _, tag := range NormalizeTags(tags) {
+		found := false
+		for _, dishTag := range dishTags {
+			if dishTag == tag {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// FilterByTags returns the dishes that have all the given tags
+func FilterByTags(dishes []models.Dish, tags []string) []models.Dish {
+	if len(tags) == 0 {
+		return dishes
+	}
+
+	var filtered []models.Dish
+	for _, dish := range dishes {
+		if HasTags(dish, tags) {
+			filtered = append(filtered, dish)
+		}
+	}
+	return filtered
+}
@@ -0,0 +1,56 @@
+package dinner
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/korjavin/whatsfordinner/pkg/models"
+)
+
+func TestOfflineSuggestionsFilterByTag(t *testing.T) {
+	service, store := newTestService(t)
+	dishes := []models.Dish{
+		{Name: "Carbonara", Cuisine: "Italian", Ingredients: []string{"pasta", "eggs"}, Tags: []string{"quick"}},
+		{Name: "Lasagne", Cuisine: "Italian", Ingredients: []string{"pasta", "beef"}, Tags: []string{"kid-friendly"}},
+		{Name: "Arrabbiata", Cuisine: "Italian", Ingredients: []string{"pasta", "chili"}, Tags: []string{"Spicy", "#Quick"}},
+	}
+	for _, dish := range dishes {
+		if err := store.Set(fmt.Sprintf("dish:%s:%s", dish.Cuisine, dish.Name), dish); err != nil {
+			t.Fatalf("failed to save dish: %v", err)
+		}
+	}
+
+	tags, rest := SplitTags("#QUICK please")
+	if !reflect.DeepEqual(tags, []string{"quick"}) || rest != "please" {
+		t.Fatalf("SplitTags() = %v, %q, want [quick] and the rest", tags, rest)
+	}
+
+	names := func(tags ...string) []string {
+		suggestions, err := service.OfflineSuggestions(1, nil, tags, 5)
+		if err != nil {
+			t.Fatalf("OfflineSuggestions failed: %v", err)
+		}
+		var names []string
+		for _, suggestion := range suggestions {
+			names = append(names, suggestion["name"].(string))
+		}
+		return names
+	}
+
+	got := names("quick")
+	sort.Strings(got)
+	if !reflect.DeepEqual(got, []string{"Arrabbiata", "Carbonara"}) {
+		t.Errorf("quick dishes = %v, want Carbonara and Arrabbiata", got)
+	}
+	if got := names("quick", "spicy"); !reflect.DeepEqual(got, []string{"Arrabbiata"}) {
+		t.Errorf("quick and spicy dishes = %v, want only Arrabbiata", got)
+	}
+	if got := names("vegan"); len(got) != 0 {
+		t.Errorf("vegan dishes = %v, want none rather than a fallback", got)
+	}
+	if got := names(); len(got) != 3 {
+		t.Errorf("untagged suggestions = %v, want every dish", got)
+	}
+}
@@ -0,0 +1,43 @@
+package dinner
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MaxStepTimer is the longest timer a recipe step can start, longer times are probably not a timer
+const MaxStepTimer = 12 * time.Hour
+
+// stepDurationPattern matches times like "20 minutes", "1.5 hours", "10-15 mins" or "30 sec"
+var stepDurationPattern = regexp.MustCompile(`(?i)\b(\d+(?:[.,]\d+)?)(?:\s*(?:-|–|to)\s*\d+(?:[.,]\d+)?)?\s*(hours?|hrs?|minutes?|mins?|seconds?|secs?)\b`)
+
+// ParseStepDuration finds the first time mentioned in a recipe step, e.g. 20 minutes in
+// "Simmer for 20 minutes". For ranges like "10-15 minutes" the shorter time is used, so the
+// cook checks early rather than late.
+func ParseStepDuration(step string) (time.Duration, bool) {
+	match := stepDurationPattern.FindStringSubmatch(step)
+	if match == nil {
+		return 0, false
+	}
+
+	amount, err := strconv.ParseFloat(strings.Replace(match[1], ",", ".", 1), 64)
+	if err != nil || amount <= 0 {
+		return 0, false
+	}
+
+	unit := time.Minute
+	switch strings.ToLower(match[2])[0] {
+	case 'h':
+		unit = time.Hour
+	case 's':
+		unit = time.Second
+	}
+
+	duration := time.Duration(amount * float64(unit)).Round(time.Second)
+	if duration <= 0 || duration > MaxStepTimer {
+		return 0, false
+	}
+	return duration, true
+}
@@ -0,0 +1,34 @@
+package dinner
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseStepDuration(t *testing.T) {
+	tests := []struct {
+		step string
+		want time.Duration
+		ok   bool
+	}{
+		{"Simmer for 20 minutes", 20 * time.Minute, true},
+		{"Bake for 1.5 hours until golden", 90 * time.Minute, true},
+		{"Bake for 1,5 hours", 90 * time.Minute, true},
+		{"Roast 10-15 mins", 10 * time.Minute, true},
+		{"Rest 5 to 10 minutes, then slice", 5 * time.Minute, true},
+		{"Blanch for 30 sec", 30 * time.Second, true},
+		{"Marinate 2 hrs, then grill 8 minutes", 2 * time.Hour, true},
+		{"Cook for 1 MINUTE", time.Minute, true},
+		{"Chop 2 onions", 0, false},
+		{"Serve at once", 0, false},
+		{"Cook for 0 minutes", 0, false},
+		// Longer times are proving or soaking overnight, not a timer
+		{"Soak the beans for 24 hours", 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := ParseStepDuration(tt.step)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("ParseStepDuration(%q) = %s, %v, want %s, %v", tt.step, got, ok, tt.want, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package fridge
+
+import (
+	"strings"
+)
+
+// Ingredient categories, in the order they are shown in the fridge
+const (
+	CategoryVegetables = "vegetables"
+	CategoryFruit      = "fruit"
+	CategoryDairy      = "dairy"
+	CategoryMeat       = "meat"
+	CategoryFish       = "fish"
+	CategoryGrains     = "grains"
+	CategorySpices     = "spices"
+	CategoryOther      = "other"
+)
+
+// Categories lists all ingredient categories in display order
+var Categories = []string{
+	CategoryVegetables,
+	CategoryFruit,
+	CategoryDairy,
+	CategoryMeat,
+	CategoryFish,
+	CategoryGrains,
+	CategorySpices,
+	CategoryOther,
+}
+
+// categoryLabels are the headers shown above each category in the fridge
+var categoryLabels = map[string]string{
+	CategoryVegetables: "🥦 Vegetables",
+	CategoryFruit:      "🍎 Fruit",
+	CategoryDairy:      "🧀 Dairy & eggs",
+	CategoryMeat:       "🥩 Meat",
+	CategoryFish:       "🐟 Fish & seafood",
+	CategoryGrains:     "🍞 Grains, bread & pasta",
+	CategorySpices:     "🧂 Spices, oils & sauces",
+	CategoryOther:      "📦 Other",
+}
+
+// categoryKeywords maps words found in ingredient names to their category
+var categoryKeywords = map[string]string{
+	"tomato": CategoryVegetables, "tomatoes": CategoryVegetables, "potato": CategoryVegetables, "potatoes": CategoryVegetables,
+	"onion": CategoryVegetables, "onions": CategoryVegetables, "garlic": CategoryVegetables, "carrot": CategoryVegetables,
+	"carrots": CategoryVegetables, "peppers": CategoryVegetables, "cucumber": CategoryVegetables,
+	"lettuce": CategoryVegetables, "spinach": CategoryVegetables, "broccoli": CategoryVegetables, "cabbage": CategoryVegetables,
+	"zucchini": CategoryVegetables, "mushroom": CategoryVegetables, "mushrooms": CategoryVegetables, "celery": CategoryVegetables,
+	"eggplant": CategoryVegetables, "beans": CategoryVegetables, "peas": CategoryVegetables, "corn": CategoryVegetables,
+
+	"apple": CategoryFruit, "apples": CategoryFruit, "banana": CategoryFruit, "bananas": CategoryFruit,
+	"lemon": CategoryFruit, "lemons": CategoryFruit, "lime": CategoryFruit, "orange": CategoryFruit,
+	"oranges": CategoryFruit, "berries": CategoryFruit, "strawberries": CategoryFruit, "grapes": CategoryFruit,
+	"pear": CategoryFruit, "pears": CategoryFruit, "avocado": CategoryFruit,
+
+	"milk": CategoryDairy, "cheese": CategoryDairy, "butter": CategoryDairy, "yogurt": CategoryDairy,
+	"yoghurt": CategoryDairy, "cream": CategoryDairy, "egg": CategoryDairy, "eggs": CategoryDairy,
+	"mozzarella": CategoryDairy, "parmesan": CategoryDairy, "feta": CategoryDairy,
+
+	"chicken": CategoryMeat, "beef": CategoryMeat, "pork": CategoryMeat, "lamb": CategoryMeat,
+	"turkey": CategoryMeat, "bacon": CategoryMeat, "ham": CategoryMeat, "sausage": CategoryMeat,
+	"sausages": CategoryMeat, "mince": CategoryMeat, "steak": CategoryMeat,
+
+	"fish": CategoryFish, "salmon": CategoryFish, "tuna": CategoryFish, "cod": CategoryFish,
+	"shrimp": CategoryFish, "prawns": CategoryFish, "mussels": CategoryFish,
+
+	"rice": CategoryGrains, "pasta": CategoryGrains, "spaghetti": CategoryGrains, "noodles": CategoryGrains,
+	"bread": CategoryGrains, "flour": CategoryGrains, "oats": CategoryGrains, "couscous": CategoryGrains,
+	"tortillas": CategoryGrains, "quinoa": CategoryGrains,
+
+	"salt": CategorySpices, "pepper": CategorySpices, "oil": CategorySpices, "vinegar": CategorySpices, "sugar": CategorySpices,
+	"paprika": CategorySpices, "cumin": CategorySpices, "oregano": CategorySpices, "basil": CategorySpices,
+	"sauce": CategorySpices, "ketchup": CategorySpices, "mustard": CategorySpices, "mayonnaise": CategorySpices,
+	"honey": CategorySpices, "cinnamon": CategorySpices,
+}
+
+// Categorize guesses the category of an ingredient from the words in its name.
+// The last known word wins, so "chicken stock" and "red pepper" are categorized by their main word.
+func Categorize(name string) string {
+	category := CategoryOther
+	for _, word := range strings.Fields(strings.ToLower(name)) {
+		if c, ok := categoryKeywords[strings.Trim(word, ",.()")]; ok {
+			category = c
+		}
+	}
+	return category
+}
+
+// CategoryLabel returns the header shown above a category in the fridge
+func CategoryLabel(category string) string {
+	if label, ok := categoryLabels[category]; ok {
+		return label
+	}
+	return categoryLabels[CategoryOther]
+}
+
+// CategoryOrder returns the position of a category in Categories, unknown categories sort last
+func CategoryOrder(category string) int {
+	for i, c := range Categories {
+		if c == category {
+			return i
+		}
+	}
+	return len(Categories)
+}
+
+// ParseCategory finds the category named by value, ignoring case, e.g. "Dairy" or "veg".
+// A value of at least three letters matches the category it starts.
+func ParseCategory(value string) (string, bool) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	for _, category := range Categories {
+		if value == category || (len(value) >= 3 && strings.HasPrefix(category, value)) {
+			return category, true
+		}
+	}
+	return "", false
+}
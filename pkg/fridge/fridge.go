@@ -2,6 +2,7 @@ package fridge
 
 import (
 	"fmt"
+	"sort"
 	"time"
 
 	"github.com/korjavin/whatsfordinner/pkg/logger"
@@ -43,12 +44,38 @@ func (s *Service) GetFridge(channelID int64) (*models.Fridge, error) {
 		}
 	}
 
+	// Ingredients saved before pantry support live in the fridge,
+	// and ones saved before categories get one from their name
+	for name, ingredient := range fridge.Ingredients {
+		if ingredient.Location == "" || ingredient.Category == "" {
+			if ingredient.Location == "" {
+				ingredient.Location = models.LocationFridge
+			}
+			if ingredient.Category == "" {
+				ingredient.Category = Categorize(name)
+			}
+			fridge.Ingredients[name] = ingredient
+		}
+	}
+
 	return &fridge, nil
 }
 
 // AddIngredient adds an ingredient to the fridge
+// If the ingredient is already kept in the pantry, it stays there
 func (s *Service) AddIngredient(channelID int64, name, quantity string) error {
-	s.logger.Info("Adding ingredient to fridge %d: %s (quantity: %s)", channelID, name, quantity)
+	return s.addIngredient(channelID, name, quantity, "")
+}
+
+// AddPantryIngredient adds a staple ingredient to the pantry, moving it there if it's in the fridge
+func (s *Service) AddPantryIngredient(channelID int64, name, quantity string) error {
+	return s.addIngredient(channelID, name, quantity, models.LocationPantry)
+}
+
+// addIngredient adds an ingredient to the given location
+// An empty location keeps the ingredient where it is, defaulting to the fridge for new ones
+func (s *Service) addIngredient(channelID int64, name, quantity, location string) error {
+	s.logger.Info("Adding ingredient to fridge %d: %s (quantity: %s, location: %s)", channelID, name, quantity, location)
 
 	fridge, err := s.GetFridge(channelID)
 	if err != nil {
@@ -56,10 +83,21 @@ func (s *Service) AddIngredient(channelID int64, name, quantity string) error {
 		return err
 	}
 
+	existing, exists := fridge.Ingredients[name]
+	if location == "" {
+		location = models.LocationFridge
+		if exists {
+			location = existing.Location
+		}
+	}
+
 	fridge.Ingredients[name] = models.Ingredient{
 		Name:     name,
 		Quantity: quantity,
 		AddedAt:  time.Now(),
+		Location: location,
+		Category: Categorize(name),
+		LowStock: exists && stillLow(existing, quantity),
 	}
 
 	fridge.LastUpdated = time.Now()
@@ -74,17 +112,55 @@ func (s *Service) AddIngredient(channelID int64, name, quantity string) error {
 	return nil
 }
 
-// RemoveIngredient removes an ingredient from the fridge
-func (s *Service) RemoveIngredient(channelID int64, name string) error {
+// RemoveIngredient removes an ingredient from the fridge.
+// The name is matched ignoring case. It returns the ingredient's name as it was in the fridge.
+func (s *Service) RemoveIngredient(channelID int64, name string) (string, error) {
 	fridge, err := s.GetFridge(channelID)
 	if err != nil {
-		return err
+		return "", err
+	}
+
+	key, ok := findIngredient(fridge, name)
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrIngredientNotFound, name)
 	}
 
-	delete(fridge.Ingredients, name)
+	delete(fridge.Ingredients, key)
 	fridge.LastUpdated = time.Now()
 
-	return s.store.Set(fridge.ID, fridge)
+	if err := s.store.Set(fridge.ID, fridge); err != nil {
+		return "", fmt.Errorf("failed to save fridge: %w", err)
+	}
+	return key, nil
+}
+
+// RemoveByCategory removes every ingredient of a category from the fridge and the pantry.
+// It returns the names of the removed ingredients, sorted.
+func (s *Service) RemoveByCategory(channelID int64, category string) ([]string, error) {
+	fridge, err := s.GetFridge(channelID)
+	if err != nil {
+		return nil, err
+	}
+
+	var removed []string
+	for name, ingredient := range fridge.Ingredients {
+		if ingredient.Category == category {
+			delete(fridge.Ingredients, name)
+			removed = append(removed, name)
+		}
+	}
+	if len(removed) == 0 {
+		return nil, nil
+	}
+	sort.Strings(removed)
+
+	fridge.LastUpdated = time.Now()
+	if err := s.store.Set(fridge.ID, fridge); err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Removed %d %s ingredients from fridge %d", len(removed), category, channelID)
+	return removed, nil
 }
 
 // ListIngredients returns a list of all ingredients in the fridge
@@ -119,18 +195,84 @@ func (s *Service) HasIngredients(channelID int64, ingredientNames []string) (boo
 	return len(missing) == 0, missing, nil
 }
 
+// HasEnough checks if the fridge has the required amounts of ingredients, given as name to quantity,
+// e.g. "flour" to "500g". It returns the shortfalls as name to missing quantity.
+// Missing ingredients are short by the whole required quantity. If either amount can't be parsed
+// or the units don't match, having the ingredient at all counts as enough.
+func (s *Service) HasEnough(channelID int64, requirements map[string]string) (bool, map[string]string) {
+	shortfalls := make(map[string]string)
+
+	fridge, err := s.GetFridge(channelID)
+	if err != nil {
+		s.logger.Error("Failed to get fridge: %v", err)
+		for name, need := range requirements {
+			shortfalls[name] = need
+		}
+		return len(shortfalls) == 0, shortfalls
+	}
+
+	for name, need := range requirements {
+		key, ok := findIngredient(fridge, name)
+		if !ok {
+			shortfalls[name] = need
+			continue
+		}
+
+		short, comparable := Shortfall(fridge.Ingredients[key].Quantity, need)
+		if comparable && short != "" {
+			shortfalls[name] = short
+		}
+	}
+
+	return len(shortfalls) == 0, shortfalls
+}
+
 // ResetFridge resets the fridge for a channel
+// Only perishables are cleared, pantry staples are kept
 func (s *Service) ResetFridge(channelID int64) error {
-	fridgeKey := fmt.Sprintf("fridge:%d", channelID)
+	fridge, err := s.GetFridge(channelID)
+	if err != nil {
+		return err
+	}
 
-	fridge := models.Fridge{
-		ID:          fridgeKey,
-		ChannelID:   channelID,
-		Ingredients: make(map[string]models.Ingredient),
-		LastUpdated: time.Now(),
+	for name, ingredient := range fridge.Ingredients {
+		if ingredient.Location != models.LocationPantry {
+			delete(fridge.Ingredients, name)
+		}
 	}
 
-	return s.store.Set(fridgeKey, fridge)
+	fridge.LastUpdated = time.Now()
+
+	return s.store.Set(fridge.ID, fridge)
+}
+
+// RawQuantities reports whether a channel wants fridge quantities shown as entered
+func (s *Service) RawQuantities(channelID int64) bool {
+	var channelState models.ChannelState
+	err := s.store.Get(fmt.Sprintf("channel:%d", channelID), &channelState)
+	if err != nil {
+		return false
+	}
+	return channelState.RawQuantities
+}
+
+// SetRawQuantities sets whether fridge quantities are shown as entered or converted to metric units
+func (s *Service) SetRawQuantities(channelID int64, raw bool) error {
+	channelKey := fmt.Sprintf("channel:%d", channelID)
+	var channelState models.ChannelState
+	err := s.store.Get(channelKey, &channelState)
+	if err != nil {
+		// Create new channel state if it doesn't exist
+		channelState = models.ChannelState{
+			ChannelID: channelID,
+			FridgeID:  fmt.Sprintf("fridge:%d", channelID),
+		}
+	}
+
+	channelState.RawQuantities = raw
+	channelState.LastActivity = time.Now()
+
+	return s.store.Set(channelKey, channelState)
 }
 
 // UpdateIngredients updates multiple ingredients at once
@@ -141,10 +283,19 @@ func (s *Service) UpdateIngredients(channelID int64, ingredients map[string]stri
 	}
 
 	for name, quantity := range ingredients {
+		location := models.LocationFridge
+		existing, exists := fridge.Ingredients[name]
+		if exists {
+			location = existing.Location
+		}
+
 		fridge.Ingredients[name] = models.Ingredient{
 			Name:     name,
 			Quantity: quantity,
 			AddedAt:  time.Now(),
+			Location: location,
+			Category: Categorize(name),
+			LowStock: exists && stillLow(existing, quantity),
 		}
 	}
 
@@ -0,0 +1,159 @@
+package fridge
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/korjavin/whatsfordinner/internal/test"
+	"github.com/korjavin/whatsfordinner/pkg/models"
+)
+
+// locations returns the location of every ingredient in a channel's fridge by name
+func locations(t *testing.T, service *Service, channelID int64) map[string]string {
+	t.Helper()
+
+	fridge, err := service.GetFridge(channelID)
+	if err != nil {
+		t.Fatalf("GetFridge failed: %v", err)
+	}
+	locations := make(map[string]string)
+	for name, ingredient := range fridge.Ingredients {
+		locations[name] = ingredient.Location
+	}
+	return locations
+}
+
+func TestResetFridgeKeepsPantry(t *testing.T) {
+	service := New(test.NewStore(t))
+
+	for _, name := range []string{"milk", "eggs"} {
+		if err := service.AddIngredient(1, name, "1"); err != nil {
+			t.Fatalf("AddIngredient failed: %v", err)
+		}
+	}
+	for _, name := range []string{"salt", "flour"} {
+		if err := service.AddPantryIngredient(1, name, ""); err != nil {
+			t.Fatalf("AddPantryIngredient failed: %v", err)
+		}
+	}
+	// Adding a staple to the fridge again, e.g. from a photo, leaves it in the pantry
+	if err := service.AddIngredient(1, "flour", "1kg"); err != nil {
+		t.Fatalf("AddIngredient failed: %v", err)
+	}
+
+	if err := service.ResetFridge(1); err != nil {
+		t.Fatalf("ResetFridge failed: %v", err)
+	}
+
+	got := locations(t, service, 1)
+	want := map[string]string{"salt": models.LocationPantry, "flour": models.LocationPantry}
+	if len(got) != len(want) || got["salt"] != want["salt"] || got["flour"] != want["flour"] {
+		t.Errorf("after a sync the fridge has %v, want only the pantry staples %v", got, want)
+	}
+}
+
+func TestIngredientsWithoutLocationAreInTheFridge(t *testing.T) {
+	store := test.NewStore(t)
+	service := New(store)
+
+	// A fridge saved before ingredients had a location
+	legacy := models.Fridge{
+		ID:          "fridge:1",
+		ChannelID:   1,
+		Ingredients: map[string]models.Ingredient{"milk": {Name: "milk", Quantity: "1l"}},
+	}
+	if err := store.Set(legacy.ID, legacy); err != nil {
+		t.Fatalf("failed to save fridge: %v", err)
+	}
+
+	if got := locations(t, service, 1)["milk"]; got != models.LocationFridge {
+		t.Errorf("milk is in %q, want %q", got, models.LocationFridge)
+	}
+	if err := service.ResetFridge(1); err != nil {
+		t.Fatalf("ResetFridge failed: %v", err)
+	}
+	if got := locations(t, service, 1); len(got) != 0 {
+		t.Errorf("after a sync the fridge has %v, want nothing", got)
+	}
+}
+
+func TestHasEnoughReportsShortfalls(t *testing.T) {
+	service := New(test.NewStore(t))
+	stock := map[string]string{"flour": "100g", "eggs": "2", "milk": "1l", "salt": "a pinch"}
+	for name, quantity := range stock {
+		if err := service.AddIngredient(1, name, quantity); err != nil {
+			t.Fatalf("AddIngredient failed: %v", err)
+		}
+	}
+
+	enough, shortfalls := service.HasEnough(1, map[string]string{
+		"flour":  "0.5kg",
+		"eggs":   "6",
+		"milk":   "250ml",
+		"salt":   "10g",
+		"butter": "50g",
+	})
+	want := map[string]string{"flour": "400g", "eggs": "4", "butter": "50g"}
+	if enough || !reflect.DeepEqual(shortfalls, want) {
+		t.Errorf("HasEnough() = %v, %v, want shortfalls %v", enough, shortfalls, want)
+	}
+
+	enough, shortfalls = service.HasEnough(1, map[string]string{"flour": "100g", "milk": "1000ml"})
+	if !enough || len(shortfalls) != 0 {
+		t.Errorf("HasEnough() = %v, %v, want enough of everything", enough, shortfalls)
+	}
+}
+
+func TestRemoveByCategoryOnlyRemovesThatCategory(t *testing.T) {
+	service := New(test.NewStore(t))
+	for _, name := range []string{"milk", "cheese", "carrots", "chicken"} {
+		if err := service.AddIngredient(1, name, ""); err != nil {
+			t.Fatalf("AddIngredient failed: %v", err)
+		}
+	}
+
+	category, ok := ParseCategory("Dairy")
+	if !ok || category != CategoryDairy {
+		t.Fatalf("ParseCategory(Dairy) = %q, %v, want dairy", category, ok)
+	}
+	removed, err := service.RemoveByCategory(1, category)
+	if err != nil {
+		t.Fatalf("RemoveByCategory failed: %v", err)
+	}
+	if !reflect.DeepEqual(removed, []string{"cheese", "milk"}) {
+		t.Errorf("RemoveByCategory() = %v, want cheese and milk", removed)
+	}
+	if got := locations(t, service, 1); len(got) != 2 || got["carrots"] == "" || got["chicken"] == "" {
+		t.Errorf("the fridge has %v left, want carrots and chicken", got)
+	}
+
+	// Nothing left to remove
+	if removed, err := service.RemoveByCategory(1, CategoryDairy); err != nil || len(removed) != 0 {
+		t.Errorf("second RemoveByCategory() = %v, %v, want nothing removed", removed, err)
+	}
+}
+
+func TestRemoveIngredientIgnoresCase(t *testing.T) {
+	service := New(test.NewStore(t))
+	for _, name := range []string{"milk", "eggs"} {
+		if err := service.AddIngredient(1, name, "1"); err != nil {
+			t.Fatalf("AddIngredient failed: %v", err)
+		}
+	}
+
+	removed, err := service.RemoveIngredient(1, " Milk ")
+	if err != nil {
+		t.Fatalf("RemoveIngredient failed: %v", err)
+	}
+	if removed != "milk" {
+		t.Errorf("RemoveIngredient() = %q, want the stored name milk", removed)
+	}
+	if got := locations(t, service, 1); len(got) != 1 || got["eggs"] == "" {
+		t.Errorf("fridge has %v after removing Milk, want only eggs", got)
+	}
+
+	if _, err := service.RemoveIngredient(1, "milk"); !errors.Is(err, ErrIngredientNotFound) {
+		t.Errorf("removing milk twice returned %v, want ErrIngredientNotFound", err)
+	}
+}
@@ -0,0 +1,82 @@
+package fridge
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/korjavin/whatsfordinner/pkg/models"
+)
+
+const (
+	// HistoryRetention is how many daily snapshots are kept per channel
+	HistoryRetention = 90
+
+	// TrendDays is how many days /fridge_trend shows
+	TrendDays = 7
+
+	// snapshotDateLayout formats snapshot dates so their keys sort chronologically
+	snapshotDateLayout = "2006-01-02"
+)
+
+// historyPrefix returns the key prefix of a channel's fridge snapshots
+func historyPrefix(channelID int64) string {
+	return fmt.Sprintf("fridge_history:%d:", channelID)
+}
+
+// Snapshot stores today's ingredient count of a channel, replacing an earlier snapshot
+// of the same day, and drops snapshots beyond HistoryRetention
+func (s *Service) Snapshot(channelID int64, now time.Time) (*models.FridgeSnapshot, error) {
+	ingredients, err := s.ListIngredients(channelID)
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot := models.FridgeSnapshot{
+		ChannelID: channelID,
+		Date:      now.Format(snapshotDateLayout),
+		Count:     len(ingredients),
+		TakenAt:   now,
+	}
+	err = s.store.Set(historyPrefix(channelID)+snapshot.Date, snapshot)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save fridge snapshot: %w", err)
+	}
+
+	keys, err := s.store.List(historyPrefix(channelID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list fridge snapshots: %w", err)
+	}
+	for len(keys) > HistoryRetention {
+		if err := s.store.Delete(keys[0]); err != nil {
+			s.logger.Error("Failed to delete fridge snapshot %s: %v", keys[0], err)
+		}
+		keys = keys[1:]
+	}
+
+	return &snapshot, nil
+}
+
+// Trend returns the snapshots of the last days days up to now, oldest first.
+// Days without a snapshot are left out.
+func (s *Service) Trend(channelID int64, days int, now time.Time) ([]models.FridgeSnapshot, error) {
+	keys, err := s.store.List(historyPrefix(channelID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list fridge snapshots: %w", err)
+	}
+
+	since := now.AddDate(0, 0, -(days - 1)).Format(snapshotDateLayout)
+	snapshots := make([]models.FridgeSnapshot, 0, days)
+	for _, key := range keys {
+		var snapshot models.FridgeSnapshot
+		if err := s.store.Get(key, &snapshot); err != nil {
+			s.logger.Error("Failed to get fridge snapshot %s: %v", key, err)
+			continue
+		}
+		if snapshot.Date < since {
+			continue
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+
+	return snapshots, nil
+}
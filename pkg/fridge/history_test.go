@@ -0,0 +1,71 @@
+package fridge
+
+import (
+	"testing"
+	"time"
+
+	"github.com/korjavin/whatsfordinner/internal/test"
+	"github.com/korjavin/whatsfordinner/pkg/models"
+)
+
+func TestSnapshotWritesOnePerDay(t *testing.T) {
+	store := test.NewStore(t)
+	service := New(store)
+	for _, name := range []string{"milk", "eggs"} {
+		if err := service.AddIngredient(1, name, "1"); err != nil {
+			t.Fatalf("AddIngredient failed: %v", err)
+		}
+	}
+
+	morning := time.Date(2024, 6, 10, 8, 0, 0, 0, time.UTC)
+	if _, err := service.Snapshot(1, morning); err != nil {
+		t.Fatalf("Snapshot failed: %v", err)
+	}
+	if err := service.AddIngredient(1, "butter", "1"); err != nil {
+		t.Fatalf("AddIngredient failed: %v", err)
+	}
+	if _, err := service.Snapshot(1, morning.Add(12*time.Hour)); err != nil {
+		t.Fatalf("Snapshot failed: %v", err)
+	}
+
+	var snapshot models.FridgeSnapshot
+	if err := store.Get("fridge_history:1:2024-06-10", &snapshot); err != nil {
+		t.Fatalf("failed to get snapshot: %v", err)
+	}
+	if snapshot.Count != 3 {
+		t.Errorf("snapshot count = %d, want the later snapshot's 3", snapshot.Count)
+	}
+	if keys, _ := store.List("fridge_history:1:"); len(keys) != 1 {
+		t.Errorf("stored %d snapshots for one day, want 1", len(keys))
+	}
+}
+
+func TestSnapshotRetentionAndTrend(t *testing.T) {
+	store := test.NewStore(t)
+	service := New(store)
+
+	start := time.Date(2024, 1, 1, 20, 0, 0, 0, time.UTC)
+	days := HistoryRetention + 5
+	for day := 0; day < days; day++ {
+		if _, err := service.Snapshot(1, start.AddDate(0, 0, day)); err != nil {
+			t.Fatalf("Snapshot failed: %v", err)
+		}
+	}
+
+	keys, err := store.List("fridge_history:1:")
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(keys) != HistoryRetention || keys[0] != "fridge_history:1:2024-01-06" {
+		t.Errorf("kept %d snapshots from %s, want the last %d", len(keys), keys[0], HistoryRetention)
+	}
+
+	now := start.AddDate(0, 0, days-1)
+	trend, err := service.Trend(1, TrendDays, now)
+	if err != nil {
+		t.Fatalf("Trend failed: %v", err)
+	}
+	if len(trend) != TrendDays || trend[0].Date != now.AddDate(0, 0, -(TrendDays-1)).Format("2006-01-02") || trend[len(trend)-1].Date != now.Format("2006-01-02") {
+		t.Errorf("trend = %+v, want the last %d days oldest first", trend, TrendDays)
+	}
+}
@@ -0,0 +1,69 @@
+package fridge
+
+import (
+	"strings"
+	"time"
+
+	"github.com/korjavin/whatsfordinner/pkg/models"
+)
+
+// SplitList splits a pasted list of ingredients on commas, semicolons and new lines,
+// trimming every item and dropping empty ones, e.g. "eggs, milk,\n\nbread" gives eggs, milk and bread
+func SplitList(text string) []string {
+	fields := strings.FieldsFunc(text, func(r rune) bool {
+		return r == ',' || r == ';' || r == '\n' || r == '\r'
+	})
+
+	var items []string
+	for _, field := range fields {
+		// Drop list markers like "- eggs" or "• milk"
+		item := strings.TrimSpace(strings.TrimLeft(strings.TrimSpace(field), "-*•"))
+		if item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// AddList adds the items of a list to the fridge, skipping ones that are already in the fridge
+// or pantry or appear twice in the list, ignoring case. Amounts at the start of an item,
+// like "500g flour", become its quantity. It returns the names that were added and the ones skipped.
+func (s *Service) AddList(channelID int64, items []string) (added, skipped []string, err error) {
+	fridge, err := s.GetFridge(channelID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, item := range items {
+		name, quantity := item, ""
+		if q, rest, ok := ParseQuantity(item); ok && rest != "" {
+			name, quantity = rest, q.String()
+		}
+
+		if _, exists := findIngredient(fridge, name); exists {
+			skipped = append(skipped, name)
+			continue
+		}
+
+		fridge.Ingredients[name] = models.Ingredient{
+			Name:     name,
+			Quantity: quantity,
+			AddedAt:  time.Now(),
+			Location: models.LocationFridge,
+			Category: Categorize(name),
+		}
+		added = append(added, name)
+	}
+
+	if len(added) == 0 {
+		return added, skipped, nil
+	}
+
+	fridge.LastUpdated = time.Now()
+	if err := s.store.Set(fridge.ID, fridge); err != nil {
+		return nil, nil, err
+	}
+
+	s.logger.Info("Added %d ingredients to fridge %d from a list, skipped %d", len(added), channelID, len(skipped))
+	return added, skipped, nil
+}
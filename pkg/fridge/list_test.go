@@ -0,0 +1,50 @@
+package fridge
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/korjavin/whatsfordinner/internal/test"
+)
+
+func TestSplitList(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []string
+	}{
+		{"commas", "eggs, milk,bread", []string{"eggs", "milk", "bread"}},
+		{"new lines", "eggs\nmilk\r\nbread\n", []string{"eggs", "milk", "bread"}},
+		{"empty entries", " , eggs,, ;\n\n milk , ", []string{"eggs", "milk"}},
+		{"list markers", "- eggs\n• milk\n* 500g flour", []string{"eggs", "milk", "500g flour"}},
+		{"nothing", " ,\n", nil},
+	}
+	for _, tt := range tests {
+		if got := SplitList(tt.text); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: SplitList(%q) = %q, want %q", tt.name, tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestAddListSkipsDuplicates(t *testing.T) {
+	service := New(test.NewStore(t))
+	if err := service.AddIngredient(1, "Milk", "1l"); err != nil {
+		t.Fatalf("AddIngredient failed: %v", err)
+	}
+
+	added, skipped, err := service.AddList(1, SplitList("milk, eggs, 500g flour, EGGS"))
+	if err != nil {
+		t.Fatalf("AddList failed: %v", err)
+	}
+	if !reflect.DeepEqual(added, []string{"eggs", "flour"}) || !reflect.DeepEqual(skipped, []string{"milk", "EGGS"}) {
+		t.Errorf("AddList() added %q and skipped %q, want eggs and flour added", added, skipped)
+	}
+
+	fridge, err := service.GetFridge(1)
+	if err != nil {
+		t.Fatalf("GetFridge failed: %v", err)
+	}
+	if got := fridge.Ingredients["flour"].Quantity; got != "500g" {
+		t.Errorf("flour quantity = %q, want the amount from the list", got)
+	}
+}
@@ -0,0 +1,44 @@
+package fridge
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/korjavin/whatsfordinner/pkg/models"
+)
+
+// MarkLow flags an ingredient as running low, or clears the flag.
+// The name is matched ignoring case. It returns the ingredient's name as it is in the fridge.
+func (s *Service) MarkLow(channelID int64, name string, low bool) (string, error) {
+	fridge, err := s.GetFridge(channelID)
+	if err != nil {
+		return "", err
+	}
+
+	key, ok := findIngredient(fridge, name)
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrIngredientNotFound, name)
+	}
+
+	ingredient := fridge.Ingredients[key]
+	ingredient.LowStock = low
+	fridge.Ingredients[key] = ingredient
+	fridge.LastUpdated = time.Now()
+
+	if err := s.store.Set(fridge.ID, fridge); err != nil {
+		return "", fmt.Errorf("failed to save fridge: %w", err)
+	}
+
+	s.logger.Info("Marked ingredient %s in fridge %d as low: %v", key, channelID, low)
+	return key, nil
+}
+
+// stillLow reports whether an ingredient marked as running low stays marked with its new quantity.
+// The flag is cleared once the quantity goes up, e.g. from "100ml" to "1l".
+func stillLow(existing models.Ingredient, quantity string) bool {
+	if !existing.LowStock {
+		return false
+	}
+	more, ok := Shortfall(existing.Quantity, quantity)
+	return !ok || more == ""
+}
@@ -0,0 +1,74 @@
+package fridge
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/korjavin/whatsfordinner/pkg/models"
+)
+
+// ErrIngredientNotFound is returned when an ingredient isn't in the fridge or pantry
+var ErrIngredientNotFound = errors.New("ingredient not found")
+
+// findIngredient returns the key of an ingredient in the fridge, ignoring case
+func findIngredient(fridge *models.Fridge, name string) (string, bool) {
+	name = strings.TrimSpace(name)
+	if _, ok := fridge.Ingredients[name]; ok {
+		return name, true
+	}
+	for key := range fridge.Ingredients {
+		if strings.EqualFold(key, name) {
+			return key, true
+		}
+	}
+	return "", false
+}
+
+// MergeIngredients combines the ingredient from into the ingredient to and removes from.
+// Quantities are added up where possible. If to isn't in the fridge yet, from is renamed to it.
+// It returns the merged ingredient.
+func (s *Service) MergeIngredients(channelID int64, from, to string) (models.Ingredient, error) {
+	fridge, err := s.GetFridge(channelID)
+	if err != nil {
+		return models.Ingredient{}, err
+	}
+
+	fromKey, ok := findIngredient(fridge, from)
+	if !ok {
+		return models.Ingredient{}, fmt.Errorf("%w: %s", ErrIngredientNotFound, from)
+	}
+	source := fridge.Ingredients[fromKey]
+
+	toKey, ok := findIngredient(fridge, to)
+	if !ok {
+		toKey = strings.TrimSpace(to)
+	}
+	if toKey == fromKey {
+		return source, nil
+	}
+
+	merged := models.Ingredient{
+		Name:     toKey,
+		Quantity: source.Quantity,
+		AddedAt:  time.Now(),
+		Location: source.Location,
+		Category: Categorize(toKey),
+	}
+	if target, ok := fridge.Ingredients[toKey]; ok {
+		merged.Quantity = SumQuantities(target.Quantity, source.Quantity)
+		merged.Location = target.Location
+	}
+
+	delete(fridge.Ingredients, fromKey)
+	fridge.Ingredients[toKey] = merged
+	fridge.LastUpdated = time.Now()
+
+	if err := s.store.Set(fridge.ID, fridge); err != nil {
+		return models.Ingredient{}, fmt.Errorf("failed to save fridge: %w", err)
+	}
+
+	s.logger.Info("Merged ingredient %s into %s in fridge %d", fromKey, toKey, channelID)
+	return merged, nil
+}
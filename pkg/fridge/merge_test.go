@@ -0,0 +1,70 @@
+package fridge
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/korjavin/whatsfordinner/internal/test"
+)
+
+func TestMergeIngredientsSumsQuantities(t *testing.T) {
+	service := New(test.NewStore(t))
+	for name, quantity := range map[string]string{"red pepper": "200g", "bell pepper": "0.5kg", "rice": "1kg"} {
+		if err := service.AddIngredient(1, name, quantity); err != nil {
+			t.Fatalf("AddIngredient failed: %v", err)
+		}
+	}
+
+	merged, err := service.MergeIngredients(1, "Red Pepper", "bell pepper")
+	if err != nil {
+		t.Fatalf("MergeIngredients failed: %v", err)
+	}
+	if merged.Name != "bell pepper" || merged.Quantity != "700g" {
+		t.Errorf("merged into %s (%s), want bell pepper (700g)", merged.Name, merged.Quantity)
+	}
+
+	fridge, err := service.GetFridge(1)
+	if err != nil {
+		t.Fatalf("GetFridge failed: %v", err)
+	}
+	if _, ok := fridge.Ingredients["red pepper"]; ok {
+		t.Error("red pepper is still in the fridge after merging it")
+	}
+	if got := fridge.Ingredients["bell pepper"].Quantity; got != "700g" {
+		t.Errorf("bell pepper quantity = %q, want 700g", got)
+	}
+	if len(fridge.Ingredients) != 2 {
+		t.Errorf("fridge has %d ingredients, want 2", len(fridge.Ingredients))
+	}
+}
+
+func TestMergeIngredientsKeepsUnparseableQuantities(t *testing.T) {
+	service := New(test.NewStore(t))
+	if err := service.AddIngredient(1, "parsley", "a bunch"); err != nil {
+		t.Fatalf("AddIngredient failed: %v", err)
+	}
+	if err := service.AddIngredient(1, "herbs", "20g"); err != nil {
+		t.Fatalf("AddIngredient failed: %v", err)
+	}
+
+	merged, err := service.MergeIngredients(1, "parsley", "herbs")
+	if err != nil {
+		t.Fatalf("MergeIngredients failed: %v", err)
+	}
+	if merged.Quantity != "20g + a bunch" {
+		t.Errorf("merged quantity = %q, want both quantities kept", merged.Quantity)
+	}
+
+	// A missing target is created by renaming the source
+	merged, err = service.MergeIngredients(1, "herbs", "fresh herbs")
+	if err != nil {
+		t.Fatalf("MergeIngredients failed: %v", err)
+	}
+	if merged.Name != "fresh herbs" || merged.Quantity != "20g + a bunch" {
+		t.Errorf("renamed to %s (%s), want fresh herbs with the same quantity", merged.Name, merged.Quantity)
+	}
+
+	if _, err := service.MergeIngredients(1, "basil", "herbs"); !errors.Is(err, ErrIngredientNotFound) {
+		t.Errorf("merging a missing ingredient returned %v, want ErrIngredientNotFound", err)
+	}
+}
@@ -0,0 +1,255 @@
+package fridge
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Quantity is a parsed ingredient amount like "200g" or "1 1/2 cups"
+type Quantity struct {
+	Amount float64
+	Unit   string
+}
+
+// quantityPattern matches a leading amount: a mixed number ("1 1/2"), a fraction ("1/2")
+// or a decimal number ("2", "0.5", "0,5")
+var quantityPattern = regexp.MustCompile(`^(\d+\s+\d+/\d+|\d+/\d+|\d+(?:[.,]\d+)?)\s*`)
+
+// attachedUnits are written right after the amount without a space, e.g. "200g"
+var attachedUnits = map[string]bool{
+	"g": true, "kg": true, "mg": true,
+	"ml": true, "l": true, "cl": true, "dl": true,
+	"oz": true, "lb": true, "lbs": true,
+}
+
+// spacedUnits are written as a separate word, e.g. "2 cups"
+var spacedUnits = map[string]bool{
+	"cup": true, "cups": true,
+	"tbsp": true, "tsp": true,
+	"tablespoon": true, "tablespoons": true,
+	"teaspoon": true, "teaspoons": true,
+	"pinch": true, "pinches": true,
+	"clove": true, "cloves": true,
+	"can": true, "cans": true,
+	"slice": true, "slices": true,
+}
+
+// ParseQuantity parses the amount at the start of text, e.g. "200g flour" or "2 cups milk".
+// It returns the quantity, the rest of the text and whether an amount was found.
+// Words that aren't known units, like "eggs" in "2 eggs", are left in the rest of the text.
+func ParseQuantity(text string) (Quantity, string, bool) {
+	text = strings.TrimSpace(text)
+
+	match := quantityPattern.FindStringSubmatch(text)
+	if match == nil {
+		return Quantity{}, text, false
+	}
+
+	amount, ok := parseAmount(match[1])
+	if !ok {
+		return Quantity{}, text, false
+	}
+
+	quantity := Quantity{Amount: amount}
+	rest := text[len(match[0]):]
+
+	// Check whether the next word is a unit
+	word := rest
+	if i := strings.IndexAny(rest, " \t"); i >= 0 {
+		word = rest[:i]
+	}
+	unit := strings.ToLower(strings.TrimSuffix(word, "."))
+	if attachedUnits[unit] || spacedUnits[unit] {
+		quantity.Unit = unit
+		rest = rest[len(word):]
+	}
+
+	return quantity, strings.TrimSpace(rest), true
+}
+
+// parseAmount parses a mixed number, a fraction or a decimal number
+func parseAmount(s string) (float64, bool) {
+	s = strings.ReplaceAll(s, ",", ".")
+
+	fields := strings.Fields(s)
+	if len(fields) == 2 {
+		// Mixed number like "1 1/2"
+		whole, ok := parseAmount(fields[0])
+		if !ok {
+			return 0, false
+		}
+		fraction, ok := parseAmount(fields[1])
+		if !ok {
+			return 0, false
+		}
+		return whole + fraction, true
+	}
+
+	if numerator, denominator, found := strings.Cut(s, "/"); found {
+		n, err := strconv.ParseFloat(numerator, 64)
+		if err != nil {
+			return 0, false
+		}
+		d, err := strconv.ParseFloat(denominator, 64)
+		if err != nil || d == 0 {
+			return 0, false
+		}
+		return n / d, true
+	}
+
+	amount, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, false
+	}
+	return amount, true
+}
+
+// String formats the quantity, rounding the amount to at most two decimals
+func (q Quantity) String() string {
+	amount := strconv.FormatFloat(q.Amount, 'f', 2, 64)
+	amount = strings.TrimRight(strings.TrimRight(amount, "0"), ".")
+
+	switch {
+	case q.Unit == "":
+		return amount
+	case attachedUnits[q.Unit]:
+		return amount + q.Unit
+	default:
+		return amount + " " + q.Unit
+	}
+}
+
+// metricUnits converts units to grams or milliliters
+var metricUnits = map[string]struct {
+	unit   string
+	factor float64
+}{
+	"mg":   {"g", 0.001},
+	"g":    {"g", 1},
+	"kg":   {"g", 1000},
+	"oz":   {"g", 28.35},
+	"lb":   {"g", 453.6},
+	"lbs":  {"g", 453.6},
+	"ml":   {"ml", 1},
+	"cl":   {"ml", 10},
+	"dl":   {"ml", 100},
+	"l":    {"ml", 1000},
+	"cup":  {"ml", 240},
+	"cups": {"ml", 240},
+}
+
+// Normalize converts weights to grams and volumes to milliliters,
+// switching to kilograms and liters from 1000 upwards.
+// Units without a sensible metric equivalent, like cloves or pinches, are kept as they are.
+func (q Quantity) Normalize() Quantity {
+	metric, ok := metricUnits[q.Unit]
+	if !ok {
+		return q
+	}
+
+	normalized := Quantity{Amount: q.Amount * metric.factor, Unit: metric.unit}
+	if normalized.Amount >= 1000 {
+		normalized.Amount /= 1000
+		if normalized.Unit == "g" {
+			normalized.Unit = "kg"
+		} else {
+			normalized.Unit = "l"
+		}
+	}
+
+	return normalized
+}
+
+// NormalizeQuantity normalizes a quantity written as text, e.g. "2 cups" becomes "480ml".
+// Text that isn't a plain amount with an optional unit is returned unchanged.
+func NormalizeQuantity(text string) string {
+	quantity, rest, ok := ParseQuantity(text)
+	if !ok || rest != "" {
+		return text
+	}
+	return quantity.Normalize().String()
+}
+
+// SumQuantities adds up two quantities written as text, e.g. "200g" and "1kg" make "1.2kg".
+// Amounts in different but convertible units are added in metric units.
+// If either quantity can't be parsed or the units don't match, both are kept, joined with " + ".
+func SumQuantities(a, b string) string {
+	a, b = strings.TrimSpace(a), strings.TrimSpace(b)
+	if a == "" {
+		return b
+	}
+	if b == "" {
+		return a
+	}
+
+	qa, restA, okA := ParseQuantity(a)
+	qb, restB, okB := ParseQuantity(b)
+	if !okA || !okB || restA != "" || restB != "" {
+		return a + " + " + b
+	}
+
+	if qa.Unit == qb.Unit {
+		return Quantity{Amount: qa.Amount + qb.Amount, Unit: qa.Unit}.String()
+	}
+
+	ma, okA := metricUnits[qa.Unit]
+	mb, okB := metricUnits[qb.Unit]
+	if !okA || !okB || ma.unit != mb.unit {
+		return a + " + " + b
+	}
+
+	sum := Quantity{Amount: qa.Amount*ma.factor + qb.Amount*mb.factor, Unit: ma.unit}
+	return sum.Normalize().String()
+}
+
+// Shortfall compares the amount in the fridge with the amount a recipe needs, e.g. "100g" and "0.5kg".
+// It returns how much is missing, empty if there is enough, and whether the amounts could be compared.
+// Amounts in different but convertible units are compared in metric units.
+func Shortfall(have, need string) (string, bool) {
+	qh, restH, okH := ParseQuantity(have)
+	qn, restN, okN := ParseQuantity(need)
+	if !okH || !okN || restH != "" || restN != "" {
+		return "", false
+	}
+
+	if qh.Unit != qn.Unit {
+		mh, okH := metricUnits[qh.Unit]
+		mn, okN := metricUnits[qn.Unit]
+		if !okH || !okN || mh.unit != mn.unit {
+			return "", false
+		}
+		qh = Quantity{Amount: qh.Amount * mh.factor, Unit: mh.unit}
+		qn = Quantity{Amount: qn.Amount * mn.factor, Unit: mn.unit}
+	}
+
+	if qh.Amount >= qn.Amount {
+		return "", true
+	}
+	return Quantity{Amount: qn.Amount - qh.Amount, Unit: qn.Unit}.Normalize().String(), true
+}
+
+// lowStock is the amount at or below which an ingredient is running low, by metric unit.
+// An empty unit is a count, like "1" egg.
+var lowStock = map[string]float64{
+	"":   1,
+	"g":  100,
+	"ml": 100,
+}
+
+// IsLow reports whether a quantity like "50g" or "1" is running low.
+// Quantities that can't be parsed or are in units like cloves never count as low.
+func IsLow(quantity string) bool {
+	q, rest, ok := ParseQuantity(quantity)
+	if !ok || rest != "" {
+		return false
+	}
+
+	amount, unit := q.Amount, q.Unit
+	if metric, ok := metricUnits[unit]; ok {
+		amount, unit = amount*metric.factor, metric.unit
+	}
+
+	limit, ok := lowStock[unit]
+	return ok && amount <= limit
+}
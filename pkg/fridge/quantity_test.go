@@ -0,0 +1,50 @@
+package fridge
+
+import "testing"
+
+func TestNormalizeQuantity(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"2 cups", "480ml"},
+		{"200g", "200g"},
+		{"1500g", "1.5kg"},
+		{"5 cups", "1.2l"},
+		{"8oz", "226.8g"},
+		{"1 1/2 lb", "680.4g"},
+		{"0,5 l", "500ml"},
+		{"3 cloves", "3 cloves"},
+		{"2", "2"},
+		// Unparseable quantities are kept as they are
+		{"a handful", "a handful"},
+		{"2 cups chopped", "2 cups chopped"},
+	}
+	for _, tt := range tests {
+		if got := NormalizeQuantity(tt.text); got != tt.want {
+			t.Errorf("NormalizeQuantity(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestSumQuantities(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want string
+	}{
+		{"200g", "300g", "500g"},
+		{"200g", "1kg", "1.2kg"},
+		{"1 cup", "240ml", "480ml"},
+		{"2", "3", "5"},
+		{"", "2 pcs", "2 pcs"},
+		{"1kg", "", "1kg"},
+		// Amounts that can't be added up are both kept
+		{"200g", "1l", "200g + 1l"},
+		{"a handful", "100g", "a handful + 100g"},
+	}
+	for _, tt := range tests {
+		if got := SumQuantities(tt.a, tt.b); got != tt.want {
+			t.Errorf("SumQuantities(%q, %q) = %q, want %q", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
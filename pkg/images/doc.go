@@ -0,0 +1,3 @@
+// Package images provides helpers for preparing photos before they are sent to the AI.
+// Large fridge photos are downscaled and re-encoded as JPEG to save tokens and time.
+package images
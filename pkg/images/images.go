@@ -0,0 +1,145 @@
+package images
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/gif" // Register the GIF decoder
+	"image/jpeg"
+	_ "image/png" // Register the PNG decoder
+	"io"
+	"net/http"
+	"time"
+)
+
+// ErrUnsupportedFormat is returned for images that can't be decoded
+var ErrUnsupportedFormat = errors.New("unsupported image format")
+
+// DefaultMaxDimension is the default size of the longest side of a compressed image
+const DefaultMaxDimension = 1024
+
+// jpegQuality is the quality compressed images are encoded with
+const jpegQuality = 85
+
+// maxDownloadSize caps how much of an image is downloaded
+const maxDownloadSize = 20 << 20
+
+// httpClient downloads the images to compress
+var httpClient = &http.Client{Timeout: 30 * time.Second}
+
+// ScaledSize returns the size of an image scaled down so that its longest side is at most
+// maxDimension, preserving the aspect ratio. Images that already fit are not scaled.
+func ScaledSize(width, height, maxDimension int) (int, int) {
+	if width <= maxDimension && height <= maxDimension {
+		return width, height
+	}
+
+	if width >= height {
+		scaledHeight := height * maxDimension / width
+		if scaledHeight < 1 {
+			scaledHeight = 1
+		}
+		return maxDimension, scaledHeight
+	}
+
+	scaledWidth := width * maxDimension / height
+	if scaledWidth < 1 {
+		scaledWidth = 1
+	}
+	return scaledWidth, maxDimension
+}
+
+// Compress downscales an image so that its longest side is at most maxDimension
+// and re-encodes it as JPEG. It returns ErrUnsupportedFormat if the image can't be decoded.
+func Compress(data []byte, maxDimension int) ([]byte, error) {
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnsupportedFormat, err)
+	}
+
+	bounds := img.Bounds()
+	width, height := ScaledSize(bounds.Dx(), bounds.Dy(), maxDimension)
+	if width != bounds.Dx() || height != bounds.Dy() {
+		img = resize(img, width, height)
+	}
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: jpegQuality}); err != nil {
+		return nil, fmt.Errorf("failed to encode image: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// CompressURL downloads an image and returns it compressed as a JPEG data URL,
+// which can be passed to the AI instead of the original URL
+func CompressURL(url string, maxDimension int) (string, error) {
+	resp, err := httpClient.Get(url)
+	if err != nil {
+		return "", fmt.Errorf("failed to download image: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to download image: %s", resp.Status)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxDownloadSize))
+	if err != nil {
+		return "", fmt.Errorf("failed to download image: %w", err)
+	}
+
+	compressed, err := Compress(data, maxDimension)
+	if err != nil {
+		return "", err
+	}
+
+	return "data:image/jpeg;base64," + base64.StdEncoding.EncodeToString(compressed), nil
+}
+
+// resize scales an image to the given size, averaging the source pixels
+// that fall into each destination pixel
+func resize(src image.Image, width, height int) image.Image {
+	bounds := src.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+
+	for y := 0; y < height; y++ {
+		y0 := bounds.Min.Y + y*bounds.Dy()/height
+		y1 := bounds.Min.Y + (y+1)*bounds.Dy()/height
+		if y1 <= y0 {
+			y1 = y0 + 1
+		}
+
+		for x := 0; x < width; x++ {
+			x0 := bounds.Min.X + x*bounds.Dx()/width
+			x1 := bounds.Min.X + (x+1)*bounds.Dx()/width
+			if x1 <= x0 {
+				x1 = x0 + 1
+			}
+
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					cr, cg, cb, ca := src.At(sx, sy).RGBA()
+					r += uint64(cr)
+					g += uint64(cg)
+					b += uint64(cb)
+					a += uint64(ca)
+					n++
+				}
+			}
+
+			dst.Set(x, y, color.RGBA64{
+				R: uint16(r / n),
+				G: uint16(g / n),
+				B: uint16(b / n),
+				A: uint16(a / n),
+			})
+		}
+	}
+
+	return dst
+}
@@ -0,0 +1,54 @@
+package images
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+func TestScaledSizeKeepsAspectRatio(t *testing.T) {
+	tests := []struct {
+		width, height int
+		wantW, wantH  int
+	}{
+		{4000, 3000, 1024, 768},
+		{3000, 4000, 768, 1024},
+		{2048, 2048, 1024, 1024},
+		{800, 600, 800, 600},
+		{5000, 2, 1024, 1},
+	}
+	for _, tt := range tests {
+		w, h := ScaledSize(tt.width, tt.height, 1024)
+		if w != tt.wantW || h != tt.wantH {
+			t.Errorf("ScaledSize(%d, %d) = %dx%d, want %dx%d", tt.width, tt.height, w, h, tt.wantW, tt.wantH)
+		}
+	}
+}
+
+func TestCompressDownscalesToJPEG(t *testing.T) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 400, 300))); err != nil {
+		t.Fatalf("failed to encode PNG: %v", err)
+	}
+
+	compressed, err := Compress(buf.Bytes(), 100)
+	if err != nil {
+		t.Fatalf("Compress failed: %v", err)
+	}
+	config, err := jpeg.DecodeConfig(bytes.NewReader(compressed))
+	if err != nil {
+		t.Fatalf("compressed image isn't a JPEG: %v", err)
+	}
+	if config.Width != 100 || config.Height != 75 {
+		t.Errorf("compressed image is %dx%d, want 100x75", config.Width, config.Height)
+	}
+}
+
+func TestCompressRejectsUnsupportedFormats(t *testing.T) {
+	if _, err := Compress([]byte("not an image"), 100); !errors.Is(err, ErrUnsupportedFormat) {
+		t.Errorf("Compress of garbage returned %v, want ErrUnsupportedFormat", err)
+	}
+}
@@ -1,3 +1,6 @@
 // Package messages provides functionality for generating chat messages.
-// It uses OpenAI to generate contextually appropriate messages for different intents.
+// It uses OpenAI to generate contextually appropriate messages for different intents,
+// or static messages when AI messages are turned off.
+// It also formats the dates and times shown in messages in a chat's time zone
+// and escapes user text for Telegram's Markdown.
 package messages
@@ -0,0 +1,18 @@
+package messages
+
+import "strings"
+
+// markdownEscaper escapes the characters Telegram's Markdown reads as formatting
+var markdownEscaper = strings.NewReplacer("_", `\_`, "*", `\*`, "`", "\\`", "[", `\[`)
+
+// EscapeMarkdown escapes user text like usernames and dish names, so it shows up as typed
+// instead of being read as formatting. It's for text outside of bold parts, see Bold.
+func EscapeMarkdown(text string) string {
+	return markdownEscaper.Replace(text)
+}
+
+// Bold makes text bold in Telegram's Markdown. Nothing can be escaped inside bold text,
+// so asterisks in it end the bold part, show up escaped and start it again.
+func Bold(text string) string {
+	return "*" + strings.ReplaceAll(text, "*", `*\**`) + "*"
+}
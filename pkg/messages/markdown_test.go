@@ -0,0 +1,22 @@
+package messages
+
+import "testing"
+
+func TestEscapeMarkdown(t *testing.T) {
+	if got, want := EscapeMarkdown("mac_and_cheese *extra* `hot` [spicy]"), "mac\\_and\\_cheese \\*extra\\* \\`hot\\` \\[spicy]"; got != want {
+		t.Errorf("EscapeMarkdown() = %q, want %q", got, want)
+	}
+}
+
+func TestBold(t *testing.T) {
+	tests := map[string]string{
+		"Lasagna":  "*Lasagna*",
+		"Mac_Hot":  "*Mac_Hot*", // Underscores are plain text inside bold
+		"2*2 Soup": "*2*\\**2 Soup*",
+	}
+	for text, want := range tests {
+		if got := Bold(text); got != want {
+			t.Errorf("Bold(%q) = %q, want %q", text, got, want)
+		}
+	}
+}
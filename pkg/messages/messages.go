@@ -1,105 +1,128 @@
 package messages
 
 import (
+	"time"
+
 	"github.com/korjavin/whatsfordinner/pkg/logger"
 	"github.com/korjavin/whatsfordinner/pkg/openai"
+	"github.com/korjavin/whatsfordinner/pkg/storage"
 )
 
+// cacheTTL is how long generated messages that don't depend on the situation are reused
+const cacheTTL = 24 * time.Hour
+
+// cachedMessage is a generated message stored for reuse
+type cachedMessage struct {
+	Text        string    `json:"text"`
+	GeneratedAt time.Time `json:"generated_at"`
+}
+
 // Service provides message generation functionality
 type Service struct {
+	store        *storage.Store
 	openaiClient *openai.Client
 	logger       *logger.Logger
+	useAI        bool
 }
 
 // New creates a new message service
-func New(openaiClient *openai.Client) *Service {
+// If useAI is false, the static fallback messages are used and OpenAI is never called
+func New(store *storage.Store, openaiClient *openai.Client, useAI bool) *Service {
 	return &Service{
+		store:        store,
 		openaiClient: openaiClient,
 		logger:       logger.New(""),
+		useAI:        useAI,
 	}
 }
 
-// GenerateWelcomeMessage generates a welcome message
-func (s *Service) GenerateWelcomeMessage() string {
-	msg, err := s.openaiClient.GenerateChatMessage("welcome", map[string]interface{}{
-		"purpose": "Help families decide what to cook for dinner",
-	})
+// generateCached works like generate, but reuses a message generated for the same cache key within cacheTTL.
+// Only use it for messages that don't depend on the situation, like the welcome message.
+func (s *Service) generateCached(cacheKey, intent string, contextData map[string]interface{}, fallback string) string {
+	if !s.useAI {
+		return fallback
+	}
+
+	key := "message_cache:" + cacheKey
+	var cached cachedMessage
+	if err := s.store.Get(key, &cached); err == nil && time.Since(cached.GeneratedAt) < cacheTTL {
+		return cached.Text
+	}
+
+	msg, err := s.openaiClient.GenerateChatMessage(intent, contextData)
 	if err != nil {
-		s.logger.Error("Failed to generate welcome message: %v", err)
-		return "👋 Welcome to WhatsForDinner bot! I'll help your family decide what to cook for dinner."
+		s.logger.Error("Failed to generate %s message: %v", intent, err)
+		return fallback
+	}
+
+	// Failed requests aren't cached, so the next call tries again
+	if err := s.store.Set(key, cachedMessage{Text: msg, GeneratedAt: time.Now()}); err != nil {
+		s.logger.Error("Failed to cache %s message: %v", intent, err)
 	}
 	return msg
 }
 
-// GenerateDinnerSuggestions generates a message with dinner suggestions
-func (s *Service) GenerateDinnerSuggestions(dishes []string) string {
-	msg, err := s.openaiClient.GenerateChatMessage("dinner_suggestions", map[string]interface{}{
-		"dishes": dishes,
-	})
+// generate asks OpenAI for a message with the given intent, returning fallback
+// if AI messages are disabled or the request fails
+func (s *Service) generate(intent string, contextData map[string]interface{}, fallback string) string {
+	if !s.useAI {
+		return fallback
+	}
+
+	msg, err := s.openaiClient.GenerateChatMessage(intent, contextData)
 	if err != nil {
-		s.logger.Error("Failed to generate dinner suggestions message: %v", err)
-		return "🍽️ Hey family! It's dinner time! Based on what we have, here are some ideas:\n" + formatDishes(dishes)
+		s.logger.Error("Failed to generate %s message: %v", intent, err)
+		return fallback
 	}
 	return msg
 }
 
+// GenerateWelcomeMessage generates a welcome message
+func (s *Service) GenerateWelcomeMessage() string {
+	return s.generateCached("welcome", "welcome", map[string]interface{}{
+		"purpose": "Help families decide what to cook for dinner",
+	}, "👋 Welcome to WhatsForDinner bot! I'll help your family decide what to cook for dinner.")
+}
+
+// GenerateDinnerSuggestions generates a message with dinner suggestions
+func (s *Service) GenerateDinnerSuggestions(dishes []string) string {
+	return s.generate("dinner_suggestions", map[string]interface{}{
+		"dishes": dishes,
+	}, "🍽️ Hey family! It's dinner time! Based on what we have, here are some ideas:\n"+formatDishes(dishes))
+}
+
 // GenerateEmptyFridgeMessage generates a message for an empty fridge
 func (s *Service) GenerateEmptyFridgeMessage() string {
-	msg, err := s.openaiClient.GenerateChatMessage("empty_fridge", map[string]interface{}{})
-	if err != nil {
-		s.logger.Error("Failed to generate empty fridge message: %v", err)
-		return "Your fridge is empty! Add ingredients with /sync_fridge or by sending a photo with /add_photo."
-	}
-	return msg
+	return s.generateCached("empty_fridge", "empty_fridge", map[string]interface{}{}, "Your fridge is empty! Add ingredients with /sync_fridge or by sending a photo with /add_photo.")
 }
 
 // GenerateFridgeContentsMessage generates a message with fridge contents
 func (s *Service) GenerateFridgeContentsMessage(ingredients []string) string {
-	msg, err := s.openaiClient.GenerateChatMessage("fridge_contents", map[string]interface{}{
+	return s.generate("fridge_contents", map[string]interface{}{
 		"ingredients": ingredients,
-	})
-	if err != nil {
-		s.logger.Error("Failed to generate fridge contents message: %v", err)
-		return "🧊 Here's what's in your fridge:\n" + formatIngredients(ingredients)
-	}
-	return msg
+	}, "🧊 Here's what's in your fridge:\n"+formatIngredients(ingredients))
 }
 
 // GenerateErrorMessage generates an error message
 func (s *Service) GenerateErrorMessage(context string) string {
-	msg, err := s.openaiClient.GenerateChatMessage("error", map[string]interface{}{
+	return s.generateCached("error:"+context, "error", map[string]interface{}{
 		"context": context,
-	})
-	if err != nil {
-		s.logger.Error("Failed to generate error message: %v", err)
-		return "😢 Sorry, something went wrong. Please try again later."
-	}
-	return msg
+	}, "😢 Sorry, something went wrong. Please try again later.")
 }
 
 // GenerateCookVolunteerRequest generates a message asking for cook volunteers
 func (s *Service) GenerateCookVolunteerRequest(dish string) string {
-	msg, err := s.openaiClient.GenerateChatMessage("cook_volunteer_request", map[string]interface{}{
+	return s.generate("cook_volunteer_request", map[string]interface{}{
 		"dish": dish,
-	})
-	if err != nil {
-		s.logger.Error("Failed to generate cook volunteer request message: %v", err)
-		return "✅ " + dish + " wins! Now, who wants to cook it?"
-	}
-	return msg
+	}, "✅ "+dish+" wins! Now, who wants to cook it?")
 }
 
 // GenerateCookConfirmation generates a message confirming the cook
 func (s *Service) GenerateCookConfirmation(cook, dish string) string {
-	msg, err := s.openaiClient.GenerateChatMessage("cook_confirmation", map[string]interface{}{
+	return s.generate("cook_confirmation", map[string]interface{}{
 		"cook": cook,
 		"dish": dish,
-	})
-	if err != nil {
-		s.logger.Error("Failed to generate cook confirmation message: %v", err)
-		return "👨‍🍳 Great! @" + cook + " is the chef tonight."
-	}
-	return msg
+	}, "👨‍🍳 Great! @"+cook+" is the chef tonight.")
 }
 
 // Helper functions for fallback formatting
@@ -0,0 +1,107 @@
+package messages
+
+import (
+	"testing"
+	"time"
+
+	"github.com/korjavin/whatsfordinner/internal/test"
+	"github.com/korjavin/whatsfordinner/pkg/openai"
+	"github.com/korjavin/whatsfordinner/pkg/storage"
+)
+
+// newTestService creates a message service on a temporary store, talking to a fake OpenAI API
+func newTestService(t *testing.T, useAI bool) (*Service, *storage.Store, *test.OpenAI) {
+	t.Helper()
+
+	store := test.NewStore(t)
+	fake := test.NewOpenAI(t, "Hello from the AI!")
+	return New(store, openai.New("test-key", fake.BaseURL(), "test-model"), useAI), store, fake
+}
+
+func TestDisabledAIMessagesSkipOpenAI(t *testing.T) {
+	service, _, fake := newTestService(t, false)
+
+	if got := service.GenerateWelcomeMessage(); got != "👋 Welcome to WhatsForDinner bot! I'll help your family decide what to cook for dinner." {
+		t.Errorf("GenerateWelcomeMessage() = %q, want the static welcome", got)
+	}
+	if got := service.GenerateCookVolunteerRequest("Soup"); got != "✅ Soup wins! Now, who wants to cook it?" {
+		t.Errorf("GenerateCookVolunteerRequest() = %q, want the static request", got)
+	}
+	service.GenerateErrorMessage("fridge")
+
+	if prompts := fake.Prompts(); len(prompts) != 0 {
+		t.Errorf("OpenAI got %d prompts with AI messages disabled, want none", len(prompts))
+	}
+}
+
+func TestEnabledAIMessagesUseOpenAI(t *testing.T) {
+	service, _, fake := newTestService(t, true)
+
+	if got := service.GenerateCookVolunteerRequest("Soup"); got != "Hello from the AI!" {
+		t.Errorf("GenerateCookVolunteerRequest() = %q, want the generated message", got)
+	}
+	if len(fake.Prompts()) == 0 {
+		t.Error("OpenAI wasn't asked for the message")
+	}
+
+	// Failed requests fall back to the static message
+	fake.SetFailing(true)
+	if got := service.GenerateCookVolunteerRequest("Soup"); got != "✅ Soup wins! Now, who wants to cook it?" {
+		t.Errorf("GenerateCookVolunteerRequest() with OpenAI failing = %q, want the static request", got)
+	}
+}
+
+func TestWelcomeMessageIsCached(t *testing.T) {
+	service, store, fake := newTestService(t, true)
+
+	// The first call misses the cache and asks OpenAI
+	if got := service.GenerateWelcomeMessage(); got != "Hello from the AI!" {
+		t.Fatalf("GenerateWelcomeMessage() = %q, want the generated message", got)
+	}
+	asked := len(fake.Prompts())
+	if asked == 0 {
+		t.Fatal("OpenAI wasn't asked for the welcome message")
+	}
+
+	// The second call hits the cache
+	fake.SetReplies("A new welcome!")
+	if got := service.GenerateWelcomeMessage(); got != "Hello from the AI!" {
+		t.Errorf("GenerateWelcomeMessage() = %q, want the cached message", got)
+	}
+	if len(fake.Prompts()) != asked {
+		t.Error("OpenAI was asked again although the welcome message was cached")
+	}
+
+	// An expired message is generated again
+	expired := cachedMessage{Text: "Hello from the AI!", GeneratedAt: time.Now().Add(-cacheTTL - time.Minute)}
+	if err := store.Set("message_cache:welcome", expired); err != nil {
+		t.Fatalf("failed to expire the cached message: %v", err)
+	}
+	if got := service.GenerateWelcomeMessage(); got != "A new welcome!" {
+		t.Errorf("GenerateWelcomeMessage() after the cache expired = %q, want a new message", got)
+	}
+}
+
+func TestDishMessagesAreNotCached(t *testing.T) {
+	service, _, fake := newTestService(t, true)
+	fake.SetReplies("Who cooks the soup?", "Who cooks the curry?")
+
+	if got := service.GenerateCookVolunteerRequest("Soup"); got != "Who cooks the soup?" {
+		t.Errorf("GenerateCookVolunteerRequest(Soup) = %q", got)
+	}
+	if got := service.GenerateCookVolunteerRequest("Curry"); got != "Who cooks the curry?" {
+		t.Errorf("GenerateCookVolunteerRequest(Curry) = %q, want a message generated for curry", got)
+	}
+}
+
+func TestFailedMessagesAreNotCached(t *testing.T) {
+	service, _, fake := newTestService(t, true)
+
+	fake.SetFailing(true)
+	service.GenerateWelcomeMessage()
+
+	fake.SetFailing(false)
+	if got := service.GenerateWelcomeMessage(); got != "Hello from the AI!" {
+		t.Errorf("GenerateWelcomeMessage() after a failure = %q, want a generated message", got)
+	}
+}
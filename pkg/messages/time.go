@@ -0,0 +1,54 @@
+package messages
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Layouts of the dates and times shown to users
+const (
+	TimeLayout = "Mon 2 Jan, 15:04"
+	DateLayout = "Mon 2 Jan"
+)
+
+// FormatTime formats a time for a chat message in the chat's time zone, e.g. "Mon 3 Jun, 18:30"
+func FormatTime(t time.Time, loc *time.Location) string {
+	return t.In(loc).Format(TimeLayout)
+}
+
+// FormatDate formats the day of a time for a chat message in the chat's time zone, e.g. "Mon 3 Jun"
+func FormatDate(t time.Time, loc *time.Location) string {
+	return t.In(loc).Format(DateLayout)
+}
+
+// FormatDuration formats a duration for a chat message, e.g. "1 hour 30 minutes" or "45 seconds"
+func FormatDuration(d time.Duration) string {
+	d = d.Round(time.Second)
+	hours := int(d / time.Hour)
+	minutes := int(d % time.Hour / time.Minute)
+	seconds := int(d % time.Minute / time.Second)
+
+	var parts []string
+	if hours > 0 {
+		parts = append(parts, plural(hours, "hour"))
+	}
+	if minutes > 0 {
+		parts = append(parts, plural(minutes, "minute"))
+	}
+	if seconds > 0 && hours == 0 {
+		parts = append(parts, plural(seconds, "second"))
+	}
+	if len(parts) == 0 {
+		return "0 seconds"
+	}
+	return strings.Join(parts, " ")
+}
+
+// plural formats a count with its unit, adding an s unless the count is 1
+func plural(count int, unit string) string {
+	if count == 1 {
+		return fmt.Sprintf("1 %s", unit)
+	}
+	return fmt.Sprintf("%d %ss", count, unit)
+}
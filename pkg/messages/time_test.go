@@ -0,0 +1,65 @@
+package messages
+
+import (
+	"testing"
+	"time"
+	_ "time/tzdata" // The tests shouldn't depend on the system's time zone database
+)
+
+func TestFormatTimeInTheChatsTimeZone(t *testing.T) {
+	load := func(name string) *time.Location {
+		loc, err := time.LoadLocation(name)
+		if err != nil {
+			t.Fatalf("failed to load %s: %v", name, err)
+		}
+		return loc
+	}
+	berlin, newYork, tokyo := load("Europe/Berlin"), load("America/New_York"), load("Asia/Tokyo")
+
+	tests := []struct {
+		name string
+		t    time.Time
+		loc  *time.Location
+		want string
+	}{
+		{"UTC", time.Date(2024, 6, 3, 16, 30, 0, 0, time.UTC), time.UTC, "Mon 3 Jun, 16:30"},
+		{"summer time in Berlin", time.Date(2024, 6, 3, 16, 30, 0, 0, time.UTC), berlin, "Mon 3 Jun, 18:30"},
+		{"behind UTC across midnight", time.Date(2024, 6, 3, 2, 0, 0, 0, time.UTC), newYork, "Sun 2 Jun, 22:00"},
+		{"ahead of UTC across midnight", time.Date(2024, 6, 3, 16, 30, 0, 0, time.UTC), tokyo, "Tue 4 Jun, 01:30"},
+		// Berlin moved its clocks from 02:00 to 03:00 on 31 March 2024
+		{"just before DST starts", time.Date(2024, 3, 31, 0, 59, 0, 0, time.UTC), berlin, "Sun 31 Mar, 01:59"},
+		{"just after DST starts", time.Date(2024, 3, 31, 1, 0, 0, 0, time.UTC), berlin, "Sun 31 Mar, 03:00"},
+		// and back from 03:00 to 02:00 on 27 October 2024, so 02:30 happens twice
+		{"first 02:30 when DST ends", time.Date(2024, 10, 27, 0, 30, 0, 0, time.UTC), berlin, "Sun 27 Oct, 02:30"},
+		{"second 02:30 when DST ends", time.Date(2024, 10, 27, 1, 30, 0, 0, time.UTC), berlin, "Sun 27 Oct, 02:30"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := FormatTime(tt.t, tt.loc); got != tt.want {
+				t.Errorf("FormatTime() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if got := FormatDate(time.Date(2024, 6, 3, 23, 30, 0, 0, time.UTC), berlin); got != "Tue 4 Jun" {
+		t.Errorf("FormatDate() = %q, want the Berlin date Tue 4 Jun", got)
+	}
+}
+
+func TestFormatDuration(t *testing.T) {
+	tests := map[time.Duration]string{
+		0:                                "0 seconds",
+		45 * time.Second:                 "45 seconds",
+		time.Minute:                      "1 minute",
+		20*time.Minute + 30*time.Second:  "20 minutes 30 seconds",
+		90 * time.Minute:                 "1 hour 30 minutes",
+		2*time.Hour + 10*time.Second:     "2 hours",
+		time.Hour + 500*time.Millisecond: "1 hour",
+	}
+	for d, want := range tests {
+		if got := FormatDuration(d); got != want {
+			t.Errorf("FormatDuration(%s) = %q, want %q", d, got, want)
+		}
+	}
+}
@@ -0,0 +1,3 @@
+// Package metrics provides simple counters for the WhatsForDinner bot.
+// Counters are exposed over HTTP in the Prometheus text format.
+package metrics
@@ -0,0 +1,113 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Registry holds a set of counters
+type Registry struct {
+	mu       sync.Mutex
+	help     map[string]string
+	counters map[string]map[string]float64 // name -> labels -> value
+}
+
+// Default is the registry used by the bot
+var Default = NewRegistry()
+
+// NewRegistry creates a new empty registry
+func NewRegistry() *Registry {
+	return &Registry{
+		help:     make(map[string]string),
+		counters: make(map[string]map[string]float64),
+	}
+}
+
+// Describe sets the help text shown for a counter
+func (r *Registry) Describe(name, help string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.help[name] = help
+}
+
+// Add increases a counter by value
+// Labels are given as key/value pairs, e.g. Add("requests_total", 1, "channel", "42")
+func (r *Registry) Add(name string, value float64, labels ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	series, ok := r.counters[name]
+	if !ok {
+		series = make(map[string]float64)
+		r.counters[name] = series
+	}
+	series[formatLabels(labels)] += value
+}
+
+// WriteTo writes all counters in the Prometheus text format
+func (r *Registry) WriteTo(w io.Writer) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	names := make([]string, 0, len(r.counters))
+	for name := range r.counters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var sb strings.Builder
+	for _, name := range names {
+		if help, ok := r.help[name]; ok {
+			fmt.Fprintf(&sb, "# HELP %s %s\n", name, help)
+		}
+		fmt.Fprintf(&sb, "# TYPE %s counter\n", name)
+
+		series := r.counters[name]
+		labelSets := make([]string, 0, len(series))
+		for labels := range series {
+			labelSets = append(labelSets, labels)
+		}
+		sort.Strings(labelSets)
+
+		for _, labels := range labelSets {
+			fmt.Fprintf(&sb, "%s%s %g\n", name, labels, series[labels])
+		}
+	}
+
+	n, err := io.WriteString(w, sb.String())
+	return int64(n), err
+}
+
+// Handler returns an HTTP handler that serves the counters
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		r.WriteTo(w)
+	})
+}
+
+// Serve serves the default registry on addr at /metrics
+func Serve(addr string) error {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", Default.Handler())
+	return http.ListenAndServe(addr, mux)
+}
+
+// formatLabels formats key/value pairs as {key="value",...}
+func formatLabels(labels []string) string {
+	if len(labels) < 2 {
+		return ""
+	}
+
+	pairs := make([]string, 0, len(labels)/2)
+	for i := 0; i+1 < len(labels); i += 2 {
+		value := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(labels[i+1])
+		pairs = append(pairs, fmt.Sprintf(`%s="%s"`, labels[i], value))
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
@@ -0,0 +1,33 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRegistryWriteTo(t *testing.T) {
+	r := NewRegistry()
+	r.Describe("requests_total", "Requests sent")
+	r.Add("requests_total", 1, "channel", "2")
+	r.Add("requests_total", 2, "channel", "1")
+	r.Add("requests_total", 3, "channel", "1")
+	r.Add("errors_total", 1)
+	r.Add("quoted_total", 1, "name", `say "hi"`)
+
+	var sb strings.Builder
+	if _, err := r.WriteTo(&sb); err != nil {
+		t.Fatalf("WriteTo failed: %v", err)
+	}
+
+	want := "# TYPE errors_total counter\n" +
+		"errors_total 1\n" +
+		"# TYPE quoted_total counter\n" +
+		"quoted_total{name=\"say \\\"hi\\\"\"} 1\n" +
+		"# HELP requests_total Requests sent\n" +
+		"# TYPE requests_total counter\n" +
+		"requests_total{channel=\"1\"} 5\n" +
+		"requests_total{channel=\"2\"} 1\n"
+	if sb.String() != want {
+		t.Errorf("WriteTo() wrote\n%s\nwant\n%s", sb.String(), want)
+	}
+}
@@ -0,0 +1,3 @@
+// Package migrate copies a family's data from one Telegram chat to another,
+// for families that moved the bot to a new group.
+package migrate
@@ -0,0 +1,202 @@
+package migrate
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/korjavin/whatsfordinner/pkg/logger"
+	"github.com/korjavin/whatsfordinner/pkg/models"
+	"github.com/korjavin/whatsfordinner/pkg/storage"
+)
+
+// ErrSameChannel is returned when a channel is migrated onto itself
+var ErrSameChannel = errors.New("can't migrate a channel onto itself")
+
+// ErrNothingToMigrate is returned when the old channel has no data
+var ErrNothingToMigrate = errors.New("the old channel has no data")
+
+// ErrTargetHasData is returned when the new channel already has data a migration would overwrite
+var ErrTargetHasData = errors.New("the new channel already has data")
+
+// Result is what a migration copied
+type Result struct {
+	Ingredients int
+	Stats       bool
+	Dinners     int
+	Suggestions int
+}
+
+// Service copies a channel's data to another channel
+type Service struct {
+	store  *storage.Store
+	logger *logger.Logger
+}
+
+// New creates a new migration service
+func New(store *storage.Store) *Service {
+	return &Service{
+		store:  store,
+		logger: logger.New(""),
+	}
+}
+
+// Copy copies the fridge, statistics, dinners and suggestions of channel from to channel to,
+// re-keying every record and its channel ID. The old channel keeps its data.
+// Nothing is copied if the new channel already has any of this data.
+func (s *Service) Copy(from, to int64) (Result, error) {
+	if from == to {
+		return Result{}, ErrSameChannel
+	}
+
+	hasData, err := s.hasData(to)
+	if err != nil {
+		return Result{}, err
+	}
+	if hasData {
+		return Result{}, ErrTargetHasData
+	}
+
+	var result Result
+
+	var fridge models.Fridge
+	err = s.store.Get(fridgeKey(from), &fridge)
+	if err != nil && !errors.Is(err, storage.ErrNotFound) {
+		return Result{}, fmt.Errorf("failed to get fridge: %w", err)
+	}
+	if err == nil {
+		fridge.ID = fridgeKey(to)
+		fridge.ChannelID = to
+		if err := s.store.Set(fridgeKey(to), fridge); err != nil {
+			return Result{}, fmt.Errorf("failed to copy fridge: %w", err)
+		}
+		result.Ingredients = len(fridge.Ingredients)
+	}
+
+	var stats models.Statistics
+	err = s.store.Get(statsKey(from), &stats)
+	if err != nil && !errors.Is(err, storage.ErrNotFound) {
+		return Result{}, fmt.Errorf("failed to get statistics: %w", err)
+	}
+	if err == nil {
+		stats.ChannelID = to
+		if err := s.store.Set(statsKey(to), stats); err != nil {
+			return Result{}, fmt.Errorf("failed to copy statistics: %w", err)
+		}
+		result.Stats = true
+	}
+
+	result.Dinners, err = s.copyDinners(from, to)
+	if err != nil {
+		return result, fmt.Errorf("failed to copy dinners: %w", err)
+	}
+
+	result.Suggestions, err = s.copySuggestions(from, to)
+	if err != nil {
+		return result, fmt.Errorf("failed to copy suggestions: %w", err)
+	}
+
+	if result == (Result{}) {
+		return result, ErrNothingToMigrate
+	}
+
+	s.logger.Info("Copied channel %d to %d: %d ingredients, %d dinners, %d suggestions", from, to, result.Ingredients, result.Dinners, result.Suggestions)
+	return result, nil
+}
+
+// hasData reports whether a channel has ingredients, statistics, dinners or suggestions
+func (s *Service) hasData(channelID int64) (bool, error) {
+	var fridge models.Fridge
+	err := s.store.Get(fridgeKey(channelID), &fridge)
+	if err == nil && len(fridge.Ingredients) > 0 {
+		return true, nil
+	}
+
+	var stats models.Statistics
+	err = s.store.Get(statsKey(channelID), &stats)
+	if err == nil && (len(stats.CookStats) > 0 || len(stats.HelperStats) > 0 || len(stats.SuggesterStats) > 0) {
+		return true, nil
+	}
+
+	for _, prefix := range []string{dinnerPrefix(channelID), suggestionPrefix(channelID)} {
+		count, err := s.store.Count(prefix)
+		if err != nil {
+			return false, err
+		}
+		if count > 0 {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// copyDinners copies a channel's dinners to another channel and returns how many were copied
+func (s *Service) copyDinners(from, to int64) (int, error) {
+	keys, err := s.store.List(dinnerPrefix(from))
+	if err != nil {
+		return 0, err
+	}
+
+	copied := 0
+	for _, key := range keys {
+		var dinner models.Dinner
+		if err := s.store.Get(key, &dinner); err != nil {
+			return copied, err
+		}
+
+		dinner.ID = dinnerPrefix(to) + strings.TrimPrefix(key, dinnerPrefix(from))
+		dinner.ChannelID = to
+		if err := s.store.Set(dinner.ID, dinner); err != nil {
+			return copied, err
+		}
+		copied++
+	}
+
+	return copied, nil
+}
+
+// copySuggestions copies a channel's suggestions to another channel and returns how many were copied
+func (s *Service) copySuggestions(from, to int64) (int, error) {
+	keys, err := s.store.List(suggestionPrefix(from))
+	if err != nil {
+		return 0, err
+	}
+
+	copied := 0
+	for _, key := range keys {
+		var suggestion models.SuggestedDish
+		if err := s.store.Get(key, &suggestion); err != nil {
+			return copied, err
+		}
+
+		suggestion.ID = suggestionPrefix(to) + strings.TrimPrefix(key, suggestionPrefix(from))
+		suggestion.ChannelID = to
+		if err := s.store.Set(suggestion.ID, suggestion); err != nil {
+			return copied, err
+		}
+		copied++
+	}
+
+	return copied, nil
+}
+
+// fridgeKey returns the key of a channel's fridge
+func fridgeKey(channelID int64) string {
+	return fmt.Sprintf("fridge:%d", channelID)
+}
+
+// statsKey returns the key of a channel's statistics
+func statsKey(channelID int64) string {
+	return fmt.Sprintf("stats:%d", channelID)
+}
+
+// dinnerPrefix returns the key prefix of a channel's dinners
+func dinnerPrefix(channelID int64) string {
+	return fmt.Sprintf("dinner:%d:", channelID)
+}
+
+// suggestionPrefix returns the key prefix of a channel's suggestions
+func suggestionPrefix(channelID int64) string {
+	return fmt.Sprintf("suggestion:%d:", channelID)
+}
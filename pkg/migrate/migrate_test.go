@@ -0,0 +1,69 @@
+package migrate
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/korjavin/whatsfordinner/internal/test"
+	"github.com/korjavin/whatsfordinner/pkg/models"
+)
+
+func TestCopyRekeysRecords(t *testing.T) {
+	store := test.NewStore(t)
+	service := New(store)
+
+	records := map[string]interface{}{
+		"fridge:1":         models.Fridge{ID: "fridge:1", ChannelID: 1, Ingredients: map[string]models.Ingredient{"eggs": {Name: "eggs"}}},
+		"stats:1":          models.Statistics{ChannelID: 1, CookStats: map[string]models.CookStat{"7": {UserID: "7"}}},
+		"dinner:1:100":     models.Dinner{ID: "dinner:1:100", ChannelID: 1, Cook: "7"},
+		"suggestion:1:200": models.SuggestedDish{ID: "suggestion:1:200", ChannelID: 1, Name: "Soup"},
+		// Another channel whose ID starts the same way isn't copied
+		"dinner:12:300": models.Dinner{ID: "dinner:12:300", ChannelID: 12},
+	}
+	for key, record := range records {
+		if err := store.Set(key, record); err != nil {
+			t.Fatalf("failed to save %s: %v", key, err)
+		}
+	}
+
+	result, err := service.Copy(1, 2)
+	if err != nil {
+		t.Fatalf("Copy failed: %v", err)
+	}
+	if result != (Result{Ingredients: 1, Stats: true, Dinners: 1, Suggestions: 1}) {
+		t.Errorf("Copy() = %+v, want one of each", result)
+	}
+
+	var fridge models.Fridge
+	if err := store.Get("fridge:2", &fridge); err != nil || fridge.ID != "fridge:2" || fridge.ChannelID != 2 || len(fridge.Ingredients) != 1 {
+		t.Errorf("copied fridge = %+v (%v), want fridge:2 with the eggs", fridge, err)
+	}
+	var stats models.Statistics
+	if err := store.Get("stats:2", &stats); err != nil || stats.ChannelID != 2 || len(stats.CookStats) != 1 {
+		t.Errorf("copied stats = %+v (%v), want channel 2 with one cook", stats, err)
+	}
+	var dinner models.Dinner
+	if err := store.Get("dinner:2:100", &dinner); err != nil || dinner.ID != "dinner:2:100" || dinner.ChannelID != 2 || dinner.Cook != "7" {
+		t.Errorf("copied dinner = %+v (%v), want dinner:2:100 cooked by 7", dinner, err)
+	}
+	var suggestion models.SuggestedDish
+	if err := store.Get("suggestion:2:200", &suggestion); err != nil || suggestion.ID != "suggestion:2:200" || suggestion.ChannelID != 2 {
+		t.Errorf("copied suggestion = %+v (%v), want suggestion:2:200", suggestion, err)
+	}
+
+	// The old channel keeps its data
+	if err := store.Get("dinner:1:100", &dinner); err != nil || dinner.ChannelID != 1 {
+		t.Errorf("old dinner = %+v (%v), want it untouched", dinner, err)
+	}
+
+	// Copying again would overwrite the new channel's data
+	if _, err := service.Copy(1, 2); !errors.Is(err, ErrTargetHasData) {
+		t.Errorf("second Copy returned %v, want ErrTargetHasData", err)
+	}
+	if _, err := service.Copy(1, 1); !errors.Is(err, ErrSameChannel) {
+		t.Errorf("Copy onto itself returned %v, want ErrSameChannel", err)
+	}
+	if _, err := service.Copy(3, 4); !errors.Is(err, ErrNothingToMigrate) {
+		t.Errorf("Copy of an empty channel returned %v, want ErrNothingToMigrate", err)
+	}
+}
@@ -13,6 +13,80 @@ type ChannelState struct {
 	LastActivity  time.Time  `json:"last_activity"`
 	Cuisines      []string   `json:"cuisines"`
 	MemberCount   int        `json:"member_count,omitempty"`
+	// WaitingForCook is the poll ID of the last ended vote, while nobody volunteered to cook its winning dish
+	WaitingForCook string `json:"waiting_for_cook,omitempty"`
+	// MemberCountManual is set when an admin set MemberCount, so it isn't refreshed from Telegram
+	MemberCountManual bool `json:"member_count_manual,omitempty"`
+	// CookVolunteerTimeout overrides the configured cook volunteer timeout when positive
+	CookVolunteerTimeout time.Duration `json:"cook_volunteer_timeout,omitempty"`
+	// Servings is the family size recipes are scaled to, 0 means unscaled
+	Servings int `json:"servings,omitempty"`
+	// PollQuestion is the dinner poll question, may contain {date} and {weekday} placeholders
+	PollQuestion string `json:"poll_question,omitempty"`
+	// Timezone is an IANA time zone name like "Europe/Berlin", empty means the server's time zone
+	Timezone string `json:"timezone,omitempty"`
+	// Staples are ingredients the family always has, nil means the default staples
+	Staples []string `json:"staples"`
+	// RestrictCookToVoters only lets people who voted for the winning dish volunteer to cook it
+	RestrictCookToVoters bool `json:"restrict_cook_to_voters,omitempty"`
+	// RawQuantities shows fridge quantities as entered instead of converting them to metric units
+	RawQuantities bool `json:"raw_quantities,omitempty"`
+	// LastWeeklySummary is when the weekly summary was last posted
+	LastWeeklySummary time.Time `json:"last_weekly_summary,omitempty"`
+	// ShoppingDay is the day the family shops every week, nil if they don't have one
+	ShoppingDay *time.Weekday `json:"shopping_day,omitempty"`
+	// LastRestockReminder is when the reminder before the shopping day was last posted
+	LastRestockReminder time.Time `json:"last_restock_reminder,omitempty"`
+	// RejectedDishes are the options of polls the family re-rolled, they aren't suggested again until the next dinner
+	RejectedDishes []string `json:"rejected_dishes,omitempty"`
+	// Rerolls is how many times the current dinner poll was re-rolled
+	Rerolls int `json:"rerolls,omitempty"`
+	// Excused are family members who are away, they don't count towards the poll threshold
+	Excused []ExcusedMember `json:"excused,omitempty"`
+	// AutoDinner is whether the scheduler starts the dinner workflow every afternoon, nil means it does
+	AutoDinner *bool `json:"auto_dinner,omitempty"`
+	// RatingStyle is "thumbs" to rate dinners with thumbs down/up only, empty means stars
+	RatingStyle string `json:"rating_style,omitempty"`
+	// VoteCards posts each suggested dish as its own message with a vote button instead of a Telegram poll
+	VoteCards bool `json:"vote_cards,omitempty"`
+	// PlanNudge is when the family is nudged to plan the next week, nil means DefaultPlanNudge
+	PlanNudge *PlanNudge `json:"plan_nudge,omitempty"`
+	// LastPlanNudge is when the nudge to plan the next week was last posted
+	LastPlanNudge time.Time `json:"last_plan_nudge,omitempty"`
+	// MealPlan is the dish planned for each day of the week, days without a dish are left out
+	MealPlan map[time.Weekday]string `json:"meal_plan,omitempty"`
+}
+
+// PlanNudge is the weekly reminder to plan the next week's dinners
+type PlanNudge struct {
+	Off  bool         `json:"off,omitempty"`
+	Day  time.Weekday `json:"day"`
+	Hour int          `json:"hour"` // In the channel's time zone
+}
+
+// ExcusedMember is a family member who is away for a while
+type ExcusedMember struct {
+	UserID   string    `json:"user_id"`
+	Username string    `json:"username"`
+	Until    time.Time `json:"until,omitempty"` // Zero until the member is unexcused
+}
+
+// Location returns the channel's time zone, falling back to the server's time zone
+// if none is set or it can't be loaded
+func (c *ChannelState) Location() *time.Location {
+	if c.Timezone == "" {
+		return time.Local
+	}
+	loc, err := time.LoadLocation(c.Timezone)
+	if err != nil {
+		return time.Local
+	}
+	return loc
+}
+
+// AutoDinnerEnabled reports whether the scheduler starts the dinner workflow for the channel
+func (c *ChannelState) AutoDinnerEnabled() bool {
+	return c.AutoDinner == nil || *c.AutoDinner
 }
 
 // Fridge represents the ingredients available in a channel's fridge
@@ -23,19 +97,40 @@ type Fridge struct {
 	LastUpdated time.Time             `json:"last_updated"`
 }
 
+// FridgeSnapshot is the number of ingredients in a channel's fridge on a given day
+type FridgeSnapshot struct {
+	ChannelID int64     `json:"channel_id"`
+	Date      string    `json:"date"` // YYYY-MM-DD in the channel's time zone
+	Count     int       `json:"count"`
+	TakenAt   time.Time `json:"taken_at"`
+}
+
 // Ingredient represents a single ingredient in the fridge
 type Ingredient struct {
 	Name     string    `json:"name"`
 	Quantity string    `json:"quantity,omitempty"`
 	AddedAt  time.Time `json:"added_at"`
+	Location string    `json:"location,omitempty"`  // LocationFridge or LocationPantry
+	Category string    `json:"category,omitempty"`  // One of the fridge.Categories
+	LowStock bool      `json:"low_stock,omitempty"` // Marked as running low with /low
 }
 
+// Ingredient locations
+const (
+	// LocationFridge is for perishables that are cleared on every fridge sync
+	LocationFridge = "fridge"
+	// LocationPantry is for staples like salt or flour that survive a fridge sync
+	LocationPantry = "pantry"
+)
+
 // Dish represents a dinner dish
 type Dish struct {
 	Name         string   `json:"name"`
 	Cuisine      string   `json:"cuisine"`
 	Ingredients  []string `json:"ingredients"`
 	Instructions []string `json:"instructions"`
+	Servings     int      `json:"servings,omitempty"` // Servings the recipe makes
+	Tags         []string `json:"tags,omitempty"`     // Lowercase traits like "quick" or "kid-friendly"
 }
 
 // VoteState represents the state of a vote
@@ -49,6 +144,16 @@ type VoteState struct {
 	WinningDish    string            `json:"winning_dish,omitempty"`
 	CookVolunteers []string          `json:"cook_volunteers,omitempty"`
 	SelectedCook   string            `json:"selected_cook,omitempty"`
+	RunoffOf       string            `json:"runoff_of,omitempty"`    // PollID of the tied vote this runoff settles
+	RunoffDepth    int               `json:"runoff_depth,omitempty"` // Number of runoffs leading up to this vote
+	LastVoteAt     time.Time         `json:"last_vote_at,omitempty"`
+	Tags           []string          `json:"tags,omitempty"`             // Tags the options were picked for, e.g. from /dinner #quick
+	ReopenedAt     time.Time         `json:"reopened_at,omitempty"`      // When the closed vote was last reopened
+	CardMessageIDs []int             `json:"card_message_ids,omitempty"` // Messages of the dish cards, for votes held with vote cards
+	Results        map[string]int    `json:"results,omitempty"`          // Votes per option when the vote ended
+	VoterNames     map[string]string `json:"voter_names,omitempty"`      // UserID -> username or first name of everyone who voted
+	// RunoffClaimedAt is when a close claimed the tied vote to settle it with a runoff, zero if none did
+	RunoffClaimedAt time.Time `json:"runoff_claimed_at,omitempty"`
 }
 
 // Dinner represents a dinner event
@@ -59,9 +164,16 @@ type Dinner struct {
 	Cook            string         `json:"cook,omitempty"` // UserID of the cook
 	StartedAt       time.Time      `json:"started_at"`
 	FinishedAt      time.Time      `json:"finished_at,omitempty"`
-	Ratings         map[string]int `json:"ratings,omitempty"` // UserID -> Rating (1-5)
+	Ratings         map[string]int `json:"ratings,omitempty"` // UserID -> Rating (1 to the configured rating scale)
 	AverageRating   float64        `json:"average_rating,omitempty"`
 	UsedIngredients []string       `json:"used_ingredients,omitempty"`
+	Attendees       []string       `json:"attendees,omitempty"` // UserIDs of the people who ate
+	// RatingsFinalized is set once the rating window has closed, after which ratings are no longer accepted
+	RatingsFinalized bool `json:"ratings_finalized,omitempty"`
+	// RatingsReopenedAt is when an admin last reopened the rating window, which then runs from this time
+	RatingsReopenedAt time.Time `json:"ratings_reopened_at,omitempty"`
+	// HandoffFrom is the UserID of a cook who passed cooking on and is waiting for someone to take over
+	HandoffFrom string `json:"handoff_from,omitempty"`
 }
 
 // Statistics represents the statistics for a channel
@@ -81,6 +193,14 @@ type CookStat struct {
 	AvgRating   float64 `json:"avg_rating"`
 }
 
+// AttendanceStat compares how often someone ate with how often they cooked
+type AttendanceStat struct {
+	UserID   string `json:"user_id"`
+	Username string `json:"username"`
+	Eaten    int    `json:"eaten"`
+	Cooked   int    `json:"cooked"`
+}
+
 // HelperStat represents the statistics for a shopping helper
 type HelperStat struct {
 	UserID        string `json:"user_id"`
@@ -107,4 +227,39 @@ type SuggestedDish struct {
 	Description string    `json:"description"`
 	SuggestedAt time.Time `json:"suggested_at"`
 	UsedInPoll  bool      `json:"used_in_poll"`
+	Deleted     bool      `json:"deleted,omitempty"` // Archived suggestions stay in history but are left out of polls
+}
+
+// AuditEvent is a workflow state transition of a channel, kept for debugging
+type AuditEvent struct {
+	ChannelID int64     `json:"channel_id"`
+	Type      string    `json:"type"`
+	Details   string    `json:"details,omitempty"`
+	At        time.Time `json:"at"`
+}
+
+// UserPrefs holds the preferences of a single user across chats
+type UserPrefs struct {
+	UserID        int64 `json:"user_id"`
+	PrivateChatID int64 `json:"private_chat_id,omitempty"` // 0 if the user never started a private chat with the bot
+	CookDigest    bool  `json:"cook_digest,omitempty"`     // Send a private recap of the ratings of dinners they cooked
+}
+
+// StepTimer is a one-shot reminder started from a recipe step, e.g. "Simmer for 20 minutes"
+type StepTimer struct {
+	ID        string    `json:"id"`
+	ChannelID int64     `json:"channel_id"`
+	Step      string    `json:"step"`
+	StartedBy string    `json:"started_by"` // Username of the user who started it
+	DueAt     time.Time `json:"due_at"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ScheduledDinner is a one-off dinner poll scheduled for a specific time
+type ScheduledDinner struct {
+	ID        string    `json:"id"`
+	ChannelID int64     `json:"channel_id"`
+	At        time.Time `json:"at"`
+	CreatedBy string    `json:"created_by"` // UserID of the user who scheduled it
+	CreatedAt time.Time `json:"created_at"`
 }
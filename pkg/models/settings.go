@@ -0,0 +1,134 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// Defaults for the per-channel settings, used when a channel hasn't set its own
+const (
+	// DefaultPollQuestion is the dinner poll question
+	DefaultPollQuestion = "What should we cook tonight?"
+	// DefaultCuisine is asked for when neither the channel nor the configuration lists any cuisines
+	DefaultCuisine = "any"
+	// DefaultRatingStyle rates dinners with stars
+	DefaultRatingStyle = "stars"
+	// DefaultVoteThreshold is the share of present members that has to vote before a poll closes
+	DefaultVoteThreshold = 2.0 / 3.0
+	// DefaultCookVolunteerTimeout is how long to wait for a cook volunteer
+	DefaultCookVolunteerTimeout = 15 * time.Minute
+	// DefaultRatingScale rates dinners from 1 to 5
+	DefaultRatingScale = 5
+)
+
+// DefaultPlanNudge nudges the family to plan the next week on Saturday morning
+var DefaultPlanNudge = PlanNudge{Day: time.Saturday, Hour: 10}
+
+// DefaultStaples are the basics most families always have at home
+var DefaultStaples = []string{"salt", "pepper", "oil", "water", "sugar"}
+
+// Settings are a channel's settings with the defaults filled in, so none of them is ever zero
+type Settings struct {
+	Cuisines             []string
+	CookVolunteerTimeout time.Duration
+	Location             *time.Location
+	PollQuestion         string // Not expanded yet, may contain placeholders
+	Staples              []string
+	RatingStyle          string
+	RatingScale          int
+	VoteThreshold        float64
+	AutoDinner           bool
+	VoteCards            bool
+	Servings             int // 0 means recipes aren't scaled
+	PlanNudge            PlanNudge
+}
+
+// DefaultSettings returns the settings of a channel that hasn't changed any of them.
+// The bot's configuration can override some of these before they're passed to Effective.
+func DefaultSettings() Settings {
+	return Settings{
+		Cuisines:             []string{DefaultCuisine},
+		CookVolunteerTimeout: DefaultCookVolunteerTimeout,
+		Location:             time.Local,
+		PollQuestion:         DefaultPollQuestion,
+		Staples:              DefaultStaples,
+		RatingStyle:          DefaultRatingStyle,
+		RatingScale:          DefaultRatingScale,
+		VoteThreshold:        DefaultVoteThreshold,
+		AutoDinner:           true,
+		PlanNudge:            DefaultPlanNudge,
+	}
+}
+
+// Effective returns the channel's settings, using the given defaults for the ones it hasn't set.
+// Defaults that are zero themselves fall back to DefaultSettings.
+func (c *ChannelState) Effective(defaults Settings) Settings {
+	settings := defaults
+	builtin := DefaultSettings()
+	if settings.CookVolunteerTimeout <= 0 {
+		settings.CookVolunteerTimeout = builtin.CookVolunteerTimeout
+	}
+	if settings.Location == nil {
+		settings.Location = builtin.Location
+	}
+	if settings.PollQuestion == "" {
+		settings.PollQuestion = builtin.PollQuestion
+	}
+	if settings.Staples == nil {
+		settings.Staples = builtin.Staples
+	}
+	if settings.RatingStyle == "" {
+		settings.RatingStyle = builtin.RatingStyle
+	}
+	if settings.RatingScale <= 0 {
+		settings.RatingScale = builtin.RatingScale
+	}
+	if settings.VoteThreshold <= 0 {
+		settings.VoteThreshold = builtin.VoteThreshold
+	}
+	if settings.PlanNudge == (PlanNudge{}) {
+		settings.PlanNudge = builtin.PlanNudge
+	}
+
+	if cuisines := nonBlank(c.Cuisines); len(cuisines) > 0 {
+		settings.Cuisines = cuisines
+	} else if cuisines := nonBlank(defaults.Cuisines); len(cuisines) > 0 {
+		settings.Cuisines = cuisines
+	} else {
+		settings.Cuisines = []string{DefaultCuisine}
+	}
+	if c.CookVolunteerTimeout > 0 {
+		settings.CookVolunteerTimeout = c.CookVolunteerTimeout
+	}
+	if c.Timezone != "" {
+		settings.Location = c.Location()
+	}
+	if c.PollQuestion != "" {
+		settings.PollQuestion = c.PollQuestion
+	}
+	if c.Staples != nil {
+		settings.Staples = c.Staples
+	}
+	if c.RatingStyle != "" {
+		settings.RatingStyle = c.RatingStyle
+	}
+	if c.PlanNudge != nil {
+		settings.PlanNudge = *c.PlanNudge
+	}
+	settings.AutoDinner = c.AutoDinnerEnabled()
+	settings.VoteCards = c.VoteCards
+	settings.Servings = c.Servings
+
+	return settings
+}
+
+// nonBlank returns the trimmed values, leaving out blank ones
+func nonBlank(values []string) []string {
+	var result []string
+	for _, value := range values {
+		if value = strings.TrimSpace(value); value != "" {
+			result = append(result, value)
+		}
+	}
+	return result
+}
@@ -0,0 +1,47 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestEffectiveFillsInDefaults(t *testing.T) {
+	var channelState ChannelState
+
+	// Even zero defaults end up as the built-in ones
+	for _, defaults := range []Settings{{}, DefaultSettings()} {
+		settings := channelState.Effective(defaults)
+		if !reflect.DeepEqual(settings, DefaultSettings()) {
+			t.Errorf("Effective(%+v) = %+v, want %+v", defaults, settings, DefaultSettings())
+		}
+	}
+}
+
+func TestEffectivePrefersChannelSettings(t *testing.T) {
+	defaults := DefaultSettings()
+	defaults.Cuisines = []string{"Italian"}
+	defaults.RatingScale = 10
+
+	var channelState ChannelState
+	if settings := channelState.Effective(defaults); !reflect.DeepEqual(settings.Cuisines, []string{"Italian"}) || settings.RatingScale != 10 {
+		t.Errorf("cuisines %v, rating scale %d, want the configured Italian and 10", settings.Cuisines, settings.RatingScale)
+	}
+
+	off := false
+	channelState = ChannelState{
+		Cuisines:             []string{" Thai ", ""},
+		CookVolunteerTimeout: time.Hour,
+		PollQuestion:         "Dinner?",
+		Staples:              []string{},
+		RatingStyle:          "thumbs",
+		AutoDinner:           &off,
+		PlanNudge:            &PlanNudge{Off: true},
+	}
+	settings := channelState.Effective(defaults)
+	if !reflect.DeepEqual(settings.Cuisines, []string{"Thai"}) || settings.CookVolunteerTimeout != time.Hour ||
+		settings.PollQuestion != "Dinner?" || len(settings.Staples) != 0 || settings.RatingStyle != "thumbs" ||
+		settings.AutoDinner || !settings.PlanNudge.Off {
+		t.Errorf("Effective() = %+v, want the channel's own settings", settings)
+	}
+}
@@ -0,0 +1,94 @@
+package openai
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrUnavailable is returned without calling the API while the circuit breaker is open
+var ErrUnavailable = errors.New("AI is temporarily unavailable")
+
+const (
+	// breakerThreshold is the number of consecutive failures that opens the circuit
+	breakerThreshold = 3
+	// breakerCooldown is how long the circuit stays open before a probe request is allowed
+	breakerCooldown = time.Minute
+)
+
+// breakerState is the state of a circuit breaker
+type breakerState int
+
+const (
+	breakerClosed breakerState = iota
+	breakerOpen
+	breakerHalfOpen
+)
+
+// circuitBreaker stops calling the API after repeated failures so users get a fast
+// error instead of waiting for a timeout. After the cooldown a single probe request is
+// let through: if it succeeds the circuit closes, otherwise it opens again.
+// It is shared by all copies of a Client made with WithChannel.
+type circuitBreaker struct {
+	mu        sync.Mutex
+	state     breakerState
+	failures  int
+	openedAt  time.Time
+	threshold int
+	cooldown  time.Duration
+	now       func() time.Time
+}
+
+func newCircuitBreaker(threshold int, cooldown time.Duration) *circuitBreaker {
+	return &circuitBreaker{
+		threshold: threshold,
+		cooldown:  cooldown,
+		now:       time.Now,
+	}
+}
+
+// allow returns ErrUnavailable if a request shouldn't be sent right now
+func (b *circuitBreaker) allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case breakerOpen:
+		if b.now().Sub(b.openedAt) < b.cooldown {
+			return ErrUnavailable
+		}
+		// Let a single probe request through
+		b.state = breakerHalfOpen
+		return nil
+	case breakerHalfOpen:
+		// A probe is already in flight
+		return ErrUnavailable
+	default:
+		return nil
+	}
+}
+
+// isOpen reports whether requests are currently being skipped
+func (b *circuitBreaker) isOpen() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state != breakerClosed
+}
+
+// record updates the breaker with the result of a request
+func (b *circuitBreaker) record(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err == nil {
+		b.state = breakerClosed
+		b.failures = 0
+		return
+	}
+
+	b.failures++
+	if b.state == breakerHalfOpen || b.failures >= b.threshold {
+		b.state = breakerOpen
+		b.openedAt = b.now()
+	}
+}
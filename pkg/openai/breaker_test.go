@@ -0,0 +1,85 @@
+package openai
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreakerTransitions(t *testing.T) {
+	now := time.Date(2024, 6, 1, 18, 0, 0, 0, time.UTC)
+	b := newCircuitBreaker(3, time.Minute)
+	b.now = func() time.Time { return now }
+	failure := errors.New("timeout")
+
+	// Closed: failures below the threshold still let requests through
+	for i := 0; i < 2; i++ {
+		if err := b.allow(); err != nil {
+			t.Fatalf("allow() = %v after %d failures, want nil", err, i)
+		}
+		b.record(failure)
+	}
+	// A success resets the count
+	b.record(nil)
+	for i := 0; i < 2; i++ {
+		b.record(failure)
+	}
+	if b.isOpen() {
+		t.Fatal("the circuit opened although the failures weren't consecutive")
+	}
+
+	// Open: the third consecutive failure fails requests fast
+	b.record(failure)
+	if err := b.allow(); !errors.Is(err, ErrUnavailable) {
+		t.Fatalf("allow() = %v with an open circuit, want ErrUnavailable", err)
+	}
+
+	// Half-open: after the cooldown a single probe goes through
+	now = now.Add(time.Minute)
+	if err := b.allow(); err != nil {
+		t.Fatalf("allow() = %v after the cooldown, want a probe", err)
+	}
+	if err := b.allow(); !errors.Is(err, ErrUnavailable) {
+		t.Fatalf("allow() = %v while a probe is in flight, want ErrUnavailable", err)
+	}
+
+	// A failed probe opens the circuit again for another cooldown
+	b.record(failure)
+	now = now.Add(30 * time.Second)
+	if err := b.allow(); !errors.Is(err, ErrUnavailable) {
+		t.Fatalf("allow() = %v after a failed probe, want ErrUnavailable", err)
+	}
+
+	// A successful probe closes it
+	now = now.Add(time.Minute)
+	if err := b.allow(); err != nil {
+		t.Fatalf("allow() = %v after the second cooldown, want a probe", err)
+	}
+	b.record(nil)
+	if b.isOpen() {
+		t.Fatal("the circuit is still open after a successful probe")
+	}
+	if err := b.allow(); err != nil {
+		t.Errorf("allow() = %v with a closed circuit, want nil", err)
+	}
+}
+
+func TestClientFailsFastWithOpenCircuit(t *testing.T) {
+	client, fake := newTestClient(t)
+	fake.SetFailing(true)
+
+	for i := 0; i < breakerThreshold; i++ {
+		if _, err := client.GenerateChatMessage("welcome", nil); err == nil {
+			t.Fatal("GenerateChatMessage succeeded against a failing API")
+		}
+	}
+
+	// Copies for other channels share the breaker
+	_, err := client.WithChannel(42).GenerateChatMessage("welcome", nil)
+	if !errors.Is(err, ErrUnavailable) {
+		t.Errorf("GenerateChatMessage() = %v with an open circuit, want ErrUnavailable", err)
+	}
+	if got := len(fake.Prompts()); got != breakerThreshold {
+		t.Errorf("the API got %d requests, want %d", got, breakerThreshold)
+	}
+}
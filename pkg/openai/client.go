@@ -13,9 +13,15 @@ import (
 
 // Client represents an OpenAI API client
 type Client struct {
-	client *openai.Client
-	model  string
-	logger *logger.Logger
+	client  *openai.Client
+	model   string
+	baseURL string
+	logger  *logger.Logger
+
+	// channelID is the channel token usage is attributed to, 0 if unknown
+	channelID int64
+	usage     *usageTracker
+	breaker   *circuitBreaker
 }
 
 // New creates a new OpenAI client
@@ -27,9 +33,12 @@ func New(apiKey, apiBase, model string) *Client {
 
 	client := openai.NewClientWithConfig(config)
 	return &Client{
-		client: client,
-		model:  model,
-		logger: logger.New(""),
+		client:  client,
+		model:   model,
+		baseURL: config.BaseURL,
+		logger:  logger.New(""),
+		usage:   newUsageTracker(),
+		breaker: newCircuitBreaker(breakerThreshold, breakerCooldown),
 	}
 }
 
@@ -49,8 +58,11 @@ Return the information in the following JSON format:
   "cuisine": "Cuisine type",
   "ingredients_needed": ["ingredient1", "ingredient2", ...],
   "instructions": ["step1", "step2", ...],
-  "description": "Brief description of the dish"
+  "servings": 4,
+  "description": "Brief description of the dish",
+  "tags": ["quick", "spicy", "kid-friendly", ...]
 }
+Start each ingredient with its amount for the given number of servings, e.g. "200g flour".
 Only return the JSON, no other text.
 `, dishName, cuisine[0])
 		c.logger.Info("Requesting dish info for %s (%s cuisine)", dishName, cuisine[0])
@@ -65,8 +77,11 @@ Return the information in the following JSON format:
   "cuisine": "Cuisine type",
   "ingredients_needed": ["ingredient1", "ingredient2", ...],
   "instructions": ["step1", "step2", ...],
-  "description": "Brief description of the dish"
+  "servings": 4,
+  "description": "Brief description of the dish",
+  "tags": ["quick", "spicy", "kid-friendly", ...]
 }
+Start each ingredient with its amount for the given number of servings, e.g. "200g flour".
 Only return the JSON, no other text.
 `, dishName)
 		c.logger.Info("Requesting dish info for %s (cuisine not specified)", dishName)
@@ -74,7 +89,7 @@ Only return the JSON, no other text.
 
 	c.logger.Debug("OpenAI prompt (first 100 chars): %s", truncateString(prompt, 100))
 
-	resp, err := c.client.CreateChatCompletion(
+	resp, err := c.createChatCompletion(
 		ctx,
 		openai.ChatCompletionRequest{
 			Model: c.model,
@@ -140,7 +155,7 @@ Return only the message text, no explanations or other text.
 
 	c.logger.Info("Generating chat message for intent: %s", intent)
 
-	resp, err := c.client.CreateChatCompletion(
+	resp, err := c.createChatCompletion(
 		ctx,
 		openai.ChatCompletionRequest{
 			Model: c.model,
@@ -180,7 +195,7 @@ For example: ["eggs", "milk", "tomatoes", "chicken breast"]
 	c.logger.Debug("Photo URL (truncated): %s", truncateString(photoURL, 50))
 
 	// Create a request with the image
-	resp, err := c.client.CreateChatCompletion(
+	resp, err := c.createChatCompletion(
 		ctx,
 		openai.ChatCompletionRequest{
 			Model: c.model,
@@ -233,14 +248,14 @@ For example: ["eggs", "milk", "tomatoes", "chicken breast"]
 		extractedIngredients := extractIngredientsFromText(content)
 		if len(extractedIngredients) > 0 {
 			c.logger.Info("Extracted %d ingredients using fallback method", len(extractedIngredients))
-			return extractedIngredients, nil
+			return dedupeIngredients(extractedIngredients), nil
 		}
 
 		return nil, fmt.Errorf("failed to parse OpenAI response: %w", err)
 	}
 
 	c.logger.Info("Successfully extracted %d ingredients from photo", len(ingredients))
-	return ingredients, nil
+	return dedupeIngredients(ingredients), nil
 }
 
 // ParseIngredientsFromText extracts ingredients from free-form text
@@ -259,7 +274,7 @@ Text: %s
 	c.logger.Info("Parsing ingredients from text")
 	c.logger.Debug("Text to parse (first 100 chars): %s", truncateString(text, 100))
 
-	resp, err := c.client.CreateChatCompletion(
+	resp, err := c.createChatCompletion(
 		ctx,
 		openai.ChatCompletionRequest{
 			Model: c.model,
@@ -293,57 +308,49 @@ Text: %s
 		return nil, fmt.Errorf("failed to parse OpenAI response: %w", err)
 	}
 
-	return ingredients, nil
+	return dedupeIngredients(ingredients), nil
 }
 
 // SuggestDinnerOptions suggests dinner options based on available ingredients and cuisines
 func (c *Client) SuggestDinnerOptions(ingredients []string, cuisines []string, count int) ([]map[string]interface{}, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-	defer cancel()
-
-	// Convert ingredients and cuisines to strings for the prompt
-	ingredientsStr := strings.Join(ingredients, ", ")
-	cuisinesStr := strings.Join(cuisines, ", ")
-
-	prompt := fmt.Sprintf(`
-You are a cooking expert. Based on the available ingredients and preferred cuisines, suggest %d dinner options.
-
-Available ingredients: %s
+	return c.SuggestFilteredDinnerOptions(ingredients, cuisines, count, SuggestionFilter{})
+}
 
-Preferred cuisines: %s
+// SuggestionFilter narrows down dinner suggestions
+type SuggestionFilter struct {
+	// Exclude are dishes the family turned down, which shouldn't be suggested again
+	Exclude []string
+	// Tags are traits every suggested dish must have, like "quick" or "kid-friendly"
+	Tags []string
+}
 
-Return the suggestions in the following JSON format:
-[
-  {
-    "name": "Dish name",
-    "cuisine": "Cuisine type",
-    "description": "Brief description of the dish",
-    "ingredients_needed": ["ingredient1", "ingredient2", ...],
-    "ingredients_missing": ["ingredient1", "ingredient2", ...]
-  },
-  ...
-]
+// SuggestFilteredDinnerOptions suggests dinner options like SuggestDinnerOptions,
+// asking only for dishes that pass the filter
+func (c *Client) SuggestFilteredDinnerOptions(ingredients []string, cuisines []string, count int, filter SuggestionFilter) ([]map[string]interface{}, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
 
-Only return the JSON array, no other text.
-`, count, ingredientsStr, cuisinesStr)
+	prompt := dinnerOptionsPrompt(ingredients, cuisines, count, filter)
 
-	c.logger.Info("Requesting dinner suggestions based on %d ingredients and %d cuisines", len(ingredients), len(cuisines))
+	c.logger.Info("Requesting dinner suggestions based on %d ingredients and %d cuisines, excluding %d dishes, tags %v", len(ingredients), len(cuisines), len(filter.Exclude), filter.Tags)
 	c.logger.Debug("OpenAI prompt (first 100 chars): %s", truncateString(prompt, 100))
 
-	resp, err := c.client.CreateChatCompletion(
+	messages := []openai.ChatCompletionMessage{
+		{
+			Role:    openai.ChatMessageRoleSystem,
+			Content: "You are a cooking expert who helps families decide what to cook for dinner based on available ingredients.",
+		},
+		{
+			Role:    openai.ChatMessageRoleUser,
+			Content: prompt,
+		},
+	}
+
+	resp, err := c.createChatCompletion(
 		ctx,
 		openai.ChatCompletionRequest{
-			Model: c.model,
-			Messages: []openai.ChatCompletionMessage{
-				{
-					Role:    openai.ChatMessageRoleSystem,
-					Content: "You are a cooking expert who helps families decide what to cook for dinner based on available ingredients.",
-				},
-				{
-					Role:    openai.ChatMessageRoleUser,
-					Content: prompt,
-				},
-			},
+			Model:       c.model,
+			Messages:    messages,
 			Temperature: 0.7,
 		},
 	)
@@ -359,13 +366,40 @@ Only return the JSON array, no other text.
 	content := resp.Choices[0].Message.Content
 	c.logger.Debug("OpenAI response (first 100 chars): %s", truncateString(content, 100))
 
-	// Clean up the response - sometimes the model returns markdown code blocks
-	content = cleanJSONResponse(content)
+	suggestions, err := parseDinnerOptions(content)
+	if err != nil {
+		c.logger.Error("Failed to parse dinner suggestions: %v, raw content: %s", err, content)
+
+		// Ask once more, insisting on plain JSON
+		c.logger.Info("Retrying dinner suggestions with a stricter JSON instruction")
+		messages = append(messages,
+			resp.Choices[0].Message,
+			openai.ChatCompletionMessage{
+				Role:    openai.ChatMessageRoleUser,
+				Content: "That wasn't valid JSON. Return ONLY a valid JSON array of dish objects as described, with no other text and no code blocks.",
+			},
+		)
+		resp, err = c.createChatCompletion(
+			ctx,
+			openai.ChatCompletionRequest{
+				Model:       c.model,
+				Messages:    messages,
+				Temperature: 0.2,
+			},
+		)
+		if err != nil {
+			return nil, fmt.Errorf("OpenAI API error: %w", err)
+		}
+		if len(resp.Choices) == 0 {
+			return nil, fmt.Errorf("no response from OpenAI API")
+		}
 
-	var suggestions []map[string]interface{}
-	if err := json.Unmarshal([]byte(content), &suggestions); err != nil {
-		c.logger.Error("Failed to parse response: %v, Content: %s", err, content)
-		return nil, fmt.Errorf("failed to parse OpenAI response: %w", err)
+		content = resp.Choices[0].Message.Content
+		suggestions, err = parseDinnerOptions(content)
+		if err != nil {
+			c.logger.Error("Failed to parse dinner suggestions after retrying: %v, raw content: %s", err, content)
+			return nil, fmt.Errorf("failed to parse OpenAI response: %w", err)
+		}
 	}
 
 	c.logger.Info("Successfully generated %d dinner suggestions", len(suggestions))
@@ -409,6 +443,86 @@ func cleanJSONResponse(s string) string {
 	return s
 }
 
+// parseDinnerOptions parses the dishes in a dinner suggestion response.
+// If the response isn't a clean JSON array, e.g. because it's wrapped in prose or cut off,
+// the dishes that can be read are recovered with extractDishesFromText.
+func parseDinnerOptions(content string) ([]map[string]interface{}, error) {
+	// Clean up the response - sometimes the model returns markdown code blocks
+	content = cleanJSONResponse(content)
+
+	var suggestions []map[string]interface{}
+	err := json.Unmarshal([]byte(content), &suggestions)
+	if err == nil {
+		return suggestions, nil
+	}
+
+	if dishes := extractDishesFromText(content); len(dishes) > 0 {
+		return dishes, nil
+	}
+	return nil, err
+}
+
+// extractDishesFromText finds the JSON objects with a dish name in text, skipping anything
+// that isn't valid JSON. Objects that wrap a list of dishes, like {"dishes": [...]}, are unwrapped.
+// This is a fallback method when JSON parsing fails
+func extractDishesFromText(s string) []map[string]interface{} {
+	var dishes []map[string]interface{}
+	for _, raw := range jsonObjects(s) {
+		var object map[string]interface{}
+		if err := json.Unmarshal([]byte(raw), &object); err != nil {
+			continue
+		}
+		if name, ok := object["name"].(string); ok && name != "" {
+			dishes = append(dishes, object)
+			continue
+		}
+		for _, value := range object {
+			list, ok := value.([]interface{})
+			if !ok {
+				continue
+			}
+			for _, item := range list {
+				if dish, ok := item.(map[string]interface{}); ok {
+					if name, ok := dish["name"].(string); ok && name != "" {
+						dishes = append(dishes, dish)
+					}
+				}
+			}
+		}
+	}
+	return dishes
+}
+
+// jsonObjects returns the outermost {...} blocks in text, respecting braces inside strings.
+// An object that is cut off at the end of the text is dropped.
+func jsonObjects(s string) []string {
+	var objects []string
+	depth, start := 0, -1
+	inString, escaped := false, false
+	for i, r := range s {
+		switch {
+		case escaped:
+			escaped = false
+		case inString && r == '\\':
+			escaped = true
+		case r == '"' && depth > 0:
+			inString = !inString
+		case inString:
+		case r == '{':
+			if depth == 0 {
+				start = i
+			}
+			depth++
+		case r == '}' && depth > 0:
+			depth--
+			if depth == 0 {
+				objects = append(objects, s[start:i+1])
+			}
+		}
+	}
+	return objects
+}
+
 // extractIngredientsFromText extracts ingredients from text using a simple heuristic
 // This is a fallback method when JSON parsing fails
 func extractIngredientsFromText(s string) []string {
@@ -439,3 +553,78 @@ func extractIngredientsFromText(s string) []string {
 
 	return ingredients
 }
+
+// dedupeIngredients removes duplicate ingredients, keeping the first spelling.
+// Names are compared case-insensitively and singular and plural forms are collapsed,
+// so "Tomato" and "tomatoes" count as the same ingredient.
+func dedupeIngredients(ingredients []string) []string {
+	seen := make(map[string]bool, len(ingredients))
+	deduped := make([]string, 0, len(ingredients))
+	for _, ingredient := range ingredients {
+		ingredient = strings.TrimSpace(ingredient)
+		if ingredient == "" {
+			continue
+		}
+
+		key := singularize(strings.ToLower(ingredient))
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		deduped = append(deduped, ingredient)
+	}
+	return deduped
+}
+
+// singularize turns a lowercase plural English word into its singular form using simple rules
+func singularize(word string) string {
+	switch {
+	case strings.HasSuffix(word, "ies") && len(word) > 4:
+		return strings.TrimSuffix(word, "ies") + "y"
+	case strings.HasSuffix(word, "oes"), strings.HasSuffix(word, "ches"), strings.HasSuffix(word, "shes"),
+		strings.HasSuffix(word, "xes"), strings.HasSuffix(word, "sses"):
+		return strings.TrimSuffix(word, "es")
+	case strings.HasSuffix(word, "s") && !strings.HasSuffix(word, "ss") && len(word) > 3:
+		return strings.TrimSuffix(word, "s")
+	}
+	return word
+}
+
+// dinnerOptionsPrompt builds the prompt asking for count dinner suggestions
+func dinnerOptionsPrompt(ingredients []string, cuisines []string, count int, filter SuggestionFilter) string {
+	// Convert ingredients and cuisines to strings for the prompt
+	ingredientsStr := strings.Join(ingredients, ", ")
+	cuisinesStr := strings.Join(cuisines, ", ")
+
+	filterStr := ""
+	if len(filter.Tags) > 0 {
+		filterStr += fmt.Sprintf("\nEvery dish must be: %s\n", strings.Join(filter.Tags, ", "))
+	}
+	if len(filter.Exclude) > 0 {
+		filterStr += fmt.Sprintf("\nThe family turned these dishes down, suggest different ones: %s\n", strings.Join(filter.Exclude, ", "))
+	}
+
+	return fmt.Sprintf(`
+You are a cooking expert. Based on the available ingredients and preferred cuisines, suggest %d dinner options.
+
+Available ingredients: %s
+
+Preferred cuisines: %s
+%s
+Return the suggestions in the following JSON format:
+[
+  {
+    "name": "Dish name",
+    "cuisine": "Cuisine type",
+    "description": "Brief description of the dish",
+    "ingredients_needed": ["ingredient1", "ingredient2", ...],
+    "ingredients_missing": ["ingredient1", "ingredient2", ...],
+    "tags": ["quick", "spicy", "kid-friendly", ...]
+  },
+  ...
+]
+
+Tags are short lowercase traits of the dish, like how long it takes, how spicy it is or who it suits.
+Only return the JSON array, no other text.
+`, count, ingredientsStr, cuisinesStr, filterStr)
+}
@@ -0,0 +1,88 @@
+package openai
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDedupeIngredients(t *testing.T) {
+	got := dedupeIngredients([]string{"tomato", "Tomatoes", " milk ", "MILK", "berries", "berry", "peach", "peaches", "", "glass", "eggs"})
+	want := []string{"tomato", "milk", "berries", "peach", "glass", "eggs"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("dedupeIngredients() = %v, want %v", got, want)
+	}
+}
+
+func TestParseIngredientsFromTextDropsDuplicates(t *testing.T) {
+	client, _ := newTestClient(t, `["tomatoes", "Tomato", "onion", "onions", "cheese"]`)
+
+	got, err := client.ParseIngredientsFromText("tomatoes, a tomato, onions and cheese")
+	if err != nil {
+		t.Fatalf("ParseIngredientsFromText failed: %v", err)
+	}
+	want := []string{"tomatoes", "onion", "cheese"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseIngredientsFromText() = %v, want %v", got, want)
+	}
+}
+
+func TestParseDinnerOptionsRecoversMalformedResponses(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []string
+	}{
+		{"clean", `[{"name": "Pasta"}, {"name": "Soup"}]`, []string{"Pasta", "Soup"}},
+		{"code fenced", "```json\n[{\"name\": \"Pasta\"}, {\"name\": \"Soup\"}]\n```", []string{"Pasta", "Soup"}},
+		{"wrapped in prose", `Here are some ideas: {"name": "Pasta"} and also {"name": "Soup"}. Enjoy!`, []string{"Pasta", "Soup"}},
+		{"wrapped in an object", `{"dishes": [{"name": "Pasta"}, {"name": "Soup"}]}`, []string{"Pasta", "Soup"}},
+		{"cut off", `[{"name": "Pasta", "description": "With {braces} and \"quotes\""}, {"name": "So`, []string{"Pasta"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dishes, err := parseDinnerOptions(tt.content)
+			if err != nil {
+				t.Fatalf("parseDinnerOptions failed: %v", err)
+			}
+			var names []string
+			for _, dish := range dishes {
+				names = append(names, dish["name"].(string))
+			}
+			if !reflect.DeepEqual(names, tt.want) {
+				t.Errorf("parseDinnerOptions() = %v, want %v", names, tt.want)
+			}
+		})
+	}
+
+	if _, err := parseDinnerOptions("Sorry, I can't help with that."); err == nil {
+		t.Error("parseDinnerOptions succeeded without any dishes, want an error")
+	}
+}
+
+func TestSuggestDinnerOptionsRetriesWithStricterInstruction(t *testing.T) {
+	client, fake := newTestClient(t, "I'd suggest pasta or soup.", `[{"name": "Pasta"}, {"name": "Soup"}]`)
+
+	suggestions, err := client.SuggestDinnerOptions([]string{"pasta"}, []string{"Italian"}, 2)
+	if err != nil {
+		t.Fatalf("SuggestDinnerOptions failed: %v", err)
+	}
+	if len(suggestions) != 2 {
+		t.Errorf("got %d suggestions, want the 2 from the retry", len(suggestions))
+	}
+	retried := false
+	for _, prompt := range fake.Prompts() {
+		if strings.Contains(prompt, "Return ONLY a valid JSON array") {
+			retried = true
+		}
+	}
+	if !retried {
+		t.Error("didn't retry with the stricter instruction")
+	}
+
+	// A second bad reply gives up
+	fake.SetReplies("Still no JSON.", "Nope.")
+	if _, err := client.SuggestDinnerOptions([]string{"pasta"}, []string{"Italian"}, 2); err == nil {
+		t.Error("SuggestDinnerOptions succeeded without any dishes, want an error")
+	}
+}
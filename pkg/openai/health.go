@@ -0,0 +1,60 @@
+package openai
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// healthCheckTimeout is how long Check waits for the API
+const healthCheckTimeout = 15 * time.Second
+
+// HealthCheck is the result of a minimal request to the AI API
+type HealthCheck struct {
+	Model       string
+	BaseURL     string
+	Latency     time.Duration
+	Reply       string
+	BreakerOpen bool // The circuit breaker was skipping requests before the check
+	Err         error
+}
+
+// Check sends a tiny completion request with the configured model and base URL,
+// to tell a misconfigured or unreachable API apart from a bug in the bot.
+// It bypasses the circuit breaker, and a successful check closes it again.
+func (c *Client) Check() HealthCheck {
+	ctx, cancel := context.WithTimeout(context.Background(), healthCheckTimeout)
+	defer cancel()
+
+	check := HealthCheck{
+		Model:       c.model,
+		BaseURL:     c.baseURL,
+		BreakerOpen: c.breaker.isOpen(),
+	}
+
+	start := time.Now()
+	resp, err := c.client.CreateChatCompletion(ctx, openai.ChatCompletionRequest{
+		Model: c.model,
+		Messages: []openai.ChatCompletionMessage{
+			{
+				Role:    openai.ChatMessageRoleUser,
+				Content: "Reply with OK.",
+			},
+		},
+		MaxTokens: 5,
+	})
+	check.Latency = time.Since(start)
+	c.breaker.record(err)
+	if err != nil {
+		check.Err = err
+		return check
+	}
+
+	c.recordUsage(resp.Usage)
+	if len(resp.Choices) > 0 {
+		check.Reply = strings.TrimSpace(resp.Choices[0].Message.Content)
+	}
+	return check
+}
@@ -0,0 +1,37 @@
+package openai
+
+import (
+	"testing"
+)
+
+func TestCheckReportsSuccessAndErrors(t *testing.T) {
+	client, fake := newTestClient(t, "OK")
+
+	check := client.Check()
+	if check.Err != nil || check.Reply != "OK" || check.BreakerOpen {
+		t.Fatalf("Check() = %+v, want a successful OK", check)
+	}
+	if check.Model != "test-model" || check.BaseURL != fake.BaseURL() {
+		t.Errorf("checked %s at %s, want the configured model and base URL", check.Model, check.BaseURL)
+	}
+	if prompts := fake.Prompts(); len(prompts) != 1 || prompts[0] != "Reply with OK." {
+		t.Errorf("sent prompts %q, want only the minimal check", prompts)
+	}
+
+	fake.SetFailing(true)
+	for i := 0; i < breakerThreshold; i++ {
+		if check := client.Check(); check.Err == nil {
+			t.Fatalf("Check() = %+v against a failing API, want an error", check)
+		}
+	}
+
+	// The check still reaches the API with an open circuit, and closes it once the API is back
+	fake.SetFailing(false)
+	check = client.Check()
+	if check.Err != nil || !check.BreakerOpen {
+		t.Errorf("Check() = %+v, want success reported with the circuit open before", check)
+	}
+	if _, err := client.GenerateChatMessage("welcome", nil); err != nil {
+		t.Errorf("GenerateChatMessage() = %v after a successful check, want the circuit closed", err)
+	}
+}
@@ -0,0 +1,126 @@
+package openai
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/korjavin/whatsfordinner/pkg/metrics"
+	"github.com/sashabaranov/go-openai"
+)
+
+func init() {
+	metrics.Default.Describe("openai_prompt_tokens_total", "Prompt tokens sent to the LLM")
+	metrics.Default.Describe("openai_completion_tokens_total", "Completion tokens received from the LLM")
+	metrics.Default.Describe("openai_requests_total", "Completion requests sent to the LLM")
+}
+
+// Usage is the number of tokens used by completion requests
+type Usage struct {
+	Requests         int64
+	PromptTokens     int64
+	CompletionTokens int64
+}
+
+// TotalTokens returns the number of prompt and completion tokens together
+func (u Usage) TotalTokens() int64 {
+	return u.PromptTokens + u.CompletionTokens
+}
+
+// usageTracker accumulates token usage globally and per channel.
+// It is shared by all copies of a Client made with WithChannel.
+type usageTracker struct {
+	mu        sync.Mutex
+	total     Usage
+	channels  map[int64]Usage
+	costPer1K float64
+}
+
+func newUsageTracker() *usageTracker {
+	return &usageTracker{
+		channels: make(map[int64]Usage),
+	}
+}
+
+// WithChannel returns a client that attributes its token usage to a channel
+func (c *Client) WithChannel(channelID int64) *Client {
+	clone := *c
+	clone.channelID = channelID
+	return &clone
+}
+
+// SetCostPerThousandTokens sets the price of 1000 tokens used for cost estimates
+func (c *Client) SetCostPerThousandTokens(cost float64) {
+	c.usage.mu.Lock()
+	defer c.usage.mu.Unlock()
+
+	c.usage.costPer1K = cost
+}
+
+// TotalUsage returns the token usage of all channels together
+func (c *Client) TotalUsage() Usage {
+	c.usage.mu.Lock()
+	defer c.usage.mu.Unlock()
+
+	return c.usage.total
+}
+
+// ChannelUsage returns the token usage attributed to a channel
+func (c *Client) ChannelUsage(channelID int64) Usage {
+	c.usage.mu.Lock()
+	defer c.usage.mu.Unlock()
+
+	return c.usage.channels[channelID]
+}
+
+// EstimateCost returns a rough dollar estimate for the given usage,
+// or false if no price per 1000 tokens is configured
+func (c *Client) EstimateCost(usage Usage) (float64, bool) {
+	c.usage.mu.Lock()
+	defer c.usage.mu.Unlock()
+
+	if c.usage.costPer1K <= 0 {
+		return 0, false
+	}
+	return float64(usage.TotalTokens()) / 1000 * c.usage.costPer1K, true
+}
+
+// createChatCompletion sends a completion request through the circuit breaker
+// and records its token usage
+func (c *Client) createChatCompletion(ctx context.Context, request openai.ChatCompletionRequest) (openai.ChatCompletionResponse, error) {
+	if err := c.breaker.allow(); err != nil {
+		c.logger.Warn("Skipping OpenAI request, circuit breaker is open")
+		return openai.ChatCompletionResponse{}, err
+	}
+
+	resp, err := c.client.CreateChatCompletion(ctx, request)
+	c.breaker.record(err)
+	if err != nil {
+		return resp, err
+	}
+
+	c.recordUsage(resp.Usage)
+	return resp, nil
+}
+
+// recordUsage adds the usage of a completion response to the totals
+func (c *Client) recordUsage(usage openai.Usage) {
+	c.usage.mu.Lock()
+	c.usage.total.Requests++
+	c.usage.total.PromptTokens += int64(usage.PromptTokens)
+	c.usage.total.CompletionTokens += int64(usage.CompletionTokens)
+
+	channelUsage := c.usage.channels[c.channelID]
+	channelUsage.Requests++
+	channelUsage.PromptTokens += int64(usage.PromptTokens)
+	channelUsage.CompletionTokens += int64(usage.CompletionTokens)
+	c.usage.channels[c.channelID] = channelUsage
+	c.usage.mu.Unlock()
+
+	channel := fmt.Sprintf("%d", c.channelID)
+	metrics.Default.Add("openai_requests_total", 1, "channel", channel)
+	metrics.Default.Add("openai_prompt_tokens_total", float64(usage.PromptTokens), "channel", channel)
+	metrics.Default.Add("openai_completion_tokens_total", float64(usage.CompletionTokens), "channel", channel)
+
+	c.logger.Debug("OpenAI usage for channel %d: %d prompt tokens, %d completion tokens", c.channelID, usage.PromptTokens, usage.CompletionTokens)
+}
@@ -0,0 +1,76 @@
+package openai
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/korjavin/whatsfordinner/internal/test"
+	"github.com/korjavin/whatsfordinner/pkg/metrics"
+)
+
+// newTestClient creates a client talking to a fake OpenAI API
+func newTestClient(t *testing.T, replies ...string) (*Client, *test.OpenAI) {
+	t.Helper()
+
+	fake := test.NewOpenAI(t, replies...)
+	return New("test-key", fake.BaseURL(), "test-model"), fake
+}
+
+func TestUsageIsAccumulated(t *testing.T) {
+	// The fake reports 10 prompt and 5 completion tokens for every request
+	client, _ := newTestClient(t, "Hi!")
+
+	for _, channelID := range []int64{661001, 661001, 661002} {
+		if _, err := client.WithChannel(channelID).GenerateChatMessage("welcome", nil); err != nil {
+			t.Fatalf("GenerateChatMessage failed: %v", err)
+		}
+	}
+
+	if got, want := client.TotalUsage(), (Usage{Requests: 3, PromptTokens: 30, CompletionTokens: 15}); got != want {
+		t.Errorf("TotalUsage() = %+v, want %+v", got, want)
+	}
+	if got, want := client.ChannelUsage(661001), (Usage{Requests: 2, PromptTokens: 20, CompletionTokens: 10}); got != want {
+		t.Errorf("ChannelUsage(661001) = %+v, want %+v", got, want)
+	}
+	if got := client.ChannelUsage(661003); got != (Usage{}) {
+		t.Errorf("ChannelUsage(661003) = %+v for a channel without requests, want nothing", got)
+	}
+
+	var sb strings.Builder
+	metrics.Default.WriteTo(&sb)
+	for _, want := range []string{
+		`openai_prompt_tokens_total{channel="661001"} 20`,
+		`openai_completion_tokens_total{channel="661002"} 5`,
+		`openai_requests_total{channel="661001"} 2`,
+	} {
+		if !strings.Contains(sb.String(), want) {
+			t.Errorf("metrics don't contain %s:\n%s", want, sb.String())
+		}
+	}
+}
+
+func TestEstimateCost(t *testing.T) {
+	client, _ := newTestClient(t)
+
+	if _, ok := client.EstimateCost(Usage{PromptTokens: 1000}); ok {
+		t.Error("estimated a cost without a price")
+	}
+
+	client.SetCostPerThousandTokens(0.5)
+	cost, ok := client.EstimateCost(Usage{PromptTokens: 1500, CompletionTokens: 500})
+	if !ok || cost != 1 {
+		t.Errorf("EstimateCost() = %v, %v, want 1, true", cost, ok)
+	}
+}
+
+func TestFailedRequestsUseNoTokens(t *testing.T) {
+	client, fake := newTestClient(t)
+	fake.SetFailing(true)
+
+	if _, err := client.GenerateChatMessage("welcome", nil); err == nil {
+		t.Fatal("GenerateChatMessage succeeded against a failing API")
+	}
+	if got := client.TotalUsage(); got != (Usage{}) {
+		t.Errorf("TotalUsage() = %+v after a failed request, want nothing", got)
+	}
+}
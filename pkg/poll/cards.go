@@ -0,0 +1,57 @@
+package poll
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/korjavin/whatsfordinner/pkg/models"
+)
+
+// NewCardPollID returns the ID of a vote held with vote cards.
+// Telegram only hands out IDs for real polls, so card votes get their own.
+func NewCardPollID(now time.Time) string {
+	return fmt.Sprintf("cards-%d", now.UnixNano())
+}
+
+// VoteCards reports whether dinner votes are held with one message per dish instead of a Telegram poll
+func (s *Service) VoteCards(channelID int64) bool {
+	var channelState models.ChannelState
+	err := s.store.Get(fmt.Sprintf("channel:%d", channelID), &channelState)
+	if err != nil {
+		return false
+	}
+	return channelState.VoteCards
+}
+
+// SetVoteCards sets whether dinner votes are held with one message per dish instead of a Telegram poll
+func (s *Service) SetVoteCards(channelID int64, cards bool) error {
+	channelKey := fmt.Sprintf("channel:%d", channelID)
+	var channelState models.ChannelState
+	err := s.store.Get(channelKey, &channelState)
+	if err != nil {
+		// Create new channel state if it doesn't exist
+		channelState = models.ChannelState{
+			ChannelID: channelID,
+			FridgeID:  fmt.Sprintf("fridge:%d", channelID),
+		}
+	}
+
+	channelState.VoteCards = cards
+	channelState.LastActivity = time.Now()
+
+	return s.store.Set(channelKey, channelState)
+}
+
+// SetCardMessages records the messages of the dish cards of a vote
+func (s *Service) SetCardMessages(channelID int64, pollID string, messageIDs []int) error {
+	voteKey := fmt.Sprintf("vote:%d:%s", channelID, pollID)
+	var vote models.VoteState
+	err := s.store.Get(voteKey, &vote)
+	if err != nil {
+		return fmt.Errorf("failed to get vote: %w", err)
+	}
+
+	vote.CardMessageIDs = messageIDs
+
+	return s.store.Set(voteKey, vote)
+}
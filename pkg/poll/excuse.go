@@ -0,0 +1,97 @@
+package poll
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/korjavin/whatsfordinner/pkg/models"
+)
+
+// ErrNotExcused is returned when unexcusing a member who isn't excused
+var ErrNotExcused = errors.New("member is not excused")
+
+// ExcuseMember marks a member as away until the given time, or until they're unexcused if until is zero.
+// Excusing a member again replaces the earlier until time.
+func (s *Service) ExcuseMember(channelID int64, userID, username string, until time.Time) error {
+	channelKey := fmt.Sprintf("channel:%d", channelID)
+	var channelState models.ChannelState
+	err := s.store.Get(channelKey, &channelState)
+	if err != nil {
+		// Create new channel state if it doesn't exist
+		channelState = models.ChannelState{
+			ChannelID: channelID,
+			FridgeID:  fmt.Sprintf("fridge:%d", channelID),
+		}
+	}
+
+	excused := models.ExcusedMember{UserID: userID, Username: username, Until: until}
+	replaced := false
+	for i, member := range channelState.Excused {
+		if member.UserID == userID {
+			channelState.Excused[i] = excused
+			replaced = true
+		}
+	}
+	if !replaced {
+		channelState.Excused = append(channelState.Excused, excused)
+	}
+	channelState.LastActivity = time.Now()
+
+	return s.store.Set(channelKey, channelState)
+}
+
+// UnexcuseMember counts a member in again before their until time
+func (s *Service) UnexcuseMember(channelID int64, userID string) error {
+	channelKey := fmt.Sprintf("channel:%d", channelID)
+	var channelState models.ChannelState
+	err := s.store.Get(channelKey, &channelState)
+	if err != nil {
+		return ErrNotExcused
+	}
+
+	remaining := make([]models.ExcusedMember, 0, len(channelState.Excused))
+	for _, member := range channelState.Excused {
+		if member.UserID != userID {
+			remaining = append(remaining, member)
+		}
+	}
+	if len(remaining) == len(channelState.Excused) {
+		return ErrNotExcused
+	}
+
+	channelState.Excused = remaining
+	channelState.LastActivity = time.Now()
+
+	return s.store.Set(channelKey, channelState)
+}
+
+// ExcusedMembers returns the members of a channel who are excused at the given time
+func (s *Service) ExcusedMembers(channelID int64, now time.Time) []models.ExcusedMember {
+	var channelState models.ChannelState
+	err := s.store.Get(fmt.Sprintf("channel:%d", channelID), &channelState)
+	if err != nil {
+		return nil
+	}
+	return ActiveExcuses(channelState.Excused, now)
+}
+
+// ActiveExcuses returns the excuses that haven't run out at the given time
+func ActiveExcuses(excused []models.ExcusedMember, now time.Time) []models.ExcusedMember {
+	var active []models.ExcusedMember
+	for _, member := range excused {
+		if member.Until.IsZero() || now.Before(member.Until) {
+			active = append(active, member)
+		}
+	}
+	return active
+}
+
+// PresentMembers returns how many of the members are not excused, counting at least one
+func PresentMembers(memberCount int, excused []models.ExcusedMember) int {
+	present := memberCount - len(excused)
+	if present < 1 {
+		return 1
+	}
+	return present
+}
@@ -0,0 +1,54 @@
+package poll
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/korjavin/whatsfordinner/internal/test"
+	"github.com/korjavin/whatsfordinner/pkg/models"
+)
+
+func TestExcusedMembersAreSkippedUntilTheirDate(t *testing.T) {
+	service := New(test.NewStore(t))
+	now := time.Date(2024, 6, 10, 18, 0, 0, 0, time.UTC)
+	back := now.AddDate(0, 0, 3)
+	if err := service.ExcuseMember(1, "2", "ben", back); err != nil {
+		t.Fatalf("ExcuseMember failed: %v", err)
+	}
+	if err := service.ExcuseMember(1, "3", "cleo", time.Time{}); err != nil {
+		t.Fatalf("ExcuseMember failed: %v", err)
+	}
+
+	roster := map[string]string{"1": "Anna", "2": "Ben", "3": "Cleo", "4": "Dan"}
+	vote := &models.VoteState{Votes: map[string]string{"1": "Pasta"}}
+	pending := func(at time.Time) []string {
+		return PendingVoters(roster, vote, service.ExcusedMembers(1, at))
+	}
+
+	if got := pending(now); !reflect.DeepEqual(got, []string{"Dan"}) {
+		t.Errorf("pending voters while Ben and Cleo are away = %v, want only Dan", got)
+	}
+	if got := PresentMembers(4, service.ExcusedMembers(1, now)); got != 2 {
+		t.Errorf("present members = %d, want 2", got)
+	}
+
+	// Ben counts again once his date has come, Cleo stays away until unexcused
+	if got := pending(back); !reflect.DeepEqual(got, []string{"Ben", "Dan"}) {
+		t.Errorf("pending voters after Ben is back = %v, want Ben and Dan", got)
+	}
+	if got := PresentMembers(4, service.ExcusedMembers(1, back)); got != 3 {
+		t.Errorf("present members after Ben is back = %d, want 3", got)
+	}
+
+	if err := service.UnexcuseMember(1, "3"); err != nil {
+		t.Fatalf("UnexcuseMember failed: %v", err)
+	}
+	if got := pending(now.AddDate(1, 0, 0)); !reflect.DeepEqual(got, []string{"Ben", "Cleo", "Dan"}) {
+		t.Errorf("pending voters after Cleo is unexcused = %v, want everyone but Anna", got)
+	}
+	if err := service.UnexcuseMember(1, "3"); !errors.Is(err, ErrNotExcused) {
+		t.Errorf("unexcusing Cleo again returned %v, want ErrNotExcused", err)
+	}
+}
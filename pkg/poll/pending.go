@@ -0,0 +1,51 @@
+package poll
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/korjavin/whatsfordinner/pkg/models"
+)
+
+// Roster returns the family members who voted in any of a channel's polls, UserID -> name.
+// Telegram doesn't let bots list the members of a group, so this is the best roster the bot has.
+func (s *Service) Roster(channelID int64) (map[string]string, error) {
+	keys, err := s.store.List(fmt.Sprintf("vote:%d:", channelID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list votes: %w", err)
+	}
+
+	roster := make(map[string]string)
+	for _, key := range keys {
+		var vote models.VoteState
+		if err := s.store.Get(key, &vote); err != nil {
+			s.logger.Error("Failed to get vote %s: %v", key, err)
+			continue
+		}
+		for userID, name := range vote.VoterNames {
+			roster[userID] = name
+		}
+	}
+
+	return roster, nil
+}
+
+// PendingVoters returns the names of the roster members who haven't voted yet, sorted.
+// Excused members don't have to vote, so they're left out.
+func PendingVoters(roster map[string]string, vote *models.VoteState, excused []models.ExcusedMember) []string {
+	away := make(map[string]bool)
+	for _, member := range excused {
+		away[member.UserID] = true
+	}
+
+	var pending []string
+	for userID, name := range roster {
+		if _, voted := vote.Votes[userID]; voted || away[userID] {
+			continue
+		}
+		pending = append(pending, name)
+	}
+
+	sort.Strings(pending)
+	return pending
+}
@@ -0,0 +1,47 @@
+package poll
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/korjavin/whatsfordinner/internal/test"
+	"github.com/korjavin/whatsfordinner/pkg/models"
+)
+
+func TestPendingVotersLeavesOutVotersAndExcusedMembers(t *testing.T) {
+	service := New(test.NewStore(t))
+	options := []string{"Pasta", "Soup"}
+
+	// Everyone who voted in an earlier poll is on the roster
+	if _, err := service.CreateVote(1, "earlier", 10, options); err != nil {
+		t.Fatalf("CreateVote failed: %v", err)
+	}
+	for userID, name := range map[string]string{"1": "anna", "2": "bob", "3": "carol", "4": "dave"} {
+		if err := service.RecordVote(1, "earlier", userID, name, "Pasta"); err != nil {
+			t.Fatalf("RecordVote failed: %v", err)
+		}
+	}
+	if _, err := service.CreateVote(1, "tonight", 11, options); err != nil {
+		t.Fatalf("CreateVote failed: %v", err)
+	}
+	if err := service.RecordVote(1, "tonight", "2", "bob", "Soup"); err != nil {
+		t.Fatalf("RecordVote failed: %v", err)
+	}
+
+	roster, err := service.Roster(1)
+	if err != nil {
+		t.Fatalf("Roster failed: %v", err)
+	}
+	if len(roster) != 4 || roster["3"] != "carol" {
+		t.Fatalf("roster = %v, want the four earlier voters", roster)
+	}
+
+	vote, err := service.GetVote(1, "tonight")
+	if err != nil {
+		t.Fatalf("GetVote failed: %v", err)
+	}
+	excused := []models.ExcusedMember{{UserID: "4", Username: "dave"}}
+	if pending := PendingVoters(roster, vote, excused); !reflect.DeepEqual(pending, []string{"anna", "carol"}) {
+		t.Errorf("PendingVoters() = %v, want anna and carol", pending)
+	}
+}
@@ -1,18 +1,68 @@
 package poll
 
 import (
+	"errors"
 	"fmt"
 	"math"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/korjavin/whatsfordinner/pkg/audit"
 	"github.com/korjavin/whatsfordinner/pkg/logger"
 	"github.com/korjavin/whatsfordinner/pkg/models"
 	"github.com/korjavin/whatsfordinner/pkg/storage"
 )
 
+// ErrDinnerStarted is returned when reopening a vote whose dinner is already being cooked
+var ErrDinnerStarted = errors.New("dinner has already started")
+
+// ErrNoVote is returned when a channel has never had a vote
+var ErrNoVote = errors.New("no vote found")
+
+// ErrVoteEnded is returned when changing a vote that has already ended
+var ErrVoteEnded = errors.New("vote has already ended")
+
+// ErrPollNotRunning is returned when looking up the chat of a poll that ended or was never posted
+var ErrPollNotRunning = errors.New("poll is not running")
+
+// ErrNotWinningVoter is returned when someone who didn't vote for the winning dish volunteers to cook it
+var ErrNotWinningVoter = errors.New("user did not vote for the winning dish")
+
+// ErrNotVolunteer is returned when selecting a cook who didn't volunteer
+var ErrNotVolunteer = errors.New("user is not a volunteer")
+
+// ErrInvalidOption is returned when voting for an option that isn't on the poll
+var ErrInvalidOption = errors.New("invalid option")
+
+// ErrOptionExists is returned when adding an option that is already on the poll
+var ErrOptionExists = errors.New("option already exists")
+
+// ErrVoteOpen is returned when volunteering to cook before the vote has a winner
+var ErrVoteOpen = errors.New("vote is still open")
+
+// ErrUndoExpired is returned when undoing the close of a vote after UndoCloseWindow,
+// or of a vote that is no longer the last one
+var ErrUndoExpired = errors.New("too late to undo the close")
+
+// UndoCloseWindow is how long after a poll closed it can be reopened with the button on the close message
+const UndoCloseWindow = 2 * time.Minute
+
+// ErrRunoffLimit is returned when a tied vote has already gone through the maximum number of runoffs
+var ErrRunoffLimit = errors.New("runoff limit reached")
+
+// MaxRunoffDepth caps how many runoffs can follow each other, so that
+// repeated ties fall back to the deterministic pick instead of looping
+const MaxRunoffDepth = 1
+
+// runoffClaimTimeout is how long a claim of ClaimRunoff holds, so a close that crashed
+// before posting its runoff poll doesn't keep the vote from ever closing
+const runoffClaimTimeout = time.Minute
+
 // Service provides poll management functionality
 type Service struct {
 	store  *storage.Store
+	audit  *audit.Service
 	logger *logger.Logger
 }
 
@@ -20,6 +70,7 @@ type Service struct {
 func New(store *storage.Store) *Service {
 	return &Service{
 		store:  store,
+		audit:  audit.New(store),
 		logger: logger.New(""),
 	}
 }
@@ -40,12 +91,10 @@ func (s *Service) CreateVote(channelID int64, pollID string, messageID int, opti
 		return nil, err
 	}
 
-	// Create a direct mapping from poll ID to channel ID for easier lookup
-	pollMappingKey := fmt.Sprintf("poll_mapping:%s", pollID)
-	err = s.store.Set(pollMappingKey, channelID)
+	// Poll answers only carry the poll ID, so they're routed to the channel through this mapping
+	err = s.store.Set(pollMappingKey(pollID), channelID)
 	if err != nil {
-		s.logger.Error("Failed to create poll mapping: %v", err)
-		// Continue anyway, as this is just an optimization
+		return nil, fmt.Errorf("failed to create poll mapping: %w", err)
 	}
 
 	// Update channel state
@@ -62,6 +111,7 @@ func (s *Service) CreateVote(channelID int64, pollID string, messageID int, opti
 	}
 
 	channelState.CurrentVote = vote
+	channelState.WaitingForCook = ""
 	channelState.LastActivity = time.Now()
 
 	err = s.store.Set(channelKey, channelState)
@@ -69,35 +119,195 @@ func (s *Service) CreateVote(channelID int64, pollID string, messageID int, opti
 		return nil, err
 	}
 
+	s.audit.Record(channelID, audit.VoteCreated, "poll %s with options %s", pollID, strings.Join(options, ", "))
 	return vote, nil
 }
 
-// RecordVote records a vote from a user
-func (s *Service) RecordVote(channelID int64, pollID, userID, option string) error {
+// CreateSurpriseVote records a dish picked without a poll as an already ended vote
+// with a single option, so the cook volunteer flow works the same as after a real poll.
+// Its poll ID doesn't belong to a Telegram poll.
+func (s *Service) CreateSurpriseVote(channelID int64, dish string) (*models.VoteState, error) {
+	// Poll IDs end up in callback data split on ":", so they can't contain one
+	pollID := fmt.Sprintf("surprise-%d", time.Now().UnixNano())
+
+	_, err := s.CreateVote(channelID, pollID, 0, []string{dish})
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.EndVote(channelID, pollID, dish)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.GetVote(channelID, pollID)
+}
+
+// runoffClaimed reports whether a close holds the claim of ClaimRunoff on a vote
+func runoffClaimed(vote *models.VoteState, now time.Time) bool {
+	return !vote.RunoffClaimedAt.IsZero() && now.Sub(vote.RunoffClaimedAt) < runoffClaimTimeout
+}
+
+// ClaimRunoff claims a tied vote for settling it with a runoff, before the runoff poll is posted,
+// so a close racing this one doesn't post a second runoff poll. Until the claim is released
+// or CreateRunoff ends the vote, other closes get ErrVoteEnded.
+// It returns ErrVoteEnded if the vote ended or another close claimed it,
+// and ErrRunoffLimit if the vote may not be settled with another runoff.
+func (s *Service) ClaimRunoff(channelID int64, pollID string, now time.Time) error {
 	voteKey := fmt.Sprintf("vote:%d:%s", channelID, pollID)
 	var vote models.VoteState
-	err := s.store.Get(voteKey, &vote)
+	return s.store.Update(voteKey, &vote, func() error {
+		if !vote.EndedAt.IsZero() || runoffClaimed(&vote, now) {
+			return ErrVoteEnded
+		}
+		if vote.RunoffDepth >= MaxRunoffDepth {
+			return ErrRunoffLimit
+		}
+		vote.RunoffClaimedAt = now
+		return nil
+	})
+}
+
+// ReleaseRunoff gives up the claim of ClaimRunoff, for when the runoff poll couldn't be posted
+func (s *Service) ReleaseRunoff(channelID int64, pollID string) error {
+	voteKey := fmt.Sprintf("vote:%d:%s", channelID, pollID)
+	var vote models.VoteState
+	return s.store.Update(voteKey, &vote, func() error {
+		if vote.RunoffClaimedAt.IsZero() {
+			return storage.ErrNoChange
+		}
+		vote.RunoffClaimedAt = time.Time{}
+		return nil
+	})
+}
+
+// CreateRunoff ends a tied vote and creates a runoff vote between the tied options.
+// The runoff poll itself is posted by the caller, which passes its poll ID and message ID.
+// The caller claims the tied vote with ClaimRunoff before posting the poll.
+func (s *Service) CreateRunoff(channelID int64, prevPollID string, tiedOptions []string, pollID string, messageID int) (*models.VoteState, error) {
+	prevVote, err := s.GetVote(channelID, prevPollID)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	// Check if the option is valid
-	optionValid := false
-	for _, validOption := range vote.Options {
-		if option == validOption {
-			optionValid = true
-			break
-		}
+	if prevVote.RunoffDepth >= MaxRunoffDepth {
+		return nil, ErrRunoffLimit
+	}
+
+	// End the tied vote without a winner
+	err = s.endVote(channelID, prevPollID, "", true)
+	if err != nil {
+		return nil, err
 	}
 
-	if !optionValid {
-		return fmt.Errorf("invalid option: %s", option)
+	vote, err := s.CreateVote(channelID, pollID, messageID, tiedOptions)
+	if err != nil {
+		return nil, err
 	}
 
-	// Record the vote
-	vote.Votes[userID] = option
+	// Link the runoff to the tied vote
+	vote.RunoffOf = prevPollID
+	vote.RunoffDepth = prevVote.RunoffDepth + 1
 
-	return s.store.Set(voteKey, vote)
+	err = s.store.Set(fmt.Sprintf("vote:%d:%s", channelID, pollID), vote)
+	if err != nil {
+		return nil, err
+	}
+
+	channelKey := fmt.Sprintf("channel:%d", channelID)
+	var channelState models.ChannelState
+	err = s.store.Get(channelKey, &channelState)
+	if err != nil {
+		return nil, err
+	}
+
+	channelState.CurrentVote = vote
+	err = s.store.Set(channelKey, channelState)
+	if err != nil {
+		return nil, err
+	}
+
+	s.audit.Record(channelID, audit.RunoffCreated, "poll %s settles the tie in poll %s", pollID, prevPollID)
+	return vote, nil
+}
+
+// RecordVote records a vote from a user and remembers their name for the channel's roster
+// Polls are single-choice, so a user has exactly one option and a new vote replaces the old one.
+// It returns ErrVoteEnded if the vote has ended.
+func (s *Service) RecordVote(channelID int64, pollID, userID, username, option string) error {
+	voteKey := fmt.Sprintf("vote:%d:%s", channelID, pollID)
+	var vote models.VoteState
+	// A vote written back after a close raced it would reopen the vote,
+	// so the check and the change happen in one transaction
+	return s.store.Update(voteKey, &vote, func() error {
+		// Late answers to a closed poll don't change its outcome
+		if !vote.EndedAt.IsZero() {
+			return ErrVoteEnded
+		}
+
+		// Check if the option is valid
+		optionValid := false
+		for _, validOption := range vote.Options {
+			if option == validOption {
+				optionValid = true
+				break
+			}
+		}
+
+		if !optionValid {
+			return fmt.Errorf("%w: %s", ErrInvalidOption, option)
+		}
+
+		// Record the vote
+		if vote.Votes == nil {
+			vote.Votes = make(map[string]string)
+		}
+		vote.Votes[userID] = option
+		vote.LastVoteAt = time.Now()
+		if username != "" {
+			if vote.VoterNames == nil {
+				vote.VoterNames = make(map[string]string)
+			}
+			vote.VoterNames[userID] = username
+		}
+		return nil
+	})
+}
+
+// MatchOption finds the option a user means, by its number starting at 1 or by its text ignoring case
+func MatchOption(options []string, text string) (string, bool) {
+	text = strings.TrimSpace(text)
+	if n, err := strconv.Atoi(text); err == nil {
+		if n >= 1 && n <= len(options) {
+			return options[n-1], true
+		}
+		return "", false
+	}
+	for _, option := range options {
+		if strings.EqualFold(option, text) {
+			return option, true
+		}
+	}
+	return "", false
+}
+
+// RetractVote removes the vote of a user, e.g. when they retract their poll answer.
+// It returns ErrVoteEnded if the vote has ended.
+func (s *Service) RetractVote(channelID int64, pollID, userID string) error {
+	voteKey := fmt.Sprintf("vote:%d:%s", channelID, pollID)
+	var vote models.VoteState
+	return s.store.Update(voteKey, &vote, func() error {
+		if !vote.EndedAt.IsZero() {
+			return ErrVoteEnded
+		}
+
+		if _, ok := vote.Votes[userID]; !ok {
+			return storage.ErrNoChange
+		}
+
+		delete(vote.Votes, userID)
+		return nil
+	})
 }
 
 // GetVoteResults returns the results of a vote
@@ -109,7 +319,15 @@ func (s *Service) GetVoteResults(channelID int64, pollID string) (map[string]int
 		return nil, "", err
 	}
 
-	// Count votes for each option
+	results, winningOption := Tally(&vote)
+	return results, winningOption, nil
+}
+
+// Tally counts the votes for every option and picks the winner.
+// Options are walked in their original order so that ties go to the earliest option,
+// which makes the winner the same wherever and whenever a vote is closed.
+// The winner is empty if nobody voted.
+func Tally(vote *models.VoteState) (map[string]int, string) {
 	results := make(map[string]int)
 	for _, option := range vote.Options {
 		results[option] = 0
@@ -119,55 +337,108 @@ func (s *Service) GetVoteResults(channelID int64, pollID string) (map[string]int
 		results[option]++
 	}
 
-	// Find the winning option
 	var winningOption string
 	var maxVotes int
-	for option, count := range results {
-		if count > maxVotes {
-			maxVotes = count
+	for _, option := range vote.Options {
+		if results[option] > maxVotes {
+			maxVotes = results[option]
 			winningOption = option
 		}
 	}
 
-	return results, winningOption, nil
+	return results, winningOption
+}
+
+// GetTiedOptions returns the options sharing the most votes, in their original order.
+// It returns more than one option only when the vote ended in a tie.
+func (s *Service) GetTiedOptions(channelID int64, pollID string) ([]string, error) {
+	vote, err := s.GetVote(channelID, pollID)
+	if err != nil {
+		return nil, err
+	}
+
+	return TiedOptions(vote), nil
+}
+
+// TiedOptions returns the options of a vote sharing the most votes, in their original order
+func TiedOptions(vote *models.VoteState) []string {
+	counts := make(map[string]int)
+	maxVotes := 0
+	for _, option := range vote.Votes {
+		counts[option]++
+		if counts[option] > maxVotes {
+			maxVotes = counts[option]
+		}
+	}
+
+	if maxVotes == 0 {
+		return nil
+	}
+
+	var tied []string
+	for _, option := range vote.Options {
+		if counts[option] == maxVotes {
+			tied = append(tied, option)
+		}
+	}
+
+	return tied
 }
 
-// EndVote marks a vote as ended and records the winning dish
+// EndVote marks a vote as ended and records the winning dish.
+// It returns ErrVoteEnded if the vote was already ended or a close claimed it for a runoff.
 func (s *Service) EndVote(channelID int64, pollID, winningDish string) error {
+	return s.endVote(channelID, pollID, winningDish, false)
+}
+
+// endVote ends a vote like EndVote. Only the runoff that claimed a vote may end it while the claim holds.
+func (s *Service) endVote(channelID int64, pollID, winningDish string, runoff bool) error {
 	voteKey := fmt.Sprintf("vote:%d:%s", channelID, pollID)
 	var vote models.VoteState
-	err := s.store.Get(voteKey, &vote)
+	// Two closes racing each other must not announce the winner twice,
+	// so the check and the end happen in one transaction
+	err := s.store.Update(voteKey, &vote, func() error {
+		if !vote.EndedAt.IsZero() || (!runoff && runoffClaimed(&vote, time.Now())) {
+			return ErrVoteEnded
+		}
+
+		vote.EndedAt = time.Now()
+		vote.WinningDish = winningDish
+		vote.Results, _ = Tally(&vote)
+		return nil
+	})
 	if err != nil {
 		return err
 	}
 
-	vote.EndedAt = time.Now()
-	vote.WinningDish = winningDish
+	// Answers to the closed poll don't count anymore, so it no longer needs a route to its chat
+	if err := s.store.Delete(pollMappingKey(pollID)); err != nil {
+		s.logger.Error("Failed to delete poll mapping of %s: %v", pollID, err)
+	}
 
-	err = s.store.Set(voteKey, vote)
-	if err != nil {
-		return err
+	if winningDish == "" {
+		s.audit.Record(channelID, audit.VoteEnded, "poll %s without a winner", pollID)
+	} else {
+		s.audit.Record(channelID, audit.VoteEnded, "poll %s, %s won", pollID, winningDish)
 	}
 
 	// Update channel state
 	channelKey := fmt.Sprintf("channel:%d", channelID)
 	var channelState models.ChannelState
-	err = s.store.Get(channelKey, &channelState)
-	if err != nil {
-		return err
-	}
-
-	// Only clear current vote if it's the same as the one we're ending
-	if channelState.CurrentVote != nil && channelState.CurrentVote.PollID == pollID {
+	return s.store.Update(channelKey, &channelState, func() error {
+		// Only clear current vote if it's the same as the one we're ending.
+		// The channel keeps waiting for a cook of the winning dish after the vote is cleared.
+		if channelState.CurrentVote == nil || channelState.CurrentVote.PollID != pollID {
+			return storage.ErrNoChange
+		}
 		channelState.CurrentVote = nil
-		channelState.LastActivity = time.Now()
-		err = s.store.Set(channelKey, channelState)
-		if err != nil {
-			return err
+		channelState.WaitingForCook = ""
+		if winningDish != "" {
+			channelState.WaitingForCook = pollID
 		}
-	}
-
-	return nil
+		channelState.LastActivity = time.Now()
+		return nil
+	})
 }
 
 // AddCookVolunteer adds a cook volunteer to a vote
@@ -179,9 +450,14 @@ func (s *Service) AddCookVolunteer(channelID int64, pollID, userID string) error
 		return err
 	}
 
-	// Check if the user voted for the winning dish
-	if vote.Votes[userID] != vote.WinningDish && len(vote.Votes) > 0 {
-		return fmt.Errorf("user did not vote for the winning dish")
+	// A reopened vote has no winner to cook yet
+	if vote.EndedAt.IsZero() || vote.WinningDish == "" {
+		return ErrVoteOpen
+	}
+
+	// Check if the user voted for the winning dish, if the channel asks for it
+	if s.RestrictCookToVoters(channelID) && vote.Votes[userID] != vote.WinningDish && len(vote.Votes) > 0 {
+		return ErrNotWinningVoter
 	}
 
 	// Add the volunteer if not already added
@@ -196,6 +472,35 @@ func (s *Service) AddCookVolunteer(channelID int64, pollID, userID string) error
 	return s.store.Set(voteKey, vote)
 }
 
+// RestrictCookToVoters reports whether only people who voted for the winning dish may cook it
+func (s *Service) RestrictCookToVoters(channelID int64) bool {
+	var channelState models.ChannelState
+	err := s.store.Get(fmt.Sprintf("channel:%d", channelID), &channelState)
+	if err != nil {
+		return false
+	}
+	return channelState.RestrictCookToVoters
+}
+
+// SetRestrictCookToVoters sets whether only people who voted for the winning dish may cook it
+func (s *Service) SetRestrictCookToVoters(channelID int64, restrict bool) error {
+	channelKey := fmt.Sprintf("channel:%d", channelID)
+	var channelState models.ChannelState
+	err := s.store.Get(channelKey, &channelState)
+	if err != nil {
+		// Create new channel state if it doesn't exist
+		channelState = models.ChannelState{
+			ChannelID: channelID,
+			FridgeID:  fmt.Sprintf("fridge:%d", channelID),
+		}
+	}
+
+	channelState.RestrictCookToVoters = restrict
+	channelState.LastActivity = time.Now()
+
+	return s.store.Set(channelKey, channelState)
+}
+
 // SelectCook selects a cook from the volunteers
 func (s *Service) SelectCook(channelID int64, pollID, userID string) error {
 	voteKey := fmt.Sprintf("vote:%d:%s", channelID, pollID)
@@ -215,14 +520,77 @@ func (s *Service) SelectCook(channelID int64, pollID, userID string) error {
 	}
 
 	if !isVolunteer {
-		return fmt.Errorf("user is not a volunteer")
+		return ErrNotVolunteer
 	}
 
 	vote.SelectedCook = userID
 
+	err = s.store.Set(voteKey, vote)
+	if err != nil {
+		return err
+	}
+
+	s.audit.Record(channelID, audit.CookSelected, "%s cooks %s (poll %s)", userID, vote.WinningDish, pollID)
+	return nil
+}
+
+// ReleaseCook clears the selected cook of a vote, e.g. when the cook passes cooking on to someone else
+func (s *Service) ReleaseCook(channelID int64, pollID string) error {
+	voteKey := fmt.Sprintf("vote:%d:%s", channelID, pollID)
+	var vote models.VoteState
+	err := s.store.Get(voteKey, &vote)
+	if err != nil {
+		return err
+	}
+
+	vote.SelectedCook = ""
+
 	return s.store.Set(voteKey, vote)
 }
 
+// SetMemberCount sets the number of family members a poll threshold is based on,
+// overriding the count detected from Telegram. A count of 0 goes back to detecting it.
+func (s *Service) SetMemberCount(channelID int64, count int) error {
+	if count < 0 {
+		return fmt.Errorf("member count must not be negative, got %d", count)
+	}
+
+	channelKey := fmt.Sprintf("channel:%d", channelID)
+	var channelState models.ChannelState
+	err := s.store.Get(channelKey, &channelState)
+	if err != nil {
+		// Create new channel state if it doesn't exist
+		channelState = models.ChannelState{
+			ChannelID: channelID,
+			FridgeID:  fmt.Sprintf("fridge:%d", channelID),
+		}
+	}
+
+	channelState.MemberCount = count
+	channelState.MemberCountManual = count > 0
+	channelState.LastActivity = time.Now()
+
+	return s.store.Set(channelKey, channelState)
+}
+
+// SetDetectedMemberCount saves the member count detected from Telegram, unless an admin set one.
+// Only the member count is changed, so it can't overwrite other changes to the channel.
+func (s *Service) SetDetectedMemberCount(channelID int64, count int) error {
+	channelKey := fmt.Sprintf("channel:%d", channelID)
+	var channelState models.ChannelState
+	err := s.store.Get(channelKey, &channelState)
+	if err != nil {
+		return err
+	}
+
+	if channelState.MemberCountManual || channelState.MemberCount == count {
+		return nil
+	}
+	channelState.MemberCount = count
+
+	return s.store.Set(channelKey, channelState)
+}
+
 // CheckVoteThreshold checks if the vote has reached the threshold to be closed
 // Returns true if the threshold is reached, the winning option, and an error if any
 func (s *Service) CheckVoteThreshold(channelID int64, pollID string, channelMemberCount int, thresholdPercent float64) (bool, string, error) {
@@ -238,11 +606,17 @@ func (s *Service) CheckVoteThreshold(channelID int64, pollID string, channelMemb
 		return false, "", nil
 	}
 
+	// A reopened vote was closed too early once, so it waits for everyone
+	if !vote.ReopenedAt.IsZero() {
+		thresholdPercent = 1
+	}
+
 	// Calculate the threshold
 	threshold := int(math.Ceil(float64(channelMemberCount) * thresholdPercent))
 	s.logger.Debug("Threshold: %d (channel members: %d, threshold percent: %.2f)", threshold, channelMemberCount, thresholdPercent)
 
 	// Count the total votes
+	// Polls are single-choice, so the number of recorded votes equals the number of voters
 	totalVotes := len(vote.Votes)
 	s.logger.Debug("Total votes: %d", totalVotes)
 
@@ -272,60 +646,69 @@ func (s *Service) GetVote(channelID int64, pollID string) (*models.VoteState, er
 	return &vote, nil
 }
 
-// FindChannelByPollID finds the channel ID that contains a poll with the given ID
+// pollMappingKey returns the key of the mapping from a poll to its channel
+func pollMappingKey(pollID string) string {
+	return fmt.Sprintf("poll_mapping:%s", pollID)
+}
+
+// FindChannelByPollID finds the channel of a running poll through its poll mapping.
+// Ended polls have no mapping anymore, so late answers to them return ErrPollNotRunning
+// right away instead of searching every channel.
 func (s *Service) FindChannelByPollID(pollID string) (int64, error) {
-	// Create a direct mapping for poll ID to channel ID
-	pollMappingKey := fmt.Sprintf("poll_mapping:%s", pollID)
 	var channelID int64
-	err := s.store.Get(pollMappingKey, &channelID)
-	if err == nil {
-		// We found a direct mapping
-		return channelID, nil
+	err := s.store.Get(pollMappingKey(pollID), &channelID)
+	if errors.Is(err, storage.ErrNotFound) {
+		return 0, fmt.Errorf("%w: %s", ErrPollNotRunning, pollID)
 	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to get poll mapping: %w", err)
+	}
+	return channelID, nil
+}
 
-	// If we don't have a direct mapping, search through all channels
+// WarmPollMappings makes sure every running poll has a poll mapping, including polls
+// created before mappings existed, and deletes the mappings of every other poll,
+// like ended polls from before EndVote deleted their mapping.
+// It returns how many running polls are mapped.
+func (s *Service) WarmPollMappings() (int, error) {
 	channelKeys, err := s.store.List("channel:")
 	if err != nil {
 		return 0, fmt.Errorf("failed to list channels: %w", err)
 	}
 
+	running := make(map[string]bool)
 	for _, channelKey := range channelKeys {
 		var channelState models.ChannelState
-		err := s.store.Get(channelKey, &channelState)
-		if err != nil {
+		if err := s.store.Get(channelKey, &channelState); err != nil {
 			s.logger.Error("Failed to get channel state: %v", err)
 			continue
 		}
 
-		// Check if this channel has a current vote with this poll ID
-		if channelState.CurrentVote != nil && channelState.CurrentVote.PollID == pollID {
-			return channelState.ChannelID, nil
+		vote := channelState.CurrentVote
+		if vote == nil || !vote.EndedAt.IsZero() {
+			continue
 		}
+		if err := s.store.Set(pollMappingKey(vote.PollID), channelState.ChannelID); err != nil {
+			return 0, fmt.Errorf("failed to create poll mapping: %w", err)
+		}
+		running[vote.PollID] = true
+	}
 
-		// Also check for past votes
-		voteKeys, err := s.store.List(fmt.Sprintf("vote:%d:", channelState.ChannelID))
-		if err != nil {
-			s.logger.Error("Failed to list votes for channel %d: %v", channelState.ChannelID, err)
+	mappingKeys, err := s.store.List("poll_mapping:")
+	if err != nil {
+		return 0, fmt.Errorf("failed to list poll mappings: %w", err)
+	}
+	for _, mappingKey := range mappingKeys {
+		pollID := strings.TrimPrefix(mappingKey, "poll_mapping:")
+		if running[pollID] {
 			continue
 		}
-
-		for _, voteKey := range voteKeys {
-			var vote models.VoteState
-			err := s.store.Get(voteKey, &vote)
-			if err != nil {
-				s.logger.Error("Failed to get vote %s: %v", voteKey, err)
-				continue
-			}
-
-			if vote.PollID == pollID {
-				// Create a mapping for future lookups
-				s.store.Set(pollMappingKey, channelState.ChannelID)
-				return channelState.ChannelID, nil
-			}
+		if err := s.store.Delete(mappingKey); err != nil {
+			s.logger.Error("Failed to delete stale poll mapping %s: %v", mappingKey, err)
 		}
 	}
 
-	return 0, fmt.Errorf("channel not found for poll %s", pollID)
+	return len(running), nil
 }
 
 // GetCurrentVote gets the current vote for a channel
@@ -338,7 +721,7 @@ func (s *Service) GetCurrentVote(channelID int64) (*models.VoteState, error) {
 	}
 
 	if channelState.CurrentVote == nil {
-		return nil, fmt.Errorf("no current vote for channel %d", channelID)
+		return nil, fmt.Errorf("%w: no current vote for channel %d", ErrNoVote, channelID)
 	}
 
 	return channelState.CurrentVote, nil
@@ -356,13 +739,13 @@ func (s *Service) AddOptionToVote(channelID int64, pollID string, newOption stri
 
 	// Check if the vote has already ended
 	if !vote.EndedAt.IsZero() {
-		return nil, fmt.Errorf("vote has already ended")
+		return nil, ErrVoteEnded
 	}
 
 	// Check if the option already exists
 	for _, option := range vote.Options {
 		if option == newOption {
-			return nil, fmt.Errorf("option already exists: %s", newOption)
+			return nil, fmt.Errorf("%w: %s", ErrOptionExists, newOption)
 		}
 	}
 
@@ -377,3 +760,181 @@ func (s *Service) AddOptionToVote(channelID int64, pollID string, newOption stri
 
 	return &vote, nil
 }
+
+// tallyMessageKey returns the key of the ID of a vote's live tally message.
+// It's kept apart from the vote, so that saving it never overwrites votes recorded meanwhile.
+func tallyMessageKey(channelID int64, pollID string) string {
+	return fmt.Sprintf("tally_message:%d:%s", channelID, pollID)
+}
+
+// SetTallyMessage records the ID of the message that shows the live tally for a vote
+func (s *Service) SetTallyMessage(channelID int64, pollID string, messageID int) error {
+	return s.store.Set(tallyMessageKey(channelID, pollID), messageID)
+}
+
+// TallyMessage returns the ID of the message that shows the live tally for a vote, 0 if there is none yet
+func (s *Service) TallyMessage(channelID int64, pollID string) (int, error) {
+	var messageID int
+	err := s.store.Get(tallyMessageKey(channelID, pollID), &messageID)
+	if errors.Is(err, storage.ErrNotFound) {
+		return 0, nil
+	}
+	return messageID, err
+}
+
+// SetVoteTags records the tags a vote's options were picked for, so re-rolls can ask for the same
+func (s *Service) SetVoteTags(channelID int64, pollID string, tags []string) error {
+	voteKey := fmt.Sprintf("vote:%d:%s", channelID, pollID)
+	var vote models.VoteState
+	err := s.store.Get(voteKey, &vote)
+	if err != nil {
+		return fmt.Errorf("failed to get vote: %w", err)
+	}
+
+	vote.Tags = tags
+
+	return s.store.Set(voteKey, vote)
+}
+
+// GetLastVote returns the channel's current vote, or the most recently started one if none is running
+func (s *Service) GetLastVote(channelID int64) (*models.VoteState, error) {
+	if vote, err := s.GetCurrentVote(channelID); err == nil {
+		return s.GetVote(channelID, vote.PollID)
+	}
+
+	voteKeys, err := s.store.List(fmt.Sprintf("vote:%d:", channelID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list votes: %w", err)
+	}
+
+	var last *models.VoteState
+	for _, voteKey := range voteKeys {
+		var vote models.VoteState
+		if err := s.store.Get(voteKey, &vote); err != nil {
+			s.logger.Error("Failed to get vote %s: %v", voteKey, err)
+			continue
+		}
+		if last == nil || vote.StartedAt.After(last.StartedAt) {
+			v := vote
+			last = &v
+		}
+	}
+
+	if last == nil {
+		return nil, ErrNoVote
+	}
+	return last, nil
+}
+
+// ReopenVote reopens the channel's current or last vote after it was closed,
+// clearing the winner and cook volunteers and restoring it as the current vote.
+// Votes whose dinner is already being cooked can't be reopened.
+// A reopened vote only closes by itself once every member has voted.
+func (s *Service) ReopenVote(channelID int64) (*models.VoteState, error) {
+	vote, err := s.GetLastVote(channelID)
+	if err != nil {
+		return nil, err
+	}
+
+	channelKey := fmt.Sprintf("channel:%d", channelID)
+	var channelState models.ChannelState
+	err = s.store.Get(channelKey, &channelState)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get channel state: %w", err)
+	}
+
+	dinner := channelState.CurrentDinner
+	if vote.SelectedCook != "" || (dinner != nil && dinner.FinishedAt.IsZero() && dinner.StartedAt.After(vote.StartedAt)) {
+		return nil, ErrDinnerStarted
+	}
+
+	vote.EndedAt = time.Time{}
+	vote.WinningDish = ""
+	vote.Results = nil
+	vote.CookVolunteers = nil
+	vote.SelectedCook = ""
+	vote.ReopenedAt = time.Now()
+
+	voteKey := fmt.Sprintf("vote:%d:%s", channelID, vote.PollID)
+	if err := s.store.Set(voteKey, vote); err != nil {
+		return nil, err
+	}
+	if err := s.store.Set(pollMappingKey(vote.PollID), channelID); err != nil {
+		return nil, fmt.Errorf("failed to restore poll mapping: %w", err)
+	}
+
+	channelState.CurrentVote = vote
+	channelState.WaitingForCook = ""
+	channelState.LastActivity = time.Now()
+	if err := s.store.Set(channelKey, channelState); err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Reopened vote %s in channel %d", vote.PollID, channelID)
+	s.audit.Record(channelID, audit.VoteReopened, "poll %s", vote.PollID)
+	return vote, nil
+}
+
+// ReplacePoll moves a running vote over to a new poll, for when the message of its poll was deleted
+// or its options changed. Votes cast so far carry over to the new vote and the old one ends without a winner.
+func (s *Service) ReplacePoll(channelID int64, oldPollID, pollID string, messageID int) (*models.VoteState, error) {
+	old, err := s.GetVote(channelID, oldPollID)
+	if err != nil {
+		return nil, err
+	}
+	if !old.EndedAt.IsZero() {
+		return nil, ErrVoteEnded
+	}
+
+	vote, err := s.CreateVote(channelID, pollID, messageID, old.Options)
+	if err != nil {
+		return nil, err
+	}
+	for userID, option := range old.Votes {
+		vote.Votes[userID] = option
+	}
+	vote.VoterNames = old.VoterNames
+	vote.LastVoteAt = old.LastVoteAt
+	vote.Tags = old.Tags
+	vote.RunoffOf = old.RunoffOf
+	vote.RunoffDepth = old.RunoffDepth
+	vote.ReopenedAt = old.ReopenedAt
+
+	// The new vote is already the current one, so ending the old vote leaves it alone
+	if err := s.EndVote(channelID, oldPollID, ""); err != nil && !errors.Is(err, ErrVoteEnded) {
+		return nil, fmt.Errorf("failed to end the old vote: %w", err)
+	}
+
+	voteKey := fmt.Sprintf("vote:%d:%s", channelID, pollID)
+	if err := s.store.Set(voteKey, vote); err != nil {
+		return nil, err
+	}
+
+	channelKey := fmt.Sprintf("channel:%d", channelID)
+	var channelState models.ChannelState
+	if err := s.store.Get(channelKey, &channelState); err != nil {
+		return nil, fmt.Errorf("failed to get channel state: %w", err)
+	}
+	channelState.CurrentVote = vote
+	if err := s.store.Set(channelKey, channelState); err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Moved vote %s in channel %d to poll %s", oldPollID, channelID, pollID)
+	return vote, nil
+}
+
+// UndoClose reopens a vote that closed less than UndoCloseWindow before now,
+// like ReopenVote but only for the given poll while it is still the channel's last vote
+func (s *Service) UndoClose(channelID int64, pollID string, now time.Time) (*models.VoteState, error) {
+	vote, err := s.GetLastVote(channelID)
+	if err != nil {
+		return nil, err
+	}
+
+	if vote.PollID != pollID || vote.EndedAt.IsZero() || now.Sub(vote.EndedAt) > UndoCloseWindow {
+		return nil, ErrUndoExpired
+	}
+
+	return s.ReopenVote(channelID)
+}
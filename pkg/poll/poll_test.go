@@ -0,0 +1,420 @@
+package poll
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/korjavin/whatsfordinner/internal/test"
+	"github.com/korjavin/whatsfordinner/pkg/models"
+	"github.com/korjavin/whatsfordinner/pkg/storage"
+)
+
+func TestEndVoteOnlyEndsOnce(t *testing.T) {
+	service := New(test.NewStore(t))
+	if _, err := service.CreateVote(1, "poll", 10, []string{"Pasta", "Soup"}); err != nil {
+		t.Fatalf("CreateVote failed: %v", err)
+	}
+
+	if err := service.EndVote(1, "poll", "Pasta"); err != nil {
+		t.Fatalf("EndVote failed: %v", err)
+	}
+	if err := service.EndVote(1, "poll", "Soup"); !errors.Is(err, ErrVoteEnded) {
+		t.Fatalf("second EndVote returned %v, want ErrVoteEnded", err)
+	}
+
+	vote, err := service.GetVote(1, "poll")
+	if err != nil {
+		t.Fatalf("GetVote failed: %v", err)
+	}
+	if vote.WinningDish != "Pasta" {
+		t.Errorf("winning dish = %q, want the first close's Pasta", vote.WinningDish)
+	}
+}
+
+func TestConcurrentEndVoteEndsOnce(t *testing.T) {
+	service := New(test.NewStore(t))
+	if _, err := service.CreateVote(1, "poll", 10, []string{"Pasta", "Soup"}); err != nil {
+		t.Fatalf("CreateVote failed: %v", err)
+	}
+
+	// The idle close, the scheduled close and /vote stop can all fire at once
+	dishes := []string{"Pasta", "Soup", "Pasta", "Soup", "Pasta", "Soup", "Pasta", "Soup"}
+	errs := make(chan error, len(dishes))
+	var wg sync.WaitGroup
+	for _, dish := range dishes {
+		wg.Add(1)
+		go func(dish string) {
+			defer wg.Done()
+			errs <- service.EndVote(1, "poll", dish)
+		}(dish)
+	}
+	wg.Wait()
+	close(errs)
+
+	ended := 0
+	for err := range errs {
+		switch {
+		case err == nil:
+			ended++
+		case !errors.Is(err, ErrVoteEnded):
+			t.Errorf("EndVote returned %v, want nil or ErrVoteEnded", err)
+		}
+	}
+	if ended != 1 {
+		t.Errorf("%d closes ended the vote, want exactly 1", ended)
+	}
+}
+
+func TestVotesRacingACloseDontReopenTheVote(t *testing.T) {
+	service := New(test.NewStore(t))
+	if _, err := service.CreateVote(1, "poll", 10, []string{"Pasta", "Soup"}); err != nil {
+		t.Fatalf("CreateVote failed: %v", err)
+	}
+	if err := service.RecordVote(1, "poll", "0", "anna", "Soup"); err != nil {
+		t.Fatalf("RecordVote failed: %v", err)
+	}
+
+	// Answers and retractions keep coming in while the scheduler closes the poll
+	const voters = 8
+	recorded := make(chan string, voters)
+	var wg sync.WaitGroup
+	for i := 1; i <= voters; i++ {
+		wg.Add(1)
+		go func(userID string) {
+			defer wg.Done()
+			err := service.RecordVote(1, "poll", userID, "", "Pasta")
+			switch {
+			case err == nil:
+				recorded <- userID
+			case !errors.Is(err, ErrVoteEnded):
+				t.Errorf("RecordVote returned %v, want nil or ErrVoteEnded", err)
+			}
+		}(strconv.Itoa(i))
+	}
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		if err := service.RetractVote(1, "poll", "0"); err != nil && !errors.Is(err, ErrVoteEnded) {
+			t.Errorf("RetractVote returned %v, want nil or ErrVoteEnded", err)
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		if err := service.EndVote(1, "poll", "Pasta"); err != nil {
+			t.Errorf("EndVote failed: %v", err)
+		}
+	}()
+	wg.Wait()
+	close(recorded)
+
+	vote, err := service.GetVote(1, "poll")
+	if err != nil {
+		t.Fatalf("GetVote failed: %v", err)
+	}
+	if vote.EndedAt.IsZero() {
+		t.Fatalf("the vote was reopened by a vote written back after the close")
+	}
+	for userID := range recorded {
+		if vote.Votes[userID] != "Pasta" {
+			t.Errorf("accepted vote of %s is missing from %v", userID, vote.Votes)
+		}
+	}
+	total := 0
+	for _, count := range vote.Results {
+		total += count
+	}
+	if total != len(vote.Votes) {
+		t.Errorf("results %v count %d votes, the vote has %d", vote.Results, total, len(vote.Votes))
+	}
+}
+
+func TestSetDetectedMemberCountOnlySavesTheCount(t *testing.T) {
+	store := test.NewStore(t)
+	service := New(store)
+	if err := store.Set("channel:1", models.ChannelState{ChannelID: 1, MemberCount: 3, PollQuestion: "Dinner?"}); err != nil {
+		t.Fatalf("failed to save channel: %v", err)
+	}
+
+	if err := service.SetDetectedMemberCount(1, 5); err != nil {
+		t.Fatalf("SetDetectedMemberCount failed: %v", err)
+	}
+
+	var channelState models.ChannelState
+	if err := store.Get("channel:1", &channelState); err != nil {
+		t.Fatalf("failed to get channel: %v", err)
+	}
+	if channelState.MemberCount != 5 || channelState.PollQuestion != "Dinner?" {
+		t.Errorf("channel = %+v, want member count 5 and the question kept", channelState)
+	}
+
+	// A count set by an admin sticks
+	channelState.MemberCountManual = true
+	channelState.MemberCount = 4
+	if err := store.Set("channel:1", channelState); err != nil {
+		t.Fatalf("failed to save channel: %v", err)
+	}
+	if err := service.SetDetectedMemberCount(1, 7); err != nil {
+		t.Fatalf("SetDetectedMemberCount failed: %v", err)
+	}
+	if err := store.Get("channel:1", &channelState); err != nil {
+		t.Fatalf("failed to get channel: %v", err)
+	}
+	if channelState.MemberCount != 4 {
+		t.Errorf("member count = %d, want the manual 4", channelState.MemberCount)
+	}
+}
+
+func TestTallyBreaksTiesByOptionOrder(t *testing.T) {
+	vote := &models.VoteState{
+		Options: []string{"Soup", "Curry", "Pasta"},
+		Votes:   map[string]string{"1": "Pasta", "2": "Curry", "3": "Pasta", "4": "Curry", "5": "Soup"},
+	}
+
+	// Votes are a map, so run it often enough that map order would show
+	for i := 0; i < 100; i++ {
+		if _, winner := Tally(vote); winner != "Curry" {
+			t.Fatalf("run %d: winner = %q, want Curry, the earliest of the tied options", i, winner)
+		}
+	}
+
+	tied := TiedOptions(vote)
+	if len(tied) != 2 || tied[0] != "Curry" || tied[1] != "Pasta" {
+		t.Errorf("TiedOptions() = %v, want [Curry Pasta]", tied)
+	}
+	want := "🤝 It's a tie between Curry and Pasta; going with *Curry*, the first option on the poll.\n"
+	if got := FormatTie(tied, "Curry"); got != want {
+		t.Errorf("FormatTie() = %q, want %q", got, want)
+	}
+}
+
+func TestNoTieWithAClearWinner(t *testing.T) {
+	vote := &models.VoteState{
+		Options: []string{"Soup", "Curry"},
+		Votes:   map[string]string{"1": "Curry", "2": "Curry", "3": "Soup"},
+	}
+
+	if tied := TiedOptions(vote); len(tied) != 1 {
+		t.Errorf("TiedOptions() = %v, want only the winner", tied)
+	}
+	if got := FormatTie(TiedOptions(vote), "Curry"); got != "" {
+		t.Errorf("FormatTie() = %q, want no tie announced", got)
+	}
+}
+
+func TestCreateRunoffLinksToTiedVote(t *testing.T) {
+	service := New(test.NewStore(t))
+	if _, err := service.CreateVote(1, "poll", 10, []string{"Pasta", "Soup", "Curry"}); err != nil {
+		t.Fatalf("CreateVote failed: %v", err)
+	}
+
+	runoff, err := service.CreateRunoff(1, "poll", []string{"Pasta", "Curry"}, "runoff", 11)
+	if err != nil {
+		t.Fatalf("CreateRunoff failed: %v", err)
+	}
+	if runoff.RunoffOf != "poll" || runoff.RunoffDepth != 1 || len(runoff.Options) != 2 {
+		t.Errorf("runoff = %+v, want a vote between the two tied options linked to poll", *runoff)
+	}
+
+	tied, err := service.GetVote(1, "poll")
+	if err != nil {
+		t.Fatalf("GetVote failed: %v", err)
+	}
+	if tied.EndedAt.IsZero() || tied.WinningDish != "" {
+		t.Errorf("tied vote = %+v, want it ended without a winner", *tied)
+	}
+	current, err := service.GetCurrentVote(1)
+	if err != nil || current.PollID != "runoff" {
+		t.Errorf("current vote = %v (%v), want the runoff", current, err)
+	}
+
+	// A tied runoff falls back to the option order instead of another runoff
+	if err := service.ClaimRunoff(1, "runoff", time.Now()); !errors.Is(err, ErrRunoffLimit) {
+		t.Errorf("ClaimRunoff() of a runoff returned %v, want ErrRunoffLimit", err)
+	}
+	if _, err := service.CreateRunoff(1, "runoff", []string{"Pasta", "Curry"}, "runoff-2", 12); !errors.Is(err, ErrRunoffLimit) {
+		t.Errorf("second runoff returned %v, want ErrRunoffLimit", err)
+	}
+}
+
+func TestErrorsWrapSentinels(t *testing.T) {
+	store := test.NewStore(t)
+	service := New(store)
+
+	// A missing key keeps the storage error through the poll service
+	if _, err := service.GetVote(1, "missing"); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("GetVote of a missing vote returned %v, want storage.ErrNotFound", err)
+	}
+
+	if err := store.Set("channel:1", models.ChannelState{ChannelID: 1}); err != nil {
+		t.Fatalf("failed to save channel: %v", err)
+	}
+	if _, err := service.GetCurrentVote(1); !errors.Is(err, ErrNoVote) {
+		t.Errorf("GetCurrentVote without a vote returned %v, want ErrNoVote", err)
+	}
+
+	if _, err := service.CreateVote(1, "poll", 10, []string{"Pasta", "Soup"}); err != nil {
+		t.Fatalf("CreateVote failed: %v", err)
+	}
+	err := service.RecordVote(1, "poll", "1", "", "Curry")
+	if !errors.Is(err, ErrInvalidOption) {
+		t.Errorf("RecordVote of an unknown option returned %v, want ErrInvalidOption", err)
+	}
+	if err == nil || !strings.Contains(err.Error(), "Curry") {
+		t.Errorf("error %v doesn't name the option", err)
+	}
+	if _, err := service.AddOptionToVote(1, "poll", "Soup"); !errors.Is(err, ErrOptionExists) {
+		t.Errorf("adding an existing option returned %v, want ErrOptionExists", err)
+	}
+
+	if err := service.EndVote(1, "poll", "Pasta"); err != nil {
+		t.Fatalf("EndVote failed: %v", err)
+	}
+	if _, err := service.AddOptionToVote(1, "poll", "Curry"); !errors.Is(err, ErrVoteEnded) {
+		t.Errorf("adding an option to an ended vote returned %v, want ErrVoteEnded", err)
+	}
+	if err := service.SelectCook(1, "poll", "2"); !errors.Is(err, ErrNotVolunteer) {
+		t.Errorf("selecting someone who didn't volunteer returned %v, want ErrNotVolunteer", err)
+	}
+}
+
+func TestUndoCloseOnlyWithinTheWindow(t *testing.T) {
+	service := New(test.NewStore(t))
+	closeVote := func(pollID string) time.Time {
+		t.Helper()
+		if _, err := service.CreateVote(1, pollID, 10, []string{"Pasta", "Soup"}); err != nil {
+			t.Fatalf("CreateVote failed: %v", err)
+		}
+		if err := service.RecordVote(1, pollID, "1", "anna", "Pasta"); err != nil {
+			t.Fatalf("RecordVote failed: %v", err)
+		}
+		if err := service.EndVote(1, pollID, "Pasta"); err != nil {
+			t.Fatalf("EndVote failed: %v", err)
+		}
+		vote, err := service.GetVote(1, pollID)
+		if err != nil {
+			t.Fatalf("GetVote failed: %v", err)
+		}
+		return vote.EndedAt
+	}
+
+	endedAt := closeVote("early")
+	vote, err := service.UndoClose(1, "early", endedAt.Add(UndoCloseWindow-time.Second))
+	if err != nil {
+		t.Fatalf("UndoClose within the window failed: %v", err)
+	}
+	current, err := service.GetCurrentVote(1)
+	if err != nil {
+		t.Fatalf("GetCurrentVote failed: %v", err)
+	}
+	if current.PollID != "early" || !vote.EndedAt.IsZero() || vote.WinningDish != "" || len(vote.Votes) != 1 {
+		t.Errorf("reopened vote = %+v, current %s, want early open again with its vote", *vote, current.PollID)
+	}
+
+	endedAt = closeVote("late")
+	if _, err := service.UndoClose(1, "late", endedAt.Add(UndoCloseWindow+time.Second)); !errors.Is(err, ErrUndoExpired) {
+		t.Errorf("UndoClose after the window returned %v, want ErrUndoExpired", err)
+	}
+	// The earlier poll is no longer the last one
+	if _, err := service.UndoClose(1, "early", endedAt); !errors.Is(err, ErrUndoExpired) {
+		t.Errorf("UndoClose of an older poll returned %v, want ErrUndoExpired", err)
+	}
+
+	endedAt = closeVote("cooking")
+	if err := service.AddCookVolunteer(1, "cooking", "1"); err != nil {
+		t.Fatalf("AddCookVolunteer failed: %v", err)
+	}
+	if err := service.SelectCook(1, "cooking", "1"); err != nil {
+		t.Fatalf("SelectCook failed: %v", err)
+	}
+	if _, err := service.UndoClose(1, "cooking", endedAt); !errors.Is(err, ErrDinnerStarted) {
+		t.Errorf("UndoClose after a cook was picked returned %v, want ErrDinnerStarted", err)
+	}
+}
+
+func TestReplacePollRefusesAnEndedVote(t *testing.T) {
+	service := New(test.NewStore(t))
+	if _, err := service.CreateVote(1, "poll", 10, []string{"Pasta", "Soup"}); err != nil {
+		t.Fatalf("CreateVote failed: %v", err)
+	}
+	if err := service.EndVote(1, "poll", "Pasta"); err != nil {
+		t.Fatalf("EndVote failed: %v", err)
+	}
+
+	if _, err := service.ReplacePoll(1, "poll", "new-poll", 11); !errors.Is(err, ErrVoteEnded) {
+		t.Fatalf("ReplacePoll returned %v, want ErrVoteEnded", err)
+	}
+	if _, err := service.GetCurrentVote(1); !errors.Is(err, ErrNoVote) {
+		t.Errorf("GetCurrentVote returned %v, want no vote running", err)
+	}
+}
+
+func TestPollMappingSurvivesRestart(t *testing.T) {
+	dir := t.TempDir()
+	store, err := storage.New(dir, false)
+	if err != nil {
+		t.Fatalf("failed to open store: %v", err)
+	}
+	service := New(store)
+	if _, err := service.CreateVote(-42, "poll", 10, []string{"Pasta", "Soup"}); err != nil {
+		t.Fatalf("CreateVote failed: %v", err)
+	}
+	if _, err := service.CreateVote(-43, "ended", 11, []string{"Pasta", "Soup"}); err != nil {
+		t.Fatalf("CreateVote failed: %v", err)
+	}
+	if err := service.EndVote(-43, "ended", ""); err != nil {
+		t.Fatalf("EndVote failed: %v", err)
+	}
+	var mapped int64
+	if err := store.Get(pollMappingKey("ended"), &mapped); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("mapping lookup of the ended poll returned %v, want it deleted by EndVote", err)
+	}
+	// A mapping whose vote is gone is stale, and so is one left behind by an ended vote
+	if err := store.Set(pollMappingKey("gone"), int64(-44)); err != nil {
+		t.Fatalf("failed to save mapping: %v", err)
+	}
+	if err := store.Set(pollMappingKey("ended"), int64(-43)); err != nil {
+		t.Fatalf("failed to save mapping: %v", err)
+	}
+	if err := store.Close(); err != nil {
+		t.Fatalf("failed to close store: %v", err)
+	}
+
+	// Restart on the same directory
+	store, err = storage.New(dir, false)
+	if err != nil {
+		t.Fatalf("failed to reopen store: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	service = New(store)
+
+	if mapped, err := service.WarmPollMappings(); err != nil || mapped != 1 {
+		t.Fatalf("WarmPollMappings() = %d, %v, want the one running poll", mapped, err)
+	}
+
+	channelID, err := service.FindChannelByPollID("poll")
+	if err != nil || channelID != -42 {
+		t.Fatalf("FindChannelByPollID() = %d, %v, want -42", channelID, err)
+	}
+	if err := service.RecordVote(channelID, "poll", "1", "anna", "Soup"); err != nil {
+		t.Fatalf("RecordVote failed: %v", err)
+	}
+	vote, err := service.GetVote(-42, "poll")
+	if err != nil || vote.Votes["1"] != "Soup" {
+		t.Errorf("votes = %v (%v), want anna's vote for Soup", vote, err)
+	}
+
+	// The ended poll isn't looked up anymore, so late answers to it are dropped
+	if err := store.Get(pollMappingKey("ended"), &mapped); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("mapping lookup of the ended poll returned %v, want it deleted by WarmPollMappings", err)
+	}
+	if _, err := service.FindChannelByPollID("ended"); !errors.Is(err, ErrPollNotRunning) {
+		t.Errorf("FindChannelByPollID() of the ended poll returned %v, want ErrPollNotRunning", err)
+	}
+	if err := store.Get(pollMappingKey("gone"), &mapped); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("stale mapping lookup returned %v, want it deleted", err)
+	}
+}
@@ -0,0 +1,81 @@
+package poll
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/korjavin/whatsfordinner/pkg/models"
+)
+
+// DefaultQuestion is the poll question used when a channel hasn't set its own
+const DefaultQuestion = models.DefaultPollQuestion
+
+// MaxQuestionLength is Telegram's limit for poll questions
+const MaxQuestionLength = 300
+
+// ExpandQuestion replaces the placeholders in a poll question:
+// {date} becomes e.g. "Monday, January 2" and {weekday} becomes e.g. "Monday"
+func ExpandQuestion(question string, now time.Time) string {
+	replacer := strings.NewReplacer(
+		"{date}", now.Format("Monday, January 2"),
+		"{weekday}", now.Format("Monday"),
+	)
+	return replacer.Replace(question)
+}
+
+// ValidateQuestion checks that a poll question fits Telegram's 1-300 character limit
+// once its placeholders are expanded
+func ValidateQuestion(question string) error {
+	expanded := strings.TrimSpace(ExpandQuestion(question, time.Now()))
+	length := utf8.RuneCountInString(expanded)
+	if length == 0 {
+		return fmt.Errorf("poll question can't be empty")
+	}
+	if length > MaxQuestionLength {
+		return fmt.Errorf("poll question is %d characters long, the limit is %d", length, MaxQuestionLength)
+	}
+	return nil
+}
+
+// GetPollQuestion returns the expanded poll question for a channel,
+// falling back to DefaultQuestion if none is set
+func (s *Service) GetPollQuestion(channelID int64) string {
+	question := DefaultQuestion
+
+	var channelState models.ChannelState
+	err := s.store.Get(fmt.Sprintf("channel:%d", channelID), &channelState)
+	if err == nil {
+		question = channelState.Effective(models.DefaultSettings()).PollQuestion
+	}
+
+	return ExpandQuestion(question, time.Now())
+}
+
+// SetPollQuestion sets the poll question for a channel
+// An empty question resets it to DefaultQuestion
+func (s *Service) SetPollQuestion(channelID int64, question string) error {
+	question = strings.TrimSpace(question)
+	if question != "" {
+		if err := ValidateQuestion(question); err != nil {
+			return err
+		}
+	}
+
+	channelKey := fmt.Sprintf("channel:%d", channelID)
+	var channelState models.ChannelState
+	err := s.store.Get(channelKey, &channelState)
+	if err != nil {
+		// Create new channel state if it doesn't exist
+		channelState = models.ChannelState{
+			ChannelID: channelID,
+			FridgeID:  fmt.Sprintf("fridge:%d", channelID),
+		}
+	}
+
+	channelState.PollQuestion = question
+	channelState.LastActivity = time.Now()
+
+	return s.store.Set(channelKey, channelState)
+}
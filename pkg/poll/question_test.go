@@ -0,0 +1,71 @@
+package poll
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/korjavin/whatsfordinner/internal/test"
+)
+
+func TestExpandQuestion(t *testing.T) {
+	now := time.Date(2024, 6, 3, 15, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		question string
+		want     string
+	}{
+		{"Dinner for {date}?", "Dinner for Monday, June 3?"},
+		{"What's cooking this {weekday}?", "What's cooking this Monday?"},
+		{"{weekday} {weekday}", "Monday Monday"},
+		{DefaultQuestion, DefaultQuestion},
+		{"{unknown}", "{unknown}"},
+	}
+	for _, tt := range tests {
+		if got := ExpandQuestion(tt.question, now); got != tt.want {
+			t.Errorf("ExpandQuestion(%q) = %q, want %q", tt.question, got, tt.want)
+		}
+	}
+}
+
+func TestPollQuestionDefaultAndOverride(t *testing.T) {
+	service := New(test.NewStore(t))
+
+	if got := service.GetPollQuestion(1); got != DefaultQuestion {
+		t.Errorf("GetPollQuestion() = %q for a new channel, want %q", got, DefaultQuestion)
+	}
+
+	if err := service.SetPollQuestion(1, "  Pizza night?  "); err != nil {
+		t.Fatalf("SetPollQuestion failed: %v", err)
+	}
+	if got := service.GetPollQuestion(1); got != "Pizza night?" {
+		t.Errorf("GetPollQuestion() = %q, want the question that was set", got)
+	}
+	if got := service.GetPollQuestion(2); got != DefaultQuestion {
+		t.Errorf("GetPollQuestion() = %q for another channel, want %q", got, DefaultQuestion)
+	}
+
+	// An empty question resets it
+	if err := service.SetPollQuestion(1, ""); err != nil {
+		t.Fatalf("SetPollQuestion failed: %v", err)
+	}
+	if got := service.GetPollQuestion(1); got != DefaultQuestion {
+		t.Errorf("GetPollQuestion() = %q after a reset, want %q", got, DefaultQuestion)
+	}
+}
+
+func TestValidateQuestion(t *testing.T) {
+	if err := ValidateQuestion(strings.Repeat("é", MaxQuestionLength)); err != nil {
+		t.Errorf("a question of %d characters was rejected: %v", MaxQuestionLength, err)
+	}
+	if err := ValidateQuestion(strings.Repeat("a", MaxQuestionLength+1)); err == nil {
+		t.Error("a question over the limit was accepted")
+	}
+	if err := ValidateQuestion("   "); err == nil {
+		t.Error("an empty question was accepted")
+	}
+	// Placeholders count as the text they expand to
+	if err := ValidateQuestion(strings.Repeat("a", MaxQuestionLength-5) + "{date}"); err == nil {
+		t.Error("a question that is too long once expanded was accepted")
+	}
+}
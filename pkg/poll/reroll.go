@@ -0,0 +1,129 @@
+package poll
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/korjavin/whatsfordinner/pkg/audit"
+	"github.com/korjavin/whatsfordinner/pkg/models"
+)
+
+// ErrRerollLimit is returned when a dinner poll has already been re-rolled the maximum number of times
+var ErrRerollLimit = errors.New("reroll limit reached")
+
+// MaxRerolls caps how many times a dinner poll can be replaced by new suggestions
+const MaxRerolls = 2
+
+// RerollVote ends the channel's current vote without a winner and remembers its options
+// as rejected, so the next suggestions can avoid them
+func (s *Service) RerollVote(channelID int64, pollID string) (*models.VoteState, error) {
+	channelKey := fmt.Sprintf("channel:%d", channelID)
+	var channelState models.ChannelState
+	err := s.store.Get(channelKey, &channelState)
+	if err != nil {
+		return nil, err
+	}
+
+	// Only the open poll can be re-rolled, older buttons stay harmless
+	if channelState.CurrentVote == nil || channelState.CurrentVote.PollID != pollID {
+		return nil, ErrVoteEnded
+	}
+	if channelState.Rerolls >= MaxRerolls {
+		return nil, ErrRerollLimit
+	}
+
+	voteKey := fmt.Sprintf("vote:%d:%s", channelID, pollID)
+	var vote models.VoteState
+	err = s.store.Get(voteKey, &vote)
+	if err != nil {
+		return nil, err
+	}
+	if !vote.EndedAt.IsZero() {
+		return nil, ErrVoteEnded
+	}
+
+	vote.EndedAt = time.Now()
+	err = s.store.Set(voteKey, vote)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, option := range vote.Options {
+		if !containsFold(channelState.RejectedDishes, option) {
+			channelState.RejectedDishes = append(channelState.RejectedDishes, option)
+		}
+	}
+	channelState.Rerolls++
+	channelState.CurrentVote = nil
+	channelState.LastActivity = time.Now()
+
+	err = s.store.Set(channelKey, channelState)
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Re-rolled vote %s for channel %d (%d/%d)", pollID, channelID, channelState.Rerolls, MaxRerolls)
+	s.audit.Record(channelID, audit.VoteRerolled, "poll %s, rejected %s", pollID, strings.Join(vote.Options, ", "))
+	return &vote, nil
+}
+
+// RejectedDishes returns the dishes the channel re-rolled away since the last dinner started
+func (s *Service) RejectedDishes(channelID int64) []string {
+	var channelState models.ChannelState
+	err := s.store.Get(fmt.Sprintf("channel:%d", channelID), &channelState)
+	if err != nil {
+		return nil
+	}
+	return channelState.RejectedDishes
+}
+
+// RerollsLeft returns how many more times the channel's dinner poll can be re-rolled
+func (s *Service) RerollsLeft(channelID int64) int {
+	var channelState models.ChannelState
+	err := s.store.Get(fmt.Sprintf("channel:%d", channelID), &channelState)
+	if err != nil {
+		return MaxRerolls
+	}
+	if channelState.Rerolls >= MaxRerolls {
+		return 0
+	}
+	return MaxRerolls - channelState.Rerolls
+}
+
+// ClearRejections forgets the rejected dishes and re-roll count, so a fresh dinner starts over
+func (s *Service) ClearRejections(channelID int64) error {
+	channelKey := fmt.Sprintf("channel:%d", channelID)
+	var channelState models.ChannelState
+	err := s.store.Get(channelKey, &channelState)
+	if err != nil {
+		// Nothing to clear
+		return nil
+	}
+
+	if len(channelState.RejectedDishes) == 0 && channelState.Rerolls == 0 {
+		return nil
+	}
+
+	channelState.RejectedDishes = nil
+	channelState.Rerolls = 0
+	channelState.LastActivity = time.Now()
+
+	return s.store.Set(channelKey, channelState)
+}
+
+// IsRejected reports whether a dish is among the rejected dishes, ignoring case
+func IsRejected(rejected []string, dish string) bool {
+	return containsFold(rejected, dish)
+}
+
+func containsFold(list []string, s string) bool {
+	s = strings.TrimSpace(s)
+	for _, item := range list {
+		if strings.EqualFold(strings.TrimSpace(item), s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,149 @@
+package poll
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/korjavin/whatsfordinner/pkg/messages"
+	"github.com/korjavin/whatsfordinner/pkg/models"
+)
+
+// FormatTally formats the current vote counts of a vote, one line per option
+// in the original option order
+func FormatTally(vote *models.VoteState) string {
+	counts := make(map[string]int)
+	for _, option := range vote.Votes {
+		counts[option]++
+	}
+
+	text := fmt.Sprintf("📊 Current votes (%d so far):\n\n", len(vote.Votes))
+	for _, option := range vote.Options {
+		text += fmt.Sprintf("• %s: %d\n", messages.EscapeMarkdown(option), counts[option])
+	}
+
+	return text
+}
+
+// FormatResults formats the final results of a vote for the poll-close announcement,
+// naming the winner and listing every option sorted by votes
+func FormatResults(results map[string]int, winningOption string) string {
+	options := make([]string, 0, len(results))
+	totalVotes := 0
+	for option, count := range results {
+		options = append(options, option)
+		totalVotes += count
+	}
+
+	sort.Slice(options, func(i, j int) bool {
+		if results[options[i]] != results[options[j]] {
+			return results[options[i]] > results[options[j]]
+		}
+		return options[i] < options[j]
+	})
+
+	text := fmt.Sprintf("%s won with %d of %d votes.\n\n", messages.Bold(winningOption), results[winningOption], totalVotes)
+	for _, option := range options {
+		text += fmt.Sprintf("• %s: %d\n", messages.EscapeMarkdown(option), results[option])
+	}
+
+	return text
+}
+
+// FormatTie announces how a tie between the given options was broken.
+// It returns an empty string when there was no tie.
+func FormatTie(tied []string, winningOption string) string {
+	if len(tied) < 2 {
+		return ""
+	}
+
+	names := messages.EscapeMarkdown(strings.Join(tied[:len(tied)-1], ", ") + " and " + tied[len(tied)-1])
+	return fmt.Sprintf("🤝 It's a tie between %s; going with %s, the first option on the poll.\n", names, messages.Bold(winningOption))
+}
+
+// Debouncer coalesces bursts of calls for the same key into a single call.
+// It is used to throttle live tally edits so we don't hit Telegram rate limits.
+// Calls for the same key never run at the same time, so a slow call can't race the next one.
+type Debouncer struct {
+	interval time.Duration
+	keys     map[string]*debounced
+	running  sync.WaitGroup
+	stopped  bool
+	mu       sync.Mutex
+}
+
+// debounced is the state of a key that has a call waiting or running
+type debounced struct {
+	next  func() // The call to run next, nil if none is waiting
+	timer *time.Timer
+}
+
+// NewDebouncer creates a new debouncer that runs at most one call per key per interval
+func NewDebouncer(interval time.Duration) *Debouncer {
+	return &Debouncer{
+		interval: interval,
+		keys:     make(map[string]*debounced),
+	}
+}
+
+// Trigger schedules fn to run for key once the interval has passed.
+// If a call for the same key is already waiting, fn replaces it and
+// the original deadline is kept. If one is running, fn runs an interval after it finished.
+func (d *Debouncer) Trigger(key string, fn func()) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.stopped {
+		return
+	}
+	if state, ok := d.keys[key]; ok {
+		state.next = fn
+		return
+	}
+
+	d.keys[key] = &debounced{next: fn}
+	d.schedule(key)
+}
+
+// schedule runs the waiting call for key once the interval has passed
+func (d *Debouncer) schedule(key string) {
+	state := d.keys[key]
+	state.timer = time.AfterFunc(d.interval, func() {
+		d.mu.Lock()
+		if d.stopped {
+			d.mu.Unlock()
+			return
+		}
+		fn := state.next
+		state.next = nil
+		d.running.Add(1)
+		d.mu.Unlock()
+
+		fn()
+		d.running.Done()
+
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		if state.next == nil || d.stopped {
+			delete(d.keys, key)
+			return
+		}
+		d.schedule(key)
+	})
+}
+
+// Stop drops the waiting calls and waits for the running ones to finish.
+// Calls triggered after Stop are ignored.
+func (d *Debouncer) Stop() {
+	d.mu.Lock()
+	d.stopped = true
+	for key, state := range d.keys {
+		state.timer.Stop()
+		delete(d.keys, key)
+	}
+	d.mu.Unlock()
+
+	d.running.Wait()
+}
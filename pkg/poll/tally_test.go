@@ -0,0 +1,158 @@
+package poll
+
+import (
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/korjavin/whatsfordinner/internal/test"
+	"github.com/korjavin/whatsfordinner/pkg/models"
+)
+
+func TestTallyReflectsRecordedVotes(t *testing.T) {
+	service := New(test.NewStore(t))
+	if _, err := service.CreateVote(1, "poll", 10, []string{"Pasta", "Soup", "Curry"}); err != nil {
+		t.Fatalf("CreateVote failed: %v", err)
+	}
+
+	for userID, option := range map[string]string{"1": "Soup", "2": "Pasta", "3": "Soup", "4": "Curry"} {
+		if err := service.RecordVote(1, "poll", userID, "", option); err != nil {
+			t.Fatalf("RecordVote failed: %v", err)
+		}
+	}
+	// A changed answer replaces the old one and a retracted one doesn't count
+	if err := service.RecordVote(1, "poll", "2", "", "Soup"); err != nil {
+		t.Fatalf("RecordVote failed: %v", err)
+	}
+	if err := service.RetractVote(1, "poll", "4"); err != nil {
+		t.Fatalf("RetractVote failed: %v", err)
+	}
+
+	vote, err := service.GetVote(1, "poll")
+	if err != nil {
+		t.Fatalf("GetVote failed: %v", err)
+	}
+	want := "📊 Current votes (3 so far):\n\n• Pasta: 0\n• Soup: 3\n• Curry: 0\n"
+	if got := FormatTally(vote); got != want {
+		t.Errorf("FormatTally() = %q, want %q", got, want)
+	}
+}
+
+func TestTallyMessageDoesNotOverwriteVote(t *testing.T) {
+	service := New(test.NewStore(t))
+	if _, err := service.CreateVote(1, "poll", 10, []string{"Pasta", "Soup"}); err != nil {
+		t.Fatalf("CreateVote failed: %v", err)
+	}
+
+	if id, err := service.TallyMessage(1, "poll"); err != nil || id != 0 {
+		t.Fatalf("TallyMessage() = %d, %v before a tally was sent, want 0, nil", id, err)
+	}
+	if err := service.RecordVote(1, "poll", "1", "", "Soup"); err != nil {
+		t.Fatalf("RecordVote failed: %v", err)
+	}
+	if err := service.EndVote(1, "poll", "Soup"); err != nil {
+		t.Fatalf("EndVote failed: %v", err)
+	}
+	if err := service.SetTallyMessage(1, "poll", 42); err != nil {
+		t.Fatalf("SetTallyMessage failed: %v", err)
+	}
+
+	if id, err := service.TallyMessage(1, "poll"); err != nil || id != 42 {
+		t.Errorf("TallyMessage() = %d, %v, want 42, nil", id, err)
+	}
+	vote, err := service.GetVote(1, "poll")
+	if err != nil {
+		t.Fatalf("GetVote failed: %v", err)
+	}
+	if vote.EndedAt.IsZero() || vote.WinningDish != "Soup" || len(vote.Votes) != 1 {
+		t.Errorf("saving the tally message changed the vote: %+v", vote)
+	}
+}
+
+func TestFormatResults(t *testing.T) {
+	got := FormatResults(map[string]int{"Soup": 1, "Pasta": 2, "Curry": 1}, "Pasta")
+	want := "*Pasta* won with 2 of 4 votes.\n\n• Pasta: 2\n• Curry: 1\n• Soup: 1\n"
+	if got != want {
+		t.Errorf("FormatResults() = %q, want %q", got, want)
+	}
+}
+
+func TestFormatTallyAndResultsEscapeDishNames(t *testing.T) {
+	vote := &models.VoteState{Options: []string{"Mac_Cheese", "2*2 Soup"}, Votes: map[string]string{"1": "2*2 Soup"}}
+	if got, want := FormatTally(vote), "📊 Current votes (1 so far):\n\n• Mac\\_Cheese: 0\n• 2\\*2 Soup: 1\n"; got != want {
+		t.Errorf("FormatTally() = %q, want %q", got, want)
+	}
+
+	got := FormatResults(map[string]int{"Mac_Cheese": 0, "2*2 Soup": 1}, "2*2 Soup")
+	want := "*2*\\**2 Soup* won with 1 of 1 votes.\n\n• 2\\*2 Soup: 1\n• Mac\\_Cheese: 0\n"
+	if got != want {
+		t.Errorf("FormatResults() = %q, want %q", got, want)
+	}
+}
+
+func TestDebouncerCoalescesCalls(t *testing.T) {
+	d := NewDebouncer(20 * time.Millisecond)
+
+	var mu sync.Mutex
+	var calls []string
+	for _, name := range []string{"first", "second", "last"} {
+		d.Trigger("poll", func() {
+			mu.Lock()
+			defer mu.Unlock()
+			calls = append(calls, name)
+		})
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	mu.Lock()
+	defer mu.Unlock()
+	if strings.Join(calls, ",") != "last" {
+		t.Errorf("ran %v, want only the last call", calls)
+	}
+}
+
+func TestDebouncerNeverRunsCallsForAKeyAtOnce(t *testing.T) {
+	d := NewDebouncer(5 * time.Millisecond)
+
+	var running, maxRunning, done int32
+	slow := func() {
+		n := atomic.AddInt32(&running, 1)
+		for {
+			m := atomic.LoadInt32(&maxRunning)
+			if n <= m || atomic.CompareAndSwapInt32(&maxRunning, m, n) {
+				break
+			}
+		}
+		time.Sleep(30 * time.Millisecond)
+		atomic.AddInt32(&running, -1)
+		atomic.AddInt32(&done, 1)
+	}
+
+	d.Trigger("poll", slow)
+	time.Sleep(15 * time.Millisecond) // The first call is running now
+	d.Trigger("poll", slow)
+	time.Sleep(120 * time.Millisecond)
+
+	if got := atomic.LoadInt32(&done); got != 2 {
+		t.Errorf("ran %d calls, want 2", got)
+	}
+	if got := atomic.LoadInt32(&maxRunning); got != 1 {
+		t.Errorf("%d calls ran at once, want 1", got)
+	}
+}
+
+func TestDebouncerStopDropsWaitingCalls(t *testing.T) {
+	d := NewDebouncer(20 * time.Millisecond)
+
+	var calls int32
+	d.Trigger("poll", func() { atomic.AddInt32(&calls, 1) })
+	d.Stop()
+	d.Trigger("other", func() { atomic.AddInt32(&calls, 1) })
+	time.Sleep(50 * time.Millisecond)
+
+	if got := atomic.LoadInt32(&calls); got != 0 {
+		t.Errorf("ran %d calls after Stop, want 0", got)
+	}
+}
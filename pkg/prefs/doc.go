@@ -0,0 +1,3 @@
+// Package prefs provides per-user preferences for the WhatsForDinner bot,
+// like opting in to private messages from the bot.
+package prefs
@@ -0,0 +1,56 @@
+package prefs
+
+import (
+	"fmt"
+
+	"github.com/korjavin/whatsfordinner/pkg/logger"
+	"github.com/korjavin/whatsfordinner/pkg/models"
+	"github.com/korjavin/whatsfordinner/pkg/storage"
+)
+
+// Service provides user preference functionality
+type Service struct {
+	store  *storage.Store
+	logger *logger.Logger
+}
+
+// New creates a new preferences service
+func New(store *storage.Store) *Service {
+	return &Service{
+		store:  store,
+		logger: logger.New(""),
+	}
+}
+
+// Get returns the preferences of a user, or empty preferences if none are saved
+func (s *Service) Get(userID int64) models.UserPrefs {
+	var prefs models.UserPrefs
+	err := s.store.Get(prefsKey(userID), &prefs)
+	if err != nil {
+		return models.UserPrefs{UserID: userID}
+	}
+	return prefs
+}
+
+// SetPrivateChat records the private chat of a user, so the bot can message them directly
+func (s *Service) SetPrivateChat(userID, chatID int64) error {
+	prefs := s.Get(userID)
+	if prefs.PrivateChatID == chatID {
+		return nil
+	}
+
+	prefs.PrivateChatID = chatID
+	return s.store.Set(prefsKey(userID), prefs)
+}
+
+// SetCookDigest sets whether a user wants a private recap of the ratings of dinners they cooked
+func (s *Service) SetCookDigest(userID int64, enabled bool) error {
+	prefs := s.Get(userID)
+	prefs.CookDigest = enabled
+	return s.store.Set(prefsKey(userID), prefs)
+}
+
+// prefsKey returns the store key of a user's preferences
+func prefsKey(userID int64) string {
+	return fmt.Sprintf("prefs:%d", userID)
+}
@@ -0,0 +1,36 @@
+package scheduler
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/korjavin/whatsfordinner/pkg/models"
+)
+
+// SetAutoDinner sets whether the dinner workflow is started for a channel every afternoon
+func (s *Service) SetAutoDinner(channelID int64, enabled bool) error {
+	channelKey := fmt.Sprintf("channel:%d", channelID)
+	var channelState models.ChannelState
+	err := s.store.Get(channelKey, &channelState)
+	if err != nil {
+		// Create new channel state if it doesn't exist
+		channelState = models.ChannelState{
+			ChannelID: channelID,
+			FridgeID:  fmt.Sprintf("fridge:%d", channelID),
+		}
+	}
+
+	channelState.AutoDinner = &enabled
+	channelState.LastActivity = time.Now()
+
+	return s.store.Set(channelKey, channelState)
+}
+
+// AutoDinnerEnabled reports whether the dinner workflow is started for a channel every afternoon
+func (s *Service) AutoDinnerEnabled(channelID int64) bool {
+	var channelState models.ChannelState
+	if err := s.store.Get(fmt.Sprintf("channel:%d", channelID), &channelState); err != nil {
+		return true
+	}
+	return channelState.AutoDinnerEnabled()
+}
@@ -0,0 +1,34 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/korjavin/whatsfordinner/pkg/models"
+)
+
+func TestDailyDinnerSkipsChannelsWithAutoDinnerOff(t *testing.T) {
+	ts := newTestScheduler(t, 10*time.Minute)
+	ts.setChannel(t, models.ChannelState{ChannelID: 1})
+	ts.setChannel(t, models.ChannelState{ChannelID: 2})
+	if err := ts.SetAutoDinner(2, false); err != nil {
+		t.Fatalf("SetAutoDinner failed: %v", err)
+	}
+
+	now := time.Now()
+	afternoon := time.Date(now.Year(), now.Month(), now.Day(), 15, 2, 0, 0, now.Location())
+	ts.startDailyDinners(afternoon)
+
+	var started []string
+	for _, call := range ts.telegram.Calls("sendMessage") {
+		if call.Params.Get("text") == "🕒 It's dinner time! Let me suggest some options based on your fridge..." {
+			started = append(started, call.Params.Get("chat_id"))
+		}
+	}
+	if len(started) != 1 || started[0] != "1" {
+		t.Errorf("started dinner in channels %v, want only channel 1", started)
+	}
+	if ts.AutoDinnerEnabled(2) || !ts.AutoDinnerEnabled(1) {
+		t.Error("auto dinner settings don't match, want it off for channel 2 only")
+	}
+}
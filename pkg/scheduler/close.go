@@ -0,0 +1,116 @@
+package scheduler
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/korjavin/whatsfordinner/pkg/dinner"
+	"github.com/korjavin/whatsfordinner/pkg/messages"
+	"github.com/korjavin/whatsfordinner/pkg/models"
+	"github.com/korjavin/whatsfordinner/pkg/poll"
+)
+
+// VoteOutcome is how a closed vote turned out
+type VoteOutcome struct {
+	Results  map[string]int
+	Winner   string // Empty if nobody voted
+	Tied     []string
+	Cooldown dinner.CooldownCheck
+	// Runoff is the runoff vote that settles a tie instead of a winner, nil if the vote was closed
+	Runoff *models.VoteState
+}
+
+// Summary announces the results, how a tie was broken and whether the winner was a repeat
+func (o VoteOutcome) Summary(now time.Time) string {
+	text := poll.FormatResults(o.Results, o.Winner) + poll.FormatTie(o.Tied, o.Winner)
+	if note := o.Cooldown.Message(now); note != "" {
+		text += "\n\n" + note
+	}
+	return text
+}
+
+// CloseVote ends a vote, picking the winner the same way wherever a vote is closed:
+// the option with the most votes, ties going to the earliest option, and the runner-up
+// instead if the repeat cooldown rejects the winner. The results are stored on the vote.
+// A tie is first settled with a runoff poll between the tied options, unless the vote
+// already was a runoff. The outcome then has the Runoff and no winner, and the tied vote ended.
+func (s *Service) CloseVote(channelID int64, pollID string, now time.Time) (VoteOutcome, error) {
+	vote, err := s.pollService.GetVote(channelID, pollID)
+	if err != nil {
+		return VoteOutcome{}, err
+	}
+
+	if tied := poll.TiedOptions(vote); len(tied) > 1 && vote.EndedAt.IsZero() {
+		runoff, err := s.startRunoff(channelID, vote, tied, now)
+		switch {
+		case errors.Is(err, poll.ErrVoteEnded):
+			return VoteOutcome{}, err
+		case err == nil:
+			return VoteOutcome{Tied: tied, Runoff: runoff}, nil
+		case !errors.Is(err, poll.ErrRunoffLimit):
+			// Fall back to the earliest of the tied options
+			s.logger.Error("Failed to start a runoff for vote %s: %v", pollID, err)
+		}
+	}
+
+	return s.closeVote(channelID, vote, now)
+}
+
+// closeVote ends a vote without a runoff, see CloseVote
+func (s *Service) closeVote(channelID int64, vote *models.VoteState, now time.Time) (VoteOutcome, error) {
+	var outcome VoteOutcome
+	outcome.Results, outcome.Winner = poll.Tally(vote)
+	outcome.Tied = poll.TiedOptions(vote)
+
+	// Don't let a dish win again right after it was cooked
+	if outcome.Winner != "" {
+		outcome.Cooldown = s.dinnerService.CheckCooldown(channelID, outcome.Results, outcome.Winner, s.repeatCooldown, now)
+		outcome.Winner = outcome.Cooldown.Winner
+	}
+	// The cooldown explains why the runner-up won, the tie-break no longer decided it
+	if outcome.Cooldown.Replaced {
+		outcome.Tied = nil
+	}
+
+	if err := s.pollService.EndVote(channelID, vote.PollID, outcome.Winner); err != nil {
+		return VoteOutcome{}, err
+	}
+
+	return outcome, nil
+}
+
+// startRunoff replaces a tied vote with a runoff poll between the tied options.
+// The vote is claimed before the poll is posted, so of two closes racing each other
+// only one posts a runoff poll and the other gets ErrVoteEnded.
+func (s *Service) startRunoff(channelID int64, vote *models.VoteState, tied []string, now time.Time) (*models.VoteState, error) {
+	if err := s.pollService.ClaimRunoff(channelID, vote.PollID, now); err != nil {
+		return nil, err
+	}
+
+	pollMsg, err := s.bot.CreatePoll(channelID, s.pollService.GetPollQuestion(channelID), tied)
+	if err != nil {
+		if err := s.pollService.ReleaseRunoff(channelID, vote.PollID); err != nil {
+			s.logger.Error("Failed to release the runoff claim of vote %s: %v", vote.PollID, err)
+		}
+		return nil, fmt.Errorf("failed to create runoff poll: %w", err)
+	}
+
+	runoff, err := s.pollService.CreateRunoff(channelID, vote.PollID, tied, pollMsg.Poll.ID, pollMsg.MessageID)
+	if err != nil {
+		s.bot.StopPoll(channelID, pollMsg.MessageID)
+		if err := s.pollService.ReleaseRunoff(channelID, vote.PollID); err != nil {
+			s.logger.Error("Failed to release the runoff claim of vote %s: %v", vote.PollID, err)
+		}
+		return nil, err
+	}
+
+	// Close the tied poll in Telegram
+	if vote.MessageID != 0 {
+		s.bot.StopPoll(channelID, vote.MessageID)
+	}
+
+	s.bot.SendMessage(channelID, fmt.Sprintf("🤝 It's a tie between %s! Please vote again in the runoff poll above.", messages.EscapeMarkdown(strings.Join(tied, " and "))))
+	return runoff, nil
+}
@@ -0,0 +1,184 @@
+package scheduler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/korjavin/whatsfordinner/pkg/dinner"
+	"github.com/korjavin/whatsfordinner/pkg/models"
+	"github.com/korjavin/whatsfordinner/pkg/poll"
+)
+
+func TestCloseVoteSummaryShowsTally(t *testing.T) {
+	ts := newTestScheduler(t, 10*time.Minute)
+	if _, err := ts.pollService.CreateVote(1, "poll-1", 1, []string{"Soup", "Lasagna", "Curry"}); err != nil {
+		t.Fatalf("CreateVote failed: %v", err)
+	}
+	for userID, option := range map[string]string{"1": "Lasagna", "2": "Soup", "3": "Lasagna", "4": "Lasagna"} {
+		if err := ts.pollService.RecordVote(1, "poll-1", userID, "", option); err != nil {
+			t.Fatalf("RecordVote failed: %v", err)
+		}
+	}
+
+	outcome, err := ts.CloseVote(1, "poll-1", time.Now())
+	if err != nil {
+		t.Fatalf("CloseVote failed: %v", err)
+	}
+
+	want := "*Lasagna* won with 3 of 4 votes.\n\n• Lasagna: 3\n• Soup: 1\n• Curry: 0\n"
+	if got := outcome.Summary(time.Now()); got != want {
+		t.Errorf("Summary() = %q, want %q", got, want)
+	}
+	if strings.Contains(outcome.Summary(time.Now()), "tie") {
+		t.Error("summary mentions a tie although there was none")
+	}
+}
+
+func TestStopClosesTiesDeterministically(t *testing.T) {
+	for i := 0; i < 5; i++ {
+		ts := newTestScheduler(t, 10*time.Minute)
+		if _, err := ts.pollService.CreateVote(1, "poll-1", 1, []string{"Soup", "Lasagna", "Curry"}); err != nil {
+			t.Fatalf("CreateVote failed: %v", err)
+		}
+		for userID, option := range map[string]string{"1": "Curry", "2": "Lasagna", "3": "Curry", "4": "Lasagna"} {
+			if err := ts.pollService.RecordVote(1, "poll-1", userID, "", option); err != nil {
+				t.Fatalf("RecordVote failed: %v", err)
+			}
+		}
+
+		ts.stopDinnerWorkflow(1)
+
+		// The tie goes to the earliest option, and the stored vote matches the announcement
+		vote, err := ts.pollService.GetVote(1, "poll-1")
+		if err != nil {
+			t.Fatalf("GetVote failed: %v", err)
+		}
+		if vote.WinningDish != "Lasagna" || vote.Results["Lasagna"] != 2 || vote.Results["Curry"] != 2 {
+			t.Fatalf("stored winner %q with results %v, want Lasagna winning a 2-2 tie", vote.WinningDish, vote.Results)
+		}
+		if sent := ts.sentContaining("*Lasagna* won"); len(sent) != 1 {
+			t.Fatalf("announced %v, want Lasagna to win once", ts.telegram.Texts())
+		}
+	}
+}
+
+func TestStopDoesNotAnnounceAFailedClose(t *testing.T) {
+	ts := newTestScheduler(t, 10*time.Minute)
+	// The channel points at a vote that can't be loaded
+	ts.setChannel(t, models.ChannelState{ChannelID: 1, CurrentVote: &models.VoteState{PollID: "missing", StartedAt: time.Now()}})
+
+	ts.stopDinnerWorkflow(1)
+
+	if sent := ts.sentContaining("closed automatically"); len(sent) != 0 {
+		t.Errorf("announced a close that failed: %v", sent)
+	}
+	if sent := ts.sentContaining("Nobody voted"); len(sent) != 0 {
+		t.Errorf("announced a close that failed: %v", sent)
+	}
+}
+
+// tiedVote creates a poll whose votes are tied between Curry and Pasta
+func tiedVote(t *testing.T, ts *testScheduler) {
+	t.Helper()
+	if _, err := ts.pollService.CreateVote(1, "poll-1", 7, []string{"Soup", "Curry", "Pasta"}); err != nil {
+		t.Fatalf("CreateVote failed: %v", err)
+	}
+	for userID, option := range map[string]string{"1": "Curry", "2": "Pasta"} {
+		if err := ts.pollService.RecordVote(1, "poll-1", userID, "", option); err != nil {
+			t.Fatalf("RecordVote failed: %v", err)
+		}
+	}
+}
+
+func TestRacingClosesPostOneRunoff(t *testing.T) {
+	ts := newTestScheduler(t, 10*time.Minute)
+	tiedVote(t, ts)
+
+	// The idle close, the 9pm close and /endvote can all fire at once
+	const closes = 4
+	runoffs := make(chan *models.VoteState, closes)
+	var wg sync.WaitGroup
+	for i := 0; i < closes; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			outcome, err := ts.CloseVote(1, "poll-1", time.Now())
+			if err != nil && !errors.Is(err, poll.ErrVoteEnded) {
+				t.Errorf("CloseVote returned %v, want nil or ErrVoteEnded", err)
+			}
+			if outcome.Runoff != nil {
+				runoffs <- outcome.Runoff
+			}
+		}()
+	}
+	wg.Wait()
+	close(runoffs)
+
+	if len(runoffs) != 1 {
+		t.Errorf("%d closes started a runoff, want 1", len(runoffs))
+	}
+	if polls := ts.telegram.Calls("sendPoll"); len(polls) != 1 {
+		t.Errorf("sent %d runoff polls, want 1", len(polls))
+	}
+	if sent := ts.sentContaining("It's a tie"); len(sent) != 1 {
+		t.Errorf("announced the tie %d times, want once", len(sent))
+	}
+}
+
+func TestFailedRunoffPollFallsBackToTheEarliestOption(t *testing.T) {
+	ts := newTestScheduler(t, 10*time.Minute)
+	tiedVote(t, ts)
+	ts.telegram.Fail("sendPoll", http.StatusBadRequest, "Bad Request: chat not found")
+
+	outcome, err := ts.CloseVote(1, "poll-1", time.Now())
+	if err != nil {
+		t.Fatalf("CloseVote failed: %v", err)
+	}
+	if outcome.Runoff != nil || outcome.Winner != "Curry" {
+		t.Errorf("outcome = %+v, want Curry winning without a runoff", outcome)
+	}
+	vote, err := ts.pollService.GetVote(1, "poll-1")
+	if err != nil {
+		t.Fatalf("GetVote failed: %v", err)
+	}
+	if vote.EndedAt.IsZero() || !vote.RunoffClaimedAt.IsZero() {
+		t.Errorf("vote ended at %v with runoff claim %v, want it ended and the claim released", vote.EndedAt, vote.RunoffClaimedAt)
+	}
+}
+
+func TestTieLostToTheCooldownIsNotAnnouncedAsATieBreak(t *testing.T) {
+	ts := newTestScheduler(t, 10*time.Minute)
+	ts.repeatCooldown = dinner.Cooldown{Window: 7 * 24 * time.Hour, Policy: dinner.RepeatReject}
+	startedAt := time.Now().AddDate(0, 0, -2)
+	id := fmt.Sprintf("dinner:1:%d", startedAt.UnixNano())
+	if err := ts.store.Set(id, models.Dinner{ID: id, ChannelID: 1, Dish: models.Dish{Name: "Curry"}, StartedAt: startedAt}); err != nil {
+		t.Fatalf("failed to save dinner: %v", err)
+	}
+	tiedVote(t, ts)
+
+	// It's too late for a runoff, so the tie goes to Curry, which the cooldown rejects
+	ts.stopDinnerWorkflow(1)
+
+	vote, err := ts.pollService.GetVote(1, "poll-1")
+	if err != nil {
+		t.Fatalf("GetVote failed: %v", err)
+	}
+	if vote.WinningDish != "Pasta" {
+		t.Fatalf("winning dish = %q, want the runner-up Pasta", vote.WinningDish)
+	}
+	sent := ts.sentContaining("*Pasta* won")
+	if len(sent) != 1 {
+		t.Fatalf("announced %v, want Pasta to win once", ts.telegram.Texts())
+	}
+	if strings.Contains(sent[0], "first option on the poll") {
+		t.Errorf("announcement %q says the tie-break picked Pasta", sent[0])
+	}
+	if !strings.Contains(sent[0], "so the runner-up Pasta wins instead") {
+		t.Errorf("announcement %q doesn't explain the cooldown", sent[0])
+	}
+}
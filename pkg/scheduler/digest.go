@@ -0,0 +1,113 @@
+package scheduler
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/korjavin/whatsfordinner/pkg/dinner"
+	"github.com/korjavin/whatsfordinner/pkg/messages"
+	"github.com/korjavin/whatsfordinner/pkg/models"
+	"github.com/korjavin/whatsfordinner/pkg/telegram"
+)
+
+// ratingFinalizerInterval is how often finished dinners are checked for a closed rating window
+const ratingFinalizerInterval = 15 * time.Minute
+
+// runRatingFinalizer periodically closes the rating window of finished dinners
+// and sends the cook a private recap if they opted in
+func (s *Service) runRatingFinalizer() {
+	s.logger.Info("Starting rating finalizer")
+
+	ticker := time.NewTicker(ratingFinalizerInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.finalizeRatings(time.Now())
+		case <-s.stopChan:
+			return
+		}
+	}
+}
+
+// finalizeRatings closes the due rating windows of every channel
+func (s *Service) finalizeRatings(now time.Time) {
+	channelKeys, err := s.store.List("channel:")
+	if err != nil {
+		s.logger.Error("Failed to list channels: %v", err)
+		return
+	}
+
+	for _, channelKey := range channelKeys {
+		var channelState models.ChannelState
+		err := s.store.Get(channelKey, &channelState)
+		if err != nil {
+			s.logger.Error("Failed to get channel state: %v", err)
+			continue
+		}
+
+		dinners, err := s.dinnerService.FinalizeDueRatings(channelState.ChannelID, now)
+		if err != nil {
+			s.logger.Error("Failed to finalize ratings for channel %d: %v", channelState.ChannelID, err)
+			continue
+		}
+
+		for _, d := range dinners {
+			s.sendCookDigest(channelState.ChannelID, d)
+		}
+	}
+}
+
+// sendCookDigest sends the cook of a dinner a private recap of its ratings, if they opted in
+func (s *Service) sendCookDigest(channelID int64, d models.Dinner) {
+	cookID, err := strconv.ParseInt(d.Cook, 10, 64)
+	if err != nil {
+		return
+	}
+
+	userPrefs := s.prefsService.Get(cookID)
+	if !userPrefs.CookDigest || userPrefs.PrivateChatID == 0 {
+		return
+	}
+
+	_, err = s.bot.SendPrivateMessage(userPrefs.PrivateChatID, s.formatCookDigest(channelID, d))
+	if errors.Is(err, telegram.ErrCannotMessageUser) {
+		s.logger.Warn("Cook %d can't be messaged privately, skipping their digest", cookID)
+		return
+	}
+	if err != nil {
+		s.logger.Error("Failed to send cook digest to %d: %v", cookID, err)
+	}
+}
+
+// formatCookDigest formats the final ratings of a dinner for its cook
+func (s *Service) formatCookDigest(channelID int64, d models.Dinner) string {
+	if len(d.Ratings) == 0 {
+		return fmt.Sprintf("🍽️ Ratings for your %s are closed. Nobody rated it this time, but thanks for cooking! 👨‍🍳", messages.EscapeMarkdown(d.Dish.Name))
+	}
+
+	msgText := fmt.Sprintf("🍽️ Ratings for your %s are closed.\n\nFinal average: %.1f ⭐ from %d ratings\n\n",
+		messages.EscapeMarkdown(d.Dish.Name), dinner.AverageRating(d.Ratings), len(d.Ratings))
+
+	raters := make([]string, 0, len(d.Ratings))
+	for userID := range d.Ratings {
+		raters = append(raters, userID)
+	}
+	sort.Strings(raters)
+
+	for _, userID := range raters {
+		name := s.memberName(channelID, userID)
+		if name == "" {
+			name = "Someone"
+		}
+		msgText += fmt.Sprintf("• %s: %s\n", messages.EscapeMarkdown(name), strings.Repeat("⭐", d.Ratings[userID]))
+	}
+
+	msgText += "\nThanks for cooking! 👨‍🍳"
+	return msgText
+}
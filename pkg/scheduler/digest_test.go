@@ -0,0 +1,74 @@
+package scheduler
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/korjavin/whatsfordinner/pkg/models"
+)
+
+func TestCookDigestOnlyGoesToOptedInCooks(t *testing.T) {
+	ts := newTestScheduler(t, 10*time.Minute)
+	now := time.Date(2024, 6, 2, 9, 0, 0, 0, time.UTC)
+	ts.setChannel(t, models.ChannelState{ChannelID: 1})
+
+	// 11 opted in from a private chat, 12 opted in but never started one, 13 didn't opt in
+	if err := ts.prefsService.SetPrivateChat(11, 111); err != nil {
+		t.Fatalf("SetPrivateChat failed: %v", err)
+	}
+	if err := ts.prefsService.SetPrivateChat(13, 113); err != nil {
+		t.Fatalf("SetPrivateChat failed: %v", err)
+	}
+	for _, userID := range []int64{11, 12} {
+		if err := ts.prefsService.SetCookDigest(userID, true); err != nil {
+			t.Fatalf("SetCookDigest failed: %v", err)
+		}
+	}
+	for i, cook := range []string{"11", "12", "13"} {
+		d := models.Dinner{
+			ID:         fmt.Sprintf("dinner:1:%d", i),
+			ChannelID:  1,
+			Dish:       models.Dish{Name: "Soup"},
+			Cook:       cook,
+			StartedAt:  now.Add(-14 * time.Hour),
+			FinishedAt: now.Add(-13 * time.Hour),
+			Ratings:    map[string]int{"2": 4},
+		}
+		if err := ts.store.Set(d.ID, d); err != nil {
+			t.Fatalf("failed to save dinner: %v", err)
+		}
+	}
+
+	ts.finalizeRatings(now)
+
+	calls := ts.telegram.Calls("sendMessage")
+	if len(calls) != 1 || calls[0].Params.Get("chat_id") != "111" {
+		t.Fatalf("sent %v, want one digest to the private chat of the opted-in cook", calls)
+	}
+
+	// The ratings are final, so nothing is sent twice
+	ts.telegram.Reset()
+	ts.finalizeRatings(now.Add(time.Hour))
+	if calls := ts.telegram.Calls("sendMessage"); len(calls) != 0 {
+		t.Errorf("sent %d more digests after the ratings were finalized", len(calls))
+	}
+}
+
+func TestCookDigestSkipsCooksWhoBlockedTheBot(t *testing.T) {
+	ts := newTestScheduler(t, 10*time.Minute)
+	if err := ts.prefsService.SetPrivateChat(11, 111); err != nil {
+		t.Fatalf("SetPrivateChat failed: %v", err)
+	}
+	if err := ts.prefsService.SetCookDigest(11, true); err != nil {
+		t.Fatalf("SetCookDigest failed: %v", err)
+	}
+	ts.telegram.Fail("sendMessage", http.StatusForbidden, "Forbidden: bot was blocked by the user")
+
+	// Blocked users only get a warning logged
+	ts.sendCookDigest(1, models.Dinner{Dish: models.Dish{Name: "Soup"}, Cook: "11"})
+	if calls := ts.telegram.Calls("sendMessage"); len(calls) != 1 {
+		t.Errorf("made %d sendMessage calls, want the one failed attempt", len(calls))
+	}
+}
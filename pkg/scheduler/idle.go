@@ -0,0 +1,102 @@
+package scheduler
+
+import (
+	"fmt"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/korjavin/whatsfordinner/pkg/messages"
+	"github.com/korjavin/whatsfordinner/pkg/models"
+)
+
+// runIdleVoteCloser closes polls once nobody voted for the configured grace period,
+// so the family doesn't have to wait for the 9pm cutoff when everyone already voted
+func (s *Service) runIdleVoteCloser() {
+	s.logger.Info("Starting idle vote closer, grace period %v", s.voteIdleGrace)
+
+	ticker := time.NewTicker(1 * time.Minute)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.closeIdleVotes(time.Now())
+		case <-s.stopChan:
+			return
+		}
+	}
+}
+
+// closeIdleVotes closes every open vote whose last vote is at least the grace period before now
+func (s *Service) closeIdleVotes(now time.Time) {
+	channelKeys, err := s.store.List("channel:")
+	if err != nil {
+		s.logger.Error("Failed to list channels: %v", err)
+		return
+	}
+
+	for _, channelKey := range channelKeys {
+		var channelState models.ChannelState
+		err := s.store.Get(channelKey, &channelState)
+		if err != nil {
+			s.logger.Error("Failed to get channel state: %v", err)
+			continue
+		}
+
+		if channelState.CurrentVote == nil || !channelState.CurrentVote.EndedAt.IsZero() {
+			continue
+		}
+
+		// The channel state keeps a snapshot, the vote record has the latest votes
+		vote, err := s.pollService.GetVote(channelState.ChannelID, channelState.CurrentVote.PollID)
+		if err != nil {
+			s.logger.Error("Failed to get vote: %v", err)
+			continue
+		}
+
+		if isVoteIdle(vote, now, s.voteIdleGrace) {
+			s.closeIdleVote(channelState.ChannelID, vote)
+		}
+	}
+}
+
+// isVoteIdle reports whether an open vote got votes, but none within the grace period before now
+// Votes nobody voted in yet are left to the 9pm cutoff
+func isVoteIdle(vote *models.VoteState, now time.Time, grace time.Duration) bool {
+	if !vote.EndedAt.IsZero() || vote.LastVoteAt.IsZero() || len(vote.Votes) == 0 {
+		return false
+	}
+	return now.Sub(vote.LastVoteAt) >= grace
+}
+
+// closeIdleVote ends a vote, announces the winner and asks for a cook, or starts a runoff if it's tied
+func (s *Service) closeIdleVote(channelID int64, vote *models.VoteState) {
+	s.logger.Info("Closing vote %s for channel %d after %v without new votes", vote.PollID, channelID, s.voteIdleGrace)
+	outcome, err := s.CloseVote(channelID, vote.PollID, time.Now())
+	if err != nil {
+		s.logger.Error("Failed to close vote: %v", err)
+		return
+	}
+	if outcome.Runoff != nil {
+		// The family votes again in the runoff poll
+		return
+	}
+	winningOption := outcome.Winner
+
+	if vote.MessageID != 0 {
+		if err := s.bot.StopPoll(channelID, vote.MessageID); err != nil {
+			s.logger.Warn("Failed to stop poll %s: %v", vote.PollID, err)
+		}
+	}
+
+	msgText := "🎉 Looks like everyone has voted! " + outcome.Summary(time.Now())
+	s.bot.SendMessage(channelID, msgText)
+
+	// Ask for cook volunteers
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("I'll cook!", fmt.Sprintf("volunteer:%s", vote.PollID)),
+		),
+	)
+	s.bot.SendMessageWithKeyboard(channelID, fmt.Sprintf("Who wants to cook %s tonight? Press the button below to volunteer!", messages.Bold(winningOption)), keyboard)
+}
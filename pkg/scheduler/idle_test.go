@@ -0,0 +1,98 @@
+package scheduler
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIdleVoteClosesAfterGrace(t *testing.T) {
+	ts := newTestScheduler(t, 10*time.Minute)
+	ts.voteIdleGrace = 20 * time.Minute
+	if _, err := ts.pollService.CreateVote(1, "poll-1", 7, []string{"Soup", "Curry"}); err != nil {
+		t.Fatalf("CreateVote failed: %v", err)
+	}
+
+	// Nobody voted yet, so the poll waits for the 9pm cutoff however long it's quiet
+	ts.closeIdleVotes(time.Now().Add(2 * time.Hour))
+	if vote, _ := ts.pollService.GetVote(1, "poll-1"); !vote.EndedAt.IsZero() {
+		t.Fatal("closed a poll nobody voted in")
+	}
+
+	if err := ts.pollService.RecordVote(1, "poll-1", "2", "ben", "Curry"); err != nil {
+		t.Fatalf("RecordVote failed: %v", err)
+	}
+	vote, err := ts.pollService.GetVote(1, "poll-1")
+	if err != nil {
+		t.Fatalf("GetVote failed: %v", err)
+	}
+	lastVote := vote.LastVoteAt
+
+	ts.closeIdleVotes(lastVote.Add(ts.voteIdleGrace - time.Second))
+	if vote, _ := ts.pollService.GetVote(1, "poll-1"); !vote.EndedAt.IsZero() {
+		t.Fatal("closed the poll before the grace period was over")
+	}
+
+	ts.closeIdleVotes(lastVote.Add(ts.voteIdleGrace))
+	if vote, _ := ts.pollService.GetVote(1, "poll-1"); vote.EndedAt.IsZero() {
+		t.Fatal("the poll is still open after the grace period")
+	}
+	if sent := ts.sentContaining("everyone has voted"); len(sent) != 1 {
+		t.Errorf("announced the idle close %d times, want once", len(sent))
+	}
+
+	// A closed poll isn't closed again
+	ts.closeIdleVotes(lastVote.Add(time.Hour))
+	if sent := ts.sentContaining("everyone has voted"); len(sent) != 1 {
+		t.Errorf("announced the idle close %d times, want once", len(sent))
+	}
+}
+
+func TestIdleCloseSettlesATieWithARunoff(t *testing.T) {
+	ts := newTestScheduler(t, 10*time.Minute)
+	ts.voteIdleGrace = 20 * time.Minute
+	if _, err := ts.pollService.CreateVote(1, "poll-1", 7, []string{"Soup", "Curry", "Pasta"}); err != nil {
+		t.Fatalf("CreateVote failed: %v", err)
+	}
+	for userID, option := range map[string]string{"1": "Curry", "2": "Pasta"} {
+		if err := ts.pollService.RecordVote(1, "poll-1", userID, "", option); err != nil {
+			t.Fatalf("RecordVote failed: %v", err)
+		}
+	}
+
+	ts.closeIdleVotes(time.Now().Add(time.Hour))
+
+	polls := ts.telegram.Calls("sendPoll")
+	if len(polls) != 1 {
+		t.Fatalf("sent %d polls after a tie, want a runoff poll", len(polls))
+	}
+	if options := polls[0].Params.Get("options"); !strings.Contains(options, "Curry") || !strings.Contains(options, "Pasta") || strings.Contains(options, "Soup") {
+		t.Errorf("runoff options = %s, want Curry and Pasta", options)
+	}
+	if sent := ts.sentContaining("everyone has voted"); len(sent) != 0 {
+		t.Errorf("announced a winner of the tied vote: %v", sent)
+	}
+	runoff, err := ts.pollService.GetCurrentVote(1)
+	if err != nil || runoff.RunoffOf != "poll-1" {
+		t.Fatalf("current vote = %v (%v), want the runoff of poll-1", runoff, err)
+	}
+
+	// The runoff ties again, so the earliest option wins instead of another runoff
+	for userID, option := range map[string]string{"1": "Pasta", "2": "Curry"} {
+		if err := ts.pollService.RecordVote(1, runoff.PollID, userID, "", option); err != nil {
+			t.Fatalf("RecordVote failed: %v", err)
+		}
+	}
+	ts.closeIdleVotes(time.Now().Add(time.Hour))
+
+	if polls := ts.telegram.Calls("sendPoll"); len(polls) != 1 {
+		t.Errorf("sent %d polls, want no second runoff", len(polls))
+	}
+	vote, err := ts.pollService.GetVote(1, runoff.PollID)
+	if err != nil {
+		t.Fatalf("GetVote failed: %v", err)
+	}
+	if vote.EndedAt.IsZero() || vote.WinningDish != "Curry" {
+		t.Errorf("runoff ended at %v with winner %q, want Curry", vote.EndedAt, vote.WinningDish)
+	}
+}
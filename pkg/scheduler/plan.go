@@ -0,0 +1,112 @@
+package scheduler
+
+import (
+	"fmt"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/korjavin/whatsfordinner/pkg/models"
+	"github.com/korjavin/whatsfordinner/pkg/storage"
+)
+
+// SetPlanNudge sets when a channel is nudged to plan the next week, nil goes back to models.DefaultPlanNudge
+func (s *Service) SetPlanNudge(channelID int64, nudge *models.PlanNudge) error {
+	if nudge != nil && (nudge.Hour < 0 || nudge.Hour > 23) {
+		return fmt.Errorf("hour must be from 0 to 23, got %d", nudge.Hour)
+	}
+
+	channelKey := fmt.Sprintf("channel:%d", channelID)
+	var channelState models.ChannelState
+	err := s.store.Get(channelKey, &channelState)
+	if err != nil {
+		// Create new channel state if it doesn't exist
+		channelState = models.ChannelState{
+			ChannelID: channelID,
+			FridgeID:  fmt.Sprintf("fridge:%d", channelID),
+		}
+	}
+
+	channelState.PlanNudge = nudge
+	channelState.LastActivity = time.Now()
+
+	return s.store.Set(channelKey, channelState)
+}
+
+// PlanNudge returns when a channel is nudged to plan the next week
+func (s *Service) PlanNudge(channelID int64) models.PlanNudge {
+	var channelState models.ChannelState
+	if err := s.store.Get(fmt.Sprintf("channel:%d", channelID), &channelState); err != nil {
+		return models.DefaultPlanNudge
+	}
+	return channelState.Effective(s.channelDefaults()).PlanNudge
+}
+
+// isPlanNudgeDue reports whether local is the day and hour of the nudge
+func isPlanNudgeDue(local time.Time, nudge models.PlanNudge) bool {
+	return !nudge.Off && local.Weekday() == nudge.Day && local.Hour() == nudge.Hour
+}
+
+// runPlanNudges nudges channels to plan the next week
+func (s *Service) runPlanNudges() {
+	s.logger.Info("Starting plan nudges")
+
+	ticker := time.NewTicker(1 * time.Minute)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.postPlanNudges(time.Now())
+		case <-s.stopChan:
+			return
+		}
+	}
+}
+
+// postPlanNudges posts the nudge to plan the next week in every channel where it's due
+func (s *Service) postPlanNudges(now time.Time) {
+	channelKeys, err := s.store.List("channel:")
+	if err != nil {
+		s.logger.Error("Failed to list channels: %v", err)
+		return
+	}
+
+	for _, channelKey := range channelKeys {
+		// Claim the nudge inside the update, so nothing else saved to the channel meanwhile is overwritten
+		var channelState models.ChannelState
+		var due bool
+		err := s.store.Update(channelKey, &channelState, func() error {
+			// A retried update must not remember that an attempt before it claimed the send
+			due = false
+			settings := channelState.Effective(s.channelDefaults())
+			if !isPlanNudgeDue(now.In(settings.Location), settings.PlanNudge) {
+				return storage.ErrNoChange
+			}
+			// Only post once per week
+			if now.Sub(channelState.LastPlanNudge) < 24*time.Hour {
+				return storage.ErrNoChange
+			}
+			channelState.LastPlanNudge = now
+			due = true
+			return nil
+		})
+		if err != nil {
+			s.logger.Error("Failed to update channel state: %v", err)
+			continue
+		}
+
+		if due {
+			s.postPlanNudge(channelState.ChannelID)
+		}
+	}
+}
+
+// postPlanNudge posts the nudge to plan the next week, with a button to start planning
+func (s *Service) postPlanNudge(channelID int64) {
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("📅 Plan next week", "plan_week"),
+		),
+	)
+	s.bot.SendMessageWithKeyboard(channelID, "📅 A new week is coming up! Want to plan a few dinners ahead? (Turn these reminders off with /plan_nudge off)", keyboard)
+}
@@ -0,0 +1,42 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/korjavin/whatsfordinner/pkg/models"
+)
+
+func TestPlanNudgeFiresOnTheConfiguredDay(t *testing.T) {
+	ts := newTestScheduler(t, 10*time.Minute)
+	ts.setChannel(t, models.ChannelState{ChannelID: 1})
+	ts.setChannel(t, models.ChannelState{ChannelID: 2, PlanNudge: &models.PlanNudge{Day: time.Sunday, Hour: 18}})
+	ts.setChannel(t, models.ChannelState{ChannelID: 3, PlanNudge: &models.PlanNudge{Off: true}})
+
+	// 8 June 2024 is a Saturday, channels without a time zone use the local one
+	saturday := time.Date(2024, 6, 8, 10, 0, 0, 0, time.Local)
+	ts.postPlanNudges(saturday.Add(-time.Hour))
+	if sent := ts.sentContaining("plan a few dinners"); len(sent) != 0 {
+		t.Fatalf("nudged before 10am: %v", sent)
+	}
+
+	ts.postPlanNudges(saturday)
+	ts.postPlanNudges(saturday.Add(30 * time.Minute))
+	if calls := ts.telegram.Calls("sendMessage"); len(calls) != 1 || calls[0].Params.Get("chat_id") != "1" {
+		t.Fatalf("sent %d nudges on Saturday, want one to the channel with the default day", len(calls))
+	}
+
+	ts.telegram.Reset()
+	sunday := time.Date(2024, 6, 9, 18, 0, 0, 0, time.Local)
+	ts.postPlanNudges(sunday)
+	if calls := ts.telegram.Calls("sendMessage"); len(calls) != 1 || calls[0].Params.Get("chat_id") != "2" {
+		t.Fatalf("sent %d nudges on Sunday, want one to the channel that picked Sunday", len(calls))
+	}
+
+	// A week later the default channel is nudged again, the channel that turned them off never is
+	ts.telegram.Reset()
+	ts.postPlanNudges(saturday.AddDate(0, 0, 7))
+	if calls := ts.telegram.Calls("sendMessage"); len(calls) != 1 || calls[0].Params.Get("chat_id") != "1" {
+		t.Errorf("sent %d nudges the next Saturday, want one to the channel with the default day", len(calls))
+	}
+}
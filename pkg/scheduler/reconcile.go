@@ -0,0 +1,106 @@
+package scheduler
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/korjavin/whatsfordinner/pkg/models"
+	"github.com/korjavin/whatsfordinner/pkg/poll"
+	"github.com/korjavin/whatsfordinner/pkg/storage"
+)
+
+// reconcileInterval is how often channel states are checked against the stored votes and dinners
+const reconcileInterval = 10 * time.Minute
+
+// runReconciler periodically clears stale vote and dinner pointers from channel states,
+// so a corrupted state can't leave a channel stuck
+func (s *Service) runReconciler() {
+	s.logger.Info("Starting channel state reconciler")
+
+	ticker := time.NewTicker(reconcileInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.reconcileChannels()
+		case <-s.stopChan:
+			return
+		}
+	}
+}
+
+// reconcileChannels checks every channel state once
+func (s *Service) reconcileChannels() {
+	channelKeys, err := s.store.List("channel:")
+	if err != nil {
+		s.logger.Error("Failed to list channels: %v", err)
+		return
+	}
+
+	for _, channelKey := range channelKeys {
+		// Reconcile inside the update, so a vote or dinner that starts or ends meanwhile isn't overwritten
+		var channelState models.ChannelState
+		err := s.store.Update(channelKey, &channelState, func() error {
+			if !s.reconcileChannel(&channelState) {
+				return storage.ErrNoChange
+			}
+			return nil
+		})
+		if err != nil {
+			s.logger.Error("Failed to reconcile channel state %s: %v", channelKey, err)
+		}
+	}
+}
+
+// reconcileChannel clears the current vote and dinner of a channel if they no longer match
+// the stored records, the same way EndVote and FinishDinner would have cleared them,
+// and drops excuses that ran out.
+// It returns true if the channel state was changed.
+func (s *Service) reconcileChannel(channelState *models.ChannelState) bool {
+	changed := false
+
+	if vote := channelState.CurrentVote; vote != nil {
+		var storedVote models.VoteState
+		err := s.store.Get(fmt.Sprintf("vote:%d:%s", channelState.ChannelID, vote.PollID), &storedVote)
+		switch {
+		case errors.Is(err, storage.ErrNotFound):
+			s.logger.Warn("Clearing current vote %s of channel %d: the vote doesn't exist", vote.PollID, channelState.ChannelID)
+			channelState.CurrentVote = nil
+			changed = true
+		case err != nil:
+			s.logger.Error("Failed to get vote %s: %v", vote.PollID, err)
+		case !storedVote.EndedAt.IsZero():
+			s.logger.Warn("Clearing current vote %s of channel %d: the vote ended at %s", vote.PollID, channelState.ChannelID, storedVote.EndedAt.Format(time.RFC3339))
+			channelState.CurrentVote = nil
+			changed = true
+		}
+	}
+
+	if dinner := channelState.CurrentDinner; dinner != nil {
+		var storedDinner models.Dinner
+		err := s.store.Get(dinner.ID, &storedDinner)
+		switch {
+		case errors.Is(err, storage.ErrNotFound):
+			s.logger.Warn("Clearing current dinner %s of channel %d: the dinner doesn't exist", dinner.ID, channelState.ChannelID)
+			channelState.CurrentDinner = nil
+			changed = true
+		case err != nil:
+			s.logger.Error("Failed to get dinner %s: %v", dinner.ID, err)
+		case !storedDinner.FinishedAt.IsZero():
+			s.logger.Warn("Clearing current dinner %s of channel %d: the dinner finished at %s", dinner.ID, channelState.ChannelID, storedDinner.FinishedAt.Format(time.RFC3339))
+			channelState.CurrentDinner = nil
+			changed = true
+		}
+	}
+
+	// Count members in again once their excuse runs out
+	if active := poll.ActiveExcuses(channelState.Excused, time.Now()); len(active) != len(channelState.Excused) {
+		s.logger.Info("Clearing %d expired excuses of channel %d", len(channelState.Excused)-len(active), channelState.ChannelID)
+		channelState.Excused = active
+		changed = true
+	}
+
+	return changed
+}
@@ -0,0 +1,53 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/korjavin/whatsfordinner/pkg/models"
+)
+
+func TestReconcileClearsStalePointers(t *testing.T) {
+	ts := newTestScheduler(t, 10*time.Minute)
+	start := time.Now().Add(-time.Hour)
+
+	// Channel 1 points at a vote that ended and a dinner that was never saved
+	ended := models.VoteState{PollID: "ended", StartedAt: start, EndedAt: start.Add(time.Minute)}
+	if err := ts.store.Set("vote:1:ended", ended); err != nil {
+		t.Fatalf("failed to save vote: %v", err)
+	}
+	ended.EndedAt = time.Time{}
+	ts.setChannel(t, models.ChannelState{
+		ChannelID:     1,
+		CurrentVote:   &ended,
+		CurrentDinner: &models.Dinner{ID: "dinner:1:1", ChannelID: 1, StartedAt: start},
+	})
+
+	// Channel 2 is consistent
+	open := models.VoteState{PollID: "open", StartedAt: start}
+	cooking := models.Dinner{ID: "dinner:2:1", ChannelID: 2, StartedAt: start}
+	if err := ts.store.Set("vote:2:open", open); err != nil {
+		t.Fatalf("failed to save vote: %v", err)
+	}
+	if err := ts.store.Set(cooking.ID, cooking); err != nil {
+		t.Fatalf("failed to save dinner: %v", err)
+	}
+	ts.setChannel(t, models.ChannelState{ChannelID: 2, CurrentVote: &open, CurrentDinner: &cooking})
+
+	ts.reconcileChannels()
+
+	var channelState models.ChannelState
+	if err := ts.store.Get("channel:1", &channelState); err != nil {
+		t.Fatalf("failed to get channel: %v", err)
+	}
+	if channelState.CurrentVote != nil || channelState.CurrentDinner != nil {
+		t.Errorf("channel 1 still points at vote %v and dinner %v, want both cleared", channelState.CurrentVote, channelState.CurrentDinner)
+	}
+
+	if err := ts.store.Get("channel:2", &channelState); err != nil {
+		t.Fatalf("failed to get channel: %v", err)
+	}
+	if channelState.CurrentVote == nil || channelState.CurrentDinner == nil {
+		t.Errorf("channel 2 lost its vote %v or dinner %v, want both kept", channelState.CurrentVote, channelState.CurrentDinner)
+	}
+}
@@ -0,0 +1,127 @@
+package scheduler
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/korjavin/whatsfordinner/pkg/messages"
+	"github.com/korjavin/whatsfordinner/pkg/models"
+	"github.com/korjavin/whatsfordinner/pkg/storage"
+)
+
+// restockReminderHour is the hour, in the channel's time zone, the reminder is posted the evening before shopping day
+const restockReminderHour = 18
+
+// ParseWeekday parses a day of the week like "saturday" or "Sat"
+func ParseWeekday(value string) (time.Weekday, bool) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	if len(value) < 3 {
+		return 0, false
+	}
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		if strings.HasPrefix(strings.ToLower(day.String()), value) {
+			return day, true
+		}
+	}
+	return 0, false
+}
+
+// SetShoppingDay sets the day a channel shops every week, nil turns the restock reminder off
+func (s *Service) SetShoppingDay(channelID int64, day *time.Weekday) error {
+	channelKey := fmt.Sprintf("channel:%d", channelID)
+	var channelState models.ChannelState
+	err := s.store.Get(channelKey, &channelState)
+	if err != nil {
+		// Create new channel state if it doesn't exist
+		channelState = models.ChannelState{
+			ChannelID: channelID,
+			FridgeID:  fmt.Sprintf("fridge:%d", channelID),
+		}
+	}
+
+	channelState.ShoppingDay = day
+	channelState.LastActivity = time.Now()
+
+	return s.store.Set(channelKey, channelState)
+}
+
+// isRestockDue reports whether local is the reminder hour on the evening before the shopping day
+func isRestockDue(local time.Time, shoppingDay time.Weekday) bool {
+	dayBefore := (shoppingDay + 6) % 7
+	return local.Weekday() == dayBefore && local.Hour() == restockReminderHour
+}
+
+// runRestockReminders reminds channels what to buy the evening before their shopping day
+func (s *Service) runRestockReminders() {
+	s.logger.Info("Starting restock reminders")
+
+	ticker := time.NewTicker(1 * time.Minute)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.postRestockReminders(time.Now())
+		case <-s.stopChan:
+			return
+		}
+	}
+}
+
+// postRestockReminders posts the restock reminder in every channel where it's due
+func (s *Service) postRestockReminders(now time.Time) {
+	channelKeys, err := s.store.List("channel:")
+	if err != nil {
+		s.logger.Error("Failed to list channels: %v", err)
+		return
+	}
+
+	for _, channelKey := range channelKeys {
+		// Claim the reminder inside the update, so nothing else saved to the channel meanwhile is overwritten
+		var channelState models.ChannelState
+		var due bool
+		err := s.store.Update(channelKey, &channelState, func() error {
+			// A retried update must not remember that an attempt before it claimed the send
+			due = false
+			if channelState.ShoppingDay == nil || !isRestockDue(now.In(channelState.Location()), *channelState.ShoppingDay) {
+				return storage.ErrNoChange
+			}
+			// Only post once per week
+			if now.Sub(channelState.LastRestockReminder) < 24*time.Hour {
+				return storage.ErrNoChange
+			}
+			channelState.LastRestockReminder = now
+			due = true
+			return nil
+		})
+		if err != nil {
+			s.logger.Error("Failed to update channel state: %v", err)
+			continue
+		}
+
+		if due {
+			s.postRestockReminder(channelState.ChannelID, *channelState.ShoppingDay)
+		}
+	}
+}
+
+// postRestockReminder posts what's running out before the shopping day
+func (s *Service) postRestockReminder(channelID int64, shoppingDay time.Weekday) {
+	list, err := s.dinnerService.RestockList(channelID)
+	if err != nil {
+		s.logger.Error("Failed to get the restock list: %v", err)
+		return
+	}
+
+	if len(list) == 0 {
+		s.bot.SendMessage(channelID, fmt.Sprintf("🛒 Tomorrow is %s, your shopping day! The fridge and pantry look well stocked.", shoppingDay))
+		return
+	}
+
+	msgText := fmt.Sprintf("🛒 Tomorrow is %s, your shopping day! Here's what's running out:\n\n", shoppingDay)
+	for _, item := range list {
+		msgText += fmt.Sprintf("• %s\n", messages.EscapeMarkdown(item))
+	}
+	s.bot.SendMessage(channelID, msgText)
+}
@@ -0,0 +1,96 @@
+package scheduler
+
+import (
+	"sync"
+	"testing"
+	"time"
+	_ "time/tzdata" // The tests shouldn't depend on the system's time zone database
+
+	"github.com/korjavin/whatsfordinner/pkg/models"
+)
+
+func TestParseWeekday(t *testing.T) {
+	tests := map[string]time.Weekday{"saturday": time.Saturday, " Sat ": time.Saturday, "SUNDAY": time.Sunday, "mon": time.Monday}
+	for value, want := range tests {
+		if got, ok := ParseWeekday(value); !ok || got != want {
+			t.Errorf("ParseWeekday(%q) = %v, %v, want %v", value, got, ok, want)
+		}
+	}
+	for _, value := range []string{"", "sa", "someday"} {
+		if _, ok := ParseWeekday(value); ok {
+			t.Errorf("ParseWeekday(%q) succeeded, want it rejected", value)
+		}
+	}
+}
+
+func TestRestockIsDueTheEveningBefore(t *testing.T) {
+	// 8 June 2024 is a Saturday
+	saturdayEvening := time.Date(2024, 6, 8, restockReminderHour, 15, 0, 0, time.UTC)
+	tests := []struct {
+		name        string
+		local       time.Time
+		shoppingDay time.Weekday
+		want        bool
+	}{
+		{"evening before", time.Date(2024, 6, 7, restockReminderHour, 0, 0, 0, time.UTC), time.Saturday, true},
+		{"earlier that day", time.Date(2024, 6, 7, restockReminderHour-1, 59, 0, 0, time.UTC), time.Saturday, false},
+		{"on shopping day", saturdayEvening, time.Saturday, false},
+		{"Sunday shopping wraps around to Saturday", saturdayEvening, time.Sunday, true},
+		{"another day", saturdayEvening, time.Wednesday, false},
+	}
+	for _, tt := range tests {
+		if got := isRestockDue(tt.local, tt.shoppingDay); got != tt.want {
+			t.Errorf("%s: isRestockDue(%s, %v) = %v, want %v", tt.name, tt.local.Format(time.RFC1123), tt.shoppingDay, got, tt.want)
+		}
+	}
+}
+
+func TestRestockReminderUsesTheChannelsTimeZone(t *testing.T) {
+	ts := newTestScheduler(t, 10*time.Minute)
+	saturday := time.Saturday
+	ts.setChannel(t, models.ChannelState{ChannelID: 1, Timezone: "Asia/Tokyo", ShoppingDay: &saturday})
+
+	// 18:00 on Friday in Tokyo is 09:00 UTC, 18:00 UTC is already Saturday there
+	ts.postRestockReminders(time.Date(2024, 6, 7, 18, 0, 0, 0, time.UTC))
+	if sent := ts.sentContaining("shopping day"); len(sent) != 0 {
+		t.Fatalf("reminded at 18:00 UTC, want the reminder at 18:00 in Tokyo")
+	}
+
+	friday := time.Date(2024, 6, 7, 9, 0, 0, 0, time.UTC)
+	ts.postRestockReminders(friday)
+	ts.postRestockReminders(friday.Add(30 * time.Minute))
+	if sent := ts.sentContaining("Tomorrow is Saturday"); len(sent) != 1 {
+		t.Errorf("sent %d reminders, want exactly one", len(sent))
+	}
+}
+
+func TestRestockReminderKeepsConcurrentChanges(t *testing.T) {
+	ts := newTestScheduler(t, 10*time.Minute)
+	saturday := time.Saturday
+	ts.setChannel(t, models.ChannelState{ChannelID: 1, ShoppingDay: &saturday})
+	friday := time.Date(2024, 6, 7, restockReminderHour, 0, 0, 0, time.UTC)
+
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			ts.postRestockReminders(friday)
+		}()
+	}
+	close(start)
+	_, err := ts.pollService.CreateVote(1, "poll-1", 0, []string{"Pasta", "Soup"})
+	wg.Wait()
+	if err != nil {
+		t.Fatalf("CreateVote failed: %v", err)
+	}
+
+	if sent := ts.sentContaining("Tomorrow is Saturday"); len(sent) != 1 {
+		t.Errorf("sent %d reminders, want exactly one", len(sent))
+	}
+	if vote, err := ts.pollService.GetCurrentVote(1); err != nil || vote.PollID != "poll-1" {
+		t.Errorf("GetCurrentVote = %v, %v, want the vote started while the reminder went out", vote, err)
+	}
+}
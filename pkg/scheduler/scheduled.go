@@ -0,0 +1,164 @@
+package scheduler
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/korjavin/whatsfordinner/pkg/models"
+)
+
+// ScheduleLayout is the date and time format accepted by /schedule
+const ScheduleLayout = "2006-01-02 15:04"
+
+// MaxScheduledDinners is how many dinners a channel can have scheduled at once
+const MaxScheduledDinners = 10
+
+// ErrTooManyScheduled is returned when a channel already has MaxScheduledDinners scheduled
+var ErrTooManyScheduled = errors.New("too many scheduled dinners")
+
+// ScheduleDinner schedules a one-off dinner poll for a channel
+func (s *Service) ScheduleDinner(channelID int64, at time.Time, createdBy string) (*models.ScheduledDinner, error) {
+	if !at.After(time.Now()) {
+		return nil, fmt.Errorf("scheduled time %s is in the past", at.Format(ScheduleLayout))
+	}
+
+	count, err := s.store.Count(fmt.Sprintf("scheduled:%d:", channelID))
+	if err != nil {
+		return nil, err
+	}
+	if count >= MaxScheduledDinners {
+		return nil, ErrTooManyScheduled
+	}
+
+	scheduled := &models.ScheduledDinner{
+		ID:        fmt.Sprintf("scheduled:%d:%d", channelID, at.Unix()),
+		ChannelID: channelID,
+		At:        at,
+		CreatedBy: createdBy,
+		CreatedAt: time.Now(),
+	}
+
+	if err := s.store.Set(scheduled.ID, scheduled); err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Scheduled dinner %s for channel %d", scheduled.ID, channelID)
+	return scheduled, nil
+}
+
+// ListScheduledDinners returns the dinners scheduled for a channel, earliest first
+func (s *Service) ListScheduledDinners(channelID int64) ([]models.ScheduledDinner, error) {
+	return s.listScheduledDinners(fmt.Sprintf("scheduled:%d:", channelID))
+}
+
+// CancelScheduledDinner removes a scheduled dinner
+func (s *Service) CancelScheduledDinner(id string) error {
+	return s.store.Delete(id)
+}
+
+// ParseScheduleTime parses a date and time like "2024-06-01 18:00" in the channel's time zone
+func (s *Service) ParseScheduleTime(channelID int64, value string) (time.Time, error) {
+	var channelState models.ChannelState
+	if err := s.store.Get(fmt.Sprintf("channel:%d", channelID), &channelState); err != nil {
+		channelState = models.ChannelState{ChannelID: channelID}
+	}
+
+	return time.ParseInLocation(ScheduleLayout, value, channelState.Location())
+}
+
+// SetTimezone sets the time zone a channel's dates and times are interpreted in
+func (s *Service) SetTimezone(channelID int64, timezone string) error {
+	if _, err := time.LoadLocation(timezone); err != nil {
+		return fmt.Errorf("unknown time zone %q: %w", timezone, err)
+	}
+
+	channelKey := fmt.Sprintf("channel:%d", channelID)
+	var channelState models.ChannelState
+	err := s.store.Get(channelKey, &channelState)
+	if err != nil {
+		// Create new channel state if it doesn't exist
+		channelState = models.ChannelState{
+			ChannelID: channelID,
+			FridgeID:  fmt.Sprintf("fridge:%d", channelID),
+		}
+	}
+
+	channelState.Timezone = timezone
+	channelState.LastActivity = time.Now()
+
+	return s.store.Set(channelKey, channelState)
+}
+
+// runScheduledDinners starts the dinner workflow for scheduled dinners once they're due
+func (s *Service) runScheduledDinners() {
+	s.logger.Info("Starting scheduled dinner runner")
+
+	ticker := time.NewTicker(1 * time.Minute)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.fireDueDinners(time.Now())
+		case <-s.stopChan:
+			return
+		}
+	}
+}
+
+// fireDueDinners starts the dinner workflow for every scheduled dinner due at now
+// and removes it, so each one fires only once
+func (s *Service) fireDueDinners(now time.Time) {
+	scheduled, err := s.listScheduledDinners("scheduled:")
+	if err != nil {
+		s.logger.Error("Failed to list scheduled dinners: %v", err)
+		return
+	}
+
+	for _, dinner := range scheduled {
+		if dinner.At.After(now) {
+			continue
+		}
+
+		// Remove the job first so a failing workflow doesn't fire again every minute
+		if err := s.store.Delete(dinner.ID); err != nil {
+			s.logger.Error("Failed to delete scheduled dinner %s: %v", dinner.ID, err)
+			continue
+		}
+
+		var channelState models.ChannelState
+		if err := s.store.Get(fmt.Sprintf("channel:%d", dinner.ChannelID), &channelState); err == nil && s.hasUnfinishedDinnerWorkflow(channelState) {
+			s.logger.Info("Skipping scheduled dinner %s, a dinner workflow is already running", dinner.ID)
+			continue
+		}
+
+		s.logger.Info("Starting scheduled dinner %s", dinner.ID)
+		s.startDinnerWorkflow(dinner.ChannelID)
+	}
+}
+
+// listScheduledDinners returns the scheduled dinners with keys starting with prefix, earliest first
+func (s *Service) listScheduledDinners(prefix string) ([]models.ScheduledDinner, error) {
+	keys, err := s.store.List(prefix)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list scheduled dinners: %w", err)
+	}
+
+	scheduled := make([]models.ScheduledDinner, 0, len(keys))
+	for _, key := range keys {
+		var dinner models.ScheduledDinner
+		if err := s.store.Get(key, &dinner); err != nil {
+			s.logger.Error("Failed to get scheduled dinner %s: %v", key, err)
+			continue
+		}
+		scheduled = append(scheduled, dinner)
+	}
+
+	sort.Slice(scheduled, func(i, j int) bool {
+		return scheduled[i].At.Before(scheduled[j].At)
+	})
+
+	return scheduled, nil
+}
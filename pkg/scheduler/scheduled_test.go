@@ -0,0 +1,73 @@
+package scheduler
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestDueScheduledDinnerFiresOnce(t *testing.T) {
+	ts := newTestScheduler(t, 10*time.Minute)
+	at := time.Now().Add(time.Hour).Truncate(time.Minute)
+	if _, err := ts.ScheduleDinner(1, at, "1"); err != nil {
+		t.Fatalf("ScheduleDinner failed: %v", err)
+	}
+	later, err := ts.ScheduleDinner(1, at.Add(24*time.Hour), "1")
+	if err != nil {
+		t.Fatalf("ScheduleDinner failed: %v", err)
+	}
+
+	ts.fireDueDinners(at.Add(-time.Minute))
+	if calls := ts.telegram.Calls(""); len(calls) != 0 {
+		t.Fatalf("made %d Telegram calls before the dinner was due", len(calls))
+	}
+
+	ts.fireDueDinners(at)
+	if calls := ts.telegram.Calls(""); len(calls) == 0 {
+		t.Fatal("the due dinner didn't start the dinner workflow")
+	}
+	scheduled, err := ts.ListScheduledDinners(1)
+	if err != nil {
+		t.Fatalf("ListScheduledDinners failed: %v", err)
+	}
+	if len(scheduled) != 1 || scheduled[0].ID != later.ID {
+		t.Fatalf("scheduled dinners = %v, want only the later one left", scheduled)
+	}
+
+	// The fired job is gone, so it doesn't start another workflow
+	ts.telegram.Reset()
+	ts.fireDueDinners(at.Add(time.Minute))
+	if calls := ts.telegram.Calls(""); len(calls) != 0 {
+		t.Errorf("made %d Telegram calls after the dinner already fired", len(calls))
+	}
+}
+
+func TestScheduledDinnersAreCappedPerChannel(t *testing.T) {
+	ts := newTestScheduler(t, 10*time.Minute)
+	at := time.Now().Add(time.Hour).Truncate(time.Minute)
+	var first string
+	for i := 0; i < MaxScheduledDinners; i++ {
+		scheduled, err := ts.ScheduleDinner(1, at.AddDate(0, 0, i), "1")
+		if err != nil {
+			t.Fatalf("ScheduleDinner %d failed: %v", i, err)
+		}
+		if i == 0 {
+			first = scheduled.ID
+		}
+	}
+
+	if _, err := ts.ScheduleDinner(1, at.AddDate(0, 1, 0), "1"); !errors.Is(err, ErrTooManyScheduled) {
+		t.Fatalf("scheduling one too many returned %v, want ErrTooManyScheduled", err)
+	}
+	// Channel 10's keys share channel 1's digits but not its prefix
+	if _, err := ts.ScheduleDinner(10, at, "1"); err != nil {
+		t.Errorf("another channel couldn't schedule a dinner: %v", err)
+	}
+
+	if err := ts.CancelScheduledDinner(first); err != nil {
+		t.Fatalf("CancelScheduledDinner failed: %v", err)
+	}
+	if _, err := ts.ScheduleDinner(1, at.AddDate(0, 1, 0), "1"); err != nil {
+		t.Errorf("scheduling after a cancel failed: %v", err)
+	}
+}
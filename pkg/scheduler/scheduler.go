@@ -2,14 +2,19 @@ package scheduler
 
 import (
 	"fmt"
+	"strings"
 	"time"
 
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 	"github.com/korjavin/whatsfordinner/pkg/dinner"
 	"github.com/korjavin/whatsfordinner/pkg/fridge"
 	"github.com/korjavin/whatsfordinner/pkg/logger"
+	"github.com/korjavin/whatsfordinner/pkg/messages"
 	"github.com/korjavin/whatsfordinner/pkg/models"
 	"github.com/korjavin/whatsfordinner/pkg/openai"
 	"github.com/korjavin/whatsfordinner/pkg/poll"
+	"github.com/korjavin/whatsfordinner/pkg/prefs"
+	"github.com/korjavin/whatsfordinner/pkg/stats"
 	"github.com/korjavin/whatsfordinner/pkg/storage"
 	"github.com/korjavin/whatsfordinner/pkg/telegram"
 )
@@ -21,10 +26,17 @@ type Service struct {
 	fridgeService *fridge.Service
 	pollService   *poll.Service
 	dinnerService *dinner.Service
+	statsService  *stats.Service
+	prefsService  *prefs.Service
 	openaiClient  *openai.Client
 	logger        *logger.Logger
 	cuisines      []string
 	stopChan      chan struct{}
+
+	cookVolunteerTimeout time.Duration
+	voteIdleGrace        time.Duration
+	repeatCooldown       dinner.Cooldown
+	ratingScale          int
 }
 
 // New creates a new scheduler service
@@ -34,8 +46,14 @@ func New(
 	fridgeService *fridge.Service,
 	pollService *poll.Service,
 	dinnerService *dinner.Service,
+	statsService *stats.Service,
+	prefsService *prefs.Service,
 	openaiClient *openai.Client,
 	cuisines []string,
+	cookVolunteerTimeout time.Duration,
+	voteIdleGrace time.Duration,
+	repeatCooldown dinner.Cooldown,
+	ratingScale int,
 ) *Service {
 	return &Service{
 		store:         store,
@@ -43,10 +61,17 @@ func New(
 		fridgeService: fridgeService,
 		pollService:   pollService,
 		dinnerService: dinnerService,
+		statsService:  statsService,
+		prefsService:  prefsService,
 		openaiClient:  openaiClient,
 		logger:        logger.New("scheduler"),
 		cuisines:      cuisines,
 		stopChan:      make(chan struct{}),
+
+		cookVolunteerTimeout: cookVolunteerTimeout,
+		voteIdleGrace:        voteIdleGrace,
+		repeatCooldown:       repeatCooldown,
+		ratingScale:          ratingScale,
 	}
 }
 
@@ -62,6 +87,35 @@ func (s *Service) Start() {
 	
 	// Start the cook volunteer timeout checker
 	go s.runCookVolunteerTimeoutChecker()
+	
+	// Start the scheduled dinner runner
+	go s.runScheduledDinners()
+	
+	// Start the channel state reconciler
+	go s.runReconciler()
+	
+	// Start the weekly summary
+	go s.runWeeklySummary()
+	
+	// Start the rating finalizer
+	go s.runRatingFinalizer()
+	
+	// Start the daily fridge snapshots
+	go s.runFridgeSnapshots()
+	
+	// Start the restock reminders
+	go s.runRestockReminders()
+	
+	// Start the nudges to plan the next week
+	go s.runPlanNudges()
+	
+	// Start the recipe step timers
+	go s.runStepTimers()
+	
+	// Start the idle vote closer
+	if s.voteIdleGrace > 0 {
+		go s.runIdleVoteCloser()
+	}
 }
 
 // Stop stops the scheduler
@@ -81,40 +135,50 @@ func (s *Service) runDailyDinnerScheduler() {
 	for {
 		select {
 		case <-ticker.C:
-			now := time.Now()
-			
-			// Check if it's around 3pm (15:00)
-			if now.Hour() == 15 && now.Minute() < 5 {
-				s.logger.Info("It's 3pm, checking if dinner workflow needs to be started")
-				
-				// Get all channels
-				channelKeys, err := s.store.List("channel:")
-				if err != nil {
-					s.logger.Error("Failed to list channels: %v", err)
-					continue
-				}
-				
-				for _, channelKey := range channelKeys {
-					var channelState models.ChannelState
-					err := s.store.Get(channelKey, &channelState)
-					if err != nil {
-						s.logger.Error("Failed to get channel state: %v", err)
-						continue
-					}
-					
-					// Check if dinner workflow has been started today
-					if !s.hasDinnerStartedToday(channelState) {
-						s.logger.Info("Starting dinner workflow for channel %d", channelState.ChannelID)
-						s.startDinnerWorkflow(channelState.ChannelID)
-					}
-				}
-			}
+			s.startDailyDinners(time.Now())
 		case <-s.stopChan:
 			return
 		}
 	}
 }
 
+// startDailyDinners starts the dinner workflow of every channel that wants it and hasn't had dinner started today,
+// if now is around 3pm
+func (s *Service) startDailyDinners(now time.Time) {
+	// Check if it's around 3pm (15:00)
+	if now.Hour() == 15 && now.Minute() < 5 {
+		s.logger.Info("It's 3pm, checking if dinner workflow needs to be started")
+		
+		// Get all channels
+		channelKeys, err := s.store.List("channel:")
+		if err != nil {
+			s.logger.Error("Failed to list channels: %v", err)
+			return
+		}
+		
+		for _, channelKey := range channelKeys {
+			var channelState models.ChannelState
+			err := s.store.Get(channelKey, &channelState)
+			if err != nil {
+				s.logger.Error("Failed to get channel state: %v", err)
+				continue
+			}
+			
+			// Some families only start dinner themselves
+			if !channelState.AutoDinnerEnabled() {
+				s.logger.Debug("Auto dinner is off for channel %d, skipping", channelState.ChannelID)
+				continue
+			}
+			
+			// Check if dinner workflow has been started today
+			if !s.hasDinnerStartedToday(channelState) {
+				s.logger.Info("Starting dinner workflow for channel %d", channelState.ChannelID)
+				s.startDinnerWorkflow(channelState.ChannelID)
+			}
+		}
+	}
+}
+
 // runDinnerTimeoutChecker checks for dinner workflows that need to be stopped at 9pm
 func (s *Service) runDinnerTimeoutChecker() {
 	s.logger.Info("Starting dinner timeout checker")
@@ -172,61 +236,103 @@ func (s *Service) runCookVolunteerTimeoutChecker() {
 	for {
 		select {
 		case <-ticker.C:
-			// Get all channels
-			channelKeys, err := s.store.List("channel:")
-			if err != nil {
-				s.logger.Error("Failed to list channels: %v", err)
-				continue
-			}
-			
-			for _, channelKey := range channelKeys {
-				var channelState models.ChannelState
-				err := s.store.Get(channelKey, &channelState)
-				if err != nil {
-					s.logger.Error("Failed to get channel state: %v", err)
-					continue
-				}
-				
-				// Check if there's a vote that has ended but no cook has been selected
-				if channelState.CurrentVote != nil && 
-				   !channelState.CurrentVote.EndedAt.IsZero() && 
-				   channelState.CurrentVote.WinningDish != "" && 
-				   len(channelState.CurrentVote.CookVolunteers) == 0 {
-					
-					voteID := channelState.CurrentVote.PollID
-					
-					// Check if we're already tracking this vote
-					startTime, exists := volunteerWaitStart[voteID]
-					if !exists {
-						// Start tracking this vote
-						volunteerWaitStart[voteID] = time.Now()
-						s.logger.Info("Started waiting for cook volunteers for vote %s in channel %d", voteID, channelState.ChannelID)
-					} else {
-						// Check if 15 minutes have passed
-						if time.Since(startTime) > 15*time.Minute {
-							s.logger.Info("No cook volunteers after 15 minutes for vote %s in channel %d", voteID, channelState.ChannelID)
-							
-							// Remove from tracking
-							delete(volunteerWaitStart, voteID)
-							
-							// Restart the dinner workflow
-							s.restartDinnerWorkflow(channelState.ChannelID)
-						}
-					}
-				} else if channelState.CurrentVote != nil && len(channelState.CurrentVote.CookVolunteers) > 0 {
-					// If there are volunteers, remove from tracking
-					voteID := channelState.CurrentVote.PollID
-					if _, exists := volunteerWaitStart[voteID]; exists {
-						delete(volunteerWaitStart, voteID)
-					}
-				}
-			}
+			s.checkCookVolunteers(volunteerWaitStart, time.Now())
 		case <-s.stopChan:
 			return
 		}
 	}
 }
 
+// checkCookVolunteers starts a new poll in the channels that waited longer than their
+// cook volunteer timeout for a volunteer. waitStart tracks when the waiting started per vote.
+func (s *Service) checkCookVolunteers(waitStart map[string]time.Time, now time.Time) {
+	// Get all channels
+	channelKeys, err := s.store.List("channel:")
+	if err != nil {
+		s.logger.Error("Failed to list channels: %v", err)
+		return
+	}
+	
+	for _, channelKey := range channelKeys {
+		var channelState models.ChannelState
+		err := s.store.Get(channelKey, &channelState)
+		if err != nil {
+			s.logger.Error("Failed to get channel state: %v", err)
+			continue
+		}
+		
+		// Check if there's a vote that has ended but no cook has been selected.
+		// EndVote clears the current vote, so the ended vote is looked up by the poll ID it left behind.
+		voteID := channelState.WaitingForCook
+		if voteID == "" {
+			continue
+		}
+		vote, err := s.pollService.GetVote(channelState.ChannelID, voteID)
+		if err != nil {
+			s.logger.Error("Failed to get vote %s: %v", voteID, err)
+			continue
+		}
+		if vote.EndedAt.IsZero() || vote.WinningDish == "" || len(vote.CookVolunteers) > 0 {
+			// Someone volunteered or the vote was reopened, so stop tracking it
+			delete(waitStart, voteID)
+			continue
+		}
+		
+		// Check if we're already tracking this vote
+		startTime, exists := waitStart[voteID]
+		if !exists {
+			// Start tracking this vote
+			waitStart[voteID] = now
+			s.logger.Info("Started waiting for cook volunteers for vote %s in channel %d", voteID, channelState.ChannelID)
+			continue
+		}
+		
+		// Check if the cook volunteer timeout has passed
+		timeout := s.volunteerTimeout(channelState)
+		if now.Sub(startTime) > timeout {
+			s.logger.Info("No cook volunteers after %v for vote %s in channel %d", timeout, voteID, channelState.ChannelID)
+			
+			// Remove from tracking
+			delete(waitStart, voteID)
+			
+			// Restart the dinner workflow
+			s.restartDinnerWorkflow(channelState.ChannelID, voteID, timeout)
+		}
+	}
+}
+
+// volunteerTimeout returns how long to wait for a cook volunteer in a channel,
+// preferring the channel's own setting over the configured default
+func (s *Service) volunteerTimeout(channelState models.ChannelState) time.Duration {
+	return channelState.Effective(s.channelDefaults()).CookVolunteerTimeout
+}
+
+// channelDefaults returns the settings of a channel that hasn't changed any of them,
+// with the cuisines, cook volunteer timeout and rating scale the scheduler was configured with
+func (s *Service) channelDefaults() models.Settings {
+	defaults := models.DefaultSettings()
+	if len(s.cuisines) > 0 {
+		defaults.Cuisines = s.cuisines
+	}
+	if s.cookVolunteerTimeout > 0 {
+		defaults.CookVolunteerTimeout = s.cookVolunteerTimeout
+	}
+	if s.ratingScale > 0 {
+		defaults.RatingScale = s.ratingScale
+	}
+	return defaults
+}
+
+// HasDinnerStartedToday checks if a dinner workflow has been started today for a channel
+func (s *Service) HasDinnerStartedToday(channelID int64) bool {
+	var channelState models.ChannelState
+	err := s.store.Get(fmt.Sprintf("channel:%d", channelID), &channelState)
+	if err != nil {
+		return false
+	}
+	return s.hasDinnerStartedToday(channelState)
+}
+
 // hasDinnerStartedToday checks if a dinner workflow has been started today for a channel
 func (s *Service) hasDinnerStartedToday(channelState models.ChannelState) bool {
 	// Check if there's a current dinner or vote
@@ -325,10 +431,26 @@ func (s *Service) startDinnerWorkflow(channelID int64) {
 	// Send a processing message
 	processingMsg, _ := s.bot.SendMessage(channelID, "🧐 Thinking about dinner options based on your ingredients... This might take a moment.")
 	
+	// A new day's dinner may suggest dishes that were re-rolled away before
+	if err := s.pollService.ClearRejections(channelID); err != nil {
+		s.logger.Error("Failed to clear rejected dishes: %v", err)
+	}
+	
 	// Get dinner suggestions from OpenAI
-	aiSuggestions, err := s.openaiClient.SuggestDinnerOptions(ingredientNames, s.cuisines, 4)
+	offline := false
+	cuisines := s.dinnerService.GetCuisines(channelID, s.cuisines)
+	aiSuggestions, err := s.openaiClient.WithChannel(channelID).SuggestDinnerOptions(ingredientNames, cuisines, 4)
 	if err != nil {
 		s.logger.Error("Failed to get dinner suggestions: %v", err)
+
+		// Fall back to scoring stored dishes against the fridge
+		aiSuggestions, err = s.dinnerService.OfflineSuggestions(channelID, cuisines, nil, 4)
+		if err != nil {
+			s.logger.Error("Failed to get offline suggestions: %v", err)
+		}
+		offline = len(aiSuggestions) > 0
+	}
+	if !offline && err != nil {
 		s.bot.EditMessage(channelID, processingMsg.MessageID, "😢 Sorry, I couldn't come up with dinner suggestions right now. Please try again later or use the /dinner command manually.")
 		return
 	}
@@ -343,6 +465,24 @@ func (s *Service) startDinnerWorkflow(channelID int64) {
 	
 	// Create a detailed message with suggestions
 	detailedMsg := "🍲 Here are some dinner suggestions based on your ingredients:\n\n"
+	if offline {
+		detailedMsg = "📴 The AI is unavailable right now, so here are some offline suggestions from your saved dishes:\n\n"
+	}
+	
+	intro := strings.TrimSpace(detailedMsg)
+	cards := make([]string, len(aiSuggestions))
+	
+	// Note when dishes were last cooked, to help the family decide
+	history, err := s.dinnerService.DishHistory(channelID)
+	if err != nil {
+		s.logger.Error("Failed to get dish history: %v", err)
+	}
+	loc := time.Local
+	var channelState models.ChannelState
+	if err := s.store.Get(fmt.Sprintf("channel:%d", channelID), &channelState); err == nil {
+		loc = channelState.Location()
+	}
+	now := time.Now().In(loc)
 	
 	// Add AI suggestions
 	for i, suggestion := range aiSuggestions {
@@ -352,14 +492,27 @@ func (s *Service) startDinnerWorkflow(channelID int64) {
 		
 		options[i] = name
 		
-		detailedMsg += fmt.Sprintf("🍴 *%s* (%s)\n%s\n\n", name, cuisine, description)
+		lastCooked := ""
+		if note := history.LastCookedNote(name, s.ratingScale, now); note != "" {
+			lastCooked = " · " + note
+		}
+		
+		cards[i] = fmt.Sprintf("🍴 %s (%s)%s\n%s", messages.Bold(name), cuisine, lastCooked, description)
+		detailedMsg += cards[i] + "\n\n"
+	}
+	
+	// With vote cards every dish gets its own message and vote button instead
+	if s.pollService.VoteCards(channelID) {
+		s.bot.EditMessage(channelID, processingMsg.MessageID, intro)
+		s.startCardVote(channelID, options, cards)
+		return
 	}
 	
 	// Edit the processing message to show the detailed suggestions
 	s.bot.EditMessage(channelID, processingMsg.MessageID, detailedMsg)
 	
 	// Create poll
-	pollMsg, err := s.bot.CreatePoll(channelID, "What should we cook tonight?", options)
+	pollMsg, err := s.bot.CreatePoll(channelID, s.pollService.GetPollQuestion(channelID), options)
 	if err != nil {
 		s.logger.Error("Failed to create poll: %v", err)
 		s.bot.SendMessage(channelID, "😢 Sorry, I couldn't create a poll for dinner options. Please try again later or use the /dinner command manually.")
@@ -375,8 +528,38 @@ func (s *Service) startDinnerWorkflow(channelID int64) {
 		s.logger.Error("Failed to create vote state: %v", err)
 	}
 	
-	// Send a message with voting instructions
-	s.bot.SendMessage(channelID, "🗳 Please vote for your preferred dinner option! The poll will close automatically when 2/3 of the channel members have voted.")
+	// Send a message with voting instructions and a button to ask for new suggestions
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("🎲 New suggestions (%d left)", poll.MaxRerolls), "reroll:"+pollID),
+		),
+	)
+	s.bot.SendMessageWithKeyboard(channelID, "🗳 Please vote for your preferred dinner option! The poll will close automatically when 2/3 of the channel members have voted.", keyboard)
+}
+
+// startCardVote posts the dinner options as vote cards and records the vote
+func (s *Service) startCardVote(channelID int64, options, cards []string) {
+	pollID := poll.NewCardPollID(time.Now())
+	header, cardIDs, err := s.bot.CreateVoteCards(channelID, s.pollService.GetPollQuestion(channelID), pollID, cards)
+	if err != nil {
+		s.logger.Error("Failed to create vote cards: %v", err)
+		s.bot.SendMessage(channelID, "😢 Sorry, I couldn't post the dinner options. Please try again later or use the /dinner command manually.")
+		return
+	}
+	s.logger.Info("Created card vote %s for channel %d", pollID, channelID)
+	
+	if _, err := s.pollService.CreateVote(channelID, pollID, header.MessageID, options); err != nil {
+		s.logger.Error("Failed to create vote state: %v", err)
+	} else if err := s.pollService.SetCardMessages(channelID, pollID, cardIDs); err != nil {
+		s.logger.Error("Failed to save vote cards: %v", err)
+	}
+	
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("🎲 New suggestions (%d left)", poll.MaxRerolls), "reroll:"+pollID),
+		),
+	)
+	s.bot.SendMessageWithKeyboard(channelID, "🗳 Tap the vote button of your preferred dish above! The vote will close automatically when 2/3 of the channel members have voted.", keyboard)
 }
 
 // stopDinnerWorkflow stops the dinner workflow for a channel
@@ -397,25 +580,25 @@ func (s *Service) stopDinnerWorkflow(channelID int64) {
 		// End the vote
 		s.logger.Info("Ending vote %s for channel %d", channelState.CurrentVote.PollID, channelID)
 		
-		// Get the current results
-		results, winningOption, err := s.pollService.GetVoteResults(channelID, channelState.CurrentVote.PollID)
+		vote, err := s.pollService.GetVote(channelID, channelState.CurrentVote.PollID)
 		if err != nil {
-			s.logger.Error("Failed to get vote results: %v", err)
-			winningOption = "No winner"
+			s.logger.Error("Failed to get vote: %v", err)
+			return
 		}
 		
-		// End the vote
-		err = s.pollService.EndVote(channelID, channelState.CurrentVote.PollID, winningOption)
+		// It's too late for a runoff, so a tie goes to the earliest option
+		outcome, err := s.closeVote(channelID, vote, time.Now())
 		if err != nil {
-			s.logger.Error("Failed to end vote: %v", err)
+			s.logger.Error("Failed to close vote: %v", err)
+			return
 		}
 		
 		// Send a message
 		s.bot.SendMessage(channelID, "⏰ It's getting late! The dinner poll has been closed automatically.")
 		
 		// If there are votes, announce the winner
-		if len(results) > 0 {
-			s.bot.SendMessage(channelID, fmt.Sprintf("🏆 The winning dish is *%s*.", winningOption))
+		if outcome.Winner != "" {
+			s.bot.SendMessage(channelID, "🏆 "+outcome.Summary(time.Now()))
 		} else {
 			s.bot.SendMessage(channelID, "😢 Nobody voted for dinner today.")
 		}
@@ -436,8 +619,9 @@ func (s *Service) stopDinnerWorkflow(channelID int64) {
 	}
 }
 
-// restartDinnerWorkflow restarts the dinner workflow for a channel
-func (s *Service) restartDinnerWorkflow(channelID int64) {
+// restartDinnerWorkflow restarts the dinner workflow for a channel that is still waiting
+// for a cook of the vote pollID
+func (s *Service) restartDinnerWorkflow(channelID int64, pollID string, waited time.Duration) {
 	s.logger.Info("Restarting dinner workflow for channel %d", channelID)
 	
 	// Get channel state
@@ -449,13 +633,13 @@ func (s *Service) restartDinnerWorkflow(channelID int64) {
 		return
 	}
 	
-	// Check if there's an active vote that has ended
-	if channelState.CurrentVote != nil && !channelState.CurrentVote.EndedAt.IsZero() {
+	// Check that no other vote started in the meantime
+	if channelState.WaitingForCook == pollID && channelState.CurrentVote == nil {
 		// Send a message
-		s.bot.SendMessage(channelID, "⏰ 15 minutes have passed and nobody volunteered to cook. Let's try again with a new poll!")
+		s.bot.SendMessage(channelID, fmt.Sprintf("⏰ Nobody volunteered to cook in %s. Let's try again with a new poll!", messages.FormatDuration(waited)))
 		
-		// Clear the current vote
-		channelState.CurrentVote = nil
+		// Stop waiting for a cook
+		channelState.WaitingForCook = ""
 		err = s.store.Set(channelKey, channelState)
 		if err != nil {
 			s.logger.Error("Failed to update channel state: %v", err)
@@ -0,0 +1,138 @@
+package scheduler
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/korjavin/whatsfordinner/internal/test"
+	"github.com/korjavin/whatsfordinner/pkg/dinner"
+	"github.com/korjavin/whatsfordinner/pkg/fridge"
+	"github.com/korjavin/whatsfordinner/pkg/models"
+	"github.com/korjavin/whatsfordinner/pkg/openai"
+	"github.com/korjavin/whatsfordinner/pkg/poll"
+	"github.com/korjavin/whatsfordinner/pkg/prefs"
+	"github.com/korjavin/whatsfordinner/pkg/stats"
+	"github.com/korjavin/whatsfordinner/pkg/storage"
+	"github.com/korjavin/whatsfordinner/pkg/telegram"
+)
+
+// testScheduler is a scheduler wired to a temporary store and fake Telegram and OpenAI APIs
+type testScheduler struct {
+	*Service
+	store    *storage.Store
+	telegram *test.Telegram
+	openai   *test.OpenAI
+}
+
+// newTestScheduler creates a scheduler that isn't started, so tests drive its checks with their own clock
+func newTestScheduler(t *testing.T, cookVolunteerTimeout time.Duration) *testScheduler {
+	t.Helper()
+
+	store := test.NewStore(t)
+	fakeTelegram := test.NewTelegram(t)
+	fakeOpenAI := test.NewOpenAI(t)
+
+	bot, err := telegram.NewWithEndpoint("test-token", fakeTelegram.Endpoint(), 1)
+	if err != nil {
+		t.Fatalf("failed to create bot: %v", err)
+	}
+	bot.SetPacing(0, 0)
+
+	openaiClient := openai.New("test-key", fakeOpenAI.BaseURL(), "test-model")
+	fridgeService := fridge.New(store)
+	dinnerService := dinner.New(store, fridgeService, openaiClient)
+	service := New(store, bot, fridgeService, poll.New(store), dinnerService, stats.New(store), prefs.New(store),
+		openaiClient, []string{"Italian"}, cookVolunteerTimeout, 0, dinner.Cooldown{}, 5)
+
+	return &testScheduler{Service: service, store: store, telegram: fakeTelegram, openai: fakeOpenAI}
+}
+
+// setChannel saves the state of a channel
+func (ts *testScheduler) setChannel(t *testing.T, channelState models.ChannelState) {
+	t.Helper()
+
+	if err := ts.store.Set(fmt.Sprintf("channel:%d", channelState.ChannelID), channelState); err != nil {
+		t.Fatalf("failed to save channel state: %v", err)
+	}
+}
+
+// sentContaining returns the messages sent so far that contain text
+func (ts *testScheduler) sentContaining(text string) []string {
+	var sent []string
+	for _, message := range ts.telegram.Texts() {
+		if strings.Contains(message, text) {
+			sent = append(sent, message)
+		}
+	}
+	return sent
+}
+
+// closeWithWinner starts a vote in a channel, votes for Pasta and closes the vote
+func (ts *testScheduler) closeWithWinner(t *testing.T, channelID int64, pollID string) {
+	t.Helper()
+
+	if _, err := ts.pollService.CreateVote(channelID, pollID, 0, []string{"Pasta", "Soup"}); err != nil {
+		t.Fatalf("CreateVote failed: %v", err)
+	}
+	if err := ts.pollService.RecordVote(channelID, pollID, "1", "Anna", "Pasta"); err != nil {
+		t.Fatalf("RecordVote failed: %v", err)
+	}
+	if _, err := ts.CloseVote(channelID, pollID, time.Now()); err != nil {
+		t.Fatalf("CloseVote failed: %v", err)
+	}
+}
+
+func TestCookVolunteerTimeoutIsRespected(t *testing.T) {
+	ts := newTestScheduler(t, 10*time.Minute)
+	start := time.Date(2024, 6, 1, 18, 0, 0, 0, time.UTC)
+
+	ts.closeWithWinner(t, 1, "poll-1")
+	// The second channel waits longer, it set its own timeout
+	ts.setChannel(t, models.ChannelState{ChannelID: 2, CookVolunteerTimeout: 30 * time.Minute})
+	ts.closeWithWinner(t, 2, "poll-2")
+
+	waitStart := make(map[string]time.Time)
+	ts.checkCookVolunteers(waitStart, start)
+	ts.checkCookVolunteers(waitStart, start.Add(9*time.Minute))
+	if sent := ts.sentContaining("Nobody volunteered"); len(sent) != 0 {
+		t.Fatalf("gave up on a volunteer before the timeout: %v", sent)
+	}
+
+	ts.checkCookVolunteers(waitStart, start.Add(11*time.Minute))
+	sent := ts.sentContaining("Nobody volunteered")
+	if len(sent) != 1 || !strings.Contains(sent[0], "10 minutes") {
+		t.Fatalf("after the timeout sent %v, want one message about 10 minutes", sent)
+	}
+
+	ts.checkCookVolunteers(waitStart, start.Add(31*time.Minute))
+	sent = ts.sentContaining("Nobody volunteered")
+	if len(sent) != 2 || !strings.Contains(sent[1], "30 minutes") {
+		t.Errorf("after the channel's own timeout sent %v, want a second message about 30 minutes", sent)
+	}
+
+	// Each channel gives up on its vote only once
+	ts.checkCookVolunteers(waitStart, start.Add(2*time.Hour))
+	if sent := ts.sentContaining("Nobody volunteered"); len(sent) != 2 {
+		t.Errorf("sent %d messages about missing volunteers, want 2", len(sent))
+	}
+}
+
+func TestCookVolunteerStopsTheTimeout(t *testing.T) {
+	ts := newTestScheduler(t, 10*time.Minute)
+	start := time.Date(2024, 6, 1, 18, 0, 0, 0, time.UTC)
+
+	ts.closeWithWinner(t, 1, "poll-1")
+
+	waitStart := make(map[string]time.Time)
+	ts.checkCookVolunteers(waitStart, start)
+	if err := ts.pollService.AddCookVolunteer(1, "poll-1", "1"); err != nil {
+		t.Fatalf("AddCookVolunteer failed: %v", err)
+	}
+	ts.checkCookVolunteers(waitStart, start.Add(time.Hour))
+
+	if sent := ts.sentContaining("Nobody volunteered"); len(sent) != 0 {
+		t.Errorf("gave up although someone volunteered: %v", sent)
+	}
+}
@@ -0,0 +1,52 @@
+package scheduler
+
+import (
+	"time"
+
+	"github.com/korjavin/whatsfordinner/pkg/models"
+)
+
+// fridgeSnapshotInterval is how often today's fridge snapshot is refreshed,
+// so the last one of the day reflects the fridge in the evening
+const fridgeSnapshotInterval = 1 * time.Hour
+
+// runFridgeSnapshots periodically records the number of ingredients in every fridge
+func (s *Service) runFridgeSnapshots() {
+	s.logger.Info("Starting fridge snapshots")
+
+	ticker := time.NewTicker(fridgeSnapshotInterval)
+	defer ticker.Stop()
+
+	s.snapshotFridges(time.Now())
+	for {
+		select {
+		case <-ticker.C:
+			s.snapshotFridges(time.Now())
+		case <-s.stopChan:
+			return
+		}
+	}
+}
+
+// snapshotFridges records today's ingredient count of every channel, dated in the channel's time zone
+func (s *Service) snapshotFridges(now time.Time) {
+	channelKeys, err := s.store.List("channel:")
+	if err != nil {
+		s.logger.Error("Failed to list channels: %v", err)
+		return
+	}
+
+	for _, channelKey := range channelKeys {
+		var channelState models.ChannelState
+		err := s.store.Get(channelKey, &channelState)
+		if err != nil {
+			s.logger.Error("Failed to get channel state: %v", err)
+			continue
+		}
+
+		_, err = s.fridgeService.Snapshot(channelState.ChannelID, now.In(channelState.Location()))
+		if err != nil {
+			s.logger.Error("Failed to snapshot fridge for channel %d: %v", channelState.ChannelID, err)
+		}
+	}
+}
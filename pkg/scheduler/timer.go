@@ -0,0 +1,102 @@
+package scheduler
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/korjavin/whatsfordinner/pkg/messages"
+	"github.com/korjavin/whatsfordinner/pkg/models"
+)
+
+// MaxStepTimers is how many recipe step timers a channel can have running at once
+const MaxStepTimers = 10
+
+// stepTimerTick is how often due step timers are checked, short enough for minute-long timers
+const stepTimerTick = 10 * time.Second
+
+// ErrTooManyTimers is returned when a channel already has MaxStepTimers running
+var ErrTooManyTimers = errors.New("too many step timers")
+
+// StartStepTimer starts a one-shot reminder for a recipe step that goes off duration after now
+func (s *Service) StartStepTimer(channelID int64, step, startedBy string, duration time.Duration, now time.Time) (*models.StepTimer, error) {
+	if duration <= 0 {
+		return nil, fmt.Errorf("timer duration must be positive, got %s", duration)
+	}
+
+	count, err := s.store.Count(fmt.Sprintf("timer:%d:", channelID))
+	if err != nil {
+		return nil, err
+	}
+	if count >= MaxStepTimers {
+		return nil, ErrTooManyTimers
+	}
+
+	timer := &models.StepTimer{
+		ID:        fmt.Sprintf("timer:%d:%d", channelID, now.UnixNano()),
+		ChannelID: channelID,
+		Step:      step,
+		StartedBy: startedBy,
+		DueAt:     now.Add(duration),
+		CreatedAt: now,
+	}
+
+	if err := s.store.Set(timer.ID, timer); err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Started step timer %s for channel %d, due in %s", timer.ID, channelID, duration)
+	return timer, nil
+}
+
+// runStepTimers checks for cooking step timers that are due
+func (s *Service) runStepTimers() {
+	s.logger.Info("Starting step timer runner")
+
+	ticker := time.NewTicker(stepTimerTick)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.fireDueTimers(time.Now())
+		case <-s.stopChan:
+			return
+		}
+	}
+}
+
+// fireDueTimers pings the channel of every step timer due at now and removes it,
+// so each one goes off only once
+func (s *Service) fireDueTimers(now time.Time) {
+	keys, err := s.store.List("timer:")
+	if err != nil {
+		s.logger.Error("Failed to list step timers: %v", err)
+		return
+	}
+
+	for _, key := range keys {
+		var timer models.StepTimer
+		if err := s.store.Get(key, &timer); err != nil {
+			s.logger.Error("Failed to get step timer %s: %v", key, err)
+			continue
+		}
+		if timer.DueAt.After(now) {
+			continue
+		}
+
+		if err := s.store.Delete(key); err != nil {
+			s.logger.Error("Failed to delete step timer %s: %v", key, err)
+			continue
+		}
+
+		msgText := fmt.Sprintf("⏰ Time's up! %s", messages.EscapeMarkdown(timer.Step))
+		if timer.StartedBy != "" {
+			msgText = fmt.Sprintf("⏰ @%s, time's up! %s", messages.EscapeMarkdown(timer.StartedBy), messages.EscapeMarkdown(timer.Step))
+		}
+		if late := now.Sub(timer.DueAt); late > time.Minute {
+			msgText += fmt.Sprintf("\n\n(Sorry, this timer went off %s late.)", messages.FormatDuration(late))
+		}
+		s.bot.SendMessage(timer.ChannelID, msgText)
+	}
+}
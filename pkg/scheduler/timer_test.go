@@ -0,0 +1,79 @@
+package scheduler
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/korjavin/whatsfordinner/pkg/models"
+)
+
+func TestStepTimersFireOnceWhenDue(t *testing.T) {
+	ts := newTestScheduler(t, 10*time.Minute)
+	now := time.Date(2024, 6, 1, 18, 0, 0, 0, time.UTC)
+
+	if _, err := ts.StartStepTimer(1, "Step 2: Simmer for 20 minutes", "anna", 20*time.Minute, now); err != nil {
+		t.Fatalf("StartStepTimer failed: %v", err)
+	}
+	if _, err := ts.StartStepTimer(1, "Step 3: Rest 5 minutes", "", 5*time.Minute, now.Add(time.Second)); err != nil {
+		t.Fatalf("StartStepTimer failed: %v", err)
+	}
+	if _, err := ts.StartStepTimer(1, "Step 4", "anna", 0, now); err == nil {
+		t.Error("StartStepTimer without a duration succeeded, want an error")
+	}
+
+	ts.fireDueTimers(now.Add(5 * time.Minute))
+	if sent := ts.telegram.Texts(); len(sent) != 0 {
+		t.Fatalf("sent %v before any timer was due", sent)
+	}
+
+	ts.fireDueTimers(now.Add(5*time.Minute + time.Second))
+	if sent := ts.sentContaining("Time's up! Step 3"); len(sent) != 1 {
+		t.Fatalf("sent %v, want the 5 minute timer to go off", ts.telegram.Texts())
+	}
+
+	ts.telegram.Reset()
+	ts.fireDueTimers(now.Add(20 * time.Minute))
+	ts.fireDueTimers(now.Add(21 * time.Minute))
+	if sent := ts.sentContaining("@anna, time's up! Step 2"); len(sent) != 1 || len(ts.telegram.Texts()) != 1 {
+		t.Errorf("sent %v, want the 20 minute timer to ping anna once", ts.telegram.Texts())
+	}
+}
+
+func TestStepTimerThatFiresLateSaysSo(t *testing.T) {
+	ts := newTestScheduler(t, 10*time.Minute)
+	now := time.Date(2024, 6, 1, 18, 0, 0, 0, time.UTC)
+	if _, err := ts.StartStepTimer(1, "Step 1: Boil for 10 minutes", "", 10*time.Minute, now); err != nil {
+		t.Fatalf("StartStepTimer failed: %v", err)
+	}
+
+	// The bot was down while the timer was due
+	ts.fireDueTimers(now.Add(15 * time.Minute))
+	if sent := ts.sentContaining("went off 5 minutes late"); len(sent) != 1 {
+		t.Errorf("sent %v, want the late timer to apologize", ts.telegram.Texts())
+	}
+}
+
+func TestStepTimersAreCapped(t *testing.T) {
+	ts := newTestScheduler(t, 10*time.Minute)
+	now := time.Date(2024, 6, 1, 18, 0, 0, 0, time.UTC)
+
+	for i := 0; i < MaxStepTimers; i++ {
+		if _, err := ts.StartStepTimer(1, "Step 1", "", time.Hour, now.Add(time.Duration(i)*time.Second)); err != nil {
+			t.Fatalf("StartStepTimer %d failed: %v", i+1, err)
+		}
+	}
+	if _, err := ts.StartStepTimer(1, "Step 1", "", time.Hour, now.Add(time.Minute)); !errors.Is(err, ErrTooManyTimers) {
+		t.Errorf("StartStepTimer over the cap returned %v, want ErrTooManyTimers", err)
+	}
+
+	// Other channels have their own timers
+	timer, err := ts.StartStepTimer(2, "Step 1", "", time.Hour, now)
+	if err != nil {
+		t.Fatalf("StartStepTimer in another channel failed: %v", err)
+	}
+	var saved models.StepTimer
+	if err := ts.store.Get(timer.ID, &saved); err != nil || !saved.DueAt.Equal(now.Add(time.Hour)) {
+		t.Errorf("saved timer = %+v (%v), want it due in an hour", saved, err)
+	}
+}
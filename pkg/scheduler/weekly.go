@@ -0,0 +1,148 @@
+package scheduler
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/korjavin/whatsfordinner/pkg/messages"
+	"github.com/korjavin/whatsfordinner/pkg/models"
+	"github.com/korjavin/whatsfordinner/pkg/storage"
+)
+
+// The weekly summary is posted on Sunday evening in each channel's time zone
+const (
+	weeklySummaryDay  = time.Sunday
+	weeklySummaryHour = 19
+)
+
+// runWeeklySummary posts the weekly summary with the cook of the week
+func (s *Service) runWeeklySummary() {
+	s.logger.Info("Starting weekly summary scheduler")
+
+	ticker := time.NewTicker(1 * time.Minute)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.postWeeklySummaries(time.Now())
+		case <-s.stopChan:
+			return
+		}
+	}
+}
+
+// postWeeklySummaries posts the weekly summary in every channel where it's due
+func (s *Service) postWeeklySummaries(now time.Time) {
+	channelKeys, err := s.store.List("channel:")
+	if err != nil {
+		s.logger.Error("Failed to list channels: %v", err)
+		return
+	}
+
+	for _, channelKey := range channelKeys {
+		// Claim the summary inside the update, so nothing else saved to the channel meanwhile is overwritten
+		var channelState models.ChannelState
+		var due bool
+		err := s.store.Update(channelKey, &channelState, func() error {
+			// A retried update must not remember that an attempt before it claimed the send
+			due = false
+			local := now.In(channelState.Location())
+			if local.Weekday() != weeklySummaryDay || local.Hour() != weeklySummaryHour {
+				return storage.ErrNoChange
+			}
+			// Only post once per week
+			if now.Sub(channelState.LastWeeklySummary) < 24*time.Hour {
+				return storage.ErrNoChange
+			}
+			channelState.LastWeeklySummary = now
+			due = true
+			return nil
+		})
+		if err != nil {
+			s.logger.Error("Failed to update channel state: %v", err)
+			continue
+		}
+		if !due {
+			continue
+		}
+
+		s.postWeeklySummary(channelState.ChannelID, now.AddDate(0, 0, -7), channelState.Location())
+	}
+}
+
+// postWeeklySummary posts a summary of the dinners since the given time, dated in the channel's time zone
+func (s *Service) postWeeklySummary(channelID int64, since time.Time, loc *time.Location) {
+	dinners, err := s.statsService.PeriodDinners(channelID, since)
+	if err != nil {
+		s.logger.Error("Failed to get the week's dinners: %v", err)
+		return
+	}
+
+	if len(dinners) == 0 {
+		s.bot.SendMessage(channelID, "📅 *Weekly summary*\n\nNo dinners were cooked this week. Let's get cooking next week! 🍳")
+		return
+	}
+
+	msgText := fmt.Sprintf("📅 *Weekly summary*\n\nYou cooked %d dinners together this week:\n", len(dinners))
+	for _, dinner := range dinners {
+		msgText += fmt.Sprintf("• %s: %s\n", messages.FormatDate(dinner.StartedAt, loc), messages.EscapeMarkdown(dinner.Dish.Name))
+	}
+
+	cooks, err := s.statsService.CookOfThePeriod(channelID, since)
+	if err != nil {
+		s.logger.Error("Failed to get the cook of the week: %v", err)
+	}
+
+	if len(cooks) > 0 {
+		names := make([]string, len(cooks))
+		for i, cook := range cooks {
+			names[i] = messages.Bold(s.cookName(channelID, cook))
+		}
+
+		if len(cooks) == 1 {
+			msgText += fmt.Sprintf("\n🎉 Cook of the week: %s with %d dinners", names[0], cooks[0].CookCount)
+		} else {
+			msgText += fmt.Sprintf("\n🎉 Cooks of the week: %s with %d dinners each", strings.Join(names, " and "), cooks[0].CookCount)
+		}
+		if cooks[0].AvgRating > 0 {
+			msgText += fmt.Sprintf(" and an average rating of %.1f⭐", cooks[0].AvgRating)
+		}
+		msgText += "! 👏"
+	}
+
+	s.bot.SendMessage(channelID, msgText)
+}
+
+// cookName returns a display name for a cook, asking Telegram if the stats don't know it
+func (s *Service) cookName(channelID int64, cook models.CookStat) string {
+	if cook.Username != "" {
+		return cook.Username
+	}
+
+	if name := s.memberName(channelID, cook.UserID); name != "" {
+		return name
+	}
+
+	return "our mystery cook"
+}
+
+// memberName looks up the display name of a chat member, or returns "" if it can't be found
+func (s *Service) memberName(channelID int64, userIDStr string) string {
+	userID, err := strconv.ParseInt(userIDStr, 10, 64)
+	if err != nil {
+		return ""
+	}
+
+	member, err := s.bot.GetChatMember(channelID, userID)
+	if err != nil || member.User == nil {
+		return ""
+	}
+
+	if member.User.UserName != "" {
+		return member.User.UserName
+	}
+	return member.User.FirstName
+}
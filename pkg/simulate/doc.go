@@ -0,0 +1,3 @@
+// Package simulate runs the whole dinner workflow with canned data for development and demos.
+// It drives the same poll and dinner services as the bot, without Telegram or timers.
+package simulate
@@ -0,0 +1,141 @@
+package simulate
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/korjavin/whatsfordinner/pkg/dinner"
+	"github.com/korjavin/whatsfordinner/pkg/logger"
+	"github.com/korjavin/whatsfordinner/pkg/models"
+	"github.com/korjavin/whatsfordinner/pkg/poll"
+	"github.com/korjavin/whatsfordinner/pkg/storage"
+)
+
+// ErrBusy is returned when a channel has a poll or dinner in progress that a simulation would overwrite
+var ErrBusy = errors.New("channel has a poll or dinner in progress")
+
+// VoteThreshold is the share of members that has to vote before a poll closes, as in the real workflow
+const VoteThreshold = models.DefaultVoteThreshold
+
+// Voters are the fake members that vote in a simulation, the first one cooks
+var Voters = []string{"sim:alice", "sim:bob", "sim:carol"}
+
+// Dishes are the canned poll options, the first one wins
+var Dishes = []models.Dish{
+	{
+		Name:         "Spaghetti Carbonara",
+		Cuisine:      "Italian",
+		Ingredients:  []string{"spaghetti", "eggs", "bacon", "parmesan"},
+		Instructions: []string{"Boil the spaghetti", "Fry the bacon", "Mix eggs and parmesan", "Toss everything together off the heat"},
+		Servings:     4,
+	},
+	{
+		Name:         "Borscht",
+		Cuisine:      "Russian",
+		Ingredients:  []string{"beetroot", "cabbage", "potatoes", "carrot", "onion"},
+		Instructions: []string{"Simmer the vegetables", "Season and serve with sour cream"},
+		Servings:     4,
+	},
+}
+
+// Service runs simulated dinner workflows
+type Service struct {
+	store         *storage.Store
+	pollService   *poll.Service
+	dinnerService *dinner.Service
+	logger        *logger.Logger
+}
+
+// New creates a new simulation service
+func New(store *storage.Store, pollService *poll.Service, dinnerService *dinner.Service) *Service {
+	return &Service{
+		store:         store,
+		pollService:   pollService,
+		dinnerService: dinnerService,
+		logger:        logger.New(""),
+	}
+}
+
+// Run takes a channel through the whole workflow: it creates a poll, records fake votes until
+// the threshold is crossed, ends the poll, picks a cook, creates the dinner and finishes it.
+// It returns a line per step, including the steps that ran before an error.
+func (s *Service) Run(channelID int64) ([]string, error) {
+	var steps []string
+	step := func(format string, args ...interface{}) {
+		line := fmt.Sprintf(format, args...)
+		s.logger.Info("Simulation in channel %d: %s", channelID, line)
+		steps = append(steps, line)
+	}
+
+	var channelState models.ChannelState
+	err := s.store.Get(fmt.Sprintf("channel:%d", channelID), &channelState)
+	if err != nil && !errors.Is(err, storage.ErrNotFound) {
+		return steps, err
+	}
+	if (channelState.CurrentVote != nil && channelState.CurrentVote.EndedAt.IsZero()) ||
+		(channelState.CurrentDinner != nil && channelState.CurrentDinner.FinishedAt.IsZero()) {
+		return steps, ErrBusy
+	}
+
+	// Poll
+	options := make([]string, len(Dishes))
+	for i, dish := range Dishes {
+		options[i] = dish.Name
+	}
+	pollID := fmt.Sprintf("sim-%d", time.Now().UnixNano())
+	if _, err := s.pollService.CreateVote(channelID, pollID, 0, options); err != nil {
+		return steps, fmt.Errorf("failed to create vote: %w", err)
+	}
+	step("Created poll %s with %d options", pollID, len(options))
+
+	// Votes, until the threshold is crossed
+	winner := ""
+	for i, voter := range Voters {
+		if err := s.pollService.RecordVote(channelID, pollID, voter, voter, options[0]); err != nil {
+			return steps, fmt.Errorf("failed to record vote: %w", err)
+		}
+
+		reached, winningOption, err := s.pollService.CheckVoteThreshold(channelID, pollID, len(Voters), VoteThreshold)
+		if err != nil {
+			return steps, fmt.Errorf("failed to check vote threshold: %w", err)
+		}
+		step("%s voted for %s (%d/%d voted)", voter, options[0], i+1, len(Voters))
+		if reached {
+			winner = winningOption
+			break
+		}
+	}
+	if winner == "" {
+		return steps, fmt.Errorf("vote threshold was never reached")
+	}
+
+	if err := s.pollService.EndVote(channelID, pollID, winner); err != nil {
+		return steps, fmt.Errorf("failed to end vote: %w", err)
+	}
+	step("Poll closed, %s won", winner)
+
+	// Cook
+	cook := Voters[0]
+	if err := s.pollService.AddCookVolunteer(channelID, pollID, cook); err != nil {
+		return steps, fmt.Errorf("failed to add cook volunteer: %w", err)
+	}
+	if err := s.pollService.SelectCook(channelID, pollID, cook); err != nil {
+		return steps, fmt.Errorf("failed to select cook: %w", err)
+	}
+	step("%s volunteered and was selected to cook", cook)
+
+	// Dinner
+	d, err := s.dinnerService.CreateDinner(channelID, Dishes[0], cook)
+	if err != nil {
+		return steps, fmt.Errorf("failed to create dinner: %w", err)
+	}
+	step("Dinner %s started", d.ID)
+
+	if err := s.dinnerService.FinishDinner(channelID); err != nil {
+		return steps, fmt.Errorf("failed to finish dinner: %w", err)
+	}
+	step("Dinner finished, ratings are open for %s", dinner.RatingWindow)
+
+	return steps, nil
+}
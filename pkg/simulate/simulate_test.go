@@ -0,0 +1,114 @@
+package simulate
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/korjavin/whatsfordinner/internal/test"
+	"github.com/korjavin/whatsfordinner/pkg/audit"
+	"github.com/korjavin/whatsfordinner/pkg/dinner"
+	"github.com/korjavin/whatsfordinner/pkg/fridge"
+	"github.com/korjavin/whatsfordinner/pkg/poll"
+)
+
+// newTestService creates a simulation service on a temporary store
+func newTestService(t *testing.T) *Service {
+	t.Helper()
+
+	store := test.NewStore(t)
+	return New(store, poll.New(store), dinner.New(store, fridge.New(store), nil))
+}
+
+func TestRunAdvancesThroughEachState(t *testing.T) {
+	service := newTestService(t)
+
+	steps, err := service.Run(1)
+	if err != nil {
+		t.Fatalf("Run failed after %q: %v", steps, err)
+	}
+
+	want := []string{"Created poll", "voted for", "Poll closed", "selected to cook", "Dinner dinner:1:", "Dinner finished"}
+	joined := strings.Join(steps, "\n")
+	last := -1
+	for _, part := range want {
+		i := strings.Index(joined, part)
+		if i < 0 || i < last {
+			t.Fatalf("steps %q don't have %q in order", steps, part)
+		}
+		last = i
+	}
+
+	vote, err := service.pollService.GetLastVote(1)
+	if err != nil {
+		t.Fatalf("GetLastVote failed: %v", err)
+	}
+	if vote.EndedAt.IsZero() || vote.WinningDish != Dishes[0].Name || vote.SelectedCook != Voters[0] {
+		t.Errorf("vote = %+v, want it ended with %s won and %s cooking", *vote, Dishes[0].Name, Voters[0])
+	}
+	dinners, err := service.dinnerService.ListDinners(1)
+	if err != nil {
+		t.Fatalf("ListDinners failed: %v", err)
+	}
+	if len(dinners) != 1 || dinners[0].FinishedAt.IsZero() || dinners[0].Cook != Voters[0] {
+		t.Errorf("dinners = %+v, want one finished by %s", dinners, Voters[0])
+	}
+
+	// Finished workflows don't block another simulation
+	if _, err := service.Run(1); err != nil {
+		t.Errorf("second Run failed: %v", err)
+	}
+}
+
+func TestRunLeavesBusyChannelsAlone(t *testing.T) {
+	service := newTestService(t)
+	if _, err := service.pollService.CreateVote(1, "poll-1", 1, []string{"Soup", "Curry"}); err != nil {
+		t.Fatalf("CreateVote failed: %v", err)
+	}
+
+	if steps, err := service.Run(1); !errors.Is(err, ErrBusy) || len(steps) != 0 {
+		t.Errorf("Run with an open poll returned %q, %v, want ErrBusy before any step", steps, err)
+	}
+}
+
+func TestEachTransitionIsAudited(t *testing.T) {
+	service := newTestService(t)
+	auditService := audit.New(service.store)
+
+	if _, err := service.Run(1); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	dinners, err := service.dinnerService.ListDinners(1)
+	if err != nil || len(dinners) != 1 {
+		t.Fatalf("ListDinners returned %d dinners, %v, want the simulated one", len(dinners), err)
+	}
+	later := dinners[0].FinishedAt.Add(dinner.RatingWindow)
+	if _, err := service.dinnerService.FinalizeDueRatings(1, later); err != nil {
+		t.Fatalf("FinalizeDueRatings failed: %v", err)
+	}
+	if _, err := service.dinnerService.ReopenRatings(1, dinners[0].ID, later); err != nil {
+		t.Fatalf("ReopenRatings failed: %v", err)
+	}
+	if _, err := service.pollService.CreateVote(1, "poll-2", 1, []string{"Soup", "Curry"}); err != nil {
+		t.Fatalf("CreateVote failed: %v", err)
+	}
+	if _, err := service.pollService.RerollVote(1, "poll-2"); err != nil {
+		t.Fatalf("RerollVote failed: %v", err)
+	}
+
+	events, err := auditService.Recent(1, 0)
+	if err != nil {
+		t.Fatalf("Recent failed: %v", err)
+	}
+	var types []string
+	for _, event := range events {
+		types = append(types, event.Type)
+	}
+	want := []string{
+		audit.VoteCreated, audit.VoteEnded, audit.CookSelected, audit.DinnerCreated, audit.DinnerFinished,
+		audit.RatingsFinalized, audit.RatingsReopened, audit.VoteCreated, audit.VoteRerolled,
+	}
+	if strings.Join(types, " ") != strings.Join(want, " ") {
+		t.Errorf("audit events = %v, want %v", types, want)
+	}
+}
@@ -1,6 +1,7 @@
 package state
 
 import (
+	"strconv"
 	"sync"
 	"time"
 )
@@ -19,6 +20,9 @@ const (
 	StateSuggestingDish State = "suggesting_dish"
 )
 
+// stateExpiry is how long a chat state lives after it was last updated
+const stateExpiry = 10 * time.Minute
+
 // ChatState represents the state of a chat
 type ChatState struct {
 	State     State
@@ -28,14 +32,54 @@ type ChatState struct {
 
 // Manager manages chat states
 type Manager struct {
-	states map[int64]ChatState
-	mu     sync.RWMutex
+	states   map[int64]ChatState
+	mu       sync.RWMutex
+	stopChan chan struct{}
+	stopOnce sync.Once
 }
 
 // New creates a new state manager
 func New() *Manager {
 	return &Manager{
-		states: make(map[int64]ChatState),
+		states:   make(map[int64]ChatState),
+		stopChan: make(chan struct{}),
+	}
+}
+
+// StartSweeper periodically removes expired chat states,
+// so abandoned states don't stay in memory until they're read again
+func (m *Manager) StartSweeper(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				m.sweep()
+			case <-m.stopChan:
+				return
+			}
+		}
+	}()
+}
+
+// StopSweeper stops the sweeper started by StartSweeper
+func (m *Manager) StopSweeper() {
+	m.stopOnce.Do(func() {
+		close(m.stopChan)
+	})
+}
+
+// sweep removes all chat states older than the expiry
+func (m *Manager) sweep() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for chatID, state := range m.states {
+		if time.Since(state.Timestamp) > stateExpiry {
+			delete(m.states, chatID)
+		}
 	}
 }
 
@@ -66,7 +110,7 @@ func (m *Manager) GetState(chatID int64) State {
 	defer m.mu.RUnlock()
 	if state, ok := m.states[chatID]; ok {
 		// If the state is older than 10 minutes, reset it to normal
-		if time.Since(state.Timestamp) > 10*time.Minute {
+		if time.Since(state.Timestamp) > stateExpiry {
 			m.mu.RUnlock()
 			m.mu.Lock()
 			delete(m.states, chatID)
@@ -119,7 +163,7 @@ func (m *Manager) GetData(chatID int64, key string) (string, bool) {
 
 	if state, ok := m.states[chatID]; ok {
 		// If the state is older than 10 minutes, reset it to normal
-		if time.Since(state.Timestamp) > 10*time.Minute {
+		if time.Since(state.Timestamp) > stateExpiry {
 			m.mu.RUnlock()
 			m.mu.Lock()
 			delete(m.states, chatID)
@@ -138,6 +182,29 @@ func (m *Manager) GetData(chatID int64, key string) (string, bool) {
 	return "", false
 }
 
+// IncrementData adds one to a counter kept in the data of a chat and returns the new count.
+// A missing or non-numeric value counts as zero.
+func (m *Manager) IncrementData(chatID int64, key string) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	state, ok := m.states[chatID]
+	if !ok || time.Since(state.Timestamp) > stateExpiry {
+		state = ChatState{State: StateNormal}
+	}
+	if state.Data == nil {
+		state.Data = make(map[string]string)
+	}
+
+	count, _ := strconv.Atoi(state.Data[key])
+	count++
+	state.Data[key] = strconv.Itoa(count)
+	state.Timestamp = time.Now()
+	m.states[chatID] = state
+
+	return count
+}
+
 // ClearData clears a data value for a chat
 func (m *Manager) ClearData(chatID int64, key string) {
 	m.mu.Lock()
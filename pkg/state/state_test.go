@@ -0,0 +1,72 @@
+package state
+
+import (
+	"testing"
+	"time"
+)
+
+// stored reports whether the manager holds a state for a chat, without expiring it like a read would
+func stored(m *Manager, chatID int64) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	_, ok := m.states[chatID]
+	return ok
+}
+
+func TestSweeperRemovesExpiredStates(t *testing.T) {
+	m := New()
+	m.SetState(1, StateAddingIngredients)
+	m.SetState(2, StateAddingPhotos)
+
+	// Chat 1 was abandoned a while ago
+	m.mu.Lock()
+	old := m.states[1]
+	old.Timestamp = time.Now().Add(-stateExpiry - time.Minute)
+	m.states[1] = old
+	m.mu.Unlock()
+
+	m.StartSweeper(5 * time.Millisecond)
+	defer m.StopSweeper()
+
+	deadline := time.Now().Add(time.Second)
+	for stored(m, 1) {
+		if time.Now().After(deadline) {
+			t.Fatal("the sweeper didn't remove the expired state")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if !stored(m, 2) {
+		t.Error("the sweeper removed a state that hasn't expired")
+	}
+	if got := m.GetState(2); got != StateAddingPhotos {
+		t.Errorf("GetState(2) = %s, want %s", got, StateAddingPhotos)
+	}
+}
+
+func TestStopSweeperTwice(t *testing.T) {
+	m := New()
+	m.StartSweeper(time.Millisecond)
+	m.StopSweeper()
+	m.StopSweeper()
+}
+
+func TestDataSurvivesStateChanges(t *testing.T) {
+	m := New()
+	m.SetData(1, "photo_count", "2")
+	m.SetState(1, StateAddingPhotos)
+
+	if got, ok := m.GetData(1, "photo_count"); !ok || got != "2" {
+		t.Errorf("GetData() = %q, %v after changing the state, want 2, true", got, ok)
+	}
+	if got := m.IncrementData(1, "photo_count"); got != 3 {
+		t.Errorf("IncrementData() = %d, want 3", got)
+	}
+
+	m.ClearState(1)
+	if got := m.GetState(1); got != StateNormal {
+		t.Errorf("GetState() = %s after ClearState, want %s", got, StateNormal)
+	}
+	if _, ok := m.GetData(1, "photo_count"); ok {
+		t.Error("ClearState kept the chat's data")
+	}
+}
@@ -0,0 +1,57 @@
+package stats
+
+import (
+	"sort"
+	"time"
+
+	"github.com/korjavin/whatsfordinner/pkg/models"
+)
+
+// CookBalance compares how often each family member ate with how often they cooked,
+// counting the dinners with recorded attendance. Whoever ate the most without cooking comes first.
+func (s *Service) CookBalance(channelID int64) ([]models.AttendanceStat, error) {
+	dinners, err := s.PeriodDinners(channelID, time.Time{})
+	if err != nil {
+		return nil, err
+	}
+
+	return cookBalance(dinners, s.usernames(channelID)), nil
+}
+
+// cookBalance computes the cook balance of the given dinners, see CookBalance
+func cookBalance(dinners []models.Dinner, usernames map[string]string) []models.AttendanceStat {
+	balance := make(map[string]*models.AttendanceStat)
+	stat := func(userID string) *models.AttendanceStat {
+		if balance[userID] == nil {
+			balance[userID] = &models.AttendanceStat{UserID: userID, Username: usernames[userID]}
+		}
+		return balance[userID]
+	}
+
+	for _, dinner := range dinners {
+		// Without attendance we don't know who ate, so cooking that dinner can't be weighed either
+		if len(dinner.Attendees) == 0 {
+			continue
+		}
+		for _, userID := range dinner.Attendees {
+			stat(userID).Eaten++
+		}
+		if dinner.Cook != "" {
+			stat(dinner.Cook).Cooked++
+		}
+	}
+
+	result := make([]models.AttendanceStat, 0, len(balance))
+	for _, s := range balance {
+		result = append(result, *s)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		di, dj := result[i].Eaten-result[i].Cooked, result[j].Eaten-result[j].Cooked
+		if di != dj {
+			return di > dj
+		}
+		return result[i].UserID < result[j].UserID
+	})
+
+	return result
+}
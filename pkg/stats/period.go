@@ -0,0 +1,117 @@
+package stats
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/korjavin/whatsfordinner/pkg/models"
+)
+
+// PeriodDinners returns the finished dinners of a channel that started at or after since
+func (s *Service) PeriodDinners(channelID int64, since time.Time) ([]models.Dinner, error) {
+	keys, err := s.store.List(fmt.Sprintf("dinner:%d:", channelID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list dinners: %w", err)
+	}
+
+	var dinners []models.Dinner
+	for _, key := range keys {
+		var dinner models.Dinner
+		if err := s.store.Get(key, &dinner); err != nil {
+			s.logger.Error("Failed to get dinner %s: %v", key, err)
+			continue
+		}
+		if dinner.FinishedAt.IsZero() || dinner.StartedAt.Before(since) {
+			continue
+		}
+		dinners = append(dinners, dinner)
+	}
+
+	return dinners, nil
+}
+
+// CookOfThePeriod picks the cook of the dinners since the given time.
+// The cook who cooked the most dinners wins, with the best average rating breaking ties.
+// Cooks that are still tied share the title, so more than one cook can be returned.
+// No cooks are returned if nobody cooked in the period.
+func (s *Service) CookOfThePeriod(channelID int64, since time.Time) ([]models.CookStat, error) {
+	dinners, err := s.PeriodDinners(channelID, since)
+	if err != nil {
+		return nil, err
+	}
+
+	return cookOfThePeriod(dinners, s.usernames(channelID)), nil
+}
+
+// cookOfThePeriod picks the cooks of the given dinners, see CookOfThePeriod
+func cookOfThePeriod(dinners []models.Dinner, usernames map[string]string) []models.CookStat {
+	cooks := make(map[string]*models.CookStat)
+	ratedDinners := make(map[string]int)
+	for _, dinner := range dinners {
+		if dinner.Cook == "" {
+			continue
+		}
+
+		cook, ok := cooks[dinner.Cook]
+		if !ok {
+			cook = &models.CookStat{UserID: dinner.Cook, Username: usernames[dinner.Cook]}
+			cooks[dinner.Cook] = cook
+		}
+
+		cook.CookCount++
+		if len(dinner.Ratings) > 0 {
+			cook.TotalRating += dinner.AverageRating
+			ratedDinners[dinner.Cook]++
+		}
+	}
+
+	ranked := make([]models.CookStat, 0, len(cooks))
+	for userID, cook := range cooks {
+		if ratedDinners[userID] > 0 {
+			cook.AvgRating = cook.TotalRating / float64(ratedDinners[userID])
+		}
+		ranked = append(ranked, *cook)
+	}
+
+	sort.Slice(ranked, func(i, j int) bool {
+		if ranked[i].CookCount != ranked[j].CookCount {
+			return ranked[i].CookCount > ranked[j].CookCount
+		}
+		if ranked[i].AvgRating != ranked[j].AvgRating {
+			return ranked[i].AvgRating > ranked[j].AvgRating
+		}
+		return ranked[i].UserID < ranked[j].UserID
+	})
+
+	// Keep every cook tied with the best one
+	for i := 1; i < len(ranked); i++ {
+		if ranked[i].CookCount != ranked[0].CookCount || ranked[i].AvgRating != ranked[0].AvgRating {
+			return ranked[:i]
+		}
+	}
+
+	return ranked
+}
+
+// usernames returns the known usernames of a channel keyed by user ID
+func (s *Service) usernames(channelID int64) map[string]string {
+	usernames := make(map[string]string)
+
+	stats, err := s.GetStatistics(channelID)
+	if err != nil {
+		return usernames
+	}
+
+	for userID, stat := range stats.HelperStats {
+		usernames[userID] = stat.Username
+	}
+	for userID, stat := range stats.SuggesterStats {
+		usernames[userID] = stat.Username
+	}
+	for userID, stat := range stats.CookStats {
+		usernames[userID] = stat.Username
+	}
+
+	return usernames
+}
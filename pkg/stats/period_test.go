@@ -0,0 +1,85 @@
+package stats
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/korjavin/whatsfordinner/internal/test"
+	"github.com/korjavin/whatsfordinner/pkg/models"
+	"github.com/korjavin/whatsfordinner/pkg/storage"
+)
+
+// saveDinner stores a finished dinner cooked by cook, started the given number of days before now
+func saveDinner(t *testing.T, store *storage.Store, id int, cook string, daysAgo int, ratings map[string]int) {
+	t.Helper()
+
+	started := time.Now().AddDate(0, 0, -daysAgo)
+	dinner := models.Dinner{
+		ID:         fmt.Sprintf("dinner:1:%d", id),
+		ChannelID:  1,
+		Cook:       cook,
+		StartedAt:  started,
+		FinishedAt: started.Add(time.Hour),
+		Ratings:    ratings,
+	}
+	var sum int
+	for _, rating := range ratings {
+		sum += rating
+	}
+	if len(ratings) > 0 {
+		dinner.AverageRating = float64(sum) / float64(len(ratings))
+	}
+	if err := store.Set(dinner.ID, dinner); err != nil {
+		t.Fatalf("failed to save dinner: %v", err)
+	}
+}
+
+func TestCookOfThePeriod(t *testing.T) {
+	store := test.NewStore(t)
+	service := New(store)
+	week := time.Now().AddDate(0, 0, -7)
+
+	// Anna and Ben both cooked twice this week, Anna's dinners were rated better
+	saveDinner(t, store, 1, "anna", 1, map[string]int{"x": 5})
+	saveDinner(t, store, 2, "anna", 3, map[string]int{"x": 4})
+	saveDinner(t, store, 3, "ben", 2, map[string]int{"x": 3})
+	saveDinner(t, store, 4, "ben", 4, nil)
+	saveDinner(t, store, 5, "cleo", 5, map[string]int{"x": 5})
+	// Ben's many older dinners don't count
+	for i := 6; i < 10; i++ {
+		saveDinner(t, store, i, "ben", 10+i, nil)
+	}
+
+	cooks, err := service.CookOfThePeriod(1, week)
+	if err != nil {
+		t.Fatalf("CookOfThePeriod failed: %v", err)
+	}
+	if len(cooks) != 1 || cooks[0].UserID != "anna" || cooks[0].CookCount != 2 || cooks[0].AvgRating != 4.5 {
+		t.Errorf("cooks of the week = %+v, want Anna with 2 dinners rated 4.5", cooks)
+	}
+}
+
+func TestCookOfThePeriodTieAndEmptyWeek(t *testing.T) {
+	store := test.NewStore(t)
+	service := New(store)
+	week := time.Now().AddDate(0, 0, -7)
+
+	cooks, err := service.CookOfThePeriod(1, week)
+	if err != nil {
+		t.Fatalf("CookOfThePeriod failed: %v", err)
+	}
+	if len(cooks) != 0 {
+		t.Errorf("cooks of an empty week = %+v, want none", cooks)
+	}
+
+	saveDinner(t, store, 1, "ben", 1, map[string]int{"x": 4})
+	saveDinner(t, store, 2, "anna", 2, map[string]int{"x": 4})
+	cooks, err = service.CookOfThePeriod(1, week)
+	if err != nil {
+		t.Fatalf("CookOfThePeriod failed: %v", err)
+	}
+	if len(cooks) != 2 || cooks[0].UserID != "anna" || cooks[1].UserID != "ben" {
+		t.Errorf("tied cooks = %+v, want Anna and Ben sharing the title", cooks)
+	}
+}
@@ -3,6 +3,7 @@ package stats
 import (
 	"fmt"
 	"sort"
+	"strings"
 
 	"github.com/korjavin/whatsfordinner/pkg/logger"
 	"github.com/korjavin/whatsfordinner/pkg/models"
@@ -76,6 +77,71 @@ func (s *Service) UpdateCookStats(channelID int64, userID, username string, rati
 	return s.store.Set(fmt.Sprintf("stats:%d", channelID), stats)
 }
 
+// AdjustCookStat manually corrects the cook statistics for a user, e.g. after a dinner
+// was credited to the wrong volunteer. Counts and ratings never go below zero and the
+// average is recomputed from the adjusted totals.
+func (s *Service) AdjustCookStat(channelID int64, userID string, deltaCount int, deltaRating float64) (*models.CookStat, error) {
+	stats, err := s.GetStatistics(channelID)
+	if err != nil {
+		return nil, err
+	}
+
+	cookStat, exists := stats.CookStats[userID]
+	if !exists {
+		cookStat = models.CookStat{UserID: userID}
+	}
+
+	cookStat.CookCount += deltaCount
+	if cookStat.CookCount < 0 {
+		cookStat.CookCount = 0
+	}
+	cookStat.TotalRating += deltaRating
+	if cookStat.TotalRating < 0 || cookStat.CookCount == 0 {
+		cookStat.TotalRating = 0
+	}
+
+	cookStat.AvgRating = 0
+	if cookStat.CookCount > 0 {
+		cookStat.AvgRating = cookStat.TotalRating / float64(cookStat.CookCount)
+	}
+
+	stats.CookStats[userID] = cookStat
+
+	s.logger.Info("Adjusted cook stats for user %s in channel %d by %d dinners and %.1f rating", userID, channelID, deltaCount, deltaRating)
+	if err := s.store.Set(fmt.Sprintf("stats:%d", channelID), stats); err != nil {
+		return nil, err
+	}
+
+	return &cookStat, nil
+}
+
+// FindUserIDByUsername looks up a user ID by username among everyone with statistics in a channel
+func (s *Service) FindUserIDByUsername(channelID int64, username string) (string, bool) {
+	stats, err := s.GetStatistics(channelID)
+	if err != nil {
+		return "", false
+	}
+
+	username = strings.TrimPrefix(username, "@")
+	for userID, cookStat := range stats.CookStats {
+		if strings.EqualFold(cookStat.Username, username) {
+			return userID, true
+		}
+	}
+	for userID, helperStat := range stats.HelperStats {
+		if strings.EqualFold(helperStat.Username, username) {
+			return userID, true
+		}
+	}
+	for userID, suggesterStat := range stats.SuggesterStats {
+		if strings.EqualFold(suggesterStat.Username, username) {
+			return userID, true
+		}
+	}
+
+	return "", false
+}
+
 // UpdateHelperStats updates the helper statistics for a user
 func (s *Service) UpdateHelperStats(channelID int64, userID, username string) error {
 	stats, err := s.GetStatistics(channelID)
@@ -0,0 +1,50 @@
+package stats
+
+import (
+	"testing"
+
+	"github.com/korjavin/whatsfordinner/internal/test"
+)
+
+func TestAdjustCookStatRecomputesAverage(t *testing.T) {
+	service := New(test.NewStore(t))
+	for _, rating := range []float64{4, 5} {
+		if err := service.UpdateCookStats(1, "7", "alice", rating); err != nil {
+			t.Fatalf("UpdateCookStats failed: %v", err)
+		}
+	}
+
+	// Credit a dinner rated 3 that went to the wrong cook
+	cookStat, err := service.AdjustCookStat(1, "7", 1, 3)
+	if err != nil {
+		t.Fatalf("AdjustCookStat failed: %v", err)
+	}
+	if cookStat.CookCount != 3 || cookStat.TotalRating != 12 || cookStat.AvgRating != 4 {
+		t.Errorf("after credit: %+v, want 3 dinners rated 12 in total, average 4", *cookStat)
+	}
+
+	// Take back a dinner rated 5
+	if _, err := service.AdjustCookStat(1, "7", -1, -5); err != nil {
+		t.Fatalf("AdjustCookStat failed: %v", err)
+	}
+	stats, err := service.GetStatistics(1)
+	if err != nil {
+		t.Fatalf("GetStatistics failed: %v", err)
+	}
+	saved := stats.CookStats["7"]
+	if saved.CookCount != 2 || saved.AvgRating != 3.5 || saved.Username != "alice" {
+		t.Errorf("after uncredit: %+v, want alice with 2 dinners averaging 3.5", saved)
+	}
+}
+
+func TestAdjustCookStatNeverGoesNegative(t *testing.T) {
+	service := New(test.NewStore(t))
+
+	cookStat, err := service.AdjustCookStat(1, "7", -2, -8)
+	if err != nil {
+		t.Fatalf("AdjustCookStat failed: %v", err)
+	}
+	if cookStat.CookCount != 0 || cookStat.TotalRating != 0 || cookStat.AvgRating != 0 {
+		t.Errorf("uncredit of an unknown cook: %+v, want all zero", *cookStat)
+	}
+}
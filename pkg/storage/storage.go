@@ -2,21 +2,35 @@ package storage
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"path/filepath"
+	"reflect"
+	"strings"
 	"time"
 
 	"github.com/dgraph-io/badger/v3"
 	"github.com/korjavin/whatsfordinner/pkg/logger"
 )
 
+// ErrNotFound is returned when a key doesn't exist in the store
+var ErrNotFound = errors.New("key not found")
+
+// ErrNoChange is returned by the function passed to Update to leave the stored value as it is
+var ErrNoChange = errors.New("no change")
+
+// ErrNoRewrite is returned by RunGC when there was no space to reclaim
+var ErrNoRewrite = badger.ErrNoRewrite
+
 // Store represents a BadgerDB storage instance
 type Store struct {
 	db *badger.DB
 }
 
-// New creates a new BadgerDB storage instance
-func New(dataDir string) (*Store, error) {
+// New creates a new BadgerDB storage instance.
+// With syncWrites every write is synced to disk before it returns, which survives a crash
+// of the machine but makes writes slower.
+func New(dataDir string, syncWrites bool) (*Store, error) {
 	// Ensure the data directory exists
 	absPath, err := filepath.Abs(dataDir)
 	if err != nil {
@@ -26,13 +40,14 @@ func New(dataDir string) (*Store, error) {
 	// Open the Badger database
 	opts := badger.DefaultOptions(absPath)
 	opts.Logger = nil // Disable Badger's internal logger
+	opts.SyncWrites = syncWrites
 
 	db, err := badger.Open(opts)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open BadgerDB: %w", err)
 	}
 
-	logger.Global.Info("BadgerDB opened at %s", absPath)
+	logger.Global.Info("BadgerDB opened at %s (sync writes: %t)", absPath, syncWrites)
 	return &Store{db: db}, nil
 }
 
@@ -73,7 +88,7 @@ func (s *Store) Get(key string, value interface{}) error {
 
 	if err != nil {
 		if err == badger.ErrKeyNotFound {
-			return fmt.Errorf("key not found: %s", key)
+			return fmt.Errorf("%w: %s", ErrNotFound, key)
 		}
 		return fmt.Errorf("failed to get value: %w", err)
 	}
@@ -81,6 +96,57 @@ func (s *Store) Get(key string, value interface{}) error {
 	return json.Unmarshal(data, value)
 }
 
+// updateAttempts is how often Update retries a read-modify-write that raced another write
+const updateAttempts = 10
+
+// Update reads the value of a key, lets change modify it and stores it again in one transaction,
+// so a check like "only end a vote once" can't race another update of the same key.
+// If the key was written in between, the update is retried with the new value.
+// An error from change aborts the update and is returned, except for ErrNoChange,
+// which skips the write and makes Update return nil.
+func (s *Store) Update(key string, value interface{}, change func() error) error {
+	var err error
+	for attempt := 0; attempt < updateAttempts; attempt++ {
+		err = s.db.Update(func(txn *badger.Txn) error {
+			item, err := txn.Get([]byte(key))
+			if err != nil {
+				if err == badger.ErrKeyNotFound {
+					return fmt.Errorf("%w: %s", ErrNotFound, key)
+				}
+				return fmt.Errorf("failed to get value: %w", err)
+			}
+
+			// Start from scratch, a retry must not see what the last attempt changed
+			target := reflect.ValueOf(value).Elem()
+			target.Set(reflect.Zero(target.Type()))
+			err = item.Value(func(val []byte) error {
+				return json.Unmarshal(val, value)
+			})
+			if err != nil {
+				return err
+			}
+
+			if err := change(); err != nil {
+				return err
+			}
+
+			data, err := json.Marshal(value)
+			if err != nil {
+				return fmt.Errorf("failed to marshal value: %w", err)
+			}
+			return txn.Set([]byte(key), data)
+		})
+		if !errors.Is(err, badger.ErrConflict) {
+			break
+		}
+	}
+
+	if errors.Is(err, ErrNoChange) {
+		return nil
+	}
+	return err
+}
+
 // Delete removes a key from the database
 func (s *Store) Delete(key string) error {
 	return s.db.Update(func(txn *badger.Txn) error {
@@ -113,11 +179,72 @@ func (s *Store) List(prefix string) ([]string, error) {
 	return keys, nil
 }
 
+// Count returns the number of keys with a given prefix without loading their values
+// End prefixes with a separator like "dinner:1:", so they don't also match "dinner:12:"
+func (s *Store) Count(prefix string) (int, error) {
+	count := 0
+	err := s.db.View(func(txn *badger.Txn) error {
+		opts := badger.DefaultIteratorOptions
+		opts.PrefetchValues = false
+		opts.Prefix = []byte(prefix)
+		it := txn.NewIterator(opts)
+		defer it.Close()
+
+		for it.Rewind(); it.Valid(); it.Next() {
+			count++
+		}
+		return nil
+	})
+
+	if err != nil {
+		return 0, fmt.Errorf("failed to count keys: %w", err)
+	}
+
+	return count, nil
+}
+
 // RunGC runs garbage collection on the database
+// It returns ErrNoRewrite if there was nothing to clean up
 func (s *Store) RunGC() error {
 	return s.db.RunValueLogGC(0.5)
 }
 
+// Stats describes the size of the database
+type Stats struct {
+	LSMSize      int64 // Bytes used by the keys
+	ValueLogSize int64 // Bytes used by the values, this is what GC reclaims
+	Keys         int
+	// KeysByType counts the keys per type, the part of the key before the first colon,
+	// e.g. "dinner" for "dinner:42:1700000000", to show what the database grows with
+	KeysByType map[string]int
+}
+
+// Stats returns the current size and number of keys of the database
+// The sizes are updated by BadgerDB about once a minute, so they can lag behind
+func (s *Store) Stats() (Stats, error) {
+	stats := Stats{KeysByType: make(map[string]int)}
+	stats.LSMSize, stats.ValueLogSize = s.db.Size()
+
+	err := s.db.View(func(txn *badger.Txn) error {
+		opts := badger.DefaultIteratorOptions
+		opts.PrefetchValues = false
+		it := txn.NewIterator(opts)
+		defer it.Close()
+
+		for it.Rewind(); it.Valid(); it.Next() {
+			keyType, _, _ := strings.Cut(string(it.Item().Key()), ":")
+			stats.KeysByType[keyType]++
+			stats.Keys++
+		}
+		return nil
+	})
+	if err != nil {
+		return Stats{}, fmt.Errorf("failed to count keys: %w", err)
+	}
+
+	return stats, nil
+}
+
 // StartGCRoutine starts a goroutine that periodically runs garbage collection
 func (s *Store) StartGCRoutine(interval time.Duration) {
 	go func() {
@@ -128,7 +255,7 @@ func (s *Store) StartGCRoutine(interval time.Duration) {
 			err := s.RunGC()
 			if err != nil {
 				// Only log when GC actually did something
-				if err != badger.ErrNoRewrite {
+				if !errors.Is(err, ErrNoRewrite) {
 					logger.Global.Error("BadgerDB GC error: %v", err)
 				}
 			}
@@ -0,0 +1,165 @@
+package storage
+
+import (
+	"errors"
+	"sync"
+	"testing"
+)
+
+// newTestStore opens a store in a temporary directory that is removed when the test ends
+func newTestStore(t *testing.T) *Store {
+	t.Helper()
+
+	store, err := New(t.TempDir(), false)
+	if err != nil {
+		t.Fatalf("failed to open store: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	return store
+}
+
+func TestStatsCountsInsertsAndDeletes(t *testing.T) {
+	store := newTestStore(t)
+
+	for _, key := range []string{"dinner:1:1", "dinner:1:2", "fridge:1", "channel:1"} {
+		if err := store.Set(key, "value"); err != nil {
+			t.Fatalf("Set failed: %v", err)
+		}
+	}
+	stats, err := store.Stats()
+	if err != nil {
+		t.Fatalf("Stats failed: %v", err)
+	}
+	if stats.Keys != 4 || stats.KeysByType["dinner"] != 2 || stats.KeysByType["fridge"] != 1 {
+		t.Errorf("stats = %d keys by type %v, want 4 with 2 dinners", stats.Keys, stats.KeysByType)
+	}
+
+	// Overwriting a key doesn't add one, deleting removes it
+	if err := store.Set("fridge:1", "new value"); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	if err := store.Delete("dinner:1:1"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	stats, err = store.Stats()
+	if err != nil {
+		t.Fatalf("Stats failed: %v", err)
+	}
+	if stats.Keys != 3 || stats.KeysByType["dinner"] != 1 {
+		t.Errorf("stats = %d keys by type %v, want 3 with 1 dinner", stats.Keys, stats.KeysByType)
+	}
+}
+
+func TestCountStaysInsideItsPrefix(t *testing.T) {
+	store := newTestStore(t)
+
+	keys := []string{
+		"dinner:1:100", "dinner:1:200", "dinner:12:100", "dinner:123:100",
+		"dinners:1:100", "suggestion:1:7:1", "suggestion:1:7:2", "suggestion:1:70:1",
+	}
+	for _, key := range keys {
+		if err := store.Set(key, "value"); err != nil {
+			t.Fatalf("Set failed: %v", err)
+		}
+	}
+
+	tests := []struct {
+		prefix string
+		want   int
+	}{
+		{"dinner:1:", 2},
+		{"dinner:12:", 1},
+		{"dinner:", 4},
+		{"suggestion:1:7:", 2},
+		{"suggestion:1:", 3},
+		{"scheduled:1:", 0},
+	}
+	for _, tt := range tests {
+		got, err := store.Count(tt.prefix)
+		if err != nil {
+			t.Fatalf("Count(%q) failed: %v", tt.prefix, err)
+		}
+		if got != tt.want {
+			t.Errorf("Count(%q) = %d, want %d", tt.prefix, got, tt.want)
+		}
+
+		listed, err := store.List(tt.prefix)
+		if err != nil {
+			t.Fatalf("List(%q) failed: %v", tt.prefix, err)
+		}
+		if len(listed) != got {
+			t.Errorf("Count(%q) = %d but List finds %d keys", tt.prefix, got, len(listed))
+		}
+	}
+}
+
+func TestNewAppliesSyncWrites(t *testing.T) {
+	for _, syncWrites := range []bool{false, true} {
+		dir := t.TempDir()
+		store, err := New(dir, syncWrites)
+		if err != nil {
+			t.Fatalf("New(%t) failed: %v", syncWrites, err)
+		}
+		if got := store.db.Opts().SyncWrites; got != syncWrites {
+			t.Errorf("New(%t) opened the store with sync writes %t", syncWrites, got)
+		}
+		if err := store.Set("key", "value"); err != nil {
+			t.Fatalf("Set failed: %v", err)
+		}
+		store.Close()
+
+		// Writes are there after reopening either way
+		store, err = New(dir, !syncWrites)
+		if err != nil {
+			t.Fatalf("reopening failed: %v", err)
+		}
+		var value string
+		if err := store.Get("key", &value); err != nil || value != "value" {
+			t.Errorf("after reopening Get() = %q, %v, want the value", value, err)
+		}
+		store.Close()
+	}
+}
+
+func TestUpdateDoesntLoseConcurrentChanges(t *testing.T) {
+	store := newTestStore(t)
+	if err := store.Set("counter", 0); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var counter int
+			if err := store.Update("counter", &counter, func() error {
+				counter++
+				return nil
+			}); err != nil {
+				t.Errorf("Update failed: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	var counter int
+	if err := store.Get("counter", &counter); err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if counter != 5 {
+		t.Errorf("counter = %d, want 5", counter)
+	}
+
+	// ErrNoChange skips the write, other errors are returned
+	if err := store.Update("counter", &counter, func() error { counter = 0; return ErrNoChange }); err != nil {
+		t.Errorf("Update with ErrNoChange returned %v, want nil", err)
+	}
+	if err := store.Get("counter", &counter); err != nil || counter != 5 {
+		t.Errorf("counter = %d (%v) after ErrNoChange, want 5", counter, err)
+	}
+	if err := store.Update("missing", &counter, func() error { return nil }); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Update of a missing key returned %v, want ErrNotFound", err)
+	}
+}
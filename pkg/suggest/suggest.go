@@ -1,7 +1,9 @@
 package suggest
 
 import (
+	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/korjavin/whatsfordinner/pkg/logger"
@@ -9,6 +11,15 @@ import (
 	"github.com/korjavin/whatsfordinner/pkg/storage"
 )
 
+// ErrDuplicate is returned when suggesting a dish that is already waiting in the pool
+var ErrDuplicate = errors.New("dish is already suggested")
+
+// ErrNoPending is returned when a suggestion waiting for confirmation is unknown or has expired
+var ErrNoPending = errors.New("no pending suggestion")
+
+// pendingExpiry is how long a suggestion waits for its suggester to confirm it
+const pendingExpiry = time.Hour
+
 // Service provides functionality for managing suggested dishes
 type Service struct {
 	store  *storage.Store
@@ -24,9 +35,15 @@ func New(store *storage.Store) *Service {
 }
 
 // AddSuggestion adds a new suggested dish
+// If the dish is already an unused suggestion, by anyone, the existing one is returned with ErrDuplicate
 func (s *Service) AddSuggestion(channelID int64, userID, username, name, cuisine, description string) (*models.SuggestedDish, error) {
 	s.logger.Info("Adding suggestion from user %s: %s (%s cuisine)", username, name, cuisine)
 	
+	if existing, ok := s.FindUnusedSuggestion(channelID, name); ok {
+		s.logger.Info("Dish %s is already suggested as %s", name, existing.ID)
+		return existing, ErrDuplicate
+	}
+	
 	suggestion := &models.SuggestedDish{
 		ID:          fmt.Sprintf("suggestion:%d:%d", channelID, time.Now().UnixNano()),
 		ChannelID:   channelID,
@@ -72,6 +89,7 @@ func (s *Service) GetSuggestions(channelID int64) ([]*models.SuggestedDish, erro
 }
 
 // GetUnusedSuggestions returns all suggestions that haven't been used in a poll
+// Archived suggestions are skipped
 func (s *Service) GetUnusedSuggestions(channelID int64) ([]*models.SuggestedDish, error) {
 	suggestions, err := s.GetSuggestions(channelID)
 	if err != nil {
@@ -80,7 +98,7 @@ func (s *Service) GetUnusedSuggestions(channelID int64) ([]*models.SuggestedDish
 	
 	unused := make([]*models.SuggestedDish, 0)
 	for _, suggestion := range suggestions {
-		if !suggestion.UsedInPoll {
+		if !suggestion.UsedInPoll && !suggestion.Deleted {
 			unused = append(unused, suggestion)
 		}
 	}
@@ -88,6 +106,45 @@ func (s *Service) GetUnusedSuggestions(channelID int64) ([]*models.SuggestedDish
 	return unused, nil
 }
 
+// FindUnusedSuggestion returns the unused suggestion of a channel with the given dish name, ignoring case
+func (s *Service) FindUnusedSuggestion(channelID int64, name string) (*models.SuggestedDish, bool) {
+	unused, err := s.GetUnusedSuggestions(channelID)
+	if err != nil {
+		s.logger.Error("Failed to get unused suggestions: %v", err)
+		return nil, false
+	}
+	
+	for _, suggestion := range unused {
+		if strings.EqualFold(strings.TrimSpace(suggestion.Name), strings.TrimSpace(name)) {
+			return suggestion, true
+		}
+	}
+	
+	return nil, false
+}
+
+// FindSuggester returns the most recent suggestion of a channel with the given dish name, ignoring case,
+// including used and archived ones, so the original suggester can be credited
+func (s *Service) FindSuggester(channelID int64, name string) (*models.SuggestedDish, bool) {
+	suggestions, err := s.GetSuggestions(channelID)
+	if err != nil {
+		s.logger.Error("Failed to get suggestions: %v", err)
+		return nil, false
+	}
+	
+	var latest *models.SuggestedDish
+	for _, suggestion := range suggestions {
+		if !strings.EqualFold(strings.TrimSpace(suggestion.Name), strings.TrimSpace(name)) {
+			continue
+		}
+		if latest == nil || suggestion.SuggestedAt.After(latest.SuggestedAt) {
+			latest = suggestion
+		}
+	}
+	
+	return latest, latest != nil
+}
+
 // MarkAsUsed marks a suggestion as used in a poll
 func (s *Service) MarkAsUsed(suggestionID string) error {
 	var suggestion models.SuggestedDish
@@ -106,7 +163,66 @@ func (s *Service) MarkAsUsed(suggestionID string) error {
 	return nil
 }
 
-// DeleteSuggestion deletes a suggestion
+// ArchiveSuggestion marks a suggestion as deleted so it's left out of polls,
+// while keeping it for the suggester's history
+func (s *Service) ArchiveSuggestion(suggestionID string) error {
+	var suggestion models.SuggestedDish
+	err := s.store.Get(suggestionID, &suggestion)
+	if err != nil {
+		return fmt.Errorf("failed to get suggestion: %w", err)
+	}
+
+	suggestion.Deleted = true
+
+	err = s.store.Set(suggestionID, suggestion)
+	if err != nil {
+		return fmt.Errorf("failed to update suggestion: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteSuggestion removes a suggestion for good, including from the suggester's history
+// Prefer ArchiveSuggestion unless the suggestion must really be gone
 func (s *Service) DeleteSuggestion(suggestionID string) error {
 	return s.store.Delete(suggestionID)
 }
+
+// pendingKey returns the key of the suggestion waiting for confirmation on a message
+func pendingKey(channelID int64, messageID int) string {
+	return fmt.Sprintf("pending_suggestion:%d:%d", channelID, messageID)
+}
+
+// SetPending keeps a suggestion until its suggester confirms or cancels it on the given message
+func (s *Service) SetPending(channelID int64, messageID int, suggestion models.SuggestedDish) error {
+	suggestion.SuggestedAt = time.Now()
+	if err := s.store.Set(pendingKey(channelID, messageID), suggestion); err != nil {
+		return fmt.Errorf("failed to save pending suggestion: %w", err)
+	}
+	return nil
+}
+
+// GetPending returns the suggestion waiting for confirmation on a message
+// It returns ErrNoPending if there is none or it has expired
+func (s *Service) GetPending(channelID int64, messageID int) (*models.SuggestedDish, error) {
+	var suggestion models.SuggestedDish
+	err := s.store.Get(pendingKey(channelID, messageID), &suggestion)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, ErrNoPending
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pending suggestion: %w", err)
+	}
+	
+	if time.Since(suggestion.SuggestedAt) > pendingExpiry {
+		s.DeletePending(channelID, messageID)
+		return nil, ErrNoPending
+	}
+	
+	return &suggestion, nil
+}
+
+// DeletePending forgets the suggestion waiting for confirmation on a message
+func (s *Service) DeletePending(channelID int64, messageID int) error {
+	return s.store.Delete(pendingKey(channelID, messageID))
+}
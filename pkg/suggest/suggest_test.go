@@ -0,0 +1,86 @@
+package suggest
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/korjavin/whatsfordinner/internal/test"
+)
+
+func TestArchivedSuggestionsStayListable(t *testing.T) {
+	service := New(test.NewStore(t))
+	soup, err := service.AddSuggestion(1, "7", "anna", "Soup", "French", "")
+	if err != nil {
+		t.Fatalf("AddSuggestion failed: %v", err)
+	}
+	if _, err := service.AddSuggestion(1, "8", "ben", "Curry", "Indian", ""); err != nil {
+		t.Fatalf("AddSuggestion failed: %v", err)
+	}
+
+	if err := service.ArchiveSuggestion(soup.ID); err != nil {
+		t.Fatalf("ArchiveSuggestion failed: %v", err)
+	}
+
+	unused, err := service.GetUnusedSuggestions(1)
+	if err != nil {
+		t.Fatalf("GetUnusedSuggestions failed: %v", err)
+	}
+	if len(unused) != 1 || unused[0].Name != "Curry" {
+		t.Errorf("unused suggestions = %v, want only Curry for the next poll", unused)
+	}
+
+	all, err := service.GetSuggestions(1)
+	if err != nil {
+		t.Fatalf("GetSuggestions failed: %v", err)
+	}
+	if len(all) != 2 {
+		t.Errorf("listed %d suggestions, want the archived one still listed", len(all))
+	}
+	if suggester, ok := service.FindSuggester(1, "soup"); !ok || suggester.UserID != "7" || !suggester.Deleted {
+		t.Errorf("FindSuggester(soup) = %v, %v, want Anna's archived suggestion", suggester, ok)
+	}
+
+	// Deleting removes it for good
+	if err := service.DeleteSuggestion(soup.ID); err != nil {
+		t.Fatalf("DeleteSuggestion failed: %v", err)
+	}
+	if _, ok := service.FindSuggester(1, "soup"); ok {
+		t.Error("deleted suggestion is still found")
+	}
+}
+
+func TestDuplicateSuggestionsAcrossUsers(t *testing.T) {
+	service := New(test.NewStore(t))
+	tacos, err := service.AddSuggestion(1, "7", "anna", "Tacos", "Mexican", "")
+	if err != nil {
+		t.Fatalf("AddSuggestion failed: %v", err)
+	}
+
+	// Another user suggesting the same dish gets Anna's suggestion back
+	existing, err := service.AddSuggestion(1, "8", "ben", " tacos", "Mexican", "")
+	if !errors.Is(err, ErrDuplicate) {
+		t.Fatalf("AddSuggestion of a duplicate returned %v, want ErrDuplicate", err)
+	}
+	if existing.ID != tacos.ID || existing.UserID != "7" {
+		t.Errorf("duplicate returned %v, want Anna's suggestion", existing)
+	}
+
+	// Other chats and dishes that were already in a poll don't count
+	if _, err := service.AddSuggestion(2, "8", "ben", "Tacos", "Mexican", ""); err != nil {
+		t.Errorf("AddSuggestion in another chat failed: %v", err)
+	}
+	if err := service.MarkAsUsed(tacos.ID); err != nil {
+		t.Fatalf("MarkAsUsed failed: %v", err)
+	}
+	if _, err := service.AddSuggestion(1, "8", "ben", "TACOS", "Mexican", ""); err != nil {
+		t.Errorf("AddSuggestion after the first tacos were used failed: %v", err)
+	}
+
+	unused, err := service.GetUnusedSuggestions(1)
+	if err != nil {
+		t.Fatalf("GetUnusedSuggestions failed: %v", err)
+	}
+	if len(unused) != 1 || unused[0].UserID != "8" {
+		t.Errorf("unused suggestions = %v, want only Ben's new tacos", unused)
+	}
+}
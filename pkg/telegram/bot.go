@@ -1,7 +1,11 @@
 package telegram
 
 import (
+	"errors"
 	"fmt"
+	"net/http"
+	"strings"
+	"time"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 	"github.com/korjavin/whatsfordinner/pkg/logger"
@@ -9,8 +13,15 @@ import (
 
 // Bot represents a Telegram bot instance
 type Bot struct {
-	api    *tgbotapi.BotAPI
-	logger *logger.Logger
+	api      *tgbotapi.BotAPI
+	logger   *logger.Logger
+	workers  int
+	pacer    *pacer
+	handlers handlers
+	// parseMode is how the text of sent and edited messages is formatted, empty for plain text
+	parseMode string
+	// pollChats finds the chat of a poll, since poll answers don't say which chat they belong to
+	pollChats PollChatResolver
 }
 
 // HandlerFunc is a function that handles a Telegram update
@@ -19,95 +30,339 @@ type HandlerFunc func(update tgbotapi.Update)
 // CommandHandler is a function that handles a Telegram command
 type CommandHandler func(message *tgbotapi.Message)
 
-// CallbackHandler is a function that handles a Telegram callback query
-type CallbackHandler func(callback *tgbotapi.CallbackQuery)
+// CallbackHandler is a function that handles a Telegram callback query.
+// It gets the payload of the callback data, everything after the first colon, see ParseCallbackData.
+type CallbackHandler func(callback *tgbotapi.CallbackQuery, payload string)
+
+// PollChatResolver returns the ID of the chat a poll was posted in
+type PollChatResolver func(pollID string) (int64, error)
+
+// PollAnswerHandler is a function that handles an answer to a poll of the given chat.
+// The chat is 0 if the poll isn't running anymore or was never posted by the bot.
+type PollAnswerHandler func(chatID int64, answer *tgbotapi.PollAnswer)
 
 // New creates a new Telegram bot instance
-func New(token string) (*Bot, error) {
-	api, err := tgbotapi.NewBotAPI(token)
+// workers is the number of updates that are handled at the same time
+func New(token string, workers int) (*Bot, error) {
+	return NewWithEndpoint(token, tgbotapi.APIEndpoint, workers)
+}
+
+// NewWithEndpoint creates a new Telegram bot instance that talks to the Bot API at endpoint,
+// a format string for the token and method like tgbotapi.APIEndpoint.
+// It is used for local Bot API servers and for fakes in tests.
+func NewWithEndpoint(token, endpoint string, workers int) (*Bot, error) {
+	api, err := tgbotapi.NewBotAPIWithAPIEndpoint(token, endpoint)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create Telegram bot: %w", err)
 	}
 
 	bot := &Bot{
-		api:    api,
-		logger: logger.New(""),
+		api:       api,
+		logger:    logger.New(""),
+		workers:   workers,
+		pacer:     newPacer(),
+		parseMode: tgbotapi.ModeMarkdown,
 	}
 
 	bot.logger.Info("Telegram bot created: @%s", api.Self.UserName)
 	return bot, nil
 }
 
+// SetPacing changes the minimum time between two messages to the same chat and between any two messages.
+// Zero intervals turn pacing off, e.g. for tests against a fake Bot API.
+func (b *Bot) SetPacing(chatInterval, globalInterval time.Duration) {
+	b.pacer.mu.Lock()
+	defer b.pacer.mu.Unlock()
+	b.pacer.chatInterval = chatInterval
+	b.pacer.globalInterval = globalInterval
+}
+
+// SetParseMode sets how message text is formatted, tgbotapi.ModeMarkdown by default or "" for plain text
+func (b *Bot) SetParseMode(mode string) {
+	b.parseMode = mode
+}
+
+// newMessage builds a text message in the bot's parse mode
+// Underscores in bot commands like /add_photo are escaped, so they don't start italic text.
+func (b *Bot) newMessage(chatID int64, text string) tgbotapi.MessageConfig {
+	if b.parseMode == tgbotapi.ModeMarkdown {
+		text = escapeCommands(text)
+	}
+	msg := tgbotapi.NewMessage(chatID, text)
+	msg.ParseMode = b.parseMode
+	return msg
+}
+
+// newEditMessage builds an edit of a message's text in the bot's parse mode, see newMessage
+func (b *Bot) newEditMessage(chatID int64, messageID int, text string) tgbotapi.EditMessageTextConfig {
+	if b.parseMode == tgbotapi.ModeMarkdown {
+		text = escapeCommands(text)
+	}
+	edit := tgbotapi.NewEditMessageText(chatID, messageID, text)
+	edit.ParseMode = b.parseMode
+	return edit
+}
+
+// sendFormatted sends a formatted message, and sends it again as plain text with the formatting
+// stripped if Telegram can't parse it, so a stray * or _ in a dish name or AI reply never loses the message
+func (b *Bot) sendFormatted(c tgbotapi.Chattable) (tgbotapi.Message, error) {
+	msg, err := b.send(c)
+	if !isParseError(err) {
+		return msg, err
+	}
+
+	b.logger.Warn("Telegram couldn't parse the message formatting, sending it as plain text: %v", err)
+	switch config := c.(type) {
+	case tgbotapi.MessageConfig:
+		config.Text = stripMarkdown(config.Text)
+		config.ParseMode = ""
+		return b.send(config)
+	case tgbotapi.EditMessageTextConfig:
+		config.Text = stripMarkdown(config.Text)
+		config.ParseMode = ""
+		return b.send(config)
+	}
+	return msg, err
+}
+
+// handlers groups the handlers passed to Start and used by Dispatch
+type handlers struct {
+	commands   map[string]CommandHandler
+	callbacks  map[string]CallbackHandler
+	reaction   ReactionHandler
+	pollAnswer PollAnswerHandler
+	fallback   HandlerFunc
+}
+
 // Start starts the bot and listens for updates
-func (b *Bot) Start(commandHandlers map[string]CommandHandler, callbackHandlers map[string]CallbackHandler, defaultHandler HandlerFunc) error {
+// Updates from different chats are handled in parallel by a bounded pool of workers,
+// updates from the same chat one at a time and in order.
+func (b *Bot) Start(commandHandlers map[string]CommandHandler, callbackHandlers map[string]CallbackHandler, reactionHandler ReactionHandler, pollAnswerHandler PollAnswerHandler, defaultHandler HandlerFunc) error {
 	u := tgbotapi.NewUpdate(0)
 	u.Timeout = 60
+	u.AllowedUpdates = allowedUpdates
+
+	updates := b.getUpdatesChan(u)
 
-	updates := b.api.GetUpdatesChan(u)
+	b.handlers = handlers{
+		commands:   commandHandlers,
+		callbacks:  callbackHandlers,
+		reaction:   reactionHandler,
+		pollAnswer: pollAnswerHandler,
+		fallback:   defaultHandler,
+	}
+
+	dispatcher := newDispatcher(b.workers, b.Dispatch)
 
 	for update := range updates {
-		// Create a channel-specific logger if we have a chat ID
-		var chatID int64
-		if update.Message != nil {
-			chatID = update.Message.Chat.ID
-		} else if update.CallbackQuery != nil {
-			chatID = update.CallbackQuery.Message.Chat.ID
-		}
+		dispatcher.submit(b.queueChatID(&update), update)
+	}
+
+	return nil
+}
 
-		if chatID != 0 {
-			b.logger = logger.New(fmt.Sprintf("%d", chatID))
+// SetPollChatResolver sets how the chat of a poll answer is found, so poll answers are
+// handled in order with the other updates of their chat instead of sharing one queue
+func (b *Bot) SetPollChatResolver(resolve PollChatResolver) {
+	b.pollChats = resolve
+}
+
+// queueChatID returns the chat whose queue an update is handled in.
+// The chat of a poll answer is looked up with the poll chat resolver and kept on the update,
+// so its handler doesn't look it up again. Other updates say which chat they belong to.
+func (b *Bot) queueChatID(update *Update) int64 {
+	if update.PollAnswer != nil && update.PollChatID == 0 && b.pollChats != nil {
+		chatID, err := b.pollChats(update.PollAnswer.PollID)
+		if err != nil {
+			b.logger.Info("No chat for an answer to poll %s: %v", update.PollAnswer.PollID, err)
+		} else {
+			update.PollChatID = chatID
 		}
+	}
+	return updateChatID(*update)
+}
+
+// updateChatID returns the ID of the chat an update belongs to, or 0 if it doesn't say
+func updateChatID(update Update) int64 {
+	switch {
+	case update.Message != nil:
+		return update.Message.Chat.ID
+	case update.CallbackQuery != nil && update.CallbackQuery.Message != nil:
+		return update.CallbackQuery.Message.Chat.ID
+	case update.MessageReaction != nil:
+		return update.MessageReaction.Chat.ID
+	case update.PollAnswer != nil:
+		return update.PollChatID
+	default:
+		return 0
+	}
+}
+
+// Dispatch routes a single update to the matching command, callback, reaction or default handler.
+// It doesn't care where the update came from, so it works the same for polling and webhooks.
+func (b *Bot) Dispatch(update Update) {
+	h := b.handlers
+
+	// Create a channel-specific logger if we have a chat ID
+	// It is local to this update, b.logger is shared by all workers and never changes
+	log := b.logger
+	if chatID := updateChatID(update); chatID != 0 {
+		log = logger.New(fmt.Sprintf("%d", chatID))
+	}
 
-		// Handle commands
-		if update.Message != nil && update.Message.IsCommand() {
-			command := update.Message.Command()
-			if handler, ok := commandHandlers[command]; ok {
-				b.logger.Info("Handling command: %s from user %s", command, update.Message.From.UserName)
-				handler(update.Message)
-				continue
-			}
+	// Handle commands
+	if update.Message != nil && update.Message.IsCommand() {
+		command := update.Message.Command()
+		if handler, ok := h.commands[command]; ok {
+			log.Info("Handling command: %s from user %s", command, update.Message.From.UserName)
+			handler(update.Message)
+			return
 		}
+	}
 
-		// Handle callback queries
-		if update.CallbackQuery != nil {
-			data := update.CallbackQuery.Data
-			for prefix, handler := range callbackHandlers {
-				if len(data) >= len(prefix) && data[:len(prefix)] == prefix {
-					b.logger.Info("Handling callback: %s from user %s", data, update.CallbackQuery.From.UserName)
-					handler(update.CallbackQuery)
-					break
-				}
-			}
-			continue
+	// Handle callback queries
+	if update.CallbackQuery != nil {
+		data := update.CallbackQuery.Data
+		if handler, ok := matchCallback(h.callbacks, data); ok {
+			log.Info("Handling callback: %s from user %s", data, update.CallbackQuery.From.UserName)
+			_, payload := ParseCallbackData(data)
+			handler(update.CallbackQuery, payload)
 		}
+		return
+	}
 
-		// Use default handler for other updates
-		if defaultHandler != nil {
-			defaultHandler(update)
+	// Handle message reactions
+	if update.MessageReaction != nil {
+		if h.reaction != nil {
+			h.reaction(update.MessageReaction)
 		}
+		return
 	}
 
-	return nil
+	// Handle poll answers in the chat queueChatID found for them
+	if update.PollAnswer != nil && h.pollAnswer != nil {
+		h.pollAnswer(update.PollChatID, update.PollAnswer)
+		return
+	}
+
+	// Use default handler for other updates
+	if h.fallback != nil {
+		h.fallback(update.Update)
+	}
+}
+
+// matchCallback returns the handler whose prefix matches the callback data.
+// If several prefixes match, like "rate:" and "rate", the longest one wins,
+// so the result doesn't depend on map iteration order.
+func matchCallback(callbacks map[string]CallbackHandler, data string) (CallbackHandler, bool) {
+	var match CallbackHandler
+	matchLen := -1
+	for prefix, handler := range callbacks {
+		if strings.HasPrefix(data, prefix) && len(prefix) > matchLen {
+			match = handler
+			matchLen = len(prefix)
+		}
+	}
+	return match, matchLen >= 0
 }
 
 // SendMessage sends a text message to a chat
 func (b *Bot) SendMessage(chatID int64, text string) (tgbotapi.Message, error) {
-	msg := tgbotapi.NewMessage(chatID, text)
-	return b.api.Send(msg)
+	msg := b.newMessage(chatID, text)
+	return b.sendFormatted(msg)
+}
+
+// ErrCannotMessageUser is returned when a user hasn't started a private chat with the bot or has blocked it
+var ErrCannotMessageUser = errors.New("cannot message user")
+
+// SendPrivateMessage sends a text message to a user's private chat with the bot
+func (b *Bot) SendPrivateMessage(userID int64, text string) (tgbotapi.Message, error) {
+	msg, err := b.SendMessage(userID, text)
+	var apiErr *tgbotapi.Error
+	if errors.As(err, &apiErr) && apiErr.Code == http.StatusForbidden {
+		return msg, fmt.Errorf("%w: %v", ErrCannotMessageUser, err)
+	}
+	return msg, err
+}
+
+// ErrMessageNotFound is returned when the message replied to was deleted
+var ErrMessageNotFound = errors.New("message not found")
+
+// SendReply sends a text message as a reply to another message of the chat
+func (b *Bot) SendReply(chatID int64, replyToMessageID int, text string) (tgbotapi.Message, error) {
+	msg := b.newMessage(chatID, text)
+	msg.ReplyToMessageID = replyToMessageID
+	sent, err := b.sendFormatted(msg)
+	var apiErr *tgbotapi.Error
+	if errors.As(err, &apiErr) && apiErr.Code == http.StatusBadRequest && strings.Contains(apiErr.Message, "not found") {
+		return sent, fmt.Errorf("%w: %v", ErrMessageNotFound, err)
+	}
+	return sent, err
 }
 
 // SendMessageWithKeyboard sends a text message with an inline keyboard
 func (b *Bot) SendMessageWithKeyboard(chatID int64, text string, keyboard tgbotapi.InlineKeyboardMarkup) (tgbotapi.Message, error) {
-	msg := tgbotapi.NewMessage(chatID, text)
+	msg := b.newMessage(chatID, text)
 	msg.ReplyMarkup = keyboard
-	return b.api.Send(msg)
+	return b.sendFormatted(msg)
+}
+
+// SendDocument sends content as a file with the given name and an optional caption
+func (b *Bot) SendDocument(chatID int64, filename string, content []byte, caption string) (tgbotapi.Message, error) {
+	doc := tgbotapi.NewDocument(chatID, tgbotapi.FileBytes{Name: filename, Bytes: content})
+	doc.Caption = caption
+	return b.send(doc)
 }
 
 // CreatePoll creates a poll in a chat
+// Polls are always non-anonymous and single-choice: every voter picks exactly one
+// dish, which is what vote recording and the close threshold rely on.
 func (b *Bot) CreatePoll(chatID int64, question string, options []string) (tgbotapi.Message, error) {
 	poll := tgbotapi.NewPoll(chatID, question, options...)
 	poll.IsAnonymous = false
-	return b.api.Send(poll)
+	poll.AllowsMultipleAnswers = false
+	return b.send(poll)
+}
+
+// CreateVoteCards posts a vote as one message per option, each with its own vote button.
+// The buttons send "vote_opt:<pollID>:<option index>". The question is posted first,
+// its message is returned along with the IDs of the card messages.
+func (b *Bot) CreateVoteCards(chatID int64, question, pollID string, cards []string) (tgbotapi.Message, []int, error) {
+	header, err := b.SendMessage(chatID, "🗳 "+question)
+	if err != nil {
+		return tgbotapi.Message{}, nil, err
+	}
+
+	cardIDs := make([]int, 0, len(cards))
+	for i, card := range cards {
+		keyboard := tgbotapi.NewInlineKeyboardMarkup(
+			tgbotapi.NewInlineKeyboardRow(
+				tgbotapi.NewInlineKeyboardButtonData("🗳 Vote for this", fmt.Sprintf("vote_opt:%s:%d", pollID, i)),
+			),
+		)
+		msg, err := b.SendMessageWithKeyboard(chatID, card, keyboard)
+		if err != nil {
+			return header, cardIDs, fmt.Errorf("failed to send vote card %d: %w", i, err)
+		}
+		cardIDs = append(cardIDs, msg.MessageID)
+	}
+
+	return header, cardIDs, nil
+}
+
+// SetCommands registers the list of commands shown in the Telegram command menu
+func (b *Bot) SetCommands(commands []tgbotapi.BotCommand) error {
+	return b.SetMyCommands(commands, tgbotapi.NewBotCommandScopeDefault())
+}
+
+// SetMyCommands registers the list of commands shown in the Telegram command menu
+// for chats matching the given scope
+func (b *Bot) SetMyCommands(commands []tgbotapi.BotCommand, scope tgbotapi.BotCommandScope) error {
+	_, err := b.api.Request(tgbotapi.NewSetMyCommandsWithScope(scope, commands...))
+	if err != nil {
+		return fmt.Errorf("failed to set bot commands for scope %s: %w", scope.Type, err)
+	}
+	return nil
 }
 
 // AnswerCallbackQuery answers a callback query
@@ -119,19 +374,26 @@ func (b *Bot) AnswerCallbackQuery(callbackID string, text string) error {
 
 // EditMessage edits a message
 func (b *Bot) EditMessage(chatID int64, messageID int, text string) (tgbotapi.Message, error) {
-	edit := tgbotapi.NewEditMessageText(chatID, messageID, text)
-	return b.api.Send(edit)
+	edit := b.newEditMessage(chatID, messageID, text)
+	return b.sendFormatted(edit)
+}
+
+// EditMessageWithKeyboard edits a message's text and inline keyboard, an empty keyboard removes the buttons
+func (b *Bot) EditMessageWithKeyboard(chatID int64, messageID int, text string, keyboard tgbotapi.InlineKeyboardMarkup) (tgbotapi.Message, error) {
+	edit := b.newEditMessage(chatID, messageID, text)
+	edit.ReplyMarkup = &keyboard
+	return b.sendFormatted(edit)
 }
 
 // EditMessageKeyboard edits a message's inline keyboard
 func (b *Bot) EditMessageKeyboard(chatID int64, messageID int, keyboard tgbotapi.InlineKeyboardMarkup) (tgbotapi.Message, error) {
 	edit := tgbotapi.NewEditMessageReplyMarkup(chatID, messageID, keyboard)
-	return b.api.Send(edit)
+	return b.send(edit)
 }
 
 // Send sends a Chattable to Telegram
 func (b *Bot) Send(c tgbotapi.Chattable) (tgbotapi.Message, error) {
-	return b.api.Send(c)
+	return b.send(c)
 }
 
 // GetFileURL gets the URL for a file
@@ -173,6 +435,35 @@ func (b *Bot) GetChatMember(chatID int64, userID int64) (*tgbotapi.ChatMember, e
 	return &member, nil
 }
 
+// GetChatAdministrators gets the administrators of a chat, including bots
+func (b *Bot) GetChatAdministrators(chatID int64) ([]tgbotapi.ChatMember, error) {
+	admins, err := b.api.GetChatAdministrators(tgbotapi.ChatAdministratorsConfig{
+		ChatConfig: tgbotapi.ChatConfig{
+			ChatID: chatID,
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get chat administrators: %w", err)
+	}
+
+	return admins, nil
+}
+
+// IsChatAdmin checks whether a user is an administrator or the creator of a chat
+// In private chats the only member counts as an admin
+func (b *Bot) IsChatAdmin(chat *tgbotapi.Chat, userID int64) (bool, error) {
+	if chat.IsPrivate() {
+		return true, nil
+	}
+
+	member, err := b.GetChatMember(chat.ID, userID)
+	if err != nil {
+		return false, err
+	}
+
+	return member.IsCreator() || member.IsAdministrator(), nil
+}
+
 // StopPoll stops a poll in a chat
 // Note: As of the current Telegram Bot API, there's no direct way to stop a poll
 // This method is added for future compatibility if Telegram adds this functionality
@@ -0,0 +1,318 @@
+package telegram
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"github.com/korjavin/whatsfordinner/internal/test"
+)
+
+// newTestBot creates a bot talking to a fake Telegram Bot API, without message pacing
+func newTestBot(t *testing.T) (*Bot, *test.Telegram) {
+	t.Helper()
+
+	fake := test.NewTelegram(t)
+	bot, err := NewWithEndpoint("test-token", fake.Endpoint(), 1)
+	if err != nil {
+		t.Fatalf("failed to create bot: %v", err)
+	}
+	bot.SetPacing(0, 0)
+	return bot, fake
+}
+
+func TestCreatePollIsSingleChoice(t *testing.T) {
+	bot, fake := newTestBot(t)
+
+	if _, err := bot.CreatePoll(1, "What's for dinner?", []string{"Pasta", "Soup"}); err != nil {
+		t.Fatalf("CreatePoll failed: %v", err)
+	}
+
+	calls := fake.Calls("sendPoll")
+	if len(calls) != 1 {
+		t.Fatalf("sent %d polls, want 1", len(calls))
+	}
+	params := calls[0].Params
+	if got := params.Get("allows_multiple_answers"); got != "false" {
+		t.Errorf("allows_multiple_answers = %q, want false", got)
+	}
+	if got := params.Get("is_anonymous"); got != "false" {
+		t.Errorf("is_anonymous = %q, want false", got)
+	}
+}
+
+// Run with -race: updates of different chats are dispatched at once and must not share mutable state
+func TestDispatchConcurrentChatsKeepsBotLogger(t *testing.T) {
+	bot, _ := newTestBot(t)
+	botLogger := bot.logger
+
+	var mu sync.Mutex
+	handled := make(map[int64]int)
+	bot.handlers = handlers{commands: map[string]CommandHandler{
+		"start": func(message *tgbotapi.Message) {
+			mu.Lock()
+			handled[message.Chat.ID]++
+			mu.Unlock()
+		},
+	}}
+
+	var wg sync.WaitGroup
+	for chatID := int64(1); chatID <= 8; chatID++ {
+		wg.Add(1)
+		go func(chatID int64) {
+			defer wg.Done()
+			for i := 0; i < 20; i++ {
+				update := chatUpdate(i, chatID)
+				update.Message.Text = "/start"
+				update.Message.From = &tgbotapi.User{ID: chatID, UserName: "cook"}
+				update.Message.Entities = []tgbotapi.MessageEntity{{Type: "bot_command", Length: len("/start")}}
+				bot.Dispatch(update)
+			}
+		}(chatID)
+	}
+	wg.Wait()
+
+	if bot.logger != botLogger {
+		t.Error("Dispatch replaced the shared bot logger")
+	}
+	for chatID := int64(1); chatID <= 8; chatID++ {
+		if handled[chatID] != 20 {
+			t.Errorf("chat %d handled %d commands, want 20", chatID, handled[chatID])
+		}
+	}
+}
+
+// commandUpdate builds an update with a command message like "/start"
+func commandUpdate(text string) Update {
+	update := chatUpdate(0, 1)
+	command, _, _ := strings.Cut(text, " ")
+	update.Message.Text = text
+	update.Message.From = &tgbotapi.User{ID: 1, UserName: "cook"}
+	update.Message.Entities = []tgbotapi.MessageEntity{{Type: "bot_command", Length: len(command)}}
+	return update
+}
+
+// callbackUpdate builds an update with a callback query carrying data
+func callbackUpdate(data string) Update {
+	return Update{Update: tgbotapi.Update{CallbackQuery: &tgbotapi.CallbackQuery{
+		ID:      "1",
+		From:    &tgbotapi.User{ID: 1, UserName: "cook"},
+		Message: &tgbotapi.Message{Chat: &tgbotapi.Chat{ID: 1}},
+		Data:    data,
+	}}}
+}
+
+func TestDispatchRoutesCommands(t *testing.T) {
+	bot, _ := newTestBot(t)
+
+	var handled []string
+	bot.handlers = handlers{
+		commands: map[string]CommandHandler{
+			"start": func(*tgbotapi.Message) { handled = append(handled, "start") },
+			"help":  func(*tgbotapi.Message) { handled = append(handled, "help") },
+		},
+		fallback: func(tgbotapi.Update) { handled = append(handled, "fallback") },
+	}
+
+	bot.Dispatch(commandUpdate("/help"))
+	bot.Dispatch(commandUpdate("/start@dinner_bot now"))
+	// Unknown commands and plain messages go to the default handler
+	bot.Dispatch(commandUpdate("/nope"))
+	plain := chatUpdate(0, 1)
+	plain.Message.Text = "hello"
+	bot.Dispatch(plain)
+
+	want := []string{"help", "start", "fallback", "fallback"}
+	if strings.Join(handled, ",") != strings.Join(want, ",") {
+		t.Errorf("handled %v, want %v", handled, want)
+	}
+}
+
+func TestDispatchMatchesCallbackPrefixes(t *testing.T) {
+	bot, _ := newTestBot(t)
+
+	var handled []string
+	handler := func(name string) CallbackHandler {
+		return func(*tgbotapi.CallbackQuery, string) { handled = append(handled, name) }
+	}
+	bot.handlers = handlers{
+		callbacks: map[string]CallbackHandler{
+			"rate":    handler("rate"),
+			"rate:":   handler("rate:"),
+			"rate_x:": handler("rate_x:"),
+		},
+		fallback: func(tgbotapi.Update) { handled = append(handled, "fallback") },
+	}
+
+	tests := []struct {
+		data string
+		want string
+	}{
+		{"rate:dinner:1:1700000000:5", "rate:"},
+		{"rate_x:dinner:1:1700000000", "rate_x:"},
+		{"rate_y", "rate"},
+	}
+	// Map iteration order is random, so dispatch each a few times
+	for i := 0; i < 10; i++ {
+		for _, tt := range tests {
+			handled = nil
+			bot.Dispatch(callbackUpdate(tt.data))
+			if len(handled) != 1 || handled[0] != tt.want {
+				t.Fatalf("callback %q handled by %v, want %s", tt.data, handled, tt.want)
+			}
+		}
+	}
+
+	// Callbacks nobody handles are dropped, not passed to the default handler
+	handled = nil
+	bot.Dispatch(callbackUpdate("unknown:1"))
+	if len(handled) != 0 {
+		t.Errorf("unknown callback handled by %v, want nothing", handled)
+	}
+}
+
+func TestDispatchFallsThroughToDefaultHandler(t *testing.T) {
+	bot, _ := newTestBot(t)
+
+	var fallback []int
+	bot.handlers = handlers{fallback: func(update tgbotapi.Update) { fallback = append(fallback, update.UpdateID) }}
+
+	bot.Dispatch(chatUpdate(1, 1))
+	bot.Dispatch(Update{Update: tgbotapi.Update{UpdateID: 2, PollAnswer: &tgbotapi.PollAnswer{PollID: "poll-1"}}})
+	// Reactions without a reaction handler are dropped
+	bot.Dispatch(Update{Update: tgbotapi.Update{UpdateID: 3}, MessageReaction: &MessageReactionUpdated{}})
+
+	if len(fallback) != 2 || fallback[0] != 1 || fallback[1] != 2 {
+		t.Errorf("default handler got updates %v, want [1 2]", fallback)
+	}
+}
+
+func TestDispatchHandsPollAnswersTheirChat(t *testing.T) {
+	bot, _ := newTestBot(t)
+	lookups := 0
+	bot.SetPollChatResolver(func(pollID string) (int64, error) {
+		lookups++
+		return 7, nil
+	})
+
+	var chats []int64
+	bot.handlers = handlers{pollAnswer: func(chatID int64, answer *tgbotapi.PollAnswer) { chats = append(chats, chatID) }}
+
+	update := Update{Update: tgbotapi.Update{UpdateID: 1, PollAnswer: &tgbotapi.PollAnswer{PollID: "poll-1"}}}
+	bot.queueChatID(&update)
+	bot.Dispatch(update)
+	// Answers to polls that aren't running are handed over without a chat
+	bot.Dispatch(Update{Update: tgbotapi.Update{UpdateID: 2, PollAnswer: &tgbotapi.PollAnswer{PollID: "poll-2"}}})
+
+	if len(chats) != 2 || chats[0] != 7 || chats[1] != 0 {
+		t.Errorf("poll answer handler got chats %v, want [7 0]", chats)
+	}
+	if lookups != 1 {
+		t.Errorf("the poll chat was looked up %d times, want once", lookups)
+	}
+}
+
+func TestDispatchPrefersTheLongestCallbackPrefix(t *testing.T) {
+	bot, _ := newTestBot(t)
+
+	var handled []string
+	bot.handlers = handlers{callbacks: map[string]CallbackHandler{
+		"done_adding":        func(*tgbotapi.CallbackQuery, string) { handled = append(handled, "done_adding") },
+		"done_adding_photos": func(*tgbotapi.CallbackQuery, string) { handled = append(handled, "done_adding_photos") },
+	}}
+
+	for i := 0; i < 20; i++ {
+		handled = nil
+		bot.Dispatch(callbackUpdate("done_adding_photos"))
+		bot.Dispatch(callbackUpdate("done_adding"))
+		if len(handled) != 2 || handled[0] != "done_adding_photos" || handled[1] != "done_adding" {
+			t.Fatalf("handled %v, want [done_adding_photos done_adding]", handled)
+		}
+	}
+}
+
+func TestDispatchPassesPayloadsWithColons(t *testing.T) {
+	bot, _ := newTestBot(t)
+
+	var payloads []string
+	handler := func(_ *tgbotapi.CallbackQuery, payload string) { payloads = append(payloads, payload) }
+	bot.handlers = handlers{callbacks: map[string]CallbackHandler{
+		"rate:":         handler,
+		"dinner_ready:": handler,
+		"update_fridge": handler,
+		"done_adding":   handler,
+	}}
+
+	bot.Dispatch(callbackUpdate("rate:dinner:-1001:1700000000:5"))
+	bot.Dispatch(callbackUpdate("dinner_ready:dinner:-1001:1700000000"))
+	bot.Dispatch(callbackUpdate("update_fridge:dinner:-1001:1700000000"))
+	bot.Dispatch(callbackUpdate("done_adding"))
+
+	want := []string{"dinner:-1001:1700000000:5", "dinner:-1001:1700000000", "dinner:-1001:1700000000", ""}
+	if strings.Join(payloads, "|") != strings.Join(want, "|") {
+		t.Errorf("payloads = %q, want %q", payloads, want)
+	}
+}
+
+func TestMessagesAreSentAsMarkdown(t *testing.T) {
+	bot, fake := newTestBot(t)
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData("Vote", "vote")))
+
+	bot.SendMessage(1, "*Pasta* won, see /dinner_info")
+	bot.SendMessageWithKeyboard(1, "*Pasta*", keyboard)
+	bot.SendReply(1, 7, "*Pasta*")
+	bot.EditMessage(1, 2, "*Pasta*")
+	bot.EditMessageWithKeyboard(1, 2, "*Pasta*", tgbotapi.InlineKeyboardMarkup{})
+
+	calls := append(fake.Calls("sendMessage"), fake.Calls("editMessageText")...)
+	if len(calls) != 5 {
+		t.Fatalf("made %d calls, want 5", len(calls))
+	}
+	for _, call := range calls {
+		if got := call.Params.Get("parse_mode"); got != tgbotapi.ModeMarkdown {
+			t.Errorf("%s parse_mode = %q, want %q", call.Method, got, tgbotapi.ModeMarkdown)
+		}
+	}
+	// Underscores in commands would start italic text
+	if got := calls[0].Params.Get("text"); got != "*Pasta* won, see /dinner\\_info" {
+		t.Errorf("text = %q, want the command escaped", got)
+	}
+	if got := calls[4].Params.Get("reply_markup"); !strings.Contains(got, "inline_keyboard") {
+		t.Errorf("reply_markup = %q, want the buttons removed", got)
+	}
+
+	fake.Reset()
+	bot.SetParseMode("")
+	bot.SendMessage(1, "*Pasta*")
+	if calls := fake.Calls("sendMessage"); len(calls) != 1 || calls[0].Params.Get("parse_mode") != "" || calls[0].Params.Get("text") != "*Pasta*" {
+		t.Errorf("plain text message sent as %v, want no parse mode", calls)
+	}
+}
+
+func TestUnparsableMessagesAreSentAsPlainText(t *testing.T) {
+	bot, fake := newTestBot(t)
+
+	for _, method := range []string{"sendMessage", "editMessageText"} {
+		fake.Fail(method, http.StatusBadRequest, "Bad Request: can't parse entities: Can't find end of the entity starting at byte offset 13")
+	}
+	if _, err := bot.SendMessage(1, "🎉 *Mac_Cheese* won, @anna\\_b cooks"); err != nil {
+		t.Fatalf("SendMessage failed: %v", err)
+	}
+	if _, err := bot.EditMessage(1, 2, "Use /add_photo for *more*"); err != nil {
+		t.Fatalf("EditMessage failed: %v", err)
+	}
+
+	for method, want := range map[string]string{"sendMessage": "🎉 Mac_Cheese won, @anna_b cooks", "editMessageText": "Use /add_photo for more"} {
+		calls := fake.Calls(method)
+		if len(calls) != 2 {
+			t.Fatalf("%s was called %d times, want a retry", method, len(calls))
+		}
+		retry := calls[1].Params
+		if retry.Get("parse_mode") != "" || retry.Get("text") != want {
+			t.Errorf("%s retried with text %q in mode %q, want %q as plain text", method, retry.Get("text"), retry.Get("parse_mode"), want)
+		}
+	}
+}
@@ -0,0 +1,14 @@
+package telegram
+
+import (
+	"strings"
+)
+
+// ParseCallbackData splits callback data like "rate:dinner:42:1700000000:5" into its action ("rate")
+// and payload ("dinner:42:1700000000:5"). Only the first colon separates them,
+// so payloads like dinner IDs may contain colons themselves.
+// Data without a colon is all action and has an empty payload.
+func ParseCallbackData(data string) (action, payload string) {
+	action, payload, _ = strings.Cut(data, ":")
+	return action, payload
+}
@@ -0,0 +1,98 @@
+package telegram
+
+import (
+	"fmt"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// CommandScope controls in which chats a command is shown in the Telegram command menu
+type CommandScope int
+
+const (
+	// ScopeGroup shows the command in group chats, where families plan dinner
+	ScopeGroup CommandScope = iota
+	// ScopePrivate shows the command in private chats with the bot
+	ScopePrivate
+	// ScopeAll shows the command in both group and private chats
+	ScopeAll
+	// ScopeHidden keeps the command out of the menu and the /help text
+	ScopeHidden
+)
+
+// Command describes a bot command together with its handler
+type Command struct {
+	Name        string
+	Description string
+	Scope       CommandScope
+	Handler     CommandHandler
+}
+
+// CommandRegistry keeps all bot commands in one place so that command routing,
+// the /help text and the Telegram command menu stay in sync
+type CommandRegistry struct {
+	commands []Command
+}
+
+// NewCommandRegistry creates a new empty command registry
+func NewCommandRegistry() *CommandRegistry {
+	return &CommandRegistry{}
+}
+
+// Register adds a command to the registry
+// Commands are shown in group chats by default, since every group is a family
+func (r *CommandRegistry) Register(name, description string, handler CommandHandler) {
+	r.RegisterScoped(name, description, ScopeGroup, handler)
+}
+
+// RegisterScoped adds a command to the registry with an explicit menu scope
+func (r *CommandRegistry) RegisterScoped(name, description string, scope CommandScope, handler CommandHandler) {
+	r.commands = append(r.commands, Command{
+		Name:        name,
+		Description: description,
+		Scope:       scope,
+		Handler:     handler,
+	})
+}
+
+// Commands returns all registered commands in registration order
+func (r *CommandRegistry) Commands() []Command {
+	return r.commands
+}
+
+// Handlers returns the command handlers keyed by command name
+func (r *CommandRegistry) Handlers() map[string]CommandHandler {
+	handlers := make(map[string]CommandHandler, len(r.commands))
+	for _, command := range r.commands {
+		handlers[command.Name] = command.Handler
+	}
+	return handlers
+}
+
+// BotCommands returns the commands shown in chats of the given scope,
+// in the format expected by Telegram
+func (r *CommandRegistry) BotCommands(scope CommandScope) []tgbotapi.BotCommand {
+	botCommands := make([]tgbotapi.BotCommand, 0, len(r.commands))
+	for _, command := range r.commands {
+		if command.Scope == ScopeHidden || (command.Scope != ScopeAll && command.Scope != scope) {
+			continue
+		}
+		botCommands = append(botCommands, tgbotapi.BotCommand{
+			Command:     command.Name,
+			Description: command.Description,
+		})
+	}
+	return botCommands
+}
+
+// HelpText returns a message listing every registered command with its description
+func (r *CommandRegistry) HelpText() string {
+	text := "🤖 Here's what I can do:\n\n"
+	for _, command := range r.commands {
+		if command.Scope == ScopeHidden {
+			continue
+		}
+		text += fmt.Sprintf("/%s – %s\n", command.Name, command.Description)
+	}
+	return text
+}
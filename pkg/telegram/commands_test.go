@@ -0,0 +1,71 @@
+package telegram
+
+import (
+	"strings"
+	"testing"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// testRegistry registers one command of every scope
+func testRegistry(handled *[]string) *CommandRegistry {
+	handler := func(name string) CommandHandler {
+		return func(*tgbotapi.Message) { *handled = append(*handled, name) }
+	}
+
+	r := NewCommandRegistry()
+	r.Register("dinner", "Start a dinner poll", handler("dinner"))
+	r.RegisterScoped("digest", "Get a private recap", ScopePrivate, handler("digest"))
+	r.RegisterScoped("help", "List all available commands", ScopeAll, handler("help"))
+	r.RegisterScoped("show_fridge", "Same as /fridge", ScopeHidden, handler("show_fridge"))
+	return r
+}
+
+func TestCommandRegistryHandlesEveryCommand(t *testing.T) {
+	var handled []string
+	r := testRegistry(&handled)
+
+	handlers := r.Handlers()
+	if len(handlers) != 4 {
+		t.Fatalf("registry has %d handlers, want 4", len(handlers))
+	}
+	for _, command := range r.Commands() {
+		handlers[command.Name](&tgbotapi.Message{})
+	}
+	if got := strings.Join(handled, ","); got != "dinner,digest,help,show_fridge" {
+		t.Errorf("handled %s, want every command in registration order", got)
+	}
+}
+
+func TestCommandRegistryHelpText(t *testing.T) {
+	r := testRegistry(new([]string))
+
+	want := "🤖 Here's what I can do:\n\n" +
+		"/dinner – Start a dinner poll\n" +
+		"/digest – Get a private recap\n" +
+		"/help – List all available commands\n"
+	if got := r.HelpText(); got != want {
+		t.Errorf("HelpText() = %q, want %q", got, want)
+	}
+}
+
+func TestCommandRegistryBotCommands(t *testing.T) {
+	r := testRegistry(new([]string))
+
+	names := func(commands []tgbotapi.BotCommand) string {
+		var names []string
+		for _, command := range commands {
+			names = append(names, command.Command)
+		}
+		return strings.Join(names, ",")
+	}
+	if got := names(r.BotCommands(ScopeGroup)); got != "dinner,help" {
+		t.Errorf("group commands = %s, want dinner,help", got)
+	}
+	if got := names(r.BotCommands(ScopePrivate)); got != "digest,help" {
+		t.Errorf("private commands = %s, want digest,help", got)
+	}
+	if got := r.BotCommands(ScopeGroup)[0].Description; got != "Start a dinner poll" {
+		t.Errorf("description = %q, want the registered one", got)
+	}
+}
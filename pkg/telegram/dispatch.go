@@ -0,0 +1,62 @@
+package telegram
+
+import (
+	"sync"
+)
+
+// dispatcher runs update handlers on a bounded number of workers.
+// Each chat has its own queue, so updates from one chat are handled in order and
+// one at a time, while a slow chat doesn't hold up the others.
+type dispatcher struct {
+	workers chan struct{} // one slot per worker, bounds how many handlers run at once
+	handle  func(update Update)
+
+	mu     sync.Mutex
+	queues map[int64][]Update // pending updates per chat, present while the chat is being drained
+}
+
+// newDispatcher creates a dispatcher with the given number of workers
+func newDispatcher(workers int, handle func(update Update)) *dispatcher {
+	if workers < 1 {
+		workers = 1
+	}
+
+	return &dispatcher{
+		workers: make(chan struct{}, workers),
+		handle:  handle,
+		queues:  make(map[int64][]Update),
+	}
+}
+
+// submit queues an update for its chat
+// Updates without a chat, like answers to unknown polls, share the queue for chat 0
+func (d *dispatcher) submit(chatID int64, update Update) {
+	d.mu.Lock()
+	queue, draining := d.queues[chatID]
+	d.queues[chatID] = append(queue, update)
+	d.mu.Unlock()
+
+	if !draining {
+		go d.drain(chatID)
+	}
+}
+
+// drain handles the queued updates of a chat in order until the queue is empty
+func (d *dispatcher) drain(chatID int64) {
+	for {
+		d.mu.Lock()
+		queue := d.queues[chatID]
+		if len(queue) == 0 {
+			delete(d.queues, chatID)
+			d.mu.Unlock()
+			return
+		}
+		update := queue[0]
+		d.queues[chatID] = queue[1:]
+		d.mu.Unlock()
+
+		d.workers <- struct{}{}
+		d.handle(update)
+		<-d.workers
+	}
+}
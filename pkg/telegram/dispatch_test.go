@@ -0,0 +1,162 @@
+package telegram
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// chatUpdate builds an update with the given ID from a chat
+func chatUpdate(id int, chatID int64) Update {
+	return Update{Update: tgbotapi.Update{
+		UpdateID: id,
+		Message:  &tgbotapi.Message{Chat: &tgbotapi.Chat{ID: chatID}},
+	}}
+}
+
+func TestDispatcherSerializesUpdatesPerChat(t *testing.T) {
+	var mu sync.Mutex
+	running := make(map[int64]int)
+	handled := make(map[int64][]int)
+	maxPerChat, maxTotal, total := 0, 0, 0
+	var wg sync.WaitGroup
+
+	d := newDispatcher(4, func(update Update) {
+		defer wg.Done()
+		chatID := updateChatID(update)
+
+		mu.Lock()
+		running[chatID]++
+		total++
+		maxPerChat = max(maxPerChat, running[chatID])
+		maxTotal = max(maxTotal, total)
+		handled[chatID] = append(handled[chatID], update.UpdateID)
+		mu.Unlock()
+
+		time.Sleep(2 * time.Millisecond)
+
+		mu.Lock()
+		running[chatID]--
+		total--
+		mu.Unlock()
+	})
+
+	for i := 0; i < 20; i++ {
+		wg.Add(2)
+		d.submit(1, chatUpdate(i, 1))
+		d.submit(2, chatUpdate(i, 2))
+	}
+	wg.Wait()
+
+	if maxPerChat != 1 {
+		t.Errorf("%d updates of one chat ran at once, want 1", maxPerChat)
+	}
+	if maxTotal < 2 {
+		t.Errorf("at most %d updates ran at once, want the chats to run in parallel", maxTotal)
+	}
+	for chatID, ids := range handled {
+		for i, id := range ids {
+			if id != i {
+				t.Fatalf("chat %d handled updates %v, want them in order", chatID, ids)
+			}
+		}
+	}
+}
+
+func TestDispatcherBoundsWorkers(t *testing.T) {
+	var mu sync.Mutex
+	running, maxRunning := 0, 0
+	var wg sync.WaitGroup
+
+	d := newDispatcher(2, func(Update) {
+		defer wg.Done()
+		mu.Lock()
+		running++
+		maxRunning = max(maxRunning, running)
+		mu.Unlock()
+
+		time.Sleep(2 * time.Millisecond)
+
+		mu.Lock()
+		running--
+		mu.Unlock()
+	})
+
+	for chatID := int64(1); chatID <= 10; chatID++ {
+		wg.Add(1)
+		d.submit(chatID, chatUpdate(0, chatID))
+	}
+	wg.Wait()
+
+	if maxRunning > 2 {
+		t.Errorf("%d handlers ran at once with 2 workers", maxRunning)
+	}
+}
+
+func TestPollAnswersQueueBehindTheirChat(t *testing.T) {
+	bot, _ := newTestBot(t)
+	bot.SetPollChatResolver(func(pollID string) (int64, error) {
+		if pollID == "poll-1" {
+			return 1, nil
+		}
+		return 0, errors.New("unknown poll")
+	})
+
+	answer := func(id int, pollID string) Update {
+		return Update{Update: tgbotapi.Update{
+			UpdateID:   id,
+			PollAnswer: &tgbotapi.PollAnswer{PollID: pollID},
+		}}
+	}
+	known, unknown := answer(0, "poll-1"), answer(0, "poll-9")
+	if got := bot.queueChatID(&known); got != 1 || known.PollChatID != 1 {
+		t.Fatalf("answer to poll-1 queued for chat %d with chat %d, want 1", got, known.PollChatID)
+	}
+	if got := bot.queueChatID(&unknown); got != 0 {
+		t.Fatalf("answer to an unknown poll queued for chat %d, want 0", got)
+	}
+
+	// A slow update of chat 1 holds back its poll answer but not chat 2
+	release := make(chan struct{})
+	var mu sync.Mutex
+	var order []int
+	var wg sync.WaitGroup
+	d := newDispatcher(4, func(update Update) {
+		defer wg.Done()
+		if update.UpdateID == 1 {
+			<-release
+		}
+		mu.Lock()
+		order = append(order, update.UpdateID)
+		mu.Unlock()
+	})
+
+	wg.Add(3)
+	d.submit(1, chatUpdate(1, 1))
+	slowAnswer := answer(2, "poll-1")
+	d.submit(bot.queueChatID(&slowAnswer), slowAnswer)
+	d.submit(2, chatUpdate(3, 2))
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		mu.Lock()
+		done := len(order)
+		mu.Unlock()
+		if done == 1 || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+	wg.Wait()
+
+	want := []int{3, 1, 2}
+	for i := range want {
+		if order[i] != want[i] {
+			t.Fatalf("handled updates %v, want %v", order, want)
+		}
+	}
+}
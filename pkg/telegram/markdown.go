@@ -0,0 +1,49 @@
+package telegram
+
+import (
+	"regexp"
+	"strings"
+)
+
+// commandPattern matches bot commands with underscores, like /add_photo
+var commandPattern = regexp.MustCompile(`/[A-Za-z0-9]*_[A-Za-z0-9_]*`)
+
+// escapeCommands escapes the underscores in bot commands like /add_photo,
+// which Markdown would otherwise read as the start of italic text
+func escapeCommands(text string) string {
+	return commandPattern.ReplaceAllStringFunc(text, func(command string) string {
+		return strings.ReplaceAll(command, "_", `\_`)
+	})
+}
+
+// stripMarkdown turns Markdown text into the plain text it shows: escaped characters lose their
+// backslash and the asterisks of bold parts are dropped
+func stripMarkdown(text string) string {
+	var plain strings.Builder
+	escaped := false
+	for _, r := range text {
+		switch {
+		case escaped:
+			if !strings.ContainsRune("_*`[", r) {
+				plain.WriteRune('\\')
+			}
+			plain.WriteRune(r)
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == '*':
+			// Bold markers don't show
+		default:
+			plain.WriteRune(r)
+		}
+	}
+	if escaped {
+		plain.WriteRune('\\')
+	}
+	return plain.String()
+}
+
+// isParseError reports whether Telegram rejected a message because it couldn't parse its formatting
+func isParseError(err error) bool {
+	return err != nil && strings.Contains(err.Error(), "can't parse entities")
+}
@@ -0,0 +1,18 @@
+package telegram
+
+import "testing"
+
+func TestStripMarkdownShowsThePlainText(t *testing.T) {
+	tests := map[string]string{
+		"🎉 *2*\\**2 Soup* won!":      "🎉 2*2 Soup won!",
+		"@anna\\_b cooks":            "@anna_b cooks",
+		"Use /add\\_photo":           "Use /add_photo",
+		"C:\\path and a trailing \\": "C:\\path and a trailing \\",
+		"*Ingredients:*\n• \\[1]":    "Ingredients:\n• [1]",
+	}
+	for text, want := range tests {
+		if got := stripMarkdown(text); got != want {
+			t.Errorf("stripMarkdown(%q) = %q, want %q", text, got, want)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package telegram
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Telegram allows about one message per second in a chat and 30 messages per second overall.
+// Outgoing messages are paced to stay under these limits instead of running into them.
+const (
+	chatSendInterval   = time.Second
+	globalSendInterval = 35 * time.Millisecond
+)
+
+// maxRetryAfter caps how long we wait when Telegram asks us to slow down,
+// so a handler never blocks for minutes
+const maxRetryAfter = 30 * time.Second
+
+// pacer hands out send slots so that messages leave in order with enough time between them.
+// Callers block until their slot, which makes it behave like an outgoing message queue.
+type pacer struct {
+	mu             sync.Mutex
+	chatInterval   time.Duration
+	globalInterval time.Duration
+	nextGlobal     time.Time
+	nextChat       map[int64]time.Time
+}
+
+// newPacer creates a new pacer that keeps to Telegram's limits
+func newPacer() *pacer {
+	return &pacer{
+		chatInterval:   chatSendInterval,
+		globalInterval: globalSendInterval,
+		nextChat:       make(map[int64]time.Time),
+	}
+}
+
+// wait blocks until a message may be sent to the chat. Chat 0 is only paced globally.
+func (p *pacer) wait(chatID int64) {
+	p.mu.Lock()
+	now := time.Now()
+
+	slot := now
+	if p.nextGlobal.After(slot) {
+		slot = p.nextGlobal
+	}
+	if chatID != 0 && p.nextChat[chatID].After(slot) {
+		slot = p.nextChat[chatID]
+	}
+
+	p.nextGlobal = slot.Add(p.globalInterval)
+	if chatID != 0 {
+		p.nextChat[chatID] = slot.Add(p.chatInterval)
+	}
+
+	// Forget chats whose slots have passed so the map doesn't grow forever
+	for id, next := range p.nextChat {
+		if next.Before(now) {
+			delete(p.nextChat, id)
+		}
+	}
+	p.mu.Unlock()
+
+	time.Sleep(time.Until(slot))
+}
+
+// retryAfter returns how long Telegram asked us to wait if err is a 429 Too Many Requests error
+func retryAfter(err error) (time.Duration, bool) {
+	var apiErr *tgbotapi.Error
+	if !errors.As(err, &apiErr) || apiErr.Code != http.StatusTooManyRequests {
+		return 0, false
+	}
+
+	wait := time.Duration(apiErr.RetryAfter) * time.Second
+	if wait <= 0 {
+		wait = time.Second
+	}
+	if wait > maxRetryAfter {
+		wait = maxRetryAfter
+	}
+	return wait, true
+}
+
+// send paces a Chattable and sends it to Telegram.
+// If Telegram answers with 429 Too Many Requests, it waits for the requested time and retries once.
+func (b *Bot) send(c tgbotapi.Chattable) (tgbotapi.Message, error) {
+	chatID := chattableChatID(c)
+
+	b.pacer.wait(chatID)
+	msg, err := b.api.Send(c)
+
+	if wait, ok := retryAfter(err); ok {
+		b.logger.Warn("Hit the Telegram rate limit in chat %d, retrying in %s", chatID, wait)
+		time.Sleep(wait)
+		b.pacer.wait(chatID)
+		msg, err = b.api.Send(c)
+	}
+
+	return msg, err
+}
+
+// chattableChatID returns the chat a Chattable is sent to, or 0 if it isn't known
+func chattableChatID(c tgbotapi.Chattable) int64 {
+	switch config := c.(type) {
+	case tgbotapi.MessageConfig:
+		return config.ChatID
+	case tgbotapi.SendPollConfig:
+		return config.ChatID
+	case tgbotapi.PhotoConfig:
+		return config.ChatID
+	case tgbotapi.DocumentConfig:
+		return config.ChatID
+	case tgbotapi.EditMessageTextConfig:
+		return config.ChatID
+	case tgbotapi.EditMessageReplyMarkupConfig:
+		return config.ChatID
+	case tgbotapi.StopPollConfig:
+		return config.ChatID
+	}
+	return 0
+}
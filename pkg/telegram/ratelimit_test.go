@@ -0,0 +1,82 @@
+package telegram
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+func TestRetryAfter(t *testing.T) {
+	tooMany := func(seconds int) error {
+		return &tgbotapi.Error{Code: http.StatusTooManyRequests, ResponseParameters: tgbotapi.ResponseParameters{RetryAfter: seconds}}
+	}
+
+	tests := []struct {
+		name   string
+		err    error
+		want   time.Duration
+		wantOK bool
+	}{
+		{"retry after", tooMany(3), 3 * time.Second, true},
+		{"wrapped", fmt.Errorf("send failed: %w", tooMany(2)), 2 * time.Second, true},
+		{"missing retry after", tooMany(0), time.Second, true},
+		{"capped", tooMany(600), maxRetryAfter, true},
+		{"other API error", &tgbotapi.Error{Code: http.StatusBadRequest}, 0, false},
+		{"other error", errors.New("connection reset"), 0, false},
+		{"no error", nil, 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := retryAfter(tt.err)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("%s: retryAfter() = %s, %v, want %s, %v", tt.name, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestSendRetriesOnceAfterRateLimit(t *testing.T) {
+	bot, fake := newTestBot(t)
+	fake.RateLimit("sendMessage", 1)
+
+	start := time.Now()
+	if _, err := bot.SendMessage(1, "Dinner is ready"); err != nil {
+		t.Fatalf("SendMessage failed: %v", err)
+	}
+	if waited := time.Since(start); waited < time.Second {
+		t.Errorf("retried after %s, want the requested second", waited)
+	}
+	if calls := fake.Calls("sendMessage"); len(calls) != 2 {
+		t.Errorf("sent %d requests, want the rate limited one and a retry", len(calls))
+	}
+
+	// A second 429 in a row is given up on
+	fake.RateLimit("sendMessage", 1)
+	fake.RateLimit("sendMessage", 1)
+	if _, err := bot.SendMessage(1, "Dinner is ready"); err == nil {
+		t.Error("SendMessage succeeded although Telegram kept rate limiting")
+	}
+}
+
+func TestPacerSpacesMessagesPerChat(t *testing.T) {
+	p := newPacer()
+	p.chatInterval = 20 * time.Millisecond
+	p.globalInterval = 0
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		p.wait(1)
+	}
+	if elapsed := time.Since(start); elapsed < 40*time.Millisecond {
+		t.Errorf("3 messages to one chat took %s, want at least 40ms", elapsed)
+	}
+
+	// Another chat isn't held back by the first one
+	start = time.Now()
+	p.wait(2)
+	if elapsed := time.Since(start); elapsed > 10*time.Millisecond {
+		t.Errorf("first message to another chat waited %s", elapsed)
+	}
+}
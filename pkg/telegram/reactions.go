@@ -0,0 +1,84 @@
+package telegram
+
+import (
+	"encoding/json"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// ReactionType describes a single reaction on a message
+type ReactionType struct {
+	Type          string `json:"type"`
+	Emoji         string `json:"emoji,omitempty"`
+	CustomEmojiID string `json:"custom_emoji_id,omitempty"`
+}
+
+// MessageReactionUpdated is sent when a user changes their reaction on a message.
+// The telegram-bot-api library doesn't know about this update type yet, so we decode it ourselves.
+type MessageReactionUpdated struct {
+	Chat        tgbotapi.Chat  `json:"chat"`
+	MessageID   int            `json:"message_id"`
+	User        *tgbotapi.User `json:"user,omitempty"`
+	ActorChat   *tgbotapi.Chat `json:"actor_chat,omitempty"`
+	Date        int            `json:"date"`
+	OldReaction []ReactionType `json:"old_reaction"`
+	NewReaction []ReactionType `json:"new_reaction"`
+}
+
+// ReactionHandler is a function that handles a change of message reactions
+type ReactionHandler func(reaction *MessageReactionUpdated)
+
+// Update is a Telegram update extended with the update types
+// that the telegram-bot-api library doesn't decode
+type Update struct {
+	tgbotapi.Update
+	MessageReaction *MessageReactionUpdated `json:"message_reaction,omitempty"`
+	// PollChatID is the chat of a poll answer, found with the poll chat resolver, 0 if it isn't known
+	PollChatID int64 `json:"-"`
+}
+
+// allowedUpdates lists the update types the bot asks Telegram for.
+// message_reaction is not sent by default, so it has to be requested explicitly.
+var allowedUpdates = []string{
+	"message",
+	"edited_message",
+	"callback_query",
+	"poll",
+	"poll_answer",
+	"my_chat_member",
+	"message_reaction",
+}
+
+// getUpdatesChan polls Telegram for updates and sends them to the returned channel.
+// It mirrors tgbotapi.BotAPI.GetUpdatesChan but decodes our extended Update type.
+func (b *Bot) getUpdatesChan(config tgbotapi.UpdateConfig) <-chan Update {
+	ch := make(chan Update, b.api.Buffer)
+
+	go func() {
+		for {
+			resp, err := b.api.Request(config)
+			if err != nil {
+				b.logger.Error("Failed to get updates, retrying in 3 seconds: %v", err)
+				time.Sleep(3 * time.Second)
+				continue
+			}
+
+			var updates []Update
+			if err := json.Unmarshal(resp.Result, &updates); err != nil {
+				b.logger.Error("Failed to decode updates: %v", err)
+				time.Sleep(3 * time.Second)
+				continue
+			}
+
+			for _, update := range updates {
+				if update.UpdateID >= config.Offset {
+					config.Offset = update.UpdateID + 1
+					ch <- update
+				}
+			}
+		}
+	}()
+
+	return ch
+}